@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/toozej/files2prompt/internal/files2prompt"
+)
+
+// newDoctorCmd creates the "doctor" command, which audits the files a run
+// over the given paths would select and reports potential problems.
+//
+// Returns:
+//   - *cobra.Command: A configured doctor command
+func newDoctorCmd() *cobra.Command {
+	opts := files2prompt.DefaultDoctorOptions()
+	var maxPaths int
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor [paths...]",
+		Short: "Audit a tree for problems before generating a prompt",
+		Long: `Audit the files that would be selected from the given paths (default ".")
+and report potential problems: extensions with no language mapping, very large
+files, binaries with text extensions, .gitignore negations that cannot take
+effect, and symlinks. Each finding names the affected paths and the flag or
+change that addresses it.
+
+Filters set through environment variables are honored.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doctorConf := conf
+			doctorConf.Paths = args
+			if len(args) == 0 {
+				doctorConf.Paths = []string{"."}
+			}
+			findings, err := files2prompt.Doctor(cmd.Context(), doctorConf, opts)
+			if err != nil {
+				return err
+			}
+			return files2prompt.WriteDoctorReport(cmd.OutOrStdout(), findings, maxPaths)
+		},
+	}
+	doctorCmd.Flags().Int64VarP(&opts.LargeFileBytes, "large-file-bytes", "", opts.LargeFileBytes,
+		"Report selected files at least this many bytes as large")
+	doctorCmd.Flags().IntVarP(&maxPaths, "max-paths", "", 10, "Maximum number of paths listed per finding (0 lists all)")
+	return doctorCmd
+}
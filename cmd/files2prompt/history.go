@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/toozej/files2prompt/internal/files2prompt"
+	"github.com/toozej/files2prompt/internal/history"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// recordHistory appends the just-completed run to the local history file,
+// unless --no-history is set.
+//
+// Failures are logged at warn level and never fail the run itself, since the
+// history is a convenience rather than part of the tool's output.
+//
+// Parameters:
+//   - cmd: The root command whose parsed flags describe the run
+//   - summary: The output summary returned by files2prompt.Run
+func recordHistory(cmd *cobra.Command, summary files2prompt.Summary) {
+	path, err := history.DefaultPath()
+	if err != nil {
+		log.Warnf("Warning: Could not determine history file location: %v", err)
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Warnf("Warning: Could not get working directory for history: %v", err)
+		return
+	}
+	entry, err := historyEntry(cmd.Flags(), conf, summary)
+	if err != nil {
+		log.Warnf("Warning: Could not record run history: %v", err)
+		return
+	}
+	entry.Time, entry.Dir = time.Now().UTC(), cwd
+	if _, err := history.Append(path, entry, conf.HistorySize); err != nil {
+		log.Warnf("Warning: Could not record run history: %v", err)
+	}
+}
+
+// historyEntry returns the history entry of a run of c, as flags set it.
+//
+// The run is recorded with the flags that reproduce its effective
+// configuration, including the settings that came from config files and the
+// environment. The values of the sensitive flags, and of --output when it is
+// a URL holding credentials, are left out, as are the paths and content read
+// from stdin; such a run can be shown but not re-run.
+//
+// Parameters:
+//   - flags: The parsed flags of the root command
+//   - c: The configuration of the run
+//   - summary: The output summary returned by files2prompt.Run
+//
+// Returns:
+//   - history.Entry: The entry, without its time and directory
+//   - error: An error if the built-in defaults cannot be determined
+func historyEntry(flags *pflag.FlagSet, c config.Config, summary files2prompt.Summary) (history.Entry, error) {
+	defaults, err := config.Defaults()
+	if err != nil {
+		return history.Entry{}, err
+	}
+	builtin := pflag.NewFlagSet("defaults", pflag.ContinueOnError)
+	addRootFlags(builtin, &defaults)
+	output := files2prompt.RedactURL(c.OutputFile)
+	if output != c.OutputFile {
+		_ = flags.SetAnnotation("output", history.SensitiveAnnotation, []string{"true"})
+	}
+	args, elided := history.FlagArgs(flags, builtin)
+	args = append(args, "--")
+	args = append(args, c.Paths...)
+	return history.Entry{
+		Args:       args,
+		OutputFile: output,
+		Elided:     elided,
+		Stdin:      len(c.StdinPaths) > 0 || slices.Contains(c.Paths, "-"),
+		Files:      summary.Files,
+		Bytes:      summary.Bytes,
+		Tokens:     summary.Tokens,
+	}, nil
+}
+
+// newHistoryCmd creates the "history" command and its "show" and "rerun" subcommands.
+//
+// Returns:
+//   - *cobra.Command: A configured history command
+func newHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show or re-run previous invocations in the current directory",
+		Long: `Show or re-run previous files2prompt invocations recorded in the local history file.
+
+Every run is recorded unless --no-history is given, or NO_HISTORY=true is set.
+History is stored in the user data directory, never inside the repository.
+The values of flags that can hold commands or credentials are not recorded.`,
+	}
+
+	var limit int
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "List recent runs in the current directory",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := loadHistoryForCwd(limit)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTIME\tFILES\tBYTES\tTOKENS\tOUTPUT\tARGS")
+			for _, e := range entries {
+				output := e.OutputFile
+				if output == "" {
+					output = "-"
+				}
+				fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%s\n",
+					e.ID, e.Time.Local().Format(time.DateTime), e.Files, e.Bytes, e.Tokens, output, strings.Join(e.Args, " "))
+			}
+			return w.Flush()
+		},
+	}
+	showCmd.Flags().IntVarP(&limit, "limit", "l", 20, "Number of entries to show")
+
+	rerunCmd := &cobra.Command{
+		Use:   "rerun <id>",
+		Short: "Re-run a previous invocation with the same effective flags",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid history id %q: %v", args[0], err)
+			}
+			path, err := history.DefaultPath()
+			if err != nil {
+				return err
+			}
+			entries, err := history.Load(path)
+			if err != nil {
+				return err
+			}
+			entry, ok := history.Find(entries, id)
+			if !ok {
+				return fmt.Errorf("no history entry with id %d", id)
+			}
+			if err := rerunnable(entry); err != nil {
+				return err
+			}
+			self, err := os.Executable()
+			if err != nil {
+				return err
+			}
+			log.Debugf("Re-running history entry %d in %s: %v", entry.ID, entry.Dir, entry.Args)
+			rerun := exec.Command(self, entry.Args...) // #nosec G204
+			rerun.Dir = entry.Dir
+			rerun.Stdout = cmd.OutOrStdout()
+			rerun.Stderr = cmd.ErrOrStderr()
+			return rerun.Run()
+		},
+	}
+
+	historyCmd.AddCommand(showCmd, rerunCmd)
+	return historyCmd
+}
+
+// rerunnable returns an error when entry was recorded without something its
+// run depended on, so that re-running it would not reproduce it.
+func rerunnable(entry history.Entry) error {
+	if entry.Stdin {
+		return fmt.Errorf("history entry %d cannot be re-run: it read from stdin, which is not recorded", entry.ID)
+	}
+	if len(entry.Elided) > 0 {
+		names := make([]string, len(entry.Elided))
+		for i, name := range entry.Elided {
+			names[i] = "--" + name
+		}
+		return fmt.Errorf("history entry %d cannot be re-run: the values of %s are not recorded, as they can hold commands or credentials",
+			entry.ID, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// loadHistoryForCwd returns up to limit history entries recorded in the current directory.
+func loadHistoryForCwd(limit int) ([]history.Entry, error) {
+	path, err := history.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := history.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return history.ForDir(entries, cwd, limit), nil
+}
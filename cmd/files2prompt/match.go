@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/toozej/files2prompt/internal/files2prompt"
+)
+
+// newMatchCmd creates the "match" command, which reports whether a walk would
+// include each given path, without reading the contents of any file.
+//
+// Returns:
+//   - *cobra.Command: A configured match command
+func newMatchCmd() *cobra.Command {
+	root := "."
+
+	matchCmd := &cobra.Command{
+		Use:   "match [flags] <path>...",
+		Short: "Report whether a walk would include each path",
+		Long: `Decide, for each path, whether walking the --root directory (default ".")
+would include it, using the same filters as a run: .gitignore and ignore
+patterns, extensions, hidden and sensitive files, sizes and so on. Each path is
+printed as "include PATH" or "exclude PATH: REASON", and the exit status is 0
+only when every path is included, for use in hooks that keep files visible to
+prompts.
+
+No file content is read, so --grep does not apply, nor do --fit-tokens and
+--max-tokens, which depend on the other files selected.
+
+Filters set through config files and environment variables are honored.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results, err := files2prompt.Match(conf, root, args)
+			if err != nil {
+				return err
+			}
+			if err := files2prompt.WriteMatchReport(cmd.OutOrStdout(), results); err != nil {
+				return err
+			}
+			excluded := 0
+			for _, r := range results {
+				if !r.Included {
+					excluded++
+				}
+			}
+			if excluded > 0 {
+				return fmt.Errorf("%d of %d paths would be excluded", excluded, len(results))
+			}
+			return nil
+		},
+	}
+	matchCmd.Flags().StringVarP(&root, "root", "", root, "Directory whose walk the paths are matched against")
+	addFilterFlags(matchCmd.Flags(), &conf)
+	return matchCmd
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// newPresetsCmd creates the "presets" command, which lists the presets --preset
+// can apply with their settings.
+//
+// Returns:
+//   - *cobra.Command: A configured presets command
+func newPresetsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "presets",
+		Short: "List the presets --preset can apply",
+		Long: `List the built-in presets and those defined under "presets" in the config
+files, each with its version, where it is defined and its settings.
+
+Presets given to --preset apply over the config files in order, each overriding
+the settings of the ones before it, under the profile, environment variables and
+flags. A preset defined in a config file replaces a built-in one of the same name.
+Pin a version as name@version to fail rather than select other files when the
+preset changes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			presets, err := config.Presets(cwd)
+			if err != nil {
+				return err
+			}
+			writePresets(cmd.OutOrStdout(), presets)
+			return nil
+		},
+	}
+}
+
+// writePresets writes each of presets under a line naming it, its version and
+// source, followed by its description and settings, with lists comma-separated
+// as environment variables take them.
+//
+// Parameters:
+//   - w: The writer to list them to
+//   - presets: The presets to list
+func writePresets(w io.Writer, presets []config.Preset) {
+	for i, p := range presets {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		version := "unversioned"
+		if p.Version > 0 {
+			version = fmt.Sprintf("version %d", p.Version)
+		}
+		fmt.Fprintf(w, "%s (%s, %s)\n", p.Name, version, p.Source)
+		if p.Description != "" {
+			fmt.Fprintf(w, "  %s\n", p.Description)
+		}
+		for _, name := range slices.Sorted(maps.Keys(p.Settings)) {
+			key := strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+			fmt.Fprintf(w, "    %s: %s\n", key, p.Settings[name])
+		}
+	}
+}
@@ -20,21 +20,26 @@
 package cmd
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/toozej/files2prompt/internal/files2prompt"
+	"github.com/toozej/files2prompt/internal/history"
 	"github.com/toozej/files2prompt/pkg/config"
 	"github.com/toozej/files2prompt/pkg/man"
 	"github.com/toozej/files2prompt/pkg/version"
 )
 
-// conf holds the application configuration loaded from environment variables.
+// conf holds the application configuration loaded from config files and environment variables.
 // It is populated during package initialization and can be modified by command-line flags.
 var (
 	conf config.Config
@@ -54,20 +59,77 @@ var rootCmd = &cobra.Command{
 	Short: "Crawl and output file contents with various filtering options for AI prompting",
 	Long: `files2prompt helps prepare files for AI prompts by crawling directories
 and outputting file contents with optional filtering and formatting.`,
+	Example: `  # Each file under a heading, as a template (file.tmpl):
+  #   ### {{.Index}}. {{.Path}} ({{.Lines}} lines)
+  #   {{.Content}}
+  files2prompt --template file.tmpl .
+
+  # Files as YAML list entries (entry.tmpl), after a header (header.tmpl: "# {{.FileCount}} files"):
+  #   - path: {{.Path}}
+  #     language: {{.Lang}}
+  #     content: |
+  #   {{.Content | indent 6}}
+  files2prompt --template entry.tmpl --header-template header.tmpl src`,
 	Args:             cobra.ArbitraryArgs,
 	PersistentPreRun: rootCmdPreRun,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Read paths from stdin if available
-		stdinPaths := readPathsFromStdin(conf.Null)
-		// Combine args and stdin paths
-		conf.Paths = append(args, stdinPaths...)
-		if len(conf.Paths) == 0 {
+		conf.Paths = args
+		if slices.Contains(args, "-") {
+			// "-" makes stdin a document
+			content, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read stdin: %v", err)
+			}
+			conf.StdinContent = string(content)
+		} else {
+			// Read paths from stdin if available; they are filtered as a file list
+			conf.StdinPaths = readPathsFromStdin(conf.Null)
+		}
+		if conf.Batch != "" {
+			return runBatch(cmd, conf)
+		}
+		// --git-diff and --git-staged default to the repository of the working directory
+		if len(conf.Paths) == 0 && len(conf.StdinPaths) == 0 && len(conf.Commands) == 0 && conf.GitDiff == "" && !conf.GitStaged {
 			return fmt.Errorf("no paths provided via arguments or stdin")
 		}
-		return files2prompt.Run(conf)
+		summary, err := files2prompt.Run(conf)
+		if err != nil {
+			return err
+		}
+		if !conf.NoHistory {
+			recordHistory(cmd, summary)
+		}
+		return nil
 	},
 }
 
+// runBatch runs the jobs of the --batch file, reporting each on stderr, and
+// fails if any of them did.
+func runBatch(cmd *cobra.Command, conf config.Config) error {
+	jobs, err := files2prompt.LoadBatch(conf.Batch)
+	if err != nil {
+		return err
+	}
+	results, err := files2prompt.RunBatch(cmd.Context(), conf, jobs)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: failed: %v\n", r.Job, r.Err)
+			continue
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s: %d files, %d bytes, ~%d tokens -> %s\n",
+			r.Job, r.Summary.Files, r.Summary.Bytes, r.Summary.Tokens, r.Output)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d batch jobs failed", failed, len(results))
+	}
+	return nil
+}
+
 // rootCmdPreRun performs setup operations before executing the root command.
 // This function is called before both the root command and any subcommands.
 //
@@ -85,18 +147,11 @@ func rootCmdPreRun(cmd *cobra.Command, args []string) {
 
 // readPathsFromStdin reads file paths from standard input when available.
 //
-// This function checks if stdin contains data and reads it as a list of file paths.
-// It supports two input formats based on the useNull parameter:
-//   - When useNull is true: paths are separated by null characters (\x00)
+// This function checks if stdin contains data and reads it as a list of file
+// paths, parsed by files2prompt.ParsePathList:
+//   - When useNull is true: paths are terminated by null characters (\x00)
 //   - When useNull is false: paths are separated by whitespace
 //
-// The function performs the following operations:
-//  1. Checks if stdin has available data
-//  2. Reads all content from stdin
-//  3. Splits content based on the specified separator
-//  4. Filters out empty path entries
-//  5. Returns the list of valid paths
-//
 // Parameters:
 //   - useNull: If true, use null character as separator; otherwise use whitespace
 //
@@ -123,28 +178,55 @@ func readPathsFromStdin(useNull bool) []string {
 	if err != nil {
 		return nil
 	}
-	var paths []string
-	if useNull {
-		paths = strings.Split(string(content), "\x00")
-	} else {
-		paths = strings.Fields(string(content))
+	return files2prompt.ParsePathList(string(content), useNull)
+}
+
+// profileArg returns the value of the --profile flag among args, or "" when
+// it is not given. It is read ahead of flag parsing, as the profile decides the
+// defaults the flags are bound with.
+func profileArg(args []string) string {
+	profile := ""
+	if values := flagValues(args, "profile"); len(values) > 0 {
+		profile = values[len(values)-1]
+	}
+	return profile
+}
+
+// presetArgs returns the presets the --preset flags among args name, in
+// order, as profileArg reads --profile.
+func presetArgs(args []string) []string {
+	var presets []string
+	for _, value := range flagValues(args, "preset") {
+		presets = append(presets, strings.Split(value, ",")...)
 	}
-	// Filter empty
-	var filtered []string
-	for _, p := range paths {
-		if p != "" {
-			filtered = append(filtered, p)
+	return presets
+}
+
+// flagValues returns the values given to the flag called name among args, in
+// order, up to a "--" argument.
+func flagValues(args []string, name string) []string {
+	var values []string
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return values
+		case arg == "--"+name && i+1 < len(args):
+			values = append(values, args[i+1])
+		case strings.HasPrefix(arg, "--"+name+"="):
+			values = append(values, strings.TrimPrefix(arg, "--"+name+"="))
 		}
 	}
-	return filtered
+	return values
 }
 
 // Execute starts the command-line interface execution.
 // This is the main entry point called from main.go to begin command processing.
 //
 // If command execution fails, it prints the error message to stdout and
-// exits the program with status code 1. This follows standard Unix conventions
-// for command-line tool error handling.
+// exits the program with status code 1, files2prompt.ExitCodeUnconfirmed when
+// crawling a path needed a confirmation that was not given, or
+// files2prompt.ExitCodeHookFailed when only the --exec hook failed. This
+// follows standard Unix conventions for command-line tool error handling.
 //
 // Example:
 //
@@ -154,63 +236,336 @@ func readPathsFromStdin(useNull bool) []string {
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err.Error())
+		var hookErr *files2prompt.HookError
+		if errors.As(err, &hookErr) {
+			os.Exit(files2prompt.ExitCodeHookFailed)
+		}
+		var guardErr *files2prompt.GuardError
+		if errors.As(err, &guardErr) {
+			os.Exit(files2prompt.ExitCodeUnconfirmed)
+		}
 		os.Exit(1)
 	}
 }
 
+// addFilterFlags binds the flags that decide which files are selected to
+// flags, so that every command sharing them selects files alike.
+//
+// Parameters:
+//   - flags: The flag set of the command to bind them to
+//   - c: The configuration the flags set, whose values are their defaults
+func addFilterFlags(flags *pflag.FlagSet, c *config.Config) {
+	flags.StringSliceVarP(&c.Extensions, "extension", "e", c.Extensions, "File extensions to include")
+	flags.StringSliceVarP(&c.ExcludeExtensions, "exclude-ext", "", c.ExcludeExtensions,
+		"File extensions to leave out, applied after --extension (can be comma-separated or specified multiple times)")
+	flags.BoolVarP(&c.IgnoreCase, "ignore-case", "", c.IgnoreCase,
+		"Match --extension, --exclude-ext, --ignore and --include regardless of case, so -e .md keeps README.MD")
+	flags.BoolVarP(&c.IncludeHidden, "include-hidden", "", c.IncludeHidden, "Include hidden files and folders")
+	flags.BoolVarP(&c.IncludeSensitive, "include-sensitive", "", c.IncludeSensitive,
+		"Include files that commonly contain secrets (.env, .env.*, *.pem, *.key, id_rsa*, credentials*.json, .netrc, kubeconfig)")
+	flags.BoolVarP(&c.IncludeVCSDirs, "include-vcs-dirs", "", c.IncludeVCSDirs,
+		"Walk into .git, .hg and .svn directories, which are skipped even with --include-hidden")
+	flags.BoolVarP(&c.IncludeGenerated, "include-generated", "", c.IncludeGenerated,
+		"Include files marked linguist-generated in .gitattributes and Go files headed \"Code generated ... DO NOT EDIT.\", which walks skip by default")
+	flags.BoolVarP(&c.IncludeJunk, "include-junk", "", c.IncludeJunk,
+		"Include OS metadata and editor leftovers (.DS_Store, ._*, Thumbs.db, desktop.ini, *.swp, *~), which are skipped even with --include-hidden")
+	flags.BoolVarP(&c.IgnoreGitignore, "ignore-gitignore", "", c.IgnoreGitignore, "Ignore .gitignore files")
+	flags.StringSliceVarP(&c.IgnorePatterns, "ignore", "", c.IgnorePatterns,
+		"Patterns to ignore (can be comma-separated or specified multiple times). "+
+			"Use '/' suffix to match directories only. Examples: "+
+			"'*.test.js', 'test/', 'path/to/ignore/, 'dir1/,dir2/'")
+	flags.BoolVarP(&c.DisableDefaultIgnores, "no-default-ignores", "", c.DisableDefaultIgnores,
+		"Walk the dependency, build and editor directories skipped by default: "+
+			strings.Join(files2prompt.DefaultIgnorePatterns, ", "))
+	flags.BoolVarP(&c.UseExportIgnore, "use-export-ignore", "", c.UseExportIgnore, "Exclude paths marked export-ignore in .gitattributes files")
+	flags.BoolVarP(&c.GitTracked, "git-tracked", "", c.GitTracked,
+		"Only walk the files git tracks (as git ls-files lists them) in the repositories holding the paths, leaving out untracked and ignored files")
+	flags.BoolVarP(&c.FollowSymlinks, "follow-symlinks", "", c.FollowSymlinks,
+		"Walk into symlinked directories, listing their files under the link's path; a link back into a directory being walked is not followed")
+	flags.IntVarP(&c.MaxDepth, "max-depth", "", c.MaxDepth,
+		"Walk at most this many levels below each directory argument, 1 being its immediate children (0 means no limit)")
+	flags.BoolVarP(&c.NoRecurse, "no-recurse", "", c.NoRecurse, "Walk only the immediate children of each directory argument, as --max-depth 1")
+	flags.StringSliceVarP(&c.IncludePatterns, "include", "", c.IncludePatterns,
+		"Only include files matching these patterns (can be comma-separated or specified multiple times). "+
+			"Patterns match the file name or the path relative to the walked directory, e.g. 'src/**/*.go'")
+	flags.BoolVarP(&c.AutoExtensions, "auto-extensions", "", c.AutoExtensions,
+		"Only include the sources of the up to 3 languages most files are in, their manifests (go.mod, package.json and the like) "+
+			"and READMEs, found by a scan of the file names; the chosen set is printed to stderr")
+	flags.StringVarP(&c.Submodules, "submodules", "", cmp.Or(c.Submodules, "include"),
+		"How git submodules are treated: 'include' their files (applying only their own ignore rules), 'skip' them, "+
+			"or emit them 'separate'ly after the superproject under a labelled section")
+	flags.Int64VarP(&c.ReadLimit, "read-limit", "", c.ReadLimit,
+		"Skip files larger than this many bytes regardless of other settings (0 means the 1 GiB default)")
+	flags.Int64VarP(&c.ZeroSizeLimit, "zero-size-limit", "", c.ZeroSizeLimit,
+		"Cut off a file that reports a size of 0 but keeps producing data, as virtual files do, after this many bytes (0 means 1 MiB)")
+	flags.BoolVarP(&c.IncludeVirtualFS, "include-virtual-fs", "", c.IncludeVirtualFS,
+		"Read paths on virtual file systems (procfs, sysfs, devfs and the like), which are skipped by default")
+	flags.StringVarP(&c.Jail, "jail", "", c.Jail,
+		"Refuse any path, symlink target or output file whose real path lies outside this directory")
+	flags.VarP(&c.MaxFileSize, "max-size", "",
+		"Skip walked and listed files larger than this size, e.g. 500k or 2m (default unlimited)")
+	flags.VarP(&c.MinFileSize, "min-size", "",
+		"Skip walked and listed files smaller than this size, e.g. 1k")
+	flags.StringVarP(&c.NewerThan, "newer-than", "", c.NewerThan,
+		"Skip walked and listed files last modified before this age, e.g. 48h or 7d, or date, e.g. 2024-06-01")
+	flags.StringVarP(&c.OlderThan, "older-than", "", c.OlderThan,
+		"Skip walked and listed files last modified after this age or date; with --newer-than, keeps the window between them")
+}
+
 // init initializes the command-line interface during package loading.
 //
 // This function performs the following setup operations:
-//   - Loads configuration from environment variables using config.GetEnvVars()
+//   - Loads configuration from config files and environment variables using config.Load()
 //   - Defines persistent flags that are available to all commands
 //   - Sets up command-specific flags for the root command
 //   - Registers subcommands (man pages and version information)
 //
 // The debug flag (-d, --debug) enables debug-level logging and is persistent,
 // meaning it's inherited by all subcommands. Other flags allow overriding
-// configuration values from environment variables or .env files.
+// configuration values from config files, environment variables or .env files.
 func init() {
-	// get configuration from environment variables
-	conf = config.GetEnvVars()
+	// get configuration from config files and environment variables; the
+	// profile and presets are applied before the flags are bound, so that they
+	// override them
+	cwd, err := os.Getwd()
+	if err == nil {
+		conf, err = config.LoadPresets(cwd, profileArg(os.Args[1:]), presetArgs(os.Args[1:]))
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
 
 	// create rootCmd-level flags
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable debug-level logging")
 
-	// override .env configurations with flags+args
-	if len(conf.Extensions) == 0 {
-		rootCmd.Flags().StringSliceVarP(&conf.Extensions, "extension", "e", []string{}, "File extensions to include")
-	}
-	if !conf.IncludeHidden {
-		rootCmd.Flags().BoolVarP(&conf.IncludeHidden, "include-hidden", "", false, "Include hidden files and folders")
-	}
-	if !conf.IgnoreGitignore {
-		rootCmd.Flags().BoolVarP(&conf.IgnoreGitignore, "ignore-gitignore", "", false, "Ignore .gitignore files")
-	}
-	if len(conf.IgnorePatterns) == 0 {
-		rootCmd.Flags().StringSliceVarP(&conf.IgnorePatterns, "ignore", "", []string{},
-			"Patterns to ignore (can be comma-separated or specified multiple times). "+
-				"Use '/' suffix to match directories only. Examples: "+
-				"'*.test.js', 'test/', 'path/to/ignore/, 'dir1/,dir2/'")
-	}
-	if conf.OutputFile == "" {
-		rootCmd.Flags().StringVarP(&conf.OutputFile, "output", "o", "", "Output file path")
-	}
-	if !conf.ClaudeXML {
-		rootCmd.Flags().BoolVarP(&conf.ClaudeXML, "cxml", "c", false, "Output in XML format for Claude")
-	}
-	if !conf.LineNumbers {
-		rootCmd.Flags().BoolVarP(&conf.LineNumbers, "line-numbers", "n", false, "Display line numbers in output")
-	}
-	if !conf.Markdown {
-		rootCmd.Flags().BoolVarP(&conf.Markdown, "markdown", "m", false, "Output in Markdown format with fenced code blocks")
-	}
-	if !conf.Null {
-		rootCmd.Flags().BoolVarP(&conf.Null, "null", "0", false, "Use NUL character as separator when reading from stdin")
-	}
+	// override configuration from config files and .env with flags+args
+	addRootFlags(rootCmd.Flags(), &conf)
 
 	// add sub-commands
 	rootCmd.AddCommand(
 		man.NewManCmd(),
 		version.Command(),
+		newHistoryCmd(),
+		newDoctorCmd(),
+		newStatsCmd(),
+		newMatchCmd(),
+		newTemplatesCmd(),
+		newPresetsCmd(),
 	)
 }
+
+// addRootFlags binds the flags of the root command to flags, each setting a
+// field of c and defaulting to its value there. It is called for the loaded
+// configuration, and for the built-in defaults by the history, which records
+// the flags that differ from those.
+//
+// Parameters:
+//   - flags: The flag set to bind them to
+//   - c: The configuration the flags set
+func addRootFlags(flags *pflag.FlagSet, c *config.Config) {
+	flags.StringVarP(&c.Profile, "profile", "", c.Profile,
+		"Apply the settings of this profile from the config files (overrides PROFILE)")
+	flags.StringSliceVarP(&c.Preset, "preset", "", c.Preset,
+		"Apply the settings of these presets over the config files, in order, each overriding the ones before, e.g. minimal-noise,go-project (see 'files2prompt presets')")
+	addFilterFlags(flags, c)
+	flags.StringVarP(&c.Grep, "grep", "", c.Grep, "Only include files whose content matches this regular expression")
+	flags.IntVarP(&c.GrepContext, "grep-context", "", c.GrepContext,
+		"With --grep, emit only the matching lines plus N lines of context around them instead of whole files")
+	flags.IntVarP(&c.Concurrency, "concurrency", "", c.Concurrency, "Number of files read at once (0 means one per CPU)")
+	flags.Float64VarP(&c.Throttle, "throttle", "", c.Throttle,
+		"Read files no faster than this many MB/s, all concurrent reads together, to leave the disk to other work (0 means unlimited)")
+	flags.BoolVarP(&c.LowPriority, "low-priority", "", c.LowPriority,
+		"Lower the CPU and I/O priority of the run, as nice and ionice do, where the platform allows")
+	flags.StringVarP(&c.OutputFile, "output", "o", c.OutputFile, "Output file path, or an http(s) URL to upload the output to")
+	flags.StringVarP(&c.OutputMethod, "output-method", "", c.OutputMethod,
+		"HTTP method for uploading to an --output URL: PUT (default) or POST")
+	flags.StringVarP(&c.OutputAuthEnv, "output-auth-env", "", c.OutputAuthEnv,
+		"Environment variable holding the Authorization header for an --output URL; a bare token is sent as a bearer token")
+	flags.Int64VarP(&c.SplitTokens, "split-tokens", "", c.SplitTokens,
+		"Write the --output file as numbered chunks (output-001.txt, ...) of at most N tokens, never splitting a document")
+	flags.VarP(&c.SplitBytes, "split-bytes", "",
+		"Write the --output file as numbered chunks of at most this size, e.g. 200k, never splitting a document")
+	flags.StringVarP(&c.SplitIndexes, "split-indexes", "", c.SplitIndexes,
+		"How Claude XML documents are numbered across --split-tokens chunks: continue or restart")
+	flags.BoolVarP(&c.FlushEveryFile, "flush-every-file", "", c.FlushEveryFile,
+		"Flush the output after every file, for tailing it while it is generated")
+	flags.StringVarP(&c.Batch, "batch", "", c.Batch,
+		"Produce every output listed in this YAML file from a single walk")
+	flags.StringVarP(&c.MirrorTo, "mirror-to", "", c.MirrorTo,
+		"Also copy the emitted content of every included file into this directory, preserving relative paths")
+	flags.BoolVarP(&c.MirrorOnly, "mirror-only", "", c.MirrorOnly, "With --mirror-to, write only the copies and no prompt output")
+	flags.BoolVarP(&c.Force, "force", "", c.Force, "Overwrite existing files in the --mirror-to directory")
+	flags.BoolVarP(&c.Clipboard, "copy", "", c.Clipboard,
+		"Copy the output to the system clipboard instead of stdout (in addition to --output when given)")
+	flags.BoolVarP(&c.CopyChunked, "copy-chunked", "", c.CopyChunked,
+		"With --copy, copy the output in parts of at most --copy-chunk-size, pressing Enter for each next part")
+	flags.VarP(&c.CopyChunkSize, "copy-chunk-size", "",
+		"Largest part --copy-chunked copies, and the size above which --copy suggests it, e.g. 500k (default 1m)")
+	flags.VarP(&c.TerminalLimit, "terminal-limit", "",
+		"Output size above which writing to a terminal warns, e.g. 1m (default 256k)")
+	flags.VarP(&c.OutputLimit, "output-limit", "",
+		"Output size above which writing to --output warns, e.g. 10m for an API that caps request size (default no limit)")
+	flags.StringVarP(&c.Exec, "exec", "", c.Exec,
+		"Command to run after a successful run; {} is replaced with the shell-quoted output file path "+
+			"(a temporary copy when writing to stdout)")
+	flags.StringArrayVarP(&c.Pipes, "pipe", "", c.Pipes,
+		"Stream the output through a shell command before writing it (can be specified multiple times to chain commands)")
+	flags.DurationVarP(&c.PipeTimeout, "pipe-timeout", "", c.PipeTimeout, "Time limit for the --pipe chain (0 means 5m)")
+	flags.StringVarP(&c.StdinName, "stdin-name", "", c.StdinName,
+		"Source name of the document read from stdin for a - path, e.g. main.go (default \"stdin\")")
+	flags.StringArrayVarP(&c.Commands, "cmd", "", c.Commands,
+		"Run a shell command and include its combined output as a document (can be specified multiple times)")
+	flags.StringArrayVarP(&c.CmdLabels, "cmd-label", "", c.CmdLabels,
+		"Display path for the --cmd document in the same position (defaults to the command itself)")
+	flags.DurationVarP(&c.CmdTimeout, "cmd-timeout", "", c.CmdTimeout, "Time limit for each --cmd command (0 means 30s)")
+	flags.Int64VarP(&c.CmdMaxBytes, "cmd-max-bytes", "", c.CmdMaxBytes,
+		"Bytes of output kept from each --cmd command before truncating (0 means 1 MiB)")
+	flags.BoolVarP(&c.CmdStrict, "cmd-strict", "", c.CmdStrict,
+		"Abort the run when a --cmd command fails or times out instead of recording the failure in its document")
+	flags.BoolVarP(&c.EnvContext, "env-context", "", c.EnvContext,
+		"Start the output with a document describing the OS, architecture and Go version; environment variables are never included")
+	flags.StringArrayVarP(&c.EnvContextCmds, "env-context-cmd", "", c.EnvContextCmds,
+		"Add the output of a command such as 'node --version' to the --env-context document, which it implies (can be specified multiple times)")
+	flags.BoolVarP(&c.ClaudeXML, "cxml", "c", c.ClaudeXML, "Output in XML format for Claude")
+	flags.Int64VarP(&c.CXMLMaxDocBytes, "cxml-max-doc-bytes", "", c.CXMLMaxDocBytes,
+		"In Claude XML mode, split any document whose content exceeds this many bytes into sequential parts")
+	flags.StringVarP(&c.CXMLSchema, "cxml-schema", "", c.CXMLSchema,
+		"Element and attribute naming of Claude XML output: anthropic, generic-files (<files><file path=\"...\">) or custom")
+	flags.StringVarP(&c.CXMLRoot, "cxml-root", "", c.CXMLRoot, "With --cxml-schema custom, the element wrapping the documents")
+	flags.StringVarP(&c.CXMLItem, "cxml-item", "", c.CXMLItem, "With --cxml-schema custom, the element of each document")
+	flags.StringVarP(&c.CXMLIndexAttr, "cxml-index-attr", "", c.CXMLIndexAttr,
+		"With --cxml-schema custom, the attribute numbering the documents ('' for none)")
+	flags.StringVarP(&c.CXMLPathAttr, "cxml-path-attr", "", c.CXMLPathAttr,
+		"With --cxml-schema custom, the attribute holding the path ('' for a <source> element)")
+	flags.StringVarP(&c.CXMLContentElement, "cxml-content-element", "", c.CXMLContentElement,
+		"With --cxml-schema custom, the element holding the content ('' for the document's own text)")
+	flags.BoolVarP(&c.CXMLCData, "cxml-cdata", "", c.CXMLCData,
+		"In Claude XML mode, wrap contents in CDATA sections instead of escaping <, > and &")
+	flags.BoolVarP(&c.LineNumbers, "line-numbers", "n", c.LineNumbers, "Display line numbers in output")
+	flags.BoolVarP(&c.LineNumbersCompact, "line-numbers-compact", "", c.LineNumbersCompact,
+		"Display line numbers with minimal \"1:\" gutters, which cost fewer tokens")
+	flags.BoolVarP(&c.HeaderStats, "header-stats", "", c.HeaderStats,
+		"Append the number of lines and bytes each document shows to its path line, or as attributes in Claude XML")
+	flags.BoolVarP(&c.IncludeMetadata, "metadata", "", c.IncludeMetadata,
+		"Add each file's size, line count, modification time and mode under its path line, or as attributes in Claude XML")
+	flags.IntVarP(&c.SquashDataBlocks, "squash-data-blocks", "", c.SquashDataBlocks,
+		"Replace runs of more than N consecutive data lines (digits, hex, base64) with an omission marker")
+	flags.IntVarP(&c.HeadLines, "head-lines", "", c.HeadLines,
+		"Show only the first N lines of each file, and a marker for the lines cut")
+	flags.IntVarP(&c.TailLines, "tail-lines", "", c.TailLines,
+		"Show only the last N lines of each file (after any --head-lines), and a marker for the lines cut")
+	flags.BoolVarP(&c.HunksOnly, "hunks-only", "", c.HunksOnly,
+		"Show only the lines git diff reports changed against HEAD, or the --git-diff ref, numbered, and skip unchanged files")
+	flags.StringVarP(&c.GitDiff, "git-diff", "", c.GitDiff,
+		"Emit only the files changed against this ref (a branch, tag or commit), beneath the paths or the working directory")
+	flags.BoolVarP(&c.GitStaged, "git-staged", "", c.GitStaged,
+		"Emit only the files staged in the index, beneath the paths or the working directory")
+	flags.IntVarP(&c.HunkContext, "context", "", c.HunkContext, "Lines of context around each --hunks-only hunk")
+	flags.BoolVarP(&c.CollapseSiblings, "collapse-generated-siblings", "", c.CollapseSiblings,
+		"Emit one of the generated files sharing a directory and a stem (foo.pb.go, foo.pb.ts, foo_pb2.py), and stubs for the others")
+	flags.BoolVarP(&c.ExpandSymlinkDuplicates, "expand-symlink-duplicates", "", c.ExpandSymlinkDuplicates,
+		"Emit files reached again through a symlink in full, rather than as stubs pointing to the first document")
+	flags.StringSliceVarP(&c.SiblingPriority, "sibling-priority", "", c.SiblingPriority,
+		"Extensions in the order --collapse-generated-siblings prefers the file to keep (default go,ts,js,py)")
+	flags.BoolVarP(&c.Markdown, "markdown", "m", c.Markdown, "Output in Markdown format with fenced code blocks")
+	flags.StringVarP(&c.MarkdownStyle, "markdown-style", "", c.MarkdownStyle,
+		"How Markdown output labels each file: headings (\"## path\", under a \"# Files\" title) or path (the bare path line)")
+	flags.IntVarP(&c.MarkdownHeadingLevel, "markdown-heading-level", "", c.MarkdownHeadingLevel,
+		"Level of the heading of each Markdown file, 1 to 6, for embedding the output in a larger document")
+	flags.BoolVarP(&c.HTML, "html", "", c.HTML,
+		"Output a self-contained HTML page, with a table of contents, for people to review what a prompt holds")
+	flags.BoolVarP(&c.OpenAI, "openai", "", c.OpenAI,
+		"Output a JSON array of chat messages for the messages field of OpenAI-compatible APIs: a system message, then a user message per file")
+	flags.BoolVarP(&c.OpenAISingleMessage, "openai-single-message", "", c.OpenAISingleMessage,
+		"With --openai, put every file in one user message rather than one each")
+	flags.StringVarP(&c.OpenAISystemTemplate, "openai-system-template", "", c.OpenAISystemTemplate,
+		"With --openai, Go text/template file for the system message, executed with .FileCount and .TotalBytes")
+	flags.BoolVarP(&c.JSONL, "jsonl", "", c.JSONL,
+		`Output one JSON object per document, each on its own line: {"path":...,"content":...,"index":1}`)
+	flags.StringVarP(&c.TemplatePath, "template", "", c.TemplatePath,
+		"Render each document with this Go text/template file, executed with .Path, .Content, .Index, .Ext, .Lang and .Lines "+
+			"(see the examples above, and 'files2prompt templates helpers')")
+	flags.StringVarP(&c.HeaderTemplate, "header-template", "", c.HeaderTemplate,
+		"With --template, Go text/template file rendered once before the documents, executed with .FileCount and .TotalBytes")
+	flags.StringVarP(&c.FooterTemplate, "footer-template", "", c.FooterTemplate,
+		"With --template, Go text/template file rendered once after the documents, executed with .FileCount and .TotalBytes")
+	flags.StringVarP(&c.Format, "format", "", c.Format,
+		"Describe each file on a line of its own rather than show it: 'digest' writes its path, size, SHA-256 hash, language "+
+			"and the start of its content, tab-separated and escaped; 'digest-json' writes the same as a JSON object")
+	flags.IntVarP(&c.DigestPreview, "digest-preview", "", c.DigestPreview,
+		"Characters of content each line of --format digest or digest-json previews (0 means 200)")
+	flags.StringVarP(&c.Prefix, "prefix", "", c.Prefix,
+		"Text written before the first document, such as the instructions for what follows, with a blank line after it")
+	flags.StringVarP(&c.PrefixFile, "prefix-file", "", c.PrefixFile,
+		"File whose text is written before the first document, as --prefix writes its own")
+	flags.StringVarP(&c.Suffix, "suffix", "", c.Suffix,
+		"Text written after the last document, with a blank line before it")
+	flags.StringVarP(&c.SuffixFile, "suffix-file", "", c.SuffixFile,
+		"File whose text is written after the last document, as --suffix writes its own")
+	flags.BoolVarP(&c.PrefixInsideWrapper, "prefix-inside-wrapper", "", c.PrefixInsideWrapper,
+		"With --cxml, write the prefix and suffix within the <documents> element rather than around it")
+	flags.BoolVarP(&c.DetectLang, "detect-lang", "", c.DetectLang,
+		"Guess the fence language of files with unknown extensions, such as Dockerfile.prod or .bashrc, from their name and content")
+	flags.StringToStringVarP(&c.LanguageOverrides, "lang", "", c.LanguageOverrides,
+		"Fence language for an extension or file name, as ext=language (can be comma-separated or specified multiple times), e.g. 'tf=hcl,Justfile=make'")
+	flags.StringVarP(&c.Compat, "compat", "", c.Compat,
+		"Reproduce the output and default filters of another tool: files-to-prompt")
+	flags.BoolVarP(&c.Null, "null", "0", c.Null, "Use NUL character as separator when reading from stdin, and when writing --list output")
+	flags.BoolVarP(&c.CountTokens, "tokens", "t", c.CountTokens,
+		"Print the number of files, bytes and estimated tokens written to stderr (per file with --debug)")
+	flags.BoolVarP(&c.Stats, "stats", "", c.Stats,
+		"Print the bytes, lines and estimated tokens of each emitted file, with totals and skip counts, to stderr")
+	flags.StringVarP(&c.StatsFormat, "stats-format", "", cmp.Or(c.StatsFormat, "table"), "Format of the --stats report: table or json")
+	flags.StringVarP(&c.BudgetScope, "budget-scope", "", cmp.Or(c.BudgetScope, "rendered"),
+		"What byte and token figures count: 'rendered' output including headers, fences and gutters, or only file 'content'")
+	flags.Int64VarP(&c.FitTokens, "fit-tokens", "", c.FitTokens,
+		"Emit files in order only while their estimated tokens fit in this budget, skipping the rest")
+	flags.BoolVarP(&c.SmallFirst, "small-first", "", c.SmallFirst,
+		"Emit files by estimated tokens, smallest first, so that --fit-tokens includes as many as possible")
+	flags.Int64VarP(&c.MaxTokens, "max-tokens", "", c.MaxTokens,
+		"Stop adding files once the output would exceed this many estimated tokens, listing those omitted on stderr")
+	flags.BoolVarP(&c.Strict, "strict", "", c.Strict, "Fail instead of omitting files when the output would exceed --max-tokens")
+	flags.BoolVarP(&c.Tree, "tree", "", c.Tree, "Write a directory tree of the emitted files before their contents")
+	flags.BoolVarP(&c.ListOnly, "list", "", c.ListOnly,
+		"Print only the paths of the files that would be included, one per line (NUL-separated with --null)")
+	flags.BoolVarP(&c.EmbedWarnings, "embed-warnings", "", c.EmbedWarnings,
+		"End the output with a section listing notable omissions, such as files skipped for size or unreadable")
+	flags.StringVarP(&c.Sort, "sort", "", cmp.Or(c.Sort, "path"), "Order of the emitted files: path, size, mtime, or none for the order they were found")
+	flags.BoolVarP(&c.FailOnEmpty, "fail-on-empty", "", c.FailOnEmpty, "Fail when a path argument produces no documents")
+	flags.BoolVarP(&c.AllowEmptyGlob, "allow-empty-glob", "", c.AllowEmptyGlob, "Skip glob path arguments that match no files instead of failing")
+	flags.Int64VarP(&c.LongRunFiles, "long-run-files", "", c.LongRunFiles,
+		"Show the long-run notice after scanning this many files (0 means 100000)")
+	flags.Int64VarP(&c.LongRunBytes, "long-run-bytes", "", c.LongRunBytes,
+		"Show the long-run notice after emitting this many bytes (0 means 50 MiB)")
+	flags.DurationVarP(&c.LongRunAfter, "long-run-after", "", c.LongRunAfter,
+		"Show the long-run notice after running this long (0 means 1m)")
+	flags.BoolVarP(&c.Progress, "progress", "", c.Progress,
+		"Report on stderr the files walked and emitted so far, as \"42% (1,234/2,950 files, ...)\" against an estimate from a quick pre-count")
+	flags.BoolVarP(&c.NoPrecount, "no-precount", "", c.NoPrecount,
+		"Skip the --progress pre-count, leaving the walk's progress without a total")
+	flags.BoolVarP(&c.Yes, "yes", "y", c.Yes,
+		"Crawl a home directory, filesystem root or huge directory outside version control without asking")
+	flags.IntVarP(&c.GuardEntries, "guard-entries", "", c.GuardEntries,
+		"Ask before crawling a directory outside version control with more immediate entries than this (0 means 1000)")
+	flags.BoolVarP(&c.Reproducible, "reproducible", "", c.Reproducible,
+		"Produce byte-identical output for the same tree: embedded timestamps use SOURCE_DATE_EPOCH or the latest git commit time")
+	flags.BoolVarP(&c.StableView, "stable-view", "", c.StableView,
+		"Hash the selected files before emitting any, and mark the documents of files modified during the run")
+	flags.BoolVarP(&c.Anonymize, "anonymize", "", c.Anonymize,
+		"Replace directory and file names, in headers and in file contents, with stable pseudonyms such as dir_a/file_01.go")
+	flags.StringVarP(&c.AnonymizeSeed, "anonymize-seed", "", c.AnonymizeSeed,
+		"Seed deciding which name gets which --anonymize pseudonym, so that runs can be compared (random by default)")
+	flags.StringVarP(&c.AnonymizeMap, "anonymize-map", "", c.AnonymizeMap,
+		"Write the --anonymize mapping from pseudonyms back to the original names to this JSON file")
+	flags.BoolVarP(&c.Redact, "redact", "", c.Redact,
+		"Replace credentials in file contents (AWS access keys, GitHub and Slack tokens, private key blocks, api_key = \"...\" assignments) with [REDACTED:<kind>]")
+	flags.StringArrayVarP(&c.RedactPatterns, "redact-pattern", "", c.RedactPatterns,
+		"With --redact, also redact what this regexp matches, or its first capture group when it has one (can be specified multiple times)")
+	flags.BoolVarP(&c.NoHistory, "no-history", "", c.NoHistory, "Do not record this run in the local history file")
+
+	// Their values can hold commands or credentials, so the history never records them
+	for _, name := range []string{"exec", "pipe", "cmd", "env-context-cmd", "redact-pattern"} {
+		_ = flags.SetAnnotation(name, history.SensitiveAnnotation, []string{"true"})
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/toozej/files2prompt/internal/files2prompt"
+)
+
+// newStatsCmd creates the "stats" command, which summarizes the files a run
+// over the given paths would select as size, age and extension histograms.
+//
+// Returns:
+//   - *cobra.Command: A configured stats command
+func newStatsCmd() *cobra.Command {
+	var asJSON, noUnicode bool
+
+	statsCmd := &cobra.Command{
+		Use:   "stats [paths...]",
+		Short: "Show size, age and extension histograms for a tree",
+		Long: `Walk the given paths (default ".") once and chart the files that would be
+selected: their size distribution, how recently they were modified, and the
+ten most common extensions.
+
+Filters set through environment variables are honored.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			statsConf := conf
+			statsConf.Paths = args
+			if len(args) == 0 {
+				statsConf.Paths = []string{"."}
+			}
+			stats, err := files2prompt.CollectStats(cmd.Context(), statsConf, time.Now())
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return files2prompt.WriteStatsJSON(cmd.OutOrStdout(), stats)
+			}
+			return files2prompt.WriteStatsReport(cmd.OutOrStdout(), stats, !noUnicode)
+		},
+	}
+	statsCmd.Flags().BoolVarP(&asJSON, "json", "", false, "Print the raw bucket counts as JSON")
+	statsCmd.Flags().BoolVarP(&noUnicode, "no-unicode", "", false, "Draw charts with '#' instead of Unicode block characters")
+	return statsCmd
+}
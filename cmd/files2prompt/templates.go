@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/toozej/files2prompt/internal/files2prompt"
+)
+
+// newTemplatesCmd creates the "templates" command and its "helpers" and
+// "validate" subcommands.
+//
+// Returns:
+//   - *cobra.Command: A configured templates command
+func newTemplatesCmd() *cobra.Command {
+	templatesCmd := &cobra.Command{
+		Use:   "templates",
+		Short: "Document and check output templates",
+		Long: `Document the functions Go text/template output templates can call, and check
+templates before using them.
+
+File templates are executed once per document with .Path, .Content, .Index,
+.Ext, .Lang and .Lines; header and footer templates once per run with
+.FileCount and .TotalBytes.`,
+	}
+
+	helpersCmd := &cobra.Command{
+		Use:   "helpers",
+		Short: "List the functions templates can call",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return files2prompt.WriteTemplateHelpers(cmd.OutOrStdout(), conf)
+		},
+	}
+
+	var run bool
+	validateCmd := &cobra.Command{
+		Use:   "validate [flags] <template>...",
+		Short: "Check templates by executing them on a sample document",
+		Long: `Parse each template and execute it on a synthetic sample document, reporting
+mistakes such as a misspelled field or a bad action by file name and line. Each
+valid template is reported as "ok TEMPLATE".`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			invalid := 0
+			for _, path := range args {
+				if err := files2prompt.ValidateTemplate(path, run, conf); err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), err)
+					invalid++
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "ok %s\n", path)
+			}
+			if invalid > 0 {
+				return fmt.Errorf("%d of %d templates are invalid", invalid, len(args))
+			}
+			return nil
+		},
+	}
+	validateCmd.Flags().BoolVarP(&run, "run", "", run, "Check header or footer templates, executed once per run, instead of file templates")
+
+	templatesCmd.AddCommand(helpersCmd, validateCmd)
+	return templatesCmd
+}
@@ -0,0 +1,276 @@
+package files2prompt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// anonymousModuleHost is the host of the module paths go.mod files are
+// anonymized to.
+const anonymousModuleHost = "example.com"
+
+// pathToken matches the runs of text that may be paths: names made of letters,
+// digits, "_", "." and "-", joined by slashes.
+var pathToken = regexp.MustCompile(`[\w.-]+(?:/[\w.-]+)*`)
+
+// goModuleLine matches the module directive of a go.mod file.
+var goModuleLine = regexp.MustCompile(`(?m)^module\s+"?([^\s"]+)"?`)
+
+// anonymizer replaces the directory and file names of a run with stable
+// pseudonyms, with --anonymize. Directories become dir_a, dir_b and so on and
+// files file_01, file_02 and so on, keeping their extension; the same name
+// always gets the same pseudonym, wherever it appears. Which name gets which
+// pseudonym depends only on the seed and the set of names. A nil *anonymizer
+// changes nothing.
+type anonymizer struct {
+	seed string
+	// dirs and files map each original directory name and file name, without
+	// its extension, to its pseudonym; modules maps go.mod module paths
+	dirs, files, modules map[string]string
+	// fileNames holds the full names of the files, extension included
+	fileNames map[string]bool
+	// paths maps each anonymized display path to the original
+	paths map[string]string
+}
+
+// newAnonymizer returns the anonymizer of the included files of plan, or nil
+// without --anonymize. The module paths of the go.mod files among them are
+// anonymized too. With --reproducible the seed must be given, since a random
+// one would give every run different pseudonyms; one derived from the commit
+// or $SOURCE_DATE_EPOCH would let anyone who knows them undo the mapping.
+func newAnonymizer(plan []PlannedFile, config config.Config) (*anonymizer, error) {
+	if !config.Anonymize {
+		if config.AnonymizeMap != "" {
+			return nil, errors.New("--anonymize-map requires --anonymize")
+		}
+		return nil, nil
+	}
+	if config.ListOnly {
+		return nil, errors.New("--anonymize cannot be combined with --list")
+	}
+	seed := config.AnonymizeSeed
+	if seed == "" && config.Reproducible {
+		return nil, errors.New("--anonymize with --reproducible needs --anonymize-seed, as the pseudonyms are otherwise picked at random")
+	}
+	if seed == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to seed --anonymize: %v", err)
+		}
+		seed = hex.EncodeToString(b)
+	}
+
+	a := &anonymizer{seed: seed, fileNames: map[string]bool{}, paths: map[string]string{}}
+	var dirs, files, modules []string
+	for _, f := range plan {
+		// Skipped files are named in warnings, so they are anonymized too
+		parts := pathParts(f.Path)
+		for i, part := range parts {
+			if i == len(parts)-1 && !f.IsDir {
+				files = append(files, fileStem(part))
+				a.fileNames[part] = true
+			} else {
+				dirs = append(dirs, part)
+			}
+		}
+		if f.Included && filepath.Base(f.Path) == "go.mod" && f.SiblingOf == "" {
+			content, err := readFileLimited(f.Path, readLimit(config), zeroSizeLimit(config))
+			if err != nil {
+				continue
+			}
+			if m := goModuleLine.FindSubmatch(content); m != nil {
+				modules = append(modules, string(m[1]))
+			}
+		}
+	}
+
+	a.dirs = a.pseudonyms(dirs, func(i, _ int) string { return "dir_" + letters(i) })
+	a.files = a.pseudonyms(files, func(i, n int) string { return fmt.Sprintf("file_%0*d", max(2, len(fmt.Sprint(n))), i+1) })
+	a.modules = a.pseudonyms(modules, func(i, _ int) string { return anonymousModuleHost + "/module_" + letters(i) })
+	for _, f := range plan {
+		if f.Included {
+			a.paths[a.path(f.DisplayPath, true)] = f.DisplayPath
+		}
+	}
+	return a, nil
+}
+
+// pseudonyms assigns each distinct name its pseudonym, name(i, n) for the ith
+// of n in an order derived from the seed, so the same seed and names always
+// give the same result while the order reveals nothing about the names.
+func (a *anonymizer) pseudonyms(names []string, name func(i, n int) string) map[string]string {
+	slices.Sort(names)
+	names = slices.Compact(names)
+	keys := map[string]string{}
+	for _, n := range names {
+		mac := hmac.New(sha256.New, []byte(a.seed))
+		mac.Write([]byte(n))
+		keys[n] = hex.EncodeToString(mac.Sum(nil))
+	}
+	slices.SortFunc(names, func(x, y string) int { return strings.Compare(keys[x], keys[y]) })
+	m := make(map[string]string, len(names))
+	for i, n := range names {
+		m[n] = name(i, len(names))
+	}
+	return m
+}
+
+// letters returns the ith name of the sequence a, b, ... z, aa, ab, ...
+func letters(i int) string {
+	s := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		s = string(rune('a'+(i-1)%26)) + s
+	}
+	return s
+}
+
+// pathParts returns the names in path, leaving out the root, "." and "..".
+func pathParts(path string) []string {
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part != "" && part != "." && part != ".." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// fileStem returns name without its extension. A name that is all extension,
+// such as ".gitignore", is its own stem.
+func fileStem(name string) string {
+	if stem := strings.TrimSuffix(name, filepath.Ext(name)); stem != "" {
+		return stem
+	}
+	return name
+}
+
+// name returns the pseudonym of the directory or, when file is set, the file
+// called name, or name itself when it has none.
+func (a *anonymizer) name(name string, file bool) string {
+	if a == nil {
+		return name
+	}
+	if !file {
+		if p, ok := a.dirs[name]; ok {
+			return p
+		}
+		return name
+	}
+	stem := fileStem(name)
+	if p, ok := a.files[stem]; ok {
+		return p + strings.TrimPrefix(name, stem)
+	}
+	return name
+}
+
+// path returns path with every name in it replaced by its pseudonym, the last
+// as a file name when file is set. Separators, the root, "." and ".." are kept.
+func (a *anonymizer) path(path string, file bool) string {
+	if a == nil {
+		return path
+	}
+	parts := strings.Split(path, string(filepath.Separator))
+	last := len(parts) - 1
+	for i, part := range parts {
+		if part != "" && part != "." && part != ".." {
+			parts[i] = a.name(part, file && i == last)
+		}
+	}
+	return strings.Join(parts, string(filepath.Separator))
+}
+
+// content replaces the paths within content that point into the anonymized
+// tree: slash-separated paths starting with one of its names, ".", ".." or a
+// go.mod module path, and the full names of its files, such as an included
+// "session.h". Other words are left alone, even when they match a directory.
+func (a *anonymizer) content(content string) string {
+	if a == nil {
+		return content
+	}
+	return pathToken.ReplaceAllStringFunc(content, func(token string) string {
+		// A sentence may end right after a path
+		trimmed := strings.TrimRight(token, ".")
+		return a.token(trimmed) + token[len(trimmed):]
+	})
+}
+
+// token anonymizes a single pathToken match.
+func (a *anonymizer) token(token string) string {
+	prefix := ""
+	for module, p := range a.modules {
+		if token == module || strings.HasPrefix(token, module+"/") {
+			prefix, token = p, strings.TrimPrefix(token, module)
+			break
+		}
+	}
+	parts := strings.Split(token, "/")
+	if prefix == "" {
+		// A bare word is only replaced when it is the full name of a file with
+		// an extension, so that "main" and "config.Load" are left alone next to
+		// a main.go and a config.go
+		first := parts[0]
+		_, dir := a.dirs[first]
+		switch {
+		case len(parts) == 1 && (!a.fileNames[first] || fileStem(first) == first):
+			return token
+		case len(parts) > 1 && !dir && !a.fileNames[first] && first != "." && first != "..":
+			return token
+		}
+	}
+	for i, part := range parts {
+		if part != "" && part != "." && part != ".." {
+			parts[i] = a.name(part, i == len(parts)-1 && a.fileNames[part])
+		}
+	}
+	return prefix + strings.Join(parts, "/")
+}
+
+// anonymizeMap is the --anonymize-map file, mapping each pseudonym back to the
+// name it replaced.
+type anonymizeMap struct {
+	Seed        string            `json:"seed"`
+	Directories map[string]string `json:"directories"`
+	Files       map[string]string `json:"files"`
+	Modules     map[string]string `json:"modules,omitempty"`
+	Paths       map[string]string `json:"paths"`
+}
+
+// writeMap writes the mapping of a to path as JSON, unless path is "".
+func (a *anonymizer) writeMap(path string) error {
+	if a == nil || path == "" {
+		return nil
+	}
+	invert := func(m map[string]string) map[string]string {
+		inverted := make(map[string]string, len(m))
+		for original, pseudonym := range m {
+			inverted[pseudonym] = original
+		}
+		return inverted
+	}
+	data, err := json.MarshalIndent(anonymizeMap{
+		Seed:        a.seed,
+		Directories: invert(a.dirs),
+		Files:       invert(a.files),
+		Modules:     invert(a.modules),
+		Paths:       a.paths,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write --anonymize-map: %v", err)
+	}
+	return nil
+}
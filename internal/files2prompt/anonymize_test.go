@@ -0,0 +1,159 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// anonymizeFixture writes a small Go module with a C library and makes it the
+// working directory, returning the path arguments covering it.
+func anonymizeFixture(t *testing.T) []string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module github.com/acme/payments\n\ngo 1.22\n",
+		"cmd/server/main.go": "package main\n\nimport (\n" +
+			"\t\"github.com/acme/payments/internal/auth\"\n" +
+			"\t\"github.com/stretchr/testify/assert\"\n)\n\n" +
+			"// main serves auth.Check, see internal/auth/auth.go.\nfunc main() { auth.Check() }\n",
+		"internal/auth/auth.go":  "package auth\n\nfunc Check() {}\n",
+		"native/session.c":       "#include \"auth/session.h\"\n",
+		"native/auth/session.h":  "int open(void);\n",
+		"native/auth/session.md": "The session library.\n",
+	})
+	t.Chdir(dir)
+	return []string{"go.mod", "cmd", "internal", "native"}
+}
+
+func TestAnonymize(t *testing.T) {
+	paths := anonymizeFixture(t)
+	run := func(seed string) (string, anonymizeMap) {
+		mapFile := filepath.Join(t.TempDir(), "map.json")
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), config.Config{
+			Paths: paths, Tree: true, Anonymize: true, AnonymizeSeed: seed, AnonymizeMap: mapFile,
+		}, &buf, nil)
+		require.NoError(t, err)
+		data, err := os.ReadFile(mapFile)
+		require.NoError(t, err)
+		var m anonymizeMap
+		require.NoError(t, json.Unmarshal(data, &m))
+		return buf.String(), m
+	}
+
+	output, m := run("seed")
+	again, _ := run("seed")
+	assert.Equal(t, output, again, "the same seed gives the same output")
+	other, otherMap := run("other seed")
+	assert.NotEqual(t, m.Paths, otherMap.Paths)
+	assert.NotEqual(t, output, other)
+
+	for _, name := range []string{"acme", "payments", "server", "internal", "native", "session.", "auth/"} {
+		assert.NotContains(t, output, name)
+	}
+	assert.Contains(t, output, `"github.com/stretchr/testify/assert"`, "third-party imports are left alone")
+	assert.Contains(t, output, "auth.Check", "identifiers are left alone")
+	assert.Contains(t, output, "The session library.", "prose is left alone")
+
+	assert.Equal(t, "seed", m.Seed)
+	assert.Equal(t, map[string]string{"example.com/module_a": "github.com/acme/payments"}, m.Modules)
+	assert.Len(t, m.Paths, 6)
+	anonymized := map[string]string{}
+	for pseudonym, original := range m.Paths {
+		assert.Equal(t, path.Ext(original), path.Ext(pseudonym), "extensions are kept")
+		assert.Equal(t, strings.Count(original, "/"), strings.Count(pseudonym, "/"), "structure is kept")
+		assert.Contains(t, output, pseudonym+"\n---\n")
+		anonymized[original] = pseudonym
+	}
+	assert.Regexp(t, `^file_\d\d\.mod$`, anonymized["go.mod"])
+	assert.Contains(t, output, "module example.com/module_a\n")
+	// Imports and references match the documents they point to
+	assert.Contains(t, output, `"example.com/module_a/`+path.Dir(anonymized["internal/auth/auth.go"])+`"`)
+	assert.Contains(t, output, "see "+anonymized["internal/auth/auth.go"]+".\n")
+	session := strings.Split(anonymized["native/auth/session.h"], "/")
+	assert.Contains(t, output, `#include "`+session[1]+"/"+session[2]+`"`)
+	// Files sharing a name share its pseudonym
+	assert.Equal(t, strings.TrimSuffix(anonymized["native/auth/session.h"], ".h")+".md", anonymized["native/auth/session.md"])
+	assert.Contains(t, output, strings.Split(anonymized["native/session.c"], "/")[0]+"\n")
+}
+
+func TestAnonymizerContent(t *testing.T) {
+	a := &anonymizer{
+		dirs:      map[string]string{"auth": "dir_a", "internal": "dir_b"},
+		files:     map[string]string{"session": "file_01", "config": "file_02"},
+		fileNames: map[string]bool{"session.h": true, "config.go": true},
+		modules:   map[string]string{"github.com/acme/payments": "example.com/module_a"},
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "include", content: `#include "auth/session.h"`, want: `#include "dir_a/file_01.h"`},
+		{name: "module import", content: `import "github.com/acme/payments/internal/auth"`, want: `import "example.com/module_a/dir_b/dir_a"`},
+		{name: "module", content: "module github.com/acme/payments\n", want: "module example.com/module_a\n"},
+		{name: "third-party import", content: `import "github.com/stretchr/testify/assert"`, want: `import "github.com/stretchr/testify/assert"`},
+		{name: "relative path", content: "../internal/auth/config.go", want: "../dir_b/dir_a/file_02.go"},
+		{name: "file name ending a sentence", content: "See config.go.", want: "See file_02.go."},
+		{name: "identifier", content: "cfg := config.Load()", want: "cfg := config.Load()"},
+		{name: "word", content: "the auth service", want: "the auth service"},
+		{name: "unknown directory", content: "vendor/auth", want: "vendor/auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, a.content(tt.content))
+		})
+	}
+
+	var none *anonymizer
+	assert.Equal(t, "auth/session.h", none.content("auth/session.h"))
+	assert.Equal(t, "auth/session.h", none.path("auth/session.h", true))
+}
+
+func TestLetters(t *testing.T) {
+	for i, want := range map[int]string{0: "a", 25: "z", 26: "aa", 27: "ab", 701: "zz", 702: "aaa"} {
+		assert.Equal(t, want, letters(i))
+	}
+}
+
+func TestAnonymizeOptions(t *testing.T) {
+	paths := anonymizeFixture(t)
+	_, err := Generate(context.Background(), config.Config{Paths: paths, Anonymize: true, ListOnly: true}, &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "--anonymize cannot be combined with --list")
+	_, err = Generate(context.Background(), config.Config{Paths: paths, AnonymizeMap: "map.json"}, &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "--anonymize-map requires --anonymize")
+
+	// A reproducible run cannot pick a random seed, and is reproducible with one
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	reproducible := config.Config{Paths: paths, Tree: true, Anonymize: true, Reproducible: true}
+	_, err = Generate(context.Background(), reproducible, &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "--anonymize with --reproducible needs --anonymize-seed, as the pseudonyms are otherwise picked at random")
+	reproducible.AnonymizeSeed = "ci"
+	var first, second bytes.Buffer
+	_, err = Generate(context.Background(), reproducible, &first, nil)
+	require.NoError(t, err)
+	_, err = Generate(context.Background(), reproducible, &second, nil)
+	require.NoError(t, err)
+	assert.Contains(t, first.String(), "file_01")
+	assert.Equal(t, first.String(), second.String())
+
+	// Without a seed one is picked, and recorded in the map
+	_, err = Generate(context.Background(), config.Config{Paths: paths, Anonymize: true, AnonymizeMap: "map.json"}, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+	data, err := os.ReadFile("map.json")
+	require.NoError(t, err)
+	var m anonymizeMap
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Len(t, m.Seed, 32)
+}
@@ -0,0 +1,193 @@
+package files2prompt
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// languageRole classifies a fence language by what its files are for.
+type languageRole string
+
+const (
+	roleSource languageRole = "source"
+	roleBuild  languageRole = "build"
+	roleMarkup languageRole = "markup"
+	roleStyle  languageRole = "style"
+	roleData   languageRole = "data"
+	roleDocs   languageRole = "docs"
+)
+
+// languageRoles gives the role of every language of extToLang and nameToLang.
+// Only source languages are counted by --auto-extensions.
+var languageRoles = map[string]languageRole{
+	"python": roleSource, "c": roleSource, "cpp": roleSource, "csharp": roleSource, "java": roleSource,
+	"kotlin": roleSource, "scala": roleSource, "groovy": roleSource, "clojure": roleSource,
+	"javascript": roleSource, "jsx": roleSource, "typescript": roleSource, "tsx": roleSource,
+	"vue": roleSource, "svelte": roleSource, "bash": roleSource, "zsh": roleSource, "fish": roleSource,
+	"powershell": roleSource, "batch": roleSource, "ruby": roleSource, "go": roleSource, "rust": roleSource,
+	"swift": roleSource, "objectivec": roleSource, "dart": roleSource, "php": roleSource, "perl": roleSource,
+	"lua": roleSource, "r": roleSource, "julia": roleSource, "elixir": roleSource, "erlang": roleSource,
+	"haskell": roleSource, "ocaml": roleSource, "fsharp": roleSource, "zig": roleSource, "nim": roleSource,
+	"sql": roleSource, "terraform": roleSource, "hcl": roleSource, "nix": roleSource,
+	"dockerfile": roleBuild, "makefile": roleBuild, "cmake": roleBuild, "starlark": roleBuild,
+	"html": roleMarkup, "xml": roleMarkup,
+	"css": roleStyle, "scss": roleStyle, "sass": roleStyle, "less": roleStyle,
+	"json": roleData, "jsonc": roleData, "yaml": roleData, "toml": roleData, "ini": roleData,
+	"graphql": roleData, "protobuf": roleData,
+	"markdown": roleDocs, "rst": roleDocs, "latex": roleDocs, "diff": roleDocs,
+}
+
+// languageManifests lists the build and dependency manifests of a language,
+// which --auto-extensions includes along with its sources.
+var languageManifests = map[string][]string{
+	"go":         {"go.mod"},
+	"javascript": {"package.json"},
+	"jsx":        {"package.json"},
+	"typescript": {"package.json", "tsconfig.json"},
+	"tsx":        {"package.json", "tsconfig.json"},
+	"vue":        {"package.json"},
+	"svelte":     {"package.json"},
+	"python":     {"pyproject.toml", "setup.cfg", "requirements*.txt"},
+	"rust":       {"Cargo.toml"},
+	"ruby":       {"Gemfile", "*.gemspec"},
+	"java":       {"pom.xml", "build.gradle"},
+	"kotlin":     {"build.gradle.kts", "settings.gradle.kts"},
+	"scala":      {"build.sbt"},
+	"c":          {"CMakeLists.txt", "meson.build"},
+	"cpp":        {"CMakeLists.txt", "meson.build"},
+	"csharp":     {"*.csproj", "*.sln"},
+	"php":        {"composer.json"},
+	"dart":       {"pubspec.yaml"},
+	"haskell":    {"*.cabal", "stack.yaml"},
+}
+
+// alwaysIncluded are the files --auto-extensions includes whatever the
+// languages: what a reader of any repository looks at first.
+var alwaysIncluded = []string{"README*", "Makefile", "Dockerfile"}
+
+const (
+	// autoLanguages is the most languages --auto-extensions chooses
+	autoLanguages = 3
+	// autoMinShare is the smallest share of the source files, in percent, a
+	// language other than the first must hold to be chosen
+	autoMinShare = 10
+)
+
+// languageCount is the number of source files of a language.
+type languageCount struct {
+	lang  string
+	files int
+}
+
+// autoExtensions returns config with the include patterns --auto-extensions
+// infers, writing them to stderr. A scan of the file names config selects
+// finds the languages most of the source files are in; their extensions, by
+// extToLang, are included with their manifests and alwaysIncluded.
+func autoExtensions(ctx context.Context, config config.Config, snap *treeSnapshot) (config.Config, error) {
+	switch {
+	case len(config.Extensions) > 0:
+		return config, errors.New("--auto-extensions cannot be combined with --extension")
+	case len(config.IncludePatterns) > 0:
+		return config, errors.New("--auto-extensions cannot be combined with --include")
+	}
+	scan := config
+	scan.AutoExtensions = false
+	plan, _, err := planFiles(ctx, scan, nil, snap)
+	if err != nil {
+		return config, err
+	}
+
+	langs := dominantLanguages(plan)
+	if len(langs) == 0 {
+		fmt.Fprintln(osStderr, "--auto-extensions: no source files found, so every file is included")
+		return config, nil
+	}
+	config.IncludePatterns = autoPatterns(langs)
+	counts := make([]string, len(langs))
+	for i, l := range langs {
+		counts[i] = fmt.Sprintf("%s (%d %s)", l.lang, l.files, plural(l.files, "file", "files"))
+	}
+	fmt.Fprintf(osStderr, "--auto-extensions: %s; including %s\n", strings.Join(counts, ", "), strings.Join(config.IncludePatterns, ", "))
+	return config, nil
+}
+
+// dominantLanguages counts the included files of plan by source language and
+// returns the most common, at most autoLanguages of them, each but the first
+// holding at least autoMinShare percent of the source files.
+func dominantLanguages(plan []PlannedFile) []languageCount {
+	byLang := map[string]int{}
+	total := 0
+	for _, f := range plan {
+		if !f.Included || f.IsDir {
+			continue
+		}
+		if lang := nameLanguage(f.Path); languageRoles[lang] == roleSource {
+			byLang[lang]++
+			total++
+		}
+	}
+	var langs []languageCount
+	for lang, files := range byLang {
+		langs = append(langs, languageCount{lang, files})
+	}
+	slices.SortFunc(langs, func(a, b languageCount) int {
+		return cmp.Or(cmp.Compare(b.files, a.files), cmp.Compare(a.lang, b.lang))
+	})
+	for i, l := range langs {
+		if i == autoLanguages || (i > 0 && l.files*100 < total*autoMinShare) {
+			return langs[:i]
+		}
+	}
+	return langs
+}
+
+// nameLanguage returns the language of the file at path by its name alone, as
+// fenceLanguage finds it without overrides or a look at the content.
+func nameLanguage(path string) string {
+	name := filepath.Base(path)
+	if lang := nameToLang[name]; lang != "" {
+		return lang
+	}
+	return extToLang[strings.TrimPrefix(filepath.Ext(name), ".")]
+}
+
+// autoPatterns returns the include patterns of the languages langs: the
+// extensions extToLang maps to each and the file names nameToLang does, then
+// their manifests and alwaysIncluded, each pattern once.
+func autoPatterns(langs []languageCount) []string {
+	var patterns []string
+	add := func(p string) {
+		if !slices.Contains(patterns, p) {
+			patterns = append(patterns, p)
+		}
+	}
+	for _, l := range langs {
+		for _, ext := range slices.Sorted(maps.Keys(extToLang)) {
+			if extToLang[ext] == l.lang {
+				add("*." + ext)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(nameToLang)) {
+			if nameToLang[name] == l.lang {
+				add(name)
+			}
+		}
+	}
+	for _, l := range langs {
+		for _, m := range languageManifests[l.lang] {
+			add(m)
+		}
+	}
+	for _, p := range alwaysIncluded {
+		add(p)
+	}
+	return patterns
+}
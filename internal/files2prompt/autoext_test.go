@@ -0,0 +1,109 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestAutoExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		included []string
+		stderr   string
+	}{
+		{
+			name: "go",
+			files: map[string]string{
+				"main.go": "", "cmd/root.go": "", "cmd/root_test.go": "", "go.mod": "", "go.sum": "",
+				"README.md": "", "docs/guide.md": "", "config.yaml": "", "Makefile": "", "LICENSE": "",
+			},
+			included: []string{"Makefile", "README.md", "cmd/root.go", "cmd/root_test.go", "go.mod", "main.go"},
+			stderr:   "--auto-extensions: go (3 files); including *.go, go.mod, README*, Makefile, Dockerfile\n",
+		},
+		{
+			name: "javascript",
+			files: map[string]string{
+				"index.js": "", "lib/util.mjs": "", "lib/cli.cjs": "", "package.json": "", "package-lock.json": "",
+				"README": "", "public/index.html": "", "public/style.css": "", ".eslintrc.json": "",
+			},
+			included: []string{"README", "index.js", "lib/cli.cjs", "lib/util.mjs", "package.json"},
+			stderr:   "--auto-extensions: javascript (3 files); including *.cjs, *.js, *.mjs, package.json, README*, Makefile, Dockerfile\n",
+		},
+		{
+			name: "mixed",
+			files: map[string]string{
+				// Python holds most sources, TypeScript enough to count, shell scripts too few
+				"app/a.py": "", "app/b.py": "", "app/c.py": "", "app/d.py": "", "app/e.py": "",
+				"app/f.py": "", "app/g.py": "", "app/h.py": "", "app/i.py": "", "app/j.py": "",
+				"app/k.py": "", "app/l.py": "", "app/m.py": "", "app/n.py": "", "app/o.py": "",
+				"web/main.ts": "", "web/view.ts": "", "web/tsconfig.json": "", "web/package.json": "",
+				"scripts/run.sh": "", "pyproject.toml": "", "Dockerfile": "", "README.rst": "",
+			},
+			included: []string{
+				"Dockerfile", "README.rst",
+				"app/a.py", "app/b.py", "app/c.py", "app/d.py", "app/e.py", "app/f.py", "app/g.py", "app/h.py",
+				"app/i.py", "app/j.py", "app/k.py", "app/l.py", "app/m.py", "app/n.py", "app/o.py",
+				"pyproject.toml", "web/main.ts", "web/package.json", "web/tsconfig.json", "web/view.ts",
+			},
+			stderr: "--auto-extensions: python (15 files), typescript (2 files); including *.py, *.pyi, *.cts, *.mts, *.ts, " +
+				"pyproject.toml, setup.cfg, requirements*.txt, package.json, tsconfig.json, README*, Makefile, Dockerfile\n",
+		},
+		{
+			name:     "no sources",
+			files:    map[string]string{"README.md": "", "data.json": ""},
+			included: []string{"README.md", "data.json"},
+			stderr:   "--auto-extensions: no source files found, so every file is included\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			t.Chdir(dir)
+			var stderr bytes.Buffer
+			originalStderr := osStderr
+			osStderr = &stderr
+			defer func() { osStderr = originalStderr }()
+
+			plan, err := Plan(context.Background(), config.Config{Paths: []string{"."}, AutoExtensions: true}, false)
+			require.NoError(t, err)
+			var included []string
+			for _, f := range plan {
+				if !f.IsDir {
+					rel, err := filepath.Rel(dir, f.DisplayPath)
+					require.NoError(t, err)
+					included = append(included, filepath.ToSlash(rel))
+				}
+			}
+			assert.Equal(t, tt.included, included)
+			assert.Equal(t, tt.stderr, stderr.String())
+		})
+	}
+}
+
+func TestAutoExtensionsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"with extension", config.Config{AutoExtensions: true, Extensions: []string{".go"}}, "--auto-extensions cannot be combined with --extension"},
+		{"with include", config.Config{AutoExtensions: true, IncludePatterns: []string{"*.go"}}, "--auto-extensions cannot be combined with --include"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"testdata"}
+			_, err := Plan(context.Background(), cfg, false)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package files2prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// BatchJob is one output of a --batch run. Fields left out of the batch file
+// keep the value given on the command line or in the environment.
+type BatchJob struct {
+	// Name labels the job in reports; it defaults to Output.
+	Name string `yaml:"name"`
+	// Output is the file the job writes. It is required.
+	Output string `yaml:"output"`
+	// Paths replaces the path arguments.
+	Paths []string `yaml:"paths"`
+	// Format is "plain", "markdown", "cxml", "html", "jsonl" or "openai".
+	Format          string           `yaml:"format"`
+	Extensions      []string         `yaml:"extensions"`
+	Ignore          []string         `yaml:"ignore"`
+	Include         []string         `yaml:"include"`
+	IncludeHidden   *bool            `yaml:"include-hidden"`
+	IgnoreGitignore *bool            `yaml:"ignore-gitignore"`
+	Grep            *string          `yaml:"grep"`
+	MaxSize         *config.ByteSize `yaml:"max-size"`
+	MinSize         *config.ByteSize `yaml:"min-size"`
+	LineNumbers     *bool            `yaml:"line-numbers"`
+	Tree            *bool            `yaml:"tree"`
+	Sort            *string          `yaml:"sort"`
+}
+
+// BatchResult is the outcome of one job of a --batch run.
+type BatchResult struct {
+	Job     string
+	Output  string
+	Summary Summary
+	// Err is set when the job failed; the other jobs run regardless.
+	Err error
+}
+
+// LoadBatch reads the jobs of the batch file at path. Unknown keys are
+// rejected, so that a misspelt filter does not silently widen a job.
+func LoadBatch(path string) ([]BatchJob, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("invalid --batch file: %v", err)
+	}
+	defer f.Close()
+
+	var file struct {
+		Jobs []BatchJob `yaml:"jobs"`
+	}
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid --batch file %s: %v", path, err)
+	}
+	if len(file.Jobs) == 0 {
+		return nil, fmt.Errorf("invalid --batch file %s: no jobs", path)
+	}
+	names := map[string]bool{}
+	for i := range file.Jobs {
+		job := &file.Jobs[i]
+		if job.Output == "" {
+			return nil, fmt.Errorf("invalid --batch file %s: job %d has no output", path, i+1)
+		}
+		if job.Name == "" {
+			job.Name = job.Output
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("invalid --batch file %s: duplicate job %q", path, job.Name)
+		}
+		names[job.Name] = true
+	}
+	return file.Jobs, nil
+}
+
+// apply returns base with the fields set in j.
+func (j BatchJob) apply(base config.Config) (config.Config, error) {
+	c := base
+	c.OutputFile = j.Output
+	if j.Paths != nil {
+		c.Paths = j.Paths
+	}
+	if j.Format != "" {
+		c.Markdown, c.ClaudeXML, c.HTML, c.JSONL, c.OpenAI = false, false, false, false, false
+		c.TemplatePath, c.HeaderTemplate, c.FooterTemplate, c.Format = "", "", "", ""
+	}
+	switch j.Format {
+	case "", "plain":
+	case "markdown":
+		c.Markdown = true
+	case "cxml":
+		c.ClaudeXML = true
+	case "html":
+		c.HTML = true
+	case "jsonl":
+		c.JSONL = true
+	case "openai":
+		c.OpenAI = true
+	case string(FormatDigest), string(FormatDigestJSON):
+		c.Format = j.Format
+	default:
+		return c, fmt.Errorf("invalid format %q: use plain, markdown, cxml, html, jsonl, openai, digest or digest-json", j.Format)
+	}
+	if j.Extensions != nil {
+		c.Extensions = j.Extensions
+	}
+	if j.Ignore != nil {
+		c.IgnorePatterns = j.Ignore
+	}
+	if j.Include != nil {
+		c.IncludePatterns = j.Include
+	}
+	setIf(&c.IncludeHidden, j.IncludeHidden)
+	setIf(&c.IgnoreGitignore, j.IgnoreGitignore)
+	setIf(&c.Grep, j.Grep)
+	setIf(&c.MaxFileSize, j.MaxSize)
+	setIf(&c.MinFileSize, j.MinSize)
+	setIf(&c.LineNumbers, j.LineNumbers)
+	setIf(&c.Tree, j.Tree)
+	setIf(&c.Sort, j.Sort)
+	return c, nil
+}
+
+func setIf[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// RunBatch runs every job against base, sharing a single walk of the paths
+// they name: the tree is walked once, and each job's filters and renderer are
+// then applied to the recorded entries. A job that fails is reported in its
+// result without stopping the others.
+func RunBatch(ctx context.Context, base config.Config, jobs []BatchJob) ([]BatchResult, error) {
+	if base.Clipboard {
+		return nil, errors.New("--batch cannot be combined with --copy")
+	}
+	if base.MirrorTo != "" {
+		// Every job would copy its files into the same directory
+		return nil, errors.New("--batch cannot be combined with --mirror-to")
+	}
+	results := make([]BatchResult, len(jobs))
+	configs := make([]config.Config, len(jobs))
+	var roots []string
+	keepVCS := false
+	for i, job := range jobs {
+		results[i] = BatchResult{Job: job.Name, Output: job.Output}
+		if configs[i], results[i].Err = job.apply(base); results[i].Err != nil {
+			continue
+		}
+		if len(configs[i].Paths) == 0 && len(configs[i].StdinPaths) == 0 {
+			results[i].Err = errors.New("no paths given by the job, the arguments or stdin")
+			continue
+		}
+		args, err := expandArgs(configs[i])
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		for _, arg := range args {
+			roots = append(roots, arg.path)
+		}
+		keepVCS = keepVCS || configs[i].IncludeVCSDirs
+	}
+
+	jail, err := newJail(base.Jail)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := snapshotTree(ctx, roots, jail, keepVCS)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range jobs {
+		if results[i].Err != nil {
+			continue
+		}
+		plan, _, err := planFiles(ctx, configs[i], nil, snap)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if plan == nil {
+			// An empty plan, rather than none, so that Generate does not walk again
+			plan = []PlannedFile{}
+		}
+		results[i].Summary, results[i].Err = runPlanned(configs[i], plan)
+	}
+	return results, nil
+}
+
+// snapshotEntry is a single call a filepath.Walk made to its WalkFunc.
+type snapshotEntry struct {
+	path string
+	info os.FileInfo
+	err  error
+}
+
+// treeSnapshot is a recorded walk of a set of directories, which several plans
+// can be made from without walking again. A nil *treeSnapshot walks the
+// filesystem.
+type treeSnapshot struct {
+	// roots maps each walked directory to its entries, in walk order
+	roots map[string][]snapshotEntry
+}
+
+// snapshotTree walks each directory in paths once, skipping those nested in
+// another and those outside jail. VCS metadata directories are recorded but not
+// entered unless keepVCS is set.
+func snapshotTree(ctx context.Context, paths []string, jail *jail, keepVCS bool) (*treeSnapshot, error) {
+	snap := &treeSnapshot{roots: map[string][]snapshotEntry{}}
+	var dirs []string
+	for _, path := range paths {
+		root, err := walkRoot(path)
+		if err != nil {
+			return nil, err
+		}
+		if info, err := os.Stat(root); err != nil || !info.IsDir() || jail.check(root) != nil {
+			continue
+		}
+		dirs = append(dirs, root)
+	}
+	for _, root := range dirs {
+		if snap.lookup(root) != "" {
+			continue
+		}
+		if slices.ContainsFunc(dirs, func(dir string) bool { return dir != root && withinDir(root, dir) }) {
+			continue
+		}
+		var entries []snapshotEntry
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			entries = append(entries, snapshotEntry{path: path, info: info, err: err})
+			if err == nil && info.IsDir() && path != root && !keepVCS && slices.Contains(vcsMetadataNames, info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		snap.roots[root] = entries
+	}
+	log.Debugf("Batch snapshot holds %d walked directories", len(snap.roots))
+	return snap, nil
+}
+
+// lookup returns the recorded directory root lies in, or "".
+func (s *treeSnapshot) lookup(root string) string {
+	if s == nil {
+		return ""
+	}
+	for dir := range s.roots {
+		if dir == root || withinDir(root, dir) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// walk calls fn for root and everything beneath it as walkTree would,
+// replaying the recorded entries when root lies in a recorded directory. The
+// snapshot holds no followed links, so with follow the disk is walked.
+func (s *treeSnapshot) walk(root string, follow bool, fn filepath.WalkFunc) error {
+	dir := s.lookup(root)
+	if dir == "" || follow {
+		return walkTree(root, follow, fn)
+	}
+	clean := filepath.Clean(root)
+	// skip is the directory whose remaining entries are being skipped, or ""
+	skip := ""
+	for _, e := range s.roots[dir] {
+		path := filepath.Clean(e.path)
+		if path != clean && !strings.HasPrefix(path, clean+string(filepath.Separator)) {
+			continue
+		}
+		if skip != "" && strings.HasPrefix(path, skip+string(filepath.Separator)) {
+			continue
+		}
+		skip = ""
+		if path == clean {
+			// Keep the caller's spelling of the root, as filepath.Walk does
+			path = root
+		}
+		err := fn(path, e.info, e.err)
+		switch {
+		case err == nil:
+		case errors.Is(err, filepath.SkipAll):
+			return nil
+		case errors.Is(err, filepath.SkipDir):
+			if path == root {
+				return nil
+			}
+			skip = path
+			if e.info == nil || !e.info.IsDir() {
+				skip = filepath.Dir(path)
+			}
+		default:
+			return err
+		}
+	}
+	return nil
+}
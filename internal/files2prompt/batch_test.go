@@ -0,0 +1,164 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// writeBatch writes a batch file holding content and returns its path.
+func writeBatch(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "batch.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestRunBatchMatchesStandalone(t *testing.T) {
+	withStdout(t)
+	out := t.TempDir()
+	batch := writeBatch(t, `
+jobs:
+  - name: go
+    output: `+filepath.Join(out, "go.xml")+`
+    format: cxml
+    extensions: [.go]
+    include-hidden: true
+  - name: docs
+    output: `+filepath.Join(out, "docs.md")+`
+    paths: [testdata/test_project/docs, testdata/test_project/temp]
+    format: markdown
+    line-numbers: true
+  - output: `+filepath.Join(out, "all.txt")+`
+    ignore: ["temp/"]
+    tree: true
+`)
+	jobs, err := LoadBatch(batch)
+	require.NoError(t, err)
+	base := config.Config{Paths: []string{"testdata/test_project"}, Sort: "path"}
+	results, err := RunBatch(context.Background(), base, jobs)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, filepath.Join(out, "all.txt"), results[2].Job, "a job is named after its output by default")
+
+	standalone := []config.Config{
+		{Paths: []string{"testdata/test_project"}, Sort: "path", ClaudeXML: true, Extensions: []string{".go"}, IncludeHidden: true},
+		{Paths: []string{"testdata/test_project/docs", "testdata/test_project/temp"}, Sort: "path", Markdown: true, LineNumbers: true},
+		{Paths: []string{"testdata/test_project"}, Sort: "path", IgnorePatterns: []string{"temp/"}, Tree: true},
+	}
+	for i, cfg := range standalone {
+		require.NoError(t, results[i].Err, results[i].Job)
+		var expected bytes.Buffer
+		summary, err := Generate(context.Background(), cfg, &expected, nil)
+		require.NoError(t, err)
+		actual, err := os.ReadFile(results[i].Output)
+		require.NoError(t, err)
+		assert.Equal(t, expected.String(), string(actual), results[i].Job)
+		assert.Equal(t, summary, results[i].Summary, results[i].Job)
+		assert.NotZero(t, summary.Files, results[i].Job)
+	}
+}
+
+func TestRunBatchReportsJobFailures(t *testing.T) {
+	withStdout(t)
+	out := t.TempDir()
+	jobs := []BatchJob{
+		{Name: "bad format", Output: filepath.Join(out, "a"), Format: "pdf"},
+		{Name: "ok", Output: filepath.Join(out, "b")},
+		{Name: "bad sort", Output: filepath.Join(out, "c"), Sort: new("random")},
+		{Name: "unwritable", Output: filepath.Join(out, "missing", "d")},
+	}
+	results, err := RunBatch(context.Background(), config.Config{Paths: []string{"testdata/file1.txt"}}, jobs)
+	require.NoError(t, err)
+	assert.EqualError(t, results[0].Err, `invalid format "pdf": use plain, markdown, cxml, html, jsonl, openai, digest or digest-json`)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, 1, results[1].Summary.Files)
+	assert.ErrorContains(t, results[2].Err, `invalid --sort "random"`)
+	assert.ErrorContains(t, results[3].Err, "failed to create output file")
+
+	results, err = RunBatch(context.Background(), config.Config{}, []BatchJob{{Name: "empty", Output: filepath.Join(out, "e")}})
+	require.NoError(t, err)
+	assert.EqualError(t, results[0].Err, "no paths given by the job, the arguments or stdin")
+
+	_, err = RunBatch(context.Background(), config.Config{Clipboard: true}, jobs)
+	assert.EqualError(t, err, "--batch cannot be combined with --copy")
+}
+
+func TestLoadBatch(t *testing.T) {
+	jobs, err := LoadBatch(writeBatch(t, "jobs:\n  - output: out.xml\n    max-size: 10k\n    grep: TODO\n"))
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "out.xml", jobs[0].Name)
+	assert.Equal(t, config.ByteSize(10<<10), *jobs[0].MaxSize)
+	assert.Equal(t, "TODO", *jobs[0].Grep)
+	assert.Nil(t, jobs[0].LineNumbers)
+
+	for content, message := range map[string]string{
+		"jobs: []\n":                                 "no jobs",
+		"jobs:\n  - name: x\n":                       "job 1 has no output",
+		"jobs:\n  - output: a\n  - output: a\n":      `duplicate job "a"`,
+		"jobs:\n  - output: a\n    extension: go\n":  "field extension not found",
+		"jobs:\n  - output: a\n    max-size: lots\n": "lots",
+	} {
+		_, err := LoadBatch(writeBatch(t, content))
+		assert.ErrorContains(t, err, message, content)
+	}
+	_, err = LoadBatch(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "invalid --batch file")
+}
+
+func TestTreeSnapshotWalk(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.go":              "a",
+		"build/out.bin":     "b",
+		"build/sub/x.go":    "x",
+		"src/main.go":       "m",
+		"src/skip.go":       "s",
+		"src/zz/deeper.go":  "d",
+		".git/HEAD":         "ref",
+		"src/.hg/store.dat": "h",
+	})
+	snap, err := snapshotTree(context.Background(), []string{root, filepath.Join(root, "src")}, nil, false)
+	require.NoError(t, err)
+	require.Len(t, snap.roots, 1, "nested directories are walked once")
+
+	// record walks from start with fn's pruning, returning the visited paths
+	record := func(walk func(string, filepath.WalkFunc) error, start string) []string {
+		var visited []string
+		require.NoError(t, walk(start, func(path string, info os.FileInfo, err error) error {
+			require.NoError(t, err)
+			visited = append(visited, path)
+			switch filepath.Base(path) {
+			case "build", ".git", ".hg":
+				return filepath.SkipDir
+			case "skip.go":
+				// Skips the rest of the directory, as with filepath.Walk
+				return filepath.SkipDir
+			}
+			return nil
+		}))
+		return visited
+	}
+	replay := func(root string, fn filepath.WalkFunc) error { return snap.walk(root, false, fn) }
+	for _, start := range []string{root, filepath.Join(root, "src"), filepath.Join(root, "build")} {
+		assert.Equal(t, record(filepath.Walk, start), record(replay, start), start)
+	}
+
+	// VCS metadata directories are recorded but not entered
+	var all []string
+	require.NoError(t, snap.walk(root, false, func(path string, _ os.FileInfo, _ error) error {
+		all = append(all, path)
+		return nil
+	}))
+	assert.Contains(t, all, filepath.Join(root, ".git"))
+	assert.NotContains(t, all, filepath.Join(root, ".git", "HEAD"))
+	assert.NotContains(t, all, filepath.Join(root, "src", ".hg", "store.dat"))
+}
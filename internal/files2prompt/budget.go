@@ -0,0 +1,130 @@
+package files2prompt
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// BudgetScope selects what counts toward byte and token figures: the run
+// summary, --tokens, the --exec environment, history, and the long-run notice.
+type BudgetScope string
+
+// Budget scopes accepted by --budget-scope.
+const (
+	// BudgetRendered counts the full formatted output, including headers,
+	// fences, line-number gutters and wrappers.
+	BudgetRendered BudgetScope = "rendered"
+	// BudgetContent counts only the file (and command) content that was emitted.
+	BudgetContent BudgetScope = "content"
+)
+
+// budgetScope returns the scope selected by config, defaulting to BudgetRendered.
+func budgetScope(config config.Config) (BudgetScope, error) {
+	switch BudgetScope(config.BudgetScope) {
+	case "", BudgetRendered:
+		return BudgetRendered, nil
+	case BudgetContent:
+		return BudgetContent, nil
+	}
+	return "", fmt.Errorf("invalid --budget-scope %q: use %s or %s", config.BudgetScope, BudgetContent, BudgetRendered)
+}
+
+// usage is an amount of output. tokens is only tracked with the BPE-style
+// estimate; otherwise it is derived from bytes when reported. lines is only
+// tracked for content.
+type usage struct {
+	bytes  int64
+	tokens int64
+	lines  int64
+}
+
+// ledger is the accounting shared by every budget-related figure. It wraps the
+// output writer to meter the rendered output, is told about the raw content
+// inside each document, and reports whichever the scope selects. A nil *ledger
+// records nothing.
+type ledger struct {
+	w     io.Writer
+	scope BudgetScope
+	// bpe selects the BPE-style token estimate instead of bytes divided by four
+	bpe      bool
+	rendered usage
+	content  usage
+}
+
+func newLedger(w io.Writer, config config.Config) (*ledger, error) {
+	scope, err := budgetScope(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ledger{w: w, scope: scope, bpe: config.CountTokens}, nil
+}
+
+// Write meters p as rendered output. Each Write is tokenized on its own, so
+// callers should write whole documents, or large chunks of streamed ones.
+func (l *ledger) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	l.rendered.add(p[:n], l.bpe)
+	return n, err
+}
+
+// addContent records content emitted inside a document.
+func (l *ledger) addContent(content string) {
+	l.addContentPart([]byte(content))
+	l.addContentLines(countLines([]byte(content)))
+}
+
+// addContentPart records part of the content of a streamed document, the lines
+// of which are recorded with addContentLines once it is complete.
+func (l *ledger) addContentPart(p []byte) {
+	if l == nil {
+		return
+	}
+	l.content.add(p, l.bpe)
+}
+
+// addContentLines records n lines of content.
+func (l *ledger) addContentLines(n int64) {
+	if l == nil {
+		return
+	}
+	l.content.lines += n
+}
+
+func (u *usage) add(p []byte, bpe bool) {
+	u.bytes += int64(len(p))
+	if bpe {
+		u.tokens += countTokens(string(p))
+	}
+}
+
+// used returns the output counted by the ledger's scope.
+func (l *ledger) used() usage {
+	if l.scope == BudgetContent {
+		return l.report(l.content)
+	}
+	return l.report(l.rendered)
+}
+
+// contentSince returns the content recorded since the ledger's content usage was before.
+func (l *ledger) contentSince(before usage) usage {
+	return l.report(usage{
+		bytes:  l.content.bytes - before.bytes,
+		tokens: l.content.tokens - before.tokens,
+		lines:  l.content.lines - before.lines,
+	})
+}
+
+// overhead returns the rendered output that is not content.
+func (l *ledger) overhead() usage {
+	rendered, content := l.report(l.rendered), l.report(l.content)
+	return usage{bytes: rendered.bytes - content.bytes, tokens: rendered.tokens - content.tokens}
+}
+
+func (l *ledger) report(u usage) usage {
+	if !l.bpe {
+		u.tokens = estimateTokens(u.bytes)
+	}
+	return u
+}
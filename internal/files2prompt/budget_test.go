@@ -0,0 +1,127 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+var budgetFixture = []string{"testdata/file1.txt", "testdata/file2.txt"}
+
+// generateScoped renders cfg under both scopes and returns the two summaries
+// together with the rendered output.
+func generateScoped(t *testing.T, cfg config.Config) (content, rendered Summary, output string) {
+	t.Helper()
+	cfg.Paths = budgetFixture
+	var buf bytes.Buffer
+	cfg.BudgetScope = string(BudgetContent)
+	content, err := Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+
+	buf.Reset()
+	cfg.BudgetScope = string(BudgetRendered)
+	rendered, err = Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	return content, rendered, buf.String()
+}
+
+func TestBudgetScopesDivergeByOverhead(t *testing.T) {
+	var contentBytes, overhead int64
+	for _, path := range budgetFixture {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		contentBytes += int64(len(data))
+		// "<path>\n---\n" before the content and "---\n\n" after it
+		overhead += int64(len(path)) + 1 + 4 + 5
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			// The newline supplied before the closing separator
+			overhead++
+		}
+	}
+
+	content, rendered, output := generateScoped(t, config.Config{})
+	assert.Equal(t, BudgetContent, content.Scope)
+	assert.Equal(t, BudgetRendered, rendered.Scope)
+	assert.Equal(t, contentBytes, content.Bytes)
+	assert.Equal(t, int64(len(output)), rendered.Bytes)
+	assert.Equal(t, overhead, rendered.Bytes-content.Bytes)
+	assert.Equal(t, estimateTokens(contentBytes), content.Tokens)
+	assert.Equal(t, estimateTokens(rendered.Bytes), rendered.Tokens)
+}
+
+func TestBudgetContentScopeIgnoresFormatting(t *testing.T) {
+	plain, _, _ := generateScoped(t, config.Config{CountTokens: true})
+
+	for name, cfg := range map[string]config.Config{
+		"markdown":     {Markdown: true},
+		"claude xml":   {ClaudeXML: true},
+		"line numbers": {LineNumbers: true},
+		"compact":      {LineNumbersCompact: true, Markdown: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg.CountTokens = true
+			content, rendered, output := generateScoped(t, cfg)
+			// The content figures do not depend on how the documents are wrapped
+			assert.Equal(t, plain.Bytes, content.Bytes)
+			assert.Equal(t, plain.Tokens, content.Tokens)
+			assert.Equal(t, int64(len(output)), rendered.Bytes)
+			assert.Equal(t, countTokensPerDocument(t, cfg), rendered.Tokens)
+			assert.Greater(t, rendered.Bytes, content.Bytes)
+		})
+	}
+}
+
+// countTokensPerDocument renders every fixture file on its own and sums the
+// BPE estimates, mirroring how the ledger meters whole documents.
+func countTokensPerDocument(t *testing.T, cfg config.Config) int64 {
+	t.Helper()
+	var tokens int64
+	if cfg.ClaudeXML {
+		tokens += countTokens("<documents>\n") + countTokens("</documents>\n")
+	}
+	if cfg.Markdown {
+		tokens += countTokens("# Files\n\n")
+	}
+	state := newEmitState()
+	for _, path := range budgetFixture {
+		var buf bytes.Buffer
+		require.NoError(t, processFile(path, cfg, &buf, state))
+		tokens += countTokens(buf.String())
+	}
+	return tokens
+}
+
+func TestBudgetContentScopeWithGrepRegions(t *testing.T) {
+	content, _, _ := generateScoped(t, config.Config{Grep: "line [23]|second", GrepContext: 0})
+	// Only the matching lines are emitted, without the last line's missing newline
+	assert.Equal(t, int64(len("line 2\nline 3")+len("second line")), content.Bytes)
+}
+
+func TestBudgetScopeValidation(t *testing.T) {
+	for _, scope := range []string{"", "rendered", "content"} {
+		_, err := budgetScope(config.Config{BudgetScope: scope})
+		assert.NoError(t, err, scope)
+	}
+	_, err := Generate(context.Background(), config.Config{Paths: budgetFixture, BudgetScope: "wrappers"}, &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, `invalid --budget-scope "wrappers": use content or rendered`)
+}
+
+func TestLedgerOverhead(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLedger(&buf, config.Config{BudgetScope: "content"})
+	require.NoError(t, err)
+	_, _ = l.Write([]byte("path\n---\nbody\n---\n\n"))
+	l.addContent("body\n")
+
+	assert.Equal(t, usage{bytes: 5, tokens: 2, lines: 1}, l.used())
+	assert.Equal(t, usage{bytes: 14, tokens: 3}, l.overhead())
+	assert.Equal(t, "path\n---\nbody\n---\n\n", buf.String())
+
+	var nilLedger *ledger
+	nilLedger.addContent("ignored")
+}
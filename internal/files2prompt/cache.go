@@ -0,0 +1,136 @@
+package files2prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syncCache is a concurrency-safe cache of values by key, for results that are
+// costly to compute and never change for a key. Lookups of cached keys take no
+// lock, so that concurrent walks and workers do not queue on it, and values are
+// computed outside any lock: two goroutines missing the same key at once may
+// both compute it, and the value stored first is the one kept.
+type syncCache[K comparable, V any] struct {
+	m sync.Map
+}
+
+// get returns the cached value of key, computing it with compute on a miss.
+func (c *syncCache[K, V]) get(key K, compute func() V) V {
+	if v, ok := c.m.Load(key); ok {
+		return v.(V)
+	}
+	v, _ := c.m.LoadOrStore(key, compute())
+	return v.(V)
+}
+
+// patternGlob is one of the comma-separated doublestar patterns of --ignore,
+// --include and the default ignores, compiled.
+type patternGlob struct {
+	glob literalGlob
+	// dir, set for a pattern with a trailing slash, matches the base name of
+	// directories, or their relative path when the pattern has a slash within
+	dir *literalGlob
+	// anchored is set for a pattern with a leading slash, which, as in a
+	// .gitignore file, matches only the path relative to the walked directory
+	anchored bool
+}
+
+// matches reports whether g matches a candidate of base name base and
+// relative path rel, a directory when isDir is set.
+func (g patternGlob) matches(base, rel string, isDir bool) bool {
+	if g.anchored {
+		return g.glob.match(rel) || g.dir != nil && isDir && g.dir.match(rel)
+	}
+	if g.glob.match(base) || g.glob.match(rel) {
+		return true
+	}
+	return g.dir != nil && isDir && (g.dir.match(base) || g.dir.match(rel))
+}
+
+// patternKey identifies a list of patterns, and whether they ignore case.
+type patternKey struct {
+	patterns   string
+	ignoreCase bool
+}
+
+// patternCache holds compilePatterns results, which every candidate of every
+// walk would otherwise redo.
+var patternCache syncCache[patternKey, []patternGlob]
+
+// compiledPatterns returns patterns, each of which may hold several
+// comma-separated patterns, compiled for matching with or without case.
+// Patterns translatePattern rejects, which patternOptions reports before any
+// walk, are left out.
+func compiledPatterns(patterns []string, ignoreCase bool) []patternGlob {
+	key := patternKey{strings.Join(patterns, "\x00"), ignoreCase}
+	return patternCache.get(key, func() []patternGlob { return compilePatterns(patterns, ignoreCase) })
+}
+
+func compilePatterns(patterns []string, ignoreCase bool) []patternGlob {
+	var globs []patternGlob
+	for _, pattern := range patterns {
+		if ignoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		for _, subPattern := range strings.Split(pattern, ",") {
+			subPattern, err := translatePattern(strings.TrimSpace(subPattern))
+			if err != nil {
+				continue
+			}
+			var g patternGlob
+			subPattern, g.anchored = strings.CutPrefix(subPattern, "/")
+			if subPattern == "" {
+				continue
+			}
+			g.glob = newLiteralGlob(subPattern)
+			if strings.HasSuffix(subPattern, "/") {
+				dir := newLiteralGlob(strings.TrimSuffix(subPattern, "/"))
+				g.dir = &dir
+			}
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+// gitignoreFile is the parsed .gitignore of a directory, as it was when it
+// had size and modTime.
+type gitignoreFile struct {
+	size    int64
+	modTime time.Time
+	rules   []compiledRule
+}
+
+// gitignoreKey identifies a directory, by its absolute path and by the path
+// its rules are scoped to.
+type gitignoreKey struct {
+	abs, dir string
+}
+
+// gitignoreCache holds a *gitignoreFile for every directory whose .gitignore
+// was read, so that repeated walks of a tree, as by Match for each of its
+// paths or by programs planning the same tree again, parse each only once. An
+// entry is used only while the file keeps its size and modification time, so
+// edits between walks are seen.
+var gitignoreCache sync.Map
+
+// cachedGitignoreRules returns the rules of the .gitignore file in dir,
+// compiled, as compileIgnoreRules(readGitignoreRules(dir)) would.
+func cachedGitignoreRules(dir string) []compiledRule {
+	info, err := os.Stat(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	key := gitignoreKey{absPath(dir), dir}
+	if v, ok := gitignoreCache.Load(key); ok {
+		if f := v.(*gitignoreFile); f.size == info.Size() && f.modTime.Equal(info.ModTime()) {
+			return f.rules
+		}
+	}
+	f := &gitignoreFile{size: info.Size(), modTime: info.ModTime(), rules: compileIgnoreRules(readGitignoreRules(dir)).rules}
+	gitignoreCache.Store(key, f)
+	return f.rules
+}
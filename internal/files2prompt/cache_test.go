@@ -0,0 +1,239 @@
+package files2prompt
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// matchesPatternsReference is matchesPatterns as it was before the patterns
+// were compiled and cached, splitting and matching each of them for every
+// candidate.
+func matchesPatternsReference(patterns []string, c candidate, ignoreCase bool) bool {
+	base, rel := filepath.Base(c.path), c.rel
+	if ignoreCase {
+		base, rel = strings.ToLower(base), strings.ToLower(rel)
+	}
+	for _, pattern := range patterns {
+		if ignoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		for _, subPattern := range strings.Split(pattern, ",") {
+			subPattern = strings.TrimSpace(subPattern)
+			// A leading slash anchors the pattern to the relative path
+			subPattern, anchored := strings.CutPrefix(subPattern, "/")
+			if subPattern == "" {
+				continue
+			}
+			names := []string{base, rel}
+			if anchored {
+				names = []string{rel}
+			}
+			for _, name := range names {
+				if match, _ := doublestar.Match(subPattern, name); match {
+					return true
+				}
+				if strings.HasSuffix(subPattern, "/") && c.info.IsDir() {
+					if match, _ := doublestar.Match(strings.TrimSuffix(subPattern, "/"), name); match {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// statInfos returns the FileInfo of a file and of a directory.
+func statInfos(t testing.TB) (file, dir os.FileInfo) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"file": ""})
+	file, err := os.Stat(filepath.Join(root, "file"))
+	require.NoError(t, err)
+	dir, err = os.Stat(root)
+	require.NoError(t, err)
+	return file, dir
+}
+
+func TestMatchesPatternsEquivalence(t *testing.T) {
+	r := rand.New(rand.NewSource(1530))
+	file, dir := statInfos(t)
+	matched := 0
+	for set := 0; set < 300; set++ {
+		patterns := make([]string, 1+r.Intn(3))
+		for i := range patterns {
+			// Some hold several comma-separated patterns, with spaces and empty ones
+			parts := make([]string, 1+r.Intn(3))
+			for j := range parts {
+				parts[j] = corpusRuleParts[r.Intn(len(corpusRuleParts))]
+			}
+			patterns[i] = strings.Join(parts, ", ")
+			if r.Intn(4) == 0 {
+				patterns[i] = strings.ToUpper(patterns[i]) + ","
+			}
+		}
+		for i := 0; i < 100; i++ {
+			rel := strings.TrimPrefix(randomPath(r), "/")
+			c := candidate{path: filepath.Join("root", rel), rel: rel, info: file}
+			if r.Intn(2) == 0 {
+				c.info = dir
+			}
+			ignoreCase := r.Intn(2) == 0
+			if matchesPatternsReference(patterns, c, ignoreCase) {
+				matched++
+			}
+			if expected := matchesPatternsReference(patterns, c, ignoreCase); matchesPatterns(patterns, c, ignoreCase) != expected {
+				t.Fatalf("patterns %q path %q (dir %v, ignore case %v): compiled=%v reference=%v",
+					patterns, rel, c.info.IsDir(), ignoreCase, !expected, expected)
+			}
+		}
+	}
+	// The corpus should match often enough for the comparison to mean something
+	assert.Greater(t, matched, 1000)
+}
+
+func TestSyncCache(t *testing.T) {
+	var cache syncCache[int, string]
+	var computed atomic.Int64
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Go(func() {
+			for key := range 100 {
+				value := cache.get(key, func() string {
+					computed.Add(1)
+					return fmt.Sprint(key)
+				})
+				assert.Equal(t, fmt.Sprint(key), value)
+			}
+		})
+	}
+	wg.Wait()
+	// Each key is computed at least once, and a few concurrently more than once
+	assert.GreaterOrEqual(t, computed.Load(), int64(100))
+	assert.Equal(t, "7", cache.get(7, func() string { return "recomputed" }), "a cached key is never computed again")
+}
+
+func TestCachedGitignoreRulesSeesEdits(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{".gitignore": "*.log\n"})
+	matcher := func() *ignoreMatcher { return compileIgnoreRules(nil).with(cachedGitignoreRules(dir)) }
+
+	assert.True(t, matcher().match(filepath.Join(dir, "debug.log"), false))
+	assert.False(t, matcher().match(filepath.Join(dir, "debug.tmp"), false))
+
+	path := filepath.Join(dir, ".gitignore")
+	require.NoError(t, os.WriteFile(path, []byte("*.tmp\n"), 0o600))
+	// The same size, so only the modification time tells the edit apart
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	assert.False(t, matcher().match(filepath.Join(dir, "debug.log"), false))
+	assert.True(t, matcher().match(filepath.Join(dir, "debug.tmp"), false))
+
+	require.NoError(t, os.Remove(path))
+	assert.Empty(t, cachedGitignoreRules(dir))
+}
+
+// writeIgnoredTree writes a synthetic tree of n files, with .gitignore files at
+// its root and in every package, and a few files only they decide on.
+func writeIgnoredTree(tb testing.TB, root string, n int) {
+	tb.Helper()
+	writeSyntheticTree(tb, root, n)
+	files := map[string]string{".gitignore": "*.log\npkg0*/sub00/\n"}
+	for i := range 50 {
+		pkg := fmt.Sprintf("pkg%02d", i)
+		files[pkg+"/.gitignore"] = fmt.Sprintf("file%05d.go\n!*.keep\n", i)
+		files[pkg+"/debug.log"] = "log\n"
+		files[pkg+"/sub01/notes.keep"] = "keep\n"
+	}
+	writeFiles(tb, root, files)
+}
+
+// TestParallelPlans plans the same tree from many goroutines at once, sharing
+// the pattern and .gitignore caches; run with -race, as make test does, it
+// checks that they are safe for concurrent use.
+func TestParallelPlans(t *testing.T) {
+	root := t.TempDir()
+	writeIgnoredTree(t, root, 700)
+	cfg := config.Config{
+		Paths: []string{root}, IgnoreGitignore: true, IncludeHidden: true,
+		IgnorePatterns: []string{"sub05/, *.keep"}, IncludePatterns: []string{"*.go,*.KEEP"}, IgnoreCase: true,
+	}
+	serial, err := Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+	expected := includedPaths(t, root, serial)
+	require.NotEmpty(t, expected)
+	assert.NotContains(t, expected, "pkg00/sub00/file00000.go", "ignored directory")
+	assert.NotContains(t, expected, "pkg03/sub03/file00003.go", "ignored by its package's .gitignore")
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			plan, err := Plan(context.Background(), cfg, true)
+			if assert.NoError(t, err) {
+				assert.Equal(t, expected, includedPaths(t, root, plan))
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func BenchmarkMatchesPatterns(b *testing.B) {
+	file, _ := statInfos(b)
+	patterns := append([]string{"*.pb.go, *_gen.go", "testdata/**", "vendor/", "*.MIN.js,*.map"}, DefaultIgnorePatterns...)
+	var candidates []candidate
+	for _, p := range benchmarkPaths {
+		rel := strings.TrimPrefix(p, "repo/")
+		candidates = append(candidates, candidate{path: p, rel: rel, info: file})
+	}
+	b.Run("reference", func(b *testing.B) {
+		for b.Loop() {
+			for _, c := range candidates {
+				matchesPatternsReference(patterns, c, true)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		for b.Loop() {
+			for _, c := range candidates {
+				matchesPatterns(patterns, c, true)
+			}
+		}
+	})
+	// The cache is read without a lock, so parallel walks should scale with
+	// the CPUs rather than queue on it
+	b.Run("cached parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, c := range candidates {
+					matchesPatterns(patterns, c, true)
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkParallelPlans(b *testing.B) {
+	root := b.TempDir()
+	writeIgnoredTree(b, root, 2000)
+	cfg := config.Config{Paths: []string{root}, IgnoreGitignore: true, IgnorePatterns: []string{"sub05/"}}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := Plan(context.Background(), cfg, false); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package files2prompt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// caseKey returns the key that path shares with the paths differing from it
+// only in case, which name the same file on a case-insensitive file system
+// such as the default ones of macOS and Windows.
+func caseKey(path string) string {
+	return strings.ToLower(filepath.ToSlash(path))
+}
+
+// caseCollisions returns, for each of paths that differs only in case from
+// one before it, the first such path.
+func caseCollisions(paths []string) map[string]string {
+	first := map[string]string{}
+	collisions := map[string]string{}
+	for _, p := range paths {
+		key := caseKey(p)
+		if earlier, ok := first[key]; !ok {
+			first[key] = p
+		} else if earlier != p {
+			collisions[p] = earlier
+		}
+	}
+	return collisions
+}
+
+// markCaseCollisions records in CaseCollision, for each included file of plan
+// whose path differs only in case from one included before it, the Path of
+// that file, warning about each: written out as they are, by --mirror-to or
+// by whoever unpacks the output, one would overwrite the other.
+func markCaseCollisions(plan []PlannedFile) {
+	var paths []string
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			paths = append(paths, f.Path)
+		}
+	}
+	collisions := caseCollisions(paths)
+	if len(collisions) == 0 {
+		return
+	}
+	for i, f := range plan {
+		if earlier, ok := collisions[f.Path]; ok && f.Included && !f.IsDir {
+			plan[i].CaseCollision = earlier
+			log.Warnf("Warning: %s and %s differ only in case; written out on a case-insensitive file system, as macOS and Windows use by default, one overwrites the other",
+				earlier, f.Path)
+		}
+	}
+}
+
+// caseCollided describes the files of plan with a CaseCollision, each by its
+// display path followed by the path it collides with in parentheses.
+func caseCollided(plan []PlannedFile) []string {
+	display := map[string]string{}
+	var collided []string
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			display[f.Path] = f.DisplayPath
+		}
+		if f.CaseCollision != "" {
+			collided = append(collided, fmt.Sprintf("%s (%s)", f.DisplayPath, display[f.CaseCollision]))
+		}
+	}
+	return collided
+}
+
+// caseRenames returns the paths of paths to write elsewhere so that no two
+// differ only in case, each mapped to the path it is written to instead: a
+// path colliding with one before it gains the suffix ~2 before its
+// extension, or ~3 and so on when that collides too, as README.md and
+// Readme.md become README.md and Readme~2.md. The resolution depends only on
+// the order of paths.
+func caseRenames(paths []string) map[string]string {
+	taken := map[string]bool{}
+	for _, p := range paths {
+		taken[caseKey(p)] = true
+	}
+	collisions := caseCollisions(paths)
+	renames := map[string]string{}
+	// Suffixes are handed out in the order of paths, so that they are stable
+	for _, p := range paths {
+		if _, ok := collisions[p]; !ok || renames[p] != "" {
+			continue
+		}
+		ext := filepath.Ext(p)
+		stem := strings.TrimSuffix(p, ext)
+		for n := 2; ; n++ {
+			renamed := fmt.Sprintf("%s~%d%s", stem, n, ext)
+			if !taken[caseKey(renamed)] {
+				taken[caseKey(renamed)] = true
+				renames[p] = renamed
+				break
+			}
+		}
+	}
+	return renames
+}
@@ -0,0 +1,141 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestCaseRenames(t *testing.T) {
+	tree := fstest.MapFS{
+		"README.md":        {Data: []byte("# upper\n")},
+		"Readme.md":        {Data: []byte("# title\n")},
+		"readme.md":        {Data: []byte("# lower\n")},
+		"Readme~2.md":      {Data: []byte("# taken\n")},
+		"docs/Makefile":    {Data: []byte("all:\n")},
+		"docs/makefile":    {Data: []byte("all:\n")},
+		"Docs/index.md":    {Data: []byte("# index\n")},
+		"src/main.go":      {Data: []byte("package main\n")},
+		"src/unrelated.go": {Data: []byte("package main\n")},
+	}
+	var paths []string
+	require.NoError(t, fs.WalkDir(tree, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return err
+	}))
+
+	assert.Equal(t, map[string]string{
+		"Readme.md":     "README.md",
+		"readme.md":     "README.md",
+		"docs/makefile": "docs/Makefile",
+	}, caseCollisions(paths))
+	// Docs/index.md collides with no file, only with the directory docs
+	assert.Equal(t, map[string]string{
+		"Readme.md":     "Readme~3.md",
+		"readme.md":     "readme~4.md",
+		"docs/makefile": "docs/makefile~2",
+	}, caseRenames(paths))
+	assert.Empty(t, caseRenames([]string{"a.go", "b.go", "a.go"}))
+}
+
+func TestMarkCaseCollisions(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	plan := []PlannedFile{
+		{Path: "/src/docs", DisplayPath: "docs", IsDir: true, Included: true},
+		{Path: "/src/README.md", DisplayPath: "README.md", Included: true},
+		{Path: "/src/Readme.md", DisplayPath: "Readme.md", Included: true},
+		{Path: "/src/readme.md", DisplayPath: "readme.md"},
+		{Path: "/src/DOCS", DisplayPath: "DOCS", Included: true},
+		{Path: "/src/main.go", DisplayPath: "main.go", Included: true},
+	}
+
+	markCaseCollisions(plan)
+	collisions := map[string]string{}
+	for _, f := range plan {
+		if f.CaseCollision != "" {
+			collisions[f.Path] = f.CaseCollision
+		}
+	}
+	// Files left out and directories collide with nothing
+	assert.Equal(t, map[string]string{"/src/Readme.md": "/src/README.md"}, collisions)
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Contains(t, hook.LastEntry().Message, "/src/README.md and /src/Readme.md differ only in case")
+	assert.Equal(t, []string{"Readme.md (README.md)"}, caseCollided(plan))
+}
+
+// caseSensitive skips t unless the file system of dir tells apart names that
+// differ only in case, as the default ones of macOS and Windows do not.
+func caseSensitive(t *testing.T, dir string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "case-probe"), nil, 0o600))
+	defer func() { _ = os.Remove(filepath.Join(dir, "case-probe")) }()
+	if _, err := os.Stat(filepath.Join(dir, "CASE-PROBE")); err == nil {
+		t.Skip("file system is case-insensitive")
+	}
+}
+
+func TestCaseCollisions(t *testing.T) {
+	root := t.TempDir()
+	caseSensitive(t, root)
+	writeFiles(t, root, map[string]string{
+		"README.md": "# upper\n",
+		"Readme.md": "# title\n",
+		"main.go":   "package main\n",
+	})
+	t.Chdir(root)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	planned, err := Plan(context.Background(), config.Config{Paths: []string{"."}}, false)
+	require.NoError(t, err)
+	collisions := map[string]string{}
+	for _, f := range planned {
+		if f.CaseCollision != "" {
+			collisions[f.Path] = f.CaseCollision
+		}
+	}
+	upper, title := filepath.Join(root, "README.md"), filepath.Join(root, "Readme.md")
+	assert.Equal(t, map[string]string{title: upper}, collisions)
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Contains(t, hook.LastEntry().Message, upper+" and "+title+" differ only in case")
+
+	var out bytes.Buffer
+	_, err = Generate(context.Background(), config.Config{Paths: []string{"."}, EmbedWarnings: true}, &out, nil)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "1 file path differs only in case from another, so that on a case-insensitive file system one overwrites the other: "+title+" ("+upper+").")
+
+	t.Run("mirror", func(t *testing.T) {
+		mirrorDir := filepath.Join(t.TempDir(), "mirror")
+		cfg := config.Config{Paths: []string{"."}, MirrorTo: mirrorDir, MirrorOnly: true}
+		_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+		require.NoError(t, err)
+		got := readTree(t, mirrorDir)
+		assert.Equal(t, "# upper\n", got["README.md"])
+		assert.Equal(t, "# title\n", got["Readme~2.md"])
+		assert.NotContains(t, got, "Readme.md")
+
+		var manifest map[string]string
+		require.NoError(t, json.Unmarshal([]byte(got[mirrorRenamesFile]), &manifest))
+		assert.Equal(t, map[string]string{"Readme~2.md": "Readme.md"}, manifest)
+
+		// A mirror of files that do not collide has no manifest
+		require.NoError(t, os.RemoveAll(mirrorDir))
+		cfg.Paths = []string{"main.go", "README.md"}
+		_, err = Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+		require.NoError(t, err)
+		assert.NotContains(t, readTree(t, mirrorDir), mirrorRenamesFile)
+	})
+}
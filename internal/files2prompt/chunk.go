@@ -0,0 +1,76 @@
+package files2prompt
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// split divides content into the contents of consecutive documents, each
+// written in at most limit bytes once escaped as n escapes it.
+//
+// Every part ends with a newline that is not content, as a whole document's
+// content ends with that of its last line, so that removing the last newline of
+// each part and joining them yields content without its own. Parts end after
+// the last newline that fits within the limit when there is one, so lines are
+// only broken when a single line is longer than limit; even then the split
+// never falls inside a multi-byte UTF-8 sequence. A limit of zero or less, or
+// content that fits, returns content as a single part.
+func (n cxmlNames) split(content string, limit int64) []string {
+	if limit <= 0 || n.textLen(content) <= limit {
+		return []string{content}
+	}
+	body, ends := strings.CutSuffix(content, "\n")
+	var parts []string
+	for body != "" {
+		cut := n.fit(body, limit-1)
+		parts = append(parts, body[:cut]+"\n")
+		body = body[cut:]
+	}
+	if !ends {
+		parts[len(parts)-1] = strings.TrimSuffix(parts[len(parts)-1], "\n")
+	}
+	return parts
+}
+
+// textLen returns the number of bytes n writes s in.
+func (n cxmlNames) textLen(s string) int64 {
+	count := &byteCounter{w: io.Discard}
+	text := n.text(count)
+	_, _ = text.Write([]byte(s))
+	_ = text.flush()
+	return count.n
+}
+
+// fit returns the length of the longest prefix of s that n writes in at most
+// budget bytes, ending at a newline when one does; it is at least one rune,
+// however long that is written.
+func (n cxmlNames) fit(s string, budget int64) int {
+	count := &byteCounter{w: io.Discard}
+	text := n.text(count)
+	line, last := 0, 0
+	for i := 0; i < len(s); {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		_, _ = text.Write([]byte(s[i : i+size]))
+		// A sequence cut short is written as U+FFFD for each byte
+		if count.n+int64(len(text.partial)*len("\uFFFD")) > budget {
+			break
+		}
+		i += size
+		last = i
+		if s[i-1] == '\n' {
+			line = i
+		}
+	}
+	switch {
+	case last == len(s):
+		return last
+	case line > 0:
+		return line
+	case last > 0:
+		return last
+	}
+	// budget is smaller than a single rune; emit the whole rune
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
@@ -0,0 +1,133 @@
+package files2prompt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestCXMLSplit(t *testing.T) {
+	cdata := cxmlNames{cdata: true}
+	tests := []struct {
+		name     string
+		names    cxmlNames
+		content  string
+		max      int64
+		expected []string
+		// over is set when a rune alone is written in more than max bytes
+		over bool
+	}{
+		{name: "no limit", content: "a\nb\n", max: 0, expected: []string{"a\nb\n"}},
+		{name: "under limit", content: "a\nb\n", max: 10, expected: []string{"a\nb\n"}},
+		{name: "limit beyond 32 bits", content: "a\nb\n", max: 1 << 33, expected: []string{"a\nb\n"}},
+		{name: "empty", content: "", max: 3, expected: []string{""}},
+		{name: "split at newline", content: "aa\nbb\ncc\n", max: 7, expected: []string{"aa\nbb\n\n", "cc\n"}},
+		{name: "long line split by bytes", content: "abcdefgh\n", max: 4, expected: []string{"abc\n", "def\n", "gh\n"}},
+		{name: "no final newline", content: "abcdefgh", max: 4, expected: []string{"abc\n", "def\n", "gh"}},
+		{name: "rune boundary respected", content: "aé€b\n", max: 5, expected: []string{"aé\n", "€b\n"}},
+		{name: "limit below rune size", content: "€€\n", max: 1, expected: []string{"€\n", "€\n"}, over: true},
+		{name: "escaped length", content: "<<<<<\n", max: 9, expected: []string{"<<\n", "<<\n", "<\n"}},
+		{name: "invalid UTF-8 replaced", content: "\xff\xe2\x82\n", max: 4, expected: []string{"\xff\n", "\xe2\n", "\x82\n"}},
+		{name: "cdata kept raw", names: cdata, content: "<<<<<\n", max: 4, expected: []string{"<<<\n", "<<\n"}},
+		{name: "cdata section split", names: cdata, content: "a]]>b\n", max: 6, expected: []string{"a]]\n", ">b\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := tt.names.split(tt.content, tt.max)
+			assert.Equal(t, tt.expected, parts)
+			var joined strings.Builder
+			for _, part := range parts {
+				if len(parts) > 1 {
+					if !tt.over {
+						assert.LessOrEqual(t, tt.names.textLen(part), tt.max, "%q", part)
+					}
+					part = strings.TrimSuffix(part, "\n")
+				}
+				joined.WriteString(part)
+			}
+			if len(parts) > 1 && strings.HasSuffix(tt.content, "\n") {
+				joined.WriteString("\n")
+			}
+			assert.Equal(t, tt.content, joined.String())
+		})
+	}
+}
+
+// documentBodies returns what out, the output of a Claude XML run, holds
+// between the start and the end of each document's content, as written.
+func documentBodies(out string, cdata bool) []string {
+	start, end := "<document_content>\n", "</document_content>"
+	if cdata {
+		start, end = start+cdataStart, cdataEnd+"\n"+end
+	}
+	var bodies []string
+	for _, m := range regexp.MustCompile("(?s)"+regexp.QuoteMeta(start)+"(.*?)"+regexp.QuoteMeta(end)).FindAllStringSubmatch(out, -1) {
+		bodies = append(bodies, m[1])
+	}
+	return bodies
+}
+
+type cxmlDocuments struct {
+	Documents []struct {
+		Index   int    `xml:"index,attr"`
+		Part    string `xml:"part,attr"`
+		Source  string `xml:"source"`
+		Content string `xml:"document_content"`
+	} `xml:"document"`
+}
+
+func TestRunCXMLMaxDocBytes(t *testing.T) {
+	const limit = 1024
+	var buf bytes.Buffer
+	originalStdout := osStdout
+	osStdout = &buf
+	defer func() { osStdout = originalStdout }()
+
+	_, err := Run(config.Config{
+		Paths:           []string{"testdata/oversized"},
+		ClaudeXML:       true,
+		CXMLMaxDocBytes: limit,
+	})
+	require.NoError(t, err)
+
+	var docs cxmlDocuments
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &docs))
+
+	original, err := os.ReadFile("testdata/oversized/big.dat")
+	require.NoError(t, err)
+
+	// The limit is that of the content as written, escaped
+	bodies := documentBodies(buf.String(), false)
+	require.Len(t, bodies, len(docs.Documents))
+	for _, body := range bodies {
+		assert.LessOrEqual(t, len(body), limit)
+	}
+
+	var reassembled strings.Builder
+	var parts int
+	for i, doc := range docs.Documents {
+		assert.Equal(t, i+1, doc.Index)
+		if doc.Source == "testdata/oversized/big.dat" {
+			parts++
+			// document_content starts with a newline after the opening tag,
+			// and every part ends with one
+			reassembled.WriteString(strings.TrimSuffix(strings.TrimPrefix(doc.Content, "\n"), "\n"))
+			assert.NotEmpty(t, doc.Part)
+		} else {
+			assert.Empty(t, doc.Part)
+		}
+	}
+	assert.Equal(t, string(original), reassembled.String()+"\n")
+	assert.Greater(t, parts, 1)
+	assert.Equal(t, fmt.Sprintf("1/%d", parts), docs.Documents[0].Part)
+	assert.Equal(t, "testdata/oversized/small.dat", docs.Documents[len(docs.Documents)-1].Source)
+}
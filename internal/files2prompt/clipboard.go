@@ -0,0 +1,225 @@
+package files2prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// defaultCopyChunkSize is the largest copy --copy-chunked makes when
+// --copy-chunk-size is not given, and above which --copy suggests it: some X11
+// clipboard managers drop selections of about a megabyte.
+const defaultCopyChunkSize = 1 << 20
+
+// clipboardProvider is a command that copies its standard input to the system
+// clipboard, and the one that pastes it back when there is one.
+type clipboardProvider struct {
+	name string
+	args []string
+	// paste writes the clipboard to standard output, its first element
+	// looked up as name is
+	paste []string
+}
+
+var (
+	pbcopy  = clipboardProvider{name: "pbcopy", paste: []string{"pbpaste"}}
+	clipExe = clipboardProvider{name: "clip.exe"}
+	wlCopy  = clipboardProvider{name: "wl-copy", paste: []string{"wl-paste", "--no-newline"}}
+	xclip   = clipboardProvider{name: "xclip", args: []string{"-selection", "clipboard"},
+		paste: []string{"xclip", "-selection", "clipboard", "-o"}}
+	xsel = clipboardProvider{name: "xsel", args: []string{"--clipboard", "--input"},
+		paste: []string{"xsel", "--clipboard", "--output"}}
+)
+
+// session names the desktop session of a Unix environment: "wayland", "x11",
+// or "" when there is neither. $XDG_SESSION_TYPE is believed when it names one
+// of them; otherwise, as over SSH with X forwarding, the display variables tell.
+func (e osEnv) session() string {
+	switch t := e.getenv("XDG_SESSION_TYPE"); t {
+	case "wayland", "x11":
+		return t
+	}
+	switch {
+	case e.getenv("WAYLAND_DISPLAY") != "":
+		return "wayland"
+	case e.getenv("DISPLAY") != "":
+		return "x11"
+	}
+	return ""
+}
+
+// clipboardProviders lists the candidate providers for goos in order of
+// preference. Under WSL the Windows clipboard comes first, and under Wayland
+// wl-copy, with the X11 tools after it for XWayland.
+func (e osEnv) clipboardProviders() []clipboardProvider {
+	switch e.goos {
+	case "darwin":
+		return []clipboardProvider{pbcopy}
+	case "windows":
+		return []clipboardProvider{clipExe}
+	}
+	var providers []clipboardProvider
+	if e.getenv("WSL_DISTRO_NAME") != "" || e.getenv("WSL_INTEROP") != "" {
+		providers = append(providers, clipExe)
+	}
+	if e.session() == "wayland" {
+		providers = append(providers, wlCopy)
+	}
+	return append(providers, xclip, xsel)
+}
+
+// clipboard is the clipboard provider found: the command line that copies to
+// it, and the one that pastes from it, nil when it cannot be read back.
+type clipboard struct {
+	copy, paste []string
+}
+
+// findClipboard returns the first available clipboard provider.
+func (e osEnv) findClipboard() (clipboard, error) {
+	var names []string
+	for _, p := range e.clipboardProviders() {
+		path, err := e.lookPath(p.name)
+		if err != nil {
+			names = append(names, p.name)
+			continue
+		}
+		c := clipboard{copy: append([]string{path}, p.args...)}
+		if len(p.paste) > 0 {
+			if paste, err := e.lookPath(p.paste[0]); err == nil {
+				c.paste = append([]string{paste}, p.paste[1:]...)
+			}
+		}
+		return c, nil
+	}
+	return clipboard{}, fmt.Errorf("--copy: no clipboard provider found (looked for %s)", joinOr(names))
+}
+
+// copyAndCheck copies content to c, and reads it back to check that the clipboard
+// holds all of it when c can be pasted from.
+func (e osEnv) copyAndCheck(c clipboard, content []byte) error {
+	if _, err := e.runTool(c.copy, content); err != nil {
+		return fmt.Errorf("--copy: %v", err)
+	}
+	if c.paste == nil {
+		return nil
+	}
+	pasted, err := e.runTool(c.paste, nil)
+	if err != nil {
+		// Copying worked, and the check is best effort
+		log.Debugf("Could not read the clipboard back: %v", err)
+		return nil
+	}
+	if !bytes.Equal(pasted, content) {
+		return fmt.Errorf("--copy: the clipboard holds %d bytes after %d were copied with %s; "+
+			"it may not hold this much, which --copy-chunked works around", len(pasted), len(content), c.copy[0])
+	}
+	return nil
+}
+
+// copyChunked copies content to c in chunks of at most size bytes, waiting for
+// the user between chunks so that each can be pasted before the next replaces it.
+func (e osEnv) copyChunked(c clipboard, content []byte, size int64) error {
+	chunks := clipboardChunks(content, size)
+	if len(chunks) > 1 && !e.interactive() {
+		return fmt.Errorf("--copy-chunked: the output needs %d copies, and there is no terminal to wait on between them", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if i > 0 && !e.proceed(fmt.Sprintf("Press Enter to copy part %d of %d, or q to stop: ", i+1, len(chunks))) {
+			return fmt.Errorf("--copy-chunked: stopped after part %d of %d", i, len(chunks))
+		}
+		if err := e.copyAndCheck(c, chunk); err != nil {
+			return err
+		}
+		if len(chunks) > 1 {
+			log.Infof("Copied part %d of %d (%d bytes) to the clipboard", i+1, len(chunks), len(chunk))
+		}
+	}
+	return nil
+}
+
+// copyToClipboard copies the output, content, to c as config asks: whole, or in
+// chunks with --copy-chunked.
+func (e osEnv) copyToClipboard(c clipboard, content []byte, config config.Config) error {
+	if config.CopyChunked {
+		return e.copyChunked(c, content, copyChunkSize(config))
+	}
+	return e.copyAndCheck(c, content)
+}
+
+// copyChunkSize returns the effective --copy-chunk-size.
+func copyChunkSize(config config.Config) int64 {
+	if config.CopyChunkSize > 0 {
+		return int64(config.CopyChunkSize)
+	}
+	return defaultCopyChunkSize
+}
+
+// clipboardChunks divides content into chunks of at most size bytes, each
+// ending at a line end when one falls within it and otherwise between
+// characters.
+func clipboardChunks(content []byte, size int64) [][]byte {
+	var chunks [][]byte
+	for int64(len(content)) > size {
+		end := bytes.LastIndexByte(content[:size], '\n') + 1
+		if end == 0 {
+			end = int(size)
+			for end > 0 && !utf8.RuneStart(content[end]) {
+				end--
+			}
+			if end == 0 {
+				// A size smaller than a character still makes progress
+				_, end = utf8.DecodeRune(content)
+			}
+		}
+		chunks = append(chunks, content[:end])
+		content = content[end:]
+	}
+	if len(content) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
+
+// runTool runs argv with stdin as its standard input and returns its standard
+// output. Its error names the command, with what it wrote to stderr.
+func runTool(argv []string, stdin []byte) ([]byte, error) {
+	cmd := exec.Command(argv[0], argv[1:]...) // #nosec G204
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// xclip and wl-copy leave a process behind to serve the selection, which
+	// may hold the output open after the command itself has exited
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			err = errors.New(string(msg))
+		}
+		return nil, fmt.Errorf("%s failed: %v", argv[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// joinOr joins names as "a", "a or b" or "a, b or c".
+func joinOr(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	var b bytes.Buffer
+	for i, name := range names[:len(names)-1] {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+	}
+	return b.String() + " or " + names[len(names)-1]
+}
@@ -0,0 +1,294 @@
+package files2prompt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// fakeLookPath finds only the named executables, under /usr/bin.
+func fakeLookPath(available ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, a := range available {
+			if a == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+// clipboardEnv is an osEnv on goos with only the variables vars set and the
+// named executables available.
+func clipboardEnv(goos string, vars map[string]string, available ...string) osEnv {
+	return osEnv{
+		goos:     goos,
+		getenv:   func(name string) string { return vars[name] },
+		lookPath: fakeLookPath(available...),
+	}
+}
+
+func TestSession(t *testing.T) {
+	tests := []struct {
+		vars     map[string]string
+		expected string
+	}{
+		{map[string]string{"XDG_SESSION_TYPE": "wayland"}, "wayland"},
+		{map[string]string{"XDG_SESSION_TYPE": "x11", "WAYLAND_DISPLAY": "wayland-0"}, "x11"},
+		{map[string]string{"XDG_SESSION_TYPE": "tty", "DISPLAY": "localhost:10.0"}, "x11"},
+		{map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, "wayland"},
+		{map[string]string{"DISPLAY": ":0"}, "x11"},
+		{map[string]string{"XDG_SESSION_TYPE": "tty"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, clipboardEnv("linux", tt.vars).session(), "%v", tt.vars)
+	}
+}
+
+func TestFindClipboard(t *testing.T) {
+	wayland := map[string]string{"WAYLAND_DISPLAY": "wayland-0"}
+	wsl := map[string]string{"WSL_DISTRO_NAME": "Ubuntu", "XDG_SESSION_TYPE": "wayland"}
+	xclipCopy := []string{"/usr/bin/xclip", "-selection", "clipboard"}
+
+	tests := []struct {
+		name        string
+		env         osEnv
+		expected    clipboard
+		expectedErr string
+	}{
+		{name: "macOS", env: clipboardEnv("darwin", nil, "pbcopy", "pbpaste"),
+			expected: clipboard{copy: []string{"/usr/bin/pbcopy"}, paste: []string{"/usr/bin/pbpaste"}}},
+		{name: "windows", env: clipboardEnv("windows", nil, "clip.exe"), expected: clipboard{copy: []string{"/usr/bin/clip.exe"}}},
+		{name: "wayland preferred", env: clipboardEnv("linux", wayland, "wl-copy", "wl-paste", "xclip"),
+			expected: clipboard{copy: []string{"/usr/bin/wl-copy"}, paste: []string{"/usr/bin/wl-paste", "--no-newline"}}},
+		{name: "wayland by session type", env: clipboardEnv("linux", map[string]string{"XDG_SESSION_TYPE": "wayland"}, "wl-copy", "xclip"),
+			expected: clipboard{copy: []string{"/usr/bin/wl-copy"}}},
+		{name: "wl-copy ignored outside wayland", env: clipboardEnv("linux", nil, "wl-copy", "xclip"),
+			expected: clipboard{copy: xclipCopy, paste: []string{"/usr/bin/xclip", "-selection", "clipboard", "-o"}}},
+		{name: "x11 session under wayland display", env: clipboardEnv("linux", map[string]string{"XDG_SESSION_TYPE": "x11", "WAYLAND_DISPLAY": "wayland-0"}, "wl-copy", "xclip"),
+			expected: clipboard{copy: xclipCopy, paste: []string{"/usr/bin/xclip", "-selection", "clipboard", "-o"}}},
+		{name: "xclip for XWayland", env: clipboardEnv("linux", wayland, "xclip"),
+			expected: clipboard{copy: xclipCopy, paste: []string{"/usr/bin/xclip", "-selection", "clipboard", "-o"}}},
+		{name: "xsel fallback", env: clipboardEnv("linux", nil, "xsel"),
+			expected: clipboard{copy: []string{"/usr/bin/xsel", "--clipboard", "--input"}, paste: []string{"/usr/bin/xsel", "--clipboard", "--output"}}},
+		{name: "WSL", env: clipboardEnv("linux", wsl, "clip.exe", "wl-copy"), expected: clipboard{copy: []string{"/usr/bin/clip.exe"}}},
+		{name: "WSL without clip.exe", env: clipboardEnv("linux", wsl, "wl-copy"), expected: clipboard{copy: []string{"/usr/bin/wl-copy"}}},
+		{name: "none on linux", env: clipboardEnv("linux", wayland),
+			expectedErr: "--copy: no clipboard provider found (looked for wl-copy, xclip or xsel)"},
+		{name: "none on WSL", env: clipboardEnv("linux", map[string]string{"WSL_INTEROP": "/run/WSL/1_interop"}),
+			expectedErr: "--copy: no clipboard provider found (looked for clip.exe, xclip or xsel)"},
+		{name: "none on macOS", env: clipboardEnv("darwin", nil), expectedErr: "--copy: no clipboard provider found (looked for pbcopy)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.env.findClipboard()
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, c)
+		})
+	}
+}
+
+// fakeClipboard stands in for clipboard commands, holding at most limit bytes
+// of what is copied when limit is set.
+type fakeClipboard struct {
+	held     []byte
+	limit    int
+	copies   []string
+	pasteErr error
+	copyErr  error
+	prompts  []string
+	answers  []bool
+	terminal bool
+}
+
+// env returns an osEnv whose clipboard tools and terminal are f.
+func (f *fakeClipboard) env() osEnv {
+	return osEnv{
+		interactive: func() bool { return f.terminal },
+		proceed: func(prompt string) bool {
+			f.prompts = append(f.prompts, prompt)
+			answer := f.answers[0]
+			f.answers = f.answers[1:]
+			return answer
+		},
+		runTool: func(argv []string, stdin []byte) ([]byte, error) {
+			if argv[0] == "/usr/bin/paste" {
+				return f.held, f.pasteErr
+			}
+			if f.copyErr != nil {
+				return nil, f.copyErr
+			}
+			f.copies = append(f.copies, string(stdin))
+			f.held = stdin
+			if f.limit > 0 && len(stdin) > f.limit {
+				f.held = stdin[:f.limit]
+			}
+			return nil, nil
+		},
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	readable := clipboard{copy: []string{"/usr/bin/copy"}, paste: []string{"/usr/bin/paste"}}
+	writeOnly := clipboard{copy: []string{"/usr/bin/copy"}}
+	content := "line 1\nline 2\nline 3\n"
+
+	tests := []struct {
+		name        string
+		board       clipboard
+		fake        fakeClipboard
+		config      config.Config
+		copies      []string
+		prompts     []string
+		expectedErr string
+	}{
+		{name: "checked", board: readable, copies: []string{content}},
+		{name: "check fails", board: readable, fake: fakeClipboard{limit: 10}, copies: []string{content},
+			expectedErr: "--copy: the clipboard holds 10 bytes after 21 were copied with /usr/bin/copy; it may not hold this much, which --copy-chunked works around"},
+		{name: "not readable", board: writeOnly, fake: fakeClipboard{limit: 10}, copies: []string{content}},
+		{name: "paste fails", board: readable, fake: fakeClipboard{pasteErr: errors.New("no selection")}, copies: []string{content}},
+		{name: "copy fails", board: readable, fake: fakeClipboard{copyErr: errors.New("/usr/bin/copy failed: Error: Can't open display")},
+			expectedErr: "--copy: /usr/bin/copy failed: Error: Can't open display"},
+		{name: "over the chunk size", board: readable, config: config.Config{CopyChunkSize: 10}, copies: []string{content}},
+		{name: "chunked", board: readable, fake: fakeClipboard{terminal: true, answers: []bool{true, true}, limit: 13},
+			config:  config.Config{CopyChunked: true, CopyChunkSize: 13},
+			copies:  []string{"line 1\n", "line 2\n", "line 3\n"},
+			prompts: []string{"Press Enter to copy part 2 of 3, or q to stop: ", "Press Enter to copy part 3 of 3, or q to stop: "}},
+		{name: "chunked in two", board: readable, fake: fakeClipboard{terminal: true, answers: []bool{true}},
+			config:  config.Config{CopyChunked: true, CopyChunkSize: 15},
+			copies:  []string{"line 1\nline 2\n", "line 3\n"},
+			prompts: []string{"Press Enter to copy part 2 of 2, or q to stop: "}},
+		{name: "chunked stopped", board: readable, fake: fakeClipboard{terminal: true, answers: []bool{false}},
+			config: config.Config{CopyChunked: true, CopyChunkSize: 7}, copies: []string{"line 1\n"},
+			prompts:     []string{"Press Enter to copy part 2 of 3, or q to stop: "},
+			expectedErr: "--copy-chunked: stopped after part 1 of 3"},
+		{name: "chunked without a terminal", board: readable, config: config.Config{CopyChunked: true, CopyChunkSize: 7},
+			expectedErr: "--copy-chunked: the output needs 3 copies, and there is no terminal to wait on between them"},
+		{name: "chunked in one", board: readable, config: config.Config{CopyChunked: true}, copies: []string{content}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := tt.fake
+			err := fake.env().copyToClipboard(tt.board, []byte(content), tt.config)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.copies, fake.copies)
+			assert.Equal(t, tt.prompts, fake.prompts)
+		})
+	}
+}
+
+func TestClipboardChunks(t *testing.T) {
+	tests := []struct {
+		content  string
+		size     int64
+		expected []string
+	}{
+		{"a\nb\nc\n", 100, []string{"a\nb\nc\n"}},
+		{"a\nb\nc\n", 4, []string{"a\nb\n", "c\n"}},
+		{"a\nbb\n", 2, []string{"a\n", "bb", "\n"}},
+		{"abcdef", 4, []string{"abcd", "ef"}},
+		// Characters are kept whole
+		{"aéé", 4, []string{"aé", "é"}},
+		{"éé", 1, []string{"é", "é"}},
+		{"", 4, []string{""}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, chunk := range clipboardChunks([]byte(tt.content), tt.size) {
+			got = append(got, string(chunk))
+		}
+		assert.Equal(t, tt.expected, got, "%q by %d", tt.content, tt.size)
+	}
+}
+
+// withFakeClipboard installs an xclip stand-in that saves what it is given, returning the file it writes.
+func withFakeClipboard(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake clipboard uses a POSIX shell script")
+	}
+	dir := t.TempDir()
+	clipped := filepath.Join(dir, "clipboard")
+	script := filepath.Join(dir, "xclip")
+	// Pasting, with -o, gives back what was copied
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nif [ \"$3\" = -o ]; then cat '"+clipped+"'; else cat > '"+clipped+"'; fi\n"), 0o700)) // #nosec G306
+
+	original := hostEnv
+	hostEnv.goos = "linux"
+	hostEnv.getenv = func(string) string { return "" }
+	hostEnv.lookPath = func(name string) (string, error) {
+		if name == "xclip" {
+			return script, nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { hostEnv = original })
+	return clipped
+}
+
+func TestRunCopy(t *testing.T) {
+	expected := "<documents>\n<document index=\"1\">\n<source>testdata/file1.txt</source>\n<document_content>\nline 1\nline 2\nline 3\n</document_content>\n</document>\n</documents>\n"
+
+	t.Run("replaces stdout", func(t *testing.T) {
+		clipped := withFakeClipboard(t)
+		stdout := withStdout(t)
+
+		summary, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, ClaudeXML: true, Clipboard: true})
+		require.NoError(t, err)
+		assert.Empty(t, stdout.String())
+		content, err := os.ReadFile(clipped)
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+		assert.Equal(t, int64(len(expected)), summary.Bytes)
+	})
+
+	t.Run("alongside output file", func(t *testing.T) {
+		clipped := withFakeClipboard(t)
+		output := filepath.Join(t.TempDir(), "out.xml")
+
+		_, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, ClaudeXML: true, Clipboard: true, OutputFile: output})
+		require.NoError(t, err)
+		for _, path := range []string{clipped, output} {
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, expected, string(content))
+		}
+	})
+
+	t.Run("no provider", func(t *testing.T) {
+		withFakeClipboard(t)
+		hostEnv.lookPath = fakeLookPath()
+		output := filepath.Join(t.TempDir(), "out.txt")
+
+		_, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, Clipboard: true, OutputFile: output})
+		assert.ErrorContains(t, err, "no clipboard provider found")
+		assert.NoFileExists(t, output)
+	})
+
+	t.Run("chunked without copy", func(t *testing.T) {
+		_, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, CopyChunked: true})
+		assert.EqualError(t, err, "--copy-chunked requires --copy")
+	})
+}
+
+func TestJoinOr(t *testing.T) {
+	assert.Equal(t, "", joinOr(nil))
+	assert.Equal(t, "a", joinOr([]string{"a"}))
+	assert.Equal(t, "a or b", joinOr([]string{"a", "b"}))
+	assert.Equal(t, "a, b or c", joinOr([]string{"a", "b", "c"}))
+}
@@ -0,0 +1,147 @@
+package files2prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// Defaults for --cmd when CmdTimeout or CmdMaxBytes are left at zero.
+const (
+	DefaultCmdTimeout  = 30 * time.Second
+	DefaultCmdMaxBytes = 1 << 20
+)
+
+// shellCommand prepares command to run through the platform shell, killed when
+// ctx is done.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	return exec.CommandContext(ctx, shell, flag, command) // #nosec G204
+}
+
+// runShellCommand runs command through the platform shell, writing its combined
+// stdout and stderr to out. It is the default osEnv.runCommand.
+func runShellCommand(ctx context.Context, command string, out io.Writer) error {
+	cmd := shellCommand(ctx, command)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Don't wait forever on grandchildren that keep the output pipe open after a timeout
+	cmd.WaitDelay = time.Second
+	return cmd.Run()
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the rest,
+// so that a chatty command is never blocked or killed by its output filling up.
+type cappedBuffer struct {
+	buf       strings.Builder
+	limit     int64
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - int64(b.buf.Len()); int64(len(p)) > room {
+		b.truncated = true
+		_, _ = b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// commandLabel returns the display path of the i-th --cmd document.
+func commandLabel(config config.Config, i int) string {
+	if i < len(config.CmdLabels) && config.CmdLabels[i] != "" {
+		return config.CmdLabels[i]
+	}
+	return config.Commands[i]
+}
+
+// commandOutput runs command and returns the document content describing its
+// output, and whether that output was truncated. A failure, timeout or
+// truncation is noted at the end of the content; failures and timeouts are
+// also returned as an error.
+func (e osEnv) commandOutput(ctx context.Context, command string, config config.Config) (string, bool, error) {
+	timeout := config.CmdTimeout
+	if timeout <= 0 {
+		timeout = DefaultCmdTimeout
+	}
+	limit := config.CmdMaxBytes
+	if limit <= 0 {
+		limit = DefaultCmdMaxBytes
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out := &cappedBuffer{limit: limit}
+	runErr := e.runCommand(cmdCtx, command, out)
+
+	content := out.String()
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if out.truncated {
+		content += fmt.Sprintf("[output truncated at %s]\n", formatBytes(limit))
+	}
+
+	var err error
+	switch {
+	case ctx.Err() != nil:
+		return "", false, ctx.Err()
+	case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+		content += fmt.Sprintf("[timed out after %s]\n", timeout)
+		err = fmt.Errorf("command %q timed out after %s", command, timeout)
+	case runErr != nil:
+		var exitErr interface{ ExitCode() int }
+		if errors.As(runErr, &exitErr) && exitErr.ExitCode() > 0 {
+			content += fmt.Sprintf("[exit status %d]\n", exitErr.ExitCode())
+		} else {
+			content += fmt.Sprintf("[failed: %v]\n", runErr)
+		}
+		err = fmt.Errorf("command %q failed: %v", command, runErr)
+	}
+	return content, out.truncated, err
+}
+
+// emitCommands runs every --cmd command and emits its output as a synthetic
+// document, through place. Failing commands are reported and emitted with their
+// exit status, unless config.CmdStrict makes them abort the run.
+func emitCommands(ctx context.Context, config config.Config, writer io.Writer, state *emitState, place func(emit func() error) error) error {
+	// --grep selects files; command output is always emitted whole
+	grep := state.grep
+	state.grep = nil
+	defer func() { state.grep = grep }()
+
+	for i, command := range config.Commands {
+		label := state.redact.label(commandLabel(config, i))
+		content, truncated, err := hostEnv.commandOutput(ctx, command, config)
+		if truncated {
+			state.truncated = append(state.truncated, label)
+		}
+		if err != nil {
+			if ctx.Err() != nil || config.CmdStrict {
+				return err
+			}
+			log.Warnf("Warning: %v", err)
+		}
+		content = state.redact.content(label, content)
+		emit := func() error { return emitDocument(label, content, "text", config, writer, state) }
+		if err := place(emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// exitError mimics *exec.ExitError for fake command runners.
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// withCommandRunner replaces the --cmd execution layer for the duration of the test.
+func withCommandRunner(t *testing.T, run func(ctx context.Context, command string, out io.Writer) error) {
+	t.Helper()
+	original := hostEnv.runCommand
+	hostEnv.runCommand = run
+	t.Cleanup(func() { hostEnv.runCommand = original })
+}
+
+func fakeRunner(ctx context.Context, command string, out io.Writer) error {
+	switch command {
+	case "ok":
+		_, _ = io.WriteString(out, "all good\n")
+		return nil
+	case "vet":
+		_, _ = io.WriteString(out, "main.go:3: unreachable code")
+		return exitError(2)
+	case "hang":
+		_, _ = io.WriteString(out, "starting\n")
+		<-ctx.Done()
+		return ctx.Err()
+	case "chatty":
+		_, _ = io.WriteString(out, strings.Repeat("x", 40))
+		_, _ = io.WriteString(out, strings.Repeat("y", 40))
+		return nil
+	}
+	return errors.New("unknown command")
+}
+
+func TestCommandOutput(t *testing.T) {
+	env := osEnv{runCommand: fakeRunner}
+	tests := []struct {
+		name        string
+		command     string
+		config      config.Config
+		expected    string
+		expectedErr string
+	}{
+		{name: "success", command: "ok", expected: "all good\n"},
+		{name: "failure records exit code", command: "vet", expected: "main.go:3: unreachable code\n[exit status 2]\n",
+			expectedErr: `command "vet" failed: exit status 2`},
+		{name: "timeout", command: "hang", config: config.Config{CmdTimeout: 10 * time.Millisecond},
+			expected: "starting\n[timed out after 10ms]\n", expectedErr: `command "hang" timed out after 10ms`},
+		{name: "output cap", command: "chatty", config: config.Config{CmdMaxBytes: 50},
+			expected: strings.Repeat("x", 40) + strings.Repeat("y", 10) + "\n[output truncated at 50 B]\n"},
+		{name: "runner error without exit code", command: "missing", expected: "[failed: unknown command]\n",
+			expectedErr: `command "missing" failed: unknown command`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, _, err := env.commandOutput(context.Background(), tt.command, tt.config)
+			assert.Equal(t, tt.expected, content)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateCommandDocuments(t *testing.T) {
+	withCommandRunner(t, fakeRunner)
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "plain with label",
+			config:   config.Config{Commands: []string{"ok", "vet"}, CmdLabels: []string{"status"}},
+			expected: "status\n---\nall good\n---\n\nvet\n---\nmain.go:3: unreachable code\n[exit status 2]\n---\n\n",
+		},
+		{
+			name:     "markdown fences as text",
+			config:   config.Config{Commands: []string{"ok"}, Markdown: true},
+			expected: "## ok\n\n```text\nall good\n```\n\n",
+		},
+		{
+			name:   "claude xml after files",
+			config: config.Config{Paths: []string{"testdata/file1.txt"}, Commands: []string{"ok"}, ClaudeXML: true},
+			expected: "<documents>\n" +
+				"<document index=\"1\">\n<source>testdata/file1.txt</source>\n<document_content>\nline 1\nline 2\nline 3\n</document_content>\n</document>\n" +
+				"<document index=\"2\">\n<source>ok</source>\n<document_content>\nall good\n</document_content>\n</document>\n" +
+				"</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), tt.config, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+			assert.Equal(t, len(tt.config.Paths)+len(tt.config.Commands), summary.Files)
+		})
+	}
+}
+
+func TestGenerateCommandStrict(t *testing.T) {
+	withCommandRunner(t, fakeRunner)
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Commands: []string{"ok", "vet"}, CmdStrict: true}, &buf, nil)
+	assert.EqualError(t, err, `command "vet" failed: exit status 2`)
+}
+
+func TestRunShellCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	content, _, err := hostEnv.commandOutput(context.Background(), "echo out; echo err >&2; exit 3", config.Config{})
+	assert.EqualError(t, err, `command "echo out; echo err >&2; exit 3" failed: exit status 3`)
+	assert.Equal(t, "out\nerr\n[exit status 3]\n", content)
+
+	content, _, err = hostEnv.commandOutput(context.Background(), "sleep 5", config.Config{CmdTimeout: 50 * time.Millisecond})
+	assert.ErrorContains(t, err, "timed out")
+	assert.Equal(t, "[timed out after 50ms]\n", content)
+}
@@ -0,0 +1,195 @@
+package files2prompt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// CompatMode selects another tool whose output --compat reproduces.
+type CompatMode string
+
+// Compat modes accepted by --compat.
+const (
+	// CompatNone renders files2prompt's own formats.
+	CompatNone CompatMode = ""
+	// CompatFilesToPrompt reproduces the plain, Markdown and Claude XML output
+	// of simonw/files-to-prompt, the Python tool files2prompt is modelled on.
+	CompatFilesToPrompt CompatMode = "files-to-prompt"
+)
+
+// compatMode returns the mode selected by config. Options that would add
+// output the reference tool cannot produce are rejected, since the point of the
+// mode is byte-compatible output.
+func compatMode(config config.Config) (CompatMode, error) {
+	switch mode := CompatMode(config.Compat); mode {
+	case CompatNone:
+		return mode, nil
+	case CompatFilesToPrompt:
+		for _, option := range []struct {
+			flag string
+			set  bool
+		}{
+			{"--tree", config.Tree},
+			{"--detect-lang", config.DetectLang},
+			{"--lang", len(config.LanguageOverrides) > 0},
+			{"--embed-warnings", config.EmbedWarnings},
+			{"--cmd", len(config.Commands) > 0},
+			{"- (standard input)", readsStdin(config)},
+			{"--line-numbers-compact", config.LineNumbersCompact},
+			{"--header-stats", config.HeaderStats},
+			{"--metadata", config.IncludeMetadata},
+			{"--anonymize", config.Anonymize},
+			{"--redact", config.Redact},
+			{"--stable-view", config.StableView},
+			{"--env-context", config.EnvContext || len(config.EnvContextCmds) > 0},
+			{"--squash-data-blocks", config.SquashDataBlocks > 0},
+			{"--head-lines", config.HeadLines > 0},
+			{"--hunks-only", config.HunksOnly},
+			{"--tail-lines", config.TailLines > 0},
+			{"--grep-context", config.Grep != "" && config.GrepContext >= 0},
+			{"--cxml-max-doc-bytes", config.CXMLMaxDocBytes > 0},
+			{"--cxml-schema", config.CXMLSchema != "" && config.CXMLSchema != string(CXMLAnthropic)},
+			{"--cxml-cdata", config.CXMLCData},
+			{"--html", config.HTML},
+			{"--jsonl", config.JSONL},
+			{"--openai", config.OpenAI},
+			{"--template", config.TemplatePath != ""},
+			{"--format", config.Format != ""},
+			{"--prefix", config.Prefix != "" || config.PrefixFile != ""},
+			{"--suffix", config.Suffix != "" || config.SuffixFile != ""},
+			{"--submodules separate", config.Submodules == string(SubmodulesSeparate)},
+			{"--collapse-generated-siblings", config.CollapseSiblings},
+		} {
+			if option.set {
+				return "", fmt.Errorf("--compat %s cannot be combined with %s", mode, option.flag)
+			}
+		}
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid --compat %q: use %s", config.Compat, CompatFilesToPrompt)
+}
+
+// appliesGitignore reports whether .gitignore rules are applied. files-to-prompt
+// applies them unless --ignore-gitignore is given, the reverse of files2prompt.
+func appliesGitignore(config config.Config) bool {
+	return config.IgnoreGitignore != (CompatMode(config.Compat) == CompatFilesToPrompt)
+}
+
+// filesToPromptLangs is the reference tool's extension to fence language map.
+var filesToPromptLangs = map[string]string{
+	"py":   "python",
+	"c":    "c",
+	"cpp":  "cpp",
+	"java": "java",
+	"js":   "javascript",
+	"ts":   "typescript",
+	"html": "html",
+	"css":  "css",
+	"xml":  "xml",
+	"json": "json",
+	"yaml": "yaml",
+	"yml":  "yaml",
+	"sh":   "bash",
+	"rb":   "ruby",
+}
+
+// compatDisplayPath returns the path files-to-prompt prints for f: a walked
+// file is the path argument joined with the file's path beneath it, as
+// os.path.join spells it, so "." and "./src" keep their leading "./".
+func compatDisplayPath(f PlannedFile) string {
+	if f.Origin != OriginWalk {
+		return f.Path
+	}
+	rel := strings.ReplaceAll(relativeToRoot(f), "\\", "/")
+	if strings.HasSuffix(f.Root, "/") {
+		return f.Root + rel
+	}
+	return f.Root + "/" + rel
+}
+
+// pythonSplitlines splits s at every line boundary recognised by Python's
+// str.splitlines, dropping the boundaries. A trailing boundary does not start
+// another line.
+func pythonSplitlines(s string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch r {
+		case '\r':
+			lines = append(lines, s[start:i])
+			if strings.HasPrefix(s[i+size:], "\n") {
+				size++
+			}
+			start = i + size
+		case '\n', '\v', '\f', 0x1c, 0x1d, 0x1e, 0x85, 0x2028, 0x2029:
+			lines = append(lines, s[start:i])
+			start = i + size
+		}
+		i += size
+	}
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+	return lines
+}
+
+// compatLineNumbers numbers content as files-to-prompt does: right-aligned
+// numbers padded to the width of the line count, two spaces, and no newline
+// after the last line.
+func compatLineNumbers(content string) string {
+	lines := pythonSplitlines(content)
+	padding := len(fmt.Sprint(len(lines)))
+	numbered := make([]string, len(lines))
+	for i, line := range lines {
+		numbered[i] = fmt.Sprintf("%*d  %s", padding, i+1, line)
+	}
+	return strings.Join(numbered, "\n")
+}
+
+// emitCompatFile renders f, read as data, in the files-to-prompt format selected by config.
+// Every line the reference tool writes ends in a newline, so content that
+// already ends in one is followed by a blank line. Like Python's text mode, the
+// reference tool reads "\r\n" and "\r" as "\n", and fails to decode files that
+// are not valid UTF-8, which are skipped with a warning.
+func emitCompatFile(f PlannedFile, data []byte, config config.Config, writer io.Writer, state *emitState) error {
+	if !utf8.Valid(data) {
+		log.Warnf("Warning: Skipping file %s due to UnicodeDecodeError", f.Path)
+		return nil
+	}
+	path := compatDisplayPath(f)
+	content := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\n"), "\r", "\n")
+	state.ledger.addContent(content)
+	state.emitted, state.lang, state.streamed = content, "", false
+
+	var output string
+	switch {
+	case config.ClaudeXML:
+		if config.LineNumbers {
+			content = compatLineNumbers(content)
+		}
+		output = fmt.Sprintf("<document index=\"%d\">\n<source>%s</source>\n<document_content>\n%s\n</document_content>\n</document>\n",
+			state.index, path, content)
+	case config.Markdown:
+		lang := filesToPromptLangs[path[strings.LastIndex(path, ".")+1:]]
+		backticks := getBackticks(content)
+		if config.LineNumbers {
+			content = compatLineNumbers(content)
+		}
+		output = fmt.Sprintf("%s\n%s%s\n%s\n%s\n", path, backticks, lang, content, backticks)
+	default:
+		if config.LineNumbers {
+			content = compatLineNumbers(content)
+		}
+		output = fmt.Sprintf("%s\n---\n%s\n\n---\n", path, content)
+	}
+	state.index++
+	state.files++
+	_, err := io.WriteString(writer, output)
+	return err
+}
@@ -0,0 +1,101 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// The golden files in testdata/compat hold the output of files-to-prompt for
+// testdata/compat/project, run from testdata/compat.
+func TestCompatFilesToPromptGolden(t *testing.T) {
+	golden, err := filepath.Abs("testdata/compat")
+	require.NoError(t, err)
+
+	tests := []struct {
+		golden string
+		dir    string
+		config config.Config
+	}{
+		{golden: "plain.golden", config: config.Config{Paths: []string{"project"}}},
+		{golden: "plain-numbered.golden", config: config.Config{Paths: []string{"project"}, LineNumbers: true}},
+		{golden: "markdown.golden", config: config.Config{Paths: []string{"project"}, Markdown: true}},
+		{golden: "markdown-numbered.golden", config: config.Config{Paths: []string{"project"}, Markdown: true, LineNumbers: true}},
+		{golden: "cxml.golden", config: config.Config{Paths: []string{"project"}, ClaudeXML: true}},
+		{golden: "cxml-numbered.golden", config: config.Config{Paths: []string{"project"}, ClaudeXML: true, LineNumbers: true}},
+		{golden: "plain-dot.golden", dir: "project", config: config.Config{Paths: []string{"."}}},
+		{golden: "markdown-args.golden", config: config.Config{Paths: []string{"project/main.py", "project/src"}, Markdown: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			expected, err := os.ReadFile(filepath.Join(golden, tt.golden))
+			require.NoError(t, err)
+			t.Chdir(filepath.Join(golden, tt.dir))
+
+			cfg := tt.config
+			cfg.Compat = string(CompatFilesToPrompt)
+			var buf bytes.Buffer
+			_, err = Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestCompatFilesToPromptDefaults(t *testing.T) {
+	t.Chdir("testdata/compat")
+	plan := func(cfg config.Config) []string {
+		cfg.Compat = string(CompatFilesToPrompt)
+		cfg.Paths = []string{"project"}
+		files, err := Plan(context.Background(), cfg, false)
+		require.NoError(t, err)
+		return includedPaths(t, "project", files)
+	}
+
+	// .gitignore applies unless --ignore-gitignore is given, as in files-to-prompt
+	assert.NotContains(t, plan(config.Config{}), "debug.log")
+	assert.Contains(t, plan(config.Config{IgnoreGitignore: true}), "debug.log")
+	// Extensions match as a suffix, with or without the dot
+	assert.Equal(t, []string{"crlf.py", "main.py"}, plan(config.Config{Extensions: []string{"py"}}))
+	// Each directory's files come before its subdirectories
+	assert.Equal(t, []string{"README.md", "binary.dat", "crlf.py", "main.py", "notes.md", "a/z.js", "src/Makefile", "src/util.sh"},
+		plan(config.Config{}))
+	assert.Equal(t, []string{"README.md", "a/z.js", "binary.dat", "crlf.py", "main.py", "notes.md", "src/Makefile", "src/util.sh"},
+		plan(config.Config{Sort: "none"}))
+}
+
+func TestCompatMode(t *testing.T) {
+	_, err := compatMode(config.Config{Compat: "repomix"})
+	assert.EqualError(t, err, `invalid --compat "repomix": use files-to-prompt`)
+
+	_, err = compatMode(config.Config{Compat: "files-to-prompt", Tree: true})
+	assert.EqualError(t, err, "--compat files-to-prompt cannot be combined with --tree")
+
+	mode, err := compatMode(config.Config{Compat: "files-to-prompt", LineNumbers: true, Grep: "x", GrepContext: -1})
+	require.NoError(t, err)
+	assert.Equal(t, CompatFilesToPrompt, mode)
+}
+
+func TestCompareWalk(t *testing.T) {
+	paths := []string{"p/src/util.sh", "p/a/z.js", "p/main.py", "p/README.md", "p/a/b/c.py", "p/a/a.py"}
+	slices.SortFunc(paths, compareWalk)
+	assert.Equal(t, []string{"p/README.md", "p/main.py", "p/a/a.py", "p/a/z.js", "p/a/b/c.py", "p/src/util.sh"}, paths)
+}
+
+func TestPythonSplitlines(t *testing.T) {
+	assert.Nil(t, pythonSplitlines(""))
+	assert.Equal(t, []string{"a", "b"}, pythonSplitlines("a\nb\n"))
+	assert.Equal(t, []string{"a", "", "b"}, pythonSplitlines("a\r\n\rb"))
+	assert.Equal(t, []string{"a", "b", "c"}, pythonSplitlines("a\fb c"))
+	assert.Equal(t, " 1  a\n 2  b\n 3  c\n 4  d\n 5  e\n 6  f\n 7  g\n 8  h\n 9  i\n10  j",
+		compatLineNumbers("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"))
+	assert.Equal(t, "", compatLineNumbers(""))
+}
@@ -0,0 +1,276 @@
+package files2prompt
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// CXMLSchema selects the element and attribute names of Claude XML output.
+type CXMLSchema string
+
+// Schemas accepted by --cxml-schema.
+const (
+	// CXMLAnthropic is Anthropic's documents/document naming, the default:
+	// <documents><document index="1"><source>PATH</source>
+	// <document_content>...</document_content></document></documents>.
+	CXMLAnthropic CXMLSchema = "anthropic"
+	// CXMLGenericFiles names the path in an attribute and gives the content as
+	// the element text: <files><file path="PATH">...</file></files>.
+	CXMLGenericFiles CXMLSchema = "generic-files"
+	// CXMLCustom takes every name from --cxml-root, --cxml-item,
+	// --cxml-index-attr, --cxml-path-attr and --cxml-content-element.
+	CXMLCustom CXMLSchema = "custom"
+)
+
+// cxmlNames are the names a Claude XML document is written with.
+type cxmlNames struct {
+	// root wraps the documents, and item each of them.
+	root, item string
+	// index is the attribute numbering the documents, or "" for none.
+	index string
+	// pathAttr is the attribute holding the path; when it is "" the path is
+	// the text of a <source> element instead.
+	pathAttr string
+	// content is the element holding the content, or "" for the item's own text.
+	content string
+	// cdata wraps the content in CDATA sections instead of escaping it.
+	cdata bool
+}
+
+// anthropicNames are the names of CXMLAnthropic, which the --cxml-* name
+// options default to.
+var anthropicNames = cxmlNames{root: "documents", item: "document", index: "index", content: "document_content"}
+
+// cxmlAttrs are the attributes documents may carry besides the index and the
+// path, which neither may be named after.
+var cxmlAttrs = []string{"language", "part", "lines", "bytes", "status", "diff", "file_size", "file_lines", "mtime", "mode"}
+
+// xmlName matches the element and attribute names the schema options accept.
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// cxmlSchema returns the names selected by config, defaulting to CXMLAnthropic.
+// The name options take effect only with CXMLCustom, where an empty root or
+// item name stands for the Anthropic one.
+func cxmlSchema(config config.Config) (cxmlNames, error) {
+	names, err := schemaNames(config)
+	names.cdata = config.CXMLCData
+	return names, err
+}
+
+func schemaNames(config config.Config) (cxmlNames, error) {
+	custom := cxmlNames{
+		root: cmp.Or(config.CXMLRoot, anthropicNames.root), item: cmp.Or(config.CXMLItem, anthropicNames.item),
+		index: config.CXMLIndexAttr, pathAttr: config.CXMLPathAttr, content: config.CXMLContentElement,
+	}
+	switch schema := CXMLSchema(config.CXMLSchema); schema {
+	case "", CXMLAnthropic, CXMLGenericFiles:
+		// The defaults of the name options are the Anthropic names
+		for _, name := range []struct{ flag, value, fallback string }{
+			{"--cxml-index-attr", config.CXMLIndexAttr, anthropicNames.index},
+			{"--cxml-path-attr", config.CXMLPathAttr, anthropicNames.pathAttr},
+			{"--cxml-content-element", config.CXMLContentElement, anthropicNames.content},
+			{"--cxml-root", custom.root, anthropicNames.root},
+			{"--cxml-item", custom.item, anthropicNames.item},
+		} {
+			if name.value != "" && name.value != name.fallback {
+				return cxmlNames{}, fmt.Errorf("%s needs --cxml-schema %s", name.flag, CXMLCustom)
+			}
+		}
+		if schema == CXMLGenericFiles {
+			return cxmlNames{root: "files", item: "file", pathAttr: "path"}, nil
+		}
+		return anthropicNames, nil
+	case CXMLCustom:
+		for _, name := range []struct{ flag, value string }{
+			{"--cxml-root", custom.root},
+			{"--cxml-item", custom.item},
+			{"--cxml-index-attr", custom.index},
+			{"--cxml-path-attr", custom.pathAttr},
+			{"--cxml-content-element", custom.content},
+		} {
+			if name.value != "" && !xmlName.MatchString(name.value) {
+				return cxmlNames{}, fmt.Errorf("invalid %s %q: not an XML name", name.flag, name.value)
+			}
+		}
+		for _, attr := range []string{custom.index, custom.pathAttr} {
+			if slices.Contains(cxmlAttrs, attr) {
+				return cxmlNames{}, fmt.Errorf("invalid --cxml-schema %s: documents already carry a %q attribute", CXMLCustom, attr)
+			}
+		}
+		if custom.index != "" && custom.index == custom.pathAttr {
+			return cxmlNames{}, fmt.Errorf("invalid --cxml-schema %s: the index and the path cannot share the %q attribute", CXMLCustom, custom.index)
+		}
+		return custom, nil
+	}
+	return cxmlNames{}, fmt.Errorf("invalid --cxml-schema %q: use %s, %s or %s",
+		config.CXMLSchema, CXMLAnthropic, CXMLGenericFiles, CXMLCustom)
+}
+
+// cxmlNamesOf returns the names of config, which Generate has validated.
+func cxmlNamesOf(config config.Config) cxmlNames {
+	names, _ := cxmlSchema(config)
+	return names
+}
+
+// openRoot returns the element opening the output.
+func (n cxmlNames) openRoot() string {
+	return "<" + n.root + ">\n"
+}
+
+// closeRoot returns the element closing the output.
+func (n cxmlNames) closeRoot() string {
+	return "</" + n.root + ">\n"
+}
+
+// open returns the start of the document numbered index for path, up to its
+// content. attrs are further attributes, each with a leading space.
+func (n cxmlNames) open(index int, path, attrs string) string {
+	var b strings.Builder
+	b.WriteString("<" + n.item)
+	if n.index != "" {
+		fmt.Fprintf(&b, " %s=\"%s\"", n.index, escapeXML(strconv.Itoa(index)))
+	}
+	if n.pathAttr != "" {
+		fmt.Fprintf(&b, " %s=\"%s\"", n.pathAttr, escapeXML(path))
+	}
+	b.WriteString(attrs + ">\n")
+	if n.pathAttr == "" {
+		b.WriteString("<source>" + escapeXML(path) + "</source>\n")
+	}
+	if n.content != "" {
+		b.WriteString("<" + n.content + ">\n")
+	}
+	if n.cdata {
+		b.WriteString(cdataStart)
+	}
+	return b.String()
+}
+
+// close returns the end of a document, after its content.
+func (n cxmlNames) close() string {
+	end := ""
+	if n.cdata {
+		end = cdataEnd + "\n"
+	}
+	if n.content != "" {
+		end += "</" + n.content + ">\n"
+	}
+	return end + "</" + n.item + ">\n"
+}
+
+// document returns the whole document numbered index for path.
+func (n cxmlNames) document(index int, path, attrs, content string) string {
+	var b strings.Builder
+	b.WriteString(n.open(index, path, attrs))
+	text := n.text(&b)
+	_, _ = text.Write([]byte(content))
+	_ = text.flush()
+	b.WriteString(n.close())
+	return b.String()
+}
+
+// text returns a writer of content to w, between open and close: escaped, or
+// with every "]]>" split across two CDATA sections when they are used. Either
+// way, characters XML does not allow and invalid UTF-8 are replaced by
+// U+FFFD, as escapeXML replaces them. A streamed document ends with a flush.
+func (n cxmlNames) text(w io.Writer) *cxmlText {
+	return &cxmlText{w: w, cdata: n.cdata}
+}
+
+const (
+	cdataStart = "<![CDATA["
+	cdataEnd   = "]]>"
+)
+
+// cxmlText escapes the content written through it, which may be split
+// anywhere across writes, as the content of a streamed document is.
+type cxmlText struct {
+	w     io.Writer
+	cdata bool
+	// brackets counts the ']' ending what was written so far, up to two
+	brackets int
+	// partial is the start of a UTF-8 sequence ending the last write
+	partial []byte
+	buf     []byte
+}
+
+func (t *cxmlText) Write(p []byte) (int, error) {
+	t.buf = t.buf[:0]
+	data := p
+	if len(t.partial) > 0 {
+		data = append(t.partial, p...)
+		t.partial = nil
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c >= utf8.RuneSelf || !xmlChar(rune(c)) {
+			if !utf8.FullRune(data[i:]) {
+				t.partial = bytes.Clone(data[i:])
+				break
+			}
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 || !xmlChar(r) {
+				t.buf = append(t.buf, "\uFFFD"...)
+			} else {
+				t.buf = append(t.buf, data[i:i+size]...)
+			}
+			t.brackets = 0
+			i += size - 1
+			continue
+		}
+		switch {
+		case !t.cdata && c == '&':
+			t.buf = append(t.buf, "&amp;"...)
+		case !t.cdata && c == '<':
+			t.buf = append(t.buf, "&lt;"...)
+		case !t.cdata && c == '>':
+			t.buf = append(t.buf, "&gt;"...)
+		case c == '>' && t.brackets == 2:
+			// The "]]" already written end the section, and the '>' starts the next
+			t.buf = append(t.buf, cdataEnd+cdataStart+">"...)
+		default:
+			t.buf = append(t.buf, c)
+		}
+		if c == ']' {
+			t.brackets = min(t.brackets+1, 2)
+		} else {
+			t.brackets = 0
+		}
+	}
+	if _, err := t.w.Write(t.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush writes what is left of a UTF-8 sequence cut short by the end of the
+// content, as U+FFFD for each byte.
+func (t *cxmlText) flush() error {
+	partial := t.partial
+	t.partial = nil
+	_, err := io.WriteString(t.w, strings.Repeat("\uFFFD", len(partial)))
+	return err
+}
+
+// xmlChar reports whether r is a character of the XML Char production.
+func xmlChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF || r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= 0x10FFFF
+}
+
+// escapeXML escapes s for element text or a double-quoted attribute value.
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,281 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// The golden files in testdata/cxml hold the Claude XML output for
+// testdata/test_project in each schema, run from testdata.
+func TestCXMLSchemaGolden(t *testing.T) {
+	golden, err := filepath.Abs("testdata/cxml")
+	require.NoError(t, err)
+	t.Chdir("testdata")
+
+	tests := []struct {
+		golden string
+		config config.Config
+	}{
+		{golden: "generic-files.golden", config: config.Config{CXMLSchema: string(CXMLGenericFiles)}},
+		{
+			golden: "custom.golden",
+			config: config.Config{
+				CXMLSchema: string(CXMLCustom), CXMLRoot: "bundle", CXMLItem: "entry",
+				CXMLIndexAttr: "n", CXMLPathAttr: "name", CXMLContentElement: "body",
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			name := tt.golden
+			if stream {
+				name += " streamed"
+			}
+			t.Run(name, func(t *testing.T) {
+				if stream {
+					// Streamed documents are written with the same names
+					withStreamThreshold(t, 1)
+				}
+				expected, err := os.ReadFile(filepath.Join(golden, tt.golden))
+				require.NoError(t, err)
+
+				cfg := tt.config
+				cfg.Paths = []string{"test_project"}
+				cfg.ClaudeXML, cfg.Tree, cfg.DetectLang = true, true, true
+				var buf bytes.Buffer
+				_, err = Generate(context.Background(), cfg, &buf, nil)
+				require.NoError(t, err)
+				assert.Equal(t, string(expected), buf.String())
+			})
+		}
+	}
+}
+
+func TestCXMLSchemaCustom(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, `a&b "c".txt`)
+	require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0o600))
+	escaped := filepath.Join(dir, "a&amp;b &#34;c&#34;.txt")
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "defaults",
+			config:   config.Config{CXMLSchema: string(CXMLCustom)},
+			expected: "<documents>\n<document>\n<source>" + escaped + "</source>\nhello\n</document>\n</documents>\n",
+		},
+		{
+			name: "path attribute",
+			config: config.Config{
+				CXMLSchema: string(CXMLCustom), CXMLRoot: "files", CXMLItem: "file", CXMLPathAttr: "path",
+			},
+			expected: "<files>\n<file path=\"" + escaped + "\">\nhello\n</file>\n</files>\n",
+		},
+		{
+			name: "the usual names",
+			config: config.Config{
+				CXMLSchema: string(CXMLCustom), CXMLIndexAttr: "index", CXMLContentElement: "document_content",
+			},
+			expected: "<documents>\n<document index=\"1\">\n<source>" + escaped + "</source>\n<document_content>\nhello\n</document_content>\n</document>\n</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths, cfg.ClaudeXML = []string{path}, true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestCXMLSchemaErrors(t *testing.T) {
+	tests := []struct {
+		config config.Config
+		err    string
+	}{
+		{config: config.Config{CXMLSchema: "repomix"}, err: `invalid --cxml-schema "repomix": use anthropic, generic-files or custom`},
+		{config: config.Config{CXMLRoot: "files"}, err: "--cxml-root needs --cxml-schema custom"},
+		{config: config.Config{CXMLSchema: string(CXMLGenericFiles), CXMLPathAttr: "name"}, err: "--cxml-path-attr needs --cxml-schema custom"},
+		{config: config.Config{CXMLSchema: string(CXMLCustom), CXMLItem: "my file"}, err: `invalid --cxml-item "my file": not an XML name`},
+		{
+			config: config.Config{CXMLSchema: string(CXMLCustom), CXMLPathAttr: "language"},
+			err:    `invalid --cxml-schema custom: documents already carry a "language" attribute`,
+		},
+		{
+			config: config.Config{CXMLSchema: string(CXMLCustom), CXMLIndexAttr: "id", CXMLPathAttr: "id"},
+			err:    `invalid --cxml-schema custom: the index and the path cannot share the "id" attribute`,
+		},
+		{
+			config: config.Config{CXMLSchema: string(CXMLGenericFiles), Compat: string(CompatFilesToPrompt)},
+			err:    "--compat files-to-prompt cannot be combined with --cxml-schema",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths, cfg.ClaudeXML = []string{"testdata/file1.txt"}, true
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	// The defaults of the name options are accepted with any schema
+	_, err := cxmlSchema(config.Config{
+		CXMLSchema: string(CXMLGenericFiles), CXMLRoot: "documents", CXMLItem: "document",
+		CXMLIndexAttr: "index", CXMLContentElement: "document_content",
+	})
+	assert.NoError(t, err)
+}
+
+// xmlDocuments is Claude XML output as an XML parser reads it.
+type xmlDocuments struct {
+	Documents []struct {
+		Index   string `xml:"index,attr"`
+		Source  string `xml:"source"`
+		Content string `xml:"document_content"`
+	} `xml:"document"`
+}
+
+func TestCXMLEscaping(t *testing.T) {
+	dir := t.TempDir()
+	content := "if a < b && c > d {\n\treturn \"</document_content>\"\n}\n// ]]> and ]]]> and <![CDATA[\n"
+	writeFiles(t, dir, map[string]string{"R&D/<notes>.go": content})
+	path := filepath.Join(dir, "R&D", "<notes>.go")
+
+	tests := []struct {
+		name   string
+		config config.Config
+		stream bool
+	}{
+		{name: "escaped"},
+		{name: "escaped streamed", stream: true},
+		{name: "escaped in parts", config: config.Config{CXMLMaxDocBytes: 20}},
+		{name: "cdata", config: config.Config{CXMLCData: true}},
+		{name: "cdata streamed", config: config.Config{CXMLCData: true}, stream: true},
+		{name: "cdata in parts", config: config.Config{CXMLCData: true, CXMLMaxDocBytes: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stream {
+				withStreamThreshold(t, 1)
+			}
+			cfg := tt.config
+			cfg.Paths, cfg.ClaudeXML, cfg.Tree = []string{dir}, true, true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+
+			var parsed xmlDocuments
+			require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed), buf.String())
+			var got strings.Builder
+			for _, doc := range parsed.Documents {
+				if doc.Source != path {
+					assert.Contains(t, doc.Content, "<notes>.go", "the tree names the file")
+					continue
+				}
+				text := strings.TrimPrefix(doc.Content, "\n")
+				if cfg.CXMLCData {
+					// The newline closing the section on a line of its own
+					text = strings.TrimSuffix(text, "\n")
+				}
+				// Every part ends with a newline, as the whole content does
+				got.WriteString(strings.TrimSuffix(text, "\n"))
+			}
+			assert.Equal(t, content, got.String()+"\n")
+			if limit := cfg.CXMLMaxDocBytes; limit > 0 {
+				bodies := documentBodies(buf.String(), cfg.CXMLCData)
+				assert.Greater(t, len(bodies), 3)
+				for _, body := range bodies[1:] {
+					assert.LessOrEqual(t, int64(len(body)), limit, "%q", body)
+				}
+			}
+
+			if cfg.CXMLCData {
+				assert.Contains(t, buf.String(), "if a < b && c > d {", "the content is kept raw")
+			} else {
+				assert.NotContains(t, buf.String(), "<![CDATA[")
+			}
+		})
+	}
+}
+
+func TestCXMLInvalidCharacters(t *testing.T) {
+	dir := t.TempDir()
+	content := "ctrl \x01\x1b[0m bell\x07\nbad \xff\xc3 utf-8\nok ✓ é 𝄞 \uFFFE\ttab\r\n"
+	want := "ctrl \uFFFD\uFFFD[0m bell\uFFFD\nbad \uFFFD\uFFFD utf-8\nok ✓ é 𝄞 \uFFFD\ttab\r\n"
+	writeFiles(t, dir, map[string]string{"ansi.log": content, "cut.txt": "ends mid-rune \xe2\x9c"})
+
+	for _, cfg := range []config.Config{{}, {CXMLCData: true}} {
+		for _, stream := range []bool{false, true} {
+			if stream {
+				withStreamThreshold(t, 1)
+			}
+			cfg.Paths, cfg.ClaudeXML = []string{dir}, true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+
+			// Every token decodes, so the output is well-formed
+			decoder := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+			for {
+				_, err := decoder.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, "%+v streamed=%v: %q", cfg, stream, buf.String())
+			}
+			var parsed xmlDocuments
+			require.NoError(t, xml.Unmarshal(buf.Bytes(), &parsed))
+			require.Len(t, parsed.Documents, 2)
+			// encoding/xml reads \r\n as \n
+			assert.Equal(t, strings.TrimSuffix(strings.ReplaceAll(want, "\r\n", "\n"), "\n"), strings.Trim(parsed.Documents[0].Content, "\n"), "%+v streamed=%v", cfg, stream)
+			assert.Equal(t, "ends mid-rune \uFFFD\uFFFD", strings.Trim(parsed.Documents[1].Content, "\n"), "%+v streamed=%v", cfg, stream)
+		}
+	}
+
+	// A rune cut across writes is kept whole
+	var split strings.Builder
+	w := cxmlNames{}.text(&split)
+	for i := range len(content) {
+		_, _ = w.Write([]byte(content[i : i+1]))
+	}
+	require.NoError(t, w.flush())
+	assert.Equal(t, want, split.String())
+}
+
+func TestCXMLTextSplitWrites(t *testing.T) {
+	content := "a]]>b]]]>c]]]]>>d]]"
+	for _, cdata := range []bool{false, true} {
+		var whole, split strings.Builder
+		names := cxmlNames{cdata: cdata}
+		_, _ = names.text(&whole).Write([]byte(content))
+		// A "]]>" may be cut anywhere by the chunks of a streamed file
+		w := names.text(&split)
+		for i := range len(content) {
+			_, _ = w.Write([]byte(content[i : i+1]))
+		}
+		assert.Equal(t, whole.String(), split.String())
+		if cdata {
+			assert.Equal(t, "a]]]]><![CDATA[>b]]]]]><![CDATA[>c]]]]]]><![CDATA[>>d]]", whole.String())
+		} else {
+			assert.Equal(t, "a]]&gt;b]]]&gt;c]]]]&gt;&gt;d]]", whole.String())
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package files2prompt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// maxDepth returns the number of levels below each directory argument a walk
+// descends to: --max-depth, or 1 with --no-recurse. 0 means no limit.
+func maxDepth(config config.Config) (int, error) {
+	switch {
+	case config.MaxDepth < 0:
+		return 0, fmt.Errorf("invalid --max-depth %d: use 1 or more levels, or 0 for no limit", config.MaxDepth)
+	case config.NoRecurse && config.MaxDepth > 1:
+		return 0, fmt.Errorf("--no-recurse cannot be combined with --max-depth %d", config.MaxDepth)
+	case config.NoRecurse:
+		return 1, nil
+	}
+	return config.MaxDepth, nil
+}
+
+// pathDepth returns the level rel, a path relative to the directory being
+// walked, lies at: 1 for its immediate children.
+func pathDepth(rel string) int {
+	return strings.Count(filepath.ToSlash(filepath.Clean(rel)), "/") + 1
+}
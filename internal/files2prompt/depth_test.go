@@ -0,0 +1,81 @@
+package files2prompt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		included []string
+		pruned   []string
+	}{
+		{
+			name: "no limit",
+			cfg:  config.Config{Paths: []string{"testdata/test_project"}},
+			included: []string{
+				"testdata/test_project/docs/README.txt",
+				"testdata/test_project/script.py",
+				"testdata/test_project/src/main.go",
+				"testdata/test_project/temp/file.txt",
+			},
+		},
+		{
+			name:     "depth 1",
+			cfg:      config.Config{Paths: []string{"testdata/test_project"}, MaxDepth: 1},
+			included: []string{"testdata/test_project/script.py"},
+			pruned:   []string{"testdata/test_project/docs", "testdata/test_project/src", "testdata/test_project/temp"},
+		},
+		{
+			name:     "no-recurse",
+			cfg:      config.Config{Paths: []string{"testdata/test_project"}, NoRecurse: true},
+			included: []string{"testdata/test_project/script.py"},
+			pruned:   []string{"testdata/test_project/docs", "testdata/test_project/src", "testdata/test_project/temp"},
+		},
+		{
+			name: "depth 2",
+			cfg:  config.Config{Paths: []string{"testdata/test_project"}, MaxDepth: 2},
+			included: []string{
+				"testdata/test_project/docs/README.txt",
+				"testdata/test_project/script.py",
+				"testdata/test_project/src/main.go",
+				"testdata/test_project/temp/file.txt",
+			},
+		},
+		{
+			name:     "relative to each argument",
+			cfg:      config.Config{Paths: []string{"testdata/test_project", "testdata/test_project/src"}, MaxDepth: 1},
+			included: []string{"testdata/test_project/script.py", "testdata/test_project/src/main.go"},
+			pruned:   []string{"testdata/test_project/docs", "testdata/test_project/src", "testdata/test_project/temp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, err := Plan(context.Background(), tt.cfg, true)
+			require.NoError(t, err)
+			var included, pruned []string
+			for _, f := range planned {
+				switch {
+				case f.Included:
+					included = append(included, f.Path)
+				case f.Reason == SkipDepth:
+					assert.True(t, f.IsDir, f.Path)
+					pruned = append(pruned, f.Path)
+				}
+			}
+			assert.Equal(t, tt.included, included)
+			assert.ElementsMatch(t, tt.pruned, pruned)
+		})
+	}
+
+	_, err := Plan(context.Background(), config.Config{Paths: []string{"testdata"}, MaxDepth: -1}, false)
+	assert.EqualError(t, err, "invalid --max-depth -1: use 1 or more levels, or 0 for no limit")
+	_, err = Plan(context.Background(), config.Config{Paths: []string{"testdata"}, MaxDepth: 3, NoRecurse: true}, false)
+	assert.EqualError(t, err, "--no-recurse cannot be combined with --max-depth 3")
+}
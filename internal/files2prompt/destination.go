@@ -0,0 +1,99 @@
+package files2prompt
+
+import (
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// DefaultTerminalLimit is the output size above which writing to a terminal
+// warns, unless config.TerminalLimit says otherwise: a terminal handed
+// megabytes scrolls for a long time and keeps little of it.
+const DefaultTerminalLimit int64 = 256 << 10
+
+// destinationLimit is the practical limit of somewhere the output goes: the
+// size above which it is likely to be cut short or hard to use.
+type destinationLimit struct {
+	limit int64
+	// flag sets limit
+	flag string
+	// problem says what happens above the limit, which follows it
+	problem string
+	advice  string
+}
+
+// destinationLimits returns the limits of the destinations config writes to:
+// the clipboard with --copy, the terminal when stdout is one, and --output
+// when --output-limit is given. A file has no limit of its own.
+func destinationLimits(config config.Config, terminal bool) []destinationLimit {
+	var limits []destinationLimit
+	if config.Clipboard && !config.CopyChunked {
+		limits = append(limits, destinationLimit{
+			limit:   copyChunkSize(config),
+			flag:    "--copy-chunk-size",
+			problem: "clipboard managers commonly truncate above",
+			advice:  "--copy-chunked, or -o to write a file",
+		})
+	}
+	if terminal {
+		limit := DefaultTerminalLimit
+		if config.TerminalLimit > 0 {
+			limit = int64(config.TerminalLimit)
+		}
+		limits = append(limits, destinationLimit{
+			limit:   limit,
+			flag:    "--terminal-limit",
+			problem: "terminals keep little of output above",
+			advice:  "-o, --copy, or piping it to a pager such as less",
+		})
+	}
+	if config.OutputFile != "" && config.OutputLimit > 0 && !splits(config) {
+		advice := "--split-bytes to write it in parts, or narrowing the selection"
+		if isOutputURL(config.OutputFile) {
+			advice = "--max-tokens, or narrowing the selection"
+		}
+		limits = append(limits, destinationLimit{
+			limit:   int64(config.OutputLimit),
+			flag:    "--output-limit",
+			problem: "it is meant to stay under",
+			advice:  advice,
+		})
+	}
+	return limits
+}
+
+// warnDestinationLimits warns about each of limits that output of size bytes
+// exceeds, naming what to do instead.
+func warnDestinationLimits(limits []destinationLimit, size int64) {
+	for _, l := range limits {
+		if size > l.limit {
+			log.Warnf("Output is %s; %s %s: consider %s (%s sets the limit)",
+				formatBytes(size), l.problem, formatBytes(l.limit), l.advice, l.flag)
+		}
+	}
+}
+
+// byteCounter passes writes on to w, counting the bytes written.
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writesToTerminal reports whether w is a terminal, such as stdout left to
+// the shell.
+func writesToTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
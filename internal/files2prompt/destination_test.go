@@ -0,0 +1,84 @@
+package files2prompt
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// limitWarnings runs cfg with stdout shown on a terminal when terminal is set,
+// returning the destination limit warnings it logs.
+func limitWarnings(t *testing.T, cfg config.Config, terminal bool) []string {
+	t.Helper()
+	stdout := withStdout(t)
+	original := hostEnv.terminal
+	hostEnv.terminal = func(w io.Writer) bool { return terminal && w == stdout }
+	t.Cleanup(func() { hostEnv.terminal = original })
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	_, err := Run(cfg)
+	require.NoError(t, err)
+	var warnings []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+	return warnings
+}
+
+func TestDestinationLimits(t *testing.T) {
+	// The plain output of file1.txt is 49 bytes
+	paths := []string{"testdata/file1.txt"}
+
+	t.Run("terminal", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"Output is 49 B; terminals keep little of output above 40 B: consider -o, --copy, or piping it to a pager such as less (--terminal-limit sets the limit)",
+		}, limitWarnings(t, config.Config{Paths: paths, TerminalLimit: 40}, true))
+		assert.Empty(t, limitWarnings(t, config.Config{Paths: paths}, true))
+		assert.Empty(t, limitWarnings(t, config.Config{Paths: paths, TerminalLimit: 49}, true))
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		assert.Empty(t, limitWarnings(t, config.Config{Paths: paths, TerminalLimit: 40}, false))
+	})
+
+	t.Run("clipboard", func(t *testing.T) {
+		withFakeClipboard(t)
+		// The clipboard replaces stdout, so the terminal limit does not apply
+		assert.Equal(t, []string{
+			"Output is 49 B; clipboard managers commonly truncate above 40 B: consider --copy-chunked, or -o to write a file (--copy-chunk-size sets the limit)",
+		}, limitWarnings(t, config.Config{Paths: paths, Clipboard: true, CopyChunkSize: 40, TerminalLimit: 40}, true))
+		assert.Empty(t, limitWarnings(t, config.Config{Paths: paths, Clipboard: true}, true))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "out.txt")
+		// A file has no limit unless given one
+		assert.Empty(t, limitWarnings(t, config.Config{Paths: paths, OutputFile: output, TerminalLimit: 40}, true))
+		assert.Equal(t, []string{
+			"Output is 49 B; it is meant to stay under 40 B: consider --split-bytes to write it in parts, or narrowing the selection (--output-limit sets the limit)",
+		}, limitWarnings(t, config.Config{Paths: paths, OutputFile: output, OutputLimit: 40}, true))
+	})
+
+	t.Run("url", func(t *testing.T) {
+		limits := destinationLimits(config.Config{OutputFile: "https://example.com/prompt", OutputLimit: 1 << 20}, false)
+		require.Len(t, limits, 1)
+		assert.Equal(t, "--max-tokens, or narrowing the selection", limits[0].advice)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		limits := destinationLimits(config.Config{Clipboard: true}, true)
+		require.Len(t, limits, 2)
+		assert.Equal(t, int64(1<<20), limits[0].limit)
+		assert.Equal(t, DefaultTerminalLimit, limits[1].limit)
+		assert.Empty(t, destinationLimits(config.Config{Clipboard: true, CopyChunked: true}, false))
+	})
+}
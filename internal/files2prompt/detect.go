@@ -0,0 +1,158 @@
+package files2prompt
+
+import (
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// detectSniffBytes is how much of a file the content heuristics look at.
+const detectSniffBytes = 4096
+
+// langHeuristic returns the language of a file given its base name and up to
+// detectSniffBytes of its content, or "" when it does not recognize the file.
+type langHeuristic func(name, head string) string
+
+// langHeuristics are tried in order by detectLanguage; the first match wins.
+// Names come first, since they are the more reliable signal, then shebangs, then
+// the characteristic first lines of each format.
+var langHeuristics = []langHeuristic{
+	byName("dockerfile", "Dockerfile*", "*.Dockerfile", "Containerfile*"),
+	byName("makefile", "Makefile", "GNUmakefile"),
+	byName("ruby", "Gemfile", "Rakefile"),
+	byName("bash", ".bashrc", ".bash_profile", ".bash_aliases", ".profile"),
+	byName("zsh", ".zshrc", ".zprofile"),
+	byName("ini", ".gitconfig", ".editorconfig"),
+	shebangLanguage,
+	byFirstLine("php", `^<\?php\b`),
+	byFirstLine("xml", `^<\?xml\s`),
+	byFirstLine("html", `(?i)^<(!doctype html|html)\b`),
+	byFirstLine("dockerfile", `^(?i:FROM)\s+\S+`),
+	byFirstLine("yaml", `^(---|%YAML\s)`),
+	byContent("json", looksJSON),
+	byFirstLine("ini", `^\[[\w .-]+( "[^"]*")?\]$`),
+}
+
+// shebangLangs maps the interpreter named by a "#!" line to a fence language.
+var shebangLangs = map[string]string{
+	"sh":      "bash",
+	"bash":    "bash",
+	"dash":    "bash",
+	"zsh":     "zsh",
+	"fish":    "fish",
+	"python":  "python",
+	"python2": "python",
+	"python3": "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"awk":     "awk",
+	"tclsh":   "tcl",
+	"Rscript": "r",
+}
+
+// detectLanguage picks a fence language for a file the extension map does not
+// cover, from its name and the start of its content. It returns "" when no
+// heuristic matches.
+func detectLanguage(filePath, content string) string {
+	name := filepath.Base(filePath)
+	head := content
+	if len(head) > detectSniffBytes {
+		head = head[:detectSniffBytes]
+	}
+	for _, h := range langHeuristics {
+		if lang := h(name, head); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+// byName returns a heuristic recognizing files whose base name matches any of patterns.
+func byName(lang string, patterns ...string) langHeuristic {
+	return func(name, _ string) string {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return lang
+			}
+		}
+		return ""
+	}
+}
+
+// byFirstLine returns a heuristic matching pattern against the first line of
+// content that is neither blank nor a "#" comment.
+func byFirstLine(lang, pattern string) langHeuristic {
+	re := regexp.MustCompile(pattern)
+	return func(_, head string) string {
+		for _, line := range strings.Split(head, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if re.MatchString(line) {
+				return lang
+			}
+			return ""
+		}
+		return ""
+	}
+}
+
+// byContent returns a heuristic recognizing content for which match is true.
+func byContent(lang string, match func(head string) bool) langHeuristic {
+	return func(_, head string) string {
+		if match(head) {
+			return lang
+		}
+		return ""
+	}
+}
+
+// shebangLanguage returns the language of the interpreter named by a "#!" first
+// line, looking through "env" and its options, and ignoring version suffixes.
+func shebangLanguage(_, head string) string {
+	line, ok := strings.CutPrefix(strings.SplitN(head, "\n", 2)[0], "#!")
+	if !ok {
+		return ""
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = field
+				break
+			}
+		}
+	}
+	if lang, ok := shebangLangs[interpreter]; ok {
+		return lang
+	}
+	// python3.12, ruby2.7
+	return shebangLangs[strings.TrimRight(interpreter, "0123456789.")]
+}
+
+// looksJSON reports whether head is a JSON object or array: valid JSON when the
+// whole file was sniffed, or an opening bracket followed by a string key or
+// value when it was cut short.
+func looksJSON(head string) bool {
+	trimmed := strings.TrimSpace(head)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return false
+	}
+	if len(head) < detectSniffBytes {
+		return json.Valid([]byte(trimmed))
+	}
+	rest := strings.TrimSpace(trimmed[1:])
+	return strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "{") || strings.HasPrefix(rest, "[")
+}
@@ -0,0 +1,101 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{"dockerfile by name", "Dockerfile.prod", "RUN make\n", "dockerfile"},
+		{"dockerfile by content", "build/image", "# syntax=docker/dockerfile:1\n\nFROM golang:1.26 AS build\n", "dockerfile"},
+		{"makefile", "Makefile", "all:\n\tgo build\n", "makefile"},
+		{"bashrc", ".bashrc", "alias ll='ls -l'\n", "bash"},
+		{"zshrc", ".zshrc", "setopt autocd\n", "zsh"},
+		{"sh shebang", "bin/deploy", "#!/bin/sh\nset -e\n", "bash"},
+		{"env shebang with options", "tools/gen", "#!/usr/bin/env -S python3 -u\nprint()\n", "python"},
+		{"versioned interpreter", "run", "#!/usr/local/bin/python3.12\n", "python"},
+		{"node shebang", "cli", "#!/usr/bin/env node\nconsole.log(1)\n", "javascript"},
+		{"unknown shebang", "script", "#!/usr/bin/frobnicate\n", ""},
+		{"php", "index.inc", "<?php\necho 1;\n", "php"},
+		{"xml prolog", "pom.template", "<?xml version=\"1.0\"?>\n<project/>\n", "xml"},
+		{"html doctype", "page.tmpl", "<!DOCTYPE html>\n<html></html>\n", "html"},
+		{"yaml document marker", "config", "---\nkey: value\n", "yaml"},
+		{"yaml directive", "stream", "%YAML 1.2\n---\na: 1\n", "yaml"},
+		{"json object", ".eslintrc", "{\n  \"extends\": \"standard\"\n}\n", "json"},
+		{"json array", "data", "[1, 2, 3]\n", "json"},
+		{"truncated json", "big", "[\"" + strings.Repeat("x", detectSniffBytes) + "\"]", "json"},
+		{"braces that are not json", "template", "{{ .Name }}\n", ""},
+		{"ini section", "settings.conf", "[server]\nport = 80\n", "ini"},
+		{"git config section", ".gitmodules.bak", "[submodule \"lib\"]\n\tpath = lib\n", "ini"},
+		{"plain text", "LICENSE", "Permission is hereby granted\n", ""},
+		{"empty", "config", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectLanguage(tt.path, tt.content))
+		})
+	}
+}
+
+func TestDetectLangOutput(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"Dockerfile.prod": "FROM alpine\n",
+		"main.go":         "package main\n",
+		"LICENSE":         "MIT\n",
+	})
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{
+			name: "markdown",
+			cfg:  config.Config{Markdown: true, DetectLang: true},
+			want: "# Files\n\n## {root}/Dockerfile.prod\n\n```dockerfile\nFROM alpine\n```\n\n" +
+				"## {root}/LICENSE\n\n```\nMIT\n```\n\n" +
+				"## {root}/main.go\n\n```go\npackage main\n```\n\n",
+		},
+		{
+			name: "markdown without detection",
+			cfg:  config.Config{Markdown: true, MarkdownStyle: string(MarkdownPath)},
+			want: "{root}/Dockerfile.prod\n```\nFROM alpine\n```\n" +
+				"{root}/LICENSE\n```\nMIT\n```\n" +
+				"{root}/main.go\n```go\npackage main\n```\n",
+		},
+		{
+			name: "cxml",
+			cfg:  config.Config{ClaudeXML: true, DetectLang: true},
+			want: "<documents>\n" +
+				"<document index=\"1\" language=\"dockerfile\">\n<source>{root}/Dockerfile.prod</source>\n<document_content>\nFROM alpine\n</document_content>\n</document>\n" +
+				"<document index=\"2\">\n<source>{root}/LICENSE</source>\n<document_content>\nMIT\n</document_content>\n</document>\n" +
+				"<document index=\"3\" language=\"go\">\n<source>{root}/main.go</source>\n<document_content>\npackage main\n</document_content>\n</document>\n" +
+				"</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Paths = []string{root}
+			var out bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &out, nil)
+			require.NoError(t, err)
+			assert.Equal(t, strings.ReplaceAll(tt.want, "{root}", filepath.ToSlash(root)), filepath.ToSlash(out.String()))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package files2prompt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// Format selects an output format of --format, for formats that describe the
+// files rather than show them.
+type Format string
+
+// Formats accepted by --format.
+const (
+	// FormatDigest writes a tab-separated line per document: its path, size,
+	// SHA-256 hash, language and the start of its content.
+	FormatDigest Format = "digest"
+	// FormatDigestJSON writes the same as a JSON object per line.
+	FormatDigestJSON Format = "digest-json"
+)
+
+// defaultDigestPreview is the number of characters of content a digest line
+// previews by default.
+const defaultDigestPreview = 200
+
+// outputFormat returns the format selected by --format, or "" without it, and
+// checks that it is not combined with another output format.
+func outputFormat(config config.Config) (Format, error) {
+	format := Format(config.Format)
+	switch format {
+	case "":
+		if config.DigestPreview != 0 {
+			return "", fmt.Errorf("--digest-preview requires --format %s or %s", FormatDigest, FormatDigestJSON)
+		}
+		return "", nil
+	case FormatDigest, FormatDigestJSON:
+	default:
+		return "", fmt.Errorf("invalid --format %q: use %s or %s", config.Format, FormatDigest, FormatDigestJSON)
+	}
+	if config.DigestPreview < 0 {
+		return "", fmt.Errorf("invalid --digest-preview %d: use 0 or more characters", config.DigestPreview)
+	}
+	for _, option := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--markdown", config.Markdown},
+		{"--cxml", config.ClaudeXML},
+		{"--html", config.HTML},
+		{"--jsonl", config.JSONL},
+		{"--openai", config.OpenAI},
+		{"--template", config.TemplatePath != ""},
+	} {
+		if option.set {
+			return "", fmt.Errorf("--format %s cannot be combined with %s", format, option.flag)
+		}
+	}
+	return format, nil
+}
+
+// digestPreview returns the number of characters digest lines preview.
+func digestPreview(config config.Config) int {
+	if config.DigestPreview > 0 {
+		return config.DigestPreview
+	}
+	return defaultDigestPreview
+}
+
+// digestEntry is a document of digest-json output.
+type digestEntry struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Language string `json:"language"`
+	Preview  string `json:"preview"`
+}
+
+// digestLine returns the digest line, with its newline, of a document shown as
+// path: size bytes of content summing to sum, language lang, starting with head.
+func digestLine(format Format, path string, size int64, sum [sha256.Size]byte, lang string, head []byte, config config.Config) (string, error) {
+	entry := digestEntry{Path: path, Size: size, SHA256: hex.EncodeToString(sum[:]), Language: lang,
+		Preview: previewOf(head, digestPreview(config))}
+	if format == FormatDigestJSON {
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(entry); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+	fields := []string{entry.Path, strconv.FormatInt(entry.Size, 10), entry.SHA256, entry.Language, entry.Preview}
+	for i, field := range fields {
+		fields[i] = escapeTSV(field)
+	}
+	return strings.Join(fields, "\t") + "\n", nil
+}
+
+// digestDocument returns the digest line of a document of content, in the
+// format of config.
+func digestDocument(path, content, lang string, config config.Config) (string, error) {
+	return digestLine(Format(config.Format), path, int64(len(content)), sha256.Sum256([]byte(content)), lang, []byte(content), config)
+}
+
+// previewOf returns the first n characters of content. An invalid UTF-8 byte
+// counts as a character, and is kept as U+FFFD.
+func previewOf(content []byte, n int) string {
+	var b strings.Builder
+	for i := 0; i < n && len(content) > 0; i++ {
+		r, size := utf8.DecodeRune(content)
+		b.WriteRune(r)
+		content = content[size:]
+	}
+	return b.String()
+}
+
+// escapeTSV escapes field so that it holds no tab and ends no line: a
+// backslash, tab, carriage return and newline become \\, \t, \r and \n, other
+// control characters \xHH, and the Unicode line breaks U+0085, U+2028 and
+// U+2029 \uHHHH. Everything else is kept as it is.
+func escapeTSV(field string) string {
+	var b strings.Builder
+	for _, r := range field {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r == '\u2028' || r == '\u2029' || r == '\u0085':
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// digestRun runs Generate with --format format and the paths of cfg.
+func digestRun(t *testing.T, format Format, cfg config.Config) string {
+	t.Helper()
+	cfg.Format = string(format)
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	return buf.String()
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDigest(t *testing.T) {
+	contents := map[string]string{
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"tabs.tsv":      "a\tb\tc\r\nd\\e\n",
+		"unicode.txt":   "héllo wörld 日本語 🎉\u2028next\x00end",
+		"name\twith.go": "package tabbed\n",
+		"empty.txt":     "",
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, contents)
+	t.Chdir(dir)
+	paths := []string{"empty.txt", "main.go", "name\twith.go", "tabs.tsv", "unicode.txt"}
+
+	for _, stream := range []bool{false, true} {
+		name := "held"
+		if stream {
+			name = "streamed"
+		}
+		t.Run(name, func(t *testing.T) {
+			if stream {
+				// A streamed file is described from its scan, without being read again
+				withStreamThreshold(t, 1)
+			}
+
+			t.Run("tsv", func(t *testing.T) {
+				out := digestRun(t, FormatDigest, config.Config{Paths: paths})
+				lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+				require.Len(t, lines, len(paths))
+				for _, line := range lines {
+					assert.Len(t, strings.Split(line, "\t"), 5, line)
+				}
+				assert.Equal(t, []string{
+					"empty.txt\t0\t" + sha256Hex("") + "\t\t",
+					"main.go\t29\t" + sha256Hex(contents["main.go"]) + "\tgo\tpackage main\\n\\nfunc main() {}\\n",
+					`name\twith.go` + "\t15\t" + sha256Hex(contents["name\twith.go"]) + "\tgo\tpackage tabbed\\n",
+					"tabs.tsv\t11\t" + sha256Hex(contents["tabs.tsv"]) + "\t\t" + `a\tb\tc\r\nd\\e\n`,
+					"unicode.txt\t39\t" + sha256Hex(contents["unicode.txt"]) + "\t\t" + `héllo wörld 日本語 🎉\u2028next\x00end`,
+				}, lines)
+			})
+
+			t.Run("json", func(t *testing.T) {
+				out := digestRun(t, FormatDigestJSON, config.Config{Paths: paths})
+				lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+				require.Len(t, lines, len(paths))
+				for i, line := range lines {
+					var entry digestEntry
+					dec := json.NewDecoder(strings.NewReader(line))
+					dec.DisallowUnknownFields()
+					require.NoError(t, dec.Decode(&entry), line)
+					assert.Equal(t, paths[i], entry.Path)
+					assert.Equal(t, int64(len(contents[paths[i]])), entry.Size)
+					assert.Equal(t, sha256Hex(contents[paths[i]]), entry.SHA256)
+					assert.Equal(t, contents[paths[i]], entry.Preview, "each file is shorter than the preview")
+				}
+			})
+
+			t.Run("preview length", func(t *testing.T) {
+				out := digestRun(t, FormatDigest, config.Config{Paths: []string{"unicode.txt"}, DigestPreview: 14})
+				assert.True(t, strings.HasSuffix(out, "\t\théllo wörld 日本\n"), out)
+			})
+
+			t.Run("line numbers", func(t *testing.T) {
+				// The line describes the document as shown, numbered
+				numbered := "1:package main\n2:\n3:func main() {}\n"
+				out := digestRun(t, FormatDigest, config.Config{Paths: []string{"main.go"}, LineNumbersCompact: true})
+				assert.Equal(t, "main.go\t35\t"+sha256Hex(numbered)+"\tgo\t"+escapeTSV(numbered)+"\n", out)
+			})
+		})
+	}
+
+	t.Run("tree and commands", func(t *testing.T) {
+		out := digestRun(t, FormatDigest, config.Config{Paths: []string{"main.go"}, Tree: true, Commands: []string{"echo hi"}})
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "directory-tree\t8\t"), lines[0])
+		assert.True(t, strings.HasSuffix(lines[2], "\ttext\thi\\n"), lines[2])
+	})
+}
+
+func TestPreviewOf(t *testing.T) {
+	assert.Equal(t, "", previewOf(nil, 5))
+	assert.Equal(t, "日本", previewOf([]byte("日本語"), 2))
+	assert.Equal(t, "a�b", previewOf([]byte("a\xffbc"), 3), "an invalid byte is one character")
+	assert.Equal(t, "abc", previewOf([]byte("abc"), 10))
+}
+
+func TestDigestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"unknown format", config.Config{Format: "csv"}, `invalid --format "csv": use digest or digest-json`},
+		{"negative preview", config.Config{Format: "digest", DigestPreview: -1}, "invalid --digest-preview -1: use 0 or more characters"},
+		{"preview alone", config.Config{DigestPreview: 10}, "--digest-preview requires --format digest or digest-json"},
+		{"with markdown", config.Config{Format: "digest", Markdown: true}, "--format digest cannot be combined with --markdown"},
+		{"with jsonl", config.Config{Format: "digest-json", JSONL: true}, "--format digest-json cannot be combined with --jsonl"},
+		{"with compat", config.Config{Format: "digest", Compat: string(CompatFilesToPrompt)}, "--compat files-to-prompt cannot be combined with --format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"testdata/file1.txt"}
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
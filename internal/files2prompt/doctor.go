@@ -0,0 +1,239 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// binarySniffBytes is how much of a file is inspected for NUL bytes when
+// deciding whether it is binary.
+const binarySniffBytes = 8000
+
+// DoctorOptions tunes the checks run by Doctor.
+type DoctorOptions struct {
+	// LargeFileBytes is the size from which a selected file is reported as large.
+	LargeFileBytes int64
+}
+
+// DefaultDoctorOptions returns the options used by the doctor command.
+func DefaultDoctorOptions() DoctorOptions {
+	return DoctorOptions{LargeFileBytes: 256 << 10}
+}
+
+// Finding is a single potential problem reported by Doctor.
+type Finding struct {
+	// Check is a stable identifier for the kind of problem, e.g. "large-files".
+	Check string
+	// Summary describes the problem in one line.
+	Summary string
+	// Paths lists the affected paths, annotated where useful.
+	Paths []string
+	// Fix names the flags that would address the problem.
+	Fix string
+}
+
+// Doctor audits the files config would select and reports potential problems:
+// extensions with no language mapping, files large enough to dominate the
+// prompt, binaries with text extensions, .gitignore negations that cannot take
+// effect, and symlinks. Findings are returned in a fixed order, with paths in walk order.
+func Doctor(ctx context.Context, config config.Config, opts DoctorOptions) ([]Finding, error) {
+	plan, _, err := planFiles(ctx, config, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmapped, large, binary, gitignore, symlinks []string
+	unmappedExts := map[string]bool{}
+	display := map[string]string{}
+	var total int64
+	for _, f := range plan {
+		display[f.Path] = doctorPath(f)
+		if f.Included {
+			total += f.Size
+		}
+	}
+
+	for _, f := range plan {
+		path := display[f.Path]
+		if !f.IsDir && filepath.Base(f.Path) == ".gitignore" {
+			gitignore = append(gitignore, ineffectiveNegations(f.Path, path)...)
+		}
+		if !f.Included {
+			continue
+		}
+
+		if note, ok := symlinkNote(f); ok {
+			symlinks = append(symlinks, path+" ("+note+")")
+			continue
+		}
+
+		ext := strings.TrimPrefix(filepath.Ext(f.Path), ".")
+		mapped := mappedLanguage(f.Path, config)
+		if !mapped && ext != "" {
+			unmapped = append(unmapped, path)
+			unmappedExts["."+ext] = true
+		}
+		if f.Size >= opts.LargeFileBytes && total > 0 {
+			large = append(large, fmt.Sprintf("%s (%s, %d%% of selected bytes)", path, formatBytes(f.Size), f.Size*100/total))
+		}
+		if mapped && looksBinary(f.Path) {
+			binary = append(binary, path)
+		}
+	}
+
+	var findings []Finding
+	add := func(check, summary, fix string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+		findings = append(findings, Finding{Check: check, Summary: summary, Paths: paths, Fix: fix})
+	}
+	exts := make([]string, 0, len(unmappedExts))
+	for ext := range unmappedExts {
+		exts = append(exts, ext)
+	}
+	slices.Sort(exts)
+
+	add("unmapped-extensions",
+		fmt.Sprintf("%d %s no language mapping (%s)", len(unmapped), plural(len(unmapped), "file has", "files have"), strings.Join(exts, ", ")),
+		"-e/--extension to select only mapped file types; --markdown fences for these files carry no language",
+		unmapped)
+	add("large-files",
+		fmt.Sprintf("%d %s at least %s would dominate the prompt", len(large), plural(len(large), "file", "files"), formatBytes(opts.LargeFileBytes)),
+		"--ignore PATTERN to leave them out, or --read-limit N to cap file size",
+		large)
+	add("binary-files",
+		fmt.Sprintf("%d %s binary", len(binary), plural(len(binary), "file with a text extension looks", "files with text extensions look")),
+		"--ignore PATTERN to leave them out",
+		binary)
+	add("gitignore-negations",
+		fmt.Sprintf("%d .gitignore %s", len(gitignore), plural(len(gitignore),
+			"negation cannot re-include a path inside an excluded directory",
+			"negations cannot re-include paths inside excluded directories")),
+		"exclude the directory's contents (dir/*) instead of the directory itself, as git requires",
+		gitignore)
+	add("symlinks",
+		fmt.Sprintf("%d %s read as regular files", len(symlinks), plural(len(symlinks), "symlink is", "symlinks are")),
+		"--ignore PATTERN to skip them",
+		symlinks)
+	return findings, nil
+}
+
+// WriteDoctorReport renders findings, listing at most maxPaths paths for each.
+func WriteDoctorReport(w io.Writer, findings []Finding, maxPaths int) error {
+	var b strings.Builder
+	for _, f := range findings {
+		fmt.Fprintf(&b, "%s: %s\n", f.Check, f.Summary)
+		for i, path := range f.Paths {
+			if maxPaths > 0 && i == maxPaths {
+				fmt.Fprintf(&b, "  ... and %d more\n", len(f.Paths)-maxPaths)
+				break
+			}
+			fmt.Fprintf(&b, "  %s\n", path)
+		}
+		fmt.Fprintf(&b, "  fix: %s\n\n", f.Fix)
+	}
+	if len(findings) == 0 {
+		b.WriteString("No problems found.\n")
+	} else {
+		fmt.Fprintf(&b, "%d %s found.\n", len(findings), plural(len(findings), "problem", "problems"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// doctorPath returns f's path relative to its root argument, joined back onto
+// the root as typed, so that "." yields paths relative to the working directory.
+func doctorPath(f PlannedFile) string {
+	root := f.Root
+	if root == "." {
+		if wd, err := os.Getwd(); err == nil {
+			root = wd
+		}
+	}
+	rel, err := filepath.Rel(root, f.Path)
+	if err != nil {
+		return f.Path
+	}
+	return filepath.Join(f.Root, rel)
+}
+
+// symlinkNote describes where the symlink f leads; ok is false when f is not a symlink.
+func symlinkNote(f PlannedFile) (note string, ok bool) {
+	info, err := os.Lstat(f.Path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return "", false
+	}
+	target, err := filepath.EvalSymlinks(f.Path)
+	if err != nil {
+		return "broken or cyclic link", true
+	}
+	if info, err := os.Stat(target); err == nil && info.IsDir() {
+		return "links to a directory, which is not walked", true
+	}
+	root, err := filepath.Abs(f.Root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "links to " + target, true
+	}
+	rel, err := filepath.Rel(root, target)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "links outside the tree to " + target, true
+	}
+	return "duplicates " + filepath.Join(f.Root, rel), true
+}
+
+// looksBinary reports whether the start of the file at path contains a NUL byte.
+func looksBinary(path string) bool {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, binarySniffBytes)
+	n, _ := io.ReadFull(f, head)
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
+// ineffectiveNegations lists the negations in the .gitignore at path that
+// cannot take effect because an earlier rule excludes a parent directory of
+// what they re-include, labelled with display and the line number.
+func ineffectiveNegations(path, display string) []string {
+	content, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil
+	}
+	var earlier []gitignoreRule
+	var found []string
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, ok := parseGitignoreRule(".", line)
+		if !ok {
+			continue
+		}
+		if rule.negate && rule.anchored {
+			parts := strings.Split(rule.pattern, "/")
+			for n := 1; n < len(parts); n++ {
+				if dir := strings.Join(parts[:n], "/"); shouldIgnore(dir, true, earlier) {
+					found = append(found, fmt.Sprintf("%s:%d: %s (%s/ is excluded)", display, i+1, line, dir))
+					break
+				}
+			}
+		}
+		earlier = append(earlier, rule)
+	}
+	return found
+}
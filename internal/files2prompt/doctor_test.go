@@ -0,0 +1,78 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// messyTree copies the doctor fixture into a temporary directory, adds the
+// symlinks git cannot portably store, and changes into it.
+func messyTree(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.CopyFS(dir, os.DirFS("testdata/doctor")))
+	require.NoError(t, os.Symlink("loop", filepath.Join(dir, "loop")))
+	require.NoError(t, os.Symlink("src", filepath.Join(dir, "docs")))
+	require.NoError(t, os.Symlink("main.go", filepath.Join(dir, "src", "link.go")))
+	t.Chdir(dir)
+}
+
+func TestDoctorReport(t *testing.T) {
+	messyTree(t)
+
+	findings, err := Doctor(context.Background(), config.Config{Paths: []string{"."}}, DoctorOptions{LargeFileBytes: 1024})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDoctorReport(&buf, findings, 10))
+	assert.Equal(t, `unmapped-extensions: 2 files have no language mapping (.dat, .tsv)
+  assets/dump.dat
+  src/table.tsv
+  fix: -e/--extension to select only mapped file types; --markdown fences for these files carry no language
+
+large-files: 1 file at least 1.0 KiB would dominate the prompt
+  assets/dump.dat (1.2 KiB, 94% of selected bytes)
+  fix: --ignore PATTERN to leave them out, or --read-limit N to cap file size
+
+binary-files: 1 file with a text extension looks binary
+  assets/blob.json
+  fix: --ignore PATTERN to leave them out
+
+gitignore-negations: 1 .gitignore negation cannot re-include a path inside an excluded directory
+  .gitignore:7: !dist/config.example.json (dist/ is excluded)
+  fix: exclude the directory's contents (dir/*) instead of the directory itself, as git requires
+
+symlinks: 1 symlink is read as regular files
+  src/link.go (duplicates src/main.go)
+  fix: --ignore PATTERN to skip them
+
+5 problems found.
+`, buf.String())
+}
+
+func TestDoctorRespectsFilters(t *testing.T) {
+	messyTree(t)
+
+	cfg := config.Config{Paths: []string{"src"}, Extensions: []string{".go"}, IgnorePatterns: []string{"link.go"}}
+	findings, err := Doctor(context.Background(), cfg, DefaultDoctorOptions())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDoctorReport(&buf, findings, 10))
+	assert.Equal(t, "No problems found.\n", buf.String())
+}
+
+func TestWriteDoctorReportCapsPaths(t *testing.T) {
+	findings := []Finding{{Check: "large-files", Summary: "3 files", Paths: []string{"a", "b", "c"}, Fix: "--ignore"}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDoctorReport(&buf, findings, 2))
+	assert.Equal(t, "large-files: 3 files\n  a\n  b\n  ... and 1 more\n  fix: --ignore\n\n1 problem found.\n", buf.String())
+}
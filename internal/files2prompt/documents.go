@@ -0,0 +1,402 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// FileDoc is a file document, as yielded by Documents.
+type FileDoc struct {
+	// File is the planned file the document was produced from.
+	File PlannedFile
+	// Lang is the language labelling the document, or "" when it is unknown.
+	Lang string
+	// Content is the text of the file shown by the document, after
+	// --grep-context, --squash-data-blocks and the like, without line numbers.
+	Content string
+	// Rendered is the output written for the document in the format selected
+	// by config, including the opening of the submodule section it starts.
+	Rendered string
+	// Streamed reports that the file was too large to hold in memory, and was
+	// rendered whole, straight from disk. Content and Rendered are then empty;
+	// the content is that of the file at File.Path.
+	Streamed bool
+	// Stats describes the content of the document.
+	Stats FileStats
+}
+
+// Documents yields the file documents selected by config in emission order,
+// rendering each as Generate would; the output wrapper, the tree and --cmd
+// documents are left out. plan is used as by Generate.
+//
+// Files are read ahead of the consumer only a few per worker at a time, so a
+// slow consumer throttles the reads, and stopping early releases the workers. A
+// failure is yielded once with a zero FileDoc, ending the sequence.
+func Documents(ctx context.Context, config config.Config, plan []PlannedFile) iter.Seq2[FileDoc, error] {
+	return func(yield func(FileDoc, error) bool) {
+		g, err := newGenerator(ctx, config, io.Discard, plan, nil)
+		if err != nil {
+			yield(FileDoc{}, err)
+			return
+		}
+		g.render = true
+		g.groupSubmodules()
+		for doc, err := range g.documents(ctx) {
+			if !yield(doc, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// generator is a run of Generate or Documents, planned and ready to emit.
+type generator struct {
+	config config.Config
+	plan   []PlannedFile
+	roots  []string
+	// mon is the long-run notice, watching the walk when the generator did it
+	mon *longRunMonitor
+	// progress reports the emission of the plan for --progress, or is nil
+	progress *progressReport
+	// capture holds what was written to writer since the last document, when
+	// render asks for the rendered documents
+	capture *captureWriter
+	render  bool
+	// limit is the --max-tokens cap, or nil
+	limit *tokenLimit
+	// split divides the output into chunks, or is nil
+	split *splitter
+	// prompt is the text of --prefix and --suffix
+	prompt  promptText
+	writer  *ledger
+	state   *emitState
+	workers int
+}
+
+// newGenerator validates config and, when plan is nil, walks the input paths.
+func newGenerator(ctx context.Context, config config.Config, w io.Writer, plan []PlannedFile, mon *longRunMonitor) (*generator, error) {
+	g := &generator{config: config, plan: plan, roots: planRoots(plan), mon: mon}
+	// Templates are loaded before the walk, so that a mistake in one is found at once
+	templates, err := newTemplateOutput(config)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := newPromptText(config)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		if g.plan, g.roots, err = planFiles(ctx, config, mon, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// --tokens counts with the BPE estimate rather than by bytes
+	g.capture = &captureWriter{w: w}
+	writer, err := newLedger(g.capture, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := statsFormat(config); err != nil {
+		return nil, err
+	}
+	if _, err := cxmlSchema(config); err != nil {
+		return nil, err
+	}
+	if _, err := markdownStyle(config); err != nil {
+		return nil, err
+	}
+	if err := jsonlOptions(config); err != nil {
+		return nil, err
+	}
+	if err := openaiOptions(config); err != nil {
+		return nil, err
+	}
+	if err := htmlOptions(config); err != nil {
+		return nil, err
+	}
+	if _, err := outputFormat(config); err != nil {
+		return nil, err
+	}
+	if g.workers, err = concurrency(config); err != nil {
+		return nil, err
+	}
+	throttle, err := newReadThrottle(config)
+	if err != nil {
+		return nil, err
+	}
+	g.writer = writer
+	g.limit = newTokenLimit(config)
+	if g.split, err = newSplitter(config, w); err != nil {
+		return nil, err
+	}
+	g.state = newEmitState()
+	g.state.ledger = writer
+	if g.state.openai, err = newOpenAIMessages(config); err != nil {
+		return nil, err
+	}
+	g.state.templates = templates
+	g.state.throttle = throttle
+	g.prompt = prompt
+	if g.state.anon, err = newAnonymizer(g.plan, config); err != nil {
+		return nil, err
+	}
+	if g.state.redact, err = newRedactor(config); err != nil {
+		return nil, err
+	}
+	g.state.view = captureView(g.plan, g.workers, config)
+	if g.state.anon != nil || g.limit != nil {
+		// The caller's plan keeps the original display paths, and the files
+		// --max-tokens leaves out
+		g.plan = slices.Clone(g.plan)
+	}
+	if g.state.anon != nil {
+		for i, f := range g.plan {
+			g.plan[i].DisplayPath = g.state.anon.path(f.DisplayPath, !f.IsDir)
+		}
+	}
+	if g.state.grep, err = compileGrep(config); err != nil {
+		return nil, err
+	}
+	if config.Reproducible {
+		if g.state.timestamp, err = hostEnv.sourceDate("."); err != nil {
+			return nil, err
+		}
+		log.Debugf("Reproducible output pinned to %s", g.state.timestamp.Format(time.RFC3339))
+	}
+	return g, nil
+}
+
+// separate reports whether submodules are emitted in sections of their own.
+func (g *generator) separate() bool {
+	return g.config.Submodules == string(SubmodulesSeparate)
+}
+
+// groupSubmodules orders the plan for emission, grouping each submodule's files
+// with --submodules separate.
+func (g *generator) groupSubmodules() {
+	if g.separate() {
+		g.plan = groupSubmodules(g.plan)
+	}
+}
+
+// hasDocuments reports whether the plan includes any file.
+func (g *generator) hasDocuments() bool {
+	return slices.ContainsFunc(g.plan, func(f PlannedFile) bool { return f.Included })
+}
+
+// documents renders the included files of the plan to the generator's writer in
+// order, yielding each document once it is written. It is the only place files
+// become documents.
+func (g *generator) documents(ctx context.Context) iter.Seq2[FileDoc, error] {
+	return func(yield func(FileDoc, error) bool) {
+		// Files are read concurrently, but rendered one at a time in plan order
+		var paths []string
+		for _, f := range g.plan {
+			if f.Included && !f.stub() {
+				paths = append(paths, f.Path)
+			}
+		}
+		reads := newReadAhead(paths, g.workers, readLimit(g.config), streamAbove(g.config), zeroSizeLimit(g.config), g.state.throttle)
+		defer reads.close()
+		defer g.capture.stop()
+
+		defer g.limit.warn()
+
+		section := ""
+		for i, f := range g.plan {
+			if f.Included && g.limit.spent() {
+				g.limit.omit(&g.plan[i])
+				continue
+			}
+			warnSkipped(f, g.config)
+			if !f.Included {
+				continue
+			}
+			g.progress.add(f.Size)
+			if err := ctx.Err(); err != nil {
+				yield(FileDoc{}, err)
+				return
+			}
+			var read fileRead
+			if !f.stub() {
+				read = reads.next()
+			}
+			// Streamed files are never held in memory, rendered or not
+			g.capture.keep = g.render && read.scan == nil
+			g.capture.reset()
+			if g.separate() && f.Submodule != section {
+				if err := writeSubmoduleSection(g.writer, g.config, g.state.anon.path(section, false), g.state.anon.path(f.Submodule, false)); err != nil {
+					yield(FileDoc{}, err)
+					return
+				}
+				section = f.Submodule
+			}
+			before, content, files := g.writer.used(), g.writer.content, g.state.files
+			emit := func() error { return emitFile(f, read, g.config, g.writer, g.state) }
+			// With --max-tokens the document is held back until it is known to
+			// fit, and with --split-tokens until its chunk is known. A streamed
+			// one is measured instead, then written once that is known
+			measured := read.scan != nil && (g.limit != nil || g.split != nil)
+			if measured {
+				used, size, ok, err := g.measure(emit)
+				if err != nil {
+					yield(FileDoc{}, err)
+					return
+				}
+				if !ok {
+					continue
+				}
+				if g.limit.exceeded(used) {
+					if err := g.limit.overflow(&g.plan[i], used); err != nil {
+						yield(FileDoc{}, err)
+						return
+					}
+					continue
+				}
+				if err := g.advance(size); err != nil {
+					yield(FileDoc{}, err)
+					return
+				}
+			}
+			writer, state := *g.writer, *g.state
+			g.capture.hold = !measured && (g.limit != nil || g.split != nil)
+			if err := emit(); err != nil {
+				yield(FileDoc{}, err)
+				return
+			}
+			if used := g.writer.used().tokens; !measured && g.limit.exceeded(used) {
+				*g.writer, *g.state = writer, state
+				g.capture.discard()
+				if err := g.limit.overflow(&g.plan[i], used); err != nil {
+					yield(FileDoc{}, err)
+					return
+				}
+				continue
+			}
+			if err := g.commit(writer, state, emit); err != nil {
+				yield(FileDoc{}, err)
+				return
+			}
+			if g.config.CountTokens {
+				log.Debugf("%8d tokens  %s", g.writer.used().tokens-before.tokens, f.DisplayPath)
+			}
+			if err := g.mon.emit(g.writer.used().bytes); err != nil {
+				yield(FileDoc{}, err)
+				return
+			}
+			if g.state.files == files {
+				// Skipped when read, as unreadable or not valid UTF-8 in compat mode
+				continue
+			}
+			emitted := g.writer.contentSince(content)
+			doc := FileDoc{
+				File:     f,
+				Lang:     g.state.lang,
+				Content:  g.state.emitted,
+				Rendered: g.capture.String(),
+				Streamed: g.state.streamed,
+				Stats:    FileStats{Path: f.DisplayPath, Bytes: emitted.bytes, Lines: emitted.lines, Tokens: emitted.tokens},
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+		if section != "" {
+			if err := writeSubmoduleSection(g.writer, g.config, g.state.anon.path(section, false), ""); err != nil {
+				yield(FileDoc{}, err)
+			}
+		}
+	}
+}
+
+// measure renders the document emit writes without writing it, and returns the
+// tokens used once it is written, as --max-tokens counts them, and its rendered
+// size. ok is false when the file was skipped when read. The writer and state
+// are left as they were, for emit to render the document again.
+func (g *generator) measure(emit func() error) (used int64, size usage, ok bool, err error) {
+	writer, state := *g.writer, *g.state
+	g.capture.drop = true
+	err = emit()
+	g.capture.drop = false
+	used, ok = g.writer.used().tokens, g.state.files != state.files
+	size = usage{bytes: g.writer.rendered.bytes - writer.rendered.bytes, tokens: g.writer.rendered.tokens - writer.rendered.tokens}
+	*g.writer, *g.state = writer, state
+	return used, size, ok, err
+}
+
+// captureWriter writes to w, keeping a copy of what was written since the last
+// reset while keep is set. While hold is set, writes are held back until they
+// are released or discarded, and while drop is set they are written nowhere.
+type captureWriter struct {
+	w    io.Writer
+	keep bool
+	buf  bytes.Buffer
+	hold bool
+	held bytes.Buffer
+	drop bool
+	// wrote is set once anything is written, and newlines counts the newlines
+	// it all ends in
+	wrote    bool
+	newlines int
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.drop {
+		return len(p), nil
+	}
+	if c.hold {
+		return c.held.Write(p)
+	}
+	n, err := c.w.Write(p)
+	if c.keep {
+		c.buf.Write(p[:n])
+	}
+	if n > 0 {
+		c.wrote = true
+		trimmed := bytes.TrimRight(p[:n], "\n")
+		if len(trimmed) > 0 {
+			c.newlines = 0
+		}
+		c.newlines += n - len(trimmed)
+	}
+	return n, err
+}
+
+func (c *captureWriter) reset() {
+	c.buf.Reset()
+}
+
+// release writes what was held back, and holds no more.
+func (c *captureWriter) release() error {
+	if !c.hold {
+		return nil
+	}
+	c.hold = false
+	_, err := c.Write(c.held.Bytes())
+	c.held.Reset()
+	return err
+}
+
+// discard drops what was held back, and holds no more.
+func (c *captureWriter) discard() {
+	c.hold = false
+	c.held.Reset()
+}
+
+// stop discards the copy and keeps no more.
+func (c *captureWriter) stop() {
+	c.keep = false
+	c.buf.Reset()
+}
+
+func (c *captureWriter) String() string {
+	return c.buf.String()
+}
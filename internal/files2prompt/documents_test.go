@@ -0,0 +1,140 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestDocumentsMatchGenerate(t *testing.T) {
+	root := t.TempDir()
+	writeSyntheticTree(t, root, 40)
+	writeFiles(t, root, map[string]string{"notes.md": "# notes\n", "unnamed": "#!/usr/bin/env python3\n"})
+
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		header, tail string
+	}{
+		{name: "plain", cfg: config.Config{}},
+		{name: "markdown with line numbers", cfg: config.Config{Markdown: true, LineNumbers: true, DetectLang: true}, header: "# Files\n\n"},
+		{name: "claude xml", cfg: config.Config{ClaudeXML: true}, header: "<documents>\n", tail: "</documents>\n"},
+		{name: "compat", cfg: config.Config{Compat: string(CompatFilesToPrompt)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Paths = []string{root}
+			var out bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &out, nil)
+			require.NoError(t, err)
+
+			var rendered strings.Builder
+			n := 0
+			for doc, err := range Documents(context.Background(), cfg, nil) {
+				require.NoError(t, err)
+				rendered.WriteString(doc.Rendered)
+				n++
+			}
+			assert.Equal(t, summary.Files, n)
+			assert.Equal(t, out.String(), tt.header+rendered.String()+tt.tail)
+		})
+	}
+}
+
+func TestDocumentsFields(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.py":    "print('a')\n\nprint('b')\n",
+		"run":     "#!/bin/sh\necho run",
+		"skip.md": "# skipped\n",
+	})
+	cfg := config.Config{Paths: []string{root}, Markdown: true, DetectLang: true, Grep: "'b'|echo", GrepContext: 0}
+
+	var docs []FileDoc
+	for doc, err := range Documents(context.Background(), cfg, nil) {
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+	require.Len(t, docs, 2)
+
+	assert.Equal(t, filepath.Join(root, "a.py"), docs[0].File.Path)
+	assert.Equal(t, "python", docs[0].Lang)
+	assert.Equal(t, "...\nprint('b')\n", docs[0].Content)
+	assert.Contains(t, docs[0].Rendered, "```python\n...\n 3 │ print('b')\n```\n")
+	assert.Equal(t, FileStats{Path: filepath.Join(root, "a.py"), Bytes: 11, Lines: 1, Tokens: 3}, docs[0].Stats)
+
+	assert.Equal(t, "bash", docs[1].Lang)
+	assert.Equal(t, "...\necho run", docs[1].Content)
+}
+
+// openFiles returns the number of file descriptors the process has open, or
+// skips the test where that cannot be told.
+func openFiles(t *testing.T) int {
+	t.Helper()
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files are only counted through /proc")
+	}
+	return len(entries)
+}
+
+func TestDocumentsEarlyBreak(t *testing.T) {
+	root := t.TempDir()
+	writeSyntheticTree(t, root, 500)
+	cfg := config.Config{Paths: []string{root}, Concurrency: 8}
+	plan, err := Plan(context.Background(), cfg, false)
+	require.NoError(t, err)
+
+	goroutines, files := runtime.NumGoroutine(), openFiles(t)
+	n := 0
+	for doc, err := range Documents(context.Background(), cfg, plan) {
+		require.NoError(t, err)
+		assert.NotEmpty(t, doc.Rendered)
+		if n++; n == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, n)
+
+	// The reads in progress when the consumer stopped finish in the background.
+	// require.Eventually would count its own goroutines.
+	deadline := time.Now().Add(5 * time.Second)
+	for (runtime.NumGoroutine() > goroutines || openFiles(t) > files) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "workers left behind after an early break")
+	assert.LessOrEqual(t, openFiles(t), files, "files left open after an early break")
+}
+
+func TestDocumentsErrors(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n"})
+
+	var errs []error
+	for doc, err := range Documents(context.Background(), config.Config{Paths: []string{root}, Concurrency: -1}, nil) {
+		assert.Equal(t, FileDoc{}, doc)
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "invalid --concurrency -1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs = nil
+	for _, err := range Documents(ctx, config.Config{Paths: []string{root}}, nil) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+}
@@ -0,0 +1,107 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// envContextSource is the display path of the --env-context document.
+const envContextSource = "environment-context"
+
+// envContext returns the content of the --env-context document for plan: the
+// platform, the Go version the module in the working directory asks for and
+// the toolchain installed, the output of each --env-context-cmd command, and
+// how deep the included files lie below their path arguments. Environment
+// variables are never read into it, so that no secret they hold can leak.
+func envContext(ctx context.Context, plan []PlannedFile, config config.Config) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "os: %s\narch: %s\n", hostEnv.goos, runtime.GOARCH)
+
+	if goMod, version := goModVersion("."); version != "" {
+		fmt.Fprintf(&b, "go (%s): %s\n", goMod, version)
+		var out bytes.Buffer
+		if _, err := hostEnv.lookPath("go"); err == nil && hostEnv.runCommand(ctx, "go version", &out) == nil {
+			writeContextLine(&b, "go version", out.String())
+		}
+	}
+	for _, command := range config.EnvContextCmds {
+		// A failure is recorded in the output rather than failing the run
+		content, _, _ := hostEnv.commandOutput(ctx, command, config)
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		writeContextLine(&b, command, content)
+	}
+
+	depth := 0
+	for _, f := range plan {
+		if f.Included {
+			depth = max(depth, strings.Count(filepath.ToSlash(relativeToRoot(f)), "/"))
+		}
+	}
+	fmt.Fprintf(&b, "directory depth: %d\n", depth)
+	return b.String(), nil
+}
+
+// writeContextLine writes the line labelled label, giving output on the same
+// line when it is a single line and indented below it otherwise.
+func writeContextLine(w io.Writer, label, output string) {
+	output = strings.TrimRight(output, "\n")
+	if !strings.Contains(output, "\n") {
+		fmt.Fprintf(w, "%s: %s\n", label, output)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n  %s\n", label, strings.ReplaceAll(output, "\n", "\n  "))
+}
+
+// goModVersion returns the path of the nearest go.mod in dir or its parents,
+// relative to dir, and the Go version its go directive names, with the
+// toolchain it asks for, if any. The version is "" when there is no go.mod or
+// it names none.
+func goModVersion(dir string) (string, string) {
+	start := absPath(dir)
+	for dir := start; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, "go.mod")
+		if content, err := os.ReadFile(path); err == nil { // #nosec G304
+			var version, toolchain string
+			for _, line := range strings.Split(string(content), "\n") {
+				fields := strings.Fields(line)
+				switch {
+				case len(fields) == 2 && fields[0] == "go":
+					version = fields[1]
+				case len(fields) == 2 && fields[0] == "toolchain":
+					toolchain = fields[1]
+				}
+			}
+			if version != "" && toolchain != "" {
+				version += " (toolchain " + toolchain + ")"
+			}
+			rel, _ := filepath.Rel(start, path)
+			return filepath.ToSlash(rel), version
+		}
+		if filepath.Dir(dir) == dir {
+			return "", ""
+		}
+	}
+}
+
+// writeEnvContext writes the --env-context document, first in the output.
+func writeEnvContext(ctx context.Context, w io.Writer, plan []PlannedFile, config config.Config, state *emitState) error {
+	content, err := envContext(ctx, plan, config)
+	if err != nil {
+		return err
+	}
+	// --grep selects files; the context is always emitted whole
+	grep := state.grep
+	state.grep = nil
+	defer func() { state.grep = grep }()
+	return emitDocument(envContextSource, state.redact.content(envContextSource, content), "text", config, w, state)
+}
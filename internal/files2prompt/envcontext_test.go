@@ -0,0 +1,114 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// withEnvContextHost makes the host a Linux machine with a Go toolchain whose
+// commands are answered by contextRunner, for the rest of the test.
+func withEnvContextHost(t *testing.T) {
+	t.Helper()
+	original := hostEnv
+	hostEnv.goos = "linux"
+	hostEnv.lookPath = fakeLookPath("go")
+	hostEnv.runCommand = contextRunner
+	t.Cleanup(func() { hostEnv = original })
+}
+
+func contextRunner(ctx context.Context, command string, out io.Writer) error {
+	switch command {
+	case "go version":
+		_, _ = io.WriteString(out, "go version go1.26.1 linux/amd64\n")
+	case "node --version":
+		_, _ = io.WriteString(out, "v20.11.0\n")
+	case "tools":
+		_, _ = io.WriteString(out, "make 4.4\ncmake 3.28\n")
+	case "broken":
+		_, _ = io.WriteString(out, "broken: not found\n")
+		return exitError(127)
+	default:
+		return errors.New("unknown command")
+	}
+	return nil
+}
+
+func TestGenerateEnvContext(t *testing.T) {
+	withEnvContextHost(t)
+	t.Setenv("API_TOKEN", "s3cr3t")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.26\n\ntoolchain go1.26.1\n",
+		"main.go":         "package main\n",
+		"internal/a/b.go": "package a\n",
+	})
+	t.Chdir(dir)
+
+	facts := "os: linux\narch: " + runtime.GOARCH + "\n" +
+		"go (go.mod): 1.26 (toolchain go1.26.1)\n" +
+		"go version: go version go1.26.1 linux/amd64\n" +
+		"node --version: v20.11.0\n" +
+		"tools:\n  make 4.4\n  cmake 3.28\n" +
+		"broken:\n  broken: not found\n  [exit status 127]\n" +
+		"directory depth: 1\n"
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name: "plain, first",
+			config: config.Config{
+				Paths:          []string{"main.go", "internal"},
+				EnvContextCmds: []string{"node --version", "tools", "broken"},
+			},
+			expected: "environment-context\n---\n" + facts + "---\n\n" +
+				filepath.Join("internal", "a", "b.go") + "\n---\npackage a\n---\n\n" +
+				"main.go\n---\npackage main\n---\n\n",
+		},
+		{
+			name:   "claude xml, before the tree",
+			config: config.Config{Paths: []string{"main.go"}, EnvContext: true, ClaudeXML: true, Tree: true},
+			expected: "<documents>\n" +
+				"<document index=\"1\">\n<source>environment-context</source>\n<document_content>\nos: linux\narch: " + runtime.GOARCH + "\n" +
+				"go (go.mod): 1.26 (toolchain go1.26.1)\ngo version: go version go1.26.1 linux/amd64\ndirectory depth: 0\n</document_content>\n</document>\n" +
+				"<document index=\"2\">\n<source>directory-tree</source>\n<document_content>\nmain.go\n</document_content>\n</document>\n" +
+				"<document index=\"3\">\n<source>main.go</source>\n<document_content>\npackage main\n</document_content>\n</document>\n" +
+				"</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), tt.config, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+			assert.NotContains(t, buf.String(), "s3cr3t")
+			assert.NotContains(t, buf.String(), "API_TOKEN")
+		})
+	}
+}
+
+func TestGoModVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"go.mod": "module example.com/app\n\ngo 1.25\n", "sub/x.go": "package sub\n"})
+
+	path, version := goModVersion(filepath.Join(dir, "sub"))
+	assert.Equal(t, "../go.mod", path)
+	assert.Equal(t, "1.25", version)
+
+	writeFiles(t, dir, map[string]string{"sub/go.mod": "module example.com/sub\n"})
+	path, version = goModVersion(filepath.Join(dir, "sub"))
+	assert.Equal(t, "go.mod", path)
+	assert.Empty(t, version)
+}
@@ -0,0 +1,75 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ExitCodeHookFailed is the process exit code used when the --exec hook fails,
+// distinguishing hook failures from errors in files2prompt itself (exit code 1).
+const ExitCodeHookFailed = 3
+
+// HookError reports that the post-generation --exec command failed.
+type HookError struct {
+	Command  string
+	ExitCode int
+	Err      error
+}
+
+func (e *HookError) Error() string {
+	if e.ExitCode > 0 {
+		return fmt.Sprintf("exec hook %q exited with status %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("exec hook %q failed: %v", e.Command, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// shellQuote quotes s so the platform shell passes it through as a single argument.
+func (e osEnv) shellQuote(s string) string {
+	if e.goos == "windows" {
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// expandExecCommand replaces every {} in command with the quoted output path.
+func (e osEnv) expandExecCommand(command, outputPath string) string {
+	return strings.ReplaceAll(command, "{}", e.shellQuote(outputPath))
+}
+
+// runExecHook runs command through the platform shell once output has been written
+// to outputPath, waiting for it to finish. Run metadata is passed in the environment
+// as F2P_OUTPUT, F2P_FILES, F2P_BYTES and F2P_TOKENS.
+func (e osEnv) runExecHook(command, outputPath string, summary Summary) error {
+	expanded := e.expandExecCommand(command, outputPath)
+	shell, flag := "sh", "-c"
+	if e.goos == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	hook := exec.Command(shell, flag, expanded) // #nosec G204
+	hook.Stdout = os.Stderr
+	hook.Stderr = os.Stderr
+	hook.Env = append(os.Environ(),
+		"F2P_OUTPUT="+outputPath,
+		"F2P_FILES="+strconv.Itoa(summary.Files),
+		"F2P_BYTES="+strconv.FormatInt(summary.Bytes, 10),
+		"F2P_TOKENS="+strconv.FormatInt(summary.Tokens, 10),
+	)
+	if err := hook.Run(); err != nil {
+		hookErr := &HookError{Command: expanded, Err: err}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			hookErr.ExitCode = exitErr.ExitCode()
+		}
+		return hookErr
+	}
+	return nil
+}
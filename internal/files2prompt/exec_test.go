@@ -0,0 +1,92 @@
+package files2prompt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestExpandExecCommand(t *testing.T) {
+	unix := osEnv{goos: "linux"}
+	windows := osEnv{goos: "windows"}
+
+	assert.Equal(t, "cat '/tmp/out.txt'", unix.expandExecCommand("cat {}", "/tmp/out.txt"))
+	assert.Equal(t, "cp '/tmp/my out.txt' '/tmp/my out.txt'.bak", unix.expandExecCommand("cp {} {}.bak", "/tmp/my out.txt"))
+	assert.Equal(t, `cat '/tmp/it'\''s.txt'`, unix.expandExecCommand("cat {}", "/tmp/it's.txt"))
+	assert.Equal(t, "echo done", unix.expandExecCommand("echo done", "/tmp/out.txt"))
+	assert.Equal(t, `type "C:\My Files\out.txt"`, windows.expandExecCommand("type {}", `C:\My Files\out.txt`))
+}
+
+func withStdout(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	originalStdout := osStdout
+	osStdout = &buf
+	t.Cleanup(func() { osStdout = originalStdout })
+	return &buf
+}
+
+func TestRunExecHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec hook tests use a POSIX shell")
+	}
+
+	t.Run("output file path with spaces and metadata", func(t *testing.T) {
+		dir := t.TempDir()
+		output := filepath.Join(dir, "prompt output.txt")
+		result := filepath.Join(dir, "result.txt")
+		withStdout(t)
+
+		summary, err := Run(config.Config{
+			Paths:      []string{"testdata/test_project/src/main.go"},
+			OutputFile: output,
+			Exec:       `{ cat {}; printf '%s|%s|%s|%s' "$F2P_OUTPUT" "$F2P_FILES" "$F2P_BYTES" "$F2P_TOKENS"; } > '` + result + `'`,
+		})
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(result)
+		require.NoError(t, err)
+		expected := "testdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n" +
+			output + "|1|72|18"
+		assert.Equal(t, expected, string(content))
+		assert.Equal(t, Summary{Files: 1, Bytes: 72, Tokens: 18, Scope: BudgetRendered}, summary)
+	})
+
+	t.Run("stdout uses a temporary file", func(t *testing.T) {
+		result := filepath.Join(t.TempDir(), "copy.txt")
+		buf := withStdout(t)
+
+		_, err := Run(config.Config{
+			Paths: []string{"testdata/test_project/src/main.go"},
+			Exec:  "cp {} '" + result + "' && echo {} > '" + result + ".path'",
+		})
+		require.NoError(t, err)
+
+		copied, err := os.ReadFile(result)
+		require.NoError(t, err)
+		assert.Equal(t, buf.String(), string(copied))
+
+		tempPath, err := os.ReadFile(result + ".path")
+		require.NoError(t, err)
+		_, err = os.Stat(string(bytes.TrimSpace(tempPath)))
+		assert.True(t, os.IsNotExist(err), "temporary file should be removed after the hook")
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		withStdout(t)
+		_, err := Run(config.Config{
+			Paths: []string{"testdata/test_project/src/main.go"},
+			Exec:  "exit 7",
+		})
+		var hookErr *HookError
+		require.ErrorAs(t, err, &hookErr)
+		assert.Equal(t, 7, hookErr.ExitCode)
+		assert.Equal(t, `exec hook "exit 7" exited with status 7`, err.Error())
+	})
+}
@@ -3,311 +3,822 @@
 package files2prompt
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
-	"github.com/bmatcuk/doublestar/v4"
 	log "github.com/sirupsen/logrus"
 	"github.com/toozej/files2prompt/pkg/config"
 )
 
+// outputBufferSize is the size of the buffer in front of the output destination.
+const outputBufferSize = 64 << 10
+
 // Standard OS functions
 var (
 	osStdout io.Writer = os.Stdout
+	osStderr io.Writer = os.Stderr
 )
 
-var extToLang = map[string]string{
-	"py":   "python",
-	"c":    "c",
-	"cpp":  "cpp",
-	"java": "java",
-	"js":   "javascript",
-	"ts":   "typescript",
-	"html": "html",
-	"css":  "css",
-	"xml":  "xml",
-	"json": "json",
-	"yaml": "yaml",
-	"yml":  "yaml",
-	"sh":   "bash",
-	"rb":   "ruby",
-	"go":   "go",
+func getBackticks(content string) string {
+	return fence('`', longestRun(content, '`'))
 }
 
-func getBackticks(content string) string {
-	backticks := "```"
-	for strings.Contains(content, backticks) {
-		backticks += "`"
+// getSeparator returns the plain-format document separator for content,
+// lengthening "---" until it is longer than every line of the content made of
+// dashes only, the lines that could be taken for it, so that the end of the
+// document stays unambiguous. Dashes within other lines, as in Markdown
+// tables, do not count.
+func getSeparator(content string) string {
+	longest := 0
+	for line := range strings.Lines(content) {
+		longest = max(longest, dashLine(line))
 	}
-	return backticks
+	return fence('-', longest)
 }
 
-func readGitignore(path string) []string {
-	gitignorePath := filepath.Join(path, ".gitignore")
-	content, err := os.ReadFile(gitignorePath) // #nosec G304
-	if err != nil {
-		return nil // Return nil for non-existent files to distinguish from empty files
+// dashLine returns the number of dashes of line when it is made of dashes
+// only, ignoring its line break and carriage returns, and 0 otherwise.
+func dashLine(line string) int {
+	n := 0
+	for i := range len(line) {
+		switch line[i] {
+		case '-':
+			n++
+		case '\r', '\n':
+		default:
+			return 0
+		}
 	}
+	return n
+}
 
-	var rules []string
-	for _, line := range strings.Split(string(content), "\n") {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			rules = append(rules, line)
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := range len(s) {
+		if s[i] != c {
+			run = 0
+			continue
 		}
+		run++
+		longest = max(longest, run)
 	}
-	return rules
+	return longest
 }
 
-func shouldIgnore(path string, gitignoreRules []string) bool {
-	base := filepath.Base(path)
-	for _, rule := range gitignoreRules {
-		// Match against base name
-		matchedBase, _ := doublestar.Match(rule, base)
-		if matchedBase {
-			return true
-		}
+// emitState carries per-run counters across processFile calls.
+type emitState struct {
+	// index is the next Claude XML document index
+	index int
+	// files counts the files emitted so far
+	files int
+	// gutterBytes counts the bytes spent on line-number gutters
+	gutterBytes int64
+	// grep is the compiled --grep pattern, or nil
+	grep *regexp.Regexp
+	// timestamp is the time written wherever output embeds one; with
+	// --reproducible it is the source date rather than the current time
+	timestamp time.Time
+	// ledger is told about the content of each document, or nil
+	ledger *ledger
+	// anon anonymizes file contents with --anonymize, or is nil
+	anon *anonymizer
+	// redact redacts the secrets in file contents with --redact, or is nil
+	redact *redactor
+	// view is the --stable-view capture, or nil. modified is set while the
+	// document of a file that changed since is rendered, and drifted lists
+	// such files.
+	view     stableView
+	modified bool
+	drifted  []string
+	// meta is, with --metadata, what the document being rendered tells of its
+	// file, or nil
+	meta *fileMeta
+	// diffSummary is set, while the document of a --hunks-only file with hunks
+	// is rendered, to the summary its header carries
+	diffSummary string
+	// unreadable lists the planned files that could not be read
+	unreadable []string
+	// truncated lists the --cmd documents whose output was cut short
+	truncated []string
+	// emitted is the content of the latest document without its gutter, and
+	// lang the language labelling it. streamed is set instead of emitted when
+	// the document was streamed from disk.
+	emitted  string
+	lang     string
+	streamed bool
+	// openai writes the messages of --openai output, or is nil
+	openai *openaiMessages
+	// templates renders --template output, or is nil
+	templates *templateOutput
+	// throttle is what files are read through with --throttle, or nil
+	throttle *readThrottle
+	// documentIndexes holds the index of the document of each file emitted
+	// in full, by Path, for the stubs of its symlinked duplicates
+	documentIndexes map[string]int
+}
 
-		// Match against full path
-		matchedPath, _ := doublestar.Match(rule, path)
-		if matchedPath {
-			return true
-		}
+func newEmitState() *emitState {
+	return &emitState{index: 1, timestamp: time.Now(), documentIndexes: map[string]int{}}
+}
 
-		// Handle directory-specific patterns
-		if strings.HasSuffix(rule, "/") {
-			// Remove trailing slash for matching
-			ruleWithoutSlash := strings.TrimSuffix(rule, "/")
-			matchedSlash, _ := doublestar.Match(ruleWithoutSlash, base)
-			if matchedSlash {
-				return true
-			}
+// lineNumberFormat returns the printf format for a numbered line of a document
+// whose highest line number is last, so that every gutter has its width.
+func lineNumberFormat(config config.Config, last int) string {
+	if config.LineNumbersCompact {
+		return "%d:%s\n"
+	}
+	padding := len(fmt.Sprintf("%d", last))
+	return fmt.Sprintf(" %%%dd │ %%s\n", padding)
+}
 
-			// Also match against full path for directory contents
-			matchedPathSlash, _ := doublestar.Match(ruleWithoutSlash+"/**", path)
-			if matchedPathSlash {
-				return true
-			}
+// writeNumberedLines writes lines numbered from first, counting the gutter bytes in state.
+func writeNumberedLines(b *strings.Builder, lines []string, first int, format string, state *emitState) {
+	for i, line := range lines {
+		numbered := fmt.Sprintf(format, first+i, line)
+		state.gutterBytes += int64(len(numbered) - len(line) - 1)
+		b.WriteString(numbered)
+	}
+}
 
-			// Handle directory patterns without trailing slash
-			// Check if this is a directory pattern by seeing if it matches directory paths
-		} else if strings.Contains(path, "/") {
-			// Try matching the rule as if it were a directory pattern
-			dirPattern := rule + "/"
-			matchedDir, _ := doublestar.Match(dirPattern, path+"/")
-			if matchedDir {
-				return true
-			}
+// emitFile renders the planned file f from its read, in the format of the
+// --compat tool when one is selected. A file that could not be read is skipped
+// with a warning, and one that changed since --stable-view captured it is
+// marked.
+func emitFile(f PlannedFile, read fileRead, config config.Config, writer io.Writer, state *emitState) error {
+	state.modified = false
+	if f.SiblingOf != "" {
+		return emitDocument(f.DisplayPath, state.anon.content(siblingStub(f)), "", config, writer, state)
+	}
+	if f.DuplicateOf != "" {
+		return emitDocument(f.DisplayPath, state.anon.content(symlinkStub(f, state)), "", config, writer, state)
+	}
+	if read.err != nil {
+		log.Warnf("Warning: Skipping file %s due to error: %v", f.Path, read.err)
+		state.unreadable = append(state.unreadable, f.DisplayPath)
+		return nil
+	}
+	state.documentIndexes[f.Path] = state.index
+	if read.cut {
+		log.Warnf("Warning: %s reports a size of 0 but kept producing data, so it was cut off after %s (use --zero-size-limit to read more)",
+			f.Path, formatBytes(zeroSizeLimit(config)))
+	}
+	if state.view.modified(f.Path, read) {
+		log.Warnf("Warning: %s was modified during the run; its document may not match the rest of the output", f.Path)
+		state.modified = true
+		state.drifted = append(state.drifted, f.DisplayPath)
+	}
+	if CompatMode(config.Compat) == CompatFilesToPrompt {
+		return emitCompatFile(f, read.content, config, writer, state)
+	}
+	state.meta = newFileMeta(f, read, config, state)
+	defer func() { state.meta = nil }()
+	if f.diff != nil {
+		return emitHunks(f, read, config, writer, state)
+	}
+	if read.scan != nil {
+		return streamDocument(f, read.scan, fenceLanguage(f.Path, string(read.scan.head), config), config, writer, state)
+	}
+	lang := fenceLanguage(f.Path, string(read.content), config)
+	content := state.redact.content(f.DisplayPath, string(read.content))
+	return emitDocument(f.DisplayPath, state.anon.content(content), lang, config, writer, state)
+}
+
+// processFile reads the file at filePath and renders it.
+func processFile(filePath string, config config.Config, writer io.Writer, state *emitState) error {
+	read := readFile(filePath, readLimit(config), streamAbove(config), zeroSizeLimit(config), state.throttle)
+	return emitFile(PlannedFile{Path: filePath, DisplayPath: filePath}, read, config, writer, state)
+}
 
-			// Also try matching against the directory part of the path
-			pathParts := strings.Split(path, "/")
-			if len(pathParts) > 1 {
-				dirPath := strings.Join(pathParts[:len(pathParts)-1], "/")
-				matchedDirPath, _ := doublestar.Match(rule, dirPath)
-				if matchedDirPath {
-					return true
+// emitDocument renders content as a single document (or, in Claude XML mode with
+// a size cap, a sequence of parts) shown as displayPath. lang labels the
+// Markdown code fence and, with --detect-lang, the Claude XML document.
+func emitDocument(displayPath string, content string, lang string, config config.Config, writer io.Writer, state *emitState) error {
+	var err error
+	// A final newline ends the last line rather than starting an empty one
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	var processedContent strings.Builder
+
+	segments := wholeFile(len(lines))
+	numbered := config.LineNumbers || config.LineNumbersCompact
+	if state.grep != nil && config.GrepContext >= 0 {
+		// Emit only the matching regions, always numbered so the real positions are kept
+		segments = regionSegments(len(lines), grepRegions(lines, state.grep, config.GrepContext))
+		numbered = true
+	}
+	if config.SquashDataBlocks > 0 {
+		segments = squashDataRuns(lines, segments, config.SquashDataBlocks)
+	}
+	if config.HeadLines > 0 || config.TailLines > 0 {
+		segments = cutLines(segments, len(lines), config.HeadLines, config.TailLines)
+	}
+	format := ""
+	if numbered && !config.HTML {
+		// Padded for the last line shown, not the length of the file
+		format = lineNumberFormat(config, lastLine(segments))
+	}
+	writeSegments(&processedContent, lines, segments, format, state)
+	terminated := strings.HasSuffix(content, "\n")
+	shown := segmentContent(lines, segments, terminated, false)
+	stats := shownStats(segments, shown)
+	state.ledger.addContent(shown)
+	state.emitted, state.lang, state.streamed = segmentContent(lines, segments, terminated, true), lang, false
+
+	switch {
+	case config.Format != "":
+		// The content as in the file, unless line numbers rework it
+		content := state.emitted
+		if format != "" {
+			content = processedContent.String()
+		}
+		var line string
+		if line, err = digestDocument(displayPath, content, lang, config); err == nil {
+			_, err = io.WriteString(writer, line)
+		}
+	case state.templates != nil:
+		// The content as in the file, unless line numbers rework it
+		content := state.emitted
+		if format != "" {
+			content = processedContent.String()
+		}
+		var output string
+		if output, err = state.templates.document(displayPath, content, lang, state.index); err == nil {
+			_, err = io.WriteString(writer, output)
+		}
+	case config.OpenAI:
+		label := displayPath + headerSuffix(config, stats) + modifiedSuffix(state) + diffSuffix(state)
+		_, err = io.WriteString(writer, state.openai.document(openaiText(label, lang, processedContent.String(), state)))
+	case config.JSONL:
+		// The content as in the file, unless line numbers rework it
+		content := state.emitted
+		if format != "" {
+			content = processedContent.String()
+		}
+		var line string
+		if line, err = jsonlLine(displayPath, content, state.index, state); err == nil {
+			_, err = io.WriteString(writer, line)
+		}
+	case config.HTML:
+		// The lines are escaped and numbered in a gutter of their own
+		label := displayPath + headerSuffix(config, stats) + modifiedSuffix(state) + diffSuffix(state)
+		_, err = io.WriteString(writer, htmlDocument(displayPath, label, lang, htmlCode(lines, segments, numbered, state), state))
+	case config.Markdown:
+		contentStr := processedContent.String()
+		backticks := getBackticks(contentStr)
+		label := displayPath + headerSuffix(config, stats) + modifiedSuffix(state) + diffSuffix(state)
+		markdownOutput := fmt.Sprintf("%s%s%s%s\n%s%s\n%s", markdownLabel(config, label),
+			metadataLine(state), backticks, lang, contentStr, backticks, markdownEnd(config))
+		_, err = writer.Write([]byte(markdownOutput))
+	case config.ClaudeXML:
+		names := cxmlNamesOf(config)
+		parts := names.split(processedContent.String(), config.CXMLMaxDocBytes)
+		langAttr := ""
+		if config.DetectLang && lang != "" {
+			langAttr = fmt.Sprintf(" language=\"%s\"", escapeXML(lang))
+		}
+		// Every part of a split document carries the counts of the whole
+		langAttr += headerAttrs(config, stats) + modifiedAttr(state) + diffAttr(state) + metadataAttrs(state)
+		for i, part := range parts {
+			partAttr := langAttr
+			if len(parts) > 1 {
+				partAttr += fmt.Sprintf(" part=\"%d/%d\"", i+1, len(parts))
+				if i > 0 {
+					state.index++
 				}
 			}
+			xmlOutput := names.document(state.index, displayPath, partAttr, part)
+			if _, err = writer.Write([]byte(xmlOutput)); err != nil {
+				break
+			}
 		}
-
+	default:
+		contentStr := processedContent.String()
+		separator := getSeparator(contentStr)
+		header := displayPath + headerSuffix(config, stats) + modifiedSuffix(state) + diffSuffix(state)
+		if separator != "---" {
+			// Record a lengthened separator so parsers know where the document ends
+			header += " [sep=" + separator + "]"
+		}
+		output := fmt.Sprintf("%s\n%s%s\n%s%s\n\n", header, metadataLine(state), separator, contentStr, separator)
+		_, err = writer.Write([]byte(output))
 	}
-	return false
+	state.index++
+	state.files++
+
+	return err
+}
+
+// Summary describes the output produced by a single Run.
+type Summary struct {
+	// Files is the number of documents written.
+	Files int
+	// Bytes is the size of the output counted by Scope.
+	Bytes int64
+	// Tokens is an estimate of the number of tokens counted by Scope:
+	// a BPE-style count with config.CountTokens, otherwise bytes divided by four.
+	Tokens int64
+	// GutterTokens is the estimated number of rendered tokens spent on line-number gutters.
+	GutterTokens int64
+	// Scope records whether Bytes and Tokens cover the rendered output or only the content.
+	Scope BudgetScope
+	// Stats is the per-file report requested with --stats, or nil.
+	Stats *RunStats
+	// Fit reports how --fit-tokens selected the files, or is nil.
+	Fit *FitReport
+	// Chunks lists the files Run divided the output into with --split-tokens
+	// or --split-bytes, in order, or is nil.
+	Chunks []Chunk
+}
+
+// estimateTokens returns a rough token estimate for n bytes of output,
+// using the common approximation of four bytes per token.
+func estimateTokens(n int64) int64 {
+	return (n + 3) / 4
+}
+
+// Run executes the files2prompt logic using the provided config.
+// It walks through each path, reads applicable files, and writes output
+// either to stdout or a file depending on config. The returned Summary
+// describes what was written.
+func Run(config config.Config) (Summary, error) {
+	return runPlanned(config, nil)
 }
 
-func processPath(path string, config config.Config, writer io.Writer, gitignoreRules []string, globalIndex *int) error {
-	// Handle current directory case
-	if path == "." {
-		var err error
-		path, err = os.Getwd()
+// runPlanned is Run emitting plan, as Generate does, instead of walking anew when plan is not nil.
+func runPlanned(config config.Config, plan []PlannedFile) (Summary, error) {
+	log.Debugf("files2prompt pkg Run config config struct contains: %v\n", config)
+	if config.LowPriority {
+		lowerPriorityBestEffort()
+	}
+
+	var err error
+	upload := isOutputURL(config.OutputFile)
+	for _, option := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--output-method", config.OutputMethod != ""},
+		{"--output-auth-env", config.OutputAuthEnv != ""},
+	} {
+		if option.set && !upload {
+			return Summary{}, fmt.Errorf("%s requires an http:// or https:// --output", option.flag)
+		}
+	}
+	if splits(config) {
+		for _, option := range []struct {
+			flag string
+			set  bool
+		}{
+			{"an http(s) --output", upload},
+			{"--copy", config.Clipboard},
+			{"--exec", config.Exec != ""},
+			{"--pipe", len(config.Pipes) > 0},
+			{"--submodules separate", config.Submodules == string(SubmodulesSeparate)},
+			{"--html", config.HTML},
+			{"--openai", config.OpenAI},
+			{"--header-template", config.HeaderTemplate != ""},
+			{"--footer-template", config.FooterTemplate != ""},
+			{"--prefix", config.Prefix != "" || config.PrefixFile != ""},
+			{"--suffix", config.Suffix != "" || config.SuffixFile != ""},
+		} {
+			if option.set {
+				return Summary{}, fmt.Errorf("%s cannot be combined with %s", splitFlag(config), option.flag)
+			}
+		}
+		if config.OutputFile == "" {
+			return Summary{}, fmt.Errorf("%s requires --output, the path the chunks are numbered after", splitFlag(config))
+		}
+	}
+	if !upload {
+		if config.OutputFile, err = hostEnv.expandTilde(config.OutputFile); err != nil {
+			return Summary{}, err
+		}
+	}
+	if config.OutputFile != "" && !upload && config.Jail != "" {
+		jail, err := newJail(config.Jail)
 		if err != nil {
-			return fmt.Errorf("failed to get working directory: %v", err)
+			return Summary{}, err
+		}
+		if err := jail.check(config.OutputFile); err != nil {
+			return Summary{}, err
 		}
 	}
 
-	info, err := os.Stat(path)
-	if err != nil {
-		return err
+	var out io.Writer = osStdout
+	var file *os.File
+	if config.MirrorOnly {
+		for _, option := range []struct {
+			flag string
+			set  bool
+		}{
+			{"--output", config.OutputFile != ""},
+			{"--copy", config.Clipboard},
+			{"--exec", config.Exec != ""},
+			{"--pipe", len(config.Pipes) > 0},
+		} {
+			if option.set {
+				return Summary{}, fmt.Errorf("--mirror-only cannot be combined with %s", option.flag)
+			}
+		}
+		out = io.Discard
 	}
 
-	if !info.IsDir() {
-		return processFile(path, config, writer, globalIndex)
+	// --copy replaces stdout, but is added alongside an output file
+	var board clipboard
+	var clip bytes.Buffer
+	if config.CopyChunked && !config.Clipboard {
+		return Summary{}, fmt.Errorf("--copy-chunked requires --copy")
+	}
+	if config.Clipboard {
+		if board, err = hostEnv.findClipboard(); err != nil {
+			return Summary{}, err
+		}
+		out = &clip
 	}
 
-	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+	var destination *httpOutput
+	var chunks *chunkFiles
+	switch {
+	case upload:
+		if destination, err = newHTTPOutput(context.Background(), config.OutputFile, config); err != nil {
+			return Summary{}, err
+		}
+		// Stopped unless it completes below
+		defer destination.abort()
+		out = destination
+		if config.Clipboard {
+			out = io.MultiWriter(destination, &clip)
+		}
+	case splits(config):
+		// Each chunk is created, and buffered, as it is reached
+		chunks = newChunkFiles(config.OutputFile, config)
+		out = chunks
+	case config.OutputFile != "":
+		file, err = os.Create(config.OutputFile)
 		if err != nil {
-			return err
+			return Summary{}, fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+		if config.Clipboard {
+			out = io.MultiWriter(file, &clip)
 		}
+	}
 
-		// Skip hidden files/directories unless specified
-		if !config.IncludeHidden && strings.HasPrefix(filepath.Base(filePath), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
+	// What reaches the destinations is held to their limits once written
+	limits := destinationLimits(config, out == osStdout && hostEnv.terminal(osStdout))
+	counted := &byteCounter{w: out}
+	out = counted
+
+	// The --exec hook needs a file to operate on; when writing to stdout or a
+	// URL, tee into a temp file
+	hookPath := config.OutputFile
+	if config.Exec != "" && file == nil {
+		file, err = os.CreateTemp("", "files2prompt-*.txt")
+		if err != nil {
+			return Summary{}, fmt.Errorf("failed to create temporary file for exec hook: %v", err)
 		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		hookPath = file.Name()
+		out = io.MultiWriter(out, file)
+	}
 
-		// Apply gitignore rules
-		if config.IgnoreGitignore {
-			if info.IsDir() {
-				newRules := readGitignore(filePath)
-				gitignoreRules = append(gitignoreRules, newRules...)
-			}
-			if shouldIgnore(filePath, gitignoreRules) {
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
+	var pipe *outputPipe
+	if len(config.Pipes) > 0 {
+		if pipe, err = startPipes(context.Background(), config.Pipes, config.PipeTimeout, out); err != nil {
+			return Summary{}, err
 		}
+		out = pipe
+	}
 
-		// Apply ignore patterns to both files and directories
-		if len(config.IgnorePatterns) > 0 {
-			relPath, err := filepath.Rel(path, filePath)
-			if err != nil {
-				log.Warnf("Warning: Could not get relative path for %s: %v", filePath, err)
-				relPath = filePath
-			}
+	// Documents are written whole, and streamed files in chunks; gather them into
+	// fewer, larger writes
+	var summary Summary
+	if chunks != nil {
+		summary, err = Generate(context.Background(), config, chunks, plan)
+		var closeErr error
+		if summary.Chunks, closeErr = chunks.Close(); err == nil {
+			err = closeErr
+		}
+	} else {
+		buffered := bufio.NewWriterSize(out, outputBufferSize)
+		summary, err = Generate(context.Background(), config, buffered, plan)
+		// What was rendered before a failure is written out, as it would be unbuffered
+		if flushErr := buffered.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	if pipe != nil {
+		// Always wait for the chain, and report its failure first: a command that
+		// died is usually why writing to it failed
+		if pipeErr := pipe.Close(); pipeErr != nil {
+			return Summary{}, pipeErr
+		}
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+	warnDestinationLimits(limits, counted.n)
+	if destination != nil {
+		if err := destination.Close(); err != nil {
+			return Summary{}, err
+		}
+		log.Infof("Uploaded %d bytes to %s", summary.Bytes, RedactURL(config.OutputFile))
+	}
 
-			for _, pattern := range config.IgnorePatterns {
-				// Split pattern into individual paths if comma-separated
-				for _, subPattern := range strings.Split(pattern, ",") {
-					subPattern = strings.TrimSpace(subPattern)
-					if subPattern == "" {
-						continue
-					}
-
-					// Match against both the base name and the relative path
-					baseMatch, _ := doublestar.Match(subPattern, filepath.Base(filePath))
-					pathMatch, _ := doublestar.Match(subPattern, relPath)
-
-					if baseMatch || pathMatch {
-						if info.IsDir() {
-							return filepath.SkipDir
-						}
-						return nil
-					}
-
-					// Handle directory-specific patterns
-					if strings.HasSuffix(subPattern, "/") && info.IsDir() {
-						dirPattern := strings.TrimSuffix(subPattern, "/")
-						if match, _ := doublestar.Match(dirPattern, filepath.Base(filePath)); match {
-							return filepath.SkipDir
-						}
-					}
-				}
-			}
+	if config.Clipboard {
+		if err := hostEnv.copyToClipboard(board, clip.Bytes(), config); err != nil {
+			return summary, err
 		}
+		log.Infof("Copied %d bytes (~%d tokens) to the clipboard", summary.Bytes, summary.Tokens)
+	}
 
-		// Apply extension filter only to files
-		if len(config.Extensions) > 0 && !info.IsDir() {
-			ext := filepath.Ext(filePath)
-			var match bool
-			for _, allowedExt := range config.Extensions {
-				if ext == allowedExt {
-					match = true
-					break
-				}
-			}
-			if !match {
-				return nil
+	if chunks != nil {
+		n := len(summary.Chunks)
+		fmt.Fprintf(osStderr, "Split the output into %d %s:\n", n, plural(n, "chunk", "chunks"))
+		for _, c := range summary.Chunks {
+			over := ""
+			if overBudget(c, config) {
+				over = fmt.Sprintf(", over the %s budget to keep a document whole", splitFlag(config))
 			}
+			fmt.Fprintf(osStderr, "  %s  %d bytes, ~%d tokens%s\n", c.Path, c.Bytes, c.Tokens, over)
 		}
+	}
 
-		if !info.IsDir() {
-			return processFile(filePath, config, writer, globalIndex)
+	if config.CountTokens {
+		scope := ""
+		if summary.Scope == BudgetContent {
+			scope = " of file content"
+		}
+		fmt.Fprintf(osStderr, "%d %s, %d bytes, ~%d tokens%s (cl100k-style estimate)\n",
+			summary.Files, plural(summary.Files, "file", "files"), summary.Bytes, summary.Tokens, scope)
+		if summary.GutterTokens > 0 {
+			fmt.Fprintf(osStderr, "  line-number gutters: ~%d tokens (~%d without numbering)\n",
+				summary.GutterTokens, summary.Tokens-summary.GutterTokens)
 		}
-		return nil
-	})
-}
-
-func processFile(filePath string, config config.Config, writer io.Writer, globalIndex *int) error {
-	content, err := os.ReadFile(filePath) // #nosec G304
-	if err != nil {
-		log.Warnf("Warning: Skipping file %s due to error: %v", filePath, err)
-		return nil
 	}
 
-	lines := strings.Split(string(content), "\n")
-	var processedContent strings.Builder
-
-	// Process content with line numbers if enabled
-	if config.LineNumbers {
-		// Calculate padding for line numbers based on total lines
-		padding := len(fmt.Sprintf("%d", len(lines)))
-		format := fmt.Sprintf("%% %dd │ %%s\n", padding)
-
-		for i, line := range lines {
-			processedContent.WriteString(fmt.Sprintf(format, i+1, line))
+	if fit := summary.Fit; fit != nil {
+		against := ""
+		if fit.SmallFirst {
+			against = fmt.Sprintf(", against %d in --sort order", fit.DefaultFiles)
 		}
-	} else {
-		processedContent.WriteString(string(content))
+		log.Infof("%d of %d %s (~%d tokens) fit the %d-token budget%s",
+			fit.Files, fit.Candidates, plural(fit.Candidates, "file", "files"), fit.Tokens, fit.Budget, against)
 	}
 
-	switch {
-	case config.Markdown:
-		ext := strings.TrimPrefix(filepath.Ext(filePath), ".")
-		lang := extToLang[ext]
-		contentStr := processedContent.String()
-		backticks := getBackticks(contentStr)
-		markdownOutput := fmt.Sprintf("%s\n%s%s\n%s%s\n", filePath, backticks, lang, contentStr, backticks)
-		_, err = writer.Write([]byte(markdownOutput))
-	case config.ClaudeXML:
-		xmlOutput := fmt.Sprintf("<document index=\"%d\">\n<source>%s</source>\n<document_content>\n%s</document_content>\n</document>\n",
-			*globalIndex, filePath, processedContent.String())
-		*globalIndex++
-		_, err = writer.Write([]byte(xmlOutput))
-	default:
-		output := fmt.Sprintf("%s\n---\n%s---\n\n", filePath, processedContent.String())
-		_, err = writer.Write([]byte(output))
+	if summary.Stats != nil {
+		// Validated by Generate
+		format, _ := statsFormat(config)
+		if err := WriteRunStats(osStderr, *summary.Stats, format); err != nil {
+			return summary, err
+		}
 	}
 
-	return err
+	if config.Exec != "" {
+		if err := file.Close(); err != nil {
+			return summary, err
+		}
+		if err := hostEnv.runExecHook(config.Exec, hookPath, summary); err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
 }
 
-// Run executes the files2prompt logic using the provided config.
-// It walks through each path, reads applicable files, and writes output
-// either to stdout or a file depending on config.
-func Run(config config.Config) error {
-	log.Debugf("files2prompt pkg Run config config struct contains: %v\n", config)
+// Generate renders the documents selected by config to w.
+//
+// When plan is nil the input paths are walked first. Otherwise the included
+// entries of plan, typically obtained from Plan, are emitted in order without
+// walking again; Generate(ctx, config, w, plan) then produces exactly the same
+// bytes as Generate(ctx, config, w, nil).
+//
+// The file documents are produced as Documents produces them, and written
+// within the wrapper, tree, standard input and --cmd documents.
+func Generate(ctx context.Context, config config.Config, w io.Writer, plan []PlannedFile) (Summary, error) {
+	// The long-run notice only watches the walk when Generate does it itself
+	var mon *longRunMonitor
+	progress := newProgressReport(config)
+	if plan == nil {
+		mon = newLongRunMonitor(config)
+		mon.progress = progress
+		if progress != nil {
+			estimate, ok := progressEstimate{}, false
+			if !config.NoPrecount {
+				estimate, ok = precount(ctx, config)
+			}
+			progress.start("Scanning", estimate, ok)
+		}
+	}
+	g, err := newGenerator(ctx, config, w, plan, mon)
+	if err != nil {
+		return Summary{}, err
+	}
+	if plan == nil {
+		progress.finish()
+	}
+	mirror, err := newMirror(config, g.plan)
+	if err != nil {
+		return Summary{}, err
+	}
+	var stats *RunStats
+	if config.Stats {
+		stats = newRunStats(g.plan)
+	}
+	writer, state := g.writer, g.state
 
-	var writer io.Writer = osStdout
-	var file *os.File
+	if config.ListOnly {
+		return writeList(ctx, g.plan, g.roots, config, writer, stats, state.throttle)
+	}
+	g.progress = progress
+	progress.start("Emitting", planEstimate(g.plan), true)
 
-	if config.OutputFile != "" {
-		var err error
-		file, err = os.Create(config.OutputFile)
+	if !g.prompt.inside {
+		g.prompt.writePrefix(writer)
+	}
+	if config.ClaudeXML {
+		_, _ = writer.Write([]byte(cxmlNamesOf(config).openRoot()))
+	}
+	if g.prompt.inside {
+		g.prompt.writePrefix(writer)
+	}
+	if config.HTML {
+		_, _ = writer.Write([]byte(htmlOpen(g.plan)))
+	}
+	if state.openai != nil {
+		open, err := state.openai.open(g.plan, config)
 		if err != nil {
-			log.Fatalf("Failed to create output file: %v", err)
+			return Summary{}, err
 		}
-		defer file.Close()
-		writer = file
+		_, _ = writer.Write([]byte(open))
+	}
+	if state.templates != nil {
+		header, err := state.templates.open(g.plan, config)
+		if err != nil {
+			return Summary{}, err
+		}
+		_, _ = writer.Write([]byte(header))
+	}
+	if config.Markdown && CompatMode(config.Compat) != CompatFilesToPrompt {
+		_, _ = writer.Write([]byte(markdownTitle(config, g.plan)))
 	}
 
-	globalIndex := 1
-	var gitignoreRules []string
+	if config.EnvContext || len(config.EnvContextCmds) > 0 {
+		if err := g.place(func() error { return writeEnvContext(ctx, writer, g.plan, config, state) }); err != nil {
+			return Summary{}, err
+		}
+	}
+	g.groupSubmodules()
+	if config.Tree && g.hasDocuments() {
+		if err := g.place(func() error { return writeTree(writer, g.plan, config, state) }); err != nil {
+			return Summary{}, err
+		}
+	}
 
-	if config.IgnoreGitignore {
-		log.Debug("files2prompt pkg Run inside config.IgnoreGitignore check")
-		for _, path := range config.Paths {
-			gitignoreRules = append(gitignoreRules, readGitignore(filepath.Dir(path))...)
+	for doc, err := range g.documents(ctx) {
+		if err != nil {
+			return Summary{}, err
+		}
+		stats.add(doc.Stats)
+		if config.FlushEveryFile {
+			if err := flush(w); err != nil {
+				return Summary{}, err
+			}
+		}
+		// The copy holds what the document does, after --grep-context and the like
+		if err := mirror.write(doc); err != nil {
+			return Summary{}, err
+		}
+	}
+	if err := mirror.close(); err != nil {
+		return Summary{}, err
+	}
+	if err := g.place(func() error { return emitStdin(config, writer, state) }); err != nil {
+		return Summary{}, err
+	}
+	if err := emitCommands(ctx, config, writer, state, g.place); err != nil {
+		return Summary{}, err
+	}
+	if config.EmbedWarnings {
+		if err := g.place(func() error { return writeWarnings(writer, omissions(g.plan, config, state), config, state) }); err != nil {
+			return Summary{}, err
 		}
 	}
 
+	if g.prompt.inside {
+		g.prompt.writeSuffix(writer, g.capture)
+	}
 	if config.ClaudeXML {
-		_, _ = writer.Write([]byte("<documents>\n"))
+		_, _ = writer.Write([]byte(cxmlNamesOf(config).closeRoot()))
+	}
+	if config.HTML {
+		_, _ = writer.Write([]byte(htmlClose()))
+	}
+	if state.openai != nil {
+		_, _ = writer.Write([]byte(state.openai.close()))
+	}
+	if state.templates != nil {
+		footer, err := state.templates.close()
+		if err != nil {
+			return Summary{}, err
+		}
+		_, _ = writer.Write([]byte(footer))
+	}
+	if !g.prompt.inside {
+		g.prompt.writeSuffix(writer, g.capture)
 	}
 
-	for _, path := range config.Paths {
-		if err := processPath(path, config, writer, gitignoreRules, &globalIndex); err != nil {
-			log.Errorf("Error processing path %s: %v", path, err)
+	progress.finish()
+
+	if err := reportRoots(g.plan, g.roots, config); err != nil {
+		return Summary{}, err
+	}
+	if err := state.anon.writeMap(config.AnonymizeMap); err != nil {
+		return Summary{}, err
+	}
+
+	used := writer.used()
+	summary := Summary{
+		Files:        state.files,
+		Bytes:        used.bytes,
+		Tokens:       used.tokens,
+		GutterTokens: estimateTokens(state.gutterBytes),
+		Scope:        writer.scope,
+		Stats:        stats,
+		Fit:          newFitReport(g.plan, config),
+	}
+	if config.LineNumbers || config.LineNumbersCompact {
+		log.Debugf("Line-number gutters add ~%d tokens (~%d with numbering, ~%d without)",
+			summary.GutterTokens, summary.Tokens, summary.Tokens-summary.GutterTokens)
+	}
+	return summary, nil
+}
+
+// flush writes out what w has buffered, if it buffers its writes.
+func flush(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// warnSkipped warns about f when it was skipped for a reason the user is likely
+// to want to hear about, such as a size limit.
+func warnSkipped(f PlannedFile, config config.Config) {
+	switch f.Reason {
+	case SkipMaxSize:
+		log.Warnf("Skipping %s: %s exceeds the %s --max-size limit",
+			f.Path, formatBytes(f.Size), formatBytes(int64(config.MaxFileSize)))
+	case SkipTooLarge:
+		log.Warnf("Skipping %s: %s exceeds the %s read limit (use --read-limit to raise it)",
+			f.Path, formatBytes(f.Size), formatBytes(readLimit(config)))
+	case SkipJail:
+		log.Warnf("Skipping %s: it links outside the --jail directory", f.Path)
+	case SkipVirtualFS:
+		if f.Origin == OriginArg || f.Origin == OriginStdin {
+			log.Warnf("Skipping %s: it is on a virtual file system (use --include-virtual-fs to read it)", f.Path)
+		}
+	case SkipDangling:
+		log.Debugf("Skipping %s: it is a dangling symlink", f.Path)
+	case SkipNamedPipe, SkipSocket, SkipDevice, SkipIrregular:
+		// Sockets left behind by dev servers are common enough in walked trees not to warn about
+		if f.Origin == OriginArg || f.Origin == OriginStdin {
+			log.Warnf("Skipping %s: %s", f.Path, f.Reason)
+		} else {
+			log.Debugf("Skipping %s: %s", f.Path, f.Reason)
 		}
 	}
+}
 
-	if config.ClaudeXML {
-		_, _ = writer.Write([]byte("</documents>\n"))
+// reportRoots explains each path argument of plan that produced no documents
+// and lists the sensitive files withheld, failing with --fail-on-empty.
+func reportRoots(plan []PlannedFile, roots []string, config config.Config) error {
+	var emptyPaths, withheld []string
+	for _, root := range roots {
+		stats := statsFromPlan(root, plan)
+		withheld = append(withheld, stats.withheld...)
+		if stats.documents == 0 {
+			log.Warn(stats.emptyDiagnostic(config))
+			emptyPaths = append(emptyPaths, root)
+		}
+	}
+	if len(withheld) > 0 {
+		log.Warnf("Withheld %d sensitive %s (use --include-sensitive to include):\n  %s",
+			len(withheld), plural(len(withheld), "file", "files"), strings.Join(withheld, "\n  "))
+	}
+	if config.FailOnEmpty && len(emptyPaths) > 0 {
+		return fmt.Errorf("no documents produced for %s", strings.Join(emptyPaths, ", "))
 	}
 	return nil
 }
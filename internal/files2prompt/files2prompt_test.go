@@ -2,9 +2,17 @@ package files2prompt
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/toozej/files2prompt/pkg/config"
 )
 
@@ -53,6 +61,7 @@ func TestShouldIgnore(t *testing.T) {
 	tests := []struct {
 		name           string
 		path           string
+		isDir          bool
 		gitignoreRules []string
 		expected       bool
 	}{
@@ -65,14 +74,23 @@ func TestShouldIgnore(t *testing.T) {
 		{
 			name:           "directory match",
 			path:           "node_modules",
+			isDir:          true,
 			gitignoreRules: []string{"node_modules/"},
 			expected:       true,
 		},
 		{
-			name:           "directory path match",
+			name:           "directory-only rule skips files",
+			path:           "node_modules",
+			gitignoreRules: []string{"node_modules/"},
+			expected:       false,
+		},
+		{
+			// The walk never descends into an ignored directory, so its contents
+			// are not matched themselves
+			name:           "directory contents are pruned, not matched",
 			path:           "node_modules/package",
 			gitignoreRules: []string{"node_modules/"},
-			expected:       true,
+			expected:       false,
 		},
 		{
 			name:           "no match",
@@ -99,27 +117,129 @@ func TestShouldIgnore(t *testing.T) {
 			expected:       true,
 		},
 		{
-			name:           "directory pattern without trailing slash",
-			path:           "temp/files",
+			name:           "pattern without a slash matches at any depth",
+			path:           "a/b/temp",
+			isDir:          true,
 			gitignoreRules: []string{"temp"},
 			expected:       true,
 		},
 		{
 			name:           "temp directory with trailing slash pattern",
 			path:           "testdata/test_project/temp",
+			isDir:          true,
 			gitignoreRules: []string{"*.log", "node_modules/", "temp/"},
 			expected:       true,
 		},
+		{
+			name:           "negation re-includes",
+			path:           "keep.log",
+			gitignoreRules: []string{"*.log", "!keep.log"},
+			expected:       false,
+		},
+		{
+			name:           "negation only affects what it matches",
+			path:           "other.log",
+			gitignoreRules: []string{"*.log", "!keep.log"},
+			expected:       true,
+		},
+		{
+			name:           "last matching rule wins",
+			path:           "keep.log",
+			gitignoreRules: []string{"*.log", "!keep.log", "keep.*"},
+			expected:       true,
+		},
+		{
+			name:           "negation inside an excluded directory's contents",
+			path:           "dist/config.example.json",
+			gitignoreRules: []string{"dist/*", "!dist/config.example.json"},
+			expected:       false,
+		},
+		{
+			name:           "escaped leading exclamation mark is literal",
+			path:           "!important",
+			gitignoreRules: []string{`\!important`},
+			expected:       true,
+		},
+		{
+			name:           "leading slash anchors to the .gitignore directory",
+			path:           "build",
+			isDir:          true,
+			gitignoreRules: []string{"/build"},
+			expected:       true,
+		},
+		{
+			name:           "anchored pattern does not match deeper",
+			path:           "src/build",
+			isDir:          true,
+			gitignoreRules: []string{"/build"},
+			expected:       false,
+		},
+		{
+			name:           "middle slash anchors too",
+			path:           "lib/src/main.go",
+			gitignoreRules: []string{"src/*.go"},
+			expected:       false,
+		},
+		{
+			name:           "double star matches at any depth",
+			path:           "a/b/logs",
+			isDir:          true,
+			gitignoreRules: []string{"**/logs"},
+			expected:       true,
+		},
+		{
+			name:           "trailing double star leaves the directory itself walkable",
+			path:           "vendor",
+			isDir:          true,
+			gitignoreRules: []string{"vendor/**"},
+			expected:       false,
+		},
+		{
+			name:           "trailing double star matches the contents",
+			path:           "vendor/lib.go",
+			gitignoreRules: []string{"vendor/**"},
+			expected:       true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := shouldIgnore(tt.path, tt.gitignoreRules)
+			result := shouldIgnore(tt.path, tt.isDir, parseGitignore(".", tt.gitignoreRules))
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
 
+func TestShouldIgnoreNestedScope(t *testing.T) {
+	rules := append(parseGitignore("repo", []string{"*.log", "/build"}),
+		parseGitignore("repo/sub", []string{"!debug.log", "*.tmp", "/build"})...)
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"repo/app.log", false, true},
+		{"repo/sub/app.log", false, true},
+		// The nested negation only applies beneath its own directory
+		{"repo/sub/debug.log", false, false},
+		{"repo/debug.log", false, true},
+		{"repo/sub/x.tmp", false, true},
+		{"repo/x.tmp", false, false},
+		// Each anchored /build is relative to its own .gitignore
+		{"repo/build", true, true},
+		{"repo/sub/build", true, true},
+		{"repo/sub/deeper/build", true, false},
+		{"other/app.log", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, shouldIgnore(tt.path, tt.isDir, rules))
+			assert.Equal(t, tt.expected, compileIgnoreRules(rules).match(tt.path, tt.isDir))
+		})
+	}
+}
+
 func TestProcessFile(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -135,7 +255,7 @@ func TestProcessFile(t *testing.T) {
 				LineNumbers: false,
 				ClaudeXML:   false,
 			},
-			expected:    "testdata/file1.txt\n---\nline 1\nline 2\nline 3---\n\n",
+			expected:    "testdata/file1.txt\n---\nline 1\nline 2\nline 3\n---\n\n",
 			expectedErr: false,
 		},
 		{
@@ -155,7 +275,7 @@ func TestProcessFile(t *testing.T) {
 				LineNumbers: false,
 				ClaudeXML:   true,
 			},
-			expected:    "<document index=\"1\">\n<source>testdata/file3.txt</source>\n<document_content>\nxml content</document_content>\n</document>\n",
+			expected:    "<document index=\"1\">\n<source>testdata/file3.txt</source>\n<document_content>\nxml content\n</document_content>\n</document>\n",
 			expectedErr: false,
 		},
 		{
@@ -196,7 +316,7 @@ func TestProcessFile(t *testing.T) {
 				ClaudeXML:   false,
 				Markdown:    true,
 			},
-			expected:    "testdata/file1.txt\n```\nline 1\nline 2\nline 3```\n",
+			expected:    "## testdata/file1.txt\n\n```\nline 1\nline 2\nline 3\n```\n\n",
 			expectedErr: false,
 		},
 		{
@@ -207,7 +327,7 @@ func TestProcessFile(t *testing.T) {
 				ClaudeXML:   false,
 				Markdown:    true,
 			},
-			expected:    "testdata/file2.txt\n```\n 1 │ first line\n 2 │ second line\n```\n",
+			expected:    "## testdata/file2.txt\n\n```\n 1 │ first line\n 2 │ second line\n```\n\n",
 			expectedErr: false,
 		},
 		{
@@ -218,17 +338,35 @@ func TestProcessFile(t *testing.T) {
 				ClaudeXML:   false,
 				Markdown:    true,
 			},
+			expected:    "## testdata/test_project/src/main.go\n\n```go\npackage main\n\nfunc main() {}\n```\n\n",
+			expectedErr: false,
+		},
+		{
+			name:     "Go file with Markdown path style",
+			filePath: "testdata/test_project/src/main.go",
+			config: config.Config{
+				Markdown:      true,
+				MarkdownStyle: string(MarkdownPath),
+			},
 			expected:    "testdata/test_project/src/main.go\n```go\npackage main\n\nfunc main() {}\n```\n",
 			expectedErr: false,
 		},
+		{
+			name:     "Go file with Markdown heading level 3",
+			filePath: "testdata/test_project/src/main.go",
+			config: config.Config{
+				Markdown:             true,
+				MarkdownHeadingLevel: 3,
+			},
+			expected:    "### testdata/test_project/src/main.go\n\n```go\npackage main\n\nfunc main() {}\n```\n\n",
+			expectedErr: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			globalIndex := 1
-
-			err := processFile(tt.filePath, tt.config, &buf, &globalIndex)
+			err := processFile(tt.filePath, tt.config, &buf, newEmitState())
 
 			if tt.expectedErr {
 				assert.Error(t, err)
@@ -240,6 +378,56 @@ func TestProcessFile(t *testing.T) {
 	}
 }
 
+func TestEmitDocumentTrailingNewline(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{"plain", config.Config{}, "notes.md\n---\nline 1\nline 2\n---\n\n"},
+		{"markdown", config.Config{Markdown: true}, "## notes.md\n\n```markdown\nline 1\nline 2\n```\n\n"},
+		{"markdown path style", config.Config{Markdown: true, MarkdownStyle: string(MarkdownPath)}, "notes.md\n```markdown\nline 1\nline 2\n```\n"},
+		{"claude xml", config.Config{ClaudeXML: true},
+			"<document index=\"1\">\n<source>notes.md</source>\n<document_content>\nline 1\nline 2\n</document_content>\n</document>\n"},
+		{"line numbers", config.Config{LineNumbers: true}, "notes.md\n---\n 1 │ line 1\n 2 │ line 2\n---\n\n"},
+	}
+	for _, tt := range tests {
+		// A file that ends in a newline renders exactly like one that does not:
+		// the closing delimiter always starts a line, with no blank line before it
+		for _, content := range []string{"line 1\nline 2\n", "line 1\nline 2"} {
+			t.Run(fmt.Sprintf("%s/%q", tt.name, content), func(t *testing.T) {
+				var buf bytes.Buffer
+				require.NoError(t, emitDocument("notes.md", content, "markdown", tt.config, &buf, newEmitState()))
+				assert.Equal(t, tt.expected, buf.String())
+			})
+		}
+	}
+}
+
+// renderPath plans a single path argument and emits its included files, as Run does for each path.
+func renderPath(path string, cfg config.Config, gitignoreRules []gitignoreRule) (string, error) {
+	grep, err := compileGrep(cfg)
+	if err != nil {
+		return "", err
+	}
+	planner := &pathPlanner{pipeline: newFilterPipeline(cfg, gitignoreRules, grep)}
+	files, err := planner.planPath(context.Background(), path, OriginArg, path)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	state := newEmitState()
+	state.grep = grep
+	for _, f := range files {
+		if f.Included {
+			if err := processFile(f.Path, cfg, &buf, state); err != nil {
+				return "", err
+			}
+		}
+	}
+	return buf.String(), nil
+}
+
 func TestProcessPath(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -263,7 +451,7 @@ func TestProcessPath(t *testing.T) {
 			config: config.Config{
 				Extensions: []string{".go", ".txt"},
 			},
-			expected:    "testdata/test_project/docs/README.txt\n---\nHello world---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\ntestdata/test_project/temp/file.txt\n---\ntemp file---\n\n",
+			expected:    "testdata/test_project/docs/README.txt\n---\nHello world\n---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\ntestdata/test_project/temp/file.txt\n---\ntemp file\n---\n\n",
 			expectedErr: false,
 		},
 		{
@@ -283,7 +471,7 @@ func TestProcessPath(t *testing.T) {
 				IgnorePatterns: []string{"*.log", "temp/"},
 				Extensions:     []string{".go", ".txt"},
 			},
-			expected:    "testdata/test_project/docs/README.txt\n---\nHello world---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n",
+			expected:    "testdata/test_project/docs/README.txt\n---\nHello world\n---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n",
 			expectedErr: false,
 		},
 		{
@@ -293,7 +481,7 @@ func TestProcessPath(t *testing.T) {
 				IncludeHidden: true,
 				Extensions:    []string{".go"},
 			},
-			expected:    "testdata/test_project/.hidden.go\n---\nhidden code---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n",
+			expected:    "testdata/test_project/.hidden.go\n---\nhidden code\n---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n",
 			expectedErr: false,
 		},
 		{
@@ -312,24 +500,22 @@ func TestProcessPath(t *testing.T) {
 			config: config.Config{
 				Extensions: []string{".txt"},
 			},
-			expected:    "testdata/empty.txt\n---\n---\n\ntestdata/file1.txt\n---\nline 1\nline 2\nline 3---\n\ntestdata/file2.txt\n---\nfirst line\nsecond line---\n\ntestdata/file3.txt\n---\nxml content---\n\ntestdata/file4.txt\n---\nline 1\nline 2---\n\ntestdata/test_project/docs/README.txt\n---\nHello world---\n\ntestdata/test_project/temp/file.txt\n---\ntemp file---\n\n",
+			expected:    "testdata/empty.txt\n---\n---\n\ntestdata/file1.txt\n---\nline 1\nline 2\nline 3\n---\n\ntestdata/file2.txt\n---\nfirst line\nsecond line\n---\n\ntestdata/file3.txt\n---\nxml content\n---\n\ntestdata/file4.txt\n---\nline 1\nline 2\n---\n\ntestdata/test_project/docs/README.txt\n---\nHello world\n---\n\ntestdata/test_project/temp/file.txt\n---\ntemp file\n---\n\n",
 			expectedErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var buf bytes.Buffer
-			var gitignoreRules []string
-			globalIndex := 1
+			var gitignoreRules []gitignoreRule
 
-			err := processPath(tt.path, tt.config, &buf, gitignoreRules, &globalIndex)
+			output, err := renderPath(tt.path, tt.config, gitignoreRules)
 
 			if tt.expectedErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expected, buf.String())
+				assert.Equal(t, tt.expected, output)
 			}
 		})
 	}
@@ -366,7 +552,17 @@ func TestRun(t *testing.T) {
 				Paths:      []string{"testdata/test_project/src", "testdata/test_project/docs"},
 				Extensions: []string{".go", ".txt"},
 			},
-			expected:    "testdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\ntestdata/test_project/docs/README.txt\n---\nHello world---\n\n",
+			expected:    "testdata/test_project/docs/README.txt\n---\nHello world\n---\n\ntestdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n",
+			expectedErr: false,
+		},
+		{
+			name: "run with multiple paths in argument order",
+			config: config.Config{
+				Paths:      []string{"testdata/test_project/src", "testdata/test_project/docs"},
+				Extensions: []string{".go", ".txt"},
+				Sort:       "none",
+			},
+			expected:    "testdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\ntestdata/test_project/docs/README.txt\n---\nHello world\n---\n\n",
 			expectedErr: false,
 		},
 		{
@@ -396,7 +592,7 @@ func TestRun(t *testing.T) {
 				Markdown:   true,
 				Extensions: []string{".go"},
 			},
-			expected:    "testdata/test_project/src/main.go\n```go\npackage main\n\nfunc main() {}\n```\n",
+			expected:    "## testdata/test_project/src/main.go\n\n```go\npackage main\n\nfunc main() {}\n```\n\n",
 			expectedErr: false,
 		},
 	}
@@ -409,7 +605,7 @@ func TestRun(t *testing.T) {
 			osStdout = &buf
 			defer func() { osStdout = originalStdout }()
 
-			err := Run(tt.config)
+			_, err := Run(tt.config)
 
 			if tt.expectedErr {
 				assert.Error(t, err)
@@ -420,3 +616,380 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessFileLineNumberGutters(t *testing.T) {
+	lines := make([]string, 12)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("l%d", i+1)
+	}
+	path := filepath.Join(t.TempDir(), "twelve.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+	tests := []struct {
+		name           string
+		config         config.Config
+		expectedBody   string
+		expectedGutter int64
+	}{
+		{
+			name:   "classic gutter",
+			config: config.Config{LineNumbers: true},
+			expectedBody: "  1 │ l1\n  2 │ l2\n  3 │ l3\n  4 │ l4\n  5 │ l5\n  6 │ l6\n  7 │ l7\n  8 │ l8\n  9 │ l9\n" +
+				" 10 │ l10\n 11 │ l11\n 12 │ l12\n",
+			expectedGutter: 12 * 8,
+		},
+		{
+			name:           "compact gutter",
+			config:         config.Config{LineNumbersCompact: true},
+			expectedBody:   "1:l1\n2:l2\n3:l3\n4:l4\n5:l5\n6:l6\n7:l7\n8:l8\n9:l9\n10:l10\n11:l11\n12:l12\n",
+			expectedGutter: 9*2 + 3*3,
+		},
+		{
+			name:           "compact wins when both are set",
+			config:         config.Config{LineNumbers: true, LineNumbersCompact: true},
+			expectedBody:   "1:l1\n2:l2\n3:l3\n4:l4\n5:l5\n6:l6\n7:l7\n8:l8\n9:l9\n10:l10\n11:l11\n12:l12\n",
+			expectedGutter: 9*2 + 3*3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			state := newEmitState()
+			assert.NoError(t, processFile(path, tt.config, &buf, state))
+			assert.Equal(t, path+"\n---\n"+tt.expectedBody+"---\n\n", buf.String())
+			assert.Equal(t, tt.expectedGutter, state.gutterBytes)
+		})
+	}
+}
+
+// gutterFixture writes a file of code lines l1 to l99, with "needle" on lines 5
+// and 95, followed by 21 lines of numeric data.
+func gutterFixture(t *testing.T) string {
+	t.Helper()
+	var b strings.Builder
+	for n := 1; n <= 120; n++ {
+		switch {
+		case n == 5 || n == 95:
+			fmt.Fprintf(&b, "l%d needle\n", n)
+		case n < 100:
+			fmt.Fprintf(&b, "l%d\n", n)
+		default:
+			b.WriteString("1024, 2048, 4096, 8192, 16384, 32768\n")
+		}
+	}
+	require.True(t, isDataLine("1024, 2048, 4096, 8192, 16384, 32768"))
+	path := filepath.Join(t.TempDir(), "gutters.py")
+	require.NoError(t, os.WriteFile(path, []byte(b.String()), 0o600))
+	return path
+}
+
+func TestLineNumberGutterGolden(t *testing.T) {
+	path := gutterFixture(t)
+	var squashed strings.Builder
+	for n := 1; n <= 99; n++ {
+		needle := ""
+		if n == 5 || n == 95 {
+			needle = " needle"
+		}
+		fmt.Fprintf(&squashed, "%3d │ l%d%s\n", n, n, needle)
+	}
+	squashed.WriteString("[... 21 lines of data omitted ...]\n")
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "early range",
+			config:   config.Config{Grep: "l5 needle", GrepContext: 1, LineNumbers: true},
+			expected: "...\n 4 │ l4\n 5 │ l5 needle\n 6 │ l6\n...\n",
+		},
+		{
+			name:     "late range",
+			config:   config.Config{Grep: "l95 needle", GrepContext: 1, LineNumbers: true},
+			expected: "...\n 94 │ l94\n 95 │ l95 needle\n 96 │ l96\n...\n",
+		},
+		{
+			name:     "data squashed at the end",
+			config:   config.Config{SquashDataBlocks: 4, LineNumbers: true},
+			expected: squashed.String(),
+		},
+		{
+			name:     "both ranges",
+			config:   config.Config{Grep: "needle", GrepContext: 0, LineNumbersCompact: true},
+			expected: "...\n5:l5 needle\n...\n95:l95 needle\n...\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{path}
+			var out bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &out, nil)
+			require.NoError(t, err)
+			docs := parsePlain(t, out.String())
+			require.Len(t, docs, 1)
+			assert.Equal(t, tt.expected, docs[0].content)
+		})
+	}
+}
+
+func TestLineNumberGutterWidth(t *testing.T) {
+	path := gutterFixture(t)
+	gutter := regexp.MustCompile(`^( *)(\d+)(?: │ |:)(?:l(\d+))?`)
+	ranges := []struct {
+		name    string
+		grep    string
+		context int
+	}{
+		{"whole file", "", -1},
+		{"early range", "l5 needle", 2},
+		{"late range", "l95 needle", 2},
+		{"both ranges", "needle", 2},
+	}
+	for _, r := range ranges {
+		for _, squash := range []int{0, 4} {
+			for _, chunk := range []int64{0, 256} {
+				for _, compact := range []bool{false, true} {
+					cfg := config.Config{
+						Paths: []string{path}, ClaudeXML: true, Grep: r.grep, GrepContext: r.context,
+						SquashDataBlocks: squash, CXMLMaxDocBytes: chunk, LineNumbers: !compact, LineNumbersCompact: compact,
+					}
+					name := fmt.Sprintf("%s, squash %d, chunk %d, compact %v", r.name, squash, chunk, compact)
+					var out bytes.Buffer
+					_, err := Generate(context.Background(), cfg, &out, nil)
+					require.NoError(t, err, name)
+
+					type numbered struct{ pad, n int }
+					var shown []numbered
+					for _, line := range strings.Split(out.String(), "\n") {
+						m := gutter.FindStringSubmatch(line)
+						if m == nil {
+							continue
+						}
+						n, _ := strconv.Atoi(m[2])
+						// The gutter numbers the line it is on
+						if m[3] != "" {
+							assert.Equal(t, m[2], m[3], "%s: %q", name, line)
+						}
+						shown = append(shown, numbered{len(m[1]) + len(m[2]), n})
+					}
+					require.NotEmpty(t, shown, name)
+					last := shown[len(shown)-1].n
+					for _, s := range shown {
+						if compact {
+							assert.Equal(t, len(strconv.Itoa(s.n)), s.pad, name)
+						} else {
+							// As narrow as the last number shown allows, and the same on every line
+							assert.Equal(t, len(strconv.Itoa(last))+1, s.pad, name)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRunReportsGutterOverhead(t *testing.T) {
+	buf := withStdout(t)
+	classic, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, LineNumbers: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/file1.txt\n---\n 1 │ line 1\n 2 │ line 2\n 3 │ line 3\n---\n\n", buf.String())
+
+	buf.Reset()
+	compact, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, LineNumbersCompact: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/file1.txt\n---\n1:line 1\n2:line 2\n3:line 3\n---\n\n", buf.String())
+
+	assert.Equal(t, estimateTokens(3*7), classic.GutterTokens)
+	assert.Equal(t, estimateTokens(3*2), compact.GutterTokens)
+	assert.Less(t, compact.Tokens, classic.Tokens)
+}
+
+func TestGetSeparator(t *testing.T) {
+	assert.Equal(t, "---", getSeparator("plain text\n"))
+	assert.Equal(t, "----", getSeparator("title\n---\nbody\n"))
+	assert.Equal(t, "-----", getSeparator("---\n----\n"))
+	assert.Equal(t, "----", getSeparator("body\r\n---\r\n"))
+	assert.Equal(t, "-----", getSeparator("body\n----"))
+	// Only a line of dashes can be taken for the separator
+	assert.Equal(t, "---", getSeparator("ends with dashes ---"))
+	assert.Equal(t, "---", getSeparator("----- x\n-- ---\n"))
+
+	dir := t.TempDir()
+	table := "| Flag | Default |\n| ---- | ------- |\n| -e   | ---     |\n"
+	writeFiles(t, dir, map[string]string{"flags.md": table})
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{filepath.Join(dir, "flags.md")}}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "flags.md")+"\n---\n"+table+"---\n\n", buf.String())
+}
+
+// plainDocument is a document recovered from plain-format output.
+type plainDocument struct {
+	path    string
+	content string
+}
+
+// parsePlain splits plain-format output back into its documents, honoring
+// lengthened separators recorded in the header line.
+func parsePlain(t *testing.T, output string) []plainDocument {
+	t.Helper()
+	var docs []plainDocument
+	for output != "" {
+		header, rest, ok := strings.Cut(output, "\n")
+		require.True(t, ok, "missing header in %q", output)
+		path, separator := header, "---"
+		if i := strings.LastIndex(header, " [sep="); i >= 0 && strings.HasSuffix(header, "]") {
+			path, separator = header[:i], header[i+len(" [sep="):len(header)-1]
+		}
+		require.True(t, strings.HasPrefix(rest, separator+"\n"), "missing opening separator for %s", path)
+		rest = rest[len(separator)+1:]
+
+		end := strings.Index(rest, separator+"\n\n")
+		require.GreaterOrEqual(t, end, 0, "missing closing separator for %s", path)
+		docs = append(docs, plainDocument{path: path, content: rest[:end]})
+		output = rest[end+len(separator)+2:]
+	}
+	return docs
+}
+
+func TestPlainSeparatorRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	contents := map[string]string{
+		"dashes.md":  "---\n---\n---\ntitle: x\n----\n---\n",
+		"front.md":   "---\ntitle: notes\n---\n\nbody ends with dashes--",
+		"plain.rst":  "no separators here\n",
+		"inline.rst": "a --- b\n\n",
+	}
+	var paths []string
+	for _, name := range []string{"dashes.md", "front.md", "inline.rst", "plain.rst"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents[name]), 0o600))
+		paths = append(paths, path)
+	}
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: paths}, &buf, nil)
+	require.NoError(t, err)
+
+	docs := parsePlain(t, buf.String())
+	require.Len(t, docs, len(paths))
+	for i, doc := range docs {
+		assert.Equal(t, paths[i], doc.path)
+		expected := contents[filepath.Base(paths[i])]
+		if !strings.HasSuffix(expected, "\n") {
+			// The closing separator is always on a line of its own
+			expected += "\n"
+		}
+		assert.Equal(t, expected, doc.content)
+	}
+
+	// Files without a conflict keep the classic separator and header
+	assert.Contains(t, buf.String(), paths[3]+"\n---\nno separators here\n---\n\n")
+	assert.Contains(t, buf.String(), paths[0]+" [sep=-----]\n-----\n")
+}
+
+// countingWriter counts the writes that reach it, as a stand-in for the
+// write system calls made on the output destination.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, os.ErrClosed }
+
+func TestRunBuffersOutput(t *testing.T) {
+	root := t.TempDir()
+	writeSyntheticTree(t, root, 200)
+
+	var unbuffered bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{root}, ClaudeXML: true}, &unbuffered, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		maxWrites int
+		minWrites int
+	}{
+		{name: "buffered", cfg: config.Config{}, maxWrites: unbuffered.Len()/outputBufferSize + 1},
+		{name: "flush every file", cfg: config.Config{FlushEveryFile: true}, minWrites: 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &countingWriter{}
+			originalStdout := osStdout
+			osStdout = out
+			t.Cleanup(func() { osStdout = originalStdout })
+
+			cfg := tt.cfg
+			cfg.Paths, cfg.ClaudeXML = []string{root}, true
+			summary, err := Run(cfg)
+			require.NoError(t, err)
+			assert.Equal(t, 200, summary.Files)
+			assert.Equal(t, unbuffered.String(), out.String())
+			if tt.maxWrites > 0 {
+				assert.LessOrEqual(t, out.writes, tt.maxWrites)
+			}
+			assert.GreaterOrEqual(t, out.writes, tt.minWrites)
+		})
+	}
+}
+
+func TestRunFlushesOnError(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"src/a.go": "package a\n"})
+	require.NoError(t, os.Mkdir(filepath.Join(root, "empty"), 0o750))
+	stdout := withStdout(t)
+
+	// A failure after the documents were rendered still writes them out
+	_, err := Run(config.Config{Paths: []string{filepath.Join(root, "src"), filepath.Join(root, "empty")}, FailOnEmpty: true})
+	require.Error(t, err)
+	assert.Contains(t, stdout.String(), "package a\n")
+
+	osStdout = failingWriter{}
+	_, err = Run(config.Config{Paths: []string{root}})
+	require.ErrorIs(t, err, os.ErrClosed)
+}
+
+// BenchmarkRunOutput reports the writes reaching the output destination per
+// run, with and without --flush-every-file.
+func BenchmarkRunOutput(b *testing.B) {
+	root := b.TempDir()
+	writeSyntheticTree(b, root, 2000)
+	for _, bc := range []struct {
+		name string
+		cfg  config.Config
+	}{
+		{"buffered", config.Config{}},
+		{"flush every file", config.Config{FlushEveryFile: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			originalStdout := osStdout
+			b.Cleanup(func() { osStdout = originalStdout })
+			cfg := bc.cfg
+			cfg.Paths = []string{root}
+			writes := 0
+			for b.Loop() {
+				out := &countingWriter{}
+				osStdout = out
+				if _, err := Run(cfg); err != nil {
+					b.Fatal(err)
+				}
+				writes += out.writes
+			}
+			b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+		})
+	}
+}
@@ -0,0 +1,496 @@
+package files2prompt
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// Origin records how a candidate path reached the planner.
+type Origin string
+
+// Candidate origins recorded in PlannedFile.Origin.
+const (
+	// OriginArg is a path named on the command line.
+	OriginArg Origin = "argument"
+	// OriginStdin is a path read from standard input.
+	OriginStdin Origin = "stdin"
+	// OriginWalk is a path found while walking a directory.
+	OriginWalk Origin = "walk"
+	// OriginGlob is a path matched by a glob pattern given as an argument.
+	OriginGlob Origin = "glob"
+)
+
+// candidate is a path under consideration by the filter pipeline.
+type candidate struct {
+	path   string
+	info   os.FileInfo
+	origin Origin
+	// abs is the cleaned absolute path, which .gitignore and .gitattributes
+	// rules are matched against whatever the spelling of path
+	abs string
+	// rel is the path relative to the directory being walked or, for
+	// candidates that were named directly, to their matchBase
+	rel string
+}
+
+// filter is a single stage of the pipeline. It applies only to candidates whose
+// origin is listed in origins, and to directories only when dirs is set.
+type filter struct {
+	reason  SkipReason
+	origins []Origin
+	dirs    bool
+	skip    func(p *filterPipeline, c candidate) bool
+}
+
+// filters is the pipeline run by filterDecision, in order. The origins encode
+// the policy for each kind of path:
+//
+//   - walk: every filter applies.
+//   - glob: the matches of a glob argument are filtered as though they had been
+//     found walking the directory the pattern starts from.
+//   - stdin: a list from find, fd or git ls-files has already made the implicit
+//     choices, such as hidden files and .gitignore rules. Only the filters the
+//     user asked for apply: patterns, extensions, size bounds and --grep.
+//   - argument: a path named explicitly is always honored, apart from --grep.
+//
+// The sensitive-file rule, the file-type rules, the read limit and the jail
+// protect every origin.
+var filters = []filter{
+	// Prune the walk at --max-depth before any rule is read below it
+	{reason: SkipDepth, origins: []Origin{OriginWalk}, dirs: true, skip: (*filterPipeline).tooDeep},
+	{reason: SkipVCS, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).vcsMetadata},
+	{reason: SkipDangling, origins: []Origin{OriginWalk}, skip: (*filterPipeline).dangling},
+	{reason: SkipJunk, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).junk},
+	{reason: SkipHidden, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).hidden},
+	{reason: SkipSensitive, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: (*filterPipeline).sensitive},
+	{reason: SkipGitignore, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).gitignored},
+	{reason: SkipUntracked, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).untracked},
+	{reason: SkipExportIgnore, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).exportIgnored},
+	{reason: SkipSubmodule, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).skippedSubmodule},
+	{reason: SkipIgnore, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, dirs: true, skip: (*filterPipeline).ignored},
+	{reason: SkipDefaults, origins: []Origin{OriginWalk, OriginGlob}, dirs: true, skip: (*filterPipeline).defaultIgnored},
+	{reason: SkipExtension, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).wrongExtension},
+	{reason: SkipExcludeExt, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).excludedExtension},
+	{reason: SkipInclude, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).notIncluded},
+	{reason: SkipMaxSize, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).aboveMaxSize},
+	{reason: SkipMinSize, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).belowMinSize},
+	{reason: SkipModTime, origins: []Origin{OriginWalk, OriginGlob, OriginStdin}, skip: (*filterPipeline).outsideModTimes},
+	// Never open pipes, sockets or devices, which can block or never end
+	{reason: SkipNamedPipe, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: irregular(SkipNamedPipe)},
+	{reason: SkipSocket, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: irregular(SkipSocket)},
+	{reason: SkipDevice, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: irregular(SkipDevice)},
+	{reason: SkipIrregular, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: irregular(SkipIrregular)},
+	{reason: SkipVirtualFS, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, dirs: true, skip: (*filterPipeline).onVirtualFS},
+	{reason: SkipTooLarge, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: (*filterPipeline).tooLarge},
+	{reason: SkipJail, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, dirs: true, skip: (*filterPipeline).outsideJail},
+	{reason: SkipSymlinkDir, origins: []Origin{OriginWalk}, dirs: true, skip: (*filterPipeline).symlinkedDir},
+	{reason: SkipGenerated, origins: []Origin{OriginWalk, OriginGlob}, skip: (*filterPipeline).generated},
+	// Select files by content last, since it requires reading them
+	{reason: SkipGrep, origins: []Origin{OriginWalk, OriginGlob, OriginStdin, OriginArg}, skip: (*filterPipeline).grepMiss},
+}
+
+// filterPipeline holds the state the filters need while planning a single path argument.
+type filterPipeline struct {
+	config            config.Config
+	limit             int64
+	maxDepth          int
+	modTimes          modTimeWindow
+	grep              *regexp.Regexp
+	jail              *jail
+	tracked           *trackedFiles
+	gitignoreRules    []gitignoreRule
+	gitignoreMatcher  *ignoreMatcher
+	exportIgnoreRules []attrRule
+	// generatedRules are the linguist-generated rules of the walked directories
+	generatedRules []attrRule
+	submoduleMode  SubmoduleMode
+	// noContent keeps the filters from reading any file, for Match
+	noContent bool
+	// submodules is nil unless a directory is being walked
+	submodules *submoduleTracker
+	// scopes holds the ignore state of the enclosing repositories while the
+	// walk is inside submodules, innermost last
+	scopes []ignoreScope
+}
+
+func newFilterPipeline(config config.Config, gitignoreRules []gitignoreRule, grep *regexp.Regexp) *filterPipeline {
+	// An invalid mode has already been rejected by planFiles
+	mode, _ := submoduleMode(config)
+	depth, _ := maxDepth(config)
+	window, _ := modTimes(config, time.Now())
+	return &filterPipeline{
+		config:           config,
+		limit:            readLimit(config),
+		maxDepth:         depth,
+		modTimes:         window,
+		grep:             grep,
+		gitignoreRules:   gitignoreRules,
+		gitignoreMatcher: compileIgnoreRules(gitignoreRules),
+		submoduleMode:    mode,
+	}
+}
+
+// fork returns a copy of p to walk another path argument with, from the state
+// p is in; the rules the walk reads are added to the copy only.
+func (p *filterPipeline) fork() *filterPipeline {
+	q := *p
+	q.gitignoreRules = slices.Clip(p.gitignoreRules)
+	q.exportIgnoreRules = slices.Clip(p.exportIgnoreRules)
+	q.generatedRules = slices.Clip(p.generatedRules)
+	q.scopes = slices.Clip(p.scopes)
+	return &q
+}
+
+// enterSubmodule sets the current ignore rules aside for a submodule about to be
+// walked: a submodule is a separate repository, so only its own rules apply inside it.
+func (p *filterPipeline) enterSubmodule(dir string) {
+	p.scopes = append(p.scopes, ignoreScope{
+		dir:               dir,
+		gitignoreRules:    p.gitignoreRules,
+		gitignoreMatcher:  p.gitignoreMatcher,
+		exportIgnoreRules: p.exportIgnoreRules,
+		generatedRules:    p.generatedRules,
+	})
+	p.gitignoreRules = nil
+	p.gitignoreMatcher = compileIgnoreRules(nil)
+	p.exportIgnoreRules = nil
+	p.generatedRules = nil
+}
+
+// leaveSubmodules restores the ignore rules of every submodule the walk has left
+// by the time it reaches path.
+func (p *filterPipeline) leaveSubmodules(path string) {
+	for n := len(p.scopes); n > 0 && !withinDir(path, p.scopes[n-1].dir); n = len(p.scopes) {
+		scope := p.scopes[n-1]
+		p.gitignoreRules = scope.gitignoreRules
+		p.gitignoreMatcher = scope.gitignoreMatcher
+		p.exportIgnoreRules = scope.exportIgnoreRules
+		p.generatedRules = scope.generatedRules
+		p.scopes = p.scopes[:n-1]
+	}
+}
+
+// currentSubmodule returns the innermost submodule being walked, or "".
+func (p *filterPipeline) currentSubmodule() string {
+	if len(p.scopes) == 0 {
+		return ""
+	}
+	return p.scopes[len(p.scopes)-1].dir
+}
+
+// enterDir loads the .gitignore, .gitattributes and .gitmodules of a directory about to be walked.
+func (p *filterPipeline) enterDir(dir string) {
+	if p.submodules != nil {
+		p.submodules.load(dir)
+	}
+	if appliesGitignore(p.config) {
+		// Rules read later, from deeper directories, take precedence
+		if newRules := cachedGitignoreRules(dir); len(newRules) > 0 {
+			for _, r := range newRules {
+				p.gitignoreRules = append(p.gitignoreRules, r.gitignoreRule)
+			}
+			p.gitignoreMatcher = p.gitignoreMatcher.with(newRules)
+		}
+	}
+	if p.config.UseExportIgnore {
+		p.exportIgnoreRules = append(p.exportIgnoreRules, readGitattributes(dir, "export-ignore")...)
+	}
+	if generatedSkipped(p.config) {
+		p.generatedRules = append(p.generatedRules, readLinguistAttributes(dir, "linguist-generated")...)
+	}
+}
+
+// filterDecision runs c through every filter that applies to its origin and
+// returns the reason of the first one that rejects it, or "" to include it.
+func (p *filterPipeline) filterDecision(c candidate) SkipReason {
+	for _, f := range filters {
+		if (c.info.IsDir() && !f.dirs) || !containsOrigin(f.origins, c.origin) {
+			continue
+		}
+		if f.skip(p, c) {
+			return f.reason
+		}
+	}
+	return ""
+}
+
+// decide returns the filterDecision for c, a candidate reached by walking in
+// order, and enters c when it is a directory the walk descends into.
+func (p *filterPipeline) decide(c candidate) SkipReason {
+	p.leaveSubmodules(c.path)
+	reason := p.filterDecision(c)
+	if c.info.IsDir() && reason == "" {
+		if c.origin == OriginWalk && p.submodules.isSubmodule(c.path) {
+			p.enterSubmodule(c.path)
+		}
+		// Rules found in a directory apply to what is beneath it
+		p.enterDir(c.abs)
+	}
+	return reason
+}
+
+func containsOrigin(origins []Origin, origin Origin) bool {
+	for _, o := range origins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// vcsMetadataNames are the directories in which version control systems keep
+// their own data. Git also uses a .git file in submodules and linked worktrees.
+var vcsMetadataNames = []string{".git", ".hg", ".svn"}
+
+// vcsMetadata skips version control metadata, even with --include-hidden,
+// unless --include-vcs-dirs is set.
+func (p *filterPipeline) vcsMetadata(c candidate) bool {
+	return !p.config.IncludeVCSDirs && slices.Contains(vcsMetadataNames, filepath.Base(c.path))
+}
+
+// junk skips operating system metadata and editor leftovers, which
+// files-to-prompt does not recognize, unless --include-junk is given.
+func (p *filterPipeline) junk(c candidate) bool {
+	if p.config.IncludeJunk || CompatMode(p.config.Compat) == CompatFilesToPrompt {
+		return false
+	}
+	reason, ok := isJunk(c.path)
+	if ok {
+		log.Debugf("Skipping %s (%s)", c.path, reason)
+	}
+	return ok
+}
+
+// hidden skips hidden files and directories unless specified.
+func (p *filterPipeline) hidden(c candidate) bool {
+	return !p.config.IncludeHidden && strings.HasPrefix(filepath.Base(c.path), ".")
+}
+
+// tooDeep skips the directories at the --max-depth level, so the walk does not
+// descend into them; the files there are kept.
+func (p *filterPipeline) tooDeep(c candidate) bool {
+	return p.maxDepth > 0 && c.info.IsDir() && pathDepth(c.rel) >= p.maxDepth
+}
+
+// onVirtualFS skips the paths on a virtual file system such as procfs or
+// sysfs, whose files report sizes that do not bound what reading them yields,
+// unless --include-virtual-fs is given. Within a walk only directories and
+// symlinks are checked, since a regular file is on its directory's file system.
+func (p *filterPipeline) onVirtualFS(c candidate) bool {
+	if p.config.IncludeVirtualFS || (c.origin == OriginWalk && c.info.Mode().IsRegular()) {
+		return false
+	}
+	name, ok := virtualFileSystem(c.path)
+	if ok {
+		log.Debugf("Skipping %s: it is on a %s file system", c.path, name)
+	}
+	return ok
+}
+
+// symlinkedDir skips the symlinks to directories the walk does not follow:
+// every one without --follow-symlinks, and with it those leading back into a
+// directory being walked. A symlinked directory named as an argument, or
+// matched by a glob, is always walked.
+func (p *filterPipeline) symlinkedDir(c candidate) bool {
+	if c.info.Mode()&os.ModeSymlink == 0 || c.info.IsDir() {
+		// Not a link, or one the walk follows
+		return false
+	}
+	info, err := os.Stat(c.path)
+	return err == nil && info.IsDir()
+}
+
+// dangling skips symlinks that lead nowhere, to a missing target or round a
+// loop of links, which could only fail to be read.
+func (p *filterPipeline) dangling(c candidate) bool {
+	if c.info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	_, err := os.Stat(c.path)
+	return err != nil
+}
+
+// sensitive withholds files that commonly contain secrets.
+func (p *filterPipeline) sensitive(c candidate) bool {
+	if p.config.IncludeSensitive {
+		return false
+	}
+	reason, ok := isSensitive(c.path)
+	if ok {
+		log.Debugf("Withholding %s (%s)", c.path, reason)
+	}
+	return ok
+}
+
+// gitignored applies the .gitignore rules of the directories enclosing c.
+func (p *filterPipeline) gitignored(c candidate) bool {
+	return appliesGitignore(p.config) && p.gitignoreMatcher.match(c.abs, c.info.IsDir())
+}
+
+// untracked prunes, with --git-tracked, the files git does not track and the
+// directories holding none it does.
+func (p *filterPipeline) untracked(c candidate) bool {
+	return !p.tracked.tracks(c)
+}
+
+// exportIgnored applies .gitattributes export-ignore rules.
+func (p *filterPipeline) exportIgnored(c candidate) bool {
+	return p.config.UseExportIgnore && hasAttribute(c.abs, c.info.IsDir(), p.exportIgnoreRules)
+}
+
+// generated skips the files .gitattributes marks linguist-generated and,
+// failing any such rule, Go files whose header says they are generated, unless
+// generatedSkipped says otherwise. An explicit -linguist-generated keeps a file
+// whatever its header.
+func (p *filterPipeline) generated(c candidate) bool {
+	if !generatedSkipped(p.config) {
+		return false
+	}
+	if set, matched := attributeState(c.abs, false, p.generatedRules); matched {
+		return set
+	}
+	if p.noContent || filepath.Ext(c.path) != ".go" {
+		return false
+	}
+	head, err := readHead(c.path)
+	// An unreadable file is left for emission to report
+	return err == nil && goGeneratedHeader.Match(head)
+}
+
+// skippedSubmodule prunes submodule directories with --submodules skip.
+func (p *filterPipeline) skippedSubmodule(c candidate) bool {
+	return p.submoduleMode == SubmodulesSkip && p.submodules != nil && c.info.IsDir() && p.submodules.isSubmodule(c.path)
+}
+
+// DefaultIgnorePatterns are the dependency, build output, cache and editor
+// directories, and generated files, that walks skip unless
+// --no-default-ignores is given. They are applied alongside --ignore.
+var DefaultIgnorePatterns = []string{
+	"node_modules/", "vendor/", "dist/", "build/", "target/", ".venv/", "venv/", "__pycache__/",
+	".idea/", ".vscode/", "coverage/", ".next/", ".terraform/", "*.min.js", "*.lock",
+}
+
+// defaultIgnored applies DefaultIgnorePatterns, which files-to-prompt does not have.
+func (p *filterPipeline) defaultIgnored(c candidate) bool {
+	if p.config.DisableDefaultIgnores || CompatMode(p.config.Compat) == CompatFilesToPrompt {
+		return false
+	}
+	return matchesPatterns(DefaultIgnorePatterns, c, p.config.IgnoreCase)
+}
+
+// ignored applies the ignore patterns to both files and directories.
+func (p *filterPipeline) ignored(c candidate) bool {
+	return matchesPatterns(p.config.IgnorePatterns, c, p.config.IgnoreCase)
+}
+
+// notIncluded applies the include patterns. It is never run on directories, so
+// that the walk still descends into them.
+func (p *filterPipeline) notIncluded(c candidate) bool {
+	return len(p.config.IncludePatterns) > 0 && !matchesPatterns(p.config.IncludePatterns, c, p.config.IgnoreCase)
+}
+
+// matchesPatterns reports whether c matches any of patterns, each of which may
+// hold several comma-separated doublestar patterns. With ignoreCase, letters
+// match regardless of case.
+func matchesPatterns(patterns []string, c candidate, ignoreCase bool) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	base, rel := filepath.Base(c.path), c.rel
+	if ignoreCase {
+		base, rel = strings.ToLower(base), strings.ToLower(rel)
+	}
+	for _, g := range compiledPatterns(patterns, ignoreCase) {
+		if g.matches(base, rel, c.info.IsDir()) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrongExtension applies the extension filter.
+func (p *filterPipeline) wrongExtension(c candidate) bool {
+	if len(p.config.Extensions) == 0 {
+		return false
+	}
+	for _, allowedExt := range p.config.Extensions {
+		// files-to-prompt keeps any name ending in the extension, so "-e py" works
+		if CompatMode(p.config.Compat) == CompatFilesToPrompt && strings.HasSuffix(c.path, allowedExt) {
+			return false
+		}
+	}
+	return !hasExtension(c.path, p.config.Extensions, p.config.IgnoreCase)
+}
+
+// excludedExtension applies --exclude-ext, after the extension filter.
+func (p *filterPipeline) excludedExtension(c candidate) bool {
+	return hasExtension(c.path, p.config.ExcludeExtensions, p.config.IgnoreCase)
+}
+
+// hasExtension reports whether the name of path ends in one of exts, each
+// normalized by normalizeExtension, so that multi-part extensions such as
+// "tar.gz" work too. With ignoreCase, ".GO" is the extension ".go".
+func hasExtension(path string, exts []string, ignoreCase bool) bool {
+	name := filepath.Base(path)
+	if ignoreCase {
+		name = strings.ToLower(name)
+	}
+	for _, e := range exts {
+		ext := normalizeExtension(e)
+		if ignoreCase {
+			ext = strings.ToLower(ext)
+		}
+		if e != "" && strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeExtension returns ext with a single leading dot, so that "go", ".go"
+// and "*.go" all name the same extension.
+func normalizeExtension(ext string) string {
+	return "." + strings.TrimLeft(strings.TrimPrefix(ext, "*"), ".")
+}
+
+// aboveMaxSize applies --max-size.
+func (p *filterPipeline) aboveMaxSize(c candidate) bool {
+	return p.config.MaxFileSize > 0 && c.info.Size() > int64(p.config.MaxFileSize)
+}
+
+// belowMinSize applies --min-size.
+func (p *filterPipeline) belowMinSize(c candidate) bool {
+	return c.info.Size() < int64(p.config.MinFileSize)
+}
+
+// outsideModTimes applies --newer-than and --older-than.
+func (p *filterPipeline) outsideModTimes(c candidate) bool {
+	return !p.modTimes.contains(c.info.ModTime())
+}
+
+// tooLarge never lets files above the read limit through, whatever the other filters decided.
+func (p *filterPipeline) tooLarge(c candidate) bool {
+	return c.info.Size() > p.limit
+}
+
+// outsideJail keeps symlinks found in the walk from leading out of the --jail
+// directory. Named paths were checked before planning, and a walk that starts
+// inside the jail cannot otherwise leave it.
+func (p *filterPipeline) outsideJail(c candidate) bool {
+	if p.jail == nil || (c.origin == OriginWalk && c.info.Mode()&os.ModeSymlink == 0) {
+		return false
+	}
+	return p.jail.check(c.path) != nil
+}
+
+// grepMiss applies the --grep content filter.
+func (p *filterPipeline) grepMiss(c candidate) bool {
+	return !grepMatches(c.path, p.grep, p.limit, zeroSizeLimit(p.config))
+}
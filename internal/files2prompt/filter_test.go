@@ -0,0 +1,462 @@
+package files2prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestFilterDecisionMatrix(t *testing.T) {
+	dir := t.TempDir()
+	allOrigins := []Origin{OriginWalk, OriginStdin, OriginArg}
+
+	tests := []struct {
+		reason SkipReason
+		name   string
+		config config.Config
+		setup  func(p *filterPipeline)
+		// origins lists the origins the filter applies to
+		origins []Origin
+	}{
+		{reason: SkipVCS, name: ".git", config: config.Config{IncludeHidden: true}, origins: []Origin{OriginWalk}},
+		{reason: SkipHidden, name: ".hidden.dat", origins: []Origin{OriginWalk}},
+		{reason: SkipSensitive, name: "server.pem", origins: allOrigins},
+		{reason: SkipGitignore, name: "app.log", config: config.Config{IgnoreGitignore: true},
+			setup: func(p *filterPipeline) {
+				p.gitignoreRules = parseGitignore(dir, []string{"*.log"})
+				p.gitignoreMatcher = compileIgnoreRules(p.gitignoreRules)
+			},
+			origins: []Origin{OriginWalk}},
+		{reason: SkipExportIgnore, name: "vendored.dat", config: config.Config{UseExportIgnore: true},
+			setup: func(p *filterPipeline) {
+				p.exportIgnoreRules = []attrRule{{base: dir, pattern: "vendored.*", set: true}}
+			},
+			origins: []Origin{OriginWalk}},
+		{reason: SkipGenerated, name: "client.dat",
+			setup: func(p *filterPipeline) {
+				p.generatedRules = []attrRule{{base: dir, pattern: "client.*", set: true}}
+			},
+			origins: []Origin{OriginWalk}},
+		{reason: SkipIgnore, name: "skip.dat", config: config.Config{IgnorePatterns: []string{"skip.*"}},
+			origins: []Origin{OriginWalk, OriginStdin}},
+		{reason: SkipExtension, name: "notes.dat", config: config.Config{Extensions: []string{".go"}},
+			origins: []Origin{OriginWalk, OriginStdin}},
+		{reason: SkipExcludeExt, name: "data.json", config: config.Config{ExcludeExtensions: []string{"json"}},
+			origins: []Origin{OriginWalk, OriginStdin}},
+		{reason: SkipInclude, name: "other.dat", config: config.Config{IncludePatterns: []string{"*.go"}},
+			origins: []Origin{OriginWalk, OriginStdin}},
+		{reason: SkipMaxSize, name: "bundle.dat", config: config.Config{MaxFileSize: 2},
+			origins: []Origin{OriginWalk, OriginStdin}},
+		{reason: SkipMinSize, name: "stub.dat", config: config.Config{MinFileSize: 4},
+			origins: []Origin{OriginWalk, OriginStdin}},
+		{reason: SkipTooLarge, name: "big.dat", config: config.Config{ReadLimit: 2}, origins: allOrigins},
+		{reason: SkipGrep, name: "haystack.dat", config: config.Config{Grep: "needle"}, origins: allOrigins},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		require.NoError(t, os.WriteFile(path, []byte("hay"), 0o600))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+
+		grep, err := compileGrep(tt.config)
+		require.NoError(t, err)
+		p := newFilterPipeline(tt.config, nil, grep)
+		if tt.setup != nil {
+			tt.setup(p)
+		}
+
+		for _, origin := range allOrigins {
+			t.Run(string(tt.reason)+"/"+string(origin), func(t *testing.T) {
+				expected := SkipReason("")
+				if containsOrigin(tt.origins, origin) {
+					expected = tt.reason
+				}
+				assert.Equal(t, expected, p.filterDecision(candidate{path: path, info: info, origin: origin, abs: path, rel: tt.name}))
+			})
+		}
+	}
+}
+
+func TestFilterDecisionDirectories(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{".git", "build", "vendor.dat"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0o700))
+	}
+	cfg := config.Config{
+		IgnorePatterns: []string{"build/"},
+		Extensions:     []string{".go"},
+		Grep:           "needle",
+		ReadLimit:      1,
+	}
+	grep, err := compileGrep(cfg)
+	require.NoError(t, err)
+	p := newFilterPipeline(cfg, nil, grep)
+
+	decide := func(name string) SkipReason {
+		info, err := os.Stat(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return p.filterDecision(candidate{path: filepath.Join(dir, name), info: info, origin: OriginWalk, abs: filepath.Join(dir, name), rel: name})
+	}
+	assert.Equal(t, SkipVCS, decide(".git"))
+	assert.Equal(t, SkipIgnore, decide("build"))
+	// File-only filters never prune directories
+	assert.Equal(t, SkipReason(""), decide("vendor.dat"))
+}
+
+func TestExtensionFilters(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":          "package main\n",
+		"main_test.go":     "package main\n",
+		"data.json":        "{}\n",
+		"logo.svg":         "<svg/>\n",
+		"README.md":        "# readme\n",
+		"web/schema.json":  "{}\n",
+		"web/index.ts":     "export {}\n",
+		"web/types.d.ts":   "export {}\n",
+		"Makefile":         "all:\n",
+		"archive.tar.gz":   "x",
+		"notes.JSON":       "{}\n",
+		"docs/diagram.svg": "<svg/>\n",
+	})
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name:    "exclude only",
+			exclude: []string{".json", "svg"},
+			want:    []string{"Makefile", "README.md", "archive.tar.gz", "main.go", "main_test.go", "notes.JSON", "web/index.ts", "web/types.d.ts"},
+		},
+		{
+			name:    "include without the dot",
+			include: []string{"go", "*.ts"},
+			want:    []string{"main.go", "main_test.go", "web/index.ts", "web/types.d.ts"},
+		},
+		{
+			name:    "include and exclude",
+			include: []string{".go", ".json", ".md"},
+			exclude: []string{"*.json"},
+			want:    []string{"README.md", "main.go", "main_test.go"},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{"ts", "gz"},
+			exclude: []string{".ts"},
+			want:    []string{"archive.tar.gz"},
+		},
+		{
+			name:    "multi-part extensions",
+			include: []string{"d.ts", "*.tar.gz"},
+			want:    []string{"archive.tar.gz", "web/types.d.ts"},
+		},
+		{
+			name:    "empty entries are ignored",
+			exclude: []string{"", "md"},
+			want:    []string{"Makefile", "archive.tar.gz", "data.json", "docs/diagram.svg", "logo.svg", "main.go", "main_test.go", "notes.JSON", "web/index.ts", "web/schema.json", "web/types.d.ts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Plan(context.Background(), config.Config{Paths: []string{dir}, Extensions: tt.include, ExcludeExtensions: tt.exclude}, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, includedPaths(t, dir, plan))
+		})
+	}
+}
+
+func TestIgnoreCase(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":       "package main\n",
+		"LEGACY.GO":     "package main\n",
+		"README.MD":     "# readme\n",
+		"error.log":     "oops\n",
+		"Photo.JPG":     "x",
+		"Docs/Guide.md": "# guide\n",
+	})
+
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		sensitive  []string
+		ignoreCase []string
+	}{
+		{
+			name:       "extension",
+			cfg:        config.Config{Extensions: []string{".go"}},
+			sensitive:  []string{"main.go"},
+			ignoreCase: []string{"LEGACY.GO", "main.go"},
+		},
+		{
+			name:       "upper-case extension",
+			cfg:        config.Config{Extensions: []string{"MD"}},
+			sensitive:  []string{"README.MD"},
+			ignoreCase: []string{"Docs/Guide.md", "README.MD"},
+		},
+		{
+			name:       "exclude extension",
+			cfg:        config.Config{ExcludeExtensions: []string{"go", "jpg", "md"}},
+			sensitive:  []string{"LEGACY.GO", "Photo.JPG", "README.MD", "error.log"},
+			ignoreCase: []string{"error.log"},
+		},
+		{
+			name:       "ignore pattern",
+			cfg:        config.Config{IgnorePatterns: []string{"*.LOG", "docs/"}},
+			sensitive:  []string{"Docs/Guide.md", "LEGACY.GO", "Photo.JPG", "README.MD", "error.log", "main.go"},
+			ignoreCase: []string{"LEGACY.GO", "Photo.JPG", "README.MD", "main.go"},
+		},
+		{
+			name:       "include pattern",
+			cfg:        config.Config{IncludePatterns: []string{"docs/**/*.MD"}},
+			sensitive:  nil,
+			ignoreCase: []string{"Docs/Guide.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Paths = []string{dir}
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sensitive, includedPaths(t, dir, plan))
+
+			cfg.IgnoreCase = true
+			plan, err = Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ignoreCase, includedPaths(t, dir, plan))
+		})
+	}
+}
+
+func TestNormalizeExtension(t *testing.T) {
+	for _, ext := range []string{"go", ".go", "*.go", "..go"} {
+		assert.Equal(t, ".go", normalizeExtension(ext), ext)
+	}
+	assert.Equal(t, ".d.ts", normalizeExtension("*.d.ts"))
+}
+
+func TestDefaultIgnores(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":                        "package main\n",
+		"web/app.js":                     "app()\n",
+		"web/app.min.js":                 "app()\n",
+		"web/node_modules/left/pad.js":   "pad()\n",
+		"node_modules/react/index.js":    "react()\n",
+		"yarn.lock":                      "# lock\n",
+		"py/__pycache__/mod.cpython.pyc": "x",
+		"py/mod.py":                      "x = 1\n",
+		"target/debug/app.d":             "x",
+		"docs/notes.md":                  "# notes\n",
+	})
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"docs/notes.md", "main.go", "py/mod.py", "web/app.js"},
+		},
+		{
+			name: "with --ignore",
+			cfg:  config.Config{IgnorePatterns: []string{"docs/"}},
+			want: []string{"main.go", "py/mod.py", "web/app.js"},
+		},
+		{
+			name: "--no-default-ignores",
+			cfg:  config.Config{DisableDefaultIgnores: true, IgnorePatterns: []string{"docs/"}},
+			want: []string{
+				"main.go", "node_modules/react/index.js", "py/__pycache__/mod.cpython.pyc", "py/mod.py",
+				"target/debug/app.d", "web/app.js", "web/app.min.js", "web/node_modules/left/pad.js", "yarn.lock",
+			},
+		},
+		{
+			name: "compat",
+			cfg:  config.Config{Compat: string(CompatFilesToPrompt), Extensions: []string{".js"}},
+			want: []string{"node_modules/react/index.js", "web/app.js", "web/app.min.js", "web/node_modules/left/pad.js"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Paths = []string{dir}
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, includedPaths(t, dir, plan))
+		})
+	}
+
+	// A path named directly is always honored
+	lib := filepath.Join(dir, "node_modules", "react")
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{filepath.Join(dir, "yarn.lock"), lib}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node_modules/react/index.js", "yarn.lock"}, includedPaths(t, dir, plan))
+}
+
+func TestPlanSkipsVCSMetadata(t *testing.T) {
+	// git refuses to track a directory named .git, so the fake repositories
+	// are built in a temporary directory rather than kept in testdata
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".git/HEAD":                     "ref: refs/heads/main\n",
+		".git/hooks/pre-commit.sample":  "#!/bin/sh\n",
+		".git/objects/pack/pack-1.pack": "PACK",
+		".hg/store/data.i":              "x",
+		"lib/.svn/entries":              "12\n",
+		"sub/.git":                      "gitdir: ../.git/modules/sub\n",
+		".env.example":                  "KEY=\n",
+		"main.go":                       "package main\n",
+		"sub/sub.go":                    "package sub\n",
+	})
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{dir}, IncludeHidden: true}, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range plan {
+		if !f.Included {
+			rel, err := filepath.Rel(dir, f.Path)
+			require.NoError(t, err)
+			reasons[filepath.ToSlash(rel)] = f.Reason
+		}
+	}
+	assert.Equal(t, map[string]SkipReason{".git": SkipVCS, ".hg": SkipVCS, "lib/.svn": SkipVCS, "sub/.git": SkipVCS}, reasons)
+	assert.Equal(t, []string{".env.example", "main.go", "sub/sub.go"}, includedPaths(t, dir, plan))
+
+	plan, err = Plan(context.Background(), config.Config{Paths: []string{dir}, IncludeHidden: true, IncludeVCSDirs: true}, false)
+	require.NoError(t, err)
+	assert.Contains(t, includedPaths(t, dir, plan), ".git/HEAD")
+	assert.Contains(t, includedPaths(t, dir, plan), "lib/.svn/entries")
+}
+
+func TestPlanOrigins(t *testing.T) {
+	cfg := config.Config{
+		Paths:      []string{"testdata/test_project/script.py", "testdata/test_project"},
+		StdinPaths: []string{"testdata/test_project/.hidden.go", "testdata/file1.txt"},
+		Extensions: []string{".go"},
+		// Keep the order the planner saw the paths in
+		Sort: "none",
+	}
+	all, err := Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+
+	type decision struct {
+		path   string
+		origin Origin
+		reason SkipReason
+	}
+	var got []decision
+	for _, f := range all {
+		got = append(got, decision{f.Path, f.Origin, f.Reason})
+	}
+	assert.Equal(t, []decision{
+		// Named explicitly, so the extension filter does not apply
+		{"testdata/test_project/script.py", OriginArg, ""},
+		{"testdata/test_project/.gitignore", OriginWalk, SkipHidden},
+		{"testdata/test_project/.hidden.go", OriginWalk, SkipHidden},
+		{"testdata/test_project/docs/README.txt", OriginWalk, SkipExtension},
+		{"testdata/test_project/script.py", OriginWalk, SkipExtension},
+		{"testdata/test_project/src/main.go", OriginWalk, ""},
+		{"testdata/test_project/temp/file.txt", OriginWalk, SkipExtension},
+		// Listed on stdin: hidden files are honored, but the extension filter applies
+		{"testdata/test_project/.hidden.go", OriginStdin, ""},
+		{"testdata/file1.txt", OriginStdin, SkipExtension},
+	}, got)
+}
+
+func TestIncludePatterns(t *testing.T) {
+	root := "testdata/test_project"
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected []string
+	}{
+		{
+			name:     "doublestar relative path",
+			config:   config.Config{IncludePatterns: []string{"src/**/*.go"}},
+			expected: []string{"src/main.go"},
+		},
+		{
+			name:     "base name with an ignored directory",
+			config:   config.Config{IncludePatterns: []string{"*.txt"}, IgnorePatterns: []string{"temp/"}},
+			expected: []string{"docs/README.txt"},
+		},
+		{
+			name:     "comma-separated and ignored files",
+			config:   config.Config{IncludePatterns: []string{"*.txt,*.py"}, IgnorePatterns: []string{"README.*"}},
+			expected: []string{"script.py", "temp/file.txt"},
+		},
+		{
+			name:     "repeated and combined with extensions",
+			config:   config.Config{IncludePatterns: []string{"*.txt", "*.go"}, Extensions: []string{".go"}},
+			expected: []string{"src/main.go"},
+		},
+		{
+			name:     "hidden files still need --include-hidden",
+			config:   config.Config{IncludePatterns: []string{"**/*.go"}, IncludeHidden: true},
+			expected: []string{".hidden.go", "src/main.go"},
+		},
+		{
+			name:     "directory names do not stop the walk",
+			config:   config.Config{IncludePatterns: []string{"main.go"}},
+			expected: []string{"src/main.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Paths = []string{root}
+			plan, err := Plan(context.Background(), tt.config, true)
+			require.NoError(t, err)
+
+			var included []string
+			for _, f := range plan {
+				rel, err := filepath.Rel(root, f.Path)
+				require.NoError(t, err)
+				switch {
+				case f.Included:
+					included = append(included, filepath.ToSlash(rel))
+				case f.IsDir:
+					assert.NotEqual(t, SkipInclude, f.Reason, "directory %s", rel)
+				}
+			}
+			assert.Equal(t, tt.expected, included)
+		})
+	}
+}
+
+func TestFilterPipelineFork(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":     "*.log\n",
+		".gitattributes": "gen.go linguist-generated\n",
+	})
+	base := newFilterPipeline(config.Config{IgnoreGitignore: true}, []gitignoreRule{{base: "/elsewhere", pattern: "*.tmp"}}, nil)
+
+	// A walk reads the rules of its directories into its copy only
+	walk := base.fork()
+	walk.enterDir(dir)
+	walk.enterSubmodule(filepath.Join(dir, "sub"))
+	assert.Len(t, walk.scopes, 1)
+	walk.leaveSubmodules(dir)
+	assert.Len(t, walk.gitignoreRules, 2)
+	assert.NotEmpty(t, walk.generatedRules)
+
+	assert.Equal(t, []gitignoreRule{{base: "/elsewhere", pattern: "*.tmp"}}, base.gitignoreRules)
+	assert.Len(t, base.gitignoreMatcher.rules, 1)
+	assert.Empty(t, base.generatedRules)
+	assert.Empty(t, base.scopes)
+	next := base.fork()
+	assert.Len(t, next.gitignoreRules, 1)
+	assert.Empty(t, next.generatedRules)
+}
@@ -0,0 +1,84 @@
+package files2prompt
+
+import (
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// sortTokens orders files by their tokenCost alone, leaving ties in the order
+// they were in. It is applied after the --sort order by --small-first.
+const sortTokens SortOrder = "tokens"
+
+// tokenCost estimates how many tokens the content of f costs, from its size.
+func tokenCost(f PlannedFile) int64 {
+	return estimateTokens(f.Size)
+}
+
+// FitReport describes how --fit-tokens selected the files of a run.
+type FitReport struct {
+	// Budget is the --fit-tokens budget.
+	Budget int64
+	// Candidates is the number of files that were eligible before the budget applied.
+	Candidates int
+	// Files is the number of files that fit.
+	Files int
+	// Tokens is the estimated cost of the files that fit.
+	Tokens int64
+	// DefaultFiles is the number of files that would have fit in the --sort
+	// order, without --small-first.
+	DefaultFiles int
+	// SmallFirst records whether the files were picked smallest first.
+	SmallFirst bool
+}
+
+// fitTokens keeps the included files of plan, in order, for as long as their
+// estimated tokens fit in budget. The first file that does not fit, and every
+// one after it, is skipped with SkipBudget, so the output stays a prefix of
+// the chosen order. It returns the number of files kept and their cost.
+func fitTokens(plan []PlannedFile, budget int64) (int, int64) {
+	var kept int
+	var used int64
+	full := false
+	for i := range plan {
+		if !plan[i].Included {
+			continue
+		}
+		if cost := tokenCost(plan[i]); !full && used+cost <= budget {
+			kept++
+			used += cost
+			continue
+		}
+		full = true
+		plan[i].Included, plan[i].Reason = false, SkipBudget
+	}
+	return kept, used
+}
+
+// newFitReport reports how --fit-tokens selected the files of plan, or returns
+// nil without it. The files that would have fit without --small-first are
+// counted by fitting the same candidates in the --sort order.
+func newFitReport(plan []PlannedFile, config config.Config) *FitReport {
+	if config.FitTokens <= 0 {
+		return nil
+	}
+	r := &FitReport{Budget: config.FitTokens, SmallFirst: config.SmallFirst}
+	var candidates []PlannedFile
+	for _, f := range plan {
+		if f.Included || f.Reason == SkipBudget {
+			r.Candidates++
+			if f.Included {
+				r.Files++
+				r.Tokens += tokenCost(f)
+			}
+			f.Included, f.Reason = true, ""
+			candidates = append(candidates, f)
+		}
+	}
+	r.DefaultFiles = r.Files
+	if config.SmallFirst {
+		// Validated when planning
+		order, _ := sortOrder(config)
+		sortPlan(candidates, order)
+		r.DefaultFiles, _ = fitTokens(candidates, config.FitTokens)
+	}
+	return r
+}
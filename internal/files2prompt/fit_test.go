@@ -0,0 +1,117 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// skewedTree writes a large file that sorts first by path, followed by ten
+// small files of growing size: 400, 440, ... 760 bytes.
+func skewedTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{"a_bundle.js": strings.Repeat("x", 30000)}
+	for i := range 10 {
+		files[fmt.Sprintf("b%02d.txt", 9-i)] = strings.Repeat("y", 400+40*i)
+	}
+	writeFiles(t, dir, files)
+	return dir
+}
+
+func TestFitTokensOrderings(t *testing.T) {
+	dir := skewedTree(t)
+
+	tests := []struct {
+		name   string
+		config config.Config
+		want   FitReport
+		first  string
+	}{
+		{
+			name:   "path order",
+			config: config.Config{FitTokens: 1000},
+			want:   FitReport{Budget: 1000, Candidates: 11},
+		},
+		{
+			name:   "small first",
+			config: config.Config{FitTokens: 1000, SmallFirst: true},
+			want:   FitReport{Budget: 1000, Candidates: 11, Files: 7, Tokens: 910, SmallFirst: true},
+			first:  "b09.txt",
+		},
+		{
+			name:   "size order",
+			config: config.Config{FitTokens: 1000, Sort: string(SortSize)},
+			want:   FitReport{Budget: 1000, Candidates: 11, Files: 7, Tokens: 910, DefaultFiles: 7},
+			first:  "b09.txt",
+		},
+		{
+			name:   "the whole budget",
+			config: config.Config{FitTokens: 10000, SmallFirst: true},
+			want:   FitReport{Budget: 10000, Candidates: 11, Files: 11, Tokens: 8950, DefaultFiles: 11, SmallFirst: true},
+			first:  "b09.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{dir}
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			require.NotNil(t, summary.Fit)
+			assert.Equal(t, tt.want, *summary.Fit)
+			assert.Equal(t, tt.want.Files, summary.Files)
+			if tt.first != "" {
+				assert.Contains(t, strings.SplitN(buf.String(), "\n", 2)[0], tt.first)
+			}
+		})
+	}
+}
+
+func TestFitTokensSkips(t *testing.T) {
+	dir := skewedTree(t)
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{dir}, FitTokens: 400, SmallFirst: true}, true)
+	require.NoError(t, err)
+
+	var kept, budget []string
+	for _, f := range plan {
+		rel := strings.TrimPrefix(f.Path, dir+"/")
+		switch {
+		case f.Included:
+			kept = append(kept, rel)
+		case f.Reason == SkipBudget:
+			budget = append(budget, rel)
+		}
+	}
+	assert.Equal(t, []string{"b09.txt", "b08.txt", "b07.txt"}, kept)
+	assert.Equal(t, []string{"b06.txt", "b05.txt", "b04.txt", "b03.txt", "b02.txt", "b01.txt", "b00.txt", "a_bundle.js"}, budget)
+
+	sentences := omissions(plan, config.Config{FitTokens: 400}, &emitState{})
+	assert.Equal(t, []string{"8 files that did not fit the 400-token budget were omitted: " +
+		dir + "/b06.txt, " + dir + "/b05.txt, " + dir + "/b04.txt and 5 more."}, sentences)
+
+	// The output is a prefix of the order: the small files would fit after the
+	// bundle, but nothing is picked past the first file that does not
+	plan, err = Plan(context.Background(), config.Config{Paths: []string{dir}, FitTokens: 400}, false)
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+}
+
+func TestSmallFirstKeepsSortOnTies(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"c.txt": "same", "a.txt": "same", "b.txt": "much longer content"})
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{dir}, SmallFirst: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "c.txt", "b.txt"}, includedPaths(t, dir, plan))
+	// Without a budget there is nothing to report
+	assert.Nil(t, newFitReport(plan, config.Config{SmallFirst: true}))
+}
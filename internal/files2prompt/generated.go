@@ -0,0 +1,160 @@
+package files2prompt
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// generatedMarkers match the notices code generators write at the top of their
+// output, such as Go's "// Code generated ... DO NOT EDIT." line.
+var generatedMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^\W*Code generated .* DO NOT EDIT\.?`),
+	regexp.MustCompile(`(?i)Generated by the protocol buffer compiler\.\s+DO NOT EDIT!`),
+	regexp.MustCompile(`(?m)^\W*@generated\b`),
+	regexp.MustCompile(`(?i)\bauto-?generated\b.*\bdo not (edit|modify)\b`),
+}
+
+// goGeneratedHeader matches the line Go's convention has generators write
+// before the package clause of the files they produce.
+var goGeneratedHeader = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.\r?$`)
+
+// isGenerated reports whether head, the start of a file, carries a
+// generated-code marker.
+func isGenerated(head []byte) bool {
+	return slices.ContainsFunc(generatedMarkers, func(re *regexp.Regexp) bool { return re.Match(head) })
+}
+
+// generatedSkipped reports whether walks skip generated files: unless
+// --include-generated is given, or --collapse-generated-siblings, which needs
+// them, and never when reproducing files-to-prompt.
+func generatedSkipped(config config.Config) bool {
+	return !config.IncludeGenerated && !config.CollapseSiblings && CompatMode(config.Compat) != CompatFilesToPrompt
+}
+
+// readHead reads up to detectSniffBytes from the start of the file at path.
+func readHead(path string) ([]byte, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, detectSniffBytes))
+}
+
+// generatedSuffixes are the suffixes code generators add to a schema's name,
+// before the extension, each with what it is replaced by to find the stem the
+// file shares with its siblings: foo.pb.go, foo.pb.ts and foo_pb2.py all have
+// the stem foo, while the gRPC stubs foo_grpc.pb.go and foo_pb2_grpc.py have
+// foo_grpc.
+var generatedSuffixes = []struct{ suffix, stem string }{
+	{"_pb2_grpc", "_grpc"},
+	{"_grpc_pb", "_grpc"},
+	{"_pb2", ""},
+	{"_pb", ""},
+	{".pb", ""},
+	{".g", ""},
+	{"_generated", ""},
+	{".generated", ""},
+}
+
+// defaultSiblingPriority is the order, by extension, in which
+// --collapse-generated-siblings picks the sibling to keep.
+var defaultSiblingPriority = []string{"go", "ts", "js", "py"}
+
+// siblingStem returns the name of the file at path without its extension and
+// generated-code suffix.
+func siblingStem(path string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, s := range generatedSuffixes {
+		if stem, ok := strings.CutSuffix(name, s.suffix); ok && stem != "" {
+			return stem + s.stem
+		}
+	}
+	return name
+}
+
+// siblingPriority returns the extension order of config, without leading dots.
+func siblingPriority(config config.Config) []string {
+	if len(config.SiblingPriority) == 0 {
+		return defaultSiblingPriority
+	}
+	priority := make([]string, len(config.SiblingPriority))
+	for i, ext := range config.SiblingPriority {
+		priority[i] = strings.TrimPrefix(ext, ".")
+	}
+	return priority
+}
+
+// collapseGeneratedSiblings marks, with --collapse-generated-siblings, the
+// included generated files that share a directory and a stem with another,
+// keeping only the one whose extension comes first in the priority order; the
+// others are emitted as stubs pointing to it. Files without a generated-code
+// marker are never collapsed, whatever their name.
+func collapseGeneratedSiblings(plan []PlannedFile, config config.Config) {
+	if !config.CollapseSiblings {
+		return
+	}
+	type key struct{ dir, stem string }
+	groups := map[key][]int{}
+	var keys []key
+	for i, f := range plan {
+		if !f.Included {
+			continue
+		}
+		k := key{filepath.Dir(f.Path), siblingStem(f.Path)}
+		if groups[k] == nil {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	priority := siblingPriority(config)
+	rank := func(i int) int {
+		ext := strings.TrimPrefix(filepath.Ext(plan[i].Path), ".")
+		if r := slices.Index(priority, ext); r >= 0 {
+			return r
+		}
+		return len(priority)
+	}
+	for _, k := range keys {
+		if len(groups[k]) < 2 {
+			continue
+		}
+		var generated []int
+		for _, i := range groups[k] {
+			head, err := readHead(plan[i].Path)
+			if err != nil {
+				// Left for emission to report
+				continue
+			}
+			if isGenerated(head) {
+				generated = append(generated, i)
+			}
+		}
+		if len(generated) < 2 {
+			continue
+		}
+		// Stable, so that ties keep the plan order
+		slices.SortStableFunc(generated, func(a, b int) int { return cmp.Compare(rank(a), rank(b)) })
+		kept := plan[generated[0]].Path
+		for _, i := range generated[1:] {
+			plan[i].SiblingOf = kept
+			log.Debugf("Collapsing generated sibling %s into %s", plan[i].Path, kept)
+		}
+	}
+}
+
+// siblingStub returns the content emitted for a file collapsed into its sibling.
+func siblingStub(f PlannedFile) string {
+	return fmt.Sprintf("[generated from the same source as %s; collapsed by --collapse-generated-siblings]\n", f.SiblingOf)
+}
@@ -0,0 +1,221 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		head string
+		want bool
+	}{
+		{"// Code generated by protoc-gen-go. DO NOT EDIT.\n// versions:\n", true},
+		{"// Code generated by protoc-gen-ts_proto. DO NOT EDIT.\n", true},
+		{"# -*- coding: utf-8 -*-\n# Generated by the protocol buffer compiler.  DO NOT EDIT!\n", true},
+		{"/*\n * @generated by codegen\n */\n", true},
+		{"// This file is auto-generated, do not edit by hand.\n", true},
+		{"package main\n\n// Code generated, or so the comment claims\n", false},
+		{"package foo\n\n// Helpers for the generated code. DO NOT EDIT lightly.\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isGenerated([]byte(tt.head)), "%q", tt.head)
+	}
+}
+
+func TestSkipGenerated(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitattributes":            "*.pb.go linguist-generated=true\npackage-lock.json linguist-generated\nclient/** linguist-generated\nclient/hand.go -linguist-generated\n",
+		"main.go":                   "package main\n",
+		"api/user.pb.go":            "package api\n",
+		"package-lock.json":         "{}\n",
+		"client/api.ts":             "export {}\n",
+		"client/hand.go":            "// Code generated by hand. DO NOT EDIT.\n\npackage client\n",
+		"mocks/mock_store.go":       "// Code generated by MockGen. DO NOT EDIT.\n// Source: store.go\n\npackage mocks\n",
+		"mocks/readme.md":           "// Code generated by MockGen. DO NOT EDIT.\n",
+		"tools/notgen.go":           "// Package tools. Code generated by nobody; DO NOT EDIT lightly.\npackage tools\n",
+		"sub/.gitattributes":        "*.txt linguist-generated\n",
+		"sub/out.txt":               "generated\n",
+		"sub/strings_string.go":     "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\r\n\r\npackage sub\r\n",
+		"sub/strings_string_old.go": "// Code generated by stringer; DO NOT EDIT\npackage sub\n",
+	})
+	t.Chdir(dir)
+
+	plan := func(t *testing.T, cfg config.Config) (included []string, reasons map[string]SkipReason) {
+		t.Helper()
+		cfg.Paths = []string{"."}
+		planned, err := Plan(context.Background(), cfg, true)
+		require.NoError(t, err)
+		reasons = map[string]SkipReason{}
+		for _, f := range planned {
+			rel, err := filepath.Rel(dir, absPath(f.Path))
+			require.NoError(t, err)
+			rel = filepath.ToSlash(rel)
+			if f.Included && !f.IsDir {
+				included = append(included, rel)
+			} else if !f.Included {
+				reasons[rel] = f.Reason
+			}
+		}
+		return included, reasons
+	}
+
+	t.Run("skipped by default", func(t *testing.T) {
+		included, reasons := plan(t, config.Config{})
+		// An explicit -linguist-generated keeps a file whatever its header, and
+		// only Go files are judged by theirs
+		assert.Equal(t, []string{"client/hand.go", "main.go", "mocks/readme.md", "sub/strings_string_old.go", "tools/notgen.go"}, included)
+		for _, path := range []string{"api/user.pb.go", "package-lock.json", "client/api.ts", "sub/out.txt", "mocks/mock_store.go", "sub/strings_string.go"} {
+			assert.Equal(t, SkipGenerated, reasons[path], path)
+		}
+	})
+
+	t.Run("included", func(t *testing.T) {
+		included, _ := plan(t, config.Config{IncludeGenerated: true})
+		// Everything but the hidden .gitattributes files
+		assert.Len(t, included, 11)
+	})
+
+	t.Run("collapse siblings", func(t *testing.T) {
+		// Collapsing generated siblings needs them walked
+		included, _ := plan(t, config.Config{CollapseSiblings: true})
+		assert.Contains(t, included, "api/user.pb.go")
+	})
+
+	t.Run("named directly", func(t *testing.T) {
+		planned, err := Plan(context.Background(), config.Config{Paths: []string{"api/user.pb.go"}}, false)
+		require.NoError(t, err)
+		require.Len(t, planned, 1)
+		assert.True(t, planned[0].Included)
+	})
+
+	t.Run("match", func(t *testing.T) {
+		results, err := Match(config.Config{}, ".", []string{"api/user.pb.go", "mocks/mock_store.go"})
+		require.NoError(t, err)
+		assert.Equal(t, SkipGenerated, results[0].Reason)
+		// Match reads no content, so the header is not looked at
+		assert.True(t, results[1].Included)
+	})
+}
+
+func TestSiblingStem(t *testing.T) {
+	for path, want := range map[string]string{
+		"api/user.pb.go":           "user",
+		"api/user.pb.ts":           "user",
+		"api/user_pb2.py":          "user",
+		"api/user_pb.js":           "user",
+		"api/user_grpc.pb.go":      "user_grpc",
+		"api/user_pb2_grpc.py":     "user_grpc",
+		"api/user_grpc_pb.js":      "user_grpc",
+		"lib/model.g.dart":         "model",
+		"api/user.go":              "user",
+		"api/.pb.go":               ".pb",
+		"api/user_generated.rs":    "user",
+		"api/user.generated.cs":    "user",
+		"api/user_pb2_extra.py":    "user_pb2_extra",
+		"api/schema.graphql":       "schema",
+		"api/schema.generated.tsx": "schema",
+	} {
+		assert.Equal(t, want, siblingStem(path), path)
+	}
+}
+
+// siblingFixture writes a protobuf triple, its gRPC stubs, an unrelated
+// hand-written file sharing the stem, and a generated file with no sibling.
+func siblingFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"api/user.pb.go":       "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n\ntype User struct{}\n",
+		"api/user.pb.ts":       "// Code generated by protoc-gen-ts_proto. DO NOT EDIT.\nexport interface User {}\n",
+		"api/user_pb2.py":      "# Generated by the protocol buffer compiler.  DO NOT EDIT!\nUSER = None\n",
+		"api/user_grpc.pb.go":  "// Code generated by protoc-gen-go-grpc. DO NOT EDIT.\npackage api\n",
+		"api/user_pb2_grpc.py": "# Generated by the gRPC Python protocol compiler plugin. DO NOT EDIT!\n# @generated\nimport grpc\n",
+		"api/user.go":          "package api\n\n// Hand-written helpers for User.\nfunc (User) Name() string { return \"\" }\n",
+		"api/order.pb.go":      "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n",
+		"web/user.pb.ts":       "// Code generated by protoc-gen-ts_proto. DO NOT EDIT.\nexport interface User {}\n",
+	})
+	return root
+}
+
+func TestCollapseGeneratedSiblings(t *testing.T) {
+	root := siblingFixture(t)
+	api := func(name string) string { return filepath.Join(root, "api", name) }
+
+	tests := []struct {
+		name     string
+		priority []string
+		want     map[string]string
+	}{
+		{
+			name: "default priority",
+			want: map[string]string{
+				"api/user.pb.ts":       api("user.pb.go"),
+				"api/user_pb2.py":      api("user.pb.go"),
+				"api/user_pb2_grpc.py": api("user_grpc.pb.go"),
+			},
+		},
+		{
+			name:     "configured priority",
+			priority: []string{".py", "ts"},
+			want: map[string]string{
+				"api/user.pb.go":      api("user_pb2.py"),
+				"api/user.pb.ts":      api("user_pb2.py"),
+				"api/user_grpc.pb.go": api("user_pb2_grpc.py"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{Paths: []string{root}, CollapseSiblings: true, SiblingPriority: tt.priority}
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			collapsed := map[string]string{}
+			for _, f := range plan {
+				if f.SiblingOf != "" {
+					rel, err := filepath.Rel(root, f.Path)
+					require.NoError(t, err)
+					collapsed[filepath.ToSlash(rel)] = f.SiblingOf
+				}
+			}
+			// The hand-written user.go, the lone order.pb.go and the copy in
+			// another directory are all kept
+			assert.Equal(t, tt.want, collapsed)
+		})
+	}
+}
+
+func TestCollapseGeneratedSiblingsOutput(t *testing.T) {
+	root := siblingFixture(t)
+	cfg := config.Config{Paths: []string{filepath.Join(root, "api")}, CollapseSiblings: true}
+
+	var out bytes.Buffer
+	summary, err := Generate(context.Background(), cfg, &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7, summary.Files)
+
+	docs := map[string]string{}
+	for _, doc := range parsePlain(t, out.String()) {
+		docs[filepath.Base(doc.path)] = doc.content
+	}
+	kept := filepath.Join(root, "api", "user.pb.go")
+	assert.Equal(t, "[generated from the same source as "+kept+"; collapsed by --collapse-generated-siblings]\n", docs["user.pb.ts"])
+	assert.Equal(t, docs["user.pb.ts"], docs["user_pb2.py"])
+	assert.Contains(t, docs["user.pb.go"], "type User struct{}")
+	assert.Contains(t, docs["user.go"], "Hand-written helpers")
+
+	// Off by default
+	out.Reset()
+	_, err = Generate(context.Background(), config.Config{Paths: cfg.Paths}, &out, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, out.String(), "collapsed by")
+}
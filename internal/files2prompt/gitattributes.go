@@ -0,0 +1,111 @@
+package files2prompt
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// attrRule is a single .gitattributes pattern that sets or unsets an attribute,
+// scoped to the directory containing the .gitattributes file it came from.
+type attrRule struct {
+	base    string
+	pattern string
+	set     bool
+}
+
+// readGitattributes parses the .gitattributes file in dir and returns the rules
+// that set or unset the named attribute. Macro definitions ([attr]...) and
+// unspecified (!attr) or valued (attr=value) states are ignored.
+func readGitattributes(dir string, attr string) []attrRule {
+	content, err := os.ReadFile(filepath.Join(dir, ".gitattributes")) // #nosec G304
+	if err != nil {
+		return nil
+	}
+	return parseGitattributes(string(content), dir, attr)
+}
+
+// readLinguistAttributes parses the .gitattributes file in dir as
+// readGitattributes does, for an attribute of GitHub's linguist such as
+// linguist-generated, which linguist also takes as set by attr=true and unset
+// by attr=false.
+func readLinguistAttributes(dir string, attr string) []attrRule {
+	content, err := os.ReadFile(filepath.Join(dir, ".gitattributes")) // #nosec G304
+	if err != nil {
+		return nil
+	}
+	return parseAttributes(string(content), dir, attr, true)
+}
+
+// parseGitattributes extracts the rules for attr from .gitattributes content read from base.
+func parseGitattributes(content string, base string, attr string) []attrRule {
+	return parseAttributes(content, base, attr, false)
+}
+
+// parseAttributes extracts the rules for attr from .gitattributes content read
+// from base, taking attr=true and attr=false as setting and unsetting it when
+// booleans is set.
+func parseAttributes(content string, base string, attr string, booleans bool) []attrRule {
+	var rules []attrRule
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[attr]") {
+			continue
+		}
+		// git skips a pattern it cannot parse, and so do the rules
+		pattern, err := translatePattern(fields[0])
+		if err != nil {
+			continue
+		}
+		for _, field := range fields[1:] {
+			switch {
+			case field == attr, booleans && field == attr+"=true":
+				rules = append(rules, attrRule{base: base, pattern: pattern, set: true})
+			case field == "-"+attr, booleans && field == attr+"=false":
+				rules = append(rules, attrRule{base: base, pattern: pattern, set: false})
+			}
+		}
+	}
+	return rules
+}
+
+// hasAttribute reports whether the last rule matching filePath sets the attribute.
+// Rules only apply to paths beneath the directory they were read from.
+func hasAttribute(filePath string, isDir bool, rules []attrRule) bool {
+	set, _ := attributeState(filePath, isDir, rules)
+	return set
+}
+
+// attributeState reports whether the last rule matching filePath sets the
+// attribute, and whether any rule matches it at all.
+func attributeState(filePath string, isDir bool, rules []attrRule) (set, matched bool) {
+	for _, rule := range rules {
+		rel, err := filepath.Rel(rule.base, filePath)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if matchAttrPattern(rule.pattern, filepath.ToSlash(rel), isDir) {
+			set, matched = rule.set, true
+		}
+	}
+	return set, matched
+}
+
+// matchAttrPattern matches a .gitattributes pattern against a slash-separated path
+// relative to the pattern's directory. Patterns without a slash match the base
+// name at any depth; a trailing slash restricts the pattern to directories.
+func matchAttrPattern(pattern, rel string, isDir bool) bool {
+	if strings.HasSuffix(pattern, "/") {
+		if !isDir {
+			return false
+		}
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if !strings.Contains(pattern, "/") {
+		return doublestar.MatchUnvalidated(pattern, path.Base(rel))
+	}
+	return doublestar.MatchUnvalidated(strings.TrimPrefix(pattern, "/"), rel)
+}
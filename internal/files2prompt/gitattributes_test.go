@@ -0,0 +1,124 @@
+package files2prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestReadGitattributes(t *testing.T) {
+	dir := "testdata/export_ignore"
+	expected := []attrRule{
+		{base: dir, pattern: "tests/", set: true},
+		{base: dir, pattern: "*.bin", set: true},
+		{base: dir, pattern: "keep.bin", set: false},
+		{base: dir, pattern: "/docs/internal.md", set: true},
+	}
+	assert.Equal(t, expected, readGitattributes(dir, "export-ignore"))
+	assert.Nil(t, readGitattributes("testdata/gitignore_nonexistent", "export-ignore"))
+}
+
+func TestParseGitattributes(t *testing.T) {
+	content := "[attr]vendored linguist-vendored export-ignore\n" +
+		"   # comment export-ignore\n" +
+		"fixtures/** export-ignore=false\n" +
+		"*.png binary -export-ignore\n" +
+		"build/ text export-ignore\n" +
+		"lonely\n"
+	expected := []attrRule{
+		{base: "root", pattern: "*.png", set: false},
+		{base: "root", pattern: "build/", set: true},
+	}
+	assert.Equal(t, expected, parseGitattributes(content, "root", "export-ignore"))
+}
+
+func TestParseLinguistAttributes(t *testing.T) {
+	content := "*.pb.go linguist-generated=true\n" +
+		"api/client/** linguist-generated\n" +
+		"api/client/hand.go -linguist-generated\n" +
+		"docs/*.md linguist-generated=false linguist-documentation\n" +
+		"vendor/** linguist-vendored\n"
+	expected := []attrRule{
+		{base: "root", pattern: "*.pb.go", set: true},
+		{base: "root", pattern: "api/client/**", set: true},
+		{base: "root", pattern: "api/client/hand.go", set: false},
+		{base: "root", pattern: "docs/*.md", set: false},
+	}
+	assert.Equal(t, expected, parseAttributes(content, "root", "linguist-generated", true))
+	// git itself takes attr=true as a value, not as set
+	assert.Equal(t, expected[1:3], parseGitattributes(content, "root", "linguist-generated"))
+
+	set, matched := attributeState("root/docs/guide.md", false, expected)
+	assert.False(t, set)
+	assert.True(t, matched)
+	_, matched = attributeState("root/main.go", false, expected)
+	assert.False(t, matched)
+}
+
+func TestHasAttribute(t *testing.T) {
+	rules := append(readGitattributes("testdata/export_ignore", "export-ignore"),
+		readGitattributes("testdata/export_ignore/sub", "export-ignore")...)
+
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "directory pattern", path: "testdata/export_ignore/tests", isDir: true, expected: true},
+		{name: "directory pattern does not match file", path: "testdata/export_ignore/docs/tests", isDir: false, expected: false},
+		{name: "glob pattern", path: "testdata/export_ignore/data.bin", expected: true},
+		{name: "glob pattern at depth", path: "testdata/export_ignore/docs/blob.bin", expected: true},
+		{name: "negated attribute overrides earlier set", path: "testdata/export_ignore/keep.bin", expected: false},
+		{name: "anchored path", path: "testdata/export_ignore/docs/internal.md", expected: true},
+		{name: "anchored path does not match elsewhere", path: "testdata/export_ignore/sub/docs/internal.md", expected: false},
+		{name: "unspecified attribute is ignored", path: "testdata/export_ignore/notes.txt", expected: false},
+		{name: "nested rules apply below their directory", path: "testdata/export_ignore/sub/gen.go", expected: true},
+		{name: "nested rules do not apply above their directory", path: "testdata/export_ignore/main.go", expected: false},
+		{name: "unrelated file", path: "testdata/export_ignore/docs/guide.md", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hasAttribute(tt.path, tt.isDir, rules))
+		})
+	}
+}
+
+func TestProcessPathExportIgnore(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:   "export-ignore disabled",
+			config: config.Config{},
+			expected: "testdata/export_ignore/data.bin\n---\nbinary\n---\n\n" +
+				"testdata/export_ignore/docs/guide.md\n---\n# Guide\n---\n\n" +
+				"testdata/export_ignore/docs/internal.md\n---\n# Internal\n---\n\n" +
+				"testdata/export_ignore/keep.bin\n---\nkept\n---\n\n" +
+				"testdata/export_ignore/main.go\n---\npackage main\n---\n\n" +
+				"testdata/export_ignore/sub/gen.go\n---\npackage sub\n---\n\n" +
+				"testdata/export_ignore/sub/notes.rst\n---\nsub notes\n---\n\n" +
+				"testdata/export_ignore/tests/foo_test.go\n---\npackage tests\n---\n\n",
+		},
+		{
+			name:   "export-ignore enabled",
+			config: config.Config{UseExportIgnore: true},
+			expected: "testdata/export_ignore/docs/guide.md\n---\n# Guide\n---\n\n" +
+				"testdata/export_ignore/keep.bin\n---\nkept\n---\n\n" +
+				"testdata/export_ignore/main.go\n---\npackage main\n---\n\n" +
+				"testdata/export_ignore/sub/notes.rst\n---\nsub notes\n---\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := renderPath("testdata/export_ignore", tt.config, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, output)
+		})
+	}
+}
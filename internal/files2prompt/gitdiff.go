@@ -0,0 +1,148 @@
+package files2prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// gitChange is a file git diff reports changed: its status letter, such as M
+// or R, and its absolute path, after any rename.
+type gitChange struct {
+	status byte
+	path   string
+}
+
+// gitDiffFlag returns the flag selecting changed files, or "" for none.
+func gitDiffFlag(config config.Config) string {
+	switch {
+	case config.GitDiff != "":
+		return "--git-diff"
+	case config.GitStaged:
+		return "--git-staged"
+	}
+	return ""
+}
+
+// gitDiffBase returns the arguments of git diff selecting what files are
+// compared with: the HEAD commit by default, --git-diff's ref, and the index
+// with --git-staged, against --git-diff's ref when both are given.
+func gitDiffBase(config config.Config) []string {
+	var base []string
+	if config.GitStaged {
+		base = append(base, "--cached")
+	}
+	switch {
+	case config.GitDiff != "":
+		base = append(base, config.GitDiff)
+	case !config.GitStaged:
+		base = append(base, "HEAD")
+	}
+	return base
+}
+
+// changedArgs replaces, with --git-diff or --git-staged, the path arguments
+// of args by the files git diff reports changed beneath them, or in the
+// repository of the working directory without any. Each is planned as a path
+// read from stdin is, being a list git made, and is reported under the path
+// argument it is beneath. Deleted files are left out with a warning. Paths
+// read from stdin are kept as they are.
+func changedArgs(args []pathArg, config config.Config) ([]pathArg, error) {
+	flag := gitDiffFlag(config)
+	if flag == "" {
+		return args, nil
+	}
+	var scopes, changed []pathArg
+	for _, arg := range args {
+		if arg.origin == OriginStdin {
+			changed = append(changed, arg)
+		} else {
+			scopes = append(scopes, arg)
+		}
+	}
+	if len(scopes) == 0 {
+		scopes = []pathArg{{path: ".", origin: OriginArg, root: "."}}
+	}
+
+	repos := map[string][]gitChange{}
+	var roots []string
+	for _, scope := range scopes {
+		root, ok := findRepoRoot(scope.path)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a git repository, and %s is not in one", flag, scope.path)
+		}
+		if _, ok := repos[root]; ok {
+			continue
+		}
+		changes, err := repoChanges(root, config)
+		if err != nil {
+			return nil, err
+		}
+		repos[root] = changes
+		roots = append(roots, root)
+	}
+
+	base := strings.Join(gitDiffBase(config), " ")
+	for _, root := range roots {
+		for _, c := range repos[root] {
+			i := slices.IndexFunc(scopes, func(scope pathArg) bool { return withinDir(c.path, absPath(scope.path)) })
+			if i < 0 {
+				continue
+			}
+			rel, err := filepath.Rel(absPath(scopes[i].path), c.path)
+			if err != nil {
+				return nil, err
+			}
+			path := filepath.Join(scopes[i].path, rel)
+			if c.status == 'D' {
+				log.Warnf("Skipping %s: git diff %s reports it deleted", path, base)
+				continue
+			}
+			changed = append(changed, pathArg{path: path, origin: OriginStdin, root: scopes[i].root})
+		}
+	}
+	return changed, nil
+}
+
+// repoChanges runs git diff in the repository at root, comparing against
+// what config selects, and returns the files it reports changed.
+func repoChanges(root string, config config.Config) ([]gitChange, error) {
+	flag := gitDiffFlag(config)
+	// -z writes the paths as they are, without quoting
+	args := append([]string{"-C", root, "diff", "--name-status", "-z", "-M", "--no-ext-diff"}, gitDiffBase(config)...)
+	cmd := exec.Command("git", append(args, "--")...) // #nosec G204
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%s: git diff in %s failed: %s", flag, root, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("%s: %v", flag, err)
+	}
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var changes []gitChange
+	for i := 0; i+1 < len(fields); i += 2 {
+		status := fields[i]
+		if status == "" {
+			break
+		}
+		if status[0] == 'R' || status[0] == 'C' {
+			// The path before a rename or copy comes first, and is not shown
+			i++
+			if i+1 >= len(fields) {
+				break
+			}
+		}
+		changes = append(changes, gitChange{status: status[0], path: filepath.Join(root, filepath.FromSlash(fields[i+1]))})
+	}
+	return changes, nil
+}
@@ -0,0 +1,111 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":        "package main\n",
+		"README.md":      "# readme\n",
+		"old.go":         "package main\n\nfunc old() {}\n",
+		"pkg/util.go":    "package pkg\n",
+		"pkg/keep.go":    "package pkg\n",
+		"pkg/removed.go": "package pkg\n",
+	})
+	runGit(t, dir, "init", "-q", "-b", "main")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	// A modification, a rename and a deletion staged, then unstaged changes on top
+	writeFiles(t, dir, map[string]string{"pkg/staged.go": "package pkg\n\nfunc staged() {}\n"})
+	runGit(t, dir, "add", "pkg/staged.go")
+	runGit(t, dir, "mv", "old.go", "new.go")
+	runGit(t, dir, "rm", "-q", "pkg/removed.go")
+	writeFiles(t, dir, map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"pkg/util.go": "package pkg\n\nfunc util() {}\n",
+		"scratch.go":  "package main\n",
+	})
+	t.Chdir(dir)
+
+	plan := func(t *testing.T, cfg config.Config) (included []string, hook *logtest.Hook) {
+		t.Helper()
+		hook = logtest.NewGlobal()
+		t.Cleanup(hook.Reset)
+		planned, err := Plan(context.Background(), cfg, true)
+		require.NoError(t, err)
+		for _, f := range planned {
+			rel, err := filepath.Rel(dir, absPath(f.Path))
+			require.NoError(t, err)
+			if f.Included && !f.IsDir {
+				included = append(included, filepath.ToSlash(rel))
+			}
+		}
+		return included, hook
+	}
+
+	t.Run("against a ref", func(t *testing.T) {
+		included, hook := plan(t, config.Config{GitDiff: "main"})
+		// Untracked files are not changes, and the rename is under its new path
+		assert.Equal(t, []string{"main.go", "new.go", "pkg/staged.go", "pkg/util.go"}, included)
+		require.Len(t, hook.Entries, 1)
+		assert.Equal(t, "Skipping pkg/removed.go: git diff main reports it deleted", hook.LastEntry().Message)
+	})
+
+	t.Run("restricted to a path", func(t *testing.T) {
+		included, _ := plan(t, config.Config{Paths: []string{"pkg"}, GitDiff: "HEAD"})
+		assert.Equal(t, []string{"pkg/staged.go", "pkg/util.go"}, included)
+	})
+
+	t.Run("with extension", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"README.md": "# changed\n"})
+		t.Cleanup(func() { writeFiles(t, dir, map[string]string{"README.md": "# readme\n"}) })
+		included, _ := plan(t, config.Config{Paths: []string{"."}, GitDiff: "main", Extensions: []string{".md"}})
+		assert.Equal(t, []string{"README.md"}, included)
+	})
+
+	t.Run("staged", func(t *testing.T) {
+		included, hook := plan(t, config.Config{GitStaged: true})
+		// The unstaged edits to main.go and pkg/util.go are left out
+		assert.Equal(t, []string{"new.go", "pkg/staged.go"}, included)
+		require.Len(t, hook.Entries, 1)
+		assert.Equal(t, "Skipping pkg/removed.go: git diff --cached reports it deleted", hook.LastEntry().Message)
+	})
+
+	t.Run("hunks", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), config.Config{Paths: []string{"pkg"}, GitStaged: true, HunksOnly: true}, &buf, nil)
+		require.NoError(t, err)
+		// Only the staged file's hunks, as the index holds it
+		assert.Equal(t, "pkg/staged.go (1 hunk, 3 changed lines)\n---\n 1 │ +package pkg\n 2 │ +\n 3 │ +func staged() {}\n---\n\n", buf.String())
+	})
+
+	t.Run("bad ref", func(t *testing.T) {
+		_, err := Plan(context.Background(), config.Config{GitDiff: "no-such-branch"}, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--git-diff: git diff in "+dir+" failed: ")
+	})
+}
+
+func TestGitDiffOutsideRepository(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o600))
+	t.Chdir(dir)
+	_, err := Plan(context.Background(), config.Config{Paths: []string{"a.txt"}, GitStaged: true}, false)
+	assert.EqualError(t, err, "--git-staged needs a git repository, and a.txt is not in one")
+}
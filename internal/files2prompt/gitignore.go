@@ -0,0 +1,134 @@
+package files2prompt
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// readGitignore returns the rule lines of the .gitignore file in path, without
+// blank lines and comments.
+func readGitignore(path string) []string {
+	gitignorePath := filepath.Join(path, ".gitignore")
+	content, err := os.ReadFile(gitignorePath) // #nosec G304
+	if err != nil {
+		return nil // Return nil for non-existent files to distinguish from empty files
+	}
+
+	var rules []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			rules = append(rules, line)
+		}
+	}
+	return rules
+}
+
+// gitignoreRule is a single .gitignore pattern, scoped to the directory
+// containing the .gitignore file it came from.
+type gitignoreRule struct {
+	// base is the directory the rule applies beneath
+	base string
+	// pattern is the doublestar pattern, stripped of any "!" and of leading and
+	// trailing slashes, as translatePattern returned it
+	pattern string
+	// negate re-includes paths an earlier rule excluded
+	negate bool
+	// dirOnly restricts the rule to directories, for patterns with a trailing slash
+	dirOnly bool
+	// anchored rules contain a slash other than a trailing one, so they match
+	// the path relative to base; the others match the base name at any depth
+	anchored bool
+}
+
+// readGitignoreRules parses the .gitignore file in dir.
+func readGitignoreRules(dir string) []gitignoreRule {
+	return parseGitignore(dir, readGitignore(dir))
+}
+
+// parseGitignore parses .gitignore lines read from the directory base.
+func parseGitignore(base string, lines []string) []gitignoreRule {
+	var rules []gitignoreRule
+	for _, line := range lines {
+		if rule, ok := parseGitignoreRule(base, line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseGitignoreRule(base, line string) (gitignoreRule, bool) {
+	rule := gitignoreRule{base: base}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		// An escaped leading "!" or "#" is literal
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	rule.anchored = strings.Contains(line, "/")
+	// git skips a pattern it cannot parse, and so does the walk
+	pattern, err := translatePattern(strings.TrimPrefix(line, "/"))
+	rule.pattern = pattern
+	return rule, err == nil && rule.pattern != ""
+}
+
+// relativeTo returns path relative to base with forward slashes, or false when
+// path is not beneath base.
+func relativeTo(base, p string) (string, bool) {
+	rel, err := filepath.Rel(base, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// target returns the string r is matched against for a path rel relative to its base.
+func (r gitignoreRule) target(rel string) string {
+	if r.anchored {
+		return rel
+	}
+	return path.Base(rel)
+}
+
+// excludesItself reports whether a "dir/**" rule would match rel only because
+// doublestar lets "/**" match nothing. In git the rule matches everything
+// inside the directory but not the directory itself, which stays walkable so
+// that later negations can re-include its contents.
+func (r gitignoreRule) excludesItself(rel string) bool {
+	dir, ok := strings.CutSuffix(r.pattern, "/**")
+	return ok && r.anchored && rel == dir
+}
+
+// shouldIgnore is the reference gitignore matcher; the walk uses the equivalent
+// precompiled ignoreMatcher instead. As in git, the last rule matching path
+// decides, so a negation re-includes what an earlier rule excluded.
+//
+// Only path itself is matched, not its parent directories: the walk never
+// descends into an ignored directory, which is also why, as in git, a file
+// cannot be re-included once a parent directory is excluded.
+func shouldIgnore(p string, isDir bool, rules []gitignoreRule) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, ok := relativeTo(r.base, p)
+		if !ok || r.excludesItself(rel) {
+			continue
+		}
+		if doublestar.MatchUnvalidated(r.pattern, r.target(rel)) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
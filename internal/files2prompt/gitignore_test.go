@@ -0,0 +1,77 @@
+package files2prompt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestParseGitignoreRule(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected gitignoreRule
+	}{
+		{"*.log", gitignoreRule{base: "d", pattern: "*.log"}},
+		{"!keep.log", gitignoreRule{base: "d", pattern: "keep.log", negate: true}},
+		{`\!bang`, gitignoreRule{base: "d", pattern: "!bang"}},
+		{`\#hash`, gitignoreRule{base: "d", pattern: "#hash"}},
+		{"/build", gitignoreRule{base: "d", pattern: "build", anchored: true}},
+		{"build/", gitignoreRule{base: "d", pattern: "build", dirOnly: true}},
+		{"/out/", gitignoreRule{base: "d", pattern: "out", dirOnly: true, anchored: true}},
+		{"docs/*.md", gitignoreRule{base: "d", pattern: "docs/*.md", anchored: true}},
+		{"!/dist/keep/", gitignoreRule{base: "d", pattern: "dist/keep", negate: true, dirOnly: true, anchored: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			rule, ok := parseGitignoreRule("d", tt.line)
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, rule)
+		})
+	}
+
+	_, ok := parseGitignoreRule("d", "/")
+	assert.False(t, ok)
+}
+
+func TestPlanGitignoreSemantics(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore": "*.log\n!keep.log\n/build\ndist/*\n!dist/config.example.json\ncache/\n!cache/kept.dat\n",
+		"app.log":    "x", "keep.log": "x", "main.go": "x",
+		"build/out.dat":            "x",
+		"dist/app.js":              "x",
+		"dist/config.example.json": "x",
+		// An excluded directory is never walked, so its contents cannot be re-included
+		"cache/kept.dat":   "x",
+		"src/build/gen.go": "x",
+		"src/.gitignore":   "!debug.log\n/gen\n",
+		"src/debug.log":    "x",
+		"src/gen/out.go":   "x",
+		"src/lib/gen/x.go": "x",
+		"other/debug.log":  "x",
+	})
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{dir}, IgnoreGitignore: true, DisableDefaultIgnores: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"dist/config.example.json",
+		"keep.log",
+		"main.go",
+		// The anchored /build only applies at the top level
+		"src/build/gen.go",
+		// The nested negation only applies beneath src
+		"src/debug.log",
+		// The nested anchored /gen only applies directly beneath src
+		"src/lib/gen/x.go",
+	}, includedPaths(t, dir, plan))
+
+	// The rules of a parent directory are scoped to it too
+	plan, err = Plan(context.Background(), config.Config{Paths: []string{filepath.Join(dir, "src")}, IgnoreGitignore: true, DisableDefaultIgnores: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build/gen.go", "debug.log", "lib/gen/x.go"}, includedPaths(t, filepath.Join(dir, "src"), plan))
+}
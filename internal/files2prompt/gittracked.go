@@ -0,0 +1,96 @@
+package files2prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// trackedFiles is what --git-tracked restricts a walk to: the files git
+// tracks in the repositories holding the path arguments, by absolute path,
+// and the directories on the way to each. A nil *trackedFiles tracks
+// everything.
+type trackedFiles struct {
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// loadTrackedFiles lists, with --git-tracked, the files tracked in every
+// repository holding one of args, asking git once per repository, and fails
+// naming the first of args that is in none. It returns nil without
+// --git-tracked.
+func loadTrackedFiles(args []pathArg, config config.Config) (*trackedFiles, error) {
+	if !config.GitTracked {
+		return nil, nil
+	}
+	t := &trackedFiles{files: map[string]bool{}, dirs: map[string]bool{}}
+	listed := map[string]bool{}
+	for _, arg := range args {
+		if arg.origin == OriginStdin {
+			// Paths read from stdin have made their own choices
+			continue
+		}
+		// A glob is looked up by the directory its matches are found beneath
+		root, ok := findRepoRoot(arg.root)
+		if !ok {
+			return nil, fmt.Errorf("--git-tracked needs a git repository, and %s is not in one", arg.path)
+		}
+		if listed[root] {
+			continue
+		}
+		listed[root] = true
+		paths, err := lsFiles(root)
+		if err != nil {
+			return nil, err
+		}
+		t.dirs[root] = true
+		for _, p := range paths {
+			file := filepath.Join(root, filepath.FromSlash(p))
+			t.files[file] = true
+			for dir := filepath.Dir(file); dir != root && !t.dirs[dir]; dir = filepath.Dir(dir) {
+				t.dirs[dir] = true
+			}
+		}
+	}
+	return t, nil
+}
+
+// tracks reports whether c is a tracked file, or a directory holding one.
+func (t *trackedFiles) tracks(c candidate) bool {
+	if t == nil {
+		return true
+	}
+	if c.info.IsDir() {
+		return t.dirs[c.abs]
+	}
+	return t.files[c.abs]
+}
+
+// lsFiles runs git ls-files in the repository at root, and those of its
+// submodules, and returns the paths of the tracked files relative to root.
+func lsFiles(root string) ([]string, error) {
+	// -z writes the paths as they are, without quoting
+	cmd := exec.Command("git", "-C", root, "ls-files", "-z", "--cached", "--recurse-submodules") // #nosec G204
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("--git-tracked: git ls-files in %s failed: %s", root, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("--git-tracked: %v", err)
+	}
+	var paths []string
+	for p := range strings.SplitSeq(string(out), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
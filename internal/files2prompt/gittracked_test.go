@@ -0,0 +1,98 @@
+package files2prompt
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestGitTracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":          "package main\n",
+		"README.md":        "# readme\n",
+		"pkg/util.go":      "package pkg\n",
+		"pkg/util_test.go": "package pkg\n",
+		"tab\tname.go":     "package tab\n",
+		".gitignore":       "*.log\n",
+	})
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	// Scratch files, one ignored and the others untracked, and a directory of nothing tracked
+	writeFiles(t, dir, map[string]string{
+		"debug.log":     "noise\n",
+		"scratch.go":    "package main\n",
+		"tmp/notes.txt": "notes\n",
+		"pkg/draft.go":  "package pkg\n",
+		"pkg/staged.go": "package pkg\n",
+	})
+	runGit(t, dir, "add", "pkg/staged.go")
+	t.Chdir(dir)
+
+	plan := func(t *testing.T, cfg config.Config) (included []string, reasons map[string]SkipReason) {
+		t.Helper()
+		cfg.GitTracked = true
+		planned, err := Plan(context.Background(), cfg, true)
+		require.NoError(t, err)
+		reasons = map[string]SkipReason{}
+		for _, f := range planned {
+			rel, err := filepath.Rel(dir, absPath(f.Path))
+			require.NoError(t, err)
+			rel = filepath.ToSlash(rel)
+			if f.Included && !f.IsDir {
+				included = append(included, rel)
+			} else if !f.Included {
+				reasons[rel] = f.Reason
+			}
+		}
+		return included, reasons
+	}
+
+	t.Run("tracked only", func(t *testing.T) {
+		included, reasons := plan(t, config.Config{Paths: []string{"."}})
+		// A file staged but never committed is tracked as well
+		assert.Equal(t, []string{"README.md", "main.go", "pkg/staged.go", "pkg/util.go", "pkg/util_test.go", "tab\tname.go"}, included)
+		assert.Equal(t, SkipUntracked, reasons["scratch.go"])
+		assert.Equal(t, SkipUntracked, reasons["debug.log"])
+		assert.Equal(t, SkipUntracked, reasons["pkg/draft.go"])
+		// The directory is pruned rather than walked
+		assert.Equal(t, SkipUntracked, reasons["tmp"])
+		assert.NotContains(t, reasons, "tmp/notes.txt")
+	})
+
+	t.Run("with extension and ignore", func(t *testing.T) {
+		included, _ := plan(t, config.Config{Paths: []string{"pkg"}, Extensions: []string{".go"}, IgnorePatterns: []string{"*_test.go"}})
+		assert.Equal(t, []string{"pkg/staged.go", "pkg/util.go"}, included)
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		included, _ := plan(t, config.Config{Paths: []string{"**/*.go"}})
+		assert.Equal(t, []string{"main.go", "pkg/staged.go", "pkg/util.go", "pkg/util_test.go", "tab\tname.go"}, included)
+	})
+
+	t.Run("match", func(t *testing.T) {
+		results, err := Match(config.Config{GitTracked: true}, ".", []string{"main.go", "scratch.go", "tmp/notes.txt"})
+		require.NoError(t, err)
+		assert.True(t, results[0].Included)
+		assert.Equal(t, SkipUntracked, results[1].Reason)
+		assert.Equal(t, "tmp", results[2].PrunedBy)
+	})
+}
+
+func TestGitTrackedOutsideRepository(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "a\n"})
+	t.Chdir(dir)
+	_, err := Plan(context.Background(), config.Config{Paths: []string{"a.txt"}, GitTracked: true}, false)
+	assert.EqualError(t, err, "--git-tracked needs a git repository, and a.txt is not in one")
+}
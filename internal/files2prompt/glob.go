@@ -0,0 +1,125 @@
+package files2prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// pathArg is a path to plan: a path argument, one of the paths a glob argument
+// matched, or a path read from stdin.
+type pathArg struct {
+	path   string
+	origin Origin
+	// root is recorded as the Root of the files planned from path: the
+	// directory named by the literal prefix of the pattern for glob matches,
+	// and path itself otherwise
+	root string
+}
+
+// isGlob reports whether the path argument p is a glob pattern. A path that
+// exists is taken literally, whatever characters its name holds.
+func isGlob(p string) bool {
+	if !strings.ContainsAny(p, "*?[{") {
+		return false
+	}
+	_, err := os.Lstat(p)
+	return err != nil
+}
+
+// expandArgs expands "~" in the path arguments of config, then replaces each
+// glob pattern with the paths it matches, so that shells without "**" support
+// need not expand them. A pattern that matches nothing is an error unless
+// AllowEmptyGlob is set.
+func expandArgs(config config.Config) ([]pathArg, error) {
+	// "-" is standard input, emitted by emitStdin rather than walked
+	paths, err := hostEnv.expandPaths(slices.DeleteFunc(slices.Clone(config.Paths), func(p string) bool { return p == stdinPath }))
+	if err != nil {
+		return nil, err
+	}
+	var args []pathArg
+	for _, p := range paths {
+		if !isGlob(p) {
+			args = append(args, pathArg{path: p, origin: OriginArg, root: p})
+			continue
+		}
+		matches, base, err := expandGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			if !config.AllowEmptyGlob {
+				return nil, fmt.Errorf("glob %q matched no files (use --allow-empty-glob to ignore)", p)
+			}
+			log.Debugf("Glob %s matched no files", p)
+			continue
+		}
+		for _, match := range matches {
+			args = append(args, pathArg{path: match, origin: OriginGlob, root: base})
+		}
+	}
+	return args, nil
+}
+
+// expandGlob returns the paths pattern matches, in lexical order, and the
+// directory named by its literal prefix. Matches beneath another match that is a
+// directory are left out, since walking that directory reaches them.
+func expandGlob(pattern string) ([]string, string, error) {
+	matches, err := doublestar.FilepathGlob(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid glob %q: %v", pattern, err)
+	}
+	base, _ := doublestar.SplitPattern(filepath.ToSlash(pattern))
+	base = filepath.Clean(filepath.FromSlash(base))
+
+	slices.Sort(matches)
+	var kept, dirs []string
+	for _, match := range matches {
+		if slices.ContainsFunc(dirs, func(dir string) bool { return withinDir(match, dir) }) {
+			continue
+		}
+		kept = append(kept, match)
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			dirs = append(dirs, match)
+		}
+	}
+	return kept, base, nil
+}
+
+// globDirs returns the directories a walk of arg's root would pass through to
+// reach arg's path, outermost first, excluding the root itself and the path.
+func globDirs(arg pathArg) []string {
+	var dirs []string
+	for dir := filepath.Dir(arg.path); dir != arg.root && withinDir(dir, arg.root); dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	slices.Reverse(dirs)
+	return dirs
+}
+
+// prunedGlobDir returns the directory between the root of the glob match arg and
+// the match itself that p would have pruned from a walk of the root, or "".
+// Matches beneath it are left out, as a walk of the root would not find them.
+func (p *filterPipeline) prunedGlobDir(arg pathArg) string {
+	for _, dir := range globDirs(arg) {
+		info, err := os.Stat(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(arg.root, dir)
+		if err != nil {
+			continue
+		}
+		c := candidate{path: dir, info: info, origin: OriginGlob, abs: absPath(dir), rel: rel}
+		if p.filterDecision(c) != "" {
+			return dir
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package files2prompt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// globFixture writes a tree for glob arguments and makes it the working directory.
+func globFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":       "*.log\nbuild/\n",
+		"debug.log":        "log\n",
+		"lit[1].go":        "package lit\n",
+		"build/gen.go":     "package build\n",
+		"cmd/main.go":      "package main\n",
+		"cmd/README.md":    "# cmd\n",
+		"src/a.go":         "package src\n",
+		"src/.x.go":        "package src\n",
+		"src/.hidden/h.go": "package hidden\n",
+		"src/deep/b.go":    "package deep\n",
+		"src/deep/c.py":    "print()\n",
+	})
+	t.Chdir(root)
+	return root
+}
+
+func TestPlanGlobArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Config
+		want  []string
+		roots []string
+	}{
+		{
+			name:  "double star",
+			cfg:   config.Config{Paths: []string{"src/**/*.go"}},
+			want:  []string{"src/a.go", "src/deep/b.go"},
+			roots: []string{"src"},
+		},
+		{
+			name:  "double star from the working directory applies .gitignore",
+			cfg:   config.Config{Paths: []string{"**/*.go"}, IgnoreGitignore: true},
+			want:  []string{"cmd/main.go", "lit[1].go", "src/a.go", "src/deep/b.go"},
+			roots: []string{"."},
+		},
+		{
+			name:  "matched directories are walked",
+			cfg:   config.Config{Paths: []string{"cmd/**"}},
+			want:  []string{"cmd/README.md", "cmd/main.go"},
+			roots: []string{"cmd"},
+		},
+		{
+			name:  "filters apply to matches",
+			cfg:   config.Config{Paths: []string{"src/**"}, Extensions: []string{".py"}},
+			want:  []string{"src/deep/c.py"},
+			roots: []string{"src"},
+		},
+		{
+			name:  "hidden matches need --include-hidden",
+			cfg:   config.Config{Paths: []string{"src/**/*.go"}, IncludeHidden: true},
+			want:  []string{"src/.hidden/h.go", "src/.x.go", "src/a.go", "src/deep/b.go"},
+			roots: []string{"src"},
+		},
+		{
+			name:  "literal path without metacharacters",
+			cfg:   config.Config{Paths: []string{"cmd/main.go"}},
+			want:  []string{"cmd/main.go"},
+			roots: []string{"cmd/main.go"},
+		},
+		{
+			name:  "existing path with metacharacters is literal",
+			cfg:   config.Config{Paths: []string{"lit[1].go"}},
+			want:  []string{"lit[1].go"},
+			roots: []string{"lit[1].go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := globFixture(t)
+			plan, roots, err := planFiles(context.Background(), tt.cfg, nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, includedAbs(t, root, plan))
+			assert.Equal(t, tt.roots, roots)
+			for _, f := range plan {
+				assert.Equal(t, tt.roots[0], f.Root, f.Path)
+			}
+		})
+	}
+}
+
+func TestPlanGlobOrigins(t *testing.T) {
+	globFixture(t)
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{"src/*.go", "cmd/*", "lit[1].go"}}, false)
+	require.NoError(t, err)
+	origins := map[string]Origin{}
+	for _, f := range plan {
+		origins[f.Path] = f.Origin
+	}
+	assert.Equal(t, map[string]Origin{
+		"src/a.go":      OriginGlob,
+		"cmd/main.go":   OriginGlob,
+		"cmd/README.md": OriginGlob,
+		"lit[1].go":     OriginArg,
+	}, origins)
+}
+
+func TestPlanGlobNoMatch(t *testing.T) {
+	globFixture(t)
+	cfg := config.Config{Paths: []string{"src/**/*.rs", "cmd/main.go"}}
+
+	_, _, err := planFiles(context.Background(), cfg, nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `glob "src/**/*.rs" matched no files`)
+
+	cfg.AllowEmptyGlob = true
+	plan, roots, err := planFiles(context.Background(), cfg, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd/main.go"}, roots)
+	assert.Len(t, plan, 1)
+}
@@ -0,0 +1,92 @@
+package files2prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// regionSeparator marks lines elided between --grep-context regions.
+const regionSeparator = "...\n"
+
+// compileGrep compiles the --grep pattern, returning nil when none is set.
+func compileGrep(config config.Config) (*regexp.Regexp, error) {
+	if config.Grep == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(config.Grep)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --grep pattern: %v", err)
+	}
+	return re, nil
+}
+
+// grepMatches reports whether the file at path contains a match for grep.
+// Every file matches when grep is nil; unreadable files are left for
+// processFile to report. Of a file reporting a size of 0, only the first
+// zeroSize bytes are searched, as only they are emitted.
+func grepMatches(path string, grep *regexp.Regexp, limit, zeroSize int64) bool {
+	if grep == nil {
+		return true
+	}
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() > limit {
+		return true
+	}
+	if info.Size() == 0 {
+		limit = min(limit, zeroSize)
+	}
+	// Matched as the file is read, so that large files are never held in memory
+	return grep.MatchReader(bufio.NewReader(io.LimitReader(f, limit)))
+}
+
+// lineRange is an inclusive, zero-based range of line indexes.
+type lineRange struct {
+	start, end int
+}
+
+// grepRegions returns the lines matching re, each widened by context lines on
+// both sides, with overlapping or adjacent regions merged as grep -C does.
+func grepRegions(lines []string, re *regexp.Regexp, context int) []lineRange {
+	var regions []lineRange
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		r := lineRange{start: max(i-context, 0), end: min(i+context, len(lines)-1)}
+		if n := len(regions); n > 0 && r.start <= regions[n-1].end+1 {
+			regions[n-1].end = max(regions[n-1].end, r.end)
+			continue
+		}
+		regions = append(regions, r)
+	}
+	return regions
+}
+
+// regionSegments turns the regions of a file with total lines into segments,
+// eliding everything between them with regionSeparator, including before the
+// first and after the last region.
+func regionSegments(total int, regions []lineRange) []segment {
+	var segments []segment
+	next := 0
+	for _, r := range regions {
+		if r.start > next {
+			segments = append(segments, segment{lineRange{next, r.start - 1}, regionSeparator})
+		}
+		segments = append(segments, segment{lineRange: r})
+		next = r.end + 1
+	}
+	if next < total {
+		segments = append(segments, segment{lineRange{next, total - 1}, regionSeparator})
+	}
+	return segments
+}
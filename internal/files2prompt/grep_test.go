@@ -0,0 +1,159 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestGrepRegions(t *testing.T) {
+	lines := strings.Split("a\nb\nMATCH\nc\nd\ne\nf\nMATCH\ng\nMATCH\nh", "\n")
+	re := regexp.MustCompile("MATCH")
+
+	tests := []struct {
+		name     string
+		context  int
+		expected []lineRange
+	}{
+		{name: "no context", context: 0, expected: []lineRange{{2, 2}, {7, 7}, {9, 9}}},
+		{name: "overlapping regions merge", context: 1, expected: []lineRange{{1, 3}, {6, 10}}},
+		{name: "adjacent regions merge", context: 2, expected: []lineRange{{0, 10}}},
+		{name: "context clamped to file", context: 50, expected: []lineRange{{0, 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, grepRegions(lines, re, tt.context))
+		})
+	}
+
+	assert.Empty(t, grepRegions(lines, regexp.MustCompile("absent"), 3))
+}
+
+func TestCompileGrep(t *testing.T) {
+	re, err := compileGrep(config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, re)
+
+	_, err = compileGrep(config.Config{Grep: "("})
+	assert.ErrorContains(t, err, "invalid --grep pattern")
+}
+
+func writeLines(t *testing.T, n int, matches ...int) string {
+	t.Helper()
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	for _, m := range matches {
+		lines[m-1] = fmt.Sprintf("line %d TODO", m)
+	}
+	path := filepath.Join(t.TempDir(), "notes.py")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+	return path
+}
+
+func TestProcessFileGrepContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    int
+		matches  []int
+		config   config.Config
+		expected func(path string) string
+	}{
+		{
+			name:    "overlapping matches in plain output",
+			lines:   12,
+			matches: []int{4, 6, 11},
+			config:  config.Config{Grep: "TODO", GrepContext: 1},
+			expected: func(path string) string {
+				return path + "\n---\n...\n" +
+					"  3 │ line 3\n  4 │ line 4 TODO\n  5 │ line 5\n  6 │ line 6 TODO\n  7 │ line 7\n" +
+					"...\n" +
+					" 10 │ line 10\n 11 │ line 11 TODO\n 12 │ line 12\n" +
+					"---\n\n"
+			},
+		},
+		{
+			name:    "matches at file boundaries",
+			lines:   5,
+			matches: []int{1, 5},
+			config:  config.Config{Grep: "TODO", GrepContext: 1, LineNumbersCompact: true},
+			expected: func(path string) string {
+				return path + "\n---\n1:line 1 TODO\n2:line 2\n...\n4:line 4\n5:line 5 TODO\n---\n\n"
+			},
+		},
+		{
+			name:    "markdown",
+			lines:   5,
+			matches: []int{3},
+			config:  config.Config{Grep: "TODO", GrepContext: 0, Markdown: true, LineNumbersCompact: true},
+			expected: func(path string) string {
+				return "## " + path + "\n\n```python\n...\n3:line 3 TODO\n...\n```\n\n"
+			},
+		},
+		{
+			name:    "claude xml",
+			lines:   3,
+			matches: []int{2},
+			config:  config.Config{Grep: "TODO", GrepContext: 0, ClaudeXML: true, LineNumbersCompact: true},
+			expected: func(path string) string {
+				return "<document index=\"1\">\n<source>" + path + "</source>\n<document_content>\n" +
+					"...\n2:line 2 TODO\n...\n</document_content>\n</document>\n"
+			},
+		},
+		{
+			name:    "whole file without grep-context",
+			lines:   3,
+			matches: []int{2},
+			config:  config.Config{Grep: "TODO", GrepContext: -1},
+			expected: func(path string) string {
+				return path + "\n---\nline 1\nline 2 TODO\nline 3\n---\n\n"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLines(t, tt.lines, tt.matches...)
+			out, err := renderPath(path, tt.config, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected(path), out)
+		})
+	}
+}
+
+func TestPlanGrepFilter(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hit.dat"), []byte("x\nneedle\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "miss.dat"), []byte("hay\n"), 0o600))
+
+	all, err := Plan(context.Background(), config.Config{Paths: []string{dir}, Grep: "needle"}, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range all {
+		if !f.IsDir {
+			reasons[filepath.Base(f.Path)] = f.Reason
+		}
+	}
+	assert.Equal(t, map[string]SkipReason{"hit.dat": "", "miss.dat": SkipGrep}, reasons)
+
+	var buf bytes.Buffer
+	_, err = Generate(context.Background(), config.Config{Paths: []string{dir}, Grep: "("}, &buf, nil)
+	assert.ErrorContains(t, err, "invalid --grep pattern")
+}
+
+func TestEmptyDiagnosticGrep(t *testing.T) {
+	s := newPathStats("src/")
+	s.skip(SkipGrep, false)
+	s.skip(SkipGrep, false)
+	assert.Equal(t, "src/: 2 files excluded by grep filter [TODO]", s.emptyDiagnostic(config.Config{Grep: "TODO"}))
+}
@@ -0,0 +1,116 @@
+package files2prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// ExitCodeUnconfirmed is the process exit code used when a run is refused
+// because a crawl root needs confirmation that was not given.
+const ExitCodeUnconfirmed = 2
+
+// DefaultGuardEntries is the number of immediate entries above which a
+// directory without version control metadata needs confirmation, when
+// GuardEntries is left at zero.
+const DefaultGuardEntries = 1000
+
+// GuardError reports that crawling Path was not confirmed.
+type GuardError struct {
+	Path   string
+	Reason string
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("refusing to crawl %s: it is %s (pass --yes to crawl it anyway)", e.Path, e.Reason)
+}
+
+// guardReason returns why crawling the directory dir needs confirmation, or ""
+// when it does not: it is the user's home directory, a filesystem root, or
+// holds more than limit immediate entries outside version control.
+func guardReason(dir string, limit int) string {
+	resolved := resolvedPath(dir)
+	if home, err := hostEnv.homeDir(); err == nil && resolvedPath(home) == resolved {
+		return "your home directory"
+	}
+	if filepath.Dir(resolved) == resolved {
+		return "a filesystem root"
+	}
+	f, err := os.Open(resolved) // #nosec G304
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	entries, _ := f.ReadDir(limit + 1)
+	if len(entries) <= limit {
+		return ""
+	}
+	if underVersionControl(resolved) {
+		return ""
+	}
+	return fmt.Sprintf("a directory of more than %d entries outside version control", limit)
+}
+
+// underVersionControl reports whether dir or one of its parents holds version
+// control metadata, as findRepoRoot looks for a Git repository.
+func underVersionControl(dir string) bool {
+	for {
+		for _, name := range vcsMetadataNames {
+			if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+				return true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// resolvedPath returns the absolute path of path with symlinks resolved, or as
+// far as it could be resolved.
+func resolvedPath(path string) string {
+	abs := absPath(path)
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		return real
+	}
+	return abs
+}
+
+// guardRoots asks for confirmation before walking any directory argument, or
+// the base of any glob, that guardReason flags, and fails with a GuardError
+// when it is not given. --yes confirms every one.
+func guardRoots(args []pathArg, config config.Config) error {
+	if config.Yes {
+		return nil
+	}
+	limit := config.GuardEntries
+	if limit <= 0 {
+		limit = DefaultGuardEntries
+	}
+	checked := map[string]bool{}
+	for _, arg := range args {
+		dir := arg.path
+		if arg.origin == OriginGlob {
+			dir = arg.root
+		}
+		if checked[dir] {
+			continue
+		}
+		checked[dir] = true
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		reason := guardReason(dir, limit)
+		if reason == "" {
+			continue
+		}
+		if !hostEnv.interactive() || !hostEnv.confirm(fmt.Sprintf("%s is %s. Crawl it anyway? [y/N] ", dir, reason)) {
+			return &GuardError{Path: dir, Reason: reason}
+		}
+	}
+	return nil
+}
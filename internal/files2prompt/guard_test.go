@@ -0,0 +1,132 @@
+package files2prompt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// withHome makes home the current user's home directory for the rest of the test.
+func withHome(t *testing.T, home string) {
+	t.Helper()
+	original := hostEnv.getenv
+	hostEnv.getenv = func(name string) string {
+		if name == "HOME" || name == "USERPROFILE" {
+			return home
+		}
+		return original(name)
+	}
+	t.Cleanup(func() { hostEnv.getenv = original })
+}
+
+// flatDir writes a directory of n files, with the VCS metadata directory vcs
+// unless it is "".
+func flatDir(t *testing.T, n int, vcs string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{}
+	for i := range n {
+		files[fmt.Sprintf("f%03d.go", i)] = "package f\n"
+	}
+	if vcs != "" {
+		files[vcs+"/HEAD"] = "ref: refs/heads/main\n"
+	}
+	writeFiles(t, dir, files)
+	return dir
+}
+
+func TestGuardReason(t *testing.T) {
+	home := t.TempDir()
+	withHome(t, home)
+	link := filepath.Join(t.TempDir(), "home")
+	require.NoError(t, os.Symlink(home, link))
+	nested := filepath.Join(flatDir(t, 0, ".hg"), "vendor")
+	require.NoError(t, os.Rename(flatDir(t, 40, ""), nested))
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{name: "home", dir: home, want: "your home directory"},
+		{name: "home through a symlink", dir: link, want: "your home directory"},
+		{name: "filesystem root", dir: string(filepath.Separator), want: "a filesystem root"},
+		{name: "huge flat directory", dir: flatDir(t, 21, ""), want: "a directory of more than 20 entries outside version control"},
+		{name: "huge repository", dir: flatDir(t, 40, ".git"), want: ""},
+		{name: "huge directory inside a repository", dir: nested, want: ""},
+		{name: "small directory", dir: flatDir(t, 20, ""), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, guardReason(tt.dir, 20))
+		})
+	}
+
+	writeFiles(t, home, map[string]string{"project/main.go": "package main\n"})
+	assert.Empty(t, guardReason(filepath.Join(home, "project"), 20))
+}
+
+func TestGuardRoots(t *testing.T) {
+	home := t.TempDir()
+	writeFiles(t, home, map[string]string{"notes.md": "# notes\n", "project/main.go": "package main\n"})
+
+	tests := []struct {
+		name        string
+		config      config.Config
+		interactive bool
+		answer      bool
+		err         bool
+		asked       int
+	}{
+		{name: "confirmed", interactive: true, answer: true, asked: 1},
+		{name: "declined", interactive: true, answer: false, err: true, asked: 1},
+		{name: "non-interactive", err: true},
+		{name: "--yes", config: config.Config{Yes: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := withPrompt(t, tt.answer)
+			hostEnv.interactive = func() bool { return tt.interactive }
+			withHome(t, home)
+
+			cfg := tt.config
+			cfg.Paths = []string{filepath.Join(home, "project"), home}
+			plan, err := Plan(context.Background(), cfg, false)
+			assert.Len(t, *asked, tt.asked)
+			if !tt.err {
+				require.NoError(t, err)
+				assert.NotEmpty(t, plan)
+				return
+			}
+			var guardErr *GuardError
+			require.ErrorAs(t, err, &guardErr)
+			assert.Equal(t, home, guardErr.Path)
+			assert.EqualError(t, err, "refusing to crawl "+home+": it is your home directory (pass --yes to crawl it anyway)")
+		})
+	}
+}
+
+func TestGuardRootsFlatDirectory(t *testing.T) {
+	dir := flatDir(t, 30, "")
+	asked := withPrompt(t, false)
+	hostEnv.interactive = func() bool { return false }
+
+	_, err := Plan(context.Background(), config.Config{Paths: []string{dir}, GuardEntries: 10}, false)
+	var guardErr *GuardError
+	require.ErrorAs(t, err, &guardErr)
+	assert.Equal(t, "a directory of more than 10 entries outside version control", guardErr.Reason)
+
+	// The default limit is higher, and files named directly are never checked
+	_, err = Plan(context.Background(), config.Config{Paths: []string{dir}}, false)
+	require.NoError(t, err)
+	_, err = Plan(context.Background(), config.Config{Paths: []string{filepath.Join(dir, "f000.go")}, GuardEntries: 10}, false)
+	require.NoError(t, err)
+	assert.Empty(t, *asked)
+}
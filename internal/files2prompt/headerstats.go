@@ -0,0 +1,44 @@
+package files2prompt
+
+import (
+	"fmt"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// docStats is the size of the content a document shows: the lines of the file
+// it emits, after --grep-context and --squash-data-blocks, without line-number
+// gutters or omission markers.
+type docStats struct {
+	lines int64
+	bytes int64
+}
+
+// shownStats returns the docStats of shown, the content segments show.
+func shownStats(segments []segment, shown string) docStats {
+	var count int64
+	for _, s := range segments {
+		if s.marker == "" {
+			count += int64(s.end - s.start + 1)
+		}
+	}
+	return docStats{lines: count, bytes: int64(len(shown))}
+}
+
+// headerSuffix returns what --header-stats appends to the path line of a plain
+// or Markdown document, such as " (184 lines, 6.2 KiB)", or "" without it.
+func headerSuffix(config config.Config, stats docStats) string {
+	if !config.HeaderStats {
+		return ""
+	}
+	return fmt.Sprintf(" (%d %s, %s)", stats.lines, plural(int(stats.lines), "line", "lines"), formatBytes(stats.bytes))
+}
+
+// headerAttrs returns the attributes --header-stats adds to a Claude XML
+// document, or "" without it.
+func headerAttrs(config config.Config, stats docStats) string {
+	if !config.HeaderStats {
+		return ""
+	}
+	return fmt.Sprintf(" lines=\"%d\" bytes=\"%d\"", stats.lines, stats.bytes)
+}
@@ -0,0 +1,119 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestHeaderStatsGolden(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"empty.txt": "",
+		"todo.txt":  "one\ntwo TODO\nthree\nfour\nfive\nsix TODO\n",
+		"data.txt":  "header\n" + strings.Repeat("0123456789abcdef\n", 4) + "footer",
+		"big.txt":   strings.Repeat("x", 2047) + "\n",
+	})
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "plain",
+			config:   config.Config{Paths: []string{"main.go"}},
+			expected: "main.go (3 lines, 29 B)\n---\npackage main\n\nfunc main() {}\n---\n\n",
+		},
+		{
+			name:     "empty file",
+			config:   config.Config{Paths: []string{"empty.txt"}},
+			expected: "empty.txt (0 lines, 0 B)\n---\n---\n\n",
+		},
+		{
+			name:     "markdown with line numbers",
+			config:   config.Config{Paths: []string{"main.go"}, Markdown: true, LineNumbersCompact: true},
+			expected: "## main.go (3 lines, 29 B)\n\n```go\n1:package main\n2:\n3:func main() {}\n```\n\n",
+		},
+		{
+			name:     "grep regions",
+			config:   config.Config{Paths: []string{"todo.txt"}, Grep: "TODO", GrepContext: 0, LineNumbersCompact: true},
+			expected: "todo.txt (2 lines, 18 B)\n---\n...\n2:two TODO\n...\n6:six TODO\n---\n\n",
+		},
+		{
+			name:     "squashed data",
+			config:   config.Config{Paths: []string{"data.txt"}, SquashDataBlocks: 2},
+			expected: "data.txt (2 lines, 13 B)\n---\nheader\n[... 4 lines of data omitted ...]\nfooter\n---\n\n",
+		},
+		{
+			name:     "kibibytes",
+			config:   config.Config{Paths: []string{"big.txt"}, Markdown: true},
+			expected: "## big.txt (1 line, 2.0 KiB)\n\n```\n" + strings.Repeat("x", 2047) + "\n```\n\n",
+		},
+		{
+			name:   "claude xml",
+			config: config.Config{Paths: []string{"main.go"}, ClaudeXML: true},
+			expected: "<documents>\n<document index=\"1\" lines=\"3\" bytes=\"29\">\n<source>main.go</source>\n<document_content>\n" +
+				"package main\n\nfunc main() {}\n</document_content>\n</document>\n</documents>\n",
+		},
+		{
+			name:   "claude xml parts",
+			config: config.Config{Paths: []string{"main.go"}, ClaudeXML: true, CXMLMaxDocBytes: 16},
+			expected: "<documents>\n" +
+				"<document index=\"1\" lines=\"3\" bytes=\"29\" part=\"1/2\">\n<source>main.go</source>\n<document_content>\npackage main\n\n\n</document_content>\n</document>\n" +
+				"<document index=\"2\" lines=\"3\" bytes=\"29\" part=\"2/2\">\n<source>main.go</source>\n<document_content>\nfunc main() {}\n</document_content>\n</document>\n" +
+				"</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var without bytes.Buffer
+			_, err := Generate(context.Background(), tt.config, &without, nil)
+			require.NoError(t, err)
+
+			cfg := tt.config
+			cfg.HeaderStats = true
+			var buf bytes.Buffer
+			_, err = Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+
+			// Without the flag the output is unchanged
+			assert.Equal(t, strings.NewReplacer(" (3 lines, 29 B)", "", " lines=\"3\" bytes=\"29\"", "", " (0 lines, 0 B)", "",
+				" (2 lines, 18 B)", "", " (2 lines, 13 B)", "", " (1 line, 2.0 KiB)", "").Replace(tt.expected), without.String())
+		})
+	}
+}
+
+func TestHeaderStatsStreamed(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("0123456789\n", 200) + "tail"
+	writeFiles(t, dir, map[string]string{"big.txt": content})
+	t.Chdir(dir)
+	withStreamThreshold(t, 64)
+
+	for _, cfg := range []config.Config{
+		{Paths: []string{"big.txt"}, HeaderStats: true},
+		{Paths: []string{"big.txt"}, HeaderStats: true, Markdown: true, LineNumbers: true},
+		{Paths: []string{"big.txt"}, HeaderStats: true, ClaudeXML: true},
+	} {
+		var streamed, whole bytes.Buffer
+		_, err := Generate(context.Background(), cfg, &streamed, nil)
+		require.NoError(t, err)
+		assert.Regexp(t, `^((## )?big\.txt \(201 lines, 2\.2 KiB\)|<documents>\n<document index="1" lines="201" bytes="2204">)\n`, streamed.String())
+
+		withStreamThreshold(t, 1<<20)
+		_, err = Generate(context.Background(), cfg, &whole, nil)
+		require.NoError(t, err)
+		withStreamThreshold(t, 64)
+		assert.Equal(t, whole.String(), streamed.String())
+	}
+}
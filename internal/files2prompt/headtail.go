@@ -0,0 +1,47 @@
+package files2prompt
+
+import "fmt"
+
+// truncationMarker replaces the lines --head-lines and --tail-lines leave out.
+const truncationMarker = "... [%d lines truncated] ...\n"
+
+// cutLines keeps only the first head and the last tail of a file's total lines
+// in segments, replacing those in between with a single truncationMarker
+// segment. The lines kept keep their real line numbers. A file of no more than
+// head+tail lines is left whole.
+func cutLines(segments []segment, total, head, tail int) []segment {
+	if head+tail >= total {
+		return segments
+	}
+	cut := lineRange{head, total - tail - 1}
+	// A marker reaching across either end of the cut is kept whole, and the
+	// lines it stands for are not counted as truncated too
+	for _, s := range segments {
+		switch {
+		case s.marker == "":
+		case s.start < cut.start && s.end >= cut.start:
+			cut.start = s.end + 1
+		case s.start >= cut.start && s.start <= cut.end && s.end > cut.end:
+			cut.end = s.start - 1
+		}
+	}
+	var kept []segment
+	marked := cut.start > cut.end
+	for _, s := range segments {
+		if s.end < cut.start || s.start > cut.end {
+			kept = append(kept, s)
+			continue
+		}
+		if s.start < cut.start {
+			kept = append(kept, segment{lineRange: lineRange{s.start, cut.start - 1}})
+		}
+		if !marked {
+			kept = append(kept, segment{cut, fmt.Sprintf(truncationMarker, cut.end-cut.start+1)})
+			marked = true
+		}
+		if s.end > cut.end {
+			kept = append(kept, segment{lineRange: lineRange{cut.end + 1, s.end}})
+		}
+	}
+	return kept
+}
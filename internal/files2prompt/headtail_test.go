@@ -0,0 +1,142 @@
+package files2prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestCutLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		segments   []segment
+		head, tail int
+		expected   []segment
+	}{
+		{
+			name:     "head",
+			segments: wholeFile(10),
+			head:     3,
+			expected: []segment{{lineRange: lineRange{0, 2}}, {lineRange{3, 9}, "... [7 lines truncated] ...\n"}},
+		},
+		{
+			name:     "tail",
+			segments: wholeFile(10),
+			tail:     2,
+			expected: []segment{{lineRange{0, 7}, "... [8 lines truncated] ...\n"}, {lineRange: lineRange{8, 9}}},
+		},
+		{
+			name:     "head and tail",
+			segments: wholeFile(10),
+			head:     2, tail: 2,
+			expected: []segment{
+				{lineRange: lineRange{0, 1}}, {lineRange{2, 7}, "... [6 lines truncated] ...\n"}, {lineRange: lineRange{8, 9}},
+			},
+		},
+		{name: "exactly head and tail", segments: wholeFile(4), head: 2, tail: 2, expected: wholeFile(4)},
+		{name: "shorter than head", segments: wholeFile(2), head: 5, expected: wholeFile(2)},
+		{
+			name:     "marker covering the cut",
+			segments: []segment{{lineRange: lineRange{0, 0}}, {lineRange{1, 8}, "[data]\n"}, {lineRange: lineRange{9, 9}}},
+			head:     2, tail: 2,
+			expected: []segment{{lineRange: lineRange{0, 0}}, {lineRange{1, 8}, "[data]\n"}, {lineRange: lineRange{9, 9}}},
+		},
+		{
+			// A squashed run reaching into the cut is kept whole and not counted again, one within it is dropped
+			name: "markers",
+			segments: []segment{
+				{lineRange: lineRange{0, 0}}, {lineRange{1, 4}, "[data]\n"}, {lineRange: lineRange{5, 5}},
+				{lineRange{6, 7}, "[data]\n"}, {lineRange: lineRange{8, 9}},
+			},
+			head: 3, tail: 1,
+			expected: []segment{
+				{lineRange: lineRange{0, 0}}, {lineRange{1, 4}, "[data]\n"}, {lineRange{5, 8}, "... [4 lines truncated] ...\n"},
+				{lineRange: lineRange{9, 9}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cutLines(tt.segments, lastLine(tt.segments), tt.head, tt.tail))
+		})
+	}
+}
+
+func TestProcessFileHeadTail(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "long.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content.String()), 0o600))
+	short := filepath.Join(dir, "short.txt")
+	require.NoError(t, os.WriteFile(short, []byte("line 1\nline 2\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		path     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "head",
+			path:     path,
+			config:   config.Config{HeadLines: 2},
+			expected: path + "\n---\nline 1\nline 2\n... [10 lines truncated] ...\n---\n\n",
+		},
+		{
+			name:     "tail",
+			path:     path,
+			config:   config.Config{TailLines: 2},
+			expected: path + "\n---\n... [10 lines truncated] ...\nline 11\nline 12\n---\n\n",
+		},
+		{
+			name:     "head then tail",
+			path:     path,
+			config:   config.Config{HeadLines: 1, TailLines: 1},
+			expected: path + "\n---\nline 1\n... [10 lines truncated] ...\nline 12\n---\n\n",
+		},
+		{
+			// Padded for line 12, and the tail keeps its real numbers
+			name:   "line numbers",
+			path:   path,
+			config: config.Config{HeadLines: 2, TailLines: 3, LineNumbers: true},
+			expected: path + "\n---\n  1 │ line 1\n  2 │ line 2\n... [7 lines truncated] ...\n" +
+				" 10 │ line 10\n 11 │ line 11\n 12 │ line 12\n---\n\n",
+		},
+		{
+			name:     "shorter than the lines kept",
+			path:     short,
+			config:   config.Config{HeadLines: 5, TailLines: 5, LineNumbersCompact: true},
+			expected: short + "\n---\n1:line 1\n2:line 2\n---\n\n",
+		},
+		{
+			name:     "markdown",
+			path:     path,
+			config:   config.Config{TailLines: 1, LineNumbersCompact: true, Markdown: true},
+			expected: "## " + path + "\n\n```\n... [11 lines truncated] ...\n12:line 12\n```\n\n",
+		},
+		{
+			name:   "claude xml",
+			path:   path,
+			config: config.Config{HeadLines: 1, ClaudeXML: true},
+			expected: "<document index=\"1\">\n<source>" + path + "</source>\n<document_content>\n" +
+				"line 1\n... [11 lines truncated] ...\n</document_content>\n</document>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderPath(tt.path, tt.config, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, out)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// htmlStyle is the whole of the styling of --html output: monospace blocks
+// striped every other line, and a gutter that is not copied with the code.
+const htmlStyle = `body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 70em; padding: 0 1em; color: #222; }
+nav li, h2 { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }
+h2 { font-size: 1em; margin: 2em 0 0.5em; word-break: break-all; }
+.meta { color: #666; font-size: 0.9em; margin: 0 0 0.5em; }
+pre { margin: 0; padding: 0.5em 0; overflow-x: auto; border: 1px solid #ddd; line-height: 1.5em;
+  background: repeating-linear-gradient(#fff 0 1.5em, #f4f4f4 1.5em 3em); background-position: 0 0.5em; background-attachment: local; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 0.9em; padding: 0 0.75em; }
+.ln { color: #999; user-select: none; padding-right: 1em; }
+.marker { color: #999; font-style: italic; }
+`
+
+// htmlOptions checks that --html is not given with another output format.
+func htmlOptions(config config.Config) error {
+	if !config.HTML {
+		return nil
+	}
+	switch {
+	case config.Markdown:
+		return errors.New("--html cannot be combined with --markdown")
+	case config.ClaudeXML:
+		return errors.New("--html cannot be combined with --cxml")
+	}
+	return nil
+}
+
+// htmlID returns the id of the section of the document shown as path. Paths
+// are kept readable, with anything an id or a URL fragment cannot hold
+// replaced, and a hash of the path added when something was, so that "a b"
+// and "a-b" do not share an id.
+func htmlID(path string) string {
+	id := []byte("file-")
+	changed := false
+	for _, c := range []byte(path) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-', c == '/':
+			id = append(id, c)
+		default:
+			id, changed = append(id, '-'), true
+		}
+	}
+	if changed {
+		h := fnv.New32a()
+		h.Write([]byte(path))
+		id = fmt.Appendf(id, "-%08x", h.Sum32())
+	}
+	return string(id)
+}
+
+// htmlOpen returns the start of --html output, up to the documents: the head,
+// with the style, and a table of contents linking to the section of every file
+// of plan.
+func htmlOpen(plan []PlannedFile) string {
+	var toc strings.Builder
+	files := 0
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			files++
+			fmt.Fprintf(&toc, "<li><a href=\"#%s\">%s</a></li>\n", html.EscapeString(htmlID(f.DisplayPath)), html.EscapeString(f.DisplayPath))
+		}
+	}
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>files2prompt: %d %s</title>\n", files, plural(files, "file", "files"))
+	b.WriteString("<style>\n" + htmlStyle + "</style>\n</head>\n<body>\n")
+	if files > 0 {
+		b.WriteString("<nav>\n<h1>Files</h1>\n<ol>\n" + toc.String() + "</ol>\n</nav>\n")
+	}
+	return b.String()
+}
+
+// htmlClose returns the end of --html output.
+func htmlClose() string {
+	return "</body>\n</html>\n"
+}
+
+// htmlDocument returns the section of a document labelled label, whose code
+// block holds code, already escaped and marked up. Its heading is the label,
+// as the header of a plain document would have it, and its id that of path.
+func htmlDocument(path, label, lang, code string, state *emitState) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<section id=\"%s\">\n<h2>%s</h2>\n", html.EscapeString(htmlID(path)), html.EscapeString(label))
+	if state.meta != nil {
+		fmt.Fprintf(&b, "<p class=\"meta\">%s</p>\n", html.EscapeString(metadataText(state.meta)))
+	}
+	class := ""
+	if lang != "" {
+		class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(lang))
+	}
+	fmt.Fprintf(&b, "<pre><code%s>%s</code></pre>\n</section>\n", class, code)
+	return b.String()
+}
+
+// htmlCode returns the lines of segments, escaped, with their numbers in a
+// gutter when numbered and the marker lines set apart. The gutter is counted in
+// state as the gutters of other formats are.
+func htmlCode(lines []string, segments []segment, numbered bool, state *emitState) string {
+	var b strings.Builder
+	width := len(fmt.Sprint(lastLine(segments)))
+	for _, s := range segments {
+		if s.marker != "" {
+			fmt.Fprintf(&b, "<span class=\"marker\">%s</span>\n", html.EscapeString(strings.TrimSuffix(s.marker, "\n")))
+			continue
+		}
+		for i, line := range lines[s.start : s.end+1] {
+			if numbered {
+				gutter := fmt.Sprintf("<span class=\"ln\">%*d</span>", width, s.start+i+1)
+				state.gutterBytes += int64(len(gutter))
+				b.WriteString(gutter)
+			}
+			b.WriteString(html.EscapeString(line) + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestHTML(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":         "if a < b && c {\n\treturn \"</code>\"\n}\n",
+		"notes/to do.txt": "one\ntwo",
+	})
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected []string
+	}{
+		{
+			name:   "plain",
+			config: config.Config{Paths: []string{"main.go", "notes"}},
+			expected: []string{
+				"<title>files2prompt: 2 files</title>\n",
+				"<nav>\n<h1>Files</h1>\n<ol>\n<li><a href=\"#file-main.go\">main.go</a></li>\n" +
+					"<li><a href=\"#file-notes/to-do.txt-ace664a5\">notes/to do.txt</a></li>\n</ol>\n</nav>\n",
+				"<section id=\"file-main.go\">\n<h2>main.go</h2>\n<pre><code class=\"language-go\">" +
+					"if a &lt; b &amp;&amp; c {\n\treturn &#34;&lt;/code&gt;&#34;\n}\n</code></pre>\n</section>\n",
+				"<section id=\"file-notes/to-do.txt-ace664a5\">\n<h2>notes/to do.txt</h2>\n<pre><code>one\ntwo\n</code></pre>\n</section>\n" +
+					"</body>\n</html>\n",
+			},
+		},
+		{
+			name:   "line numbers",
+			config: config.Config{Paths: []string{"main.go"}, LineNumbers: true, HeaderStats: true},
+			expected: []string{
+				"<h2>main.go (3 lines, 36 B)</h2>\n<pre><code class=\"language-go\">" +
+					"<span class=\"ln\">1</span>if a &lt; b &amp;&amp; c {\n<span class=\"ln\">2</span>\treturn &#34;&lt;/code&gt;&#34;\n<span class=\"ln\">3</span>}\n</code></pre>\n",
+			},
+		},
+		{
+			name:   "markers and real line numbers",
+			config: config.Config{Paths: []string{"main.go"}, TailLines: 1, LineNumbersCompact: true},
+			expected: []string{
+				"<pre><code class=\"language-go\"><span class=\"marker\">... [2 lines truncated] ...</span>\n<span class=\"ln\">3</span>}\n</code></pre>\n",
+			},
+		},
+		{
+			name:   "tree and omissions",
+			config: config.Config{Paths: []string{"main.go", "missing.go"}, Tree: true, EmbedWarnings: true},
+			expected: []string{
+				"<section id=\"file-directory-tree\">\n<h2>directory-tree</h2>\n<pre><code>main.go\n</code></pre>\n</section>\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Documents are rendered whole whatever their size
+			withStreamThreshold(t, 1)
+			cfg := tt.config
+			cfg.HTML = true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			out := buf.String()
+			assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n"), out)
+			assert.Contains(t, out, "<style>\n"+htmlStyle+"</style>\n")
+			for _, part := range tt.expected {
+				assert.Contains(t, out, part)
+			}
+			// No asset is loaded from elsewhere
+			assert.NotContains(t, out, "<link")
+			assert.NotContains(t, out, "<script")
+			assert.NotContains(t, out, "http")
+		})
+	}
+}
+
+func TestHTMLID(t *testing.T) {
+	assert.Equal(t, "file-src/main_test.go", htmlID("src/main_test.go"))
+	assert.Equal(t, "file-a-b-10a3f9f2", htmlID("a b"))
+	// Paths differing only in what is replaced keep apart
+	assert.NotEqual(t, htmlID("a b"), htmlID("a-b"))
+	assert.NotEqual(t, htmlID("a b"), htmlID("a#b"))
+	assert.Equal(t, "file-", htmlID("")[:5])
+}
+
+func TestHTMLErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"with markdown", config.Config{Markdown: true}, "--html cannot be combined with --markdown"},
+		{"with cxml", config.Config{ClaudeXML: true}, "--html cannot be combined with --cxml"},
+		{"with compat", config.Config{Compat: string(CompatFilesToPrompt)}, "--compat files-to-prompt cannot be combined with --html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths, cfg.HTML = []string{"testdata/file1.txt"}, true
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	withStdout(t)
+	_, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, HTML: true, SplitBytes: 100, OutputFile: t.TempDir() + "/out.html"})
+	assert.EqualError(t, err, "--split-bytes cannot be combined with --html")
+}
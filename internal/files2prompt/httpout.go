@@ -0,0 +1,262 @@
+package files2prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// httpOutputAttempts is how many times an upload is tried before giving up on
+// server errors.
+const httpOutputAttempts = 4
+
+// httpRetryDelay is the wait before the first retry of an upload, doubled for
+// each one after it.
+var httpRetryDelay = time.Second
+
+// httpErrorSnippet is how much of a failed response body is quoted in the error.
+const httpErrorSnippet = 512
+
+// isOutputURL reports whether the --output destination is an HTTP(S) URL.
+func isOutputURL(output string) bool {
+	return strings.HasPrefix(output, "http://") || strings.HasPrefix(output, "https://")
+}
+
+// RedactURL returns s, an --output URL, without the user information and
+// query that may hold credentials, for logs and errors.
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "[invalid URL]"
+	}
+	u.User, u.Fragment, u.RawFragment = nil, "", ""
+	if u.RawQuery != "" || u.ForceQuery {
+		u.RawQuery, u.ForceQuery = "", false
+		return u.String() + "?[redacted]"
+	}
+	return u.String()
+}
+
+// outputMethod returns the HTTP method selected by --output-method, defaulting to PUT.
+func outputMethod(config config.Config) (string, error) {
+	switch method := strings.ToUpper(config.OutputMethod); method {
+	case "":
+		return http.MethodPut, nil
+	case http.MethodPut, http.MethodPost:
+		return method, nil
+	}
+	return "", fmt.Errorf("invalid --output-method %q: use PUT or POST", config.OutputMethod)
+}
+
+// outputContentType returns the media type of the output format of config.
+func outputContentType(config config.Config) string {
+	switch {
+	case Format(config.Format) == FormatDigest:
+		return "text/tab-separated-values; charset=utf-8"
+	case Format(config.Format) == FormatDigestJSON:
+		return "application/jsonl; charset=utf-8"
+	case config.OpenAI:
+		return "application/json; charset=utf-8"
+	case config.JSONL:
+		return "application/jsonl; charset=utf-8"
+	case config.HTML:
+		return "text/html; charset=utf-8"
+	case config.Markdown:
+		return "text/markdown; charset=utf-8"
+	case config.ClaudeXML:
+		return "application/xml; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// outputAuth returns the Authorization header named by --output-auth-env, or "".
+// A bare token is sent as a bearer token.
+func outputAuth(config config.Config) (string, error) {
+	if config.OutputAuthEnv == "" {
+		return "", nil
+	}
+	value := strings.TrimSpace(os.Getenv(config.OutputAuthEnv))
+	if value == "" {
+		return "", fmt.Errorf("--output-auth-env %s is not set", config.OutputAuthEnv)
+	}
+	if !strings.Contains(value, " ") {
+		value = "Bearer " + value
+	}
+	return value, nil
+}
+
+// httpOutput uploads the output to a URL as it is written. The first request
+// streams the body; a copy is spooled to a temporary file meanwhile, so that a
+// request failing with a server error can be retried without holding the
+// output in memory.
+type httpOutput struct {
+	ctx                            context.Context
+	url, method, contentType, auth string
+	client                         *http.Client
+
+	spool *os.File
+	// live is the body of the first request, nil once it ended
+	live *io.PipeWriter
+	// first reports how the first request went
+	first chan httpAttempt
+	once  sync.Once
+	err   error
+}
+
+// httpAttempt is the outcome of one request: a response status and the start
+// of the response body, or the error that prevented a response.
+type httpAttempt struct {
+	status  int
+	snippet string
+	err     error
+}
+
+// newHTTPOutput starts uploading to url with the method, media type and
+// authorization selected by config.
+func newHTTPOutput(ctx context.Context, url string, config config.Config) (*httpOutput, error) {
+	method, err := outputMethod(config)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := outputAuth(config)
+	if err != nil {
+		return nil, err
+	}
+	spool, err := os.CreateTemp("", "files2prompt-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file for upload: %v", err)
+	}
+	o := &httpOutput{
+		ctx:         ctx,
+		url:         url,
+		method:      method,
+		contentType: outputContentType(config),
+		auth:        auth,
+		client:      http.DefaultClient,
+		spool:       spool,
+		first:       make(chan httpAttempt, 1),
+	}
+
+	body, live := io.Pipe()
+	o.live = live
+	go func() {
+		attempt := o.send(body, -1)
+		// Writes made after the server stopped reading go to the spool only
+		body.CloseWithError(errors.New("upload ended"))
+		o.first <- attempt
+	}()
+	return o, nil
+}
+
+// Write spools p and adds it to the body of the first request, while it lasts.
+func (o *httpOutput) Write(p []byte) (int, error) {
+	n, err := o.spool.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to spool upload: %v", err)
+	}
+	if o.live != nil {
+		if _, err := o.live.Write(p); err != nil {
+			o.live = nil
+		}
+	}
+	return n, nil
+}
+
+// send makes one request with body, of length size or -1 when unknown.
+func (o *httpOutput) send(body io.Reader, size int64) httpAttempt {
+	req, err := http.NewRequestWithContext(o.ctx, o.method, o.url, body)
+	if err != nil {
+		return httpAttempt{err: err}
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", o.contentType)
+	if o.auth != "" {
+		req.Header.Set("Authorization", o.auth)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return httpAttempt{err: err}
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, httpErrorSnippet))
+	return httpAttempt{status: resp.StatusCode, snippet: strings.TrimSpace(string(snippet))}
+}
+
+// Close ends the body of the first request and waits for the upload to
+// succeed, retrying from the spool on server and connection errors. A client
+// error fails at once, quoting the start of the response.
+func (o *httpOutput) Close() error {
+	o.once.Do(func() {
+		defer os.Remove(o.spool.Name())
+		defer o.spool.Close()
+		if o.live != nil {
+			o.live.Close()
+		}
+		attempt := <-o.first
+		delay := httpRetryDelay
+		for i := 1; ; i++ {
+			if attempt.err == nil && attempt.status < 400 {
+				log.Debugf("Uploaded output to %s: %d", RedactURL(o.url), attempt.status)
+				return
+			}
+			if attempt.err == nil && attempt.status < 500 {
+				o.err = attempt.failure(o)
+				return
+			}
+			if i == httpOutputAttempts {
+				o.err = fmt.Errorf("%v, after %d attempts", attempt.failure(o), i)
+				return
+			}
+			log.Warnf("Upload to %s failed (%v), retrying in %s", RedactURL(o.url), attempt.failure(o), delay)
+			time.Sleep(delay)
+			delay *= 2
+
+			size, err := o.spool.Seek(0, io.SeekEnd)
+			if err != nil {
+				o.err = fmt.Errorf("failed to read back upload: %v", err)
+				return
+			}
+			attempt = o.send(io.NewSectionReader(o.spool, 0, size), size)
+		}
+	})
+	return o.err
+}
+
+// abort ends the upload without completing it, unless Close did already.
+func (o *httpOutput) abort() {
+	o.once.Do(func() {
+		defer os.Remove(o.spool.Name())
+		defer o.spool.Close()
+		if o.live != nil {
+			o.live.CloseWithError(errors.New("output aborted"))
+		}
+		<-o.first
+		o.err = errors.New("upload aborted")
+	})
+}
+
+// failure describes a failed attempt.
+func (a httpAttempt) failure(o *httpOutput) error {
+	if a.err != nil {
+		// The client's errors quote the URL, with its query
+		if uerr, ok := errors.AsType[*url.Error](a.err); ok {
+			uerr.URL = RedactURL(uerr.URL)
+		}
+		return fmt.Errorf("failed to upload output: %v", a.err)
+	}
+	err := fmt.Errorf("%s %s: %d %s", o.method, RedactURL(o.url), a.status, http.StatusText(a.status))
+	if a.snippet != "" {
+		err = fmt.Errorf("%v: %s", err, a.snippet)
+	}
+	return err
+}
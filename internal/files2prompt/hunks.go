@@ -0,0 +1,383 @@
+package files2prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// fileDiff is the change git diff reports for one file.
+type fileDiff struct {
+	// path is the file's path in the repository, and oldPath its path before
+	// the change, which differs from it for a rename
+	path, oldPath string
+	// oldMode and newMode are set when the file mode changed
+	oldMode, newMode string
+	// similarity is the similarity index of a rename, such as "92%"
+	similarity string
+	binary     bool
+	hunks      []hunk
+}
+
+// hunk is one @@ section of a diff.
+type hunk struct {
+	// oldStart and newStart number the first line it covers in the old and the
+	// new file
+	oldStart, newStart int
+	// lines each start with ' ', '+', '-' or, for a "\ No newline at end of
+	// file" note, '\'
+	lines []string
+}
+
+// hunkHeader matches the line opening a hunk: "@@ -12,7 +12,9 @@ func main() {".
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseDiff parses the unified diff git diff writes, one fileDiff per file.
+// Anything before the first "diff --git" line is ignored.
+func parseDiff(text string) ([]fileDiff, error) {
+	var diffs []fileDiff
+	var d *fileDiff
+	// oldLeft and newLeft count the lines of the old and new file the current
+	// hunk has yet to cover
+	oldLeft, newLeft := 0, 0
+	for line := range strings.Lines(text) {
+		line = strings.TrimSuffix(line, "\n")
+		if oldLeft > 0 || newLeft > 0 {
+			h := &d.hunks[len(d.hunks)-1]
+			switch {
+			case line == "" || line[0] == ' ':
+				// Some tools strip the space from empty context lines
+				oldLeft, newLeft = oldLeft-1, newLeft-1
+				line = " " + strings.TrimPrefix(line, " ")
+			case line[0] == '-':
+				oldLeft--
+			case line[0] == '+':
+				newLeft--
+			case line[0] == '\\':
+			default:
+				return nil, fmt.Errorf("diff of %s: unexpected line %q in a hunk", d.path, line)
+			}
+			h.lines = append(h.lines, line)
+			continue
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			diffs = append(diffs, fileDiff{})
+			d = &diffs[len(diffs)-1]
+			d.oldPath, d.path = diffGitPaths(strings.TrimPrefix(line, "diff --git "))
+			continue
+		}
+		if d == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("diff of %s: malformed hunk header %q", d.path, line)
+			}
+			h := hunk{oldStart: atoiOr(m[1], 0), newStart: atoiOr(m[3], 0)}
+			oldLeft, newLeft = atoiOr(m[2], 1), atoiOr(m[4], 1)
+			d.hunks = append(d.hunks, h)
+		case strings.HasPrefix(line, `\`) && len(d.hunks) > 0:
+			// The note follows the last line of a hunk
+			h := &d.hunks[len(d.hunks)-1]
+			h.lines = append(h.lines, line)
+		case strings.HasPrefix(line, "old mode "):
+			d.oldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			d.newMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "similarity index "):
+			d.similarity = strings.TrimPrefix(line, "similarity index ")
+		case strings.HasPrefix(line, "rename from "):
+			d.oldPath = unquoteGitPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			d.path = unquoteGitPath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			d.binary = true
+		case strings.HasPrefix(line, "--- "):
+			if p := diffFilePath(line, "--- ", "a/"); p != "" {
+				d.oldPath = p
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if p := diffFilePath(line, "+++ ", "b/"); p != "" {
+				d.path = p
+			}
+		}
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, fmt.Errorf("diff of %s ends in the middle of a hunk", d.path)
+	}
+	return diffs, nil
+}
+
+// atoiOr returns s as a number, or fallback when s is empty.
+func atoiOr(s string, fallback int) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// diffGitPaths returns the paths of a "diff --git a/OLD b/NEW" line, after its
+// "diff --git ". Unquoted paths can hold spaces, so they are told apart by the
+// two being equal, which they are unless the file was renamed; the "rename
+// from" and "rename to" lines that follow then give them unambiguously.
+func diffGitPaths(s string) (oldPath, path string) {
+	if strings.HasPrefix(s, `"`) {
+		if end := quotedEnd(s); end > 0 {
+			return strings.TrimPrefix(unquoteGitPath(s[:end]), "a/"), strings.TrimPrefix(unquoteGitPath(strings.TrimSpace(s[end:])), "b/")
+		}
+	}
+	if n := (len(s) - len("a/ b/")) / 2; n > 0 && s[2+n:2+n+3] == " b/" {
+		return s[2 : 2+n], s[2+n+3:]
+	}
+	return "", ""
+}
+
+// quotedEnd returns the length of the quoted string s starts with, or 0.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// diffFilePath returns the path of a "--- a/PATH" or "+++ b/PATH" line, or ""
+// for /dev/null.
+func diffFilePath(line, marker, prefix string) string {
+	// git ends a path holding a space with a tab
+	p := unquoteGitPath(strings.TrimSuffix(strings.TrimPrefix(line, marker), "\t"))
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// unquoteGitPath undoes the C-style quoting git applies to paths holding
+// unusual characters.
+func unquoteGitPath(p string) string {
+	if strings.HasPrefix(p, `"`) {
+		if unquoted, err := strconv.Unquote(p); err == nil {
+			return unquoted
+		}
+	}
+	return p
+}
+
+// changedLines returns the number of lines the hunks of d add or remove.
+func (d *fileDiff) changedLines() int {
+	n := 0
+	for _, h := range d.hunks {
+		for _, line := range h.lines {
+			if line[0] == '+' || line[0] == '-' {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// summary describes the change to d in a line, as in "renamed from old.go, 3
+// hunks, 47 changed lines". oldName shows the path before a rename.
+func (d *fileDiff) summary(oldName func(string) string) string {
+	var parts []string
+	if d.oldPath != "" && d.oldPath != d.path {
+		renamed := "renamed from " + oldName(d.oldPath)
+		if d.similarity != "" && len(d.hunks) == 0 {
+			renamed += " (" + d.similarity + " similar)"
+		}
+		parts = append(parts, renamed)
+	}
+	if d.oldMode != "" && d.newMode != "" {
+		parts = append(parts, fmt.Sprintf("mode changed from %s to %s", d.oldMode, d.newMode))
+	}
+	if d.binary {
+		parts = append(parts, "binary content changed")
+	}
+	if n := len(d.hunks); n > 0 {
+		changed := d.changedLines()
+		parts = append(parts, fmt.Sprintf("%d %s, %d changed %s", n, plural(n, "hunk", "hunks"), changed, plural(changed, "line", "lines")))
+	}
+	if len(parts) == 0 {
+		return "no content changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// render returns the document --hunks-only shows for d: its hunks, separated
+// by regionSeparator, each line numbered as in the new file and marked as in
+// a unified diff, with removed lines unnumbered. A change without hunks, such
+// as a pure rename or a mode change, is summed up in a single line instead.
+func (d *fileDiff) render(summary string) string {
+	if len(d.hunks) == 0 {
+		return summary + "\n"
+	}
+	last := 0
+	for _, h := range d.hunks {
+		last = h.newStart - 1
+		for _, line := range h.lines {
+			if line[0] == ' ' || line[0] == '+' {
+				last++
+			}
+		}
+	}
+	width := len(strconv.Itoa(last))
+	var b strings.Builder
+	for i, h := range d.hunks {
+		if i > 0 {
+			b.WriteString(regionSeparator)
+		}
+		n := h.newStart
+		for _, line := range h.lines {
+			if line[0] == '-' || line[0] == '\\' {
+				fmt.Fprintf(&b, " %*s │ %s\n", width, "", line)
+				continue
+			}
+			fmt.Fprintf(&b, " %*d │ %s\n", width, n, line)
+			n++
+		}
+	}
+	return b.String()
+}
+
+// hunksOptions checks that the options of config can be combined with
+// --hunks-only, which shows hunks rather than the lines of the file.
+func hunksOptions(config config.Config) error {
+	if !config.HunksOnly {
+		return nil
+	}
+	if config.HunkContext < 0 {
+		return fmt.Errorf("invalid --context %d: use 0 or more lines", config.HunkContext)
+	}
+	for _, option := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--grep-context", config.Grep != "" && config.GrepContext >= 0},
+		{"--squash-data-blocks", config.SquashDataBlocks > 0},
+		{"--head-lines", config.HeadLines > 0},
+		{"--tail-lines", config.TailLines > 0},
+	} {
+		if option.set {
+			return fmt.Errorf("--hunks-only cannot be combined with %s", option.flag)
+		}
+	}
+	return nil
+}
+
+// planHunks attaches, with --hunks-only, the changes git diff reports against
+// HEAD to each included file of plan, and skips the files it reports none for.
+// Every repository holding a file is diffed once.
+func planHunks(plan []PlannedFile, config config.Config) error {
+	if !config.HunksOnly {
+		return nil
+	}
+	repos := map[string]map[string]*fileDiff{}
+	for i := range plan {
+		f := &plan[i]
+		if !f.Included || f.IsDir {
+			continue
+		}
+		abs := absPath(f.Path)
+		root, ok := findRepoRoot(filepath.Dir(abs))
+		if !ok {
+			return fmt.Errorf("--hunks-only needs a git repository, and %s is not in one", f.Path)
+		}
+		diffs, ok := repos[root]
+		if !ok {
+			var err error
+			if diffs, err = repoDiff(root, config.HunkContext, gitDiffBase(config)); err != nil {
+				return err
+			}
+			repos[root] = diffs
+		}
+		if d := diffs[abs]; d != nil {
+			f.diff = d
+		} else {
+			f.Included, f.Reason = false, SkipUnchanged
+		}
+	}
+	return nil
+}
+
+// repoDiff runs git diff against base, as gitDiffBase returns it, in the
+// repository at root, with context lines of context around each hunk, and
+// returns the changes by the absolute path of the file.
+func repoDiff(root string, context int, base []string) (map[string]*fileDiff, error) {
+	// Prefixes and quoting are pinned whatever the user's git configuration
+	args := []string{"-C", root, "-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "--no-textconv",
+		"--src-prefix=a/", "--dst-prefix=b/", "-M", "-U" + strconv.Itoa(context)}
+	cmd := exec.Command("git", append(append(args, base...), "--")...) // #nosec G204
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("--hunks-only: git diff in %s failed: %s", root, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("--hunks-only: %v", err)
+	}
+	parsed, err := parseDiff(string(out))
+	if err != nil {
+		return nil, err
+	}
+	diffs := map[string]*fileDiff{}
+	for i := range parsed {
+		// Deleted files are listed too, but never looked up, as they are not walked
+		if p := parsed[i].path; p != "" {
+			diffs[filepath.Join(root, filepath.FromSlash(p))] = &parsed[i]
+		}
+	}
+	return diffs, nil
+}
+
+// emitHunks renders the document --hunks-only shows for f, whose header notes
+// the size of the change.
+func emitHunks(f PlannedFile, read fileRead, config config.Config, writer io.Writer, state *emitState) error {
+	head := read.content
+	if read.scan != nil {
+		head = read.scan.head
+	}
+	lang := fenceLanguage(f.Path, string(head), config)
+	summary := f.diff.summary(func(p string) string { return state.anon.path(filepath.FromSlash(p), true) })
+	content := state.redact.content(f.DisplayPath, f.diff.render(summary))
+	if len(f.diff.hunks) > 0 {
+		state.diffSummary = summary
+		defer func() { state.diffSummary = "" }()
+	}
+	// The hunks carry the real line numbers already
+	config.LineNumbers, config.LineNumbersCompact = false, false
+	return emitDocument(f.DisplayPath, state.anon.content(content), lang, config, writer, state)
+}
+
+// diffSuffix returns the summary ending the path line of a plain or Markdown
+// --hunks-only document, or "".
+func diffSuffix(state *emitState) string {
+	if state.diffSummary == "" {
+		return ""
+	}
+	return " (" + state.diffSummary + ")"
+}
+
+// diffAttr returns the attribute holding the summary of a Claude XML
+// --hunks-only document, or "".
+func diffAttr(state *emitState) string {
+	if state.diffSummary == "" {
+		return ""
+	}
+	return ` diff="` + escapeXML(state.diffSummary) + `"`
+}
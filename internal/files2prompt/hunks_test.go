@@ -0,0 +1,237 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// testdata/hunks/changes.diff is git diff -M HEAD output for a scratch
+// repository, as repoDiff runs it, with a file of each kind of change.
+func parseFixtureDiff(t *testing.T) map[string]*fileDiff {
+	t.Helper()
+	text, err := os.ReadFile("testdata/hunks/changes.diff")
+	require.NoError(t, err)
+	diffs, err := parseDiff(string(text))
+	require.NoError(t, err)
+	byPath := map[string]*fileDiff{}
+	for i := range diffs {
+		byPath[diffs[i].path] = &diffs[i]
+	}
+	return byPath
+}
+
+func TestParseDiff(t *testing.T) {
+	diffs := parseFixtureDiff(t)
+	tests := []struct {
+		path    string
+		oldPath string
+		hunks   int
+		summary string
+	}{
+		{path: "fresh.go", oldPath: "fresh.go", hunks: 1, summary: "1 hunk, 1 changed line"},
+		{path: "gone.txt", oldPath: "gone.txt", hunks: 1, summary: "1 hunk, 1 changed line"},
+		{path: "logo.png", oldPath: "logo.png", summary: "binary content changed"},
+		{path: "main.go", oldPath: "main.go", hunks: 3, summary: "3 hunks, 6 changed lines"},
+		{path: "moved.txt", oldPath: "same.txt", summary: "renamed from same.txt (100% similar)"},
+		{path: "new.go", oldPath: "old.go", hunks: 1, summary: "renamed from old.go, 1 hunk, 2 changed lines"},
+		{path: "notes file.txt", oldPath: "notes file.txt", hunks: 1, summary: "1 hunk, 2 changed lines"},
+		{path: "run.sh", oldPath: "run.sh", summary: "mode changed from 100644 to 100755"},
+		{path: "tab\tname.txt", oldPath: "tab\tname.txt", hunks: 1, summary: "1 hunk, 1 changed line"},
+	}
+	assert.Len(t, diffs, len(tests))
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			d := diffs[tt.path]
+			require.NotNil(t, d, "parsed paths: %v", diffs)
+			assert.Equal(t, tt.oldPath, d.oldPath)
+			assert.Len(t, d.hunks, tt.hunks)
+			assert.Equal(t, tt.summary, d.summary(func(p string) string { return p }))
+		})
+	}
+
+	main := diffs["main.go"]
+	assert.Equal(t, []int{1, 12, 24}, []int{main.hunks[0].oldStart, main.hunks[1].oldStart, main.hunks[2].oldStart})
+	assert.Equal(t, []int{1, 12, 23}, []int{main.hunks[0].newStart, main.hunks[1].newStart, main.hunks[2].newStart})
+	// The empty context line of new.go keeps its marker
+	assert.Equal(t, " ", diffs["new.go"].hunks[0].lines[0])
+	assert.Equal(t, []string{" a", "-b", `\ No newline at end of file`, "+c", `\ No newline at end of file`}, diffs["notes file.txt"].hunks[0].lines)
+}
+
+func TestParseDiffErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		err  string
+	}{
+		{
+			name: "malformed hunk header",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @\n",
+			err:  `diff of a.go: malformed hunk header "@@ -1 +1 @"`,
+		},
+		{
+			name: "truncated hunk",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,3 +1,3 @@\n a\n-b\n",
+			err:  "diff of a.go ends in the middle of a hunk",
+		},
+		{
+			name: "stray line in a hunk",
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,2 +1,2 @@\n a\n*b\n",
+			err:  `diff of a.go: unexpected line "*b" in a hunk`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDiff(tt.diff)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestRenderHunks(t *testing.T) {
+	diffs := parseFixtureDiff(t)
+	expected := "" +
+		"  1 │  line 1\n" +
+		"  2 │  line 2\n" +
+		"    │ -line 3\n" +
+		"  3 │ +line three\n" +
+		"  4 │  line 4\n" +
+		"  5 │  line 5\n" +
+		"  6 │  line 6\n" +
+		"...\n" +
+		" 12 │  line 12\n" +
+		" 13 │  line 13\n" +
+		" 14 │  line 14\n" +
+		"    │ -line 15\n" +
+		" 15 │  line 16\n" +
+		" 16 │  line 17\n" +
+		" 17 │  line 18\n" +
+		"...\n" +
+		" 23 │  line 24\n" +
+		" 24 │  line 25\n" +
+		" 25 │  line 26\n" +
+		"    │ -line 27\n" +
+		" 26 │ +line twenty-seven\n" +
+		" 27 │  line 28\n" +
+		" 28 │  line 29\n" +
+		" 29 │  line 30\n" +
+		" 30 │ +line 31\n"
+	assert.Equal(t, expected, diffs["main.go"].render("3 hunks, 6 changed lines"))
+	assert.Equal(t, " 1 │  a\n   │ -b\n   │ \\ No newline at end of file\n 2 │ +c\n   │ \\ No newline at end of file\n",
+		diffs["notes file.txt"].render(""))
+	assert.Equal(t, "mode changed from 100644 to 100755\n", diffs["run.sh"].render("mode changed from 100644 to 100755"))
+}
+
+// hunksRepo creates a repository with a commit of several files, then changes
+// some of them in the working tree, and returns its path.
+func hunksRepo(t *testing.T) string {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":      "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"util/old.go":  "package util\n\n// A is a.\nfunc A() {}\n",
+		"untouched.go": "package main\n",
+		"README.md":    "# Title\n",
+	})
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "init")
+	writeFiles(t, dir, map[string]string{
+		"main.go":    "package main\n\nfunc main() {\n\tprintln(\"hello, world\")\n}\n",
+		"README.md":  "# Title\n\nMore.\n",
+		"scratch.go": "package main\n",
+	})
+	runGit(t, dir, "mv", "util/old.go", "util/new.go")
+	return dir
+}
+
+func TestHunksOnly(t *testing.T) {
+	dir := hunksRepo(t)
+	t.Chdir(dir)
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:   "plain",
+			config: config.Config{Paths: []string{"main.go", "untouched.go", "util"}, HunkContext: 1},
+			expected: "main.go (1 hunk, 2 changed lines)\n---\n" +
+				" 3 │  func main() {\n   │ -\tprintln(\"hello\")\n 4 │ +\tprintln(\"hello, world\")\n 5 │  }\n---\n\n" +
+				"util/new.go\n---\nrenamed from util/old.go (100% similar)\n---\n\n",
+		},
+		{
+			name:   "markdown with line numbers",
+			config: config.Config{Paths: []string{"README.md"}, Markdown: true, LineNumbers: true, HunkContext: 3},
+			expected: "## README.md (1 hunk, 2 changed lines)\n\n```markdown\n" +
+				" 1 │  # Title\n 2 │ +\n 3 │ +More.\n```\n\n",
+		},
+		{
+			name:   "cxml",
+			config: config.Config{Paths: []string{"README.md"}, ClaudeXML: true},
+			expected: "<documents>\n<document index=\"1\" diff=\"1 hunk, 2 changed lines\">\n<source>README.md</source>\n<document_content>\n" +
+				" 2 │ +\n 3 │ +More.\n</document_content>\n</document>\n</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.HunksOnly = true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+
+	t.Run("plan", func(t *testing.T) {
+		plan, err := Plan(context.Background(), config.Config{Paths: []string{"."}, HunksOnly: true}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"README.md", "main.go", "util/new.go"}, includedPaths(t, dir, plan))
+		for _, f := range plan {
+			if !f.Included && !f.IsDir {
+				assert.Equal(t, SkipUnchanged, f.Reason, f.Path)
+			}
+		}
+	})
+}
+
+func TestHunksOnlyErrors(t *testing.T) {
+	outside := t.TempDir()
+	writeFiles(t, outside, map[string]string{"a.go": "package a\n"})
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{
+			name:   "outside a repository",
+			config: config.Config{Paths: []string{filepath.Join(outside, "a.go")}},
+			err:    "--hunks-only needs a git repository, and " + filepath.Join(outside, "a.go") + " is not in one",
+		},
+		{
+			name:   "with --head-lines",
+			config: config.Config{Paths: []string{outside}, HeadLines: 10},
+			err:    "--hunks-only cannot be combined with --head-lines",
+		},
+		{
+			name:   "negative context",
+			config: config.Config{Paths: []string{outside}, HunkContext: -1},
+			err:    "invalid --context -1: use 0 or more lines",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.HunksOnly = true
+			_, err := Plan(context.Background(), cfg, false)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
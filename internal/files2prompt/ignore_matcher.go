@@ -0,0 +1,108 @@
+package files2prompt
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// literalGlob is a doublestar pattern annotated with the literal prefix and suffix
+// every matching name must have. Checking those with plain string comparisons
+// rejects most candidates before any glob matching happens. The pattern must be
+// one translatePattern returned.
+type literalGlob struct {
+	pattern string
+	prefix  string
+	suffix  string
+	literal bool
+}
+
+const (
+	// globMeta are the characters that start a non-literal doublestar construct.
+	globMeta = `*?[{\`
+	// globMetaEnd additionally includes the characters that close one.
+	globMetaEnd = globMeta + "]}"
+)
+
+func newLiteralGlob(pattern string) literalGlob {
+	first := strings.IndexAny(pattern, globMeta)
+	if first == -1 {
+		return literalGlob{pattern: pattern, prefix: pattern, suffix: pattern, literal: true}
+	}
+	last := strings.LastIndexAny(pattern, globMetaEnd)
+
+	prefix := pattern[:first]
+	// "a/**" also matches "a", so the separator before ** is optional
+	if strings.HasPrefix(pattern[first:], "**") {
+		prefix = strings.TrimSuffix(prefix, "/")
+	}
+	suffix := pattern[last+1:]
+	// "**/b" also matches "b", so the separator after ** is optional
+	if last > 0 && pattern[last] == '*' && pattern[last-1] == '*' {
+		suffix = strings.TrimPrefix(suffix, "/")
+	}
+	return literalGlob{pattern: pattern, prefix: prefix, suffix: suffix}
+}
+
+func (g literalGlob) match(name string) bool {
+	if !strings.HasPrefix(name, g.prefix) || !strings.HasSuffix(name, g.suffix) {
+		return false
+	}
+	if g.literal {
+		return name == g.pattern
+	}
+	// Patterns are translated and validated as they are read
+	return doublestar.MatchUnvalidated(g.pattern, name)
+}
+
+// compiledRule is a gitignore rule with its pattern precompiled.
+type compiledRule struct {
+	gitignoreRule
+	glob literalGlob
+}
+
+// ignoreMatcher is a precompiled, behavior-identical replacement for shouldIgnore.
+//
+// Rules are scanned from the last, since the last matching rule decides, and
+// the path relative to each rule's base is only recomputed when the base
+// changes: rules from the same .gitignore are adjacent.
+type ignoreMatcher struct {
+	rules []compiledRule
+}
+
+func compileIgnoreRules(rules []gitignoreRule) *ignoreMatcher {
+	m := &ignoreMatcher{}
+	for _, rule := range rules {
+		m.rules = append(m.rules, compiledRule{gitignoreRule: rule, glob: newLiteralGlob(rule.pattern)})
+	}
+	return m
+}
+
+// with returns a matcher of m's rules followed by rules, leaving m as it is.
+func (m *ignoreMatcher) with(rules []compiledRule) *ignoreMatcher {
+	return &ignoreMatcher{rules: append(slices.Clip(m.rules), rules...)}
+}
+
+// match reports whether path is ignored by the compiled rules, exactly as
+// shouldIgnore(path, isDir, rules) would.
+func (m *ignoreMatcher) match(path string, isDir bool) bool {
+	base, rel, within := "", "", false
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		r := &m.rules[i]
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if i == len(m.rules)-1 || r.base != base {
+			base = r.base
+			rel, within = relativeTo(base, path)
+		}
+		if !within || r.excludesItself(rel) {
+			continue
+		}
+		if r.glob.match(r.target(rel)) {
+			return !r.negate
+		}
+	}
+	return false
+}
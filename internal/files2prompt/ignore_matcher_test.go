@@ -0,0 +1,151 @@
+package files2prompt
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLiteralGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		prefix  string
+		suffix  string
+		literal bool
+	}{
+		{pattern: "node_modules", prefix: "node_modules", suffix: "node_modules", literal: true},
+		{pattern: "*.log", prefix: "", suffix: ".log"},
+		{pattern: "src/*.go", prefix: "src/", suffix: ".go"},
+		{pattern: "build/**", prefix: "build", suffix: ""},
+		{pattern: "**/tmp", prefix: "", suffix: "tmp"},
+		{pattern: "a/**/b", prefix: "a", suffix: "b"},
+		{pattern: `foo\*`, prefix: "foo", suffix: ""},
+		{pattern: "{a,b}.txt", prefix: "", suffix: ".txt"},
+		{pattern: "[!x]y/", prefix: "", suffix: "y/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			g := newLiteralGlob(tt.pattern)
+			assert.Equal(t, tt.prefix, g.prefix)
+			assert.Equal(t, tt.suffix, g.suffix)
+			assert.Equal(t, tt.literal, g.literal)
+		})
+	}
+}
+
+var (
+	corpusRuleParts = []string{
+		"*.log", "node_modules/", "dist/", "temp", "build", "src/*.go", "**/tmp", "a?c", "[ab]*", "[!x]y",
+		"{x,y}.txt", "deep/**/file.go", "**", "*", "docs/", "a/b", "a/**", "**/", "/abs", "main.go",
+		"*.go", "vendor/**", "c/", "x/*/z", `esc\*`, "*/", "src", "a/b/", ".DS_Store", "[a-c]/",
+		"!main.go", "!*.go", "!a/b", "!dist/x.txt", "!/build", "!src/", "dist/*", "/a/**", `\!x`,
+	}
+	// corpusBases are the directories the corpus rules are read from
+	corpusBases    = []string{".", "a", "src/x"}
+	corpusSegments = []string{"a", "b", "c", "x", "y", "z", "src", "temp", "build", "deep", "tmp", "docs",
+		"node_modules", "main.go", "file.go", "app.log", "x.txt", "ac", "abc", "bb", "xy", ".DS_Store", "esc*", "dist"}
+)
+
+func randomPath(r *rand.Rand) string {
+	n := 1 + r.Intn(6)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = corpusSegments[r.Intn(len(corpusSegments))]
+	}
+	p := strings.Join(parts, "/")
+	if r.Intn(8) == 0 {
+		p = "/" + p
+	}
+	return p
+}
+
+func TestIgnoreMatcherEquivalence(t *testing.T) {
+	r := rand.New(rand.NewSource(1491))
+
+	// every rule on its own
+	for _, line := range corpusRuleParts {
+		rules := parseGitignore(".", []string{line})
+		m := compileIgnoreRules(rules)
+		for i := 0; i < 500; i++ {
+			path, isDir := randomPath(r), r.Intn(2) == 0
+			if expected := shouldIgnore(path, isDir, rules); m.match(path, isDir) != expected {
+				t.Fatalf("rule %q path %q: matcher=%v shouldIgnore=%v", line, path, !expected, expected)
+			}
+		}
+	}
+
+	// random rule sets read from nested directories, exercising negations and
+	// the per-base relative paths
+	for set := 0; set < 300; set++ {
+		var rules []gitignoreRule
+		for _, base := range corpusBases[:1+r.Intn(len(corpusBases))] {
+			lines := make([]string, r.Intn(5))
+			for i := range lines {
+				lines[i] = corpusRuleParts[r.Intn(len(corpusRuleParts))]
+			}
+			rules = append(rules, parseGitignore(base, lines)...)
+		}
+		m := compileIgnoreRules(rules)
+		for i := 0; i < 100; i++ {
+			path, isDir := randomPath(r), r.Intn(2) == 0
+			if expected := shouldIgnore(path, isDir, rules); m.match(path, isDir) != expected {
+				t.Fatalf("rules %v path %q: matcher=%v shouldIgnore=%v", rules, path, !expected, expected)
+			}
+		}
+	}
+
+	assert.False(t, compileIgnoreRules(nil).match("any/path", false))
+}
+
+// benchmarkRules builds n realistic gitignore rules, none of which match benchmarkPaths
+// so every rule has to be evaluated.
+func benchmarkRules(n int) []gitignoreRule {
+	rules := make([]string, n)
+	for i := range rules {
+		switch i % 4 {
+		case 0:
+			rules[i] = fmt.Sprintf("*.gen%d", i)
+		case 1:
+			rules[i] = fmt.Sprintf("cache%d/", i)
+		case 2:
+			rules[i] = fmt.Sprintf("build/out%d/*.o", i)
+		default:
+			rules[i] = fmt.Sprintf("tmp%d", i)
+		}
+	}
+	return parseGitignore("repo", rules)
+}
+
+var benchmarkPaths = []string{
+	"repo/src/internal/pkg/service/handlers/v1/user_handler.go",
+	"repo/src/internal/pkg/service/handlers/v1/user_handler_test.go",
+	"repo/src/internal/pkg/service/handlers/v1/order_handler.go",
+	"repo/web/app/components/forms/inputs/text/TextInput.tsx",
+	"repo/docs/architecture/decisions/0001-record-architecture.md",
+}
+
+func BenchmarkShouldIgnore(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		rules := benchmarkRules(n)
+		b.Run(fmt.Sprintf("rules=%d/reference", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, p := range benchmarkPaths {
+					shouldIgnore(p, false, rules)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("rules=%d/compiled", n), func(b *testing.B) {
+			m := compileIgnoreRules(rules)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, p := range benchmarkPaths {
+					m.match(p, false)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package files2prompt
+
+import (
+	"io/fs"
+	"os"
+)
+
+// irregularType returns the skip reason for a file of the given mode that
+// cannot be read as a document, or "" for regular files, directories and
+// symlinks. Opening a named pipe blocks until something writes to it, and
+// reading a device may never end, so these are skipped before any read.
+func irregularType(mode fs.FileMode) SkipReason {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return SkipNamedPipe
+	case mode&fs.ModeSocket != 0:
+		return SkipSocket
+	case mode&fs.ModeDevice != 0:
+		return SkipDevice
+	case mode&fs.ModeIrregular != 0:
+		return SkipIrregular
+	}
+	return ""
+}
+
+// irregular returns a filter skipping files whose type irregularType reports as
+// reason. Symlinks are judged by what they point to, since that is what would
+// be read.
+func irregular(reason SkipReason) func(p *filterPipeline, c candidate) bool {
+	return func(p *filterPipeline, c candidate) bool {
+		mode := c.info.Mode()
+		if mode&fs.ModeSymlink != 0 {
+			info, err := os.Stat(c.path)
+			if err != nil {
+				return false
+			}
+			mode = info.Mode()
+		}
+		return irregularType(mode) == reason
+	}
+}
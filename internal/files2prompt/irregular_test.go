@@ -0,0 +1,29 @@
+package files2prompt
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIrregularType(t *testing.T) {
+	tests := []struct {
+		mode fs.FileMode
+		want SkipReason
+	}{
+		{0o644, ""},
+		{fs.ModeDir | 0o755, ""},
+		{fs.ModeSymlink | 0o777, ""},
+		{fs.ModeNamedPipe | 0o644, SkipNamedPipe},
+		{fs.ModeSocket | 0o755, SkipSocket},
+		{fs.ModeDevice | 0o660, SkipDevice},
+		{fs.ModeDevice | fs.ModeCharDevice | 0o666, SkipDevice},
+		{fs.ModeIrregular, SkipIrregular},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, irregularType(tt.mode))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+//go:build !windows
+
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// irregularFixture writes a tree holding a named pipe, a socket and a symlink
+// to the pipe next to a regular file.
+func irregularFixture(t *testing.T) string {
+	t.Helper()
+	// Socket paths are limited to about 100 bytes, too few for some temp directories
+	root, err := os.MkdirTemp("", "f2p")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(root) })
+	writeFiles(t, root, map[string]string{"main.go": "package main\n"})
+	require.NoError(t, syscall.Mkfifo(filepath.Join(root, "pipe"), 0o600))
+	require.NoError(t, os.Symlink("pipe", filepath.Join(root, "pipe-link")))
+	listener, err := net.Listen("unix", filepath.Join(root, "dev.sock"))
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	return root
+}
+
+// generateWithin runs Generate, failing the test if it has not returned after a
+// few seconds, as happens when a named pipe is opened.
+func generateWithin(t *testing.T, cfg config.Config) string {
+	t.Helper()
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		_, err := Generate(context.Background(), cfg, &out, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Generate blocked on an irregular file")
+	}
+	return out.String()
+}
+
+func TestIrregularFilesSkipped(t *testing.T) {
+	root := irregularFixture(t)
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{root}}, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range plan {
+		if !f.Included {
+			reasons[filepath.Base(f.Path)] = f.Reason
+		}
+	}
+	assert.Equal(t, map[string]SkipReason{
+		"pipe":      SkipNamedPipe,
+		"pipe-link": SkipNamedPipe,
+		"dev.sock":  SkipSocket,
+	}, reasons)
+
+	out := generateWithin(t, config.Config{Paths: []string{root}, Grep: "package", GrepContext: -1})
+	assert.Equal(t, filepath.Join(root, "main.go")+"\n---\npackage main\n---\n\n", out)
+}
+
+func TestIrregularFileArgument(t *testing.T) {
+	root := irregularFixture(t)
+	pipe := filepath.Join(root, "pipe")
+
+	out := generateWithin(t, config.Config{Paths: []string{pipe}, StdinPaths: []string{filepath.Join(root, "dev.sock")}})
+	assert.Empty(t, out)
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{pipe}}, true)
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Equal(t, SkipNamedPipe, plan[0].Reason)
+}
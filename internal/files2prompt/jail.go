@@ -0,0 +1,102 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideJail is returned when a path given to a run with --jail resolves
+// outside the jail directory.
+var ErrOutsideJail = errors.New("path is outside the jail")
+
+// jail confines a run to a directory tree. Paths are compared by their real
+// location, with every symlink resolved, so neither ".." nor a link can escape it.
+// A nil *jail allows every path.
+type jail struct {
+	// root is the real path of the jail directory
+	root string
+}
+
+// newJail returns a jail for dir, or nil when dir is empty.
+func newJail(dir string) (*jail, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	dir, err := hostEnv.expandTilde(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, err := realPath(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --jail directory: %v", err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --jail directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("invalid --jail directory: %s is not a directory", dir)
+	}
+	return &jail{root: root}, nil
+}
+
+// realPath returns the absolute path of path with every symlink resolved. Path
+// elements that do not exist yet, such as an output file about to be created,
+// are appended to the real path of their nearest existing ancestor.
+//
+// The path is deliberately not cleaned before resolution: "link/.." names the
+// parent of the link's target, not the directory holding the link.
+func realPath(path string) (string, error) {
+	abs := path
+	if !filepath.IsAbs(abs) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		abs = wd + string(filepath.Separator) + path
+	}
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		i := strings.LastIndexByte(abs, filepath.Separator)
+		if i <= len(filepath.VolumeName(abs)) {
+			return "", err
+		}
+		missing = append([]string{abs[i+1:]}, missing...)
+		abs = abs[:i]
+	}
+}
+
+// contains reports whether the real path real lies within the jail, the jail
+// directory itself included.
+func (j *jail) contains(real string) bool {
+	rel, err := filepath.Rel(j.root, real)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// check returns an ErrOutsideJail error when path resolves outside the jail.
+func (j *jail) check(path string) error {
+	if j == nil {
+		return nil
+	}
+	real, err := realPath(path)
+	if err != nil {
+		return fmt.Errorf("%w: cannot resolve %s: %v", ErrOutsideJail, path, err)
+	}
+	if !j.contains(real) {
+		return fmt.Errorf("%w: %s resolves to %s, outside %s", ErrOutsideJail, path, real, j.root)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// jailFixture creates a jail directory next to an outside directory holding a
+// secret, with links from inside the jail pointing out of it.
+//
+//	jail/inside.dat
+//	jail/sub/nested.dat
+//	jail/escape.dat -> outside/secret.dat (absolute)
+//	jail/linkdir -> outside (absolute)
+//	outside/secret.dat
+func jailFixture(t *testing.T) (jailDir, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	jailDir = filepath.Join(base, "jail")
+	outside = filepath.Join(base, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(jailDir, "sub"), 0o700))
+	require.NoError(t, os.Mkdir(outside, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(jailDir, "inside.dat"), []byte("inside\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(jailDir, "sub", "nested.dat"), []byte("nested\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.dat"), []byte("secret\n"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.dat"), filepath.Join(jailDir, "escape.dat")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(jailDir, "linkdir")))
+	return jailDir, outside
+}
+
+func TestJailRejectsEscapingPaths(t *testing.T) {
+	jailDir, outside := jailFixture(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"dot-dot traversal", filepath.Join(jailDir, "..", "outside", "secret.dat")},
+		{"dot-dot beyond a missing directory", filepath.Join(jailDir, "missing") + "/../../outside/secret.dat"},
+		{"absolute symlink", filepath.Join(jailDir, "escape.dat")},
+		{"symlinked intermediate directory", filepath.Join(jailDir, "linkdir", "secret.dat")},
+		{"dot-dot through a symlink", filepath.Join(jailDir, "linkdir") + "/../outside/secret.dat"},
+		{"outside directory", outside},
+		{"sibling sharing the jail's name as a prefix", jailDir + "-other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, cfg := range map[string]config.Config{
+				"argument": {Jail: jailDir, Paths: []string{tt.path}},
+				"stdin":    {Jail: jailDir, StdinPaths: []string{tt.path}},
+			} {
+				var buf bytes.Buffer
+				_, err := Generate(context.Background(), cfg, &buf, nil)
+				assert.ErrorIs(t, err, ErrOutsideJail, name)
+				assert.NotContains(t, buf.String(), "secret", name)
+			}
+		})
+	}
+}
+
+func TestJailAllowsPathsAtTheBoundary(t *testing.T) {
+	jailDir, _ := jailFixture(t)
+
+	for _, path := range []string{
+		jailDir,
+		jailDir + string(filepath.Separator),
+		filepath.Join(jailDir, "inside.dat"),
+		filepath.Join(jailDir, "sub") + "/../inside.dat",
+	} {
+		t.Run(path, func(t *testing.T) {
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), config.Config{Jail: jailDir, Paths: []string{path}}, &buf, nil)
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), "inside\n")
+			assert.NotContains(t, buf.String(), "secret")
+			assert.GreaterOrEqual(t, summary.Files, 1)
+		})
+	}
+}
+
+func TestJailSkipsWalkedSymlinksLeadingOutside(t *testing.T) {
+	jailDir, _ := jailFixture(t)
+	// A link that stays inside the jail is still followed
+	require.NoError(t, os.Symlink(filepath.Join(jailDir, "sub", "nested.dat"), filepath.Join(jailDir, "alias.dat")))
+
+	plan, err := Plan(context.Background(), config.Config{Jail: jailDir, Paths: []string{jailDir}}, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range plan {
+		reasons[filepath.Base(f.Path)] = f.Reason
+	}
+	assert.Equal(t, SkipJail, reasons["escape.dat"])
+	assert.Equal(t, SkipJail, reasons["linkdir"])
+	assert.Equal(t, SkipReason(""), reasons["alias.dat"])
+	assert.Equal(t, SkipReason(""), reasons["inside.dat"])
+
+	// Without a jail the link is read as before
+	plan, err = Plan(context.Background(), config.Config{Paths: []string{jailDir}}, false)
+	require.NoError(t, err)
+	var paths []string
+	for _, f := range plan {
+		paths = append(paths, filepath.Base(f.Path))
+	}
+	assert.Contains(t, paths, "escape.dat")
+}
+
+func TestJailConstrainsOutput(t *testing.T) {
+	jailDir, outside := jailFixture(t)
+	withStdout(t)
+
+	cfg := config.Config{Jail: jailDir, Paths: []string{filepath.Join(jailDir, "inside.dat")}}
+	for _, output := range []string{
+		filepath.Join(outside, "prompt.md"),
+		filepath.Join(jailDir, "linkdir", "prompt.md"),
+		filepath.Join(jailDir, "..", "prompt.md"),
+	} {
+		cfg.OutputFile = output
+		_, err := Run(cfg)
+		assert.ErrorIs(t, err, ErrOutsideJail, output)
+		assert.NoFileExists(t, output)
+	}
+
+	cfg.OutputFile = filepath.Join(jailDir, "out", "..", "prompt.md")
+	require.NoError(t, os.Mkdir(filepath.Join(jailDir, "out"), 0o700))
+	_, err := Run(cfg)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(jailDir, "prompt.md"))
+}
+
+func TestNewJail(t *testing.T) {
+	jailDir, _ := jailFixture(t)
+
+	j, err := newJail("")
+	require.NoError(t, err)
+	assert.Nil(t, j)
+	assert.NoError(t, j.check("/anywhere"))
+
+	// The jail itself may be reached through a symlink
+	link := filepath.Join(t.TempDir(), "jail-link")
+	require.NoError(t, os.Symlink(jailDir, link))
+	j, err = newJail(link)
+	require.NoError(t, err)
+	assert.NoError(t, j.check(filepath.Join(jailDir, "inside.dat")))
+
+	_, err = newJail(filepath.Join(jailDir, "inside.dat"))
+	assert.ErrorContains(t, err, "not a directory")
+	_, err = newJail(filepath.Join(jailDir, "missing"))
+	assert.ErrorContains(t, err, "invalid --jail directory")
+}
@@ -0,0 +1,73 @@
+package files2prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// jsonlDocument is a line of --jsonl output.
+type jsonlDocument struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Index   int    `json:"index"`
+	// The fields of --metadata, absent without it
+	*jsonlMeta
+}
+
+// jsonlMeta is the --metadata of a --jsonl document, under the names of the
+// Claude XML attributes.
+type jsonlMeta struct {
+	FileSize  int64  `json:"file_size"`
+	FileLines int64  `json:"file_lines"`
+	MTime     string `json:"mtime,omitempty"`
+	Mode      string `json:"mode"`
+	// Provenance is where the content came from, and Retrieved when a remote
+	// source was fetched
+	Provenance string `json:"provenance"`
+	Retrieved  string `json:"retrieved,omitempty"`
+}
+
+// jsonlOptions checks that --jsonl is not given with another output format.
+func jsonlOptions(config config.Config) error {
+	if !config.JSONL {
+		return nil
+	}
+	switch {
+	case config.Markdown:
+		return errors.New("--jsonl cannot be combined with --markdown")
+	case config.ClaudeXML:
+		return errors.New("--jsonl cannot be combined with --cxml")
+	case config.HTML:
+		return errors.New("--jsonl cannot be combined with --html")
+	}
+	return nil
+}
+
+// jsonlLine returns the line of the document numbered index shown as path,
+// with its newline. The metadata of state is added with --metadata. As with
+// the jsonEscape template helper, <, > and & are left as they are.
+func jsonlLine(path, content string, index int, state *emitState) (string, error) {
+	doc := jsonlDocument{Path: path, Content: content, Index: index}
+	if m := state.meta; m != nil {
+		doc.jsonlMeta = &jsonlMeta{FileSize: m.size, FileLines: m.lines, Mode: fmt.Sprintf("%04o", m.mode.Perm()),
+			Provenance: m.provenance.String()}
+		if !m.modTime.IsZero() {
+			doc.MTime = m.modTime.UTC().Format(time.RFC3339)
+		}
+		if retrieved := m.provenance.Retrieved; !retrieved.IsZero() {
+			doc.Retrieved = retrieved.UTC().Format(time.RFC3339)
+		}
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,135 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// jsonlLines decodes every line of out, failing unless each is a JSON object.
+func jsonlLines(t *testing.T, out string) []map[string]any {
+	t.Helper()
+	require.True(t, strings.HasSuffix(out, "\n"), out)
+	var docs []map[string]any
+	for line := range strings.Lines(out) {
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &doc), line)
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestJSONL(t *testing.T) {
+	dir := t.TempDir()
+	tricky := "quote \" backslash \\ <tag> & done\n\ttab\x01\r\nünïcode"
+	writeFiles(t, dir, map[string]string{
+		"a.txt":     "line 1\nline 2\n",
+		"b/c.go":    tricky,
+		"empty.txt": "",
+	})
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected []map[string]any
+	}{
+		{
+			name:   "documents",
+			config: config.Config{Paths: []string{"a.txt", "b", "empty.txt"}},
+			expected: []map[string]any{
+				{"path": "a.txt", "content": "line 1\nline 2\n", "index": 1.0},
+				{"path": "b/c.go", "content": tricky, "index": 2.0},
+				{"path": "empty.txt", "content": "", "index": 3.0},
+			},
+		},
+		{
+			name:   "line numbers",
+			config: config.Config{Paths: []string{"a.txt"}, LineNumbers: true},
+			expected: []map[string]any{
+				{"path": "a.txt", "content": " 1 │ line 1\n 2 │ line 2\n", "index": 1.0},
+			},
+		},
+		{
+			name:   "tree and omissions",
+			config: config.Config{Paths: []string{"a.txt", "b"}, Tree: true, EmbedWarnings: true, HeadLines: 1, MaxFileSize: 20},
+			expected: []map[string]any{
+				{"path": "directory-tree", "content": "a.txt\n", "index": 1.0},
+				{"path": "a.txt", "content": "line 1\n... [1 lines truncated] ...\n", "index": 2.0},
+				{"path": "omissions", "index": 3.0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Documents are encoded whole whatever their size
+			withStreamThreshold(t, 1)
+			cfg := tt.config
+			cfg.JSONL = true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			docs := jsonlLines(t, buf.String())
+			require.Len(t, docs, len(tt.expected), buf.String())
+			for i, expected := range tt.expected {
+				if expected["path"] == warningsSource {
+					assert.Contains(t, docs[i]["content"], "Some content was left out of this prompt:\n- 1 file over 20 B was omitted: b/c.go")
+					delete(docs[i], "content")
+				}
+				assert.Equal(t, expected, docs[i])
+			}
+		})
+	}
+
+	// Nothing is escaped that JSON does not need to be
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"b"}, JSONL: true}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"path":"b/c.go","content":"quote \" backslash \\ <tag> & done\n\ttab\u0001\r\nünïcode","index":1}`+"\n", buf.String())
+}
+
+func TestJSONLMetadata(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	writeFiles(t, dir, map[string]string{"a.txt": "one\ntwo\n"})
+	require.NoError(t, os.Chmod(path, 0o640))
+	modTime := time.Date(2024, 8, 1, 12, 30, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	t.Chdir(dir)
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"a.txt"}, JSONL: true, IncludeMetadata: true}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"path":"a.txt","content":"one\ntwo\n","index":1,"file_size":8,"file_lines":2,"mtime":"2024-08-01T12:30:00Z","mode":"0640","provenance":"local"}`+"\n", buf.String())
+}
+
+func TestJSONLErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"with markdown", config.Config{Markdown: true}, "--jsonl cannot be combined with --markdown"},
+		{"with cxml", config.Config{ClaudeXML: true}, "--jsonl cannot be combined with --cxml"},
+		{"with html", config.Config{HTML: true}, "--jsonl cannot be combined with --html"},
+		{"with compat", config.Config{Compat: string(CompatFilesToPrompt)}, "--compat files-to-prompt cannot be combined with --jsonl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths, cfg.JSONL = []string{"testdata/file1.txt"}, true
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package files2prompt
+
+import (
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// junkFiles is the table of base-name patterns for the metadata, caches and
+// editor leftovers that operating systems and editors drop into working trees.
+// They are skipped whatever the platform the tree is walked on, hidden or not,
+// unless --include-junk is given.
+var junkFiles = []struct {
+	pattern string
+	reason  string
+}{
+	{".DS_Store", "macOS Finder metadata"},
+	{"._*", "macOS AppleDouble resource fork"},
+	{"Thumbs.db", "Windows thumbnail cache"},
+	{"desktop.ini", "Windows folder settings"},
+	{"*.swp", "Vim swap file"},
+	{"*~", "editor backup file"},
+}
+
+// isJunk reports whether the file at path is one of junkFiles, returning the
+// matching table entry's description.
+func isJunk(path string) (string, bool) {
+	base := filepath.Base(path)
+	for _, entry := range junkFiles {
+		if doublestar.MatchUnvalidated(entry.pattern, base) {
+			return entry.reason, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package files2prompt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestIsJunk(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: ".DS_Store", expected: true},
+		{path: "assets/.DS_Store", expected: true},
+		{path: "._main.go", expected: true},
+		{path: "docs/._README.md", expected: true},
+		{path: "Thumbs.db", expected: true},
+		{path: "images/desktop.ini", expected: true},
+		{path: ".main.go.swp", expected: true},
+		{path: "notes.txt~", expected: true},
+		{path: "desktop.initializer.go", expected: false},
+		{path: "thumbs.go", expected: false},
+		{path: "swap.go", expected: false},
+		{path: "main.swp.go", expected: false},
+		{path: "_main.go", expected: false},
+		{path: "~notes.txt", expected: false},
+		{path: ".DS_Store.go", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, ok := isJunk(tt.path)
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+}
+
+func TestPlanSkipsJunk(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".DS_Store":              "\x00\x00\x00\x01Bud1",
+		"._main.go":              "\x00\x05\x16\x07",
+		"main.go":                "package main\n",
+		"desktop.initializer.go": "package main\n",
+		"img/Thumbs.db":          "\xd0\xcf\x11\xe0",
+		"img/desktop.ini":        "[.ShellClassInfo]\n",
+		"img/logo.svg":           "<svg/>\n",
+		".main.go.swp":           "b0VIM 9.0",
+		"notes.txt~":             "old notes\n",
+	})
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected []string
+	}{
+		{
+			name:     "skipped even with hidden files",
+			config:   config.Config{Paths: []string{dir}, IncludeHidden: true},
+			expected: []string{"desktop.initializer.go", "img/logo.svg", "main.go"},
+		},
+		{
+			name:   "--include-junk",
+			config: config.Config{Paths: []string{dir}, IncludeHidden: true, IncludeJunk: true},
+			expected: []string{".DS_Store", "._main.go", ".main.go.swp", "desktop.initializer.go",
+				"img/Thumbs.db", "img/desktop.ini", "img/logo.svg", "main.go", "notes.txt~"},
+		},
+		{
+			name:     "named explicitly",
+			config:   config.Config{Paths: []string{filepath.Join(dir, ".DS_Store"), filepath.Join(dir, "notes.txt~")}},
+			expected: []string{".DS_Store", "notes.txt~"},
+		},
+		{
+			name:     "files-to-prompt compatibility",
+			config:   config.Config{Paths: []string{filepath.Join(dir, "img")}, Compat: string(CompatFilesToPrompt)},
+			expected: []string{"img/Thumbs.db", "img/desktop.ini", "img/logo.svg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Plan(context.Background(), tt.config, true)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, includedPaths(t, dir, plan))
+			for _, f := range plan {
+				if !f.Included {
+					assert.Equal(t, SkipJunk, f.Reason, f.Path)
+				}
+			}
+		})
+	}
+}
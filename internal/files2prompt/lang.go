@@ -0,0 +1,168 @@
+package files2prompt
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// extToLang maps a file extension, without its dot, to the language labelling
+// its Markdown fence.
+var extToLang = map[string]string{
+	"py":         "python",
+	"pyi":        "python",
+	"c":          "c",
+	"h":          "c",
+	"cpp":        "cpp",
+	"cc":         "cpp",
+	"cxx":        "cpp",
+	"hpp":        "cpp",
+	"hh":         "cpp",
+	"cs":         "csharp",
+	"java":       "java",
+	"kt":         "kotlin",
+	"kts":        "kotlin",
+	"scala":      "scala",
+	"groovy":     "groovy",
+	"gradle":     "groovy",
+	"clj":        "clojure",
+	"js":         "javascript",
+	"mjs":        "javascript",
+	"cjs":        "javascript",
+	"jsx":        "jsx",
+	"ts":         "typescript",
+	"mts":        "typescript",
+	"cts":        "typescript",
+	"tsx":        "tsx",
+	"vue":        "vue",
+	"svelte":     "svelte",
+	"html":       "html",
+	"htm":        "html",
+	"css":        "css",
+	"scss":       "scss",
+	"sass":       "sass",
+	"less":       "less",
+	"xml":        "xml",
+	"svg":        "xml",
+	"json":       "json",
+	"jsonc":      "jsonc",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+	"toml":       "toml",
+	"ini":        "ini",
+	"cfg":        "ini",
+	"sh":         "bash",
+	"bash":       "bash",
+	"zsh":        "zsh",
+	"fish":       "fish",
+	"ps1":        "powershell",
+	"bat":        "batch",
+	"cmd":        "batch",
+	"rb":         "ruby",
+	"go":         "go",
+	"rs":         "rust",
+	"swift":      "swift",
+	"m":          "objectivec",
+	"mm":         "objectivec",
+	"dart":       "dart",
+	"php":        "php",
+	"pl":         "perl",
+	"pm":         "perl",
+	"lua":        "lua",
+	"r":          "r",
+	"jl":         "julia",
+	"ex":         "elixir",
+	"exs":        "elixir",
+	"erl":        "erlang",
+	"hs":         "haskell",
+	"ml":         "ocaml",
+	"fs":         "fsharp",
+	"zig":        "zig",
+	"nim":        "nim",
+	"sql":        "sql",
+	"graphql":    "graphql",
+	"gql":        "graphql",
+	"proto":      "protobuf",
+	"tf":         "terraform",
+	"tfvars":     "terraform",
+	"hcl":        "hcl",
+	"nix":        "nix",
+	"dockerfile": "dockerfile",
+	"mk":         "makefile",
+	"cmake":      "cmake",
+	"md":         "markdown",
+	"rst":        "rst",
+	"tex":        "latex",
+	"diff":       "diff",
+	"patch":      "diff",
+}
+
+// nameToLang maps well-known file names, whatever their extension, to the
+// language labelling their Markdown fence.
+var nameToLang = map[string]string{
+	"Dockerfile":     "dockerfile",
+	"Containerfile":  "dockerfile",
+	"Makefile":       "makefile",
+	"GNUmakefile":    "makefile",
+	"makefile":       "makefile",
+	"Jenkinsfile":    "groovy",
+	"CMakeLists.txt": "cmake",
+	"Gemfile":        "ruby",
+	"Rakefile":       "ruby",
+	"Vagrantfile":    "ruby",
+	"Podfile":        "ruby",
+	"BUILD":          "starlark",
+	"BUILD.bazel":    "starlark",
+	"WORKSPACE":      "starlark",
+	"Tiltfile":       "starlark",
+}
+
+// fenceLanguage returns the language labelling the document of the file at
+// path, whose content starts with head, or "" when none is known. A --lang
+// override for its name or extension comes first, then nameToLang and
+// extToLang, then the interpreter of a "#!" line when it has no extension and,
+// with --detect-lang, the other heuristics of detectLanguage.
+func fenceLanguage(path, head string, config config.Config) string {
+	name := filepath.Base(path)
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	if lang := overrideLanguage(name, config.LanguageOverrides); lang != "" {
+		return lang
+	}
+	if lang := nameToLang[name]; lang != "" {
+		return lang
+	}
+	if lang := extToLang[ext]; lang != "" {
+		return lang
+	}
+	if config.DetectLang {
+		return detectLanguage(path, head)
+	}
+	if ext == "" {
+		return shebangLanguage(name, head)
+	}
+	return ""
+}
+
+// overrideLanguage returns the language overrides gives the file called name:
+// the one keyed by the name itself or, failing that, by the longest extension
+// it ends in, written as --extension accepts it ("tf", ".tf" or "*.tf").
+func overrideLanguage(name string, overrides map[string]string) string {
+	if lang, ok := overrides[name]; ok {
+		return lang
+	}
+	lang, longest := "", 0
+	for key, l := range overrides {
+		if ext := normalizeExtension(key); len(ext) > longest && hasExtension(name, []string{key}, false) {
+			lang, longest = l, len(ext)
+		}
+	}
+	return lang
+}
+
+// mappedLanguage reports whether the file at path has a language without
+// looking at its content: by --lang, nameToLang or extToLang.
+func mappedLanguage(path string, config config.Config) bool {
+	config.DetectLang = false
+	return fenceLanguage(path, "", config) != ""
+}
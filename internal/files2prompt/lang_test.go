@@ -0,0 +1,83 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestFenceLanguage(t *testing.T) {
+	overrides := map[string]string{"tf": "hcl", "*.d.ts": "typescript-declarations", ".ts": "ts", "Justfile": "make", "txt": "text"}
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		config  config.Config
+		want    string
+	}{
+		{name: "extension", path: "src/lib.rs", want: "rust"},
+		{name: "kotlin script", path: "build.gradle.kts", want: "kotlin"},
+		{name: "terraform", path: "infra/main.tf", want: "terraform"},
+		{name: "proto", path: "api/v1/service.proto", want: "protobuf"},
+		{name: "unknown extension", path: "data.xyz", want: ""},
+		{name: "dockerfile", path: "Dockerfile", want: "dockerfile"},
+		{name: "makefile", path: "sub/Makefile", want: "makefile"},
+		{name: "jenkinsfile", path: "Jenkinsfile", want: "groovy"},
+		{name: "cmake lists", path: "CMakeLists.txt", want: "cmake"},
+		{name: "name is case-sensitive", path: "dockerfile", want: ""},
+		{name: "env shebang", path: "bin/tool", content: "#!/usr/bin/env python3\nprint()\n", want: "python"},
+		{name: "sh shebang", path: "configure", content: "#!/bin/sh\n", want: "bash"},
+		{name: "shebang needs no extension", path: "notes.txt", content: "#!/bin/sh\n", want: ""},
+		{name: "extensionless without shebang", path: "LICENSE", content: "MIT\n", want: ""},
+		{
+			name:    "other heuristics with --detect-lang",
+			path:    "config",
+			content: "---\nkey: value\n",
+			config:  config.Config{DetectLang: true},
+			want:    "yaml",
+		},
+		{name: "override", path: "infra/main.tf", config: config.Config{LanguageOverrides: overrides}, want: "hcl"},
+		{name: "override by name", path: "Justfile", config: config.Config{LanguageOverrides: overrides}, want: "make"},
+		{name: "longest override wins", path: "types.d.ts", config: config.Config{LanguageOverrides: overrides}, want: "typescript-declarations"},
+		{name: "override with a dot", path: "index.ts", config: config.Config{LanguageOverrides: overrides}, want: "ts"},
+		{name: "override beats a special name", path: "CMakeLists.txt", config: config.Config{LanguageOverrides: overrides}, want: "text"},
+		{name: "no override applies", path: "main.go", config: config.Config{LanguageOverrides: overrides}, want: "go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fenceLanguage(tt.path, tt.content, tt.config))
+		})
+	}
+}
+
+func TestLanguageOverridesOutput(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.tf":  "resource \"null\" \"x\" {}\n",
+		"Makefile": "all:\n",
+		"deploy":   "#!/usr/bin/env bash\nexit 0\n",
+	})
+	t.Chdir(root)
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"Makefile", "deploy", "main.tf"}, Markdown: true, LanguageOverrides: map[string]string{".tf": "hcl"}}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "# Files\n\n## Makefile\n\n```makefile\nall:\n```\n\n"+
+		"## deploy\n\n```bash\n#!/usr/bin/env bash\nexit 0\n```\n\n"+
+		"## main.tf\n\n```hcl\nresource \"null\" \"x\" {}\n```\n\n", buf.String())
+
+	_, err = compatMode(config.Config{Compat: string(CompatFilesToPrompt), LanguageOverrides: map[string]string{"tf": "hcl"}})
+	assert.EqualError(t, err, "--compat files-to-prompt cannot be combined with --lang")
+}
+
+func TestMappedLanguage(t *testing.T) {
+	assert.True(t, mappedLanguage("main.go", config.Config{}))
+	assert.True(t, mappedLanguage("Dockerfile", config.Config{}))
+	assert.False(t, mappedLanguage("table.tsv", config.Config{}))
+	assert.True(t, mappedLanguage("table.tsv", config.Config{LanguageOverrides: map[string]string{"tsv": "csv"}}))
+}
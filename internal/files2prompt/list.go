@@ -0,0 +1,77 @@
+package files2prompt
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// writeList writes the path of every file of plan that a real run would emit,
+// instead of the documents themselves, as --list does. Paths are written one
+// per line, or NUL-terminated with --null so that any file name survives.
+// --cmd commands are not run. With --stats, each listed file is read to count
+// its lines and tokens, which are recorded in stats, reading through throttle.
+func writeList(ctx context.Context, plan []PlannedFile, roots []string, config config.Config, writer *ledger, stats *RunStats, throttle *readThrottle) (Summary, error) {
+	terminator := "\n"
+	if config.Null {
+		terminator = "\x00"
+	}
+	files := 0
+	for _, f := range plan {
+		warnSkipped(f, config)
+		if !f.Included {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return Summary{}, err
+		}
+		if _, err := io.WriteString(writer, f.Path+terminator); err != nil {
+			return Summary{}, err
+		}
+		files++
+		if stats != nil {
+			content, err := readFileThrottled(f.Path, readLimit(config), zeroSizeLimit(config), throttle)
+			if err != nil {
+				log.Warnf("Warning: Could not read %s for --stats: %v", f.Path, err)
+				continue
+			}
+			var u usage
+			u.add(content, writer.bpe)
+			u.lines = countLines(content)
+			u = writer.report(u)
+			stats.add(FileStats{Path: f.DisplayPath, Bytes: u.bytes, Lines: u.lines, Tokens: u.tokens})
+		}
+	}
+	if err := reportRoots(plan, roots, config); err != nil {
+		return Summary{}, err
+	}
+	used := writer.used()
+	return Summary{Files: files, Bytes: used.bytes, Tokens: used.tokens, Scope: writer.scope, Stats: stats}, nil
+}
+
+// ParsePathList splits a list of paths, as read from stdin, into its entries.
+// With null the entries are NUL-terminated, as find -print0 and --list --null
+// write them, so that names may hold spaces and newlines; a final newline
+// after the last NUL, which some pipelines add, is ignored. Otherwise entries
+// are separated by whitespace. Empty entries are dropped.
+func ParsePathList(content string, null bool) []string {
+	var paths []string
+	if null {
+		// A name may itself end in a newline, so only one following the last NUL goes
+		content = strings.TrimSuffix(content, "\x00\n")
+		paths = strings.Split(content, "\x00")
+	} else {
+		paths = strings.Fields(content)
+	}
+	var filtered []string
+	for _, p := range paths {
+		if p != "" {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
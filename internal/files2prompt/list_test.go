@@ -0,0 +1,126 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestListOnly(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":         "*.log\n",
+		".env.example":       "KEY=\n",
+		"debug.log":          "log\n",
+		"main.go":            "package main\n",
+		"notes.md":           "# notes\n",
+		"name with space.go": "package main\n",
+		"vendor/lib.go":      "package lib\n",
+		".hidden/secret.go":  "package hidden\n",
+	})
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string
+	}{
+		{
+			name: "defaults",
+			cfg:  config.Config{},
+			want: []string{"debug.log", "main.go", "name with space.go", "notes.md"},
+		},
+		{
+			name: "filters",
+			cfg:  config.Config{Extensions: []string{".go"}, IgnorePatterns: []string{"vendor/"}, IgnoreGitignore: true},
+			want: []string{"main.go", "name with space.go"},
+		},
+		{
+			name: "hidden and gitignore",
+			cfg:  config.Config{IncludeHidden: true, IgnoreGitignore: true},
+			want: []string{".env.example", ".gitignore", ".hidden/secret.go", "main.go", "name with space.go", "notes.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Paths = []string{root}
+
+			// The list is exactly the files a real run includes
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, includedPaths(t, root, plan))
+
+			cfg.ListOnly = true
+			var out bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &out, nil)
+			require.NoError(t, err)
+			var want strings.Builder
+			for _, rel := range tt.want {
+				want.WriteString(filepath.Join(root, filepath.FromSlash(rel)) + "\n")
+			}
+			assert.Equal(t, want.String(), out.String())
+			assert.Equal(t, len(tt.want), summary.Files)
+		})
+	}
+}
+
+func TestListOnlyNull(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n", "line\nbreak.go": "package b\n"})
+
+	var out bytes.Buffer
+	cfg := config.Config{Paths: []string{root}, ListOnly: true, Null: true, ClaudeXML: true, Tree: true}
+	_, err := Generate(context.Background(), cfg, &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "a.go")+"\x00"+filepath.Join(root, "line\nbreak.go")+"\x00", out.String())
+}
+
+func TestParsePathList(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		null    bool
+		want    []string
+	}{
+		{name: "whitespace", content: "a.go  b.go\nc.go\n", want: []string{"a.go", "b.go", "c.go"}},
+		{name: "nul terminated", content: "my file.go\x00line\nbreak.go\x00", null: true, want: []string{"my file.go", "line\nbreak.go"}},
+		{name: "nul separated", content: "a.go\x00b.go", null: true, want: []string{"a.go", "b.go"}},
+		{name: "newline after the last nul", content: "a.go\x00b c.go\x00\n", null: true, want: []string{"a.go", "b c.go"}},
+		{name: "name ending in a newline", content: "a.go\x00b.go\n\x00", null: true, want: []string{"a.go", "b.go\n"}},
+		{name: "empty entries", content: "\x00a.go\x00\x00", null: true, want: []string{"a.go"}},
+		{name: "empty", content: "", null: true, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParsePathList(tt.content, tt.null))
+		})
+	}
+}
+
+func TestListNullRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{"two words.go": "package a\n", "line\nbreak.go": "package b\n", "tab\there.go": "package c\n"}
+	writeFiles(t, root, files)
+
+	var list bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{root}, ListOnly: true, Null: true}, &list, nil)
+	require.NoError(t, err)
+	paths := ParsePathList(list.String()+"\n", true)
+	require.Len(t, paths, len(files))
+
+	// The listed paths, read back from stdin, select the same files
+	var out bytes.Buffer
+	summary, err := Generate(context.Background(), config.Config{StdinPaths: paths, Markdown: true}, &out, nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(files), summary.Files)
+	for name, content := range files {
+		assert.Contains(t, out.String(), "## "+filepath.Join(root, name)+"\n\n```go\n"+content+"```\n")
+	}
+}
@@ -0,0 +1,228 @@
+package files2prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// Defaults for the long-run notice thresholds left at zero in config.
+const (
+	DefaultLongRunFiles       = 100_000
+	DefaultLongRunBytes int64 = 50 << 20
+	DefaultLongRunAfter       = time.Minute
+)
+
+// longRunTopK is how many of the largest directories the long-run notice tracks.
+const longRunTopK = 5
+
+// ErrAborted is returned when the user declines to continue a long run.
+var ErrAborted = errors.New("aborted at the long-run prompt")
+
+// topK approximates the k keys with the largest totals using the space-saving
+// algorithm: it never holds more than k counters, so memory stays bounded no
+// matter how many keys are seen.
+type topK struct {
+	k      int
+	counts map[string]int64
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k, counts: map[string]int64{}}
+}
+
+// add credits n to key, evicting the smallest counter if key is new and the table is full.
+func (t *topK) add(key string, n int64) {
+	if _, ok := t.counts[key]; !ok && len(t.counts) >= t.k {
+		minKey, minCount := "", int64(-1)
+		for k, c := range t.counts {
+			if minCount < 0 || c < minCount || (c == minCount && k < minKey) {
+				minKey, minCount = k, c
+			}
+		}
+		delete(t.counts, minKey)
+		// The newcomer inherits the evicted count, so its total is an upper bound
+		t.counts[key] = minCount
+	}
+	t.counts[key] += n
+}
+
+// entry is a key and its total.
+type entry struct {
+	key   string
+	count int64
+}
+
+// top returns the tracked keys from largest to smallest total.
+func (t *topK) top() []entry {
+	entries := make([]entry, 0, len(t.counts))
+	for k, c := range t.counts {
+		entries = append(entries, entry{k, c})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].key < entries[j].key
+	})
+	return entries
+}
+
+// longRunMonitor watches a run's progress and, once any threshold is crossed,
+// prints a one-time notice naming the largest directories so far. In
+// interactive sessions it then asks whether to continue. A nil *longRunMonitor
+// is valid and watches nothing.
+type longRunMonitor struct {
+	maxFiles int64
+	maxBytes int64
+	maxTime  time.Duration
+	start    time.Time
+	now      func() time.Time
+
+	scanned  int64
+	selected int64
+	emitted  int64
+	dirs     *topK
+	notified bool
+	// progress reports the files the walk selects, for --progress
+	progress *progressReport
+}
+
+func newLongRunMonitor(config config.Config) *longRunMonitor {
+	m := &longRunMonitor{
+		maxFiles: config.LongRunFiles,
+		maxBytes: config.LongRunBytes,
+		maxTime:  config.LongRunAfter,
+		now:      time.Now,
+		dirs:     newTopK(longRunTopK),
+	}
+	if m.maxFiles <= 0 {
+		m.maxFiles = DefaultLongRunFiles
+	}
+	if m.maxBytes <= 0 {
+		m.maxBytes = DefaultLongRunBytes
+	}
+	if m.maxTime <= 0 {
+		m.maxTime = DefaultLongRunAfter
+	}
+	m.start = m.now()
+	return m
+}
+
+// scan records a file decision made by the planner.
+func (m *longRunMonitor) scan(f PlannedFile) error {
+	if m == nil || f.IsDir {
+		return nil
+	}
+	m.scanned++
+	if f.Included {
+		m.selected += f.Size
+		m.progress.add(f.Size)
+		if dir := topLevelDir(f); dir != "" {
+			m.dirs.add(dir, f.Size)
+		}
+	}
+	return m.check()
+}
+
+// emit records that the output has grown to total bytes.
+func (m *longRunMonitor) emit(total int64) error {
+	if m == nil {
+		return nil
+	}
+	m.emitted = total
+	return m.check()
+}
+
+// check prints the notice the first time a threshold is crossed, returning
+// ErrAborted if the user chooses to stop.
+func (m *longRunMonitor) check() error {
+	if m.notified {
+		return nil
+	}
+	elapsed := m.now().Sub(m.start)
+	if m.scanned < m.maxFiles && m.emitted < m.maxBytes && elapsed < m.maxTime {
+		return nil
+	}
+	m.notified = true
+
+	log.Warn(m.notice(elapsed))
+	if hostEnv.interactive() && hostEnv.confirm("Abort this run? [y/N] ") {
+		return ErrAborted
+	}
+	return nil
+}
+
+// notice summarizes the run so far and the flags that would narrow it.
+func (m *longRunMonitor) notice(elapsed time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "This run is getting large: %d files scanned, %s selected, %s emitted, %s elapsed.",
+		m.scanned, formatBytes(m.selected), formatBytes(m.emitted), elapsed.Round(time.Second))
+	if top := m.dirs.top(); len(top) > 0 {
+		b.WriteString(" Largest directories so far:")
+		for _, e := range top {
+			fmt.Fprintf(&b, "\n  %-10s %s  (--ignore '%s/')", formatBytes(e.count), e.key, filepath.Base(e.key))
+		}
+	}
+	return b.String()
+}
+
+// topLevelDir returns the directory directly beneath f's root that contains f,
+// joined onto the root, or "" for files that are not inside such a directory.
+func topLevelDir(f PlannedFile) string {
+	if f.Origin != OriginWalk {
+		return ""
+	}
+	root := f.Root
+	if root == "." {
+		if wd, err := os.Getwd(); err == nil {
+			root = wd
+		}
+	}
+	rel, err := filepath.Rel(root, f.Path)
+	if err != nil {
+		return ""
+	}
+	first, _, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if !ok {
+		return ""
+	}
+	return filepath.Join(f.Root, first)
+}
+
+// stdinIsTerminal reports whether both stdin and stderr are terminals, so that
+// a prompt on stderr can be answered.
+func stdinIsTerminal() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stderr} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// confirmOnTerminal asks question on stderr and reports whether the answer read from stdin is yes.
+func confirmOnTerminal(question string) bool {
+	fmt.Fprint(os.Stderr, question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// proceedOnTerminal shows prompt on stderr and waits for a line on stdin,
+// reporting false when it is q or stdin ends.
+func proceedOnTerminal(prompt string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return err == nil && answer != "q" && answer != "quit"
+}
@@ -0,0 +1,131 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestTopK(t *testing.T) {
+	top := newTopK(2)
+	top.add("a", 5)
+	top.add("b", 3)
+	top.add("a", 1)
+	assert.Equal(t, []entry{{"a", 6}, {"b", 3}}, top.top())
+
+	// A newcomer evicts the smallest counter and inherits its count
+	top.add("c", 10)
+	assert.Equal(t, []entry{{"c", 13}, {"a", 6}}, top.top())
+	assert.Len(t, top.counts, 2)
+}
+
+// longRunTree generates a tree where third_party dominates the selected bytes.
+func longRunTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(rel string, size int) {
+		path := filepath.Join(dir, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o600))
+	}
+	for i := 0; i < 4; i++ {
+		write(filepath.Join("third_party", "lib", string(rune('a'+i))+".dat"), 2048)
+	}
+	write(filepath.Join("src", "main.dat"), 100)
+	write("README.dat", 10)
+	return dir
+}
+
+// withPrompt fakes an interactive session whose user answers every prompt with answer.
+func withPrompt(t *testing.T, answer bool) *[]string {
+	t.Helper()
+	var asked []string
+	original := hostEnv
+	hostEnv.interactive = func() bool { return true }
+	hostEnv.confirm = func(question string) bool {
+		asked = append(asked, question)
+		return answer
+	}
+	t.Cleanup(func() { hostEnv = original })
+	return &asked
+}
+
+func longRunNotices(hook *logtest.Hook) []string {
+	var notices []string
+	for _, e := range hook.AllEntries() {
+		if strings.HasPrefix(e.Message, "This run is getting large") {
+			notices = append(notices, e.Message)
+		}
+	}
+	return notices
+}
+
+func TestLongRunNoticeAfterFilesScanned(t *testing.T) {
+	dir := longRunTree(t)
+	asked := withPrompt(t, false)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	summary, err := Generate(context.Background(), config.Config{Paths: []string{dir}, LongRunFiles: 5}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 6, summary.Files)
+
+	notices := longRunNotices(hook)
+	require.Len(t, notices, 1)
+	assert.Equal(t, "This run is getting large: 5 files scanned, 6.1 KiB selected, 0 B emitted, 0s elapsed. Largest directories so far:\n"+
+		"  6.0 KiB    "+filepath.Join(dir, "third_party")+"  (--ignore 'third_party/')\n"+
+		"  100 B      "+filepath.Join(dir, "src")+"  (--ignore 'src/')", notices[0])
+	assert.Equal(t, []string{"Abort this run? [y/N] "}, *asked)
+}
+
+func TestLongRunNoticeAfterBytesEmitted(t *testing.T) {
+	dir := longRunTree(t)
+	withPrompt(t, false)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{dir}, LongRunBytes: 3000}, &buf, nil)
+	require.NoError(t, err)
+
+	notices := longRunNotices(hook)
+	require.Len(t, notices, 1)
+	assert.Contains(t, notices[0], "6 files scanned, 8.1 KiB selected, 4.4 KiB emitted")
+	assert.Contains(t, notices[0], "(--ignore 'third_party/')")
+	assert.Contains(t, notices[0], "(--ignore 'src/')")
+}
+
+func TestLongRunAbort(t *testing.T) {
+	dir := longRunTree(t)
+	withPrompt(t, true)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{dir}, LongRunFiles: 2}, &buf, nil)
+	assert.ErrorIs(t, err, ErrAborted)
+	assert.Empty(t, buf.String())
+}
+
+func TestLongRunNonInteractiveNeverPrompts(t *testing.T) {
+	dir := longRunTree(t)
+	asked := withPrompt(t, true)
+	hostEnv.interactive = func() bool { return false }
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{dir}, LongRunFiles: 1}, &buf, nil)
+	require.NoError(t, err)
+	assert.Len(t, longRunNotices(hook), 1)
+	assert.Empty(t, *asked)
+}
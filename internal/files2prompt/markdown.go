@@ -0,0 +1,102 @@
+package files2prompt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// MarkdownStyle selects how Markdown output labels each document.
+type MarkdownStyle string
+
+// Styles accepted by --markdown-style.
+const (
+	// MarkdownHeadings labels each document with a heading, the default:
+	// "## PATH", a blank line and the fenced block, titled "# Files" when
+	// there are several.
+	MarkdownHeadings MarkdownStyle = "headings"
+	// MarkdownPath puts the bare path on the line before the fenced block, as
+	// files2prompt did before headings.
+	MarkdownPath MarkdownStyle = "path"
+)
+
+// defaultHeadingLevel is the level of document headings when
+// --markdown-heading-level is not given.
+const defaultHeadingLevel = 2
+
+// markdownStyle returns the style selected by config, defaulting to
+// MarkdownHeadings, and checks the heading level it is written at.
+func markdownStyle(config config.Config) (MarkdownStyle, error) {
+	if level := config.MarkdownHeadingLevel; level < 0 || level > 6 {
+		return "", fmt.Errorf("invalid --markdown-heading-level %d: use 1 to 6", level)
+	}
+	switch style := MarkdownStyle(config.MarkdownStyle); style {
+	case "", MarkdownHeadings:
+		return MarkdownHeadings, nil
+	case MarkdownPath:
+		return MarkdownPath, nil
+	default:
+		return "", fmt.Errorf("invalid --markdown-style %q: use %s or %s", style, MarkdownHeadings, MarkdownPath)
+	}
+}
+
+// headingLevel returns the level of the headings of documents and sections.
+func headingLevel(config config.Config) int {
+	if config.MarkdownHeadingLevel == 0 {
+		return defaultHeadingLevel
+	}
+	return config.MarkdownHeadingLevel
+}
+
+// markdownHeading returns a heading of title at level, followed by a blank line.
+func markdownHeading(level int, title string) string {
+	return strings.Repeat("#", level) + " " + title + "\n\n"
+}
+
+// markdownSection returns the heading of a section, such as the omissions,
+// which is a heading in either style.
+func markdownSection(config config.Config, title string) string {
+	return markdownHeading(headingLevel(config), title)
+}
+
+// markdownLabel returns what goes before the fence of the document labelled
+// label: its heading, or with MarkdownPath the label on a line of its own.
+func markdownLabel(config config.Config, label string) string {
+	if style, _ := markdownStyle(config); style == MarkdownPath {
+		return label + "\n"
+	}
+	return markdownSection(config, label)
+}
+
+// markdownEnd returns what follows the closing fence of a document: the blank
+// line before the next heading, or nothing with MarkdownPath.
+func markdownEnd(config config.Config) string {
+	if style, _ := markdownStyle(config); style == MarkdownPath {
+		return ""
+	}
+	return "\n"
+}
+
+// markdownTitle returns the "# Files" title of Markdown output in headings
+// style with several documents, one level above theirs, or "" when there is
+// none: with one document, or with headings at level 1.
+func markdownTitle(config config.Config, plan []PlannedFile) string {
+	if style, _ := markdownStyle(config); style != MarkdownHeadings || headingLevel(config) == 1 {
+		return ""
+	}
+	// Standard input counts as one of them
+	files := 0
+	if readsStdin(config) {
+		files++
+	}
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			files++
+		}
+	}
+	if files < 2 {
+		return ""
+	}
+	return markdownHeading(headingLevel(config)-1, "Files")
+}
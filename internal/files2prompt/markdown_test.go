@@ -0,0 +1,94 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestMarkdownHeadings(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.go": "package a\n", "b.txt": "bee"})
+	t.Chdir(dir)
+
+	both := []string{"a.go", "b.txt"}
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:   "headings",
+			config: config.Config{Paths: both},
+			expected: "# Files\n\n## a.go\n\n```go\npackage a\n```\n\n" +
+				"## b.txt\n\n```\nbee\n```\n\n",
+		},
+		{
+			name:     "one file has no title",
+			config:   config.Config{Paths: []string{"a.go"}},
+			expected: "## a.go\n\n```go\npackage a\n```\n\n",
+		},
+		{
+			name:   "level 3",
+			config: config.Config{Paths: both, MarkdownHeadingLevel: 3},
+			expected: "## Files\n\n### a.go\n\n```go\npackage a\n```\n\n" +
+				"### b.txt\n\n```\nbee\n```\n\n",
+		},
+		{
+			name:   "level 1 has no title",
+			config: config.Config{Paths: both, MarkdownHeadingLevel: 1},
+			expected: "# a.go\n\n```go\npackage a\n```\n\n" +
+				"# b.txt\n\n```\nbee\n```\n\n",
+		},
+		{
+			name:     "path style",
+			config:   config.Config{Paths: both, MarkdownStyle: string(MarkdownPath), MarkdownHeadingLevel: 3},
+			expected: "a.go\n```go\npackage a\n```\nb.txt\n```\nbee\n```\n",
+		},
+		{
+			name:   "sections take the level",
+			config: config.Config{Paths: []string{"a.go"}, Tree: true, MarkdownHeadingLevel: 4},
+			expected: "#### directory-tree\n\n```\na.go\n```\n\n" +
+				"#### a.go\n\n```go\npackage a\n```\n\n",
+		},
+	}
+	for _, tt := range tests {
+		for _, threshold := range []int64{1 << 20, 1} {
+			t.Run(tt.name, func(t *testing.T) {
+				// Streamed documents are labelled the same
+				withStreamThreshold(t, threshold)
+				cfg := tt.config
+				cfg.Markdown = true
+				var buf bytes.Buffer
+				_, err := Generate(context.Background(), cfg, &buf, nil)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, buf.String())
+			})
+		}
+	}
+}
+
+func TestMarkdownStyleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"unknown style", config.Config{MarkdownStyle: "bold"}, `invalid --markdown-style "bold": use headings or path`},
+		{"level too deep", config.Config{MarkdownHeadingLevel: 7}, "invalid --markdown-heading-level 7: use 1 to 6"},
+		{"negative level", config.Config{MarkdownHeadingLevel: -1}, "invalid --markdown-heading-level -1: use 1 to 6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths, cfg.Markdown = []string{"testdata/file1.txt"}, true
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package files2prompt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// MatchResult is the decision Match made for one path.
+type MatchResult struct {
+	// Path is the path as given to Match.
+	Path string
+	// Included reports whether a walk would include the path.
+	Included bool
+	// Reason explains why the path was excluded; it is empty for included paths.
+	Reason SkipReason
+	// PrunedBy is the enclosing directory whose exclusion kept the walk from
+	// reaching Path, or "" when Path itself was excluded.
+	PrunedBy string
+}
+
+// Match decides, for each of paths, whether a walk of root with config would
+// include it, without walking anything else or reading any file's content. It
+// runs the filter pipeline of Plan over the directories from root down to each
+// path, as the walk would reach them, so that the two always agree. --grep
+// and the generated-code header of Go files, which need the content, and
+// --fit-tokens and --max-tokens, which depend on the other files, do not apply.
+func Match(config config.Config, root string, paths []string) ([]MatchResult, error) {
+	if _, err := submoduleMode(config); err != nil {
+		return nil, err
+	}
+	if _, err := compatMode(config); err != nil {
+		return nil, err
+	}
+	if err := patternOptions(config); err != nil {
+		return nil, err
+	}
+	if _, err := maxDepth(config); err != nil {
+		return nil, err
+	}
+	if _, err := modTimes(config, time.Now()); err != nil {
+		return nil, err
+	}
+	jail, err := newJail(config.Jail)
+	if err != nil {
+		return nil, err
+	}
+	if err := jail.check(root); err != nil {
+		return nil, err
+	}
+	rootPath, err := walkRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	abs := absPath(rootPath)
+	start := candidate{path: rootPath, info: info, origin: OriginArg, abs: abs, rel: newMatchBases([]string{abs}, jail).rel(abs)}
+	arg := pathArg{path: root, origin: OriginArg, root: root}
+	rules := argGitignoreRules([]pathArg{arg}, config, jail)
+	tracked, err := loadTrackedFiles([]pathArg{arg}, config)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]MatchResult, 0, len(paths))
+	for _, path := range paths {
+		result, err := matchPath(config, rules, jail, tracked, root, start, path)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// matchPath decides whether the walk of root, which starts at the candidate
+// start, includes path.
+func matchPath(config config.Config, rules []gitignoreRule, jail *jail, tracked *trackedFiles, root string, start candidate, path string) (MatchResult, error) {
+	result := MatchResult{Path: path}
+	within, err := filepath.Rel(start.abs, absPath(path))
+	if err != nil || within == ".." || strings.HasPrefix(within, ".."+string(filepath.Separator)) {
+		return result, fmt.Errorf("%s is not beneath %s", path, root)
+	}
+	var parts []string
+	if within != "." {
+		parts = strings.Split(within, string(filepath.Separator))
+	}
+
+	pipeline := newFilterPipeline(config, rules, nil)
+	pipeline.jail, pipeline.tracked, pipeline.noContent = jail, tracked, true
+	pipeline.submodules = newSubmoduleTracker(start.path, jail)
+	if result.Reason = pipeline.decide(start); result.Reason != "" {
+		if len(parts) > 0 {
+			result.PrunedBy = root
+		}
+		return result, nil
+	}
+	c := start
+	for i, part := range parts {
+		if !c.info.IsDir() {
+			return result, fmt.Errorf("%s is not beneath %s: %s is not a directory the walk descends into", path, root, c.path)
+		}
+		rel := filepath.Join(parts[:i+1]...)
+		c = candidate{path: filepath.Join(c.path, part), origin: OriginWalk, abs: filepath.Join(start.abs, rel), rel: rel}
+		// The walk follows links only with --follow-symlinks, and so does the match
+		if c.info, err = os.Lstat(c.path); err != nil {
+			return result, err
+		}
+		if config.FollowSymlinks {
+			c.info = followLink(c.path, c.info)
+		}
+		if result.Reason = pipeline.decide(c); result.Reason != "" {
+			if i < len(parts)-1 {
+				result.PrunedBy = filepath.Join(root, rel)
+			}
+			return result, nil
+		}
+	}
+	result.Included = true
+	return result, nil
+}
+
+// WriteMatchReport writes one line per result: "include PATH", or "exclude
+// PATH: REASON" naming the directory that pruned it, if any.
+func WriteMatchReport(w io.Writer, results []MatchResult) error {
+	for _, r := range results {
+		var err error
+		switch {
+		case r.Included:
+			_, err = fmt.Fprintf(w, "include %s\n", r.Path)
+		case r.PrunedBy != "":
+			_, err = fmt.Fprintf(w, "exclude %s: %s (directory %s)\n", r.Path, r.Reason, r.PrunedBy)
+		default:
+			_, err = fmt.Fprintf(w, "exclude %s: %s\n", r.Path, r.Reason)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
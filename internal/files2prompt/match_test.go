@@ -0,0 +1,171 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+var matchFiles = map[string]string{
+	".gitignore":                 "*.log\nbuild/\n",
+	"src/main.go":                "package main\n",
+	"src/main_test.go":           "package main\n",
+	"src/debug.log":              "log\n",
+	"src/.gitignore":             "generated.go\n",
+	"src/generated.go":           "package main\n",
+	"build/out.go":               "package out\n",
+	"node_modules/pkg/index.js":  "module.exports = {}\n",
+	".hidden/notes.md":           "notes\n",
+	".env":                       "TOKEN=x\n",
+	"docs/guide.md":              "# Guide\n",
+	"docs/big.md":                strings.Repeat("x", 2048),
+	"vendor/lib/lib.go":          "package lib\n",
+	"third_party/.git/HEAD":      "ref: refs/heads/main\n",
+	"third_party/lib/private.go": "package lib\n",
+}
+
+// matchAgainstPlan returns what Match decides for every file in matchFiles,
+// and what the walk of Plan decided, for the file or the directory it pruned.
+func matchAgainstPlan(t *testing.T, cfg config.Config) (matched, planned map[string]MatchResult) {
+	t.Helper()
+	cfg.Paths = []string{"."}
+	plan, err := Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+	wd, err := filepath.Abs(".")
+	require.NoError(t, err)
+	decisions := map[string]PlannedFile{}
+	for _, f := range plan {
+		rel, err := filepath.Rel(wd, f.Path)
+		require.NoError(t, err)
+		decisions[filepath.ToSlash(rel)] = f
+	}
+
+	var paths []string
+	for path := range matchFiles {
+		paths = append(paths, filepath.FromSlash(path))
+	}
+	results, err := Match(cfg, ".", paths)
+	require.NoError(t, err)
+
+	matched, planned = map[string]MatchResult{}, map[string]MatchResult{}
+	for _, r := range results {
+		path := filepath.ToSlash(r.Path)
+		matched[path] = r
+		if f, ok := decisions[path]; ok {
+			planned[path] = MatchResult{Path: r.Path, Included: f.Included, Reason: f.Reason}
+			continue
+		}
+		// Not reached by the walk, so one of its directories was pruned
+		for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+			if f, ok := decisions[dir]; ok && f.IsDir {
+				planned[path] = MatchResult{Path: r.Path, Reason: f.Reason, PrunedBy: filepath.FromSlash(dir)}
+			}
+		}
+	}
+	return matched, planned
+}
+
+func TestMatchAgreesWithPlan(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, matchFiles)
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		excluded map[string]SkipReason
+	}{
+		{
+			name:   "defaults",
+			config: config.Config{},
+			excluded: map[string]SkipReason{
+				".gitignore":                SkipHidden,
+				"src/.gitignore":            SkipHidden,
+				"build/out.go":              SkipDefaults,
+				"node_modules/pkg/index.js": SkipDefaults,
+				".hidden/notes.md":          SkipHidden,
+				".env":                      SkipHidden,
+				"vendor/lib/lib.go":         SkipDefaults,
+				"third_party/.git/HEAD":     SkipVCS,
+			},
+		},
+		{
+			name: "filters",
+			config: config.Config{
+				Extensions: []string{"go", "md"}, IgnorePatterns: []string{"*_test.go", "third_party/"},
+				IncludeHidden: true, IgnoreGitignore: true, DisableDefaultIgnores: true, MaxFileSize: 1024,
+			},
+			excluded: map[string]SkipReason{
+				".gitignore":                 SkipExtension,
+				"src/.gitignore":             SkipExtension,
+				"src/main_test.go":           SkipIgnore,
+				"src/debug.log":              SkipGitignore,
+				"src/generated.go":           SkipGitignore,
+				"build/out.go":               SkipGitignore,
+				"node_modules/pkg/index.js":  SkipExtension,
+				".env":                       SkipSensitive,
+				"docs/big.md":                SkipMaxSize,
+				"third_party/.git/HEAD":      SkipIgnore,
+				"third_party/lib/private.go": SkipIgnore,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, planned := matchAgainstPlan(t, tt.config)
+			assert.Equal(t, planned, matched)
+			for path, r := range matched {
+				if reason, ok := tt.excluded[path]; ok {
+					assert.False(t, r.Included, path)
+					assert.Equal(t, reason, r.Reason, path)
+				} else {
+					assert.True(t, r.Included, path)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchPrunedBy(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, matchFiles)
+	t.Chdir(dir)
+
+	cfg := config.Config{IgnoreGitignore: true}
+	results, err := Match(cfg, ".", []string{"src/main.go", "build/out.go", "node_modules/pkg/index.js", "build"})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, WriteMatchReport(&buf, results))
+	assert.Equal(t, "include src/main.go\n"+
+		"exclude build/out.go: .gitignore rules (directory build)\n"+
+		"exclude node_modules/pkg/index.js: default ignores (directory node_modules)\n"+
+		"exclude build: .gitignore rules\n", buf.String())
+
+	// A root other than the working directory
+	results, err = Match(cfg, "src", []string{"src/main.go", "src/generated.go"})
+	require.NoError(t, err)
+	assert.True(t, results[0].Included)
+	assert.Equal(t, SkipGitignore, results[1].Reason)
+}
+
+func TestMatchErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, matchFiles)
+	t.Chdir(dir)
+
+	_, err := Match(config.Config{}, "src", []string{"docs/guide.md"})
+	assert.EqualError(t, err, "docs/guide.md is not beneath src")
+	_, err = Match(config.Config{}, ".", []string{"src/missing.go"})
+	assert.ErrorContains(t, err, "no such file or directory")
+	_, err = Match(config.Config{}, "src/main.go", []string{"src/main.go"})
+	assert.EqualError(t, err, "src/main.go is not a directory")
+	_, err = Match(config.Config{}, ".", []string{"src/main.go/x"})
+	assert.EqualError(t, err, "src/main.go/x is not beneath .: "+filepath.Join(dir, "src", "main.go")+" is not a directory the walk descends into")
+}
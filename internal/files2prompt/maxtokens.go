@@ -0,0 +1,65 @@
+package files2prompt
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// tokenLimit is the --max-tokens cap on a run's output. Unlike --fit-tokens,
+// which estimates from file sizes while planning, it counts what the ledger
+// meters as each document is written. A nil *tokenLimit caps nothing.
+type tokenLimit struct {
+	budget int64
+	strict bool
+	// omitted lists the files left out once the budget was spent
+	omitted []string
+}
+
+func newTokenLimit(config config.Config) *tokenLimit {
+	if config.MaxTokens <= 0 {
+		return nil
+	}
+	return &tokenLimit{budget: config.MaxTokens, strict: config.Strict}
+}
+
+// spent reports whether a file has been left out, after which every later
+// file is too, so that the output stays a prefix of the plan.
+func (l *tokenLimit) spent() bool {
+	return l != nil && len(l.omitted) > 0
+}
+
+// exceeded reports whether used tokens are over the budget.
+func (l *tokenLimit) exceeded(used int64) bool {
+	return l != nil && used > l.budget
+}
+
+// overflow leaves out f, whose document would have taken the output to used
+// tokens, or fails with --strict.
+func (l *tokenLimit) overflow(f *PlannedFile, used int64) error {
+	if l.strict {
+		return fmt.Errorf("%s would take the output to %d tokens, over the %d-token --max-tokens budget",
+			f.DisplayPath, used, l.budget)
+	}
+	l.omit(f)
+	return nil
+}
+
+// omit leaves f out of the output.
+func (l *tokenLimit) omit(f *PlannedFile) {
+	f.Included, f.Reason = false, SkipMaxTokens
+	l.omitted = append(l.omitted, f.DisplayPath)
+}
+
+// warn lists the files left out on stderr.
+func (l *tokenLimit) warn() {
+	if !l.spent() {
+		return
+	}
+	n := len(l.omitted)
+	log.Warnf("Stopped adding files at the %d-token --max-tokens budget; %d %s omitted:\n  %s",
+		l.budget, n, plural(n, "file was", "files were"), strings.Join(l.omitted, "\n  "))
+}
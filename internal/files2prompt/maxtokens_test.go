@@ -0,0 +1,162 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// The plain documents of testdata/test_project cost 15, 14, 18 and 14
+// estimated tokens, in path order, so that the output after each is 15, 29, 47
+// and 61 tokens.
+var maxTokensFiles = []string{
+	"testdata/test_project/docs/README.txt",
+	"testdata/test_project/script.py",
+	"testdata/test_project/src/main.go",
+	"testdata/test_project/temp/file.txt",
+}
+
+func TestMaxTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		stream bool
+		kept   []string
+	}{
+		{name: "no limit", config: config.Config{}, kept: maxTokensFiles},
+		{name: "everything fits", config: config.Config{MaxTokens: 61}, kept: maxTokensFiles},
+		{name: "nothing fits", config: config.Config{MaxTokens: 14}},
+		{name: "exactly one", config: config.Config{MaxTokens: 15}, kept: maxTokensFiles[:1]},
+		{name: "one short of two", config: config.Config{MaxTokens: 28}, kept: maxTokensFiles[:1]},
+		{
+			// temp/file.txt would still fit after main.go is left out, but the output stays a prefix
+			name:   "a prefix",
+			config: config.Config{MaxTokens: 46},
+			kept:   maxTokensFiles[:2],
+		},
+		{name: "streamed", config: config.Config{MaxTokens: 46}, stream: true, kept: maxTokensFiles[:2]},
+		{name: "streamed with bpe", config: config.Config{MaxTokens: 46, CountTokens: true}, stream: true, kept: maxTokensFiles[:2]},
+		{name: "streamed claude xml", config: config.Config{MaxTokens: 68, ClaudeXML: true}, stream: true, kept: maxTokensFiles[:1]},
+		{
+			name:   "smallest first",
+			config: config.Config{MaxTokens: 46, SmallFirst: true},
+			kept:   []string{"testdata/test_project/temp/file.txt", "testdata/test_project/docs/README.txt", "testdata/test_project/script.py"},
+		},
+		{
+			// The <documents> wrapper counts, and each document costs more
+			name:   "claude xml",
+			config: config.Config{MaxTokens: 68, ClaudeXML: true},
+			kept:   maxTokensFiles[:1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"testdata/test_project"}
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			var held bytes.Buffer
+			if tt.stream {
+				_, err := Generate(context.Background(), cfg, &held, plan)
+				require.NoError(t, err)
+				withStreamThreshold(t, 1)
+				for doc, err := range Documents(context.Background(), cfg, plan) {
+					require.NoError(t, err)
+					assert.True(t, doc.Streamed, doc.File.DisplayPath)
+				}
+			}
+			hook := logtest.NewGlobal()
+			defer hook.Reset()
+
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &buf, plan)
+			require.NoError(t, err)
+			if tt.stream {
+				// Streamed documents are measured before they are written, to
+				// the same output as when they are held back
+				assert.Equal(t, held.String(), buf.String())
+			}
+
+			assert.Equal(t, len(tt.kept), summary.Files)
+			if cfg.MaxTokens > 0 {
+				assert.LessOrEqual(t, summary.Tokens, cfg.MaxTokens)
+			}
+			var omitted []string
+			for _, path := range maxTokensFiles {
+				if slices.Contains(tt.kept, path) {
+					assert.Contains(t, buf.String(), path)
+				} else {
+					assert.NotContains(t, buf.String(), path)
+					omitted = append(omitted, path)
+				}
+			}
+			// The caller's plan still includes every file
+			assert.Len(t, includedPaths(t, ".", plan), len(maxTokensFiles))
+
+			// A path argument left with no documents is diagnosed on its own
+			var warnings []string
+			for _, e := range hook.AllEntries() {
+				if e.Level == log.WarnLevel && strings.HasPrefix(e.Message, "Stopped adding files") {
+					warnings = append(warnings, e.Message)
+				}
+			}
+			if len(omitted) == 0 {
+				assert.Empty(t, warnings)
+				return
+			}
+			require.Len(t, warnings, 1)
+			for _, path := range omitted {
+				assert.Contains(t, warnings[0], "\n  "+path)
+			}
+		})
+	}
+}
+
+func TestMaxTokensStrict(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{
+		Paths: []string{"testdata/test_project"}, MaxTokens: 46, Strict: true,
+	}, &buf, nil)
+	assert.EqualError(t, err, "testdata/test_project/src/main.go would take the output to 47 tokens, over the 46-token --max-tokens budget")
+	assert.NotContains(t, buf.String(), "main.go", "the document that does not fit is never written")
+
+	// --strict alone changes nothing
+	buf.Reset()
+	summary, err := Generate(context.Background(), config.Config{Paths: []string{"testdata/test_project"}, Strict: true}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, len(maxTokensFiles), summary.Files)
+}
+
+func TestMaxTokensWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{
+		Paths: []string{"testdata/test_project"}, MaxTokens: 28, EmbedWarnings: true,
+	}, &buf, nil)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "3 files were left out once the output reached the 28-token --max-tokens budget: "+
+		"testdata/test_project/script.py, testdata/test_project/src/main.go, testdata/test_project/temp/file.txt.")
+}
+
+func TestMaxTokensDiagnostic(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "alpha\n"})
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"testdata/test_project", dir}, MaxTokens: 61}, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+	var messages []string
+	for _, e := range hook.AllEntries() {
+		messages = append(messages, e.Message)
+	}
+	assert.Contains(t, messages, dir+": 1 file excluded by max tokens [61 tokens]")
+}
@@ -0,0 +1,79 @@
+package files2prompt
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// fileMeta is what --metadata tells of the file behind a document.
+type fileMeta struct {
+	size  int64
+	lines int64
+	// modTime is zero when unknown
+	modTime time.Time
+	mode    os.FileMode
+	// provenance is shown in structured output only
+	provenance Provenance
+}
+
+// newFileMeta returns the metadata of the planned file f read as read, or nil
+// without --metadata. With --reproducible the modification time is the pinned
+// timestamp, as every other time the output embeds.
+func newFileMeta(f PlannedFile, read fileRead, config config.Config, state *emitState) *fileMeta {
+	if !config.IncludeMetadata {
+		return nil
+	}
+	m := &fileMeta{size: int64(len(read.content)), lines: countLines(read.content), modTime: f.ModTime, mode: f.Mode, provenance: f.Provenance}
+	if read.scan != nil {
+		m.size, m.lines = read.scan.size, read.scan.lines()
+	}
+	if config.Reproducible {
+		m.modTime = state.timestamp
+		if !m.provenance.Retrieved.IsZero() {
+			m.provenance.Retrieved = state.timestamp
+		}
+	}
+	return m
+}
+
+// metadataLine returns the comment a plain or Markdown document carries under
+// its path line with --metadata, such as "<!-- 1.2 KiB, 85 lines, modified
+// 2024-08-01, mode 0644 -->", or "".
+func metadataLine(state *emitState) string {
+	if state.meta == nil {
+		return ""
+	}
+	return "<!-- " + metadataText(state.meta) + " -->\n"
+}
+
+// metadataText returns the text of m, such as "1.2 KiB, 85 lines, modified
+// 2024-08-01, mode 0644".
+func metadataText(m *fileMeta) string {
+	text := fmt.Sprintf("%s, %d %s", formatBytes(m.size), m.lines, plural(int(m.lines), "line", "lines"))
+	if !m.modTime.IsZero() {
+		text += ", modified " + m.modTime.UTC().Format(time.DateOnly)
+	}
+	return text + fmt.Sprintf(", mode %04o", m.mode.Perm())
+}
+
+// metadataAttrs returns the attributes a Claude XML document carries with
+// --metadata, or "". They are named apart from the lines and bytes attributes
+// of --header-stats, which count only what the document shows.
+func metadataAttrs(state *emitState) string {
+	m := state.meta
+	if m == nil {
+		return ""
+	}
+	attrs := fmt.Sprintf(" file_size=\"%d\" file_lines=\"%d\"", m.size, m.lines)
+	if !m.modTime.IsZero() {
+		attrs += fmt.Sprintf(" mtime=\"%s\"", m.modTime.UTC().Format(time.RFC3339))
+	}
+	attrs += fmt.Sprintf(" mode=\"%04o\" provenance=\"%s\"", m.mode.Perm(), escapeXML(m.provenance.String()))
+	if retrieved := m.provenance.Retrieved; !retrieved.IsZero() {
+		attrs += fmt.Sprintf(" retrieved=\"%s\"", retrieved.UTC().Format(time.RFC3339))
+	}
+	return attrs
+}
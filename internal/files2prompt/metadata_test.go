@@ -0,0 +1,80 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestMetadata(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {}"), 0o600))
+	require.NoError(t, os.Chmod(path, 0o640))
+	modified := time.Date(2024, 8, 1, 12, 34, 56, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "plain",
+			config:   config.Config{},
+			expected: path + "\n<!-- 28 B, 3 lines, modified 2024-08-01, mode 0640 -->\n---\npackage main\n\nfunc main() {}\n---\n\n",
+		},
+		{
+			name:     "markdown",
+			config:   config.Config{Markdown: true},
+			expected: "## " + path + "\n\n<!-- 28 B, 3 lines, modified 2024-08-01, mode 0640 -->\n```go\npackage main\n\nfunc main() {}\n```\n\n",
+		},
+		{
+			name:   "cxml",
+			config: config.Config{ClaudeXML: true},
+			expected: "<documents>\n<document index=\"1\" file_size=\"28\" file_lines=\"3\" mtime=\"2024-08-01T12:34:56Z\" mode=\"0640\" provenance=\"local\">\n" +
+				"<source>" + path + "</source>\n<document_content>\npackage main\n\nfunc main() {}\n</document_content>\n</document>\n</documents>\n",
+		},
+		{
+			name:   "cxml with header stats of the lines shown",
+			config: config.Config{ClaudeXML: true, HeaderStats: true, HeadLines: 1},
+			expected: "<documents>\n<document index=\"1\" lines=\"1\" bytes=\"13\" file_size=\"28\" file_lines=\"3\" mtime=\"2024-08-01T12:34:56Z\" mode=\"0640\" provenance=\"local\">\n" +
+				"<source>" + path + "</source>\n<document_content>\npackage main\n... [2 lines truncated] ...\n</document_content>\n</document>\n</documents>\n",
+		},
+	}
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			name := tt.name
+			if stream {
+				name += " streamed"
+			}
+			t.Run(name, func(t *testing.T) {
+				if stream {
+					withStreamThreshold(t, 1)
+				}
+				cfg := tt.config
+				cfg.Paths, cfg.IncludeMetadata = []string{path}, true
+				var buf bytes.Buffer
+				_, err := Generate(context.Background(), cfg, &buf, nil)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, buf.String())
+			})
+		}
+	}
+
+	t.Run("reproducible", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), config.Config{Paths: []string{path}, IncludeMetadata: true, Reproducible: true}, &buf, nil)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "<!-- 28 B, 3 lines, modified 2023-11-14, mode 0640 -->\n")
+	})
+}
@@ -0,0 +1,180 @@
+package files2prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// mirror copies the content emitted for each file into a directory, as
+// --mirror-to does. A nil *mirror copies nothing.
+type mirror struct {
+	// dir is the absolute mirror directory, with symlinks resolved
+	dir   string
+	force bool
+	// written maps each copy made to the file it was made from
+	written map[string]string
+	// renames maps the mirror paths that differ only in case from one before
+	// them to the paths they are copied to instead, and renamed records the
+	// copies made there
+	renames, renamed map[string]string
+}
+
+// mirrorRenamesFile is the manifest written into the mirror directory when
+// files were copied under other names, mapping each copy's path to the one
+// it would have had.
+const mirrorRenamesFile = ".files2prompt-renames.json"
+
+// newMirror creates the --mirror-to directory of config, if any, for the
+// files of plan. Files whose copies would differ only in case are copied
+// under names suffixed as caseRenames does, so that the mirror unpacks the
+// same on case-insensitive file systems.
+func newMirror(config config.Config, plan []PlannedFile) (*mirror, error) {
+	if config.MirrorTo == "" {
+		if config.MirrorOnly {
+			return nil, errors.New("--mirror-only requires --mirror-to")
+		}
+		return nil, nil
+	}
+	dir, err := hostEnv.expandTilde(config.MirrorTo)
+	if err != nil {
+		return nil, err
+	}
+	jail, err := newJail(config.Jail)
+	if err != nil {
+		return nil, err
+	}
+	if err := jail.check(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create --mirror-to directory: %v", err)
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, fmt.Errorf("failed to resolve --mirror-to directory: %v", err)
+	}
+	var paths []string
+	for _, f := range plan {
+		if rel, err := mirrorPath(f); err == nil && f.Included && !f.IsDir {
+			paths = append(paths, rel)
+		}
+	}
+	return &mirror{
+		dir:     absPath(dir),
+		force:   config.Force,
+		written: map[string]string{},
+		renames: caseRenames(paths),
+		renamed: map[string]string{},
+	}, nil
+}
+
+// mirrorPath returns where f is copied to beneath the mirror directory: its
+// path relative to the working directory when it lies beneath it, and
+// otherwise its path beneath the path argument it was found under, prefixed
+// with that argument's name.
+func mirrorPath(f PlannedFile) (string, error) {
+	abs := absPath(f.Path)
+	if wd, err := os.Getwd(); err == nil && abs != wd && withinDir(abs, wd) {
+		return filepath.Rel(wd, abs)
+	}
+	root := absPath(f.Root)
+	if abs == root {
+		return filepath.Base(abs), nil
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Base(root), rel), nil
+}
+
+// write copies the content of doc into the mirror, straight from the file
+// when it was streamed. It refuses to write anywhere outside the mirror
+// directory, including through symlinks found in it, and to replace an
+// existing file unless --force was given.
+func (m *mirror) write(doc FileDoc) error {
+	if m == nil {
+		return nil
+	}
+	f := doc.File
+	rel, err := mirrorPath(f)
+	if err != nil {
+		return fmt.Errorf("cannot mirror %s: %v", f.Path, err)
+	}
+	if renamed, ok := m.renames[rel]; ok {
+		log.Infof("Mirroring %s as %s: its copy differs from another only in case", f.Path, renamed)
+		m.renamed[filepath.ToSlash(renamed)] = filepath.ToSlash(rel)
+		rel = renamed
+	}
+	var content io.Reader = strings.NewReader(doc.Content)
+	if doc.Streamed {
+		src, err := os.Open(f.Path) // #nosec G304
+		if err != nil {
+			return fmt.Errorf("failed to mirror %s: %v", f.Path, err)
+		}
+		defer src.Close()
+		content = src
+	}
+	return m.copy(rel, f.Path, content)
+}
+
+// close writes the mirrorRenamesFile manifest when files were copied under
+// other names.
+func (m *mirror) close() error {
+	if m == nil || len(m.renamed) == 0 {
+		return nil
+	}
+	manifest, err := json.MarshalIndent(m.renamed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return m.copy(mirrorRenamesFile, "the renames manifest", strings.NewReader(string(manifest)+"\n"))
+}
+
+// copy writes content, made from source, to rel beneath the mirror
+// directory, refusing to write outside it or to replace what write would not.
+func (m *mirror) copy(rel, source string, content io.Reader) error {
+	dest := filepath.Join(m.dir, rel)
+	if dest == m.dir || !withinDir(dest, m.dir) {
+		return fmt.Errorf("refusing to mirror %s outside %s", source, m.dir)
+	}
+	if previous, ok := m.written[dest]; ok {
+		return fmt.Errorf("cannot mirror both %s and %s to %s", previous, source, dest)
+	}
+	m.written[dest] = source
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o750); err != nil {
+		return fmt.Errorf("failed to mirror %s: %v", source, err)
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(dest))
+	if err != nil {
+		return fmt.Errorf("failed to mirror %s: %v", source, err)
+	}
+	if !withinDir(parent, m.dir) {
+		return fmt.Errorf("refusing to mirror %s to %s, which links outside %s", source, dest, m.dir)
+	}
+	if info, err := os.Lstat(dest); err == nil {
+		if !m.force {
+			return fmt.Errorf("refusing to overwrite %s (use --force)", dest)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("refusing to overwrite %s, which is not a regular file", dest)
+		}
+	}
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to mirror %s: %v", source, err)
+	}
+	if _, err := io.Copy(out, content); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to mirror %s: %v", source, err)
+	}
+	return out.Close()
+}
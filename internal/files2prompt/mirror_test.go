@@ -0,0 +1,179 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// readTree returns the content of every regular file beneath root, keyed by its
+// slash-separated path relative to root.
+func readTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	require.NoError(t, filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	}))
+	return files
+}
+
+func TestMirrorMatchesDocuments(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":        "package main\n",
+		"no-newline.md":  "# no final newline",
+		"data/blob.go":   "package data\n\nvar x = []int{\n1,\n2,\n3,\n4,\n5,\n}\n",
+		"nested/a/b.py":  "print('b')\n",
+		"skipped/.x.py":  "hidden\n",
+		"skipped/log.sh": "echo skipped\n",
+	})
+	t.Chdir(root)
+	mirrorDir := filepath.Join(t.TempDir(), "mirror")
+
+	tests := []struct {
+		name string
+		cfg  config.Config
+	}{
+		{name: "whole files", cfg: config.Config{}},
+		{name: "squashed data", cfg: config.Config{SquashDataBlocks: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Paths = []string{"."}
+			cfg.IgnorePatterns = []string{"skipped/"}
+			cfg.MirrorTo = mirrorDir
+			cfg.Force = true
+			require.NoError(t, os.RemoveAll(mirrorDir))
+
+			var out bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &out, nil)
+			require.NoError(t, err)
+
+			want := map[string]string{}
+			for _, doc := range parsePlain(t, out.String()) {
+				rel, err := filepath.Rel(root, doc.path)
+				require.NoError(t, err)
+				want[filepath.ToSlash(rel)] = doc.content
+			}
+			require.NotEmpty(t, want)
+			got := readTree(t, mirrorDir)
+			// The copy keeps the file without the newline the closing separator needs
+			assert.Equal(t, "# no final newline", got["no-newline.md"])
+			got["no-newline.md"] += "\n"
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+// The gutter --grep-context adds to the document is left out of the copy.
+func TestMirrorGrepContext(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.go": "package a\n\nfunc one() {}\n\nfunc two() {}\n",
+		"b.md": "no final newline",
+	})
+	t.Chdir(root)
+	mirrorDir := t.TempDir()
+
+	cfg := config.Config{Paths: []string{"."}, MirrorTo: mirrorDir, Grep: "two|newline", GrepContext: 0}
+	_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"a.go": "...\nfunc two() {}\n",
+		"b.md": "no final newline",
+	}, readTree(t, mirrorDir))
+}
+
+func TestMirrorOverwrite(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n"})
+	t.Chdir(root)
+	mirrorDir := t.TempDir()
+	writeFiles(t, mirrorDir, map[string]string{"a.go": "stale\n"})
+
+	cfg := config.Config{Paths: []string{"a.go"}, MirrorTo: mirrorDir}
+	_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to overwrite")
+	assert.Contains(t, err.Error(), "use --force")
+	assert.Equal(t, map[string]string{"a.go": "stale\n"}, readTree(t, mirrorDir))
+
+	cfg.Force = true
+	_, err = Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a.go": "package a\n"}, readTree(t, mirrorDir))
+}
+
+func TestMirrorOutsideWorkingDirectory(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"pkg/a.go": "package a\n"})
+	t.Chdir(t.TempDir())
+	mirrorDir := t.TempDir()
+
+	// Files elsewhere are kept beneath the name of their path argument
+	cfg := config.Config{Paths: []string{filepath.Join(src, "pkg")}, MirrorTo: mirrorDir}
+	_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pkg/a.go": "package a\n"}, readTree(t, mirrorDir))
+}
+
+func TestMirrorRefusesSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"pkg/a.go": "package a\n"})
+	t.Chdir(root)
+	outside := t.TempDir()
+	mirrorDir := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(mirrorDir, "pkg")))
+
+	cfg := config.Config{Paths: []string{"pkg"}, MirrorTo: mirrorDir, Force: true}
+	_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "links outside")
+	entries, err := os.ReadDir(outside)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestMirrorOnly(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n"})
+	t.Chdir(root)
+	mirrorDir := t.TempDir()
+
+	stdout := withStdout(t)
+	summary, err := Run(config.Config{Paths: []string{"a.go"}, MirrorTo: mirrorDir, MirrorOnly: true})
+	require.NoError(t, err)
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, 1, summary.Files)
+	assert.Equal(t, map[string]string{"a.go": "package a\n"}, readTree(t, mirrorDir))
+
+	_, err = Run(config.Config{Paths: []string{"a.go"}, MirrorOnly: true})
+	require.EqualError(t, err, "--mirror-only requires --mirror-to")
+
+	_, err = Run(config.Config{Paths: []string{"a.go"}, MirrorTo: mirrorDir, MirrorOnly: true, OutputFile: "out.md"})
+	require.EqualError(t, err, "--mirror-only cannot be combined with --output")
+}
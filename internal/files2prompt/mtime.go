@@ -0,0 +1,118 @@
+package files2prompt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// modTimeWindow is the span of modification times --newer-than and
+// --older-than keep. A zero bound is not checked.
+type modTimeWindow struct {
+	after, before time.Time
+}
+
+// contains reports whether a file modified at t lies within w.
+func (w modTimeWindow) contains(t time.Time) bool {
+	return (w.after.IsZero() || t.After(w.after)) && (w.before.IsZero() || t.Before(w.before))
+}
+
+// modTimes returns the window of --newer-than and --older-than, their ages
+// counted back from now.
+func modTimes(config config.Config, now time.Time) (modTimeWindow, error) {
+	var w modTimeWindow
+	var err error
+	if w.after, err = timeBound("--newer-than", config.NewerThan, now); err != nil {
+		return modTimeWindow{}, err
+	}
+	if w.before, err = timeBound("--older-than", config.OlderThan, now); err != nil {
+		return modTimeWindow{}, err
+	}
+	if !w.after.IsZero() && !w.before.IsZero() && !w.after.Before(w.before) {
+		return modTimeWindow{}, fmt.Errorf("--newer-than %s and --older-than %s leave no time between them", config.NewerThan, config.OlderThan)
+	}
+	return w, nil
+}
+
+// modTimesLabel describes the window of --newer-than and --older-than, e.g.
+// "newer than 7d, older than 2024-06-01".
+func modTimesLabel(config config.Config) string {
+	var bounds []string
+	if config.NewerThan != "" {
+		bounds = append(bounds, "newer than "+config.NewerThan)
+	}
+	if config.OlderThan != "" {
+		bounds = append(bounds, "older than "+config.OlderThan)
+	}
+	return strings.Join(bounds, ", ")
+}
+
+// timeBound parses the value of flag, an age such as "48h" or "7d" or a date
+// such as "2024-06-01", returning the time it stands for. A date is in local
+// time, and may carry a time of day as in RFC 3339.
+func timeBound(flag, value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if age, err := parseAge(value); err == nil {
+		return now.Add(-age), nil
+	}
+	for _, layout := range []string{time.DateOnly, "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q: use an age such as 48h or 7d, or a date such as 2024-06-01", flag, value)
+}
+
+// ageUnits are the units parseAge accepts beyond those of time.ParseDuration.
+var ageUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseAge parses s as time.ParseDuration does, also accepting days and
+// weeks, as in "7d" or "1d12h". An age is never negative.
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	isNumber := func(r rune) bool { return r >= '0' && r <= '9' || r == '.' }
+	var age time.Duration
+	for rest := s; rest != ""; {
+		// Each term is a number and its unit, which runs to the next number
+		unitStart := strings.IndexFunc(rest, func(r rune) bool { return !isNumber(r) })
+		switch unitStart {
+		case 0:
+			return 0, fmt.Errorf("invalid age %q", s)
+		case -1:
+			// Left to time.ParseDuration, which takes a bare 0
+			unitStart = len(rest)
+		}
+		end := strings.IndexFunc(rest[unitStart:], isNumber)
+		if end < 0 {
+			end = len(rest)
+		} else {
+			end += unitStart
+		}
+		term := rest[:end]
+		rest = rest[end:]
+		if unit, ok := ageUnits[term[unitStart:]]; ok {
+			n, err := strconv.ParseFloat(term[:unitStart], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			age += time.Duration(n * float64(unit))
+			continue
+		}
+		d, err := time.ParseDuration(term)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		age += d
+	}
+	return age, nil
+}
@@ -0,0 +1,101 @@
+package files2prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestParseAge(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"48h":    48 * time.Hour,
+		"7d":     7 * 24 * time.Hour,
+		"2w":     14 * 24 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"1.5d":   36 * time.Hour,
+		"90m":    90 * time.Minute,
+		"12h30m": 12*time.Hour + 30*time.Minute,
+		"0":      0,
+	} {
+		age, err := parseAge(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, age, s)
+	}
+	for _, s := range []string{"", "d", "7", "-3d", "7days", "2024-06-01"} {
+		_, err := parseAge(s)
+		assert.EqualError(t, err, "invalid age \""+s+"\"")
+	}
+}
+
+func TestModTimes(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	w, err := modTimes(config.Config{NewerThan: "7d", OlderThan: "2024-06-09"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, modTimeWindow{after: now.AddDate(0, 0, -7), before: time.Date(2024, 6, 9, 0, 0, 0, 0, time.Local)}, w)
+	assert.True(t, w.contains(time.Date(2024, 6, 5, 0, 0, 0, 0, time.Local)))
+	assert.False(t, w.contains(time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)))
+	assert.False(t, w.contains(time.Date(2024, 6, 9, 8, 0, 0, 0, time.Local)))
+
+	w, err = modTimes(config.Config{OlderThan: "2024-06-01T09:30"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 9, 30, 0, 0, time.Local), w.before)
+	assert.True(t, w.after.IsZero())
+	assert.True(t, modTimeWindow{}.contains(now))
+
+	_, err = modTimes(config.Config{NewerThan: "yesterday"}, now)
+	assert.EqualError(t, err, `invalid --newer-than "yesterday": use an age such as 48h or 7d, or a date such as 2024-06-01`)
+	_, err = modTimes(config.Config{NewerThan: "2d", OlderThan: "7d"}, now)
+	assert.EqualError(t, err, "--newer-than 2d and --older-than 7d leave no time between them")
+}
+
+func TestModTimeFilter(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"fresh.go": "package fresh\n",
+		"week.go":  "package week\n",
+		"old.go":   "package old\n",
+	})
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"fresh.go": time.Hour, "week.go": 5 * 24 * time.Hour, "old.go": 60 * 24 * time.Hour} {
+		mtime := now.Add(-age)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), mtime, mtime))
+	}
+
+	included := func(cfg config.Config) []string {
+		t.Helper()
+		cfg.Paths = append(cfg.Paths, dir)
+		planned, err := Plan(context.Background(), cfg, true)
+		require.NoError(t, err)
+		var names []string
+		for _, f := range planned {
+			if f.Included {
+				names = append(names, filepath.Base(f.Path))
+			} else {
+				assert.Equal(t, SkipModTime, f.Reason, f.Path)
+			}
+		}
+		return names
+	}
+	assert.Equal(t, []string{"fresh.go", "old.go", "week.go"}, included(config.Config{}))
+	assert.Equal(t, []string{"fresh.go"}, included(config.Config{NewerThan: "48h"}))
+	assert.Equal(t, []string{"fresh.go", "week.go"}, included(config.Config{NewerThan: "2w"}))
+	assert.Equal(t, []string{"old.go", "week.go"}, included(config.Config{OlderThan: "1d"}))
+	assert.Equal(t, []string{"week.go"}, included(config.Config{NewerThan: "2w", OlderThan: "1d"}))
+	assert.Equal(t, []string{"old.go"}, included(config.Config{OlderThan: now.AddDate(0, 0, -30).Format(time.DateOnly)}))
+
+	// A file named as an argument is always read
+	planned, err := Plan(context.Background(), config.Config{Paths: []string{filepath.Join(dir, "old.go")}, NewerThan: "1d"}, false)
+	require.NoError(t, err)
+	assert.Len(t, planned, 1)
+
+	cfg := config.Config{Paths: []string{dir}, NewerThan: "30m"}
+	planned, err = Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+	assert.Equal(t, dir+": 3 files excluded by modification time [newer than 30m]", statsFromPlan(dir, planned).emptyDiagnostic(cfg))
+}
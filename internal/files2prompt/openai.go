@@ -0,0 +1,135 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// openaiSystem and openaiSystemSingle are the templates of the system message
+// opening --openai output, without and with --openai-single-message. Like
+// header templates, they are executed with a TemplateRun.
+const (
+	openaiSystem = `You are given the following {{.FileCount}} {{if eq .FileCount 1}}file{{else}}files{{end}}, each in a message of its own: ` +
+		`its path on the first line, then its content in a fenced code block. Refer to the files by their paths.`
+	openaiSystemSingle = `You are given the following {{.FileCount}} {{if eq .FileCount 1}}file{{else}}files{{end}} in the next message, each as its path ` +
+		`on a line of its own followed by its content in a fenced code block. Refer to the files by their paths.`
+)
+
+// openaiMessages writes --openai output: a JSON array of chat messages, opened
+// by a system message, with a user message per document or, with
+// --openai-single-message, one for them all. The array is written as the
+// documents are, so the single message is too, a document at a time.
+type openaiMessages struct {
+	system *template.Template
+	single bool
+	// parts counts the documents written
+	parts int
+}
+
+// openaiOptions checks that --openai is not given with another output format,
+// and that the options refining it are not given without it.
+func openaiOptions(config config.Config) error {
+	if !config.OpenAI {
+		switch {
+		case config.OpenAISingleMessage:
+			return errors.New("--openai-single-message requires --openai")
+		case config.OpenAISystemTemplate != "":
+			return errors.New("--openai-system-template requires --openai")
+		}
+		return nil
+	}
+	for _, option := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--markdown", config.Markdown},
+		{"--cxml", config.ClaudeXML},
+		{"--html", config.HTML},
+		{"--jsonl", config.JSONL},
+	} {
+		if option.set {
+			return fmt.Errorf("--openai cannot be combined with %s", option.flag)
+		}
+	}
+	return nil
+}
+
+// newOpenAIMessages returns the writer of --openai output, with the system
+// message template of --openai-system-template if given, or nil without
+// --openai.
+func newOpenAIMessages(config config.Config) (*openaiMessages, error) {
+	if !config.OpenAI {
+		return nil, nil
+	}
+	m := &openaiMessages{single: config.OpenAISingleMessage}
+	if config.OpenAISystemTemplate != "" {
+		var err error
+		if m.system, err = loadTemplate(config.OpenAISystemTemplate, sampleRun, config); err != nil {
+			return nil, fmt.Errorf("--openai-system-template: %v", err)
+		}
+		return m, nil
+	}
+	text := openaiSystem
+	if m.single {
+		text = openaiSystemSingle
+	}
+	m.system = template.Must(template.New("system").Parse(text))
+	return m, nil
+}
+
+// open returns the start of the array, up to and with the system message, for
+// the files of plan.
+func (m *openaiMessages) open(plan []PlannedFile, config config.Config) (string, error) {
+	run := TemplateRun{}
+	if readsStdin(config) {
+		run.FileCount++
+	}
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			run.FileCount++
+			run.TotalBytes += f.Size
+		}
+	}
+	var system strings.Builder
+	if err := m.system.Execute(&system, run); err != nil {
+		return "", fmt.Errorf("--openai-system-template: %v", err)
+	}
+	return "[\n" + openaiMessage("system", system.String()), nil
+}
+
+// document returns the output for a document shown as text: a user message of
+// its own or, with --openai-single-message, the next part of the one.
+func (m *openaiMessages) document(text string) string {
+	m.parts++
+	if !m.single {
+		return ",\n" + openaiMessage("user", text)
+	}
+	if m.parts == 1 {
+		return ",\n" + `{"role":"user","content":"` + jsonEscape(text)
+	}
+	return jsonEscape("\n\n" + text)
+}
+
+// close returns the end of the array, closing the single user message first.
+func (m *openaiMessages) close() string {
+	if m.single && m.parts > 0 {
+		return "\"}\n]\n"
+	}
+	return "\n]\n"
+}
+
+// openaiMessage returns the chat message of role saying content.
+func openaiMessage(role, content string) string {
+	return `{"role":"` + role + `","content":"` + jsonEscape(content) + `"}`
+}
+
+// openaiText returns how a document labelled label appears in a user message:
+// the label on a line of its own, then the content fenced as in Markdown.
+func openaiText(label, lang, content string, state *emitState) string {
+	backticks := getBackticks(content)
+	return fmt.Sprintf("%s\n%s%s%s\n%s%s", label, metadataLine(state), backticks, lang, content, backticks)
+}
@@ -0,0 +1,146 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// chatMessage is a message of --openai output, as an API would decode it.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openaiRun runs Generate with --openai and the paths of cfg, decoding the
+// output, which must be a JSON array of messages and nothing else.
+func openaiRun(t *testing.T, cfg config.Config) []chatMessage {
+	t.Helper()
+	cfg.OpenAI = true
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	var messages []chatMessage
+	dec := json.NewDecoder(&buf)
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(&messages), buf.String())
+	assert.False(t, dec.More())
+	return messages
+}
+
+func TestOpenAI(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"notes.md":  "Use ```go fences\" & <tags>\n",
+		"empty.txt": "",
+	})
+	t.Chdir(dir)
+	paths := []string{"empty.txt", "main.go", "notes.md"}
+
+	for _, stream := range []bool{false, true} {
+		name := "held"
+		if stream {
+			name = "streamed"
+		}
+		t.Run(name, func(t *testing.T) {
+			if stream {
+				// Documents are encoded whole whatever their size
+				withStreamThreshold(t, 1)
+			}
+
+			t.Run("a message per file", func(t *testing.T) {
+				messages := openaiRun(t, config.Config{Paths: paths})
+				require.Len(t, messages, 4)
+				assert.Equal(t, chatMessage{"system", "You are given the following 3 files, each in a message of its own: " +
+					"its path on the first line, then its content in a fenced code block. Refer to the files by their paths."}, messages[0])
+				assert.Equal(t, []chatMessage{
+					{"user", "empty.txt\n```\n```"},
+					{"user", "main.go\n```go\npackage main\n\nfunc main() {}\n```"},
+					{"user", "notes.md\n````markdown\nUse ```go fences\" & <tags>\n````"},
+				}, messages[1:])
+			})
+
+			t.Run("one message for all", func(t *testing.T) {
+				messages := openaiRun(t, config.Config{Paths: paths, OpenAISingleMessage: true})
+				require.Len(t, messages, 2)
+				assert.Equal(t, "system", messages[0].Role)
+				assert.Contains(t, messages[0].Content, "You are given the following 3 files in the next message")
+				assert.Equal(t, chatMessage{"user", "empty.txt\n```\n```\n\n" +
+					"main.go\n```go\npackage main\n\nfunc main() {}\n```\n\n" +
+					"notes.md\n````markdown\nUse ```go fences\" & <tags>\n````"}, messages[1])
+			})
+		})
+	}
+
+	t.Run("tree, line numbers and commands", func(t *testing.T) {
+		messages := openaiRun(t, config.Config{Paths: []string{"main.go"}, Tree: true, LineNumbersCompact: true, Commands: []string{"echo hi"}})
+		require.Len(t, messages, 4)
+		assert.Contains(t, messages[0].Content, "the following 1 file, each")
+		assert.Equal(t, []chatMessage{
+			{"user", "directory-tree\n```\nmain.go\n```"},
+			{"user", "main.go\n```go\n1:package main\n2:\n3:func main() {}\n```"},
+			{"user", "echo hi\n```text\n1:hi\n```"},
+		}, messages[1:])
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		for _, single := range []bool{false, true} {
+			messages := openaiRun(t, config.Config{Paths: []string{t.TempDir()}, OpenAISingleMessage: single})
+			require.Len(t, messages, 1)
+			assert.Equal(t, "system", messages[0].Role)
+		}
+	})
+}
+
+func TestOpenAISystemTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "12345\n", "b.txt": "678\n"})
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile("system.tmpl", []byte("Review these {{.FileCount}} files ({{.TotalBytes}} bytes).\n"), 0o600))
+
+	messages := openaiRun(t, config.Config{Paths: []string{"a.txt", "b.txt"}, OpenAISystemTemplate: "system.tmpl"})
+	require.Len(t, messages, 3)
+	assert.Equal(t, chatMessage{"system", "Review these 2 files (10 bytes).\n"}, messages[0])
+
+	require.NoError(t, os.WriteFile("bad.tmpl", []byte("{{.Path}}"), 0o600))
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"a.txt"}, OpenAI: true, OpenAISystemTemplate: "bad.tmpl"}, &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "--openai-system-template: bad.tmpl:1:2: <.Path>: header and footer templates have no field Path; "+
+		"use .FileCount or .TotalBytes (.Path is only for file templates)")
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"with markdown", config.Config{OpenAI: true, Markdown: true}, "--openai cannot be combined with --markdown"},
+		{"with cxml", config.Config{OpenAI: true, ClaudeXML: true}, "--openai cannot be combined with --cxml"},
+		{"with html", config.Config{OpenAI: true, HTML: true}, "--openai cannot be combined with --html"},
+		{"with jsonl", config.Config{OpenAI: true, JSONL: true}, "--openai cannot be combined with --jsonl"},
+		{"with compat", config.Config{OpenAI: true, Compat: string(CompatFilesToPrompt)}, "--compat files-to-prompt cannot be combined with --openai"},
+		{"single message alone", config.Config{OpenAISingleMessage: true}, "--openai-single-message requires --openai"},
+		{"system template alone", config.Config{OpenAISystemTemplate: "system.tmpl"}, "--openai-system-template requires --openai"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"testdata/file1.txt"}
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	withStdout(t)
+	_, err := Run(config.Config{Paths: []string{"testdata/file1.txt"}, OpenAI: true, SplitBytes: 100, OutputFile: filepath.Join(t.TempDir(), "out.json")})
+	assert.EqualError(t, err, "--split-bytes cannot be combined with --openai")
+}
@@ -0,0 +1,125 @@
+package files2prompt
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// SortOrder selects the order in which the selected files are emitted.
+type SortOrder string
+
+// Sort orders accepted by --sort.
+const (
+	// SortPath sorts every selected file by path, compared element by element,
+	// so the output does not depend on the order of the path arguments.
+	SortPath SortOrder = "path"
+	// SortSize emits the smallest files first.
+	SortSize SortOrder = "size"
+	// SortModTime emits the least recently modified files first.
+	SortModTime SortOrder = "mtime"
+	// SortNone keeps the order in which the files were found: each path argument
+	// in turn, walked in lexical order, followed by the paths read from stdin.
+	SortNone SortOrder = "none"
+)
+
+// sortWalk is the order of the files-to-prompt walk, which --compat
+// files-to-prompt uses in place of SortPath: each path argument in turn, and
+// within each directory its files by name before its subdirectories by name.
+const sortWalk SortOrder = "walk"
+
+// sortOrder returns the order selected by config, defaulting to SortPath.
+func sortOrder(config config.Config) (SortOrder, error) {
+	switch order := SortOrder(config.Sort); order {
+	case "", SortPath:
+		if CompatMode(config.Compat) == CompatFilesToPrompt {
+			return sortWalk, nil
+		}
+		return SortPath, nil
+	case SortSize, SortModTime, SortNone:
+		return order, nil
+	}
+	return "", fmt.Errorf("invalid --sort %q: use %s, %s, %s or %s",
+		config.Sort, SortPath, SortSize, SortModTime, SortNone)
+}
+
+// comparePaths orders paths element by element, which keeps a directory's
+// contents together in the order a walk visits them.
+func comparePaths(a, b string) int {
+	return slices.Compare(strings.Split(filepath.ToSlash(a), "/"), strings.Split(filepath.ToSlash(b), "/"))
+}
+
+// compareWalk orders paths beneath the same path argument as files-to-prompt
+// walks them: at the first element where they differ, a file comes before a
+// directory, and otherwise the names are compared.
+func compareWalk(a, b string) int {
+	as, bs := strings.Split(filepath.ToSlash(a), "/"), strings.Split(filepath.ToSlash(b), "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		if aDir, bDir := i < len(as)-1, i < len(bs)-1; aDir != bDir {
+			if aDir {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(as[i], bs[i])
+	}
+	return len(as) - len(bs)
+}
+
+// sortPlan reorders the included files of plan in place by order. Skipped
+// entries keep their positions, so a dry run still lists each near miss
+// alongside its neighbours. Ties are broken by path, compared in absolute
+// form so that relative and absolute spellings interleave correctly, except
+// by sortTokens, which leaves them in the order they were in.
+func sortPlan(plan []PlannedFile, order SortOrder) {
+	if order == SortNone {
+		return
+	}
+	type entry struct {
+		PlannedFile
+		abs string
+		// root numbers the path argument the file was found under
+		root int
+	}
+	var slots []int
+	var included []entry
+	roots := map[string]int{}
+	for i, f := range plan {
+		if f.Included {
+			if _, ok := roots[f.Root]; !ok {
+				roots[f.Root] = len(roots)
+			}
+			slots = append(slots, i)
+			included = append(included, entry{f, absPath(f.Path), roots[f.Root]})
+		}
+	}
+	slices.SortStableFunc(included, func(a, b entry) int {
+		switch order {
+		case sortWalk:
+			if a.root != b.root {
+				return a.root - b.root
+			}
+			return compareWalk(a.abs, b.abs)
+		case SortSize:
+			if a.Size != b.Size {
+				return int(a.Size - b.Size)
+			}
+		case SortModTime:
+			if c := a.ModTime.Compare(b.ModTime); c != 0 {
+				return c
+			}
+		case sortTokens:
+			return int(tokenCost(a.PlannedFile) - tokenCost(b.PlannedFile))
+		}
+		return comparePaths(a.abs, b.abs)
+	})
+	for i, slot := range slots {
+		plan[slot] = included[i].PlannedFile
+	}
+}
@@ -0,0 +1,102 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// orderFixture creates two directories and a loose file, each file with a
+// distinct size and modification time, and returns the three path arguments.
+func orderFixture(t *testing.T) (alpha, beta, loose string) {
+	dir := t.TempDir()
+	alpha, beta, loose = filepath.Join(dir, "alpha"), filepath.Join(dir, "beta"), filepath.Join(dir, "loose.dat")
+	files := []struct {
+		path string
+		size int
+		age  time.Duration
+	}{
+		{filepath.Join(beta, "b.dat"), 10, 1 * time.Hour},
+		{filepath.Join(alpha, "sub", "c.dat"), 40, 4 * time.Hour},
+		{filepath.Join(alpha, "a.dat"), 30, 2 * time.Hour},
+		{loose, 20, 3 * time.Hour},
+	}
+	now := time.Now()
+	for _, f := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(f.path), 0o750))
+		require.NoError(t, os.WriteFile(f.path, make([]byte, f.size), 0o600))
+		require.NoError(t, os.Chtimes(f.path, now.Add(-f.age), now.Add(-f.age)))
+	}
+	return alpha, beta, loose
+}
+
+func TestSortPlan(t *testing.T) {
+	alpha, beta, loose := orderFixture(t)
+	a, c, b := filepath.Join(alpha, "a.dat"), filepath.Join(alpha, "sub", "c.dat"), filepath.Join(beta, "b.dat")
+
+	tests := []struct {
+		sort     string
+		expected []string
+	}{
+		{"", []string{a, c, b, loose}},
+		{"path", []string{a, c, b, loose}},
+		{"size", []string{b, loose, a, c}},
+		{"mtime", []string{c, loose, a, b}},
+		{"none", []string{loose, b, a, c}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cfg := config.Config{Paths: []string{loose, beta}, StdinPaths: []string{alpha}, Sort: tt.sort}
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			var paths []string
+			for _, f := range plan {
+				paths = append(paths, f.Path)
+			}
+			assert.Equal(t, tt.expected, paths)
+		})
+	}
+}
+
+func TestSortPathIgnoresArgumentOrder(t *testing.T) {
+	alpha, beta, loose := orderFixture(t)
+	var outputs []string
+	for _, paths := range [][]string{{alpha, beta, loose}, {loose, beta, alpha}, {beta, loose, alpha}} {
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), config.Config{Paths: paths}, &buf, nil)
+		require.NoError(t, err)
+		outputs = append(outputs, buf.String())
+	}
+	assert.Equal(t, outputs[0], outputs[1])
+	assert.Equal(t, outputs[0], outputs[2])
+}
+
+func TestSortPlanKeepsSkippedEntries(t *testing.T) {
+	plan := []PlannedFile{
+		{Path: "z", Included: true},
+		{Path: "skipped", Reason: SkipExtension},
+		{Path: "a", Included: true},
+	}
+	sortPlan(plan, SortPath)
+	assert.Equal(t, []string{"a", "skipped", "z"}, []string{plan[0].Path, plan[1].Path, plan[2].Path})
+}
+
+func TestComparePaths(t *testing.T) {
+	// A directory's contents sort before siblings that share its name as a prefix
+	assert.Negative(t, comparePaths("a/b/c", "a/b.go"))
+	assert.Negative(t, comparePaths("a/b", "a/b/c"))
+	assert.Zero(t, comparePaths("a/b", "a/b"))
+}
+
+func TestSortInvalid(t *testing.T) {
+	_, err := Plan(context.Background(), config.Config{Paths: []string{"testdata"}, Sort: "random"}, false)
+	assert.EqualError(t, err, `invalid --sort "random": use path, size, mtime or none`)
+}
@@ -0,0 +1,76 @@
+package files2prompt
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// absPath returns the cleaned absolute form of path, or path itself when the
+// working directory cannot be determined.
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// matchBases finds the directory that ignore and include patterns are matched
+// relative to for a path named directly, so that a path read from stdin is
+// filtered the same whether it was spelled absolute or relative.
+type matchBases struct {
+	// dirs are the absolute paths of the directory arguments
+	dirs []string
+	jail *jail
+	// repos caches the repository root enclosing each directory, or ""
+	repos map[string]string
+}
+
+func newMatchBases(abs []string, jail *jail) *matchBases {
+	b := &matchBases{jail: jail, repos: map[string]string{}}
+	for _, path := range abs {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			b.dirs = append(b.dirs, path)
+		}
+	}
+	return b
+}
+
+// rel returns abs relative to the deepest directory argument containing it,
+// else to the root of the repository enclosing it, else to the working
+// directory. abs is returned unchanged when none of them contains it.
+func (b *matchBases) rel(abs string) string {
+	var base string
+	for _, dir := range b.dirs {
+		if dir != abs && withinDir(abs, dir) && len(dir) > len(base) {
+			base = dir
+		}
+	}
+	if base == "" {
+		base = b.repoRoot(filepath.Dir(abs))
+	}
+	if base == "" {
+		base, _ = os.Getwd()
+	}
+	if base == "" || !withinDir(abs, base) {
+		return abs
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return abs
+	}
+	return rel
+}
+
+// repoRoot returns the root of the repository enclosing dir, or "" outside a
+// repository or when the root lies outside the jail.
+func (b *matchBases) repoRoot(dir string) string {
+	root, ok := b.repos[dir]
+	if !ok {
+		if repo, found := findRepoRoot(dir); found && b.jail.check(repo) == nil {
+			root = repo
+		}
+		b.repos[dir] = root
+	}
+	return root
+}
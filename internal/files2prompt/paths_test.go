@@ -0,0 +1,142 @@
+package files2prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// spellingFixture writes a repository with .gitignore and export-ignore rules
+// and returns its root.
+func spellingFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":          "*.log\n/sub/generated.go\n",
+		".gitattributes":      "sub/vendor.go export-ignore\n",
+		"main.go":             "package main\n",
+		"debug.log":           "log\n",
+		"sub/a.go":            "package sub\n",
+		"sub/a_test.go":       "package sub\n",
+		"sub/generated.go":    "package sub\n",
+		"sub/vendor.go":       "package sub\n",
+		"sub/deep/b.go":       "package deep\n",
+		"sub/deep/b_test.go":  "package deep\n",
+		"sub/deep/notes.json": "{}\n",
+	})
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o700))
+	return root
+}
+
+// spellingFiles are the fixture's files, relative to its root.
+var spellingFiles = []string{
+	"main.go", "debug.log", "sub/a.go", "sub/a_test.go", "sub/generated.go",
+	"sub/vendor.go", "sub/deep/b.go", "sub/deep/b_test.go", "sub/deep/notes.json",
+}
+
+// includedAbs returns the included files of plan as slash-separated paths
+// relative to root, whatever their spelling.
+func includedAbs(t *testing.T, root string, plan []PlannedFile) []string {
+	t.Helper()
+	var paths []string
+	for _, f := range plan {
+		if f.Included {
+			rel, err := filepath.Rel(root, absPath(f.Path))
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+	return paths
+}
+
+func TestPlanPathSpellings(t *testing.T) {
+	root := spellingFixture(t)
+	// Run from a subdirectory, so that relative spellings differ from repo-relative paths
+	t.Chdir(filepath.Join(root, "sub"))
+
+	relative := make([]string, len(spellingFiles))
+	absolute := make([]string, len(spellingFiles))
+	mixed := make([]string, len(spellingFiles))
+	for i, name := range spellingFiles {
+		absolute[i] = filepath.Join(root, filepath.FromSlash(name))
+		rel, err := filepath.Rel(filepath.Join(root, "sub"), absolute[i])
+		require.NoError(t, err)
+		relative[i] = rel
+		mixed[i] = relative[i]
+		if i%2 == 0 {
+			mixed[i] = absolute[i]
+		}
+	}
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected []string
+	}{
+		{
+			name:     "anchored ignore pattern",
+			config:   config.Config{IgnorePatterns: []string{"sub/deep/*"}},
+			expected: []string{"main.go", "debug.log", "sub/a.go", "sub/a_test.go", "sub/generated.go", "sub/vendor.go"},
+		},
+		{
+			name:     "base name ignore pattern",
+			config:   config.Config{IgnorePatterns: []string{"*_test.go"}},
+			expected: []string{"main.go", "debug.log", "sub/a.go", "sub/generated.go", "sub/vendor.go", "sub/deep/b.go", "sub/deep/notes.json"},
+		},
+		{
+			name:     "include pattern",
+			config:   config.Config{IncludePatterns: []string{"sub/**/*.go"}, IgnorePatterns: []string{"**/*_test.go"}},
+			expected: []string{"sub/a.go", "sub/generated.go", "sub/vendor.go", "sub/deep/b.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, stdin := range [][]string{relative, absolute, mixed} {
+				cfg := tt.config
+				cfg.StdinPaths = stdin
+				cfg.Sort = "none"
+				plan, err := Plan(context.Background(), cfg, false)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, includedAbs(t, root, plan), stdin)
+			}
+		})
+	}
+}
+
+func TestPlanPathSpellingsWalk(t *testing.T) {
+	root := spellingFixture(t)
+	t.Chdir(root)
+
+	// A walk applies the same .gitignore and export-ignore rules whether the
+	// directory was named relative or absolute
+	plan := func(paths ...string) []string {
+		cfg := config.Config{Paths: paths, IgnoreGitignore: true, UseExportIgnore: true, IgnorePatterns: []string{"**/deep/*.json"}}
+		plan, err := Plan(context.Background(), cfg, false)
+		require.NoError(t, err)
+		return includedAbs(t, root, plan)
+	}
+	expected := []string{"main.go", "sub/a.go", "sub/a_test.go", "sub/deep/b.go", "sub/deep/b_test.go"}
+	assert.Equal(t, expected, plan("."))
+	assert.Equal(t, expected, plan(root))
+	assert.Equal(t, plan("sub", "main.go"), plan(filepath.Join(root, "sub"), filepath.Join(root, "main.go")))
+	assert.Equal(t, plan("sub", "main.go"), plan(filepath.Join(root, "sub"), "main.go"))
+}
+
+func TestMatchBasesRel(t *testing.T) {
+	root := spellingFixture(t)
+	outside := t.TempDir()
+	t.Chdir(outside)
+	bases := newMatchBases([]string{filepath.Join(root, "sub"), filepath.Join(root, "sub", "deep"), filepath.Join(root, "main.go")}, nil)
+
+	assert.Equal(t, "b.go", bases.rel(filepath.Join(root, "sub", "deep", "b.go")), "deepest directory argument")
+	assert.Equal(t, "a.go", bases.rel(filepath.Join(root, "sub", "a.go")))
+	assert.Equal(t, "main.go", bases.rel(filepath.Join(root, "main.go")), "repository root")
+	assert.Equal(t, "x.go", bases.rel(filepath.Join(outside, "x.go")), "working directory")
+	assert.Equal(t, "/elsewhere/x.go", bases.rel("/elsewhere/x.go"))
+}
@@ -0,0 +1,130 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// posixClasses are the ranges of the POSIX character classes, [:alpha:] and
+// the like, that gitignore bracket expressions may hold and doublestar lacks.
+var posixClasses = map[string]string{
+	"alnum":  "0-9A-Za-z",
+	"alpha":  "A-Za-z",
+	"blank":  " \t",
+	"cntrl":  "\x00-\x1f\x7f",
+	"digit":  "0-9",
+	"graph":  "!-~",
+	"lower":  "a-z",
+	"print":  " -~",
+	"punct":  "!-/:-@\\[-`{-~",
+	"space":  " \t\n\v\f\r",
+	"upper":  "A-Z",
+	"xdigit": "0-9A-Fa-f",
+}
+
+// translatePattern returns pattern as a valid doublestar pattern: its POSIX
+// character classes are rewritten as ranges, and a pattern doublestar would
+// fail to match with is an error saying why. Every pattern is translated once,
+// when it is read, so that matching it can never fail.
+func translatePattern(pattern string) (string, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			b.WriteString(pattern[i : i+2])
+			i++
+			continue
+		case !inClass && c == '[':
+			if name, ok := posixClassAt(pattern[i:]); ok {
+				return "", fmt.Errorf("[:%s:] matches only within brackets, as in [[:%s:]]", name, name)
+			}
+			inClass = true
+		case inClass && c == ']':
+			inClass = false
+		case inClass && strings.HasPrefix(pattern[i:], "[:"):
+			name, ok := posixClassAt(pattern[i:])
+			if !ok {
+				break
+			}
+			ranges, known := posixClasses[name]
+			if !known {
+				return "", fmt.Errorf("[:%s:] is not a character class; use one of %s", name,
+					"[:"+strings.Join(slices.Sorted(maps.Keys(posixClasses)), ":], [:")+":]")
+			}
+			b.WriteString(ranges)
+			i += len(name) + 3
+			continue
+		}
+		b.WriteByte(c)
+	}
+	translated := b.String()
+	if !doublestar.ValidatePattern(translated) {
+		return "", errors.New(patternProblem(translated))
+	}
+	return translated, nil
+}
+
+// posixClassAt returns the name of the [:name:] class s starts with.
+func posixClassAt(s string) (string, bool) {
+	rest, ok := strings.CutPrefix(s, "[:")
+	if !ok {
+		return "", false
+	}
+	end := strings.Index(rest, ":]")
+	if end < 1 || strings.ContainsAny(rest[:end], "[]/") {
+		return "", false
+	}
+	return rest[:end], true
+}
+
+// patternProblem says what makes pattern, which doublestar rejects, invalid.
+func patternProblem(pattern string) string {
+	if strings.HasSuffix(strings.ReplaceAll(pattern, `\\`, ""), `\`) {
+		return `it ends in a \ that escapes nothing`
+	}
+	if doublestar.ValidatePattern(strings.NewReplacer("{", `\{`, "}", `\}`).Replace(pattern)) {
+		return `its { and } do not pair up; escape literal braces as \{ and \}`
+	}
+	return `a [ is not closed by ], or encloses nothing; escape a literal [ as \[`
+}
+
+// patternFlags are the options taking doublestar patterns, with how to get
+// what a leading "!" would mean in a .gitignore file.
+var patternFlags = []struct {
+	flag     string
+	patterns func(config.Config) []string
+	negation string
+}{
+	{"--ignore", func(c config.Config) []string { return c.IgnorePatterns }, "leave the files out of the pattern, or name them with --include"},
+	{"--include", func(c config.Config) []string { return c.IncludePatterns }, "leave files out with --ignore instead"},
+}
+
+// patternOptions checks every --ignore and --include pattern of config, before
+// anything is walked, so that one that could never match as meant is an
+// error rather than a filter silently doing nothing.
+func patternOptions(config config.Config) error {
+	for _, option := range patternFlags {
+		for _, patterns := range option.patterns(config) {
+			for _, pattern := range strings.Split(patterns, ",") {
+				pattern = strings.TrimSpace(pattern)
+				if strings.HasPrefix(pattern, "!") {
+					return fmt.Errorf(`%s %q: a leading "!" re-includes files in a .gitignore file, but %s patterns cannot be negated; %s`,
+						option.flag, pattern, option.flag, option.negation)
+				}
+				if _, err := translatePattern(pattern); err != nil {
+					return fmt.Errorf("%s %q: %v", option.flag, pattern, err)
+				}
+			}
+		}
+	}
+	return nil
+}
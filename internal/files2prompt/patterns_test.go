@@ -0,0 +1,136 @@
+package files2prompt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestTranslatePattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected string
+	}{
+		{"*.go", "*.go"},
+		{"src/**/*.{js,ts}", "src/**/*.{js,ts}"},
+		{"[[:alpha:]]*.txt", "[A-Za-z]*.txt"},
+		{"v[[:digit:].]*", "v[0-9.]*"},
+		{"[[:upper:][:digit:]]*", "[A-Z0-9]*"},
+		{"[![:space:]]", "[! \t\n\v\f\r]"},
+		{"[^[:xdigit:]]", "[^0-9A-Fa-f]"},
+		{"[[:punct:]]", "[!-/:-@\\[-`{-~]"},
+		{`\[[[:alpha:]]\]`, `\[[A-Za-z]\]`},
+		{"[:]", "[:]"},
+	}
+	for _, tt := range tests {
+		translated, err := translatePattern(tt.pattern)
+		require.NoError(t, err, tt.pattern)
+		assert.Equal(t, tt.expected, translated, tt.pattern)
+	}
+}
+
+func TestTranslatePatternErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		err     string
+	}{
+		{"[[:word:]]", "[:word:] is not a character class; use one of [:alnum:], [:alpha:], [:blank:], [:cntrl:], [:digit:], " +
+			"[:graph:], [:lower:], [:print:], [:punct:], [:space:], [:upper:], [:xdigit:]"},
+		{"[:alpha:]*", "[:alpha:] matches only within brackets, as in [[:alpha:]]"},
+		{"src/[abc", `a [ is not closed by ], or encloses nothing; escape a literal [ as \[`},
+		{"a[]b", `a [ is not closed by ], or encloses nothing; escape a literal [ as \[`},
+		{"*.{js,ts", `its { and } do not pair up; escape literal braces as \{ and \}`},
+		{"a}b", `its { and } do not pair up; escape literal braces as \{ and \}`},
+		{`trailing\`, `it ends in a \ that escapes nothing`},
+	}
+	for _, tt := range tests {
+		_, err := translatePattern(tt.pattern)
+		assert.EqualError(t, err, tt.err, tt.pattern)
+	}
+}
+
+func TestPatternOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"negated ignore", config.Config{IgnorePatterns: []string{"*.log,!keep.log"}},
+			`--ignore "!keep.log": a leading "!" re-includes files in a .gitignore file, but --ignore patterns cannot be negated; ` +
+				"leave the files out of the pattern, or name them with --include"},
+		{"negated include", config.Config{IncludePatterns: []string{"!*.md"}},
+			`--include "!*.md": a leading "!" re-includes files in a .gitignore file, but --include patterns cannot be negated; ` +
+				"leave files out with --ignore instead"},
+		{"unclosed class", config.Config{IgnorePatterns: []string{"build/[ab"}},
+			`--ignore "build/[ab": a [ is not closed by ], or encloses nothing; escape a literal [ as \[`},
+		{"unknown class", config.Config{IncludePatterns: []string{"[[:word:]]*"}}, `--include "[[:word:]]*": [:word:] is not a character class; ` +
+			"use one of [:alnum:], [:alpha:], [:blank:], [:cntrl:], [:digit:], [:graph:], [:lower:], [:print:], [:punct:], [:space:], [:upper:], [:xdigit:]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"testdata"}
+			_, err := Plan(context.Background(), cfg, false)
+			assert.EqualError(t, err, tt.err)
+			_, err = Match(cfg, "testdata", nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	assert.NoError(t, patternOptions(config.Config{IgnorePatterns: []string{`\!literal`, "foo/**/bar/", "/out"}}))
+}
+
+func TestPatternShapes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"foo/x/bar/a.txt": "", "foo/bar/b.txt": "", "bar/c.txt": "",
+		"out/d.txt": "", "src/out/e.txt": "",
+		"1.log": "", "a.log": "",
+		"A.tmp": "", "b.tmp": "",
+		".gitignore": "[[:upper:]]*.tmp\n[oops\n",
+	})
+	t.Chdir(dir)
+
+	plan, err := Plan(context.Background(), config.Config{
+		Paths:           []string{"."},
+		IgnoreGitignore: true,
+		IgnorePatterns:  []string{"foo/**/bar/", "/out", "[[:digit:]]*.log"},
+	}, false)
+	require.NoError(t, err)
+	var included []string
+	for _, f := range plan {
+		if !f.IsDir {
+			rel, err := filepath.Rel(dir, f.Path)
+			require.NoError(t, err)
+			included = append(included, filepath.ToSlash(rel))
+		}
+	}
+	// The .gitignore rule git cannot parse is skipped, leaving the others working
+	assert.Equal(t, []string{"a.log", "b.tmp", "bar/c.txt", "src/out/e.txt"}, included)
+}
+
+func TestBuiltinPatterns(t *testing.T) {
+	var patterns []string
+	for _, entry := range junkFiles {
+		patterns = append(patterns, entry.pattern)
+	}
+	for _, entry := range sensitiveFiles {
+		patterns = append(patterns, entry.pattern)
+	}
+	patterns = append(patterns, DefaultIgnorePatterns...)
+	patterns = append(patterns, alwaysIncluded...)
+	for _, manifests := range languageManifests {
+		patterns = append(patterns, manifests...)
+	}
+	// They are matched unvalidated, so each must be a pattern translatePattern returns unchanged
+	for _, pattern := range patterns {
+		translated, err := translatePattern(pattern)
+		require.NoError(t, err, pattern)
+		assert.Equal(t, pattern, translated)
+	}
+}
@@ -0,0 +1,135 @@
+package files2prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// DefaultPipeTimeout is the time limit for the whole --pipe chain when
+// PipeTimeout is left at zero.
+const DefaultPipeTimeout = 5 * time.Minute
+
+// outputPipe streams the rendered output through a chain of --pipe commands,
+// each run through the platform shell with its stdin connected to the previous
+// command's stdout, like a shell pipeline. Writes go to the first command; the
+// last command's stdout goes to the destination.
+type outputPipe struct {
+	commands []string
+	cmds     []*exec.Cmd
+	ctx      context.Context
+	cancel   context.CancelFunc
+	timeout  time.Duration
+	stdin    io.WriteCloser
+}
+
+// startPipes starts the commands, connected in order, with the last one writing
+// to dst. The chain is killed if it is still running once timeout has passed.
+func startPipes(ctx context.Context, commands []string, timeout time.Duration, dst io.Writer) (*outputPipe, error) {
+	if timeout <= 0 {
+		timeout = DefaultPipeTimeout
+	}
+	p := &outputPipe{commands: commands, timeout: timeout}
+	p.ctx, p.cancel = context.WithTimeout(ctx, timeout)
+
+	// The commands are joined by OS pipes rather than copying goroutines, so a
+	// command blocked on its output applies backpressure all the way up
+	var parentEnds []*os.File
+	closeParentEnds := func() {
+		for _, f := range parentEnds {
+			_ = f.Close()
+		}
+	}
+	for i, command := range commands {
+		cmd := shellCommand(p.ctx, command)
+		cmd.Stderr = osStderr
+		cmd.WaitDelay = time.Second
+		if i > 0 {
+			r, w, err := os.Pipe()
+			if err != nil {
+				closeParentEnds()
+				p.cancel()
+				return nil, fmt.Errorf("failed to connect --pipe %q: %v", command, err)
+			}
+			parentEnds = append(parentEnds, r, w)
+			p.cmds[i-1].Stdout = w
+			cmd.Stdin = r
+		}
+		p.cmds = append(p.cmds, cmd)
+	}
+	p.cmds[len(p.cmds)-1].Stdout = dst
+
+	var err error
+	if p.stdin, err = p.cmds[0].StdinPipe(); err != nil {
+		closeParentEnds()
+		p.cancel()
+		return nil, fmt.Errorf("failed to connect --pipe %q: %v", commands[0], err)
+	}
+	for i, cmd := range p.cmds {
+		if err := cmd.Start(); err != nil {
+			closeParentEnds()
+			_ = p.stdin.Close()
+			p.cancel()
+			for _, started := range p.cmds[:i] {
+				_ = started.Wait()
+			}
+			return nil, fmt.Errorf("failed to start --pipe %q: %v", commands[i], err)
+		}
+	}
+	// The children hold their own copies; ours must go so that each command
+	// sees end of input once the one before it exits
+	closeParentEnds()
+	return p, nil
+}
+
+// Write sends b to the first command. A command may stop reading before the
+// end of its input, as head does; the rest of the output is then discarded and
+// the commands' exit statuses alone decide whether the run failed.
+func (p *outputPipe) Write(b []byte) (int, error) {
+	n, err := p.stdin.Write(b)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Close ends the input and waits for every command to exit, returning the
+// first failure in chain order.
+func (p *outputPipe) Close() error {
+	defer p.cancel()
+	_ = p.stdin.Close()
+	var first error
+	for i, cmd := range p.cmds {
+		err := cmd.Wait()
+		if err == nil || first != nil || brokenPipe(err) {
+			continue
+		}
+		var exitErr *exec.ExitError
+		switch {
+		case errors.Is(p.ctx.Err(), context.DeadlineExceeded):
+			first = fmt.Errorf("--pipe %q timed out after %s", p.commands[i], p.timeout)
+		case errors.As(err, &exitErr) && exitErr.ExitCode() > 0:
+			first = fmt.Errorf("--pipe %q exited with status %d", p.commands[i], exitErr.ExitCode())
+		default:
+			first = fmt.Errorf("--pipe %q failed: %v", p.commands[i], err)
+		}
+	}
+	return first
+}
+
+// brokenPipe reports whether err is a command being killed for writing to a
+// command further down the chain that had already exited, which a shell
+// pipeline does not treat as a failure either.
+func brokenPipe(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGPIPE
+}
@@ -0,0 +1,111 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestRunPipe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pipe tests use POSIX commands")
+	}
+	document := "testdata/test_project/src/main.go\n---\npackage main\n\nfunc main() {}\n---\n\n"
+
+	tests := []struct {
+		name     string
+		pipes    []string
+		expected string
+	}{
+		{"single command", []string{"tr a-z A-Z"}, strings.ToUpper(document)},
+		{"chained in order", []string{"tr a-z A-Z", "sed 's/MAIN/entry/g'", "cat"}, strings.ReplaceAll(strings.ToUpper(document), "MAIN", "entry")},
+		{"command stopping early", []string{"cat", "head -c 8"}, document[:8]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := withStdout(t)
+			summary, err := Run(config.Config{Paths: []string{"testdata/test_project/src/main.go"}, Pipes: tt.pipes})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+			// Figures describe the output before it was piped
+			assert.Equal(t, int64(len(document)), summary.Bytes)
+		})
+	}
+
+	t.Run("to an output file", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "prompt.txt")
+		_, err := Run(config.Config{Paths: []string{"testdata/test_project/src/main.go"}, OutputFile: output, Pipes: []string{"wc -l"}})
+		require.NoError(t, err)
+		content, err := os.ReadFile(output)
+		require.NoError(t, err)
+		assert.Equal(t, "7", strings.TrimSpace(string(content)))
+	})
+}
+
+func TestRunPipeFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pipe tests use POSIX commands")
+	}
+
+	tests := []struct {
+		name    string
+		config  config.Config
+		message string
+	}{
+		{
+			name:    "failing command",
+			config:  config.Config{Pipes: []string{"cat", "cat >/dev/null; exit 3"}},
+			message: `--pipe "cat >/dev/null; exit 3" exited with status 3`,
+		},
+		{
+			name:    "failing without reading",
+			config:  config.Config{Pipes: []string{"exit 4", "cat"}},
+			message: `--pipe "exit 4" exited with status 4`,
+		},
+		{
+			name:    "timeout",
+			config:  config.Config{Pipes: []string{"exec sleep 10"}, PipeTimeout: 100 * time.Millisecond},
+			message: `--pipe "exec sleep 10" timed out after 100ms`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdout(t)
+			cfg := tt.config
+			cfg.Paths = []string{"testdata/test_project/src/main.go"}
+			start := time.Now()
+			_, err := Run(cfg)
+			assert.EqualError(t, err, tt.message)
+			assert.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func TestOutputPipeLargeOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pipe tests use POSIX commands")
+	}
+	// Far larger than any pipe buffer, so a chain that is not drained
+	// concurrently would deadlock
+	input := bytes.Repeat([]byte("0123456789abcdef"), 1<<18)
+	var out bytes.Buffer
+	pipe, err := startPipes(context.Background(), []string{"cat", "tr 0 x", "cat"}, time.Minute, &out)
+	require.NoError(t, err)
+	for chunk := range slices.Chunk(input, 4096) {
+		_, err := pipe.Write(chunk)
+		require.NoError(t, err)
+	}
+	require.NoError(t, pipe.Close())
+	assert.Equal(t, bytes.ReplaceAll(input, []byte("0"), []byte("x")), out.Bytes())
+}
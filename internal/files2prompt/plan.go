@@ -0,0 +1,351 @@
+package files2prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// PlannedFile is a single filter decision made while walking the input paths.
+type PlannedFile struct {
+	// Path is the file's location on disk.
+	Path string
+	// DisplayPath is the path shown in the rendered output.
+	DisplayPath string
+	// Root is the path argument the file was found under or, for the matches
+	// of a glob argument, the directory named by the pattern's literal prefix.
+	Root string
+	// Submodule is the git submodule directory the file was found in, or "".
+	Submodule string
+	// Size is the file size in bytes.
+	Size int64
+	// ModTime is the file's modification time.
+	ModTime time.Time
+	// Mode is the file's mode and permission bits.
+	Mode os.FileMode
+	// Origin records how the path reached the planner.
+	Origin Origin
+	// Provenance records the source the file's content is read from.
+	Provenance Provenance
+	// IsDir is true for directories pruned from the walk.
+	IsDir bool
+	// Included reports whether the file will be emitted.
+	Included bool
+	// Reason explains why a file was skipped; it is empty for included files.
+	Reason SkipReason
+	// SiblingOf is, for a generated file collapsed by
+	// --collapse-generated-siblings, the Path of the sibling emitted in full;
+	// the file itself is emitted as a stub pointing to it.
+	SiblingOf string
+	// DuplicateOf is, for a file that is through a symlink the same file as
+	// one included before it, the Path of that file; the file itself is
+	// emitted as a stub pointing to its document.
+	DuplicateOf string
+	// CaseCollision is, for an included file whose path differs only in case
+	// from that of one included before it, the Path of that file; on a
+	// case-insensitive file system the two cannot both be written out.
+	CaseCollision string
+	// diff is, with --hunks-only, the change the file's document shows.
+	diff *fileDiff
+}
+
+// stub reports whether f is emitted as a stub pointing to another file rather
+// than read.
+func (f PlannedFile) stub() bool {
+	return f.SiblingOf != "" || f.DuplicateOf != ""
+}
+
+// Plan returns the files that Run would emit for config, in emission order.
+//
+// When includeSkipped is true the result also contains the near misses: files
+// and directories excluded by a filter, each with the Reason it was skipped.
+// The returned plan can be passed to Generate so that the selection and the
+// emission share a single walk.
+func Plan(ctx context.Context, config config.Config, includeSkipped bool) ([]PlannedFile, error) {
+	files, _, err := planFiles(ctx, config, nil, nil)
+	if err != nil || includeSkipped {
+		return files, err
+	}
+	var included []PlannedFile
+	for _, f := range files {
+		if f.Included {
+			included = append(included, f)
+		}
+	}
+	return included, nil
+}
+
+// planFiles walks every path in config, recording both included and skipped candidates
+// and reporting each to mon. It also returns the (expanded) path arguments that could
+// be walked; paths that failed are logged and left out. Directories are walked
+// in snap when it holds them, and on disk otherwise.
+func planFiles(ctx context.Context, config config.Config, mon *longRunMonitor, snap *treeSnapshot) ([]PlannedFile, []string, error) {
+	if config.AutoExtensions {
+		var err error
+		if config, err = autoExtensions(ctx, config, snap); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Expand ~ and globs in user-supplied paths before anything checks for their existence
+	args, err := expandArgs(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	stdinPaths, err := hostEnv.expandPaths(config.StdinPaths)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, path := range stdinPaths {
+		args = append(args, pathArg{path: path, origin: OriginStdin, root: path})
+	}
+	if _, err := submoduleMode(config); err != nil {
+		return nil, nil, err
+	}
+	if _, err := compatMode(config); err != nil {
+		return nil, nil, err
+	}
+	if err := hunksOptions(config); err != nil {
+		return nil, nil, err
+	}
+	if err := patternOptions(config); err != nil {
+		return nil, nil, err
+	}
+	if _, err := maxDepth(config); err != nil {
+		return nil, nil, err
+	}
+	if _, err := modTimes(config, time.Now()); err != nil {
+		return nil, nil, err
+	}
+	order, err := sortOrder(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if args, err = changedArgs(args, config); err != nil {
+		return nil, nil, err
+	}
+
+	jail, err := newJail(config.Jail)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, arg := range args {
+		if err := jail.check(arg.path); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := guardRoots(args, config); err != nil {
+		return nil, nil, err
+	}
+	// Rules are matched against absolute paths, whatever their spelling
+	abs := make([]string, len(args))
+	for i, arg := range args {
+		abs[i] = absPath(arg.path)
+	}
+	bases := newMatchBases(abs, jail)
+
+	gitignoreRules := argGitignoreRules(args, config, jail)
+	grep, err := compileGrep(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracked, err := loadTrackedFiles(args, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipeline := newFilterPipeline(config, gitignoreRules, grep)
+	pipeline.jail, pipeline.tracked = jail, tracked
+	planner := &pathPlanner{pipeline: pipeline, mon: mon, snap: snap}
+	var files []PlannedFile
+	var roots []string
+	for i, arg := range args {
+		rel := bases.rel(abs[i])
+		if arg.origin == OriginGlob {
+			if dir := pipeline.prunedGlobDir(arg); dir != "" {
+				log.Debugf("Skipping glob match %s beneath skipped directory %s", arg.path, dir)
+				continue
+			}
+			if rel, err = filepath.Rel(arg.root, arg.path); err != nil {
+				rel = arg.path
+			}
+		}
+		planned, err := planner.planPath(ctx, arg.path, arg.origin, rel)
+		for j := range planned {
+			planned[j].Root = arg.root
+		}
+		files = append(files, planned...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			if errors.Is(err, ErrAborted) {
+				return nil, nil, err
+			}
+			log.Errorf("Error processing path %s: %v", arg.path, err)
+			continue
+		}
+		if !slices.Contains(roots, arg.root) {
+			roots = append(roots, arg.root)
+		}
+	}
+	if err := planHunks(files, config); err != nil {
+		return nil, nil, err
+	}
+	sortPlan(files, order)
+	if config.SmallFirst {
+		sortPlan(files, sortTokens)
+	}
+	if config.FitTokens > 0 {
+		fitTokens(files, config.FitTokens)
+	}
+	collapseGeneratedSiblings(files, config)
+	markSymlinkDuplicates(files, config)
+	markCaseCollisions(files)
+	return files, roots, nil
+}
+
+// argGitignoreRules returns the .gitignore rules of the directories enclosing
+// the path arguments args, which apply before any read while walking them.
+func argGitignoreRules(args []pathArg, config config.Config, jail *jail) []gitignoreRule {
+	if !appliesGitignore(config) {
+		return nil
+	}
+	var rules []gitignoreRule
+	read := map[string]bool{}
+	for _, arg := range args {
+		dirs := []string{filepath.Dir(arg.path)}
+		if arg.origin == OriginGlob {
+			// A glob match is filtered as if found walking the pattern's base
+			dirs = append([]string{filepath.Dir(arg.root), arg.root}, globDirs(arg)...)
+		}
+		for _, dir := range dirs {
+			// Parent directories outside the jail must not be read either
+			if dirAbs := absPath(dir); !read[dirAbs] && jail.check(dir) == nil {
+				read[dirAbs] = true
+				rules = append(rules, readGitignoreRules(dirAbs)...)
+			}
+		}
+	}
+	return rules
+}
+
+// walkRoot returns the path a walk of the path argument root starts from: the
+// working directory for ".", and root itself otherwise.
+func walkRoot(root string) (string, error) {
+	if root != "." {
+		return root, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %v", err)
+	}
+	return wd, nil
+}
+
+// planRoots returns the distinct roots of plan in order of first appearance.
+func planRoots(plan []PlannedFile) []string {
+	var roots []string
+	seen := map[string]bool{}
+	for _, f := range plan {
+		if !seen[f.Root] {
+			seen[f.Root] = true
+			roots = append(roots, f.Root)
+		}
+	}
+	return roots
+}
+
+// pathPlanner plans the path arguments of a run one at a time, sharing what
+// their walks have in common.
+type pathPlanner struct {
+	// pipeline is the filter pipeline every walk starts from, left as it is
+	pipeline *filterPipeline
+	// mon is reported every candidate, or is nil
+	mon *longRunMonitor
+	// snap holds the directories to walk instead of the disk, or is nil
+	snap *treeSnapshot
+}
+
+// planPath applies the filter pipeline to root and, for directories, everything
+// beneath it. rel is root relative to the directory patterns are matched against.
+func (pl *pathPlanner) planPath(ctx context.Context, root string, origin Origin, rel string) ([]PlannedFile, error) {
+	path, err := walkRoot(root)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	abs := absPath(path)
+	var files []PlannedFile
+	pipeline := pl.pipeline.fork()
+	// decide records the pipeline's decision and prunes skipped directories from the walk
+	decide := func(c candidate) error {
+		reason := pipeline.decide(c)
+		if c.info.IsDir() && reason == "" {
+			return nil
+		}
+		f := PlannedFile{
+			Path:        c.path,
+			DisplayPath: c.path,
+			Root:        root,
+			Submodule:   pipeline.currentSubmodule(),
+			Origin:      c.origin,
+			Size:        c.info.Size(),
+			ModTime:     c.info.ModTime(),
+			Mode:        c.info.Mode(),
+			IsDir:       c.info.IsDir(),
+			Included:    reason == "",
+			Reason:      reason,
+		}
+		files = append(files, f)
+		if err := pl.mon.scan(f); err != nil {
+			return err
+		}
+		if reason != "" && c.info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
+	if !info.IsDir() {
+		return files, decide(candidate{path: path, info: info, origin: origin, abs: abs, rel: rel})
+	}
+
+	pipeline.submodules = newSubmoduleTracker(path, pipeline.jail)
+	err = pl.snap.walk(path, pipeline.config.FollowSymlinks, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		c := candidate{path: filePath, info: info, origin: OriginWalk}
+		if filePath == path {
+			// The directory itself was named directly
+			c.origin = origin
+			c.abs, c.rel = abs, rel
+			return decide(c)
+		}
+		if c.rel, err = filepath.Rel(path, filePath); err != nil {
+			log.Warnf("Warning: Could not get relative path for %s: %v", filePath, err)
+			c.rel = filePath
+			c.abs = absPath(filePath)
+		} else {
+			c.abs = filepath.Join(abs, c.rel)
+		}
+		return decide(c)
+	})
+	return files, err
+}
@@ -0,0 +1,96 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestPlan(t *testing.T) {
+	cfg := config.Config{
+		Paths:          []string{"testdata/test_project", "testdata/file1.txt"},
+		Extensions:     []string{".go", ".txt"},
+		IgnorePatterns: []string{"temp/"},
+	}
+
+	included, err := Plan(context.Background(), cfg, false)
+	require.NoError(t, err)
+	for i := range included {
+		// Modification times and modes depend on the checkout, so only check that they are recorded
+		assert.False(t, included[i].ModTime.IsZero(), included[i].Path)
+		assert.True(t, included[i].Mode.IsRegular() && included[i].Mode.Perm() != 0, included[i].Path)
+		included[i].ModTime, included[i].Mode = time.Time{}, 0
+	}
+	assert.Equal(t, []PlannedFile{
+		{Path: "testdata/file1.txt", DisplayPath: "testdata/file1.txt", Root: "testdata/file1.txt", Size: 20, Origin: OriginArg, Included: true},
+		{Path: "testdata/test_project/docs/README.txt", DisplayPath: "testdata/test_project/docs/README.txt", Root: "testdata/test_project", Size: 11, Origin: OriginWalk, Included: true},
+		{Path: "testdata/test_project/src/main.go", DisplayPath: "testdata/test_project/src/main.go", Root: "testdata/test_project", Size: 29, Origin: OriginWalk, Included: true},
+	}, included)
+
+	all, err := Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range all {
+		if !f.Included {
+			reasons[f.Path] = f.Reason
+		}
+	}
+	assert.Equal(t, map[string]SkipReason{
+		"testdata/test_project/.gitignore": SkipHidden,
+		"testdata/test_project/.hidden.go": SkipHidden,
+		"testdata/test_project/script.py":  SkipExtension,
+		"testdata/test_project/temp":       SkipIgnore,
+	}, reasons)
+}
+
+func TestPlanCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Plan(ctx, config.Config{Paths: []string{"testdata"}}, false)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGenerateWithPlanMatchesGenerate(t *testing.T) {
+	configs := map[string]config.Config{
+		"plain": {
+			Paths:      []string{"testdata/test_project", "testdata/file2.txt"},
+			Extensions: []string{".go", ".txt", ".py"},
+		},
+		"claude xml with gitignore": {
+			Paths:           []string{"testdata/test_project", "testdata/file3.txt"},
+			ClaudeXML:       true,
+			IgnoreGitignore: true,
+		},
+		"markdown with line numbers and ignores": {
+			Paths:          []string{"testdata"},
+			Markdown:       true,
+			LineNumbers:    true,
+			IncludeHidden:  true,
+			IgnorePatterns: []string{"*.dat", "export_ignore/"},
+		},
+	}
+
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			var direct bytes.Buffer
+			directSummary, err := Generate(context.Background(), cfg, &direct, nil)
+			require.NoError(t, err)
+
+			for _, includeSkipped := range []bool{false, true} {
+				plan, err := Plan(context.Background(), cfg, includeSkipped)
+				require.NoError(t, err)
+
+				var planned bytes.Buffer
+				plannedSummary, err := Generate(context.Background(), cfg, &planned, plan)
+				require.NoError(t, err)
+				assert.Equal(t, direct.String(), planned.String())
+				assert.Equal(t, directSummary, plannedSummary)
+			}
+		})
+	}
+}
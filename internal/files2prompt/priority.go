@@ -0,0 +1,16 @@
+package files2prompt
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// lowPriorityNice is the nice value --low-priority runs at.
+const lowPriorityNice = 10
+
+// lowerPriorityBestEffort lowers the priority of the process for
+// --low-priority, logging rather than failing where the platform refuses.
+func lowerPriorityBestEffort() {
+	if err := lowerPriority(); err != nil {
+		log.Debugf("--low-priority: could not lower the process priority: %v", err)
+	}
+}
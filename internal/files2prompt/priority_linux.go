@@ -0,0 +1,23 @@
+package files2prompt
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ioprioIdle is the I/O scheduling class and level of a process that only
+// gets the disk when nothing else wants it, as ionice -c 3 sets.
+const ioprioIdle = 3 << 13
+
+// lowerPriority lowers the CPU priority of the process as nice 10 does, and
+// its I/O priority as ionice -c 3 does.
+func lowerPriority() error {
+	errNice := syscall.Setpriority(syscall.PRIO_PROCESS, 0, lowPriorityNice)
+	// ioprio_set(IOPRIO_WHO_PROCESS, 0, ...)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, 1, 0, ioprioIdle)
+	var errIO error
+	if errno != 0 {
+		errIO = errno
+	}
+	return errors.Join(errNice, errIO)
+}
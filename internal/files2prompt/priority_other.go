@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package files2prompt
+
+import "errors"
+
+// lowerPriority reports that the platform has no priority to lower.
+func lowerPriority() error {
+	return errors.New("not supported on this platform")
+}
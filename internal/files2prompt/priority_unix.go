@@ -0,0 +1,11 @@
+//go:build unix && !linux
+
+package files2prompt
+
+import "syscall"
+
+// lowerPriority lowers the CPU priority of the process as nice 10 does.
+// Where the scheduler ties disk access to it, the reads slow down too.
+func lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, lowPriorityNice)
+}
@@ -0,0 +1,20 @@
+package files2prompt
+
+import "syscall"
+
+// processModeBackgroundBegin lowers the CPU, I/O and memory priority of the
+// calling process together.
+const processModeBackgroundBegin = 0x00100000
+
+// lowerPriority puts the process in background processing mode.
+func lowerPriority() error {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return err
+	}
+	r, _, err := syscall.NewLazyDLL("kernel32.dll").NewProc("SetPriorityClass").Call(uintptr(process), processModeBackgroundBegin)
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package files2prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// progressInterval is the least time between two --progress lines of a phase.
+var progressInterval = time.Second
+
+// precountBudget is how long the --progress pre-count may walk before it gives
+// up, leaving the progress of the walk indeterminate.
+var precountBudget = 2 * time.Second
+
+// progressEstimate is how many files, and bytes of them, a run is expected to emit.
+type progressEstimate struct {
+	files int
+	bytes int64
+}
+
+// precount estimates what a run of config emits from the names and sizes of
+// the files alone: it plans the run with the filters that read content left
+// out, so --grep, generated-code headers and --collapse-generated-siblings
+// do not narrow it. It reports false without an estimate when the walk takes
+// longer than precountBudget or fails.
+func precount(ctx context.Context, config config.Config) (progressEstimate, bool) {
+	ctx, cancel := context.WithTimeout(ctx, precountBudget)
+	defer cancel()
+	config.Grep = ""
+	config.IncludeGenerated = true
+	config.CollapseSiblings = false
+
+	// The planning proper reports what there is to say about the paths
+	out := log.StandardLogger().Out
+	log.SetOutput(io.Discard)
+	plan, _, err := planFiles(ctx, config, nil, nil)
+	log.SetOutput(out)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Debugf("Pre-count gave up after %s; the walk's progress is indeterminate", precountBudget)
+		} else {
+			log.Debugf("Pre-count failed: %v", err)
+		}
+		return progressEstimate{}, false
+	}
+	estimate := planEstimate(plan)
+	log.Debugf("Pre-count expects %d files, %s", estimate.files, formatBytes(estimate.bytes))
+	return estimate, true
+}
+
+// planEstimate returns the number and total size of the files plan includes.
+func planEstimate(plan []PlannedFile) progressEstimate {
+	var estimate progressEstimate
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			estimate.files++
+			estimate.bytes += f.Size
+		}
+	}
+	return estimate
+}
+
+// progressReport writes the --progress lines of a run to w: while the walk
+// selects files, out of the pre-count's estimate when there is one, and while
+// the selected files are emitted, out of the plan. A nil *progressReport
+// reports nothing.
+type progressReport struct {
+	w   io.Writer
+	now func() time.Time
+	// phase names what is being counted, and last is when its latest line was written
+	phase string
+	last  time.Time
+	done  progressEstimate
+	// total is the estimate of the phase, if known
+	total    progressEstimate
+	hasTotal bool
+}
+
+// newProgressReport returns the report of --progress, or nil without it.
+func newProgressReport(config config.Config) *progressReport {
+	if !config.Progress {
+		return nil
+	}
+	return &progressReport{w: osStderr, now: time.Now}
+}
+
+// start begins phase, reporting it out of total when hasTotal is set.
+func (p *progressReport) start(phase string, total progressEstimate, hasTotal bool) {
+	if p == nil {
+		return
+	}
+	p.phase, p.total, p.hasTotal = phase, total, hasTotal
+	p.done = progressEstimate{}
+	p.last = p.now()
+}
+
+// add counts a file of size bytes, writing a line once progressInterval has
+// passed since the last one.
+func (p *progressReport) add(size int64) {
+	if p == nil {
+		return
+	}
+	p.done.files++
+	p.done.bytes += size
+	if now := p.now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.write()
+	}
+}
+
+// finish writes the final line of the phase.
+func (p *progressReport) finish() {
+	if p == nil {
+		return
+	}
+	p.write()
+}
+
+// write writes a line such as "Emitting: 42% (1,234/2,950 files, 1.1 MiB/2.5
+// MiB)", or "Scanning: 1,234 files, 1.1 MiB" without an estimate.
+func (p *progressReport) write() {
+	if !p.hasTotal {
+		fmt.Fprintf(p.w, "%s: %s %s, %s\n", p.phase, formatCount(p.done.files), plural(p.done.files, "file", "files"), formatBytes(p.done.bytes))
+		return
+	}
+	percent := 100
+	if p.total.files > 0 {
+		// Late filters leave the walk short of the estimate, and a tree that
+		// grew since the pre-count can take it past
+		percent = min(100, p.done.files*100/p.total.files)
+	}
+	fmt.Fprintf(p.w, "%s: %d%% (%s/%s %s, %s/%s)\n", p.phase, percent, formatCount(p.done.files), formatCount(p.total.files),
+		plural(p.total.files, "file", "files"), formatBytes(p.done.bytes), formatBytes(p.total.bytes))
+}
+
+// formatCount renders n with commas between groups of three digits, e.g. "2,950".
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
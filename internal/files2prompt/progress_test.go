@@ -0,0 +1,98 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestPrecount(t *testing.T) {
+	// Without filters that read content, the estimate is the exact count
+	for _, cfg := range []config.Config{
+		{Paths: []string{"testdata/test_project"}},
+		{Paths: []string{"testdata"}, Extensions: []string{".go", ".txt"}, IgnorePatterns: []string{"temp/"}},
+		{Paths: []string{"testdata"}, MaxFileSize: 20, MaxDepth: 2},
+	} {
+		planned, err := Plan(context.Background(), cfg, false)
+		require.NoError(t, err)
+		require.NotEmpty(t, planned)
+		estimate, ok := precount(context.Background(), cfg)
+		require.True(t, ok)
+		assert.Equal(t, planEstimate(planned), estimate, "%v", cfg.Paths)
+	}
+
+	// --grep only narrows the walk, so it is left out of the estimate
+	cfg := config.Config{Paths: []string{"testdata/test_project"}, Grep: "package main"}
+	planned, err := Plan(context.Background(), cfg, false)
+	require.NoError(t, err)
+	estimate, ok := precount(context.Background(), cfg)
+	require.True(t, ok)
+	assert.Equal(t, 1, planEstimate(planned).files)
+	assert.Equal(t, 4, estimate.files)
+
+	t.Run("over budget", func(t *testing.T) {
+		budget := precountBudget
+		precountBudget = 0
+		t.Cleanup(func() { precountBudget = budget })
+		_, ok := precount(context.Background(), config.Config{Paths: []string{"testdata"}})
+		assert.False(t, ok)
+	})
+}
+
+func TestProgressReport(t *testing.T) {
+	var out bytes.Buffer
+	clock := time.Unix(0, 0)
+	p := &progressReport{w: &out, now: func() time.Time { return clock }}
+
+	p.start("Scanning", progressEstimate{files: 2950, bytes: 3 << 20}, true)
+	for range 1233 {
+		p.add(1 << 10)
+	}
+	assert.Empty(t, out.String())
+	clock = clock.Add(progressInterval)
+	p.add(1 << 10)
+	assert.Equal(t, "Scanning: 41% (1,234/2,950 files, 1.2 MiB/3.0 MiB)\n", out.String())
+	p.add(1 << 10)
+	p.finish()
+	assert.Equal(t, "Scanning: 41% (1,234/2,950 files, 1.2 MiB/3.0 MiB)\nScanning: 41% (1,235/2,950 files, 1.2 MiB/3.0 MiB)\n", out.String())
+
+	out.Reset()
+	p.start("Scanning", progressEstimate{}, false)
+	p.add(512)
+	p.finish()
+	assert.Equal(t, "Scanning: 1 file, 512 B\n", out.String())
+
+	var nilReport *progressReport
+	nilReport.start("Emitting", progressEstimate{}, true)
+	nilReport.add(1)
+	nilReport.finish()
+
+	assert.Equal(t, "0", formatCount(0))
+	assert.Equal(t, "999", formatCount(999))
+	assert.Equal(t, "1,000", formatCount(1000))
+	assert.Equal(t, "1,234,567", formatCount(1234567))
+}
+
+func TestGenerateProgress(t *testing.T) {
+	var stderr bytes.Buffer
+	originalStderr := osStderr
+	osStderr = &stderr
+	t.Cleanup(func() { osStderr = originalStderr })
+
+	cfg := config.Config{Paths: []string{"testdata/test_project"}, Extensions: []string{".go", ".txt"}, Progress: true}
+	_, err := Generate(context.Background(), cfg, io.Discard, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Scanning: 100% (3/3 files, 49 B/49 B)\nEmitting: 100% (3/3 files, 49 B/49 B)\n", stderr.String())
+
+	stderr.Reset()
+	cfg.NoPrecount = true
+	_, err = Generate(context.Background(), cfg, io.Discard, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Scanning: 3 files, 49 B\nEmitting: 100% (3/3 files, 49 B/49 B)\n", stderr.String())
+}
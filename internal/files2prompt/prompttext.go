@@ -0,0 +1,110 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// promptText is the free-form text of --prefix and --suffix, written before
+// the first document and after the last.
+type promptText struct {
+	prefix, suffix string
+	// inside places the text within the Claude XML root element rather than
+	// around it
+	inside bool
+}
+
+// newPromptText returns the text of --prefix or --prefix-file and of --suffix
+// or --suffix-file, reading the files so that a missing one is reported before
+// anything is walked.
+func newPromptText(config config.Config) (promptText, error) {
+	var text promptText
+	for _, option := range []struct {
+		flag, fileFlag string
+		inline, path   string
+		dst            *string
+	}{
+		{"--prefix", "--prefix-file", config.Prefix, config.PrefixFile, &text.prefix},
+		{"--suffix", "--suffix-file", config.Suffix, config.SuffixFile, &text.suffix},
+	} {
+		switch {
+		case option.inline != "" && option.path != "":
+			return promptText{}, fmt.Errorf("%s cannot be combined with %s", option.flag, option.fileFlag)
+		case option.path != "":
+			data, err := os.ReadFile(option.path) // #nosec G304
+			if err != nil {
+				return promptText{}, fmt.Errorf("%s: %v", option.fileFlag, err)
+			}
+			*option.dst = string(data)
+		default:
+			*option.dst = option.inline
+		}
+		if *option.dst != "" {
+			if flag := promptFormatFlag(config); flag != "" {
+				return promptText{}, fmt.Errorf("%s cannot be combined with %s", promptFlag(option.flag, option.path), flag)
+			}
+		}
+	}
+	if config.PrefixInsideWrapper {
+		if !config.ClaudeXML {
+			return promptText{}, errors.New("--prefix-inside-wrapper requires --cxml")
+		}
+		text.inside = true
+	}
+	// Newlines are settled where the text is written
+	text.prefix = strings.TrimRight(text.prefix, "\r\n")
+	text.suffix = strings.TrimRight(strings.TrimLeft(text.suffix, "\r\n"), "\r\n")
+	return text, nil
+}
+
+// promptFlag returns the flag text was given with: flag, or its file variant
+// when path is set.
+func promptFlag(flag, path string) string {
+	if path != "" {
+		return flag + "-file"
+	}
+	return flag
+}
+
+// promptFormatFlag returns the flag of the output format free-form text cannot
+// be added to without breaking it, or "".
+func promptFormatFlag(config config.Config) string {
+	for _, option := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--html", config.HTML},
+		{"--jsonl", config.JSONL},
+		{"--openai", config.OpenAI},
+		{"--format " + config.Format, config.Format != ""},
+	} {
+		if option.set {
+			return option.flag
+		}
+	}
+	return ""
+}
+
+// writePrefix writes the prefix, if any, followed by a blank line.
+func (p promptText) writePrefix(w *ledger) {
+	if p.prefix != "" {
+		_, _ = w.Write([]byte(p.prefix + "\n\n"))
+	}
+}
+
+// writeSuffix writes the suffix, if any, after a blank line: capture holds
+// how many newlines the output written so far ends in.
+func (p promptText) writeSuffix(w *ledger, capture *captureWriter) {
+	if p.suffix == "" {
+		return
+	}
+	gap := ""
+	if capture.wrote {
+		gap = strings.Repeat("\n", max(0, 2-capture.newlines))
+	}
+	_, _ = w.Write([]byte(gap + p.suffix + "\n"))
+}
@@ -0,0 +1,94 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestPromptText(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.txt":      "a\n",
+		"b.txt":      "b",
+		"prefix.txt": "Review this code for security issues.\n\n\n",
+		"suffix.txt": "\nList the issues by severity.",
+	})
+	t.Chdir(dir)
+	run := func(t *testing.T, cfg config.Config) string {
+		t.Helper()
+		cfg.Paths = []string{"a.txt", "b.txt"}
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), cfg, &buf, nil)
+		require.NoError(t, err)
+		return buf.String()
+	}
+	plain := "a.txt\n---\na\n---\n\nb.txt\n---\nb\n---\n\n"
+	cxml := "<document index=\"1\">\n<source>a.txt</source>\n<document_content>\na\n</document_content>\n</document>\n" +
+		"<document index=\"2\">\n<source>b.txt</source>\n<document_content>\nb\n</document_content>\n</document>\n"
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{"inline", config.Config{Prefix: "Review this code.", Suffix: "Thanks."},
+			"Review this code.\n\n" + plain + "Thanks.\n"},
+		{"inline newlines", config.Config{Prefix: "Review this code.\n\n\n\n", Suffix: "\n\nThanks.\n\n"},
+			// However many newlines the text has, a single blank line separates it from the documents
+			"Review this code.\n\n" + plain + "Thanks.\n"},
+		{"files", config.Config{PrefixFile: "prefix.txt", SuffixFile: "suffix.txt"},
+			"Review this code for security issues.\n\n" + plain + "List the issues by severity.\n"},
+		{"prefix only", config.Config{Prefix: "Review this code."}, "Review this code.\n\n" + plain},
+		{"cxml around", config.Config{ClaudeXML: true, Prefix: "Review this code.", Suffix: "Thanks."},
+			"Review this code.\n\n<documents>\n" + cxml + "</documents>\n\nThanks.\n"},
+		{"cxml inside", config.Config{ClaudeXML: true, PrefixInsideWrapper: true, Prefix: "Review this code.", Suffix: "Thanks."},
+			"<documents>\nReview this code.\n\n" + cxml + "\nThanks.\n</documents>\n"},
+		{"markdown", config.Config{Markdown: true, MarkdownStyle: string(MarkdownPath), Prefix: "Review this code.", Suffix: "Thanks."},
+			"Review this code.\n\na.txt\n```\na\n```\nb.txt\n```\nb\n```\n\nThanks.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, run(t, tt.config))
+		})
+	}
+}
+
+func TestPromptTextErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"prefix.txt": "Review\n", "a.txt": "a\n"})
+	t.Chdir(dir)
+
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"both prefixes", config.Config{Prefix: "Review", PrefixFile: "prefix.txt"}, "--prefix cannot be combined with --prefix-file"},
+		{"both suffixes", config.Config{Suffix: "Thanks", SuffixFile: "prefix.txt"}, "--suffix cannot be combined with --suffix-file"},
+		{"missing file", config.Config{SuffixFile: "missing.txt"}, "--suffix-file: open missing.txt: no such file or directory"},
+		{"inside without cxml", config.Config{Prefix: "Review", PrefixInsideWrapper: true}, "--prefix-inside-wrapper requires --cxml"},
+		{"with jsonl", config.Config{Prefix: "Review", JSONL: true}, "--prefix cannot be combined with --jsonl"},
+		{"file with openai", config.Config{PrefixFile: "prefix.txt", OpenAI: true}, "--prefix-file cannot be combined with --openai"},
+		{"with digest", config.Config{Suffix: "Thanks", Format: string(FormatDigest)}, "--suffix cannot be combined with --format digest"},
+		{"with compat", config.Config{Prefix: "Review", Compat: string(CompatFilesToPrompt)}, "--compat files-to-prompt cannot be combined with --prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"a.txt"}
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	withStdout(t)
+	_, err := Run(config.Config{Paths: []string{"a.txt"}, Prefix: "Review", SplitBytes: 100, OutputFile: filepath.Join(t.TempDir(), "out.txt")})
+	assert.EqualError(t, err, "--split-bytes cannot be combined with --prefix")
+}
@@ -0,0 +1,37 @@
+package files2prompt
+
+import "time"
+
+// ProvenanceKind names the kind of source a document was read from.
+type ProvenanceKind string
+
+const (
+	// ProvenanceLocal is a file on disk, named by its path.
+	ProvenanceLocal ProvenanceKind = "local"
+	// ProvenanceArchive is a member of an archive.
+	ProvenanceArchive ProvenanceKind = "archive"
+	// ProvenanceURL is a download.
+	ProvenanceURL ProvenanceKind = "url"
+	// ProvenanceGit is a file read from a git ref.
+	ProvenanceGit ProvenanceKind = "git"
+)
+
+// Provenance records where the content of a document came from, for the
+// --metadata of structured output; the path it is shown under stays as it is.
+// The zero Provenance is a local file, which is what every input path the
+// planner walks reads.
+type Provenance struct {
+	Kind ProvenanceKind
+	// Source is the archive path, the URL origin, or REMOTE@REF, by Kind
+	Source string
+	// Retrieved is when a remote source was fetched, and zero otherwise
+	Retrieved time.Time
+}
+
+// String returns p as "local", "archive:PATH", "url:ORIGIN" or "git:REMOTE@REF".
+func (p Provenance) String() string {
+	if p.Kind == "" || p.Kind == ProvenanceLocal {
+		return string(ProvenanceLocal)
+	}
+	return string(p.Kind) + ":" + p.Source
+}
@@ -0,0 +1,89 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestProvenanceString(t *testing.T) {
+	tests := []struct {
+		provenance Provenance
+		expected   string
+	}{
+		{Provenance{}, "local"},
+		{Provenance{Kind: ProvenanceLocal}, "local"},
+		{Provenance{Kind: ProvenanceArchive, Source: "dist/src.tar.gz"}, "archive:dist/src.tar.gz"},
+		{Provenance{Kind: ProvenanceURL, Source: "https://example.com"}, "url:https://example.com"},
+		{Provenance{Kind: ProvenanceGit, Source: "origin@v1.2.0"}, "git:origin@v1.2.0"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.provenance.String())
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"local.go": "a\n", "member.go": "b\n", "fetched.go": "c\n"})
+	t.Chdir(dir)
+	retrieved := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	sources := map[string]Provenance{
+		"member.go":  {Kind: ProvenanceArchive, Source: "dist/src.tar.gz"},
+		"fetched.go": {Kind: ProvenanceURL, Source: "https://example.com", Retrieved: retrieved},
+	}
+
+	// One run mixing documents of every source, as the planner would record them
+	combined := func(t *testing.T, cfg config.Config) string {
+		t.Helper()
+		cfg.Paths, cfg.IncludeMetadata = []string{"local.go", "member.go", "fetched.go"}, true
+		plan, err := Plan(context.Background(), cfg, false)
+		require.NoError(t, err)
+		for i := range plan {
+			plan[i].Provenance = sources[plan[i].DisplayPath]
+		}
+		var buf bytes.Buffer
+		_, err = Generate(context.Background(), cfg, &buf, plan)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("cxml", func(t *testing.T) {
+		out := combined(t, config.Config{ClaudeXML: true})
+		assert.Contains(t, out, " provenance=\"local\">\n<source>local.go</source>")
+		assert.Contains(t, out, " provenance=\"archive:dist/src.tar.gz\">\n<source>member.go</source>")
+		assert.Contains(t, out, " provenance=\"url:https://example.com\" retrieved=\"2024-08-01T12:00:00Z\">\n<source>fetched.go</source>")
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		docs := jsonlLines(t, combined(t, config.Config{JSONL: true}))
+		require.Len(t, docs, 3)
+		got := map[string][2]any{}
+		for _, doc := range docs {
+			got[doc["path"].(string)] = [2]any{doc["provenance"], doc["retrieved"]}
+		}
+		assert.Equal(t, map[string][2]any{
+			"local.go":   {"local", nil},
+			"member.go":  {"archive:dist/src.tar.gz", nil},
+			"fetched.go": {"url:https://example.com", "2024-08-01T12:00:00Z"},
+		}, got)
+	})
+
+	t.Run("paths stay clean", func(t *testing.T) {
+		out := combined(t, config.Config{})
+		assert.NotContains(t, out, "archive:")
+		assert.NotContains(t, out, "url:")
+		assert.Contains(t, out, "\nmember.go\n<!-- 2 B, 1 line, modified ")
+	})
+
+	t.Run("reproducible", func(t *testing.T) {
+		t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+		out := combined(t, config.Config{JSONL: true, Reproducible: true})
+		assert.Contains(t, out, `"provenance":"url:https://example.com","retrieved":"2023-11-14T22:13:20Z"}`)
+	})
+}
@@ -0,0 +1,97 @@
+package files2prompt
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// readAheadPerWorker is how many finished reads each worker may hold before the
+// emitter takes them, bounding the memory used by reads waiting their turn.
+const readAheadPerWorker = 4
+
+// fileRead is the outcome of reading a planned file: its content, or for a
+// file to be streamed, only its scan.
+type fileRead struct {
+	content []byte
+	scan    *contentScan
+	// cut reports that the file reported a size of 0 and was cut off at the zero-size limit
+	cut bool
+	err error
+}
+
+// concurrency returns the number of files read at once selected by config:
+// one per CPU unless set.
+func concurrency(config config.Config) (int, error) {
+	switch {
+	case config.Concurrency < 0:
+		return 0, fmt.Errorf("invalid --concurrency %d: use a positive number, or 0 for one per CPU", config.Concurrency)
+	case config.Concurrency == 0:
+		return runtime.NumCPU(), nil
+	}
+	return config.Concurrency, nil
+}
+
+// readAhead reads a list of files with a pool of workers while they are being
+// emitted, handing the reads back strictly in list order so that the output,
+// including Claude XML indexes, does not depend on which read finishes first.
+type readAhead struct {
+	// order holds a channel per started read, in list order
+	order    chan chan fileRead
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newReadAhead starts reading paths with workers goroutines, failing any file
+// larger than limit and only scanning those larger than above, as readFile does.
+// Together the workers read no faster than throttle allows.
+func newReadAhead(paths []string, workers int, limit, above, zeroSize int64, throttle *readThrottle) *readAhead {
+	r := &readAhead{
+		order: make(chan chan fileRead, workers*readAheadPerWorker),
+		stop:  make(chan struct{}),
+	}
+	type job struct {
+		path   string
+		result chan fileRead
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for _, path := range paths {
+			result := make(chan fileRead, 1)
+			// Blocks once the emitter has fallen far enough behind
+			select {
+			case r.order <- result:
+			case <-r.stop:
+				return
+			}
+			select {
+			case jobs <- job{path, result}:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	for range workers {
+		go func() {
+			for j := range jobs {
+				j.result <- readFile(j.path, limit, above, zeroSize, throttle)
+			}
+		}()
+	}
+	return r
+}
+
+// next waits for the read of the next file in the list. It must be called at
+// most once per path.
+func (r *readAhead) next() fileRead {
+	return <-<-r.order
+}
+
+// close stops reading files that have not been started. The reads in progress
+// finish in the background.
+func (r *readAhead) close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
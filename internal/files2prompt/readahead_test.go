@@ -0,0 +1,117 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// writeSyntheticTree writes n small Go files spread over nested directories.
+func writeSyntheticTree(tb testing.TB, root string, n int) {
+	tb.Helper()
+	for i := range n {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%02d", i%50), fmt.Sprintf("sub%02d", i%7))
+		require.NoError(tb, os.MkdirAll(dir, 0o750))
+		content := fmt.Sprintf("package p%d\n\n// File %d.\nfunc F%d() int { return %d }\n", i, i, i, i)
+		require.NoError(tb, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%05d.go", i)), []byte(content), 0o600))
+	}
+}
+
+func TestReadAheadOrder(t *testing.T) {
+	root := t.TempDir()
+	var paths []string
+	for i := range 200 {
+		path := filepath.Join(root, fmt.Sprintf("%03d.go", i))
+		// Vary the sizes so that reads finish out of order
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat(fmt.Sprint(i), (i*37)%500)), 0o600))
+		paths = append(paths, path)
+	}
+	paths = append(paths, filepath.Join(root, "missing.go"))
+
+	reads := newReadAhead(paths, 8, readLimit(config.Config{}), 0, DefaultZeroSizeLimit, nil)
+	defer reads.close()
+	for i := range 200 {
+		read := reads.next()
+		require.NoError(t, read.err)
+		assert.Equal(t, strings.Repeat(fmt.Sprint(i), (i*37)%500), string(read.content), "file %d", i)
+	}
+	assert.Error(t, reads.next().err)
+}
+
+func TestReadAheadCloseEarly(t *testing.T) {
+	root := t.TempDir()
+	writeSyntheticTree(t, root, 100)
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{root}}, false)
+	require.NoError(t, err)
+	var paths []string
+	for _, f := range plan {
+		paths = append(paths, f.Path)
+	}
+
+	reads := newReadAhead(paths, 2, readLimit(config.Config{}), 0, DefaultZeroSizeLimit, nil)
+	require.NoError(t, reads.next().err)
+	reads.close()
+	reads.close()
+}
+
+func TestGenerateConcurrency(t *testing.T) {
+	root := t.TempDir()
+	writeSyntheticTree(t, root, 300)
+
+	generate := func(cfg config.Config) string {
+		t.Helper()
+		cfg.Paths = []string{root}
+		var out bytes.Buffer
+		_, err := Generate(context.Background(), cfg, &out, nil)
+		require.NoError(t, err)
+		return out.String()
+	}
+	for _, cfg := range []config.Config{{ClaudeXML: true}, {Markdown: true, LineNumbers: true}, {Compat: string(CompatFilesToPrompt), ClaudeXML: true}} {
+		sequential := cfg
+		sequential.Concurrency = 1
+		want := generate(sequential)
+		for _, workers := range []int{2, 16} {
+			cfg.Concurrency = workers
+			assert.Equal(t, want, generate(cfg), "%d workers", workers)
+		}
+	}
+
+	// Claude XML indexes follow the emission order
+	out := generate(config.Config{ClaudeXML: true, Concurrency: 16})
+	for i := 1; i <= 300; i++ {
+		require.Contains(t, out, fmt.Sprintf("<document index=\"%d\">", i))
+	}
+	assert.Less(t, strings.Index(out, "<document index=\"1\">"), strings.Index(out, "<document index=\"2\">"))
+
+	_, err := Generate(context.Background(), config.Config{Paths: []string{root}, Concurrency: -1}, &bytes.Buffer{}, nil)
+	assert.EqualError(t, err, "invalid --concurrency -1: use a positive number, or 0 for one per CPU")
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	root := b.TempDir()
+	writeSyntheticTree(b, root, 10000)
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{root}}, false)
+	require.NoError(b, err)
+
+	// One worker reads sequentially, just ahead of rendering
+	for _, workers := range []int{1, max(runtime.NumCPU(), 8)} {
+		b.Run(fmt.Sprintf("concurrency=%d", workers), func(b *testing.B) {
+			cfg := config.Config{Paths: []string{root}, ClaudeXML: true, Concurrency: workers}
+			for b.Loop() {
+				if _, err := Generate(context.Background(), cfg, &bytes.Buffer{}, plan); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
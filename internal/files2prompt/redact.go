@@ -0,0 +1,131 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// redaction is a kind of secret --redact replaces. When pattern has a
+// capture group, only what the first group matches is replaced, so that an
+// assignment keeps its name.
+type redaction struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// redactions are the credential shapes --redact knows. They look for the
+// fixed prefixes and lengths the issuers use, so that ordinary identifiers
+// are left alone.
+var redactions = []redaction{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	// A value in brackets has been redacted already
+	{"api-key", regexp.MustCompile(`(?i)\b[\w-]*(?:api[_-]?key|secret|token|passw(?:or)?d)[\w-]*["']?\s*[:=]\s*["']([^"'\s\[][^"'\s]{7,})["']`)},
+}
+
+// redactor replaces the secrets in file contents, standard input and command
+// output with [REDACTED:<kind>], with --redact. A nil *redactor changes nothing.
+type redactor struct {
+	rules []redaction
+}
+
+// newRedactor returns the redactor of config, or nil without --redact. The
+// --redact-pattern regexps are matched after the built-in ones, as kind
+// "pattern".
+func newRedactor(config config.Config) (*redactor, error) {
+	if !config.Redact {
+		if len(config.RedactPatterns) > 0 {
+			return nil, errors.New("--redact-pattern requires --redact")
+		}
+		return nil, nil
+	}
+	r := &redactor{rules: slices.Clone(redactions)}
+	for _, p := range config.RedactPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --redact-pattern %q: %v", p, err)
+		}
+		r.rules = append(r.rules, redaction{"pattern", re})
+	}
+	return r, nil
+}
+
+// content returns content with its secrets redacted, logging how many of each
+// kind were found in the document shown as displayPath. A redacted secret
+// keeps its line breaks, so the lines after it keep their numbers.
+func (r *redactor) content(displayPath, content string) string {
+	if r == nil {
+		return content
+	}
+	content, counts := r.replace(content)
+	if len(counts) > 0 {
+		log.Warnf("Redacted %s in %s", redactionCounts(r.rules, counts), displayPath)
+	}
+	return content
+}
+
+// label returns the label of a document, such as a --cmd command line, with
+// its secrets redacted, and without logging them.
+func (r *redactor) label(label string) string {
+	if r == nil {
+		return label
+	}
+	label, _ = r.replace(label)
+	return label
+}
+
+// replace returns content with its secrets redacted, and how many of each
+// kind it held.
+func (r *redactor) replace(content string) (string, map[string]int) {
+	counts := map[string]int{}
+	for _, rule := range r.rules {
+		matches := rule.pattern.FindAllStringSubmatchIndex(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) > 2 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if start == end {
+				continue
+			}
+			b.WriteString(content[last:start])
+			b.WriteString("[REDACTED:" + rule.kind + "]")
+			b.WriteString(strings.Repeat("\n", strings.Count(content[start:end], "\n")))
+			last = end
+			counts[rule.kind]++
+		}
+		b.WriteString(content[last:])
+		content = b.String()
+	}
+	return content, counts
+}
+
+// redactionCounts describes counts, by kind in the order of rules, e.g. "3
+// secrets (2 aws-access-key, 1 github-token)".
+func redactionCounts(rules []redaction, counts map[string]int) string {
+	total := 0
+	var kinds []string
+	for i, rule := range rules {
+		n := counts[rule.kind]
+		// The --redact-pattern rules share a kind
+		if n == 0 || slices.ContainsFunc(rules[:i], func(r redaction) bool { return r.kind == rule.kind }) {
+			continue
+		}
+		kinds = append(kinds, fmt.Sprintf("%d %s", n, rule.kind))
+		total += n
+	}
+	return fmt.Sprintf("%d %s (%s)", total, plural(total, "secret", "secrets"), strings.Join(kinds, ", "))
+}
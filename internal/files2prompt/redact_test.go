@@ -0,0 +1,107 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// The fake credentials are assembled here rather than spelled out, so that
+// secret scanners leave the repository alone.
+var (
+	fakeAWSKey    = "AKIA" + "IOSFODNN7EXAMPLE"
+	fakeGitHubPAT = "ghp" + "_" + strings.Repeat("a1B2", 9)
+	fakeSlackBot  = "xoxb" + "-123456789012-abcdefABCDEF"
+	fakeKeyBlock  = "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIEowIBAAKCAQEA\nq8Xh5lX9w2Y=\n-----END RSA " + "PRIVATE KEY-----"
+)
+
+func TestRedact(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"aws.env":          "AWS_ACCESS_KEY_ID=" + fakeAWSKey + "\nREGION=us-east-1\n",
+		"github.sh":        "export GH_TOKEN=" + fakeGitHubPAT + "\n",
+		"slack.yaml":       "webhook: https://hooks.slack.com\nbot: " + fakeSlackBot + "\n",
+		"deploy-notes.txt": fakeKeyBlock + "\n",
+		"settings.py": "api_key = \"0123456789abcdef\"\n" +
+			"DB_PASSWORD: 'hunter2hunter2'\n" +
+			"token = \"short\"\n" +
+			"slack_token = \"" + fakeSlackBot + "\"\n",
+		"clean.go": "package clean\n\nconst AKIA = \"not a key\"\n",
+	})
+
+	redacted := func(name string, cfg config.Config) string {
+		t.Helper()
+		cfg.Paths = []string{filepath.Join(dir, name)}
+		cfg.Redact = true
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), cfg, &buf, nil)
+		require.NoError(t, err)
+		return strings.TrimPrefix(buf.String(), filepath.Join(dir, name)+"\n---\n")
+	}
+	assert.Equal(t, "AWS_ACCESS_KEY_ID=[REDACTED:aws-access-key]\nREGION=us-east-1\n---\n\n", redacted("aws.env", config.Config{}))
+	assert.Equal(t, "export GH_TOKEN=[REDACTED:github-token]\n---\n\n", redacted("github.sh", config.Config{}))
+	assert.Equal(t, "webhook: https://hooks.slack.com\nbot: [REDACTED:slack-token]\n---\n\n", redacted("slack.yaml", config.Config{}))
+	assert.Equal(t, "api_key = \"[REDACTED:api-key]\"\n"+
+		"DB_PASSWORD: '[REDACTED:api-key]'\n"+
+		"token = \"short\"\n"+
+		"slack_token = \"[REDACTED:slack-token]\"\n---\n\n", redacted("settings.py", config.Config{}))
+	assert.Equal(t, "package clean\n\nconst AKIA = \"not a key\"\n---\n\n", redacted("clean.go", config.Config{}))
+
+	// The key block keeps its line breaks, so the line numbers after it are the file's
+	assert.Equal(t, "[REDACTED:private-key]\n\n\n\n---\n\n", redacted("deploy-notes.txt", config.Config{}))
+	writeFiles(t, dir, map[string]string{"config.rb": "KEY = <<~PEM\n" + fakeKeyBlock + "\nPEM\nputs KEY\n"})
+	assert.Equal(t, " 1 │ KEY = <<~PEM\n 2 │ [REDACTED:private-key]\n 3 │ \n 4 │ \n 5 │ \n 6 │ PEM\n 7 │ puts KEY\n---\n\n",
+		redacted("config.rb", config.Config{LineNumbers: true}))
+
+	t.Run("patterns", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{"internal.txt": "employee 4711\nhost db-7.corp.example.com\n"})
+		assert.Equal(t, "employee [REDACTED:pattern]\nhost [REDACTED:pattern].corp.example.com\n---\n\n",
+			redacted("internal.txt", config.Config{RedactPatterns: []string{`employee (\d+)`, `\bdb-\d+`}}))
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		hook := logtest.NewGlobal()
+		defer hook.Reset()
+		redacted("settings.py", config.Config{})
+		require.Len(t, hook.AllEntries(), 1)
+		assert.Equal(t, "Redacted 3 secrets (1 slack-token, 2 api-key) in "+filepath.Join(dir, "settings.py"), hook.LastEntry().Message)
+
+		hook.Reset()
+		redacted("clean.go", config.Config{})
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("stdin and commands", func(t *testing.T) {
+		for name, cfg := range map[string]config.Config{
+			"stdin":       {Paths: []string{"-"}, StdinContent: "AWS_ACCESS_KEY_ID=" + fakeAWSKey + "\n"},
+			"cmd":         {Commands: []string{"echo " + fakeGitHubPAT}},
+			"env-context": {EnvContextCmds: []string{"echo " + fakeSlackBot}},
+		} {
+			cfg.Redact = true
+			var buf bytes.Buffer
+			_, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err, name)
+			for _, secret := range []string{fakeAWSKey, fakeGitHubPAT, fakeSlackBot} {
+				assert.NotContains(t, buf.String(), secret, name)
+			}
+			assert.Contains(t, buf.String(), "[REDACTED:", name)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		_, err := newRedactor(config.Config{RedactPatterns: []string{"x"}})
+		assert.EqualError(t, err, "--redact-pattern requires --redact")
+		_, err = newRedactor(config.Config{Redact: true, RedactPatterns: []string{"("}})
+		assert.ErrorContains(t, err, `invalid --redact-pattern "(":`)
+		r, err := newRedactor(config.Config{})
+		require.NoError(t, err)
+		assert.Equal(t, fakeAWSKey, r.content("aws.env", fakeAWSKey))
+	})
+}
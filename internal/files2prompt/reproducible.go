@@ -0,0 +1,34 @@
+package files2prompt
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sourceDate returns the timestamp that --reproducible embeds in place of the
+// current or file modification times: $SOURCE_DATE_EPOCH when set, otherwise
+// the committer time of the latest commit of the git checkout containing dir.
+//
+// See https://reproducible-builds.org/specs/source-date-epoch/.
+func (e osEnv) sourceDate(dir string) (time.Time, error) {
+	if epoch := e.getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %v", epoch, err)
+		}
+		return time.Unix(secs, 0).UTC(), nil
+	}
+
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%ct").Output() // #nosec G204
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--reproducible needs SOURCE_DATE_EPOCH or a git checkout with at least one commit: %v", err)
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected git commit time %q: %v", strings.TrimSpace(string(out)), err)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
@@ -0,0 +1,128 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestSourceDate(t *testing.T) {
+	env := func(epoch string) osEnv {
+		return osEnv{getenv: func(name string) string {
+			if name == "SOURCE_DATE_EPOCH" {
+				return epoch
+			}
+			return ""
+		}}
+	}
+
+	got, err := env("1700000000").sourceDate(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC), got)
+
+	_, err = env("yesterday").sourceDate(t.TempDir())
+	assert.ErrorContains(t, err, `invalid SOURCE_DATE_EPOCH "yesterday"`)
+
+	_, err = env("").sourceDate(t.TempDir())
+	assert.ErrorContains(t, err, "--reproducible needs SOURCE_DATE_EPOCH or a git checkout")
+}
+
+func TestSourceDateFromGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/a.dat", []byte("a"), 0o600))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "a.dat"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_COMMITTER_DATE=2024-06-01T12:00:00Z", "GIT_AUTHOR_DATE=2024-06-01T12:00:00Z")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	got, err := osEnv{getenv: func(string) string { return "" }}.sourceDate(dir)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), got)
+}
+
+func TestReproducibleRunsAreByteIdentical(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	// Created out of order, so that neither creation nor directory order
+	// decides the output's
+	dir := t.TempDir()
+	for _, name := range []string{"zeta.go", "alpha.go", "sub/mid.go", "beta.go"} {
+		writeFiles(t, dir, map[string]string{name: "package " + strings.TrimSuffix(filepath.Base(name), ".go") + "\n"})
+	}
+	touch := func(when time.Time) {
+		t.Helper()
+		require.NoError(t, filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(path, when, when)
+		}))
+	}
+
+	configs := map[string]config.Config{
+		"plain":    {Paths: []string{"testdata/test_project", "testdata/file1.txt", dir}, IncludeMetadata: true, Reproducible: true},
+		"markdown": {Paths: []string{"testdata/test_project", dir}, Markdown: true, LineNumbers: true, IncludeMetadata: true, Reproducible: true},
+		"cxml":     {Paths: []string{"testdata/oversized", dir}, ClaudeXML: true, CXMLMaxDocBytes: 64, IncludeMetadata: true, Reproducible: true},
+		"parallel": {Paths: []string{dir}, Concurrency: 8, Tree: true, IncludeMetadata: true, Reproducible: true},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			var first, second bytes.Buffer
+			touch(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+			_, err := Generate(context.Background(), cfg, &first, nil)
+			require.NoError(t, err)
+			// The files change times between the runs, as on a fresh checkout
+			touch(time.Now())
+			_, err = Generate(context.Background(), cfg, &second, nil)
+			require.NoError(t, err)
+			out := first.String()
+			assert.NotEmpty(t, out)
+			assert.Equal(t, out, second.String())
+
+			if cfg.ClaudeXML {
+				assert.Contains(t, out, `mtime="2023-11-14T22:13:20Z"`)
+			} else {
+				assert.Contains(t, out, "modified 2023-11-14")
+			}
+			assert.NotContains(t, out, "2020-01-02")
+			var at []int
+			for _, name := range []string{"alpha.go", "beta.go", filepath.Join("sub", "mid.go"), "zeta.go"} {
+				at = append(at, strings.LastIndex(out, name))
+			}
+			assert.True(t, slices.IsSorted(at), "files out of order: %v", at)
+		})
+	}
+
+	// Without --reproducible the metadata tells the file's own time
+	cfg := configs["plain"]
+	cfg.Reproducible = false
+	touch(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	var out bytes.Buffer
+	_, err := Generate(context.Background(), cfg, &out, nil)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "modified 2020-01-02")
+
+	t.Setenv("SOURCE_DATE_EPOCH", "soon")
+	_, err = Generate(context.Background(), configs["plain"], &bytes.Buffer{}, nil)
+	assert.ErrorContains(t, err, "invalid SOURCE_DATE_EPOCH")
+}
@@ -0,0 +1,136 @@
+package files2prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// StatsFormat selects how the --stats report is written.
+type StatsFormat string
+
+// Stats formats accepted by --stats-format.
+const (
+	// StatsTable writes an aligned table for people.
+	StatsTable StatsFormat = "table"
+	// StatsJSON writes the report as indented JSON for scripts.
+	StatsJSON StatsFormat = "json"
+)
+
+// statsFormat returns the format selected by config, defaulting to StatsTable.
+func statsFormat(config config.Config) (StatsFormat, error) {
+	switch StatsFormat(config.StatsFormat) {
+	case "", StatsTable:
+		return StatsTable, nil
+	case StatsJSON:
+		return StatsJSON, nil
+	}
+	return "", fmt.Errorf("invalid --stats-format %q: use %s or %s", config.StatsFormat, StatsTable, StatsJSON)
+}
+
+// FileStats describes the content emitted for a single file. Tokens is
+// estimated as for Summary.Tokens.
+type FileStats struct {
+	Path   string `json:"path"`
+	Bytes  int64  `json:"bytes"`
+	Lines  int64  `json:"lines"`
+	Tokens int64  `json:"tokens"`
+}
+
+// RunStats is the --stats report: every emitted file in emission order, their
+// totals, and how many files and directories each filter skipped.
+type RunStats struct {
+	Files       []FileStats        `json:"files"`
+	Total       FileStats          `json:"total"`
+	Skipped     map[SkipReason]int `json:"skipped_files"`
+	SkippedDirs map[SkipReason]int `json:"skipped_directories"`
+}
+
+// newRunStats starts a report, tallying the skipped entries of plan.
+func newRunStats(plan []PlannedFile) *RunStats {
+	s := &RunStats{Files: []FileStats{}, Skipped: map[SkipReason]int{}, SkippedDirs: map[SkipReason]int{}}
+	for _, f := range plan {
+		switch {
+		case f.Included:
+		case f.IsDir:
+			s.SkippedDirs[f.Reason]++
+		default:
+			s.Skipped[f.Reason]++
+		}
+	}
+	return s
+}
+
+// add records an emitted file. A nil *RunStats records nothing.
+func (s *RunStats) add(f FileStats) {
+	if s == nil {
+		return
+	}
+	s.Files = append(s.Files, f)
+	s.Total.Bytes += f.Bytes
+	s.Total.Lines += f.Lines
+	s.Total.Tokens += f.Tokens
+}
+
+// countLines returns the number of lines in content, counting a final line
+// without a newline.
+func countLines(content []byte) int64 {
+	n := int64(strings.Count(string(content), "\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// WriteRunStats writes stats in format.
+func WriteRunStats(w io.Writer, stats RunStats, format StatsFormat) error {
+	if format == StatsJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	bytesWidth, linesWidth, tokensWidth := len("BYTES"), len("LINES"), len("TOKENS")
+	for _, f := range append(stats.Files, stats.Total) {
+		bytesWidth = max(bytesWidth, len(fmt.Sprint(f.Bytes)))
+		linesWidth = max(linesWidth, len(fmt.Sprint(f.Lines)))
+		tokensWidth = max(tokensWidth, len(fmt.Sprint(f.Tokens)))
+	}
+	row := func(b *strings.Builder, bytes, lines, tokens any, label string) {
+		fmt.Fprintf(b, "%*v  %*v  %*v  %s\n", bytesWidth, bytes, linesWidth, lines, tokensWidth, tokens, label)
+	}
+
+	var b strings.Builder
+	row(&b, "BYTES", "LINES", "TOKENS", "FILE")
+	for _, f := range stats.Files {
+		row(&b, f.Bytes, f.Lines, f.Tokens, f.Path)
+	}
+	n := len(stats.Files)
+	row(&b, stats.Total.Bytes, stats.Total.Lines, stats.Total.Tokens, fmt.Sprintf("total (%d %s)", n, plural(n, "file", "files")))
+	writeSkipCounts(&b, "Skipped files", stats.Skipped)
+	writeSkipCounts(&b, "Skipped directories", stats.SkippedDirs)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeSkipCounts writes a titled list of the non-zero counts, in skipOrder.
+func writeSkipCounts(b *strings.Builder, title string, counts map[SkipReason]int) {
+	width := 0
+	for reason, n := range counts {
+		if n > 0 {
+			width = max(width, len(reason))
+		}
+	}
+	if width == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for _, reason := range skipOrder {
+		if n := counts[reason]; n > 0 {
+			fmt.Fprintf(b, "  %-*s  %d\n", width, reason, n)
+		}
+	}
+}
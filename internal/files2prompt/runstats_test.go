@@ -0,0 +1,114 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// runStatsFixture writes a tree with files skipped by several filters and
+// returns its root.
+func runStatsFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":    "*.log\n",
+		"debug.log":     "log\n",
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"util.go":       "package main",
+		"notes.md":      "# notes\n",
+		".hidden/x.go":  "package x\n",
+		"vendor/lib.go": "package lib\n",
+	})
+	return root
+}
+
+func runStatsConfig(root string) config.Config {
+	return config.Config{
+		Paths:           []string{root},
+		Extensions:      []string{".go"},
+		IgnorePatterns:  []string{"vendor/"},
+		IgnoreGitignore: true,
+		Stats:           true,
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	root := runStatsFixture(t)
+	list := runStatsConfig(root)
+	list.ListOnly = true
+
+	for _, cfg := range []config.Config{runStatsConfig(root), list} {
+		summary, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, summary.Stats)
+		assert.Equal(t, RunStats{
+			Files: []FileStats{
+				{Path: filepath.Join(root, "main.go"), Bytes: 29, Lines: 3, Tokens: 8},
+				{Path: filepath.Join(root, "util.go"), Bytes: 12, Lines: 1, Tokens: 3},
+			},
+			Total: FileStats{Bytes: 41, Lines: 4, Tokens: 11},
+			Skipped: map[SkipReason]int{
+				SkipHidden:    1,
+				SkipGitignore: 1,
+				SkipExtension: 1,
+			},
+			SkippedDirs: map[SkipReason]int{SkipHidden: 1, SkipIgnore: 1},
+		}, *summary.Stats, "list %v", cfg.ListOnly)
+	}
+
+	summary, err := Generate(context.Background(), config.Config{Paths: []string{root}}, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, summary.Stats)
+}
+
+func TestWriteRunStats(t *testing.T) {
+	root := runStatsFixture(t)
+	t.Chdir(root)
+	cfg := runStatsConfig(root)
+	cfg.Paths = []string{"main.go", "util.go"}
+	summary, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+
+	var table bytes.Buffer
+	require.NoError(t, WriteRunStats(&table, *summary.Stats, StatsTable))
+	assert.Equal(t, `BYTES  LINES  TOKENS  FILE
+   29      3       8  main.go
+   12      1       3  util.go
+   41      4      11  total (2 files)
+`, table.String())
+
+	summary.Stats.Skipped[SkipGitignore] = 2
+	summary.Stats.Skipped[SkipExtension] = 1
+	summary.Stats.SkippedDirs[SkipIgnore] = 1
+	table.Reset()
+	require.NoError(t, WriteRunStats(&table, *summary.Stats, StatsTable))
+	assert.Contains(t, table.String(), `  41      4      11  total (2 files)
+
+Skipped files:
+  extension filter  1
+  .gitignore rules  2
+
+Skipped directories:
+  ignore patterns  1
+`)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteRunStats(&out, *summary.Stats, StatsJSON))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, map[string]any{"path": "main.go", "bytes": 29.0, "lines": 3.0, "tokens": 8.0}, decoded["files"].([]any)[0])
+	assert.Equal(t, map[string]any{"extension filter": 1.0, ".gitignore rules": 2.0}, decoded["skipped_files"])
+}
+
+func TestStatsFormat(t *testing.T) {
+	_, err := Generate(context.Background(), config.Config{Stats: true, StatsFormat: "csv"}, &bytes.Buffer{}, []PlannedFile{})
+	assert.EqualError(t, err, `invalid --stats-format "csv": use table or json`)
+}
@@ -0,0 +1,77 @@
+package files2prompt
+
+import (
+	"slices"
+	"strings"
+)
+
+// segment is a range of a file's lines that is either shown or, when marker is
+// set, replaced by that marker line.
+type segment struct {
+	lineRange
+	marker string
+}
+
+// wholeFile returns the single segment showing all total lines, or none for an empty file.
+func wholeFile(total int) []segment {
+	if total == 0 {
+		return nil
+	}
+	return []segment{{lineRange: lineRange{0, total - 1}}}
+}
+
+// lastLine returns the number of the last line segments show, or 0 when they
+// show none. Marker segments are not numbered and do not count.
+func lastLine(segments []segment) int {
+	for _, s := range slices.Backward(segments) {
+		if s.marker == "" {
+			return s.end + 1
+		}
+	}
+	return 0
+}
+
+// writeSegments renders segments of lines. With a line-number format the shown
+// lines keep their real numbers; without one they are written exactly as they
+// appear in the file.
+func writeSegments(b *strings.Builder, lines []string, segments []segment, format string, state *emitState) {
+	for _, s := range segments {
+		switch {
+		case s.marker != "":
+			b.WriteString(s.marker)
+		case format != "":
+			writeNumberedLines(b, lines[s.start:s.end+1], s.start+1, format, state)
+		default:
+			b.WriteString(segmentText(lines, s))
+		}
+	}
+}
+
+// segmentContent returns the text of the lines shown by segments, exactly as it
+// appears in the file, with the marker lines too when markers is set.
+// terminated reports whether the file ends in a newline; when it does not, the
+// newline segmentText supplies is left out.
+func segmentContent(lines []string, segments []segment, terminated, markers bool) string {
+	var b strings.Builder
+	for _, s := range segments {
+		if s.marker != "" {
+			if markers {
+				b.WriteString(s.marker)
+			}
+			continue
+		}
+		text := segmentText(lines, s)
+		if !terminated && s.end == len(lines)-1 {
+			text = strings.TrimSuffix(text, "\n")
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// segmentText returns the lines of s as in the file, each ending in a newline.
+// One missing from the last line of the file is supplied, so that a closing
+// delimiter written after it starts a line of its own.
+func segmentText(lines []string, s segment) string {
+	return strings.Join(lines[s.start:s.end+1], "\n") + "\n"
+}
@@ -0,0 +1,51 @@
+package files2prompt
+
+import (
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// sensitiveFiles is the audited table of base-name patterns for files that commonly
+// hold secrets. Matching files are withheld unless --include-sensitive is given.
+var sensitiveFiles = []struct {
+	pattern string
+	reason  string
+}{
+	{".env", "environment file"},
+	{".env.*", "environment file"},
+	{"*.pem", "PEM certificate or key"},
+	{"*.key", "private key"},
+	{"id_rsa*", "SSH private key"},
+	{"id_dsa*", "SSH private key"},
+	{"id_ecdsa*", "SSH private key"},
+	{"id_ed25519*", "SSH private key"},
+	{"credentials*.json", "cloud credentials"},
+	{".netrc", "netrc credentials"},
+	{"kubeconfig", "Kubernetes credentials"},
+}
+
+// sensitiveExceptions are base names that match sensitiveFiles but are conventionally
+// safe templates and therefore never withheld.
+var sensitiveExceptions = []string{
+	".env.example",
+	".env.sample",
+	".env.template",
+}
+
+// isSensitive reports whether the file at path looks like it contains secrets,
+// returning the matching table entry's description.
+func isSensitive(path string) (string, bool) {
+	base := filepath.Base(path)
+	for _, exception := range sensitiveExceptions {
+		if base == exception {
+			return "", false
+		}
+	}
+	for _, entry := range sensitiveFiles {
+		if doublestar.MatchUnvalidated(entry.pattern, base) {
+			return entry.reason, true
+		}
+	}
+	return "", false
+}
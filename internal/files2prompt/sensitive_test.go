@@ -0,0 +1,79 @@
+package files2prompt
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestIsSensitive(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: ".env", expected: true},
+		{path: "app/.env.production", expected: true},
+		{path: ".env.local", expected: true},
+		{path: ".env.example", expected: false},
+		{path: "deploy/.env.sample", expected: false},
+		{path: "certs/server.pem", expected: true},
+		{path: "tls.key", expected: true},
+		{path: "id_rsa", expected: true},
+		{path: "id_ed25519.pub", expected: true},
+		{path: "credentials.json", expected: true},
+		{path: "credentials-prod.json", expected: true},
+		{path: ".netrc", expected: true},
+		{path: "kubeconfig", expected: true},
+		{path: "environment.go", expected: false},
+		{path: "keys.go", expected: false},
+		{path: "credentials.go", expected: false},
+		{path: "monkey.keynote", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, ok := isSensitive(tt.path)
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+}
+
+func TestRunWithholdsSensitiveFiles(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	buf := withStdout(t)
+	_, err := Run(config.Config{Paths: []string{"testdata/sensitive"}, IncludeHidden: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/sensitive/.env.example\n---\nSECRET=changeme\n---\n\n"+
+		"testdata/sensitive/config.go\n---\npackage config\n---\n\n", buf.String())
+
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+		assert.Equal(t, "Withheld 6 sensitive files (use --include-sensitive to include):\n"+
+			"  testdata/sensitive/.env\n"+
+			"  testdata/sensitive/.env.production\n"+
+			"  testdata/sensitive/credentials-prod.json\n"+
+			"  testdata/sensitive/id_rsa\n"+
+			"  testdata/sensitive/kubeconfig\n"+
+			"  testdata/sensitive/server.pem", hook.LastEntry().Message)
+	}
+
+	// explicit file arguments are withheld too
+	buf.Reset()
+	_, err = Run(config.Config{Paths: []string{"testdata/sensitive/.env.production"}})
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	// the override restores inclusion
+	buf.Reset()
+	hook.Reset()
+	summary, err := Run(config.Config{Paths: []string{"testdata/sensitive"}, IncludeHidden: true, IncludeSensitive: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 8, summary.Files)
+	assert.Contains(t, buf.String(), "testdata/sensitive/.env.production\n---\nSECRET=prod\n")
+	assert.Empty(t, hook.AllEntries())
+}
@@ -0,0 +1,105 @@
+package files2prompt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// DefaultReadLimit is the size above which files are never read, unless
+// config.ReadLimit raises or lowers the ceiling.
+const DefaultReadLimit int64 = 1 << 30
+
+// readLimit returns the effective per-file read ceiling for config.
+func readLimit(config config.Config) int64 {
+	if config.ReadLimit > 0 {
+		return config.ReadLimit
+	}
+	return DefaultReadLimit
+}
+
+// formatBytes renders n using binary units, e.g. "512 B", "1.5 KiB" or "6.0 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit || v <= -unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// DefaultZeroSizeLimit is how much of a file reporting a size of 0 is read
+// before it is cut off, unless config.ZeroSizeLimit says otherwise.
+const DefaultZeroSizeLimit int64 = 1 << 20
+
+// zeroSizeLimit returns the effective cutoff for files reporting a size of 0.
+func zeroSizeLimit(config config.Config) int64 {
+	if config.ZeroSizeLimit > 0 {
+		return config.ZeroSizeLimit
+	}
+	return DefaultZeroSizeLimit
+}
+
+// zeroSizeCutoff ends the content of a file cut off at the zero-size limit.
+const zeroSizeCutoff = "... [cut off after %s: the file reports a size of 0, as virtual files do] ...\n"
+
+// readFileLimited reads the file at path, failing instead of reading further
+// once its content exceeds limit bytes. This guards against files that grew
+// after they were planned. A file reporting a size of 0 is cut off after
+// zeroSize bytes, as readCapped does.
+func readFileLimited(path string, limit, zeroSize int64) ([]byte, error) {
+	return readFileThrottled(path, limit, zeroSize, nil)
+}
+
+// readFileThrottled is readFileLimited reading through throttle.
+func readFileThrottled(path string, limit, zeroSize int64, throttle *readThrottle) ([]byte, error) {
+	content, _, err := openCapped(path, limit, zeroSize, throttle)
+	return content, err
+}
+
+// openCapped opens the file at path and reads it as readCapped does.
+func openCapped(path string, limit, zeroSize int64, throttle *readThrottle) ([]byte, bool, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	return readCapped(f, limit, zeroSize, throttle)
+}
+
+// readCapped reads f, failing instead of reading further once its content
+// exceeds limit bytes. A file reporting a size of 0 that yields more than
+// zeroSize bytes, as files under /proc and /sys can without end, is cut off
+// there instead, after its last whole line, with a note ending its content,
+// and reported as cut.
+func readCapped(f fs.File, limit, zeroSize int64, throttle *readThrottle) ([]byte, bool, error) {
+	upTo := limit
+	if info, err := f.Stat(); err == nil && info.Size() == 0 && zeroSize < limit {
+		upTo = zeroSize
+	}
+	content, err := io.ReadAll(io.LimitReader(throttle.reader(f), upTo+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(content)) <= upTo {
+		return content, false, nil
+	}
+	if upTo == limit {
+		return nil, false, fmt.Errorf("file exceeds the %s read limit", formatBytes(limit))
+	}
+	content = content[:upTo]
+	if i := bytes.LastIndexByte(content, '\n'); i >= 0 {
+		content = content[:i+1]
+	} else {
+		content = append(content, '\n')
+	}
+	return append(content, fmt.Sprintf(zeroSizeCutoff, formatBytes(zeroSize))...), true, nil
+}
@@ -0,0 +1,161 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{1 << 30, "1.0 GiB"},
+		{6 << 30, "6.0 GiB"},
+		{1<<31 + 1<<30, "3.0 GiB"},
+		{1 << 50, "1.0 PiB"},
+		{1<<63 - 1, "8.0 EiB"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatBytes(tt.n), "formatBytes(%d)", tt.n)
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	assert.Equal(t, DefaultReadLimit, readLimit(config.Config{}))
+	assert.Equal(t, int64(10), readLimit(config.Config{ReadLimit: 10}))
+}
+
+func TestReadFileLimited(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grown.dat")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o600))
+
+	content, err := readFileLimited(path, 10, DefaultZeroSizeLimit)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(content))
+
+	_, err = readFileLimited(path, 9, DefaultZeroSizeLimit)
+	assert.EqualError(t, err, "file exceeds the 9 B read limit")
+}
+
+// sparseFile creates a file reporting size bytes without allocating them on disk.
+func sparseFile(t *testing.T, path string, size int64) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(size))
+	require.NoError(t, f.Close())
+}
+
+func TestPlanSkipsFilesAboveReadLimit(t *testing.T) {
+	dir := t.TempDir()
+	huge := filepath.Join(dir, "core.dat")
+	sparseFile(t, huge, 6<<30)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.dat"), []byte("ok\n"), 0o600))
+
+	for name, paths := range map[string][]string{"walked": {dir}, "explicit": {huge}} {
+		t.Run(name, func(t *testing.T) {
+			// The ceiling applies even when every other filter would let the file through
+			cfg := config.Config{Paths: paths, IncludeHidden: true, IncludeSensitive: true}
+			all, err := Plan(context.Background(), cfg, true)
+			require.NoError(t, err)
+
+			var skipped PlannedFile
+			for _, f := range all {
+				if f.Path == huge {
+					skipped = f
+				}
+			}
+			assert.Equal(t, SkipTooLarge, skipped.Reason)
+			assert.False(t, skipped.Included)
+			assert.Equal(t, int64(6<<30), skipped.Size)
+		})
+	}
+}
+
+func TestGenerateWarnsAboutFilesAboveReadLimit(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.dat"), bytes.Repeat([]byte("x"), 2048), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.dat"), []byte("ok\n"), 0o600))
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	summary, err := Generate(context.Background(), config.Config{Paths: []string{dir}, ReadLimit: 1024}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Files)
+	assert.NotContains(t, buf.String(), "big.dat")
+
+	var messages []string
+	for _, e := range hook.AllEntries() {
+		messages = append(messages, e.Message)
+	}
+	assert.Contains(t, messages,
+		"Skipping "+filepath.Join(dir, "big.dat")+": 2.0 KiB exceeds the 1.0 KiB read limit (use --read-limit to raise it)")
+}
+
+func TestDiagnosticReportsReadLimit(t *testing.T) {
+	dir := t.TempDir()
+	sparseFile(t, filepath.Join(dir, "core.dat"), 3<<30)
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{dir}}, &buf, nil)
+	require.NoError(t, err)
+
+	var messages []string
+	for _, e := range hook.AllEntries() {
+		messages = append(messages, e.Message)
+	}
+	assert.Contains(t, messages, dir+": 1 file excluded by read limit")
+}
+
+func TestGenerateSizeBounds(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle.dat")
+	require.NoError(t, os.WriteFile(bundle, bytes.Repeat([]byte("x"), 3000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stub.dat"), []byte("x\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.dat"), bytes.Repeat([]byte("y"), 100), 0o600))
+
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	var buf bytes.Buffer
+	cfg := config.Config{Paths: []string{dir}, MaxFileSize: 2048, MinFileSize: 10}
+	summary, err := Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Files)
+	assert.Contains(t, buf.String(), "main.dat")
+	assert.NotContains(t, buf.String(), "bundle.dat")
+	assert.NotContains(t, buf.String(), "stub.dat")
+
+	var warnings []string
+	for _, e := range hook.AllEntries() {
+		if e.Level == log.WarnLevel {
+			warnings = append(warnings, e.Message)
+		}
+	}
+	assert.Equal(t, []string{"Skipping " + bundle + ": 2.9 KiB exceeds the 2.0 KiB --max-size limit"}, warnings)
+
+	// A file named explicitly is emitted whatever its size
+	buf.Reset()
+	cfg.Paths = []string{bundle}
+	summary, err = Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Files)
+}
@@ -0,0 +1,181 @@
+package files2prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// SkipReason identifies the filter responsible for excluding a path.
+type SkipReason string
+
+// Skip reasons recorded in PlannedFile.Reason.
+const (
+	SkipVCS          SkipReason = "version control metadata"
+	SkipHidden       SkipReason = "hidden-file rule"
+	SkipSensitive    SkipReason = "sensitive-file rule"
+	SkipGitignore    SkipReason = ".gitignore rules"
+	SkipExportIgnore SkipReason = ".gitattributes export-ignore"
+	SkipGenerated    SkipReason = "generated-code rule"
+	SkipSubmodule    SkipReason = "submodule"
+	SkipDefaults     SkipReason = "default ignores"
+	SkipJunk         SkipReason = "junk files"
+	SkipIgnore       SkipReason = "ignore patterns"
+	SkipExtension    SkipReason = "extension filter"
+	SkipExcludeExt   SkipReason = "excluded extensions"
+	SkipInclude      SkipReason = "include patterns"
+	SkipMaxSize      SkipReason = "max size"
+	SkipModTime      SkipReason = "modification time"
+	SkipMinSize      SkipReason = "min size"
+	SkipTooLarge     SkipReason = "read limit"
+	SkipJail         SkipReason = "jail"
+	SkipGrep         SkipReason = "grep filter"
+	SkipBudget       SkipReason = "token budget"
+	SkipMaxTokens    SkipReason = "max tokens"
+	SkipNamedPipe    SkipReason = "named pipe"
+	SkipSocket       SkipReason = "socket"
+	SkipDevice       SkipReason = "device file"
+	SkipIrregular    SkipReason = "irregular file"
+	SkipVirtualFS    SkipReason = "virtual file system"
+	SkipSymlinkDir   SkipReason = "symlinked directory"
+	SkipDangling     SkipReason = "dangling symlink"
+	SkipUnchanged    SkipReason = "hunks-only filter"
+	SkipUntracked    SkipReason = "git-tracked filter"
+	SkipDepth        SkipReason = "depth limit"
+)
+
+// skipOrder fixes the order in which reasons with equal counts are reported.
+var skipOrder = []SkipReason{SkipExtension, SkipExcludeExt, SkipInclude, SkipDepth, SkipIgnore, SkipDefaults, SkipGitignore, SkipUntracked, SkipExportIgnore, SkipGenerated, SkipSubmodule, SkipVCS, SkipJunk, SkipHidden, SkipSensitive, SkipMaxSize, SkipMinSize, SkipModTime, SkipTooLarge, SkipJail, SkipGrep, SkipBudget, SkipMaxTokens, SkipNamedPipe, SkipSocket, SkipDevice, SkipIrregular, SkipVirtualFS, SkipSymlinkDir, SkipDangling, SkipUnchanged}
+
+// pathStats summarizes what happened to the candidates found under a single path argument.
+// A nil *pathStats is valid and records nothing.
+type pathStats struct {
+	root         string
+	documents    int
+	skippedFiles map[SkipReason]int
+	skippedDirs  map[SkipReason]int
+	// withheld lists the sensitive files that were skipped, for the end-of-run notice
+	withheld []string
+}
+
+func newPathStats(root string) *pathStats {
+	return &pathStats{
+		root:         root,
+		skippedFiles: map[SkipReason]int{},
+		skippedDirs:  map[SkipReason]int{},
+	}
+}
+
+// skip records that a file or directory was excluded for reason.
+func (s *pathStats) skip(reason SkipReason, isDir bool) {
+	if s == nil {
+		return
+	}
+	if isDir {
+		s.skippedDirs[reason]++
+	} else {
+		s.skippedFiles[reason]++
+	}
+}
+
+// withhold records that a sensitive file was skipped.
+func (s *pathStats) withhold(path string) {
+	if s == nil {
+		return
+	}
+	s.skip(SkipSensitive, false)
+	s.withheld = append(s.withheld, path)
+}
+
+// statsFromPlan tallies the planned files that came from root.
+func statsFromPlan(root string, files []PlannedFile) *pathStats {
+	s := newPathStats(root)
+	for _, f := range files {
+		switch {
+		case f.Root != root:
+		case f.Included:
+			s.documents++
+		case f.Reason == SkipSensitive:
+			s.withhold(f.Path)
+		default:
+			s.skip(f.Reason, f.IsDir)
+		}
+	}
+	return s
+}
+
+// emptyDiagnostic explains why the path argument produced no documents, listing
+// the skip reasons from most to least frequent.
+func (s *pathStats) emptyDiagnostic(config config.Config) string {
+	type item struct {
+		count  int
+		noun   string
+		reason SkipReason
+		rank   int
+	}
+	var items []item
+	for rank, reason := range skipOrder {
+		if n := s.skippedFiles[reason]; n > 0 {
+			items = append(items, item{n, plural(n, "file", "files"), reason, rank})
+		}
+		if n := s.skippedDirs[reason]; n > 0 {
+			items = append(items, item{n, plural(n, "directory", "directories"), reason, rank})
+		}
+	}
+	if len(items) == 0 {
+		return fmt.Sprintf("%s: no files found", s.root)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].count != items[j].count {
+			return items[i].count > items[j].count
+		}
+		return items[i].rank < items[j].rank
+	})
+
+	parts := make([]string, 0, len(items))
+	for i, it := range items {
+		label := string(it.reason)
+		switch it.reason {
+		case SkipExtension:
+			label += " [" + strings.Join(config.Extensions, ", ") + "]"
+		case SkipExcludeExt:
+			label += " [" + strings.Join(config.ExcludeExtensions, ", ") + "]"
+		case SkipMaxSize:
+			label += " [" + formatBytes(int64(config.MaxFileSize)) + "]"
+		case SkipMinSize:
+			label += " [" + formatBytes(int64(config.MinFileSize)) + "]"
+		case SkipModTime:
+			label += " [" + modTimesLabel(config) + "]"
+		case SkipInclude:
+			if config.AutoExtensions {
+				label += " [--auto-extensions]"
+			} else {
+				label += " [" + strings.Join(config.IncludePatterns, ", ") + "]"
+			}
+		case SkipDepth:
+			depth, _ := maxDepth(config)
+			label += fmt.Sprintf(" [%d]", depth)
+		case SkipGrep:
+			label += " [" + config.Grep + "]"
+		case SkipBudget:
+			label += fmt.Sprintf(" [%d tokens]", config.FitTokens)
+		case SkipMaxTokens:
+			label += fmt.Sprintf(" [%d tokens]", config.MaxTokens)
+		}
+		verb := "by"
+		if i == 0 {
+			verb = "excluded by"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s %s %s", it.count, it.noun, verb, label))
+	}
+	return fmt.Sprintf("%s: %s", s.root, strings.Join(parts, ", "))
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
@@ -0,0 +1,71 @@
+package files2prompt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestEmptyDiagnostic(t *testing.T) {
+	cfg := config.Config{Extensions: []string{".go"}}
+
+	stats := newPathStats("src/")
+	assert.Equal(t, "src/: no files found", stats.emptyDiagnostic(cfg))
+
+	for i := 0; i < 214; i++ {
+		stats.skip(SkipExtension, false)
+	}
+	for i := 0; i < 12; i++ {
+		stats.skip(SkipIgnore, false)
+	}
+	stats.skip(SkipHidden, true)
+	assert.Equal(t, "src/: 214 files excluded by extension filter [.go], 12 files by ignore patterns, 1 directory by hidden-file rule",
+		stats.emptyDiagnostic(cfg))
+
+	// nil stats are ignored
+	var none *pathStats
+	none.skip(SkipHidden, false)
+}
+
+func TestRunEmptyPathDiagnostics(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	var buf bytes.Buffer
+	originalStdout := osStdout
+	osStdout = &buf
+	defer func() { osStdout = originalStdout }()
+
+	cfg := config.Config{
+		Paths:          []string{"testdata/test_project", "testdata/gitignore_valid", "testdata/test_project/src"},
+		Extensions:     []string{".rs", ".go"},
+		IgnorePatterns: []string{"temp/", "*.go"},
+	}
+	_, err := Run(cfg)
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	var warnings []string
+	for _, e := range hook.AllEntries() {
+		if e.Level == logrus.WarnLevel {
+			warnings = append(warnings, e.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"testdata/test_project: 2 files excluded by extension filter [.rs, .go], 2 files by hidden-file rule, 1 file by ignore patterns, 1 directory by ignore patterns",
+		"testdata/gitignore_valid: 1 file excluded by hidden-file rule",
+		"testdata/test_project/src: 1 file excluded by ignore patterns",
+	}, warnings)
+
+	cfg.FailOnEmpty = true
+	_, err = Run(cfg)
+	assert.EqualError(t, err, "no documents produced for testdata/test_project, testdata/gitignore_valid, testdata/test_project/src")
+
+	cfg.Paths = []string{"testdata/test_project/src"}
+	cfg.IgnorePatterns = nil
+	_, err = Run(cfg)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,312 @@
+package files2prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// SplitIndexes selects how Claude XML documents are numbered across the chunks
+// of a split output.
+type SplitIndexes string
+
+// Numberings accepted by --split-indexes.
+const (
+	// SplitIndexesContinue numbers the documents of every chunk on from the
+	// last of the chunk before, as an unsplit output would, the default.
+	SplitIndexesContinue SplitIndexes = "continue"
+	// SplitIndexesRestart numbers the documents of every chunk from 1.
+	SplitIndexesRestart SplitIndexes = "restart"
+)
+
+// Chunk is one of the files --split-tokens or --split-bytes divided the
+// output into.
+type Chunk struct {
+	// Path is the path of the file, numbered after the --output path.
+	Path string
+	// Bytes is the size of the file.
+	Bytes int64
+	// Tokens is an estimate of the number of tokens in the file, made as
+	// Summary.Tokens is.
+	Tokens int64
+}
+
+// splitIndexes returns the numbering selected by config, defaulting to SplitIndexesContinue.
+func splitIndexes(config config.Config) (SplitIndexes, error) {
+	switch mode := SplitIndexes(config.SplitIndexes); mode {
+	case "":
+		return SplitIndexesContinue, nil
+	case SplitIndexesContinue, SplitIndexesRestart:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid --split-indexes %q: use %s or %s", config.SplitIndexes, SplitIndexesContinue, SplitIndexesRestart)
+}
+
+// splits reports whether config divides the output into chunks.
+func splits(config config.Config) bool {
+	return config.SplitTokens > 0 || config.SplitBytes > 0
+}
+
+// splitFlag returns the option config divides the output with.
+func splitFlag(config config.Config) string {
+	if config.SplitTokens > 0 {
+		return "--split-tokens"
+	}
+	return "--split-bytes"
+}
+
+// overBudget reports whether c is larger than the budget of config, which
+// happens only to a chunk holding a single document too large for it.
+func overBudget(c Chunk, config config.Config) bool {
+	if config.SplitTokens > 0 {
+		return c.Tokens > config.SplitTokens
+	}
+	return c.Bytes > int64(config.SplitBytes)
+}
+
+// chunkPath returns the path of the nth chunk of output: output-001.txt for
+// output.txt.
+func chunkPath(output string, n int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(output, ext), n, ext)
+}
+
+// chunkFiles is the writer of a split output, writing to one chunk file until
+// next moves on to another. Each chunk is created at its first write.
+type chunkFiles struct {
+	output string
+	bpe    bool
+	file   *os.File
+	buf    *bufio.Writer
+	chunks []Chunk
+	// open reports whether the last of chunks is still being written
+	open bool
+}
+
+func newChunkFiles(output string, config config.Config) *chunkFiles {
+	return &chunkFiles{output: output, bpe: config.CountTokens}
+}
+
+func (c *chunkFiles) Write(p []byte) (int, error) {
+	if !c.open {
+		path := chunkPath(c.output, len(c.chunks)+1)
+		file, err := os.Create(path) // #nosec G304
+		if err != nil {
+			return 0, fmt.Errorf("failed to create output file: %v", err)
+		}
+		c.file, c.buf, c.open = file, bufio.NewWriterSize(file, outputBufferSize), true
+		c.chunks = append(c.chunks, Chunk{Path: path})
+	}
+	n, err := c.buf.Write(p)
+	chunk := &c.chunks[len(c.chunks)-1]
+	chunk.Bytes += int64(n)
+	if c.bpe {
+		chunk.Tokens += countTokens(string(p[:n]))
+	}
+	return n, err
+}
+
+// size returns the bytes and tokens of the chunk being written, with extra
+// written to it too.
+func (c *chunkFiles) size(extra string) (bytes, tokens int64) {
+	if c.open {
+		chunk := c.chunks[len(c.chunks)-1]
+		bytes, tokens = chunk.Bytes, chunk.Tokens
+	}
+	bytes += int64(len(extra))
+	if c.bpe {
+		return bytes, tokens + countTokens(extra)
+	}
+	return bytes, estimateTokens(bytes)
+}
+
+// Flush writes out what is buffered for the chunk being written.
+func (c *chunkFiles) Flush() error {
+	if !c.open {
+		return nil
+	}
+	return c.buf.Flush()
+}
+
+// next closes the chunk being written, so that the next write starts another.
+func (c *chunkFiles) next() error {
+	if !c.open {
+		return nil
+	}
+	c.open = false
+	err := c.buf.Flush()
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Close closes the last chunk, and returns the chunks written.
+func (c *chunkFiles) Close() ([]Chunk, error) {
+	err := c.next()
+	if !c.bpe {
+		for i := range c.chunks {
+			c.chunks[i].Tokens = estimateTokens(c.chunks[i].Bytes)
+		}
+	}
+	return c.chunks, err
+}
+
+// splitter decides where a split output moves on to a new chunk: before any
+// document that would take the chunk over the budget, unless the chunk holds
+// no document yet. A nil *splitter splits nothing.
+type splitter struct {
+	chunks *chunkFiles
+	budget int64
+	// tokens reports whether the budget is in tokens rather than bytes
+	tokens  bool
+	restart bool
+	// open and close wrap every chunk in Claude XML mode
+	open, close string
+	// docs counts the documents in the chunk being written
+	docs int
+}
+
+// newSplitter returns the splitter dividing output written to w, or nil when
+// config does not split it or w is not the chunkFiles of Run.
+func newSplitter(config config.Config, w io.Writer) (*splitter, error) {
+	restart, err := splitIndexes(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.SplitTokens > 0 && config.SplitBytes > 0 {
+		return nil, fmt.Errorf("--split-tokens cannot be combined with --split-bytes")
+	}
+	chunks, ok := w.(*chunkFiles)
+	if !splits(config) || !ok {
+		return nil, nil
+	}
+	s := &splitter{chunks: chunks, budget: int64(config.SplitBytes), restart: restart == SplitIndexesRestart && config.ClaudeXML}
+	if config.SplitTokens > 0 {
+		s.budget, s.tokens = config.SplitTokens, true
+	}
+	if config.ClaudeXML {
+		names := cxmlNamesOf(config)
+		s.open, s.close = names.openRoot(), names.closeRoot()
+	}
+	return s, nil
+}
+
+// full reports whether doc would take the chunk being written over the budget.
+func (s *splitter) full(doc []byte) bool {
+	if s == nil || s.docs == 0 {
+		return false
+	}
+	bytes, tokens := s.chunks.size(string(doc) + s.close)
+	if s.tokens {
+		return tokens > s.budget
+	}
+	return bytes > s.budget
+}
+
+// fullBy reports whether a document rendered in size would take the chunk being
+// written over the budget, as full does for one held back; size.tokens is only
+// counted with the BPE estimate.
+func (s *splitter) fullBy(size usage) bool {
+	if s == nil || s.docs == 0 {
+		return false
+	}
+	bytes, tokens := s.chunks.size(s.close)
+	bytes += size.bytes
+	if s.tokens && s.chunks.bpe {
+		return tokens+size.tokens > s.budget
+	}
+	if s.tokens {
+		return estimateTokens(bytes) > s.budget
+	}
+	return bytes > s.budget
+}
+
+// next closes the chunk being written with w, which meters the output, and
+// opens the next.
+func (s *splitter) next(w io.Writer) error {
+	if _, err := io.WriteString(w, s.close); err != nil {
+		return err
+	}
+	if err := s.chunks.next(); err != nil {
+		return err
+	}
+	s.docs = 0
+	_, err := io.WriteString(w, s.open)
+	return err
+}
+
+// place renders the document emit writes into the chunk being written or, when
+// it would take that over the budget, into the next.
+func (g *generator) place(emit func() error) error {
+	if g.split == nil {
+		return emit()
+	}
+	writer, state := *g.writer, *g.state
+	g.capture.hold = true
+	if err := emit(); err != nil {
+		g.capture.discard()
+		return err
+	}
+	return g.commit(writer, state, emit)
+}
+
+// advance moves on to the next chunk before a document rendered in size is
+// written, when it would take the one being written over the budget.
+func (g *generator) advance(size usage) error {
+	s := g.split
+	if s == nil || size.bytes == 0 {
+		return nil
+	}
+	if s.fullBy(size) {
+		if err := s.next(g.writer); err != nil {
+			return err
+		}
+		if s.restart {
+			g.state.index = 1
+		}
+	}
+	s.docs++
+	return nil
+}
+
+// commit writes the document held back since writer and state were saved,
+// moving on to the next chunk first when it would take the one being written
+// over the budget. With restarting indexes, the document is rendered again by
+// emit to number it for the new chunk.
+func (g *generator) commit(writer ledger, state emitState, emit func() error) error {
+	s := g.split
+	if s == nil || g.capture.held.Len() == 0 {
+		// Skipped when read, or not split at all
+		return g.capture.release()
+	}
+	if !s.full(g.capture.held.Bytes()) {
+		s.docs++
+		return g.capture.release()
+	}
+	if s.restart {
+		*g.writer, *g.state = writer, state
+		g.capture.discard()
+		if err := s.next(g.writer); err != nil {
+			return err
+		}
+		s.docs++
+		g.state.index = 1
+		return emit()
+	}
+	held := slices.Clone(g.capture.held.Bytes())
+	g.capture.discard()
+	// The document was metered when it was rendered
+	if err := s.next(g.writer); err != nil {
+		return err
+	}
+	s.docs++
+	_, err := g.capture.Write(held)
+	return err
+}
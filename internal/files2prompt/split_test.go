@@ -0,0 +1,242 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// splitProject writes ten files of 180 bytes each to src in a new working
+// directory, in which each plain document is 204 bytes.
+func splitProject(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	files := map[string]string{}
+	for i := range 10 {
+		files[fmt.Sprintf("src/file%02d.txt", i)] = strings.Repeat(fmt.Sprintf("line of file %d\n", i), 12)
+	}
+	writeFiles(t, dir, files)
+}
+
+// runSplit runs cfg with its output split into chunks in a directory of their
+// own, returning the summary, the content of each chunk and what Run wrote to
+// stderr.
+func runSplit(t *testing.T, cfg config.Config) (Summary, []string, string) {
+	t.Helper()
+	var stderr bytes.Buffer
+	originalStderr := osStderr
+	osStderr = &stderr
+	defer func() { osStderr = originalStderr }()
+
+	cfg.OutputFile = filepath.Join(t.TempDir(), "output.txt")
+	summary, err := Run(cfg)
+	require.NoError(t, err)
+	var chunks []string
+	for i, c := range summary.Chunks {
+		assert.Equal(t, chunkPath(cfg.OutputFile, i+1), c.Path)
+		data, err := os.ReadFile(c.Path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), c.Bytes)
+		chunks = append(chunks, string(data))
+	}
+	_, err = os.Stat(cfg.OutputFile)
+	assert.True(t, os.IsNotExist(err), "only the chunks are written")
+	return summary, chunks, stderr.String()
+}
+
+// xmlIndexes checks that chunk is a well-formed XML document with a single
+// root element, and returns the index attributes of the elements within it.
+func xmlIndexes(t *testing.T, chunk string) []int {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(chunk))
+	var indexes []int
+	depth, roots := 0, 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err, chunk)
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+			for _, attr := range token.Attr {
+				if attr.Name.Local == "index" {
+					n, err := strconv.Atoi(attr.Value)
+					require.NoError(t, err)
+					indexes = append(indexes, n)
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+	assert.Equal(t, 1, roots, chunk)
+	return indexes
+}
+
+func TestSplitOutput(t *testing.T) {
+	splitProject(t)
+
+	tests := []struct {
+		name   string
+		config config.Config
+		chunks int
+	}{
+		{name: "bytes", config: config.Config{SplitBytes: 700}, chunks: 4},
+		{name: "tokens", config: config.Config{SplitTokens: 175}, chunks: 4},
+		{name: "markdown", config: config.Config{SplitBytes: 700, Markdown: true}, chunks: 4},
+		{name: "with a tree", config: config.Config{SplitBytes: 700, Tree: true}, chunks: 4},
+		{name: "claude xml", config: config.Config{SplitBytes: 900, ClaudeXML: true}, chunks: 4},
+		{name: "claude xml with a tree", config: config.Config{SplitBytes: 900, ClaudeXML: true, Tree: true}, chunks: 4},
+		{name: "one chunk", config: config.Config{SplitBytes: 1 << 20, ClaudeXML: true}, chunks: 1},
+		{name: "a document per chunk", config: config.Config{SplitBytes: 1, ClaudeXML: true}, chunks: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"src"}
+			summary, chunks, stderr := runSplit(t, cfg)
+			require.Len(t, chunks, tt.chunks)
+
+			// Every document is written whole into a single chunk
+			whole := cfg
+			whole.SplitBytes, whole.SplitTokens = 0, 0
+			var docs int
+			for doc, err := range Documents(context.Background(), whole, nil) {
+				require.NoError(t, err)
+				docs++
+				var holding int
+				for _, chunk := range chunks {
+					if strings.Contains(chunk, doc.File.DisplayPath) {
+						holding++
+						assert.Contains(t, chunk, doc.Content)
+					}
+				}
+				assert.Equal(t, 1, holding, doc.File.DisplayPath)
+			}
+			assert.Equal(t, 10, docs)
+
+			// The chunks hold the unsplit output, with each wrapped on its own
+			var unsplit bytes.Buffer
+			_, err := Generate(context.Background(), whole, &unsplit, nil)
+			require.NoError(t, err)
+			joined, wrappers := strings.Join(chunks, ""), 0
+			if cfg.ClaudeXML {
+				joined = strings.ReplaceAll(joined, "</documents>\n<documents>\n", "")
+				wrappers = (len(chunks) - 1) * len("</documents>\n<documents>\n")
+			}
+			assert.Equal(t, unsplit.String(), joined)
+			assert.Equal(t, int64(unsplit.Len()+wrappers), summary.Bytes)
+
+			for i, c := range summary.Chunks {
+				if tt.name != "a document per chunk" {
+					assert.False(t, overBudget(c, cfg), c.Path)
+				}
+				assert.Contains(t, stderr, fmt.Sprintf("  %s  %d bytes, ~%d tokens", c.Path, c.Bytes, c.Tokens))
+				if cfg.ClaudeXML {
+					xmlIndexes(t, chunks[i])
+				}
+			}
+			assert.True(t, strings.HasPrefix(stderr, fmt.Sprintf("Split the output into %d chunk", tt.chunks)), stderr)
+		})
+	}
+}
+
+func TestSplitOutputIndexes(t *testing.T) {
+	splitProject(t)
+	for _, tt := range []struct {
+		indexes  string
+		expected [][]int
+	}{
+		{indexes: "continue", expected: [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {10}}},
+		{indexes: "restart", expected: [][]int{{1, 2, 3}, {1, 2, 3}, {1, 2, 3}, {1}}},
+	} {
+		t.Run(tt.indexes, func(t *testing.T) {
+			_, chunks, _ := runSplit(t, config.Config{Paths: []string{"src"}, SplitBytes: 900, ClaudeXML: true, SplitIndexes: tt.indexes})
+			var indexes [][]int
+			for _, chunk := range chunks {
+				indexes = append(indexes, xmlIndexes(t, chunk))
+			}
+			assert.Equal(t, tt.expected, indexes)
+		})
+	}
+}
+
+func TestSplitOutputStreamed(t *testing.T) {
+	splitProject(t)
+	for _, cfg := range []config.Config{
+		{SplitBytes: 700},
+		{SplitTokens: 175},
+		{SplitTokens: 175, CountTokens: true},
+		{SplitBytes: 900, ClaudeXML: true},
+		{SplitBytes: 900, ClaudeXML: true, SplitIndexes: "restart"},
+		{SplitBytes: 1},
+	} {
+		t.Run(fmt.Sprintf("%+v", cfg), func(t *testing.T) {
+			cfg.Paths = []string{"src"}
+			_, held, _ := runSplit(t, cfg)
+			withStreamThreshold(t, 1)
+			for doc, err := range Documents(context.Background(), cfg, nil) {
+				require.NoError(t, err)
+				assert.True(t, doc.Streamed, doc.File.DisplayPath)
+			}
+			// Streamed documents are measured to find their chunk, into the
+			// same chunks as when they are held back
+			_, streamed, _ := runSplit(t, cfg)
+			assert.Equal(t, held, streamed)
+		})
+	}
+}
+
+func TestSplitOutputOverBudget(t *testing.T) {
+	splitProject(t)
+	_, chunks, stderr := runSplit(t, config.Config{Paths: []string{"src"}, SplitBytes: 100})
+	assert.Len(t, chunks, 10)
+	assert.Contains(t, stderr, "output-010.txt  204 bytes, ~51 tokens, over the --split-bytes budget to keep a document whole\n")
+}
+
+func TestSplitOutputErrors(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.txt")
+	tests := []struct {
+		config config.Config
+		err    string
+	}{
+		{config: config.Config{SplitTokens: 100}, err: "--split-tokens requires --output, the path the chunks are numbered after"},
+		{config: config.Config{SplitBytes: 100, OutputFile: output, Clipboard: true}, err: "--split-bytes cannot be combined with --copy"},
+		{config: config.Config{SplitTokens: 100, OutputFile: "https://example.com/out"}, err: "--split-tokens cannot be combined with an http(s) --output"},
+		{config: config.Config{SplitTokens: 100, SplitBytes: 100, OutputFile: output}, err: "--split-tokens cannot be combined with --split-bytes"},
+		{config: config.Config{SplitTokens: 100, OutputFile: output, SplitIndexes: "reset"}, err: `invalid --split-indexes "reset": use continue or restart`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			cfg := tt.config
+			cfg.Paths = []string{"testdata/file1.txt"}
+			_, err := Run(cfg)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestChunkPath(t *testing.T) {
+	assert.Equal(t, "output-001.txt", chunkPath("output.txt", 1))
+	assert.Equal(t, filepath.Join("out", "prompt-012.xml"), chunkPath(filepath.Join("out", "prompt.xml"), 12))
+	assert.Equal(t, "prompt-003", chunkPath("prompt", 3))
+}
@@ -0,0 +1,166 @@
+package files2prompt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// dataMarker replaces a run of data lines squashed by --squash-data-blocks.
+const dataMarker = "[... %d lines of data omitted ...]\n"
+
+// dataLineThreshold is the share of a line's characters, separators aside, that
+// must belong to data tokens for the line to count as data.
+const dataLineThreshold = 0.9
+
+// dataLineMinLength is the length below which a line is never data, so that
+// short lines such as "0," or "}" do not join or bridge a run.
+const dataLineMinLength = 8
+
+// hexDumpLine matches the lines of xxd, hexdump and od style dumps: an offset
+// followed by several groups of hex digits, with any ASCII column after them.
+var hexDumpLine = regexp.MustCompile(`^[0-9a-fA-F]{4,}:?(\s+[0-9a-fA-F]{2,8}){4,}`)
+
+// isDataSeparator reports whether r separates the tokens of a data line. Such
+// characters delimit values in literals and tables and are not counted either way.
+func isDataSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(",;()[]{}\"':", r)
+}
+
+// isDataToken reports whether token is a number, a hex value, or a chunk of
+// base64, as opposed to an identifier, keyword or operator.
+func isDataToken(token string) bool {
+	if isNumber(token) {
+		return true
+	}
+	if hex, ok := strings.CutPrefix(strings.ToLower(token), "0x"); ok {
+		return hex != "" && strings.Trim(hex, "0123456789abcdef") == ""
+	}
+	if len(token) >= 2 && strings.Trim(token, "0123456789abcdefABCDEF") == "" {
+		// Bare hex needs a digit, so words such as "added" or "face" are not data
+		return strings.ContainsAny(token, "0123456789")
+	}
+	return isBase64Chunk(token)
+}
+
+// isNumber reports whether token is a decimal number, optionally signed, with a
+// fraction or an exponent.
+func isNumber(token string) bool {
+	token = strings.TrimLeft(token, "+-")
+	mantissa, exponent, hasExponent := strings.Cut(strings.ToLower(token), "e")
+	if hasExponent && !isDigits(strings.TrimLeft(exponent, "+-")) {
+		return false
+	}
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	if whole == "" && fraction == "" {
+		return false
+	}
+	return (whole == "" || isDigits(whole)) && (fraction == "" || isDigits(fraction))
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// isBase64Chunk reports whether token looks like a run of base64. Long
+// identifiers share its alphabet, so the token also needs what encoded bytes
+// have and names lack: letters of both cases in similar numbers, and digits,
+// '+' or '/' sprinkled through it or '=' padding at its end.
+func isBase64Chunk(token string) bool {
+	if len(token) < 16 {
+		return false
+	}
+	upper, lower, marks := 0, 0, 0
+	for _, r := range token {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper++
+		case r >= 'a' && r <= 'z':
+			lower++
+		case r >= '0' && r <= '9', r == '+', r == '/':
+			marks++
+		case r == '=':
+		default:
+			return false
+		}
+	}
+	letters := upper + lower
+	if upper*4 < letters || lower*4 < letters {
+		return false
+	}
+	return marks*32 >= len(token) || strings.HasSuffix(token, "=")
+}
+
+// isHexBytes reports whether tokens are all short groups of hex digits, at
+// least one with a digit in it, as in a dump without an offset column.
+func isHexBytes(tokens []string) bool {
+	digits := false
+	for _, token := range tokens {
+		if len(token)%2 != 0 || len(token) > 8 || strings.Trim(token, "0123456789abcdefABCDEF") != "" {
+			return false
+		}
+		digits = digits || strings.ContainsAny(token, "0123456789")
+	}
+	return digits
+}
+
+// isDataLine reports whether line is made up almost entirely of numbers, hex or
+// base64, as in embedded certificates, hex dumps and numeric tables.
+func isDataLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(trimmed) < dataLineMinLength {
+		return false
+	}
+	if hexDumpLine.MatchString(trimmed) {
+		return true
+	}
+	tokens := strings.FieldsFunc(trimmed, isDataSeparator)
+	if isHexBytes(tokens) {
+		return true
+	}
+	total, data := 0, 0
+	for _, token := range tokens {
+		total += len(token)
+		if isDataToken(token) {
+			data += len(token)
+		}
+	}
+	return total > 0 && float64(data) >= dataLineThreshold*float64(total)
+}
+
+// squashDataRuns splits the shown segments around every run of more than
+// minRun consecutive data lines, replacing each run with a dataMarker segment.
+// The lines around a run keep their real line numbers.
+func squashDataRuns(lines []string, segments []segment, minRun int) []segment {
+	var squashed []segment
+	for _, s := range segments {
+		if s.marker != "" {
+			squashed = append(squashed, s)
+			continue
+		}
+		next := s.start
+		for i := s.start; i <= s.end; {
+			if !isDataLine(lines[i]) {
+				i++
+				continue
+			}
+			end := i
+			for end < s.end && isDataLine(lines[end+1]) {
+				end++
+			}
+			if run := end - i + 1; run > minRun {
+				if i > next {
+					squashed = append(squashed, segment{lineRange: lineRange{next, i - 1}})
+				}
+				squashed = append(squashed, segment{lineRange{i, end}, fmt.Sprintf(dataMarker, run)})
+				next = end + 1
+			}
+			i = end + 1
+		}
+		if next <= s.end {
+			squashed = append(squashed, segment{lineRange: lineRange{next, s.end}})
+		}
+	}
+	return squashed
+}
@@ -0,0 +1,207 @@
+package files2prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestIsDataLine(t *testing.T) {
+	tests := map[string]struct {
+		line string
+		data bool
+	}{
+		// Embedded certificates and keys
+		"pem body":      {"MIIDdzCCAl+gAwIBAgIEAgAAuTANBgkqhkiG9w0BAQUFADBaMQswCQYDVQQGEwJJ", true},
+		"quoted base64": {`	"MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAu1SU1LfVLPHCozMxH2Mo",`, true},
+		"base64 tail":   {"4pLvlp2qE0oXbJ8lQg==", true},
+		// Hex dumps
+		"xxd":            {"00000010: 0200 3e00 0100 0000 c045 4000 0000 0000  ..>......E@.....", true},
+		"hexdump -C":     {"00000000  7f 45 4c 46 02 01 01 00  00 00 00 00 00 00 00 00  |.ELF............|", true},
+		"byte array":     {"	0x7f, 0x45, 0x4c, 0x46, 0x02, 0x01, 0x01, 0x00,", true},
+		"bare hex bytes": {"de ad be ef 00 11 22 33 44 55 66 77", true},
+		// Numeric tables
+		"csv row":         {"2024,1,15,3.14159,-0.5,1e-3,42", true},
+		"aligned columns": {"  12.5    13.75   -2.0    100", true},
+		"go literal row":  {"	{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},", true},
+		// Dense but legitimate code
+		"assignment":      {"	x := y*2 + offset[i] - 1", false},
+		"function call":   {"	return fmt.Sprintf(\"%d:%d\", a, b)", false},
+		"identifier list": {"	ErrNotFound, ErrPermission, ErrExist, ErrClosed,", false},
+		"long identifier": {"	configurationManagerFactoryBuilder.Build()", false},
+		"digit in name":   {"	base64EncodedPayloadV2 = decodeUTF16LE(utf8ToRunes)", false},
+		"import path":     {"	\"golang.org/x/crypto/chacha20poly1305\"", false},
+		"hex words":       {"	cafe, face, bead, added, faded, decade", false},
+		"comment":         {"// 1024 bytes per block, 4 blocks per page", false},
+		"mixed literal":   {"	{Name: \"a\", Size: 1, Mode: 0o644},", false},
+		"url":             {"	const api = \"https://example.com/v1/items/42\"", false},
+		// Lines too short to judge
+		"short number":  {"	42,", false},
+		"closing brace": {"}", false},
+		"blank":         {"", false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.data, isDataLine(tt.line), tt.line)
+		})
+	}
+}
+
+// dataFixtures are files embedding data blocks, with the number of data lines
+// each block is expected to squash to.
+var dataFixtures = map[string]struct {
+	content string
+	runs    []int
+}{
+	"certificate": {
+		content: `package tlsutil
+
+// testCert is a self-signed certificate used by the tests.
+const testCert = ` + "`" + `-----BEGIN CERTIFICATE-----
+MIIDdzCCAl+gAwIBAgIEAgAAuTANBgkqhkiG9w0BAQUFADBaMQswCQYDVQQGEwJJ
+RTESMBAGA1UEChMJQmFsdGltb3JlMRMwEQYDVQQLEwpDeWJlclRydXN0MSIwIAYD
+VQQDExlCYWx0aW1vcmUgQ3liZXJUcnVzdCBSb290MB4XDTAwMDUxMjE4NDYwMFoX
+DTI1MDUxMjIzNTkwMFowWjELMAkGA1UEBhMCSUUxEjAQBgNVBAoTCUJhbHRpbW9y
+ZTETMBEGA1UECxMKQ3liZXJUcnVzdDEiMCAGA1UEAxMZQmFsdGltb3JlIEN5YmVy
+VHJ1c3QgUm9vdDCCASIwDQYJKoZIhvcNAQEBBQADggEPADCCAQoCggEBAKMEuyKr
+4pLvlp2qE0oXbJ8lQg==
+-----END CERTIFICATE-----` + "`" + `
+
+func load() error {
+	return parse(testCert)
+}
+`,
+		runs: []int{7},
+	},
+	"hex dump": {
+		content: `# Header of the test binary
+00000000: 7f45 4c46 0201 0100 0000 0000 0000 0000  .ELF............
+00000010: 0200 3e00 0100 0000 c045 4000 0000 0000  ..>......E@.....
+00000020: 4000 0000 0000 0000 e82b 0000 0000 0000  @........+......
+00000030: 0000 0000 4000 3800 0a00 4000 1f00 1e00  ....@.8...@.....
+00000040: 0600 0000 0400 0000 4000 0000 0000 0000  ........@.......
+# End of header
+`,
+		runs: []int{5},
+	},
+	"numeric table": {
+		content: `var samples = [][]float64{
+	{0.12, 0.53, 0.98, 1.21},
+	{0.15, 0.57, 1.02, 1.33},
+	{0.19, 0.61, 1.07, 1.38},
+	{0.22, 0.64, 1.11, 1.45},
+	{0.27, 0.69, 1.18, 1.52},
+	{0.31, 0.73, 1.24, 1.60},
+}
+
+func mean(xs []float64) float64 {
+`,
+		runs: []int{6},
+	},
+	"byte array": {
+		content: `static const unsigned char icon[] = {
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a,
+	0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0xf3, 0xff,
+};
+`,
+		runs: []int{4},
+	},
+	// Dense but legitimate code must never be squashed
+	"dense code": {
+		content: `func checksum(p []byte) uint32 {
+	var a, b uint32 = 1, 0
+	for i := 0; i < len(p); i++ {
+		a = (a + uint32(p[i])) % 65521
+		b = (b + a) % 65521
+	}
+	return b<<16 | a
+}
+
+var primes = []int{
+	2, 3, 5, 7,
+	11, 13,
+}
+
+var masks = map[string]uint8{
+	"read":  0x4,
+	"write": 0x2,
+	"exec":  0x1,
+}
+`,
+	},
+}
+
+func TestSquashDataRuns(t *testing.T) {
+	for name, fixture := range dataFixtures {
+		t.Run(name, func(t *testing.T) {
+			lines := strings.Split(fixture.content, "\n")
+			var runs []int
+			for _, s := range squashDataRuns(lines, wholeFile(len(lines)), 3) {
+				if s.marker != "" {
+					runs = append(runs, s.end-s.start+1)
+				}
+			}
+			assert.Equal(t, fixture.runs, runs)
+		})
+	}
+}
+
+func TestSquashDataRunsThreshold(t *testing.T) {
+	lines := strings.Split(dataFixtures["byte array"].content, "\n")
+	// A run is squashed only when it is longer than the threshold
+	assert.Equal(t, wholeFile(len(lines)), squashDataRuns(lines, wholeFile(len(lines)), 4))
+	assert.Len(t, squashDataRuns(lines, wholeFile(len(lines)), 3), 3)
+}
+
+func TestProcessFileSquashDataBlocks(t *testing.T) {
+	content := "header\n" + strings.Repeat("0123456789abcdef0123456789abcdef\n", 5) + "footer\n"
+	path := filepath.Join(t.TempDir(), "blob.dat")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	tests := []struct {
+		name     string
+		config   config.Config
+		expected string
+	}{
+		{
+			name:     "plain",
+			config:   config.Config{SquashDataBlocks: 2},
+			expected: path + "\n---\nheader\n[... 5 lines of data omitted ...]\nfooter\n---\n\n",
+		},
+		{
+			name:     "line numbers keep their positions",
+			config:   config.Config{SquashDataBlocks: 2, LineNumbersCompact: true},
+			expected: path + "\n---\n1:header\n[... 5 lines of data omitted ...]\n7:footer\n---\n\n",
+		},
+		{
+			name:     "with grep context",
+			config:   config.Config{SquashDataBlocks: 2, LineNumbersCompact: true, Grep: "footer", GrepContext: 4},
+			expected: path + "\n---\n...\n[... 4 lines of data omitted ...]\n7:footer\n---\n\n",
+		},
+		{
+			name:     "run not longer than the threshold",
+			config:   config.Config{SquashDataBlocks: 5},
+			expected: path + "\n---\n" + content + "---\n\n",
+		},
+		{
+			name:     "disabled",
+			config:   config.Config{},
+			expected: path + "\n---\n" + content + "---\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderPath(path, tt.config, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, out)
+		})
+	}
+}
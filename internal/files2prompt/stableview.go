@@ -0,0 +1,122 @@
+package files2prompt
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// modifiedMarker labels the documents of files that changed after
+// --stable-view captured them.
+const modifiedMarker = "modified during run"
+
+// viewEntry is what --stable-view captured of a file before emission.
+type viewEntry struct {
+	size int64
+	sum  [sha256.Size]byte
+}
+
+// stableView pins the content of the included files of a run, keyed by path,
+// so that files changing between the capture and their emission are noticed.
+// Only the size and hash of each file are held, never its content. A nil
+// stableView notices nothing.
+type stableView map[string]viewEntry
+
+// viewCaptured is called once a stableView is captured, for tests to change
+// files between the capture and their emission.
+var viewCaptured func()
+
+// captureView hashes the included files of plan with workers goroutines, or
+// returns nil without --stable-view or with --list, which reads no content. A
+// file that cannot be read is left out, to fail when it is emitted.
+func captureView(plan []PlannedFile, workers int, config config.Config) stableView {
+	if !config.StableView || config.ListOnly {
+		return nil
+	}
+	var paths []string
+	for _, f := range plan {
+		if f.Included && !f.stub() {
+			paths = append(paths, f.Path)
+		}
+	}
+
+	view := make(stableView, len(paths))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	limit := readLimit(config)
+	for range workers {
+		wg.Go(func() {
+			for path := range jobs {
+				if entry, ok := hashFile(path, limit); ok {
+					mu.Lock()
+					view[path] = entry
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	log.Debugf("Captured %d of %d files for --stable-view", len(view), len(paths))
+	if viewCaptured != nil {
+		viewCaptured()
+	}
+	return view
+}
+
+// hashFile returns the viewEntry of the file at path, reading no more than
+// limit bytes and one past it.
+func hashFile(path string, limit int64) (viewEntry, bool) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return viewEntry{}, false
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(f, limit+1))
+	if err != nil {
+		return viewEntry{}, false
+	}
+	entry := viewEntry{size: n}
+	h.Sum(entry.sum[:0])
+	return entry, true
+}
+
+// modified reports whether read, the read of the file at path, differs from
+// what v captured of it.
+func (v stableView) modified(path string, read fileRead) bool {
+	captured, ok := v[path]
+	if !ok {
+		return false
+	}
+	if read.scan != nil {
+		return read.scan.size != captured.size || read.scan.sum() != captured.sum
+	}
+	return int64(len(read.content)) != captured.size || sha256.Sum256(read.content) != captured.sum
+}
+
+// modifiedSuffix returns the marker ending the path line of a plain or Markdown
+// document whose file changed after --stable-view captured it, or "".
+func modifiedSuffix(state *emitState) string {
+	if !state.modified {
+		return ""
+	}
+	return " [" + modifiedMarker + "]"
+}
+
+// modifiedAttr returns the attribute marking a Claude XML document whose file
+// changed after --stable-view captured it, or "".
+func modifiedAttr(state *emitState) string {
+	if !state.modified {
+		return ""
+	}
+	return ` status="` + modifiedMarker + `"`
+}
@@ -0,0 +1,137 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// withViewCaptured calls captured once --stable-view captured its files, for
+// the rest of the test.
+func withViewCaptured(t *testing.T, captured func()) {
+	t.Helper()
+	original := viewCaptured
+	viewCaptured = captured
+	t.Cleanup(func() { viewCaptured = original })
+}
+
+func TestStableView(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.Config
+		stream   bool
+		expected string
+	}{
+		{
+			name:   "plain",
+			config: config.Config{StableView: true},
+			expected: "a.txt\n---\nalpha\n---\n\n" +
+				"b.txt [modified during run]\n---\nbravo, edited\n---\n\n" +
+				"c.txt\n---\ncharlie\n---\n\n",
+		},
+		{
+			name:   "streamed",
+			config: config.Config{StableView: true},
+			stream: true,
+			expected: "a.txt\n---\nalpha\n---\n\n" +
+				"b.txt [modified during run]\n---\nbravo, edited\n---\n\n" +
+				"c.txt\n---\ncharlie\n---\n\n",
+		},
+		{
+			name:   "markdown",
+			config: config.Config{StableView: true, Markdown: true, HeaderStats: true},
+			expected: "# Files\n\n## a.txt (1 line, 6 B)\n\n```\nalpha\n```\n\n" +
+				"## b.txt (1 line, 14 B) [modified during run]\n\n```\nbravo, edited\n```\n\n" +
+				"## c.txt (1 line, 8 B)\n\n```\ncharlie\n```\n\n",
+		},
+		{
+			name:   "claude xml",
+			config: config.Config{StableView: true, ClaudeXML: true},
+			expected: "<documents>\n" +
+				"<document index=\"1\">\n<source>a.txt</source>\n<document_content>\nalpha\n</document_content>\n</document>\n" +
+				"<document index=\"2\" status=\"modified during run\">\n<source>b.txt</source>\n<document_content>\nbravo, edited\n</document_content>\n</document>\n" +
+				"<document index=\"3\">\n<source>c.txt</source>\n<document_content>\ncharlie\n</document_content>\n</document>\n" +
+				"</documents>\n",
+		},
+		{
+			name:   "without --stable-view",
+			config: config.Config{},
+			expected: "a.txt\n---\nalpha\n---\n\n" +
+				"b.txt\n---\nbravo, edited\n---\n\n" +
+				"c.txt\n---\ncharlie\n---\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"a.txt": "alpha\n", "b.txt": "bravo\n", "c.txt": "charlie\n"})
+			t.Chdir(dir)
+			if tt.stream {
+				withStreamThreshold(t, 1)
+			}
+			// Without --stable-view nothing is captured, so edit up front
+			edit := func() {
+				require.NoError(t, os.WriteFile("b.txt", []byte("bravo, edited\n"), 0o600))
+				require.NoError(t, os.WriteFile("d.txt", []byte("delta\n"), 0o600))
+			}
+			if tt.config.StableView {
+				withViewCaptured(t, edit)
+			}
+
+			cfg := tt.config
+			cfg.Paths = []string{"a.txt", "b.txt", "c.txt"}
+			plan, err := Plan(context.Background(), cfg, false)
+			require.NoError(t, err)
+			if !tt.config.StableView {
+				edit()
+			}
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &buf, plan)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+			assert.Equal(t, 3, summary.Files)
+		})
+	}
+}
+
+func TestStableViewWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": "alpha\n", "b.txt": "bravo\n", "c.txt": "charlie\n"})
+	t.Chdir(dir)
+	withViewCaptured(t, func() {
+		require.NoError(t, os.WriteFile("a.txt", []byte("alpha, edited\n"), 0o600))
+		require.NoError(t, os.WriteFile("c.txt", []byte("charlie, edited\n"), 0o600))
+		require.NoError(t, os.WriteFile("d.txt", []byte("delta\n"), 0o600))
+	})
+
+	var buf bytes.Buffer
+	summary, err := Generate(context.Background(), config.Config{
+		Paths: []string{"."}, StableView: true, Tree: true, EmbedWarnings: true,
+	}, &buf, nil)
+	require.NoError(t, err)
+	// d.txt was created after the capture, so neither the tree nor the documents have it
+	assert.Equal(t, 3, summary.Files)
+	assert.Contains(t, buf.String(), "├── a.txt\n├── b.txt\n└── c.txt\n")
+	assert.NotContains(t, buf.String(), "d.txt")
+	assert.Contains(t, buf.String(), "2 files changed while the output was being produced, so their documents may not match the rest of it: "+
+		dir+"/a.txt, "+dir+"/c.txt.")
+}
+
+func TestStableViewModified(t *testing.T) {
+	content := []byte("alpha\n")
+	scan := &contentScan{}
+	_, _ = scan.Write(content)
+	view := stableView{"a.txt": {size: 6, sum: scan.sum()}}
+
+	assert.False(t, view.modified("a.txt", fileRead{content: content}))
+	assert.False(t, view.modified("a.txt", fileRead{scan: scan}))
+	assert.True(t, view.modified("a.txt", fileRead{content: []byte("alpha!")}))
+	assert.False(t, view.modified("b.txt", fileRead{content: []byte("bravo\n")}), "files not captured are not checked")
+	assert.False(t, stableView(nil).modified("a.txt", fileRead{content: content}))
+}
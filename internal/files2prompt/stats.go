@@ -0,0 +1,242 @@
+package files2prompt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// statsTopExtensions is how many extensions the extension chart lists before
+// folding the rest into a single "other" bucket.
+const statsTopExtensions = 10
+
+// statsBarWidth is the width in cells of the longest bar in a chart.
+const statsBarWidth = 30
+
+// Bucket is a single labelled count in a histogram.
+type Bucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// TreeStats describes the files a run would select. The histograms carry raw
+// counts so that the charts written by WriteStatsReport are purely presentational.
+type TreeStats struct {
+	Files      int      `json:"files"`
+	Bytes      int64    `json:"bytes"`
+	Sizes      []Bucket `json:"size_buckets"`
+	Ages       []Bucket `json:"age_buckets"`
+	Extensions []Bucket `json:"extensions"`
+}
+
+// sizeBuckets are the upper bounds (exclusive) of the file size histogram; the
+// last bucket holds everything larger.
+var sizeBuckets = []struct {
+	label string
+	below int64
+}{
+	{"<1K", 1 << 10},
+	{"1–10K", 10 << 10},
+	{"10–100K", 100 << 10},
+	{"100K–1M", 1 << 20},
+	{"1–10M", 10 << 20},
+	{"10M+", -1},
+}
+
+// ageBuckets are the upper bounds (exclusive) of the file age histogram; the
+// last bucket holds everything older.
+var ageBuckets = []struct {
+	label string
+	below time.Duration
+}{
+	{"<1 week", 7 * 24 * time.Hour},
+	{"<1 month", 30 * 24 * time.Hour},
+	{"<1 year", 365 * 24 * time.Hour},
+	{"1 year+", -1},
+}
+
+// sizeBucket returns the index of the size histogram bucket for size.
+func sizeBucket(size int64) int {
+	for i, b := range sizeBuckets {
+		if size < b.below {
+			return i
+		}
+	}
+	return len(sizeBuckets) - 1
+}
+
+// ageBucket returns the index of the age histogram bucket for a file last modified age ago.
+func ageBucket(age time.Duration) int {
+	for i, b := range ageBuckets {
+		if age < b.below {
+			return i
+		}
+	}
+	return len(ageBuckets) - 1
+}
+
+// topExtensions returns the n most common extensions in counts, most frequent
+// first with ties broken by name, followed by an "other" bucket for the rest.
+func topExtensions(counts map[string]int, n int) []Bucket {
+	buckets := make([]Bucket, 0, len(counts))
+	for ext, count := range counts {
+		buckets = append(buckets, Bucket{Label: ext, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Count != buckets[j].Count {
+			return buckets[i].Count > buckets[j].Count
+		}
+		return buckets[i].Label < buckets[j].Label
+	})
+	if len(buckets) <= n {
+		return buckets
+	}
+	other := Bucket{Label: "other"}
+	for _, b := range buckets[n:] {
+		other.Count += b.Count
+	}
+	return append(buckets[:n], other)
+}
+
+// CollectStats gathers size, age and extension histograms for the files config
+// would select, from a single walk. Ages are measured relative to now.
+func CollectStats(ctx context.Context, config config.Config, now time.Time) (TreeStats, error) {
+	plan, _, err := planFiles(ctx, config, nil, nil)
+	if err != nil {
+		return TreeStats{}, err
+	}
+
+	stats := TreeStats{
+		Sizes: make([]Bucket, len(sizeBuckets)),
+		Ages:  make([]Bucket, len(ageBuckets)),
+	}
+	for i, b := range sizeBuckets {
+		stats.Sizes[i].Label = b.label
+	}
+	for i, b := range ageBuckets {
+		stats.Ages[i].Label = b.label
+	}
+	exts := map[string]int{}
+	for _, f := range plan {
+		if !f.Included {
+			continue
+		}
+		stats.Files++
+		stats.Bytes += f.Size
+		stats.Sizes[sizeBucket(f.Size)].Count++
+		stats.Ages[ageBucket(now.Sub(f.ModTime))].Count++
+		ext := filepath.Ext(f.Path)
+		if ext == "" {
+			ext = "(none)"
+		}
+		exts[ext]++
+	}
+	stats.Extensions = topExtensions(exts, statsTopExtensions)
+	return stats, nil
+}
+
+// chartGlyphs are the characters used to draw bars and sparklines.
+type chartGlyphs struct {
+	// bar holds the partial cells of a bar, from empty to a full cell
+	bar []string
+	// spark holds the sparkline levels, from lowest to highest
+	spark []string
+}
+
+var (
+	unicodeGlyphs = chartGlyphs{
+		bar:   []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉", "█"},
+		spark: []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"},
+	}
+	asciiGlyphs = chartGlyphs{
+		bar:   []string{"", "#"},
+		spark: []string{".", ":", "-", "=", "+", "*", "#"},
+	}
+)
+
+func glyphsFor(unicode bool) chartGlyphs {
+	if unicode {
+		return unicodeGlyphs
+	}
+	return asciiGlyphs
+}
+
+// renderBar draws count as a bar scaled so that largest fills width cells. Any
+// non-zero count is drawn at least one partial cell long.
+func renderBar(count, largest, width int, glyphs chartGlyphs) string {
+	if count <= 0 || largest <= 0 {
+		return ""
+	}
+	steps := len(glyphs.bar) - 1
+	units := count * width * steps / largest
+	if units == 0 {
+		units = 1
+	}
+	return strings.Repeat(glyphs.bar[steps], units/steps) + glyphs.bar[units%steps]
+}
+
+// renderSparkline draws one character per count, scaled to the largest count.
+func renderSparkline(counts []int, glyphs chartGlyphs) string {
+	largest := 0
+	for _, c := range counts {
+		largest = max(largest, c)
+	}
+	var b strings.Builder
+	for _, c := range counts {
+		if c == 0 {
+			b.WriteString(" ")
+			continue
+		}
+		b.WriteString(glyphs.spark[(c*len(glyphs.spark)-1)/largest])
+	}
+	return b.String()
+}
+
+// writeHistogram writes a titled chart for buckets: a sparkline on the title
+// line, followed by one labelled bar and count per bucket.
+func writeHistogram(b *strings.Builder, title string, buckets []Bucket, glyphs chartGlyphs) {
+	counts := make([]int, len(buckets))
+	labelWidth, largest := 0, 0
+	for i, bucket := range buckets {
+		counts[i] = bucket.Count
+		labelWidth = max(labelWidth, utf8.RuneCountInString(bucket.Label))
+		largest = max(largest, bucket.Count)
+	}
+	fmt.Fprintf(b, "%s\n", strings.TrimRight(title+"  "+renderSparkline(counts, glyphs), " "))
+	for _, bucket := range buckets {
+		bar := renderBar(bucket.Count, largest, statsBarWidth, glyphs)
+		fmt.Fprintf(b, "  %-*s  %-*s  %d\n", labelWidth, bucket.Label, statsBarWidth, bar, bucket.Count)
+	}
+}
+
+// WriteStatsReport renders stats as text histograms, using Unicode block
+// characters unless unicode is false, in which case bars are drawn with '#'.
+func WriteStatsReport(w io.Writer, stats TreeStats, unicode bool) error {
+	glyphs := glyphsFor(unicode)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %s, %s\n\n", stats.Files, plural(stats.Files, "file", "files"), formatBytes(stats.Bytes))
+	writeHistogram(&b, "File sizes", stats.Sizes, glyphs)
+	b.WriteString("\n")
+	writeHistogram(&b, "File ages", stats.Ages, glyphs)
+	if len(stats.Extensions) > 0 {
+		b.WriteString("\n")
+		writeHistogram(&b, "Extensions", stats.Extensions, glyphs)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteStatsJSON writes stats as indented JSON.
+func WriteStatsJSON(w io.Writer, stats TreeStats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats)
+}
@@ -0,0 +1,161 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestSizeBucket(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "<1K"},
+		{1023, "<1K"},
+		{1024, "1–10K"},
+		{10<<10 - 1, "1–10K"},
+		{10 << 10, "10–100K"},
+		{100 << 10, "100K–1M"},
+		{1 << 20, "1–10M"},
+		{10 << 20, "10M+"},
+		{6 << 30, "10M+"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, sizeBuckets[sizeBucket(tt.size)].label, "size %d", tt.size)
+	}
+}
+
+func TestAgeBucket(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{-time.Hour, "<1 week"},
+		{0, "<1 week"},
+		{6 * day, "<1 week"},
+		{7 * day, "<1 month"},
+		{29 * day, "<1 month"},
+		{30 * day, "<1 year"},
+		{365 * day, "1 year+"},
+		{10 * 365 * day, "1 year+"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, ageBuckets[ageBucket(tt.age)].label, "age %s", tt.age)
+	}
+}
+
+func TestTopExtensions(t *testing.T) {
+	counts := map[string]int{".go": 5, ".md": 2, ".rs": 2, ".py": 1, ".c": 1}
+	assert.Equal(t, []Bucket{{".go", 5}, {".md", 2}, {".rs", 2}, {"other", 2}}, topExtensions(counts, 3))
+	assert.Equal(t, []Bucket{{".go", 5}, {".md", 2}, {".rs", 2}, {".c", 1}, {".py", 1}}, topExtensions(counts, 5))
+	assert.Empty(t, topExtensions(map[string]int{}, 10))
+}
+
+func TestRenderBar(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		largest  int
+		unicode  bool
+		expected string
+	}{
+		{"full", 10, 10, true, "██████████"},
+		{"half", 5, 10, true, "█████"},
+		{"fraction", 3, 16, true, "█▉"},
+		{"tiny count still visible", 1, 1000, true, "▏"},
+		{"zero", 0, 10, true, ""},
+		{"ascii full", 10, 10, false, "##########"},
+		{"ascii fraction", 3, 16, false, "#"},
+		{"ascii tiny", 1, 1000, false, "#"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, renderBar(tt.count, tt.largest, 10, glyphsFor(tt.unicode)))
+		})
+	}
+}
+
+func TestRenderSparkline(t *testing.T) {
+	assert.Equal(t, "▁▄█ ", renderSparkline([]int{1, 4, 8, 0}, unicodeGlyphs))
+	assert.Equal(t, ".=# ", renderSparkline([]int{1, 4, 7, 0}, asciiGlyphs))
+	assert.Equal(t, "   ", renderSparkline([]int{0, 0, 0}, unicodeGlyphs))
+}
+
+func TestCollectStats(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{"main.go", 100, time.Hour},
+		{"util.go", 2 << 10, 10 * 24 * time.Hour},
+		{"README.md", 20 << 10, 400 * 24 * time.Hour},
+		{"Makefile", 10, 2 * 24 * time.Hour},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		require.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("x"), f.size), 0o600))
+		mtime := now.Add(-f.age)
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	stats, err := CollectStats(context.Background(), config.Config{Paths: []string{dir}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Files)
+	assert.Equal(t, int64(100+2<<10+20<<10+10), stats.Bytes)
+	assert.Equal(t, []Bucket{{"<1K", 2}, {"1–10K", 1}, {"10–100K", 1}, {"100K–1M", 0}, {"1–10M", 0}, {"10M+", 0}}, stats.Sizes)
+	assert.Equal(t, []Bucket{{"<1 week", 2}, {"<1 month", 1}, {"<1 year", 0}, {"1 year+", 1}}, stats.Ages)
+	assert.Equal(t, []Bucket{{".go", 2}, {"(none)", 1}, {".md", 1}}, stats.Extensions)
+
+	// The JSON carries the same raw counts the charts are drawn from
+	var buf bytes.Buffer
+	require.NoError(t, WriteStatsJSON(&buf, stats))
+	var decoded TreeStats
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, stats, decoded)
+	assert.Contains(t, buf.String(), `"size_buckets"`)
+}
+
+func TestWriteStatsReport(t *testing.T) {
+	stats := TreeStats{
+		Files:      3,
+		Bytes:      3 << 10,
+		Sizes:      []Bucket{{"<1K", 2}, {"1–10K", 1}},
+		Ages:       []Bucket{{"<1 week", 3}, {"1 year+", 0}},
+		Extensions: []Bucket{{".go", 3}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteStatsReport(&buf, stats, false))
+	pad := func(bar string) string { return bar + strings.Repeat(" ", statsBarWidth-len(bar)) }
+	expected := "3 files, 3.0 KiB\n\n" +
+		"File sizes  #=\n" +
+		"  <1K    " + pad(strings.Repeat("#", 30)) + "  2\n" +
+		"  1–10K  " + pad(strings.Repeat("#", 15)) + "  1\n" +
+		"\n" +
+		"File ages  #\n" +
+		"  <1 week  " + pad(strings.Repeat("#", 30)) + "  3\n" +
+		"  1 year+  " + pad("") + "  0\n" +
+		"\n" +
+		"Extensions  #\n" +
+		"  .go  " + pad(strings.Repeat("#", 30)) + "  3\n"
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteStatsReport(&buf, stats, true))
+	assert.Contains(t, buf.String(), "File sizes  █▄\n")
+	assert.Contains(t, buf.String(), "  1–10K  "+strings.Repeat("█", 15)+strings.Repeat(" ", 15)+"  1\n")
+}
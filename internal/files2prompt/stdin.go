@@ -0,0 +1,46 @@
+package files2prompt
+
+import (
+	"io"
+	"slices"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// stdinPath is the path argument standing for the content of standard input.
+const stdinPath = "-"
+
+// defaultStdinName is the source name of the standard input document when
+// --stdin-name is not given.
+const defaultStdinName = "stdin"
+
+// readsStdin reports whether the path arguments of config include "-", so that
+// standard input is a document rather than a list of paths.
+func readsStdin(config config.Config) bool {
+	return slices.Contains(config.Paths, stdinPath)
+}
+
+// stdinName returns the source name of the standard input document.
+func stdinName(config config.Config) string {
+	if config.StdinName != "" {
+		return config.StdinName
+	}
+	return defaultStdinName
+}
+
+// emitStdin emits config.StdinContent as a document named by --stdin-name,
+// labelled with the language fenceLanguage finds for that name and content.
+// Like --cmd output, it is emitted whole and after the files.
+func emitStdin(config config.Config, writer io.Writer, state *emitState) error {
+	if !readsStdin(config) {
+		return nil
+	}
+	// --grep selects files; standard input is always emitted whole
+	grep := state.grep
+	state.grep = nil
+	defer func() { state.grep = grep }()
+
+	name := stdinName(config)
+	content := state.redact.content(name, config.StdinContent)
+	return emitDocument(name, content, fenceLanguage(name, config.StdinContent, config), config, writer, state)
+}
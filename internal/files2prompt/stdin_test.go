@@ -0,0 +1,94 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestGenerateStdinDocument(t *testing.T) {
+	const content = "package main\n\nfunc main() {}\n"
+	tests := []struct {
+		name     string
+		config   config.Config
+		content  string
+		expected string
+		files    int
+	}{
+		{
+			name:     "plain",
+			config:   config.Config{Paths: []string{"-"}},
+			expected: "stdin\n---\n" + content + "---\n\n",
+			files:    1,
+		},
+		{
+			name:     "markdown named",
+			config:   config.Config{Paths: []string{"-"}, StdinName: "main.go", Markdown: true},
+			expected: "## main.go\n\n```go\n" + content + "```\n\n",
+			files:    1,
+		},
+		{
+			name:     "markdown detected",
+			config:   config.Config{Paths: []string{"-"}, Markdown: true, DetectLang: true},
+			content:  "#!/bin/sh\necho hi\n",
+			expected: "## stdin\n\n```bash\n#!/bin/sh\necho hi\n```\n\n",
+			files:    1,
+		},
+		{
+			name:   "claude xml after files, before commands",
+			config: config.Config{Paths: []string{"-", "testdata/file1.txt"}, Commands: []string{"ok"}, ClaudeXML: true},
+			expected: "<documents>\n" +
+				"<document index=\"1\">\n<source>testdata/file1.txt</source>\n<document_content>\nline 1\nline 2\nline 3\n</document_content>\n</document>\n" +
+				"<document index=\"2\">\n<source>stdin</source>\n<document_content>\n" + content + "</document_content>\n</document>\n" +
+				"<document index=\"3\">\n<source>ok</source>\n<document_content>\nall good\n</document_content>\n</document>\n" +
+				"</documents>\n",
+			files: 3,
+		},
+		{
+			name:     "without -, stdin is not a document",
+			config:   config.Config{StdinPaths: []string{"testdata/file1.txt"}},
+			expected: "testdata/file1.txt\n---\nline 1\nline 2\nline 3\n---\n\n",
+			files:    1,
+		},
+	}
+	withCommandRunner(t, fakeRunner)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.StdinContent = content
+			if tt.content != "" {
+				cfg.StdinContent = tt.content
+			}
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+			assert.Equal(t, tt.files, summary.Files)
+		})
+	}
+}
+
+func TestStdinNotWalked(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n", "-": "not stdin\n"})
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{"-", filepath.Join(root, "a.go")}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.go"}, includedPaths(t, root, plan))
+
+	// A file named "-" is still reachable by another path
+	plan, err = Plan(context.Background(), config.Config{Paths: []string{"-", filepath.Join(root, "-")}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-"}, includedPaths(t, root, plan))
+}
+
+func TestStdinCompat(t *testing.T) {
+	_, err := compatMode(config.Config{Compat: string(CompatFilesToPrompt), Paths: []string{"-"}})
+	assert.EqualError(t, err, "--compat files-to-prompt cannot be combined with - (standard input)")
+}
@@ -0,0 +1,318 @@
+package files2prompt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// streamThreshold is the size above which a file is streamed from disk rather
+// than read into memory, when its document shows the whole file.
+var streamThreshold int64 = 8 << 20
+
+// streamChunkSize is how much of a streamed file is read and written at a time.
+const streamChunkSize = 64 << 10
+
+// streamAbove returns the size above which files are streamed for config, or 0
+// when every file must be held in memory: --grep-context, --squash-data-blocks,
+// --head-lines, --tail-lines and --cxml-max-doc-bytes rework the content as a
+// whole, --anonymize rewrites the paths within it, --compat decodes it as the reference tool does, --html marks up each line,
+// --jsonl and --openai encode each document whole, --template executes on it
+// and --format digest hashes it as shown.
+func streamAbove(config config.Config) int64 {
+	switch {
+	case CompatMode(config.Compat) == CompatFilesToPrompt,
+		config.Anonymize,
+		config.Redact,
+		config.HTML,
+		config.JSONL,
+		config.OpenAI,
+		config.TemplatePath != "",
+		// The preview must fit in the head of the scan, and be of the numbered lines
+		config.Format != "" && (digestPreview(config)*utf8.UTFMax > detectSniffBytes || config.LineNumbers || config.LineNumbersCompact),
+		config.Grep != "" && config.GrepContext >= 0,
+		config.SquashDataBlocks > 0,
+		config.HeadLines > 0 || config.TailLines > 0,
+		config.ClaudeXML && config.CXMLMaxDocBytes > 0:
+		return 0
+	}
+	return streamThreshold
+}
+
+// contentScan measures content fed to it in pieces: everything the wrappers of
+// a streamed document need to know before its content is written.
+type contentScan struct {
+	size     int64
+	newlines int64
+	last     byte
+	// backticks is the longest run of backticks in the content, and dashes
+	// the most dashes on a finished line made of dashes only
+	backticks, dashes int
+	// lineDashes counts the dashes of the line being scanned, or is -1 once
+	// it holds anything else
+	lineDashes int
+	// run is the length of the run of last ending the content so far
+	run int
+	// head is the start of the content, for --detect-lang
+	head []byte
+	// digest hashes the content, for --stable-view
+	digest hash.Hash
+}
+
+func (s *contentScan) Write(p []byte) (int, error) {
+	if s.digest == nil {
+		s.digest = sha256.New()
+	}
+	s.digest.Write(p)
+	if len(s.head) < detectSniffBytes {
+		s.head = append(s.head, p[:min(len(p), detectSniffBytes-len(s.head))]...)
+	}
+	for _, b := range p {
+		if b == s.last && s.size > 0 {
+			s.run++
+		} else {
+			s.run = 1
+		}
+		s.last = b
+		s.size++
+		switch b {
+		case '\n':
+			s.newlines++
+			s.dashes = max(s.dashes, s.lineDashes)
+			s.lineDashes = 0
+		case '`':
+			s.backticks = max(s.backticks, s.run)
+		case '-':
+			if s.lineDashes >= 0 {
+				s.lineDashes++
+			}
+		case '\r':
+		default:
+			s.lineDashes = -1
+		}
+	}
+	return len(p), nil
+}
+
+// separator returns the plain-format document separator of the content
+// scanned, as getSeparator does.
+func (s *contentScan) separator() string {
+	return fence('-', max(s.dashes, s.lineDashes))
+}
+
+// lines returns the number of lines scanned, counting a final line without a newline.
+func (s *contentScan) lines() int64 {
+	if s.size > 0 && s.last != '\n' {
+		return s.newlines + 1
+	}
+	return s.newlines
+}
+
+// sum returns the SHA-256 hash of the content scanned.
+func (s *contentScan) sum() [sha256.Size]byte {
+	var sum [sha256.Size]byte
+	if s.digest == nil {
+		return sha256.Sum256(nil)
+	}
+	s.digest.Sum(sum[:0])
+	return sum
+}
+
+// same reports whether s and o scanned content that renders the same wrappers.
+func (s *contentScan) same(o *contentScan) bool {
+	return s.size == o.size && s.newlines == o.newlines && s.last == o.last &&
+		s.backticks == o.backticks && s.separator() == o.separator()
+}
+
+// fence returns the shortest run of c, at least three long, that is longer
+// than longest and so does not occur in content whose longest run of c it is.
+func fence(c byte, longest int) string {
+	return strings.Repeat(string(c), max(3, longest+1))
+}
+
+// readFile reads the file at path for emitting, failing it when larger than
+// limit. A file larger than above, when that is not 0, is only scanned, to be
+// streamed from disk when emitted.
+func readFile(path string, limit, above, zeroSize int64, throttle *readThrottle) fileRead {
+	if above > 0 {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Size() > above {
+			scan, err := scanFile(path, limit, throttle)
+			return fileRead{scan: scan, err: err}
+		}
+	}
+	content, cut, err := openCapped(path, limit, zeroSize, throttle)
+	return fileRead{content: content, cut: cut, err: err}
+}
+
+// scanFile scans the file at path, failing instead of reading further once
+// it proves larger than limit.
+func scanFile(path string, limit int64, throttle *readThrottle) (*contentScan, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scan := &contentScan{}
+	if _, err := io.CopyBuffer(scan, io.LimitReader(throttle.reader(f), limit+1), make([]byte, streamChunkSize)); err != nil {
+		return nil, err
+	}
+	if scan.size > limit {
+		return nil, fmt.Errorf("file exceeds the %s read limit", formatBytes(limit))
+	}
+	return scan, nil
+}
+
+// appendGutter appends the gutter of line n, numbered as lineNumberFormat
+// numbers a document whose last line number has width digits, without going
+// through fmt for every line.
+func appendGutter(dst []byte, config config.Config, width, n int) []byte {
+	if config.LineNumbersCompact {
+		return append(strconv.AppendInt(dst, int64(n), 10), ':')
+	}
+	digits := 1
+	for v := n; v >= 10; v /= 10 {
+		digits++
+	}
+	dst = append(dst, ' ')
+	for range width - digits {
+		dst = append(dst, ' ')
+	}
+	return append(strconv.AppendInt(dst, int64(n), 10), " │ "...)
+}
+
+// streamDocument renders the whole file f, scanned as scan, as emitDocument
+// would, reading it again in chunks so that it is never held in memory. The
+// file is scanned once more on the way, and the run fails if it changed since.
+func streamDocument(f PlannedFile, scan *contentScan, lang string, config config.Config, writer io.Writer, state *emitState) error {
+	file, err := os.Open(f.Path) // #nosec G304
+	if err != nil {
+		log.Warnf("Warning: Skipping file %s due to error: %v", f.Path, err)
+		state.unreadable = append(state.unreadable, f.DisplayPath)
+		return nil
+	}
+	defer file.Close()
+
+	if config.Format != "" {
+		// The scan holds all a digest line tells, so the file is not read again
+		line, err := digestLine(Format(config.Format), f.DisplayPath, scan.size, scan.sum(), lang, scan.head, config)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, line); err != nil {
+			return err
+		}
+		state.index++
+		state.files++
+		return nil
+	}
+
+	stats := docStats{lines: scan.lines(), bytes: scan.size}
+	var opening, closing string
+	// text is where the content goes, escaped in Claude XML mode
+	text := writer
+	var escaped *cxmlText
+	switch {
+	case config.Markdown:
+		backticks := fence('`', scan.backticks)
+		label := f.DisplayPath + headerSuffix(config, stats) + modifiedSuffix(state)
+		opening = fmt.Sprintf("%s%s%s%s\n", markdownLabel(config, label), metadataLine(state), backticks, lang)
+		closing = backticks + "\n" + markdownEnd(config)
+	case config.ClaudeXML:
+		langAttr := ""
+		if config.DetectLang && lang != "" {
+			langAttr = fmt.Sprintf(" language=\"%s\"", escapeXML(lang))
+		}
+		langAttr += headerAttrs(config, stats) + modifiedAttr(state) + metadataAttrs(state)
+		names := cxmlNamesOf(config)
+		opening, closing = names.open(state.index, f.DisplayPath, langAttr), names.close()
+		escaped = names.text(writer)
+		text = escaped
+	default:
+		separator := scan.separator()
+		if config.LineNumbers || config.LineNumbersCompact {
+			// No numbered line is made of dashes only
+			separator = fence('-', 0)
+		}
+		header := f.DisplayPath + headerSuffix(config, stats) + modifiedSuffix(state)
+		if separator != "---" {
+			header += " [sep=" + separator + "]"
+		}
+		opening, closing = header+"\n"+metadataLine(state)+separator+"\n", separator+"\n\n"
+	}
+
+	numbered := config.LineNumbers || config.LineNumbersCompact
+	width := len(strconv.FormatInt(scan.lines(), 10))
+	state.emitted, state.lang, state.streamed = "", lang, true
+	if _, err := io.WriteString(writer, opening); err != nil {
+		return err
+	}
+
+	var seen contentScan
+	var out, prefix []byte
+	buf := make([]byte, streamChunkSize)
+	content := io.TeeReader(io.LimitReader(state.throttle.reader(file), scan.size+1), &seen)
+	line, lineStart := 1, true
+	for {
+		n, readErr := content.Read(buf)
+		chunk := buf[:n]
+		state.ledger.addContentPart(chunk)
+		out = out[:0]
+		for len(chunk) > 0 {
+			if numbered && lineStart {
+				prefix = appendGutter(prefix[:0], config, width, line)
+				state.gutterBytes += int64(len(prefix))
+				out = append(out, prefix...)
+				line++
+			}
+			i := bytes.IndexByte(chunk, '\n')
+			if i < 0 {
+				out = append(out, chunk...)
+				lineStart = false
+				break
+			}
+			out = append(out, chunk[:i+1]...)
+			chunk, lineStart = chunk[i+1:], true
+		}
+		if len(out) > 0 {
+			if _, err := text.Write(out); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read %s: %v", f.Path, readErr)
+		}
+	}
+	if escaped != nil {
+		if err := escaped.flush(); err != nil {
+			return err
+		}
+	}
+	state.ledger.addContentLines(seen.lines())
+	if !seen.same(scan) {
+		return fmt.Errorf("%s changed while it was being read", f.Path)
+	}
+
+	// The closing delimiter is always on a line of its own
+	if !lineStart {
+		closing = "\n" + closing
+	}
+	if _, err := io.WriteString(writer, closing); err != nil {
+		return err
+	}
+	state.index++
+	state.files++
+	return nil
+}
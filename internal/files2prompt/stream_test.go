@@ -0,0 +1,234 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// withStreamThreshold streams every file larger than n bytes for the rest of the test.
+func withStreamThreshold(t *testing.T, n int64) {
+	t.Helper()
+	original := streamThreshold
+	streamThreshold = n
+	t.Cleanup(func() { streamThreshold = original })
+}
+
+func TestContentScan(t *testing.T) {
+	content := "a ```` b\n-- c ----- d\n````\n----\r\n-----x\n| --- | --- |\nlast"
+	for _, size := range []int{1, 2, 3, 7, len(content)} {
+		var scan contentScan
+		for p := content; p != ""; {
+			n := min(size, len(p))
+			_, _ = scan.Write([]byte(p[:n]))
+			p = p[n:]
+		}
+		assert.Equal(t, int64(len(content)), scan.size, "pieces of %d", size)
+		assert.Equal(t, int64(7), scan.lines(), "pieces of %d", size)
+		assert.Equal(t, 4, scan.backticks, "pieces of %d", size)
+		assert.Equal(t, "-----", scan.separator(), "pieces of %d", size)
+		assert.Equal(t, content, string(scan.head), "pieces of %d", size)
+		assert.Equal(t, getBackticks(content), fence('`', scan.backticks))
+		assert.Equal(t, getSeparator(content), scan.separator())
+	}
+}
+
+func TestAppendGutter(t *testing.T) {
+	for _, cfg := range []config.Config{{LineNumbers: true}, {LineNumbersCompact: true}} {
+		for _, total := range []int{1, 9, 10, 99, 100, 12345} {
+			format := lineNumberFormat(cfg, total)
+			for _, n := range []int{1, 7, 10, 99, 100, total} {
+				want := strings.TrimSuffix(fmt.Sprintf(format, n, ""), "\n")
+				assert.Equal(t, want, string(appendGutter(nil, cfg, len(fmt.Sprint(total)), n)), "%q with %d", format, n)
+			}
+		}
+	}
+}
+
+func TestStreamMatchesInMemory(t *testing.T) {
+	root := t.TempDir()
+	// Runs of backticks and dashes, and a long line, across chunk boundaries
+	big := strings.Repeat("x", streamChunkSize-3) + "``````\n" + strings.Repeat("line -\n", 20000) +
+		strings.Repeat("y", 3*streamChunkSize) + "\n-------"
+	writeFiles(t, root, map[string]string{
+		"big.md":        big,
+		"fences.md":     "```go\nfunc main() {}\n```\n---\n",
+		"crlf.py":       "print(1)\r\nprint(2)\r\n",
+		"no-newline.go": "package x",
+		"blank.sh":      "\n\n\n",
+		"script":        "#!/usr/bin/env ruby\nputs 1\n",
+	})
+
+	formats := []config.Config{
+		{},
+		{LineNumbers: true},
+		{Markdown: true, DetectLang: true},
+		{Markdown: true, LineNumbersCompact: true},
+		{ClaudeXML: true, DetectLang: true},
+		{ClaudeXML: true, LineNumbers: true, Tree: true},
+	}
+	inMemory := streamThreshold
+	withStreamThreshold(t, inMemory)
+	for _, cfg := range formats {
+		cfg.Paths = []string{root}
+		generate := func() (string, Summary) {
+			t.Helper()
+			var out bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &out, nil)
+			require.NoError(t, err)
+			return out.String(), summary
+		}
+		streamThreshold = inMemory
+		want, wantSummary := generate()
+		streamThreshold = 1
+		got, gotSummary := generate()
+
+		assert.Equal(t, want, got, "%+v", cfg)
+		assert.Equal(t, wantSummary.Bytes, gotSummary.Bytes, "%+v", cfg)
+		assert.Equal(t, wantSummary.GutterTokens, gotSummary.GutterTokens, "%+v", cfg)
+	}
+}
+
+func TestStreamDocuments(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.go": "package a\n\nfunc A() {}\n", "b.go": "package b\n"})
+	withStreamThreshold(t, 12)
+
+	mirrorDir := t.TempDir()
+	cfg := config.Config{Paths: []string{root}, Stats: true, MirrorTo: mirrorDir}
+	var docs []FileDoc
+	for doc, err := range Documents(context.Background(), cfg, nil) {
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+	require.Len(t, docs, 2)
+	assert.True(t, docs[0].Streamed)
+	assert.Empty(t, docs[0].Rendered)
+	assert.Empty(t, docs[0].Content)
+	assert.Equal(t, FileStats{Path: filepath.Join(root, "a.go"), Bytes: 23, Lines: 3, Tokens: 6}, docs[0].Stats)
+	assert.False(t, docs[1].Streamed)
+	assert.Equal(t, "package b\n", docs[1].Content)
+
+	// Streamed files are mirrored from disk
+	t.Chdir(root)
+	cfg.Paths = []string{"."}
+	_, err := Generate(context.Background(), cfg, io.Discard, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a.go": "package a\n\nfunc A() {}\n", "b.go": "package b\n"}, readTree(t, mirrorDir))
+}
+
+func TestStreamNeedsWholeContent(t *testing.T) {
+	for _, cfg := range []config.Config{
+		{Compat: string(CompatFilesToPrompt)},
+		{Grep: "x", GrepContext: 2},
+		{SquashDataBlocks: 4},
+		{ClaudeXML: true, CXMLMaxDocBytes: 1024},
+	} {
+		assert.Zero(t, streamAbove(cfg), "%+v", cfg)
+	}
+	// --grep alone filters files without reworking their content
+	assert.Equal(t, streamThreshold, streamAbove(config.Config{Grep: "x", GrepContext: -1}))
+}
+
+func TestStreamChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grows.go")
+	require.NoError(t, os.WriteFile(path, []byte("package a\n"), 0o600))
+	scan, err := scanFile(path, readLimit(config.Config{}), nil)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("package a\n\nvar b = 1\n"), 0o600))
+
+	var out bytes.Buffer
+	err = streamDocument(PlannedFile{Path: path}, scan, "go", config.Config{}, &out, newEmitState())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "changed while it was being read")
+}
+
+func TestStreamReadLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.go")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("a", 100)), 0o600))
+	read := readFile(path, 50, 10, DefaultZeroSizeLimit, nil)
+	require.Error(t, read.err)
+	assert.Contains(t, read.err.Error(), "read limit")
+	assert.Nil(t, read.scan)
+
+	read = readFile(path, 200, 10, DefaultZeroSizeLimit, nil)
+	require.NoError(t, read.err)
+	require.NotNil(t, read.scan)
+	assert.Nil(t, read.content)
+	assert.Equal(t, int64(100), read.scan.size)
+}
+
+// writeLargeFile writes size bytes of source-like lines to path.
+func writeLargeFile(tb testing.TB, path string, size int) {
+	tb.Helper()
+	f, err := os.Create(path)
+	require.NoError(tb, err)
+	defer f.Close()
+	chunk := []byte(strings.Repeat("2026-10-14T11:00:00Z level=info msg=\"request served\" status=200\n", 1024))
+	for written := 0; written < size; written += len(chunk) {
+		_, err := f.Write(chunk[:min(len(chunk), size-written)])
+		require.NoError(tb, err)
+	}
+}
+
+func TestStreamBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes a 64 MiB file")
+	}
+	const size = 64 << 20
+	root := t.TempDir()
+	writeLargeFile(t, filepath.Join(root, "server.log"), size)
+	cfg := config.Config{Paths: []string{root}, Markdown: true}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	summary, err := Generate(context.Background(), cfg, io.Discard, nil)
+	require.NoError(t, err)
+	runtime.ReadMemStats(&after)
+
+	assert.Equal(t, 1, summary.Files)
+	assert.Greater(t, summary.Bytes, int64(size))
+	allocated := after.TotalAlloc - before.TotalAlloc
+	assert.Less(t, allocated, uint64(size/16), "allocated %s rendering a %s file", formatBytes(int64(allocated)), formatBytes(size))
+}
+
+// BenchmarkStreamLargeFile renders a 500 MiB file; its B/op stays a small
+// fraction of the file size.
+func BenchmarkStreamLargeFile(b *testing.B) {
+	const size = 500 << 20
+	root := b.TempDir()
+	writeLargeFile(b, filepath.Join(root, "server.log"), size)
+
+	for _, bc := range []struct {
+		name string
+		cfg  config.Config
+	}{
+		{"plain", config.Config{}},
+		{"markdown", config.Config{Markdown: true}},
+		{"line numbers", config.Config{ClaudeXML: true, LineNumbers: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			cfg := bc.cfg
+			cfg.Paths = []string{root}
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := Generate(context.Background(), cfg, io.Discard, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package files2prompt
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// SubmoduleMode selects how git submodules found while walking are treated.
+type SubmoduleMode string
+
+// Submodule modes accepted by --submodules.
+const (
+	// SubmodulesInclude walks submodules like any other directory, applying
+	// only their own .gitignore and .gitattributes rules inside them.
+	SubmodulesInclude SubmoduleMode = "include"
+	// SubmodulesSkip prunes submodule directories from the walk.
+	SubmodulesSkip SubmoduleMode = "skip"
+	// SubmodulesSeparate emits each submodule's files after the superproject's,
+	// under a section labelled with the submodule path.
+	SubmodulesSeparate SubmoduleMode = "separate"
+)
+
+// submoduleMode returns the mode selected by config, defaulting to SubmodulesInclude.
+func submoduleMode(config config.Config) (SubmoduleMode, error) {
+	switch mode := SubmoduleMode(config.Submodules); mode {
+	case "":
+		return SubmodulesInclude, nil
+	case SubmodulesInclude, SubmodulesSkip, SubmodulesSeparate:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid --submodules %q: use %s, %s or %s",
+		config.Submodules, SubmodulesInclude, SubmodulesSkip, SubmodulesSeparate)
+}
+
+// findRepoRoot returns the nearest directory at or above dir containing a .git
+// entry. The entry may be a directory, or a file pointing elsewhere, as in
+// linked worktrees and submodules. ok is false outside a repository.
+func findRepoRoot(dir string) (root string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// readGitmodules returns the absolute paths of the submodules declared in the
+// .gitmodules file of the repository rooted at dir.
+func readGitmodules(dir string) []string {
+	f, err := os.Open(filepath.Join(dir, ".gitmodules")) // #nosec G304
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(key) == "path" {
+			paths = append(paths, filepath.Join(dir, filepath.FromSlash(strings.TrimSpace(value))))
+		}
+	}
+	return paths
+}
+
+// hasSubmoduleGitFile reports whether dir holds the .git file stub of a checked
+// out submodule, which points into the superproject's modules directory.
+func hasSubmoduleGitFile(dir string) bool {
+	content, err := os.ReadFile(filepath.Join(dir, ".git")) // #nosec G304
+	if err != nil {
+		return false
+	}
+	gitdir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	return ok && strings.Contains(filepath.ToSlash(gitdir), "/modules/")
+}
+
+// submoduleTracker recognizes the submodules of the repositories seen during a walk.
+type submoduleTracker struct {
+	declared map[string]bool
+}
+
+// newSubmoduleTracker prepares a tracker for a walk starting at root, loading
+// the submodules declared by the repository enclosing it, unless that lies
+// outside jail.
+func newSubmoduleTracker(root string, jail *jail) *submoduleTracker {
+	t := &submoduleTracker{declared: map[string]bool{}}
+	if repo, ok := findRepoRoot(root); ok && jail.check(repo) == nil {
+		t.load(repo)
+	}
+	return t
+}
+
+// load records the submodules declared in dir's .gitmodules.
+func (t *submoduleTracker) load(dir string) {
+	for _, path := range readGitmodules(dir) {
+		t.declared[path] = true
+	}
+}
+
+// isSubmodule reports whether the directory at path is a submodule, either
+// declared in a .gitmodules file or checked out with a .git file stub.
+func (t *submoduleTracker) isSubmodule(path string) bool {
+	if t == nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return t.declared[abs] || hasSubmoduleGitFile(abs)
+}
+
+// ignoreScope is the ignore state set aside while the walk is inside a
+// submodule, to be restored once it leaves.
+type ignoreScope struct {
+	// dir is the submodule directory the scope was entered for
+	dir               string
+	gitignoreRules    []gitignoreRule
+	gitignoreMatcher  *ignoreMatcher
+	exportIgnoreRules []attrRule
+	generatedRules    []attrRule
+}
+
+// groupSubmodules orders plan with the superproject's entries first, followed
+// by each submodule's entries in order of first appearance.
+func groupSubmodules(plan []PlannedFile) []PlannedFile {
+	grouped := make([]PlannedFile, 0, len(plan))
+	var order []string
+	bySubmodule := map[string][]PlannedFile{}
+	for _, f := range plan {
+		if f.Submodule == "" {
+			grouped = append(grouped, f)
+			continue
+		}
+		if _, ok := bySubmodule[f.Submodule]; !ok {
+			order = append(order, f.Submodule)
+		}
+		bySubmodule[f.Submodule] = append(bySubmodule[f.Submodule], f)
+	}
+	for _, dir := range order {
+		grouped = append(grouped, bySubmodule[dir]...)
+	}
+	return grouped
+}
+
+// writeSubmoduleSection writes the label opening the section for the submodule
+// at dir, first closing the section of the previous submodule, if any.
+func writeSubmoduleSection(w io.Writer, config config.Config, previous, dir string) error {
+	var label string
+	switch {
+	case config.ClaudeXML:
+		if previous != "" {
+			label = "</submodule>\n"
+		}
+		if dir != "" {
+			label += fmt.Sprintf("<submodule path=\"%s\">\n", escapeXML(dir))
+		}
+	case dir == "", config.JSONL, config.OpenAI, config.TemplatePath != "", config.Format != "":
+		// A line, a message or a template holds a document and nothing else;
+		// the paths tell the submodules apart
+	case config.HTML:
+		label = fmt.Sprintf("<h1 class=\"submodule\">Submodule %s</h1>\n", html.EscapeString(dir))
+	case config.Markdown:
+		label = markdownSection(config, "Submodule "+dir)
+	default:
+		label = fmt.Sprintf("=== Submodule %s ===\n\n", dir)
+	}
+	_, err := io.WriteString(w, label)
+	return err
+}
+
+// withinDir reports whether path is dir or lies beneath it.
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,196 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// runGit runs git in dir, failing the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "protocol.file.allow=always"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// writeFiles creates each file under dir with its content.
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
+
+// submoduleFixture builds a superproject with a submodule checked out at
+// libs/sub, each with its own .gitignore, and returns the superproject path.
+//
+//	super/.gitignore        *.log
+//	super/main.go
+//	super/libs/sub/.gitignore  *.gen
+//	super/libs/sub/sub.go
+//	super/libs/sub/notes.log   (not ignored: the superproject's rules stop at the submodule)
+//	super/libs/sub/x.gen       (ignored by the submodule's rules)
+//	super/zz.gen               (not ignored: the submodule's rules do not leak out)
+//	super/zz.log
+func submoduleFixture(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	base := t.TempDir()
+	sub := filepath.Join(base, "sub")
+	super := filepath.Join(base, "super")
+
+	writeFiles(t, sub, map[string]string{".gitignore": "*.gen\n", "sub.go": "package sub\n"})
+	runGit(t, sub, "init", "-q")
+	runGit(t, sub, "add", ".")
+	runGit(t, sub, "commit", "-q", "-m", "sub")
+
+	writeFiles(t, super, map[string]string{".gitignore": "*.log\n", "main.go": "package main\n"})
+	runGit(t, super, "init", "-q")
+	runGit(t, super, "add", ".")
+	runGit(t, super, "commit", "-q", "-m", "super")
+	runGit(t, super, "submodule", "add", "-q", sub, "libs/sub")
+	runGit(t, super, "commit", "-q", "-m", "add submodule")
+
+	writeFiles(t, super, map[string]string{
+		"libs/sub/notes.log": "sub log\n",
+		"libs/sub/x.gen":     "generated\n",
+		"zz.gen":             "gen\n",
+		"zz.log":             "log\n",
+	})
+	return super
+}
+
+// includedPaths returns the included files of plan relative to root.
+func includedPaths(t *testing.T, root string, plan []PlannedFile) []string {
+	t.Helper()
+	var paths []string
+	for _, f := range plan {
+		if f.Included {
+			rel, err := filepath.Rel(root, f.Path)
+			require.NoError(t, err)
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+	}
+	return paths
+}
+
+func TestSubmodulesInclude(t *testing.T) {
+	super := submoduleFixture(t)
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{super}, IgnoreGitignore: true}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"libs/sub/notes.log", "libs/sub/sub.go", "main.go", "zz.gen"}, includedPaths(t, super, plan))
+	for _, f := range plan {
+		expected := ""
+		if strings.Contains(f.Path, filepath.Join("libs", "sub")+string(filepath.Separator)) {
+			expected = filepath.Join(super, "libs", "sub")
+		}
+		assert.Equal(t, expected, f.Submodule, f.Path)
+	}
+}
+
+func TestSubmodulesSkip(t *testing.T) {
+	super := submoduleFixture(t)
+
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{super}, IgnoreGitignore: true, Submodules: "skip"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "zz.gen"}, includedPaths(t, super, plan))
+
+	var pruned []string
+	for _, f := range plan {
+		if f.Reason == SkipSubmodule {
+			pruned = append(pruned, f.Path)
+			assert.True(t, f.IsDir)
+		}
+	}
+	assert.Equal(t, []string{filepath.Join(super, "libs", "sub")}, pruned)
+}
+
+func TestSubmodulesSeparate(t *testing.T) {
+	super := submoduleFixture(t)
+	subDir := filepath.Join(super, "libs", "sub")
+	cfg := config.Config{Paths: []string{super}, IgnoreGitignore: true, Submodules: "separate"}
+
+	var buf bytes.Buffer
+	summary, err := Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, summary.Files)
+	expected := filepath.Join(super, "main.go") + "\n---\npackage main\n---\n\n" +
+		filepath.Join(super, "zz.gen") + "\n---\ngen\n---\n\n" +
+		"=== Submodule " + subDir + " ===\n\n" +
+		filepath.Join(subDir, "notes.log") + "\n---\nsub log\n---\n\n" +
+		filepath.Join(subDir, "sub.go") + "\n---\npackage sub\n---\n\n"
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	cfg.ClaudeXML = true
+	_, err = Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "</document>\n<submodule path=\""+subDir+"\">\n<document index=\"3\">")
+	assert.True(t, strings.HasSuffix(out, "</document>\n</submodule>\n</documents>\n"), out)
+
+	buf.Reset()
+	cfg.ClaudeXML, cfg.Markdown = false, true
+	_, err = Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "```\n\n## Submodule "+subDir+"\n\n## "+filepath.Join(subDir, "notes.log"))
+}
+
+func TestSubmodulesInWorktree(t *testing.T) {
+	super := submoduleFixture(t)
+	wt := filepath.Join(filepath.Dir(super), "wt")
+	runGit(t, super, "worktree", "add", "-q", wt)
+	// The submodule is not initialized in the worktree, so only .gitmodules names it
+	writeFiles(t, wt, map[string]string{"libs/sub/stray.dat": "stray\n", "libs/other.dat": "other\n"})
+	require.NoFileExists(t, filepath.Join(wt, "libs", "sub", ".git"))
+
+	root, ok := findRepoRoot(filepath.Join(wt, "libs"))
+	assert.True(t, ok)
+	assert.Equal(t, wt, root)
+
+	libs := filepath.Join(wt, "libs")
+	plan, err := Plan(context.Background(), config.Config{Paths: []string{libs}, Submodules: "skip"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"other.dat"}, includedPaths(t, libs, plan))
+
+	var buf bytes.Buffer
+	_, err = Generate(context.Background(), config.Config{Paths: []string{libs}, Submodules: "separate"}, &buf, nil)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "=== Submodule "+filepath.Join(libs, "sub")+" ===\n\n")
+}
+
+func TestSubmoduleModeValidation(t *testing.T) {
+	_, err := Plan(context.Background(), config.Config{Paths: []string{"testdata/file1.txt"}, Submodules: "flatten"}, false)
+	assert.EqualError(t, err, `invalid --submodules "flatten": use include, skip or separate`)
+}
+
+func TestHasSubmoduleGitFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"sub/.git":      "gitdir: ../.git/modules/sub\n",
+		"worktree/.git": "gitdir: /repo/.git/worktrees/feature\n",
+		"plain/file":    "x",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested", ".git"), 0o700))
+
+	assert.True(t, hasSubmoduleGitFile(filepath.Join(dir, "sub")))
+	assert.False(t, hasSubmoduleGitFile(filepath.Join(dir, "worktree")))
+	assert.False(t, hasSubmoduleGitFile(filepath.Join(dir, "plain")))
+	assert.False(t, hasSubmoduleGitFile(filepath.Join(dir, "nested")))
+}
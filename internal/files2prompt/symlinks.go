@@ -0,0 +1,158 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// linkInfo describes a symlink to a directory that a walk follows: the
+// directory, with ModeSymlink set so the filters still see a link.
+type linkInfo struct {
+	fs.FileInfo
+}
+
+func (i linkInfo) Mode() fs.FileMode { return i.FileInfo.Mode() | fs.ModeSymlink }
+
+// followLink returns the info a walk reports for the entry at path, whose
+// own info is info: for a symlink to a directory a linkInfo, so that the walk
+// descends into it, and info itself for anything else.
+func followLink(path string, info fs.FileInfo) fs.FileInfo {
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return info
+	}
+	if target, err := os.Stat(path); err == nil && target.IsDir() {
+		return linkInfo{target}
+	}
+	return info
+}
+
+// walkTree calls fn for root and everything beneath it in lexical order, as
+// filepath.Walk does, except that a root which is a symlink to a directory is
+// walked, and with follow so are the symlinked directories beneath it. A
+// followed link is reported with a linkInfo, and what lies beneath it under
+// the link's own path. A link to a directory the walk is already within would
+// never end, so it is logged and reported unfollowed.
+func walkTree(root string, follow bool, fn filepath.WalkFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkEntry(root, followLink(root, info), follow, nil, fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// walkEntry walks path, whose info is info, for walkTree. within holds the
+// real paths of the directories being walked, outermost first, when
+// following links.
+func walkEntry(path string, info fs.FileInfo, follow bool, within []string, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+	names, err := readDirNames(path)
+	if err := fn(path, info, err); err != nil || names == nil {
+		return err
+	}
+	if follow {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			real = path
+		}
+		within = append(within, absPath(real))
+	}
+	for _, name := range names {
+		child := filepath.Join(path, name)
+		childInfo, err := os.Lstat(child)
+		if err != nil {
+			if err := fn(child, nil, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+				return err
+			}
+			continue
+		}
+		if follow {
+			if followed := followLink(child, childInfo); followed != childInfo {
+				if real, err := filepath.EvalSymlinks(child); err == nil && slices.Contains(within, absPath(real)) {
+					log.Warnf("Not following %s: it links back to %s, which is already being walked", child, real)
+				} else {
+					childInfo = followed
+				}
+			}
+		}
+		if err := walkEntry(child, childInfo, follow, within, fn); err != nil {
+			if !childInfo.IsDir() || !errors.Is(err, filepath.SkipDir) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readDirNames returns the names of the entries of the directory dir, sorted.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// markSymlinkDuplicates marks, unless --expand-symlink-duplicates is given,
+// the included files that are, through a symlink, the same file as one
+// included before them: a link to a file that is also included, or a file
+// reached through a symlinked directory as well as directly. Each is emitted
+// as a stub pointing to the earlier document instead of repeating its
+// content. A file included twice under the same path is left alone.
+func markSymlinkDuplicates(plan []PlannedFile, config config.Config) {
+	if config.ExpandSymlinkDuplicates || CompatMode(config.Compat) == CompatFilesToPrompt {
+		return
+	}
+	// first is the index in plan of the first file resolving to each path
+	first := map[string]int{}
+	for i, f := range plan {
+		if !f.Included || f.IsDir || f.stub() {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(f.Path)
+		if err != nil {
+			// Left for emission to report
+			continue
+		}
+		resolved = absPath(resolved)
+		j, seen := first[resolved]
+		if !seen {
+			first[resolved] = i
+			continue
+		}
+		if absPath(plan[j].Path) == absPath(f.Path) {
+			continue
+		}
+		plan[i].DuplicateOf = plan[j].Path
+		log.Debugf("%s is the same file as %s through a symlink; emitting a stub", f.Path, plan[j].Path)
+	}
+}
+
+// symlinkStub returns the content emitted for a file marked by
+// markSymlinkDuplicates, referring to the document of the file it duplicates
+// when it was emitted.
+func symlinkStub(f PlannedFile, state *emitState) string {
+	if index, ok := state.documentIndexes[f.DuplicateOf]; ok {
+		return fmt.Sprintf("[same file as %s through a symlink, content identical; see document %d]\n", f.DuplicateOf, index)
+	}
+	return fmt.Sprintf("[same file as %s through a symlink, content identical]\n", f.DuplicateOf)
+}
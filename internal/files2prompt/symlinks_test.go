@@ -0,0 +1,148 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// symlinkFixture writes a tree whose shared directory is also reachable
+// through the symlinked directory lib/shared, and changes into it.
+func symlinkFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/main.go":        "package main\n",
+		"src/shared/util.go": "package shared\n",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0o700))
+	if err := os.Symlink(filepath.Join("..", "src", "shared"), filepath.Join(dir, "lib", "shared")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func TestSymlinkDuplicates(t *testing.T) {
+	symlinkFixture(t)
+	// The trailing slash has the symlinked directory walked
+	cfg := config.Config{Paths: []string{"src", "lib/shared/"}, ClaudeXML: true}
+
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), cfg, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "<documents>\n"+
+		"<document index=\"1\">\n<source>lib/shared/util.go</source>\n<document_content>\npackage shared\n</document_content>\n</document>\n"+
+		"<document index=\"2\">\n<source>src/main.go</source>\n<document_content>\npackage main\n</document_content>\n</document>\n"+
+		"<document index=\"3\">\n<source>src/shared/util.go</source>\n<document_content>\n"+
+		"[same file as lib/shared/util.go through a symlink, content identical; see document 1]\n"+
+		"</document_content>\n</document>\n"+
+		"</documents>\n", buf.String())
+
+	planned, err := Plan(context.Background(), cfg, false)
+	require.NoError(t, err)
+	require.Len(t, planned, 3)
+	// The first in the sorted plan keeps the content, whichever is the link
+	assert.Equal(t, "lib/shared/util.go", planned[2].DuplicateOf)
+
+	t.Run("expanded", func(t *testing.T) {
+		cfg := cfg
+		cfg.ExpandSymlinkDuplicates = true
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), cfg, &buf, nil)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "<source>src/shared/util.go</source>\n<document_content>\npackage shared\n</document_content>")
+		assert.NotContains(t, buf.String(), "through a symlink")
+	})
+
+	t.Run("linked file", func(t *testing.T) {
+		require.NoError(t, os.Symlink(filepath.Join("src", "main.go"), "main.go"))
+		t.Cleanup(func() { _ = os.Remove("main.go") })
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), config.Config{Paths: []string{"main.go", "src/main.go"}}, &buf, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "main.go\n---\npackage main\n---\n\n"+
+			"src/main.go\n---\n[same file as main.go through a symlink, content identical; see document 1]\n---\n\n", buf.String())
+	})
+
+	t.Run("same path twice", func(t *testing.T) {
+		planned, err := Plan(context.Background(), config.Config{Paths: []string{"src/main.go", "./src/main.go"}}, false)
+		require.NoError(t, err)
+		require.Len(t, planned, 2)
+		assert.Empty(t, planned[1].DuplicateOf)
+	})
+}
+
+// planReasons plans cfg, returning the skip reason of each planned path, "" for those included.
+func planReasons(t *testing.T, cfg config.Config) map[string]SkipReason {
+	t.Helper()
+	planned, err := Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range planned {
+		if !f.IsDir || f.Reason != "" {
+			reasons[f.Path] = f.Reason
+		}
+	}
+	return reasons
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	symlinkFixture(t)
+	require.NoError(t, os.Symlink("missing.go", filepath.Join("src", "dangling.go")))
+
+	// By default the symlinked directory is left out, as is the link leading nowhere
+	assert.Equal(t, map[string]SkipReason{
+		"lib/shared":         SkipSymlinkDir,
+		"src/dangling.go":    SkipDangling,
+		"src/main.go":        "",
+		"src/shared/util.go": "",
+	}, planReasons(t, config.Config{Paths: []string{"lib", "src"}}))
+
+	assert.Equal(t, map[string]SkipReason{
+		"lib/shared/util.go": "",
+		"src/dangling.go":    SkipDangling,
+		"src/main.go":        "",
+		"src/shared/util.go": "",
+	}, planReasons(t, config.Config{Paths: []string{"lib", "src"}, FollowSymlinks: true}))
+
+	// The files beneath a followed link duplicate those reached directly
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"lib", "src"}, FollowSymlinks: true}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "lib/shared/util.go\n---\npackage shared\n---\n\n"+
+		"src/main.go\n---\npackage main\n---\n\n"+
+		"src/shared/util.go\n---\n[same file as lib/shared/util.go through a symlink, content identical; see document 1]\n---\n\n", buf.String())
+
+	results, err := Match(config.Config{FollowSymlinks: true}, ".", []string{"lib/shared/util.go"})
+	require.NoError(t, err)
+	assert.True(t, results[0].Included)
+	results, err = Match(config.Config{}, ".", []string{"lib/shared/util.go"})
+	require.NoError(t, err)
+	assert.Equal(t, SkipSymlinkDir, results[0].Reason)
+	assert.Equal(t, "lib/shared", results[0].PrunedBy)
+
+	t.Run("cycle", func(t *testing.T) {
+		hook := logtest.NewGlobal()
+		defer hook.Reset()
+		require.NoError(t, os.Symlink("..", filepath.Join("src", "shared", "up")))
+		t.Cleanup(func() { _ = os.Remove(filepath.Join("src", "shared", "up")) })
+
+		reasons := planReasons(t, config.Config{Paths: []string{"src"}, FollowSymlinks: true})
+		assert.Equal(t, SkipSymlinkDir, reasons["src/shared/up"])
+		assert.Len(t, reasons, 4)
+		var warnings []string
+		for _, entry := range hook.AllEntries() {
+			warnings = append(warnings, entry.Message)
+		}
+		assert.Contains(t, warnings, "Not following src/shared/up: it links back to src, which is already being walked")
+	})
+}
@@ -0,0 +1,211 @@
+package files2prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// TemplateFile is what a file template is executed with, once per document.
+type TemplateFile struct {
+	// Path is the path the document is shown under
+	Path string
+	// Content is the content of the document
+	Content string
+	// Index numbers the documents from 1
+	Index int
+	// Ext is the extension of the file, with its dot, such as ".go"
+	Ext string
+	// Lang is its fence language, or "" when none is known
+	Lang string
+	// Lines is the number of lines of Content
+	Lines int
+}
+
+// TemplateRun is what header and footer templates are executed with, once per run.
+type TemplateRun struct {
+	// FileCount is the number of documents
+	FileCount int
+	// TotalBytes is the size of their contents
+	TotalBytes int64
+}
+
+// sampleFile and sampleRun are the synthetic data templates are tried on when
+// they are loaded, so that mistakes surface before any file is walked rather
+// than halfway through the output.
+var (
+	sampleFile = TemplateFile{
+		Path: "src/example.go", Content: "package example\n\nfunc Example() {}\n", Index: 1, Ext: ".go", Lang: "go", Lines: 3,
+	}
+	sampleRun = TemplateRun{FileCount: 2, TotalBytes: 1234}
+)
+
+// templateHelper is a function templates can call.
+type templateHelper struct {
+	name, usage, doc string
+	fn               any
+}
+
+// templateHelpers returns the functions templates can call, as config
+// configures them.
+func templateHelpers(config config.Config) []templateHelper {
+	return []templateHelper{
+		{"indent", "indent N TEXT", "Indent every non-empty line of TEXT by N spaces: {{.Content | indent 4}}", indent},
+		{"trimTrailing", "trimTrailing TEXT", "Remove the spaces and tabs ending each line of TEXT, and the blank lines ending TEXT", trimTrailing},
+		{"tokenEstimate", "tokenEstimate TEXT", "Estimate the tokens of TEXT, as --tokens does: {{tokenEstimate .Content}} tokens", countTokens},
+		{"langFor", "langFor PATH", "The fence language of a file called PATH, by its name and the --lang overrides", func(path string) string {
+			return fenceLanguage(path, "", config)
+		}},
+		{"jsonEscape", "jsonEscape TEXT", `Escape TEXT for use between the quotes of a JSON string: "{{jsonEscape .Content}}"`, jsonEscape},
+		{"xmlEscape", "xmlEscape TEXT", `Escape &, <, >, " and ' in TEXT for XML text or attribute values`, xmlEscape},
+	}
+}
+
+// WriteTemplateHelpers documents the functions templates can call.
+func WriteTemplateHelpers(w io.Writer, config config.Config) error {
+	for _, h := range templateHelpers(config) {
+		if _, err := fmt.Fprintf(w, "%s\n    %s\n", h.usage, h.doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func indent(n int, text string) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("indent by %d: use 0 or more spaces", n)
+	}
+	var b strings.Builder
+	pad := strings.Repeat(" ", n)
+	for line := range strings.Lines(text) {
+		if strings.TrimRight(line, "\r\n") != "" {
+			b.WriteString(pad)
+		}
+		b.WriteString(line)
+	}
+	return b.String(), nil
+}
+
+func trimTrailing(text string) string {
+	var b strings.Builder
+	for line := range strings.Lines(text) {
+		body := strings.TrimRight(line, "\n")
+		b.WriteString(strings.TrimRight(body, " \t\r"))
+		if len(body) < len(line) {
+			b.WriteByte('\n')
+		}
+	}
+	trimmed := strings.TrimRight(b.String(), "\n")
+	if trimmed != "" && strings.HasSuffix(text, "\n") {
+		// The final newline of the last line that is not blank stays
+		trimmed += "\n"
+	}
+	return trimmed
+}
+
+func jsonEscape(text string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(text)
+	// Encode writes a quoted string and a newline
+	return string(b.Bytes()[1 : b.Len()-2])
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+func xmlEscape(text string) string {
+	return xmlEscaper.Replace(text)
+}
+
+// loadTemplate reads the template at path and parses it with the helpers of
+// templateHelpers. A byte order mark is dropped and \r\n line endings read as
+// \n, so that templates saved on Windows render as they were meant to. The
+// template is then executed with sample, sampleFile or sampleRun, and fails
+// as it would on any document, reporting the line of the mistake.
+func loadTemplate(path string, sample any, config config.Config) (*template.Template, error) {
+	text, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %v", err)
+	}
+	text = bytes.TrimPrefix(text, []byte("\ufeff"))
+	text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+
+	funcs := template.FuncMap{}
+	for _, h := range templateHelpers(config) {
+		funcs[h.name] = h.fn
+	}
+	tmpl, err := template.New(path).Funcs(funcs).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, templateError(path, err, sample)
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, templateError(path, err, sample)
+	}
+	return tmpl, nil
+}
+
+// missingField matches the message text/template gives for a field the data
+// does not have.
+var missingField = regexp.MustCompile(`can't evaluate field (\w+) in type (\S+)`)
+
+// templateError rewrites err, an error parsing or executing the template at
+// path with sample, as "PATH:LINE[:COL]: MESSAGE". A field missing from the
+// template data is named along with the fields it has.
+func templateError(path string, err error, sample any) error {
+	msg := strings.TrimPrefix(err.Error(), "template: ")
+	// Where an execution error arose is already in its position
+	msg = strings.Replace(msg, fmt.Sprintf("executing %q at ", path), "", 1)
+
+	var exec template.ExecError
+	if m := missingField.FindStringSubmatch(msg); m != nil && errors.As(err, &exec) {
+		data := reflect.TypeOf(sample)
+		if m[2] == data.String() {
+			fields := make([]string, data.NumField())
+			for i := range fields {
+				fields[i] = "." + data.Field(i).Name
+			}
+			hint := fmt.Sprintf("%s have no field %s; use %s or %s",
+				templateKind(sample), m[1], strings.Join(fields[:len(fields)-1], ", "), fields[len(fields)-1])
+			// A field of the other kind of template is a likely mix-up
+			other := any(sampleRun)
+			if _, ok := sample.(TemplateRun); ok {
+				other = sampleFile
+			}
+			if _, ok := reflect.TypeOf(other).FieldByName(m[1]); ok {
+				hint += fmt.Sprintf(" (.%s is only for %s)", m[1], templateKind(other))
+			}
+			msg = strings.Replace(msg, m[0], hint, 1)
+		}
+	}
+	return errors.New(msg)
+}
+
+// templateKind names the templates executed with data like sample.
+func templateKind(sample any) string {
+	if _, ok := sample.(TemplateRun); ok {
+		return "header and footer templates"
+	}
+	return "file templates"
+}
+
+// ValidateTemplate loads the template at path as loadTemplate does, as a
+// header or footer template when run is set and as a file template otherwise,
+// and returns what is wrong with it.
+func ValidateTemplate(path string, run bool, config config.Config) error {
+	sample := any(sampleFile)
+	if run {
+		sample = sampleRun
+	}
+	_, err := loadTemplate(path, sample, config)
+	return err
+}
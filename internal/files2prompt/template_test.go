@@ -0,0 +1,204 @@
+package files2prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		n        int
+		text     string
+		expected string
+	}{
+		{2, "a\nb\n", "  a\n  b\n"},
+		{4, "a\n\nb", "    a\n\n    b"},
+		{1, "a\r\n\r\nb\r\n", " a\r\n\r\n b\r\n"},
+		{0, "a\n", "a\n"},
+		{3, "", ""},
+	}
+	for _, tt := range tests {
+		got, err := indent(tt.n, tt.text)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, got, "%d %q", tt.n, tt.text)
+	}
+	_, err := indent(-1, "a")
+	assert.EqualError(t, err, "indent by -1: use 0 or more spaces")
+}
+
+func TestTrimTrailing(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"a  \nb\t\n", "a\nb\n"},
+		{"a\n\n \n\t\n", "a\n"},
+		{"a \r\nb", "a\nb"},
+		{"  a", "  a"},
+		{" \n\n", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, trimTrailing(tt.text), "%q", tt.text)
+	}
+}
+
+func TestTokenEstimateHelper(t *testing.T) {
+	out := executeTemplate(t, `{{tokenEstimate .Content}}`, sampleFile)
+	assert.Equal(t, strconv.FormatInt(countTokens(sampleFile.Content), 10), out)
+	assert.Equal(t, "0", executeTemplate(t, `{{tokenEstimate ""}}`, sampleFile))
+}
+
+func TestLangFor(t *testing.T) {
+	cfg := config.Config{LanguageOverrides: map[string]string{"tf": "hcl"}}
+	for path, lang := range map[string]string{
+		"main.go":       "go",
+		"web/Makefile":  "makefile",
+		"infra/main.tf": "hcl",
+		"notes":         "",
+	} {
+		assert.Equal(t, lang, executeTemplateWith(t, `{{langFor "`+path+`"}}`, sampleFile, cfg), path)
+	}
+}
+
+func TestJSONEscape(t *testing.T) {
+	for _, text := range []string{"plain", "quote \" and \\ backslash", "tab\tnewline\n", "<html> & more", "\x01 control", "ünïcode"} {
+		escaped := jsonEscape(text)
+		var decoded string
+		require.NoError(t, json.Unmarshal([]byte(`"`+escaped+`"`), &decoded), escaped)
+		assert.Equal(t, text, decoded)
+	}
+	// HTML characters are left as they are
+	assert.Equal(t, `<a> & \"b\"\n`, jsonEscape("<a> & \"b\"\n"))
+}
+
+func TestXMLEscape(t *testing.T) {
+	assert.Equal(t, "a &lt; b &amp;&amp; c &gt; d\n&quot;e&quot; &apos;f&apos;", xmlEscape("a < b && c > d\n\"e\" 'f'"))
+	assert.Equal(t, "plain\ttext\n", xmlEscape("plain\ttext\n"))
+}
+
+func TestWriteTemplateHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteTemplateHelpers(&buf, config.Config{}))
+	for _, name := range []string{"indent", "trimTrailing", "tokenEstimate", "langFor", "jsonEscape", "xmlEscape"} {
+		assert.Contains(t, "\n"+buf.String(), "\n"+name+" ", name)
+	}
+	assert.True(t, strings.HasPrefix(buf.String(), "indent N TEXT\n    Indent"), buf.String())
+}
+
+func TestLoadTemplate(t *testing.T) {
+	// A byte order mark and \r\n line endings are normalized away
+	out := executeTemplate(t, "\ufeff<file path=\"{{xmlEscape .Path}}\">\r\n{{.Content | indent 2}}</file>\r\n", sampleFile)
+	assert.Equal(t, "<file path=\"src/example.go\">\n  package example\n\n  func Example() {}\n</file>\n", out)
+	assert.Equal(t, "2 files, 1234 B\n", executeTemplate(t, "{{.FileCount}} files, {{.TotalBytes}} B\n", sampleRun))
+}
+
+func TestLoadTemplateErrors(t *testing.T) {
+	fileFields := ".Path, .Content, .Index, .Ext, .Lang or .Lines"
+	tests := []struct {
+		name   string
+		text   string
+		sample any
+		err    string
+	}{
+		{
+			name:   "undefined field",
+			text:   "{{.Path}}\n  {{.Size}}\n",
+			sample: sampleFile,
+			err:    "t.tmpl:2:4: <.Size>: file templates have no field Size; use " + fileFields,
+		},
+		{
+			name:   "field of header templates",
+			text:   "{{.FileCount}}",
+			sample: sampleFile,
+			err:    "t.tmpl:1:2: <.FileCount>: file templates have no field FileCount; use " + fileFields + " (.FileCount is only for header and footer templates)",
+		},
+		{
+			name:   "field of file templates",
+			text:   "{{.Path}}",
+			sample: sampleRun,
+			err:    "t.tmpl:1:2: <.Path>: header and footer templates have no field Path; use .FileCount or .TotalBytes (.Path is only for file templates)",
+		},
+		{
+			name:   "bad syntax",
+			text:   "{{.Path}}\n{{if .Lang}}\n",
+			sample: sampleFile,
+			err:    "t.tmpl:3: unexpected EOF",
+		},
+		{
+			name:   "unclosed action",
+			text:   "ok\n{{.Path }\n",
+			sample: sampleFile,
+			err:    `t.tmpl:2: unexpected "}" in operand`,
+		},
+		{
+			name:   "undefined helper",
+			text:   "{{upper .Path}}",
+			sample: sampleFile,
+			err:    `t.tmpl:1: function "upper" not defined`,
+		},
+		{
+			name:   "field of the range value",
+			text:   "{{range .Lines}}\n{{.Path}}{{end}}",
+			sample: sampleFile,
+			err:    "t.tmpl:2:2: <.Path>: can't evaluate field Path in type int",
+		},
+		{
+			name:   "helper failing in a range",
+			text:   "{{range $i := .Lines}}{{indent -2 $.Content}}{{end}}",
+			sample: sampleFile,
+			err:    "t.tmpl:1:24: <indent -2 $.Content>: error calling indent: indent by -2: use 0 or more spaces",
+		},
+		{
+			name:   "ranging over a string",
+			text:   "{{range .Path}}{{.}}{{end}}",
+			sample: sampleFile,
+			err:    "t.tmpl:1:8: <.Path>: range can't iterate over src/example.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			require.NoError(t, os.WriteFile("t.tmpl", []byte(tt.text), 0o600))
+			_, err := loadTemplate("t.tmpl", tt.sample, config.Config{})
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	_, err := loadTemplate(filepath.Join(t.TempDir(), "missing.tmpl"), sampleFile, config.Config{})
+	assert.ErrorContains(t, err, "failed to read template: ")
+}
+
+func TestValidateTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "footer.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte("{{.FileCount}} files\n"), 0o600))
+	assert.NoError(t, ValidateTemplate(path, true, config.Config{}))
+	assert.ErrorContains(t, ValidateTemplate(path, false, config.Config{}), "file templates have no field FileCount")
+}
+
+// executeTemplate loads text as a template and returns its output for data.
+func executeTemplate(t *testing.T, text string, data any) string {
+	t.Helper()
+	return executeTemplateWith(t, text, data, config.Config{})
+}
+
+func executeTemplateWith(t *testing.T, text string, data any, cfg config.Config) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "t.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+	tmpl, err := loadTemplate(path, data, cfg)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, data))
+	return buf.String()
+}
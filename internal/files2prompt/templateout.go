@@ -0,0 +1,123 @@
+package files2prompt
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// templateOutput renders --template output: each document with the file
+// template, between what the header and footer templates render, once each.
+type templateOutput struct {
+	file, header, footer *template.Template
+	// run is what the header and footer templates are executed with
+	run TemplateRun
+}
+
+// templateOptions checks that --template is not given with another output
+// format, and that --header-template and --footer-template are not given
+// without it.
+func templateOptions(config config.Config) error {
+	if config.TemplatePath == "" {
+		switch {
+		case config.HeaderTemplate != "":
+			return errors.New("--header-template requires --template")
+		case config.FooterTemplate != "":
+			return errors.New("--footer-template requires --template")
+		}
+		return nil
+	}
+	for _, option := range []struct {
+		flag string
+		set  bool
+	}{
+		{"--markdown", config.Markdown},
+		{"--cxml", config.ClaudeXML},
+		{"--html", config.HTML},
+		{"--jsonl", config.JSONL},
+		{"--openai", config.OpenAI},
+	} {
+		if option.set {
+			return fmt.Errorf("--template cannot be combined with %s", option.flag)
+		}
+	}
+	return nil
+}
+
+// newTemplateOutput loads the templates of --template, --header-template and
+// --footer-template, or returns nil without --template. Each is executed on
+// sample data as it is loaded, so that a mistake is reported before anything
+// is walked.
+func newTemplateOutput(config config.Config) (*templateOutput, error) {
+	if err := templateOptions(config); err != nil || config.TemplatePath == "" {
+		return nil, err
+	}
+	t := &templateOutput{}
+	for _, tmpl := range []struct {
+		flag, path string
+		sample     any
+		dst        **template.Template
+	}{
+		{"--template", config.TemplatePath, sampleFile, &t.file},
+		{"--header-template", config.HeaderTemplate, sampleRun, &t.header},
+		{"--footer-template", config.FooterTemplate, sampleRun, &t.footer},
+	} {
+		if tmpl.path == "" {
+			continue
+		}
+		var err error
+		if *tmpl.dst, err = loadTemplate(tmpl.path, tmpl.sample, config); err != nil {
+			return nil, fmt.Errorf("%s: %v", tmpl.flag, err)
+		}
+	}
+	return t, nil
+}
+
+// open returns what the header template renders for the files of plan.
+func (t *templateOutput) open(plan []PlannedFile, config config.Config) (string, error) {
+	if readsStdin(config) {
+		t.run.FileCount++
+		t.run.TotalBytes += int64(len(config.StdinContent))
+	}
+	for _, f := range plan {
+		if f.Included && !f.IsDir {
+			t.run.FileCount++
+			t.run.TotalBytes += f.Size
+		}
+	}
+	return t.execute("--header-template", t.header, t.run)
+}
+
+// close returns what the footer template renders.
+func (t *templateOutput) close() (string, error) {
+	return t.execute("--footer-template", t.footer, t.run)
+}
+
+// document returns what the file template renders for the document numbered
+// index, shown as path.
+func (t *templateOutput) document(path, content, lang string, index int) (string, error) {
+	return t.execute("--template", t.file, TemplateFile{
+		Path:    path,
+		Content: content,
+		Index:   index,
+		Ext:     filepath.Ext(path),
+		Lang:    lang,
+		Lines:   int(countLines([]byte(content))),
+	})
+}
+
+// execute returns what tmpl, if any, renders with data.
+func (t *templateOutput) execute(flag string, tmpl *template.Template, data any) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%s: %v", flag, templateError(tmpl.Name(), err, data))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,111 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestTemplateOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/main.go":    "package main\n\nfunc main() {}\n",
+		"src/notes":      "no extension",
+		"header.tmpl":    "{{.FileCount}} files, {{.TotalBytes}} bytes\n",
+		"footer.tmpl":    "end\n",
+		"file.tmpl":      "{{.Index}} {{.Path}} ext={{.Ext}} lang={{.Lang}} lines={{.Lines}}\n{{.Content}}\n",
+		"indented.tmpl":  "- {{.Path}}: |\n{{.Content | indent 4}}",
+		"directory.tmpl": "[{{.Path}}]\n",
+	})
+	t.Chdir(dir)
+	run := func(t *testing.T, cfg config.Config) string {
+		t.Helper()
+		cfg.Paths = []string{"src/main.go", "src/notes"}
+		var buf bytes.Buffer
+		_, err := Generate(context.Background(), cfg, &buf, nil)
+		require.NoError(t, err)
+		return buf.String()
+	}
+
+	t.Run("file, header and footer", func(t *testing.T) {
+		assert.Equal(t, "2 files, 41 bytes\n"+
+			"1 src/main.go ext=.go lang=go lines=3\npackage main\n\nfunc main() {}\n\n"+
+			"2 src/notes ext= lang= lines=1\nno extension\n"+
+			"end\n",
+			run(t, config.Config{TemplatePath: "file.tmpl", HeaderTemplate: "header.tmpl", FooterTemplate: "footer.tmpl"}))
+	})
+
+	t.Run("helpers", func(t *testing.T) {
+		assert.Equal(t, "- src/main.go: |\n    package main\n\n    func main() {}\n- src/notes: |\n    no extension",
+			run(t, config.Config{TemplatePath: "indented.tmpl"}))
+	})
+
+	t.Run("line numbers", func(t *testing.T) {
+		assert.Contains(t, run(t, config.Config{TemplatePath: "file.tmpl", LineNumbersCompact: true}),
+			"lines=3\n1:package main\n2:\n3:func main() {}\n")
+	})
+
+	t.Run("tree and commands", func(t *testing.T) {
+		assert.Equal(t, "[directory-tree]\n[src/main.go]\n[src/notes]\n[echo hi]\n",
+			run(t, config.Config{TemplatePath: "directory.tmpl", Tree: true, Commands: []string{"echo hi"}}))
+	})
+
+	t.Run("held when large", func(t *testing.T) {
+		// Templates see the whole content, so files are never streamed
+		withStreamThreshold(t, 1)
+		assert.Contains(t, run(t, config.Config{TemplatePath: "file.tmpl"}), "lines=3\npackage main\n\nfunc main() {}\n\n")
+	})
+}
+
+func TestTemplateOutputErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"file.tmpl":   "{{.Path}}\n{{.Content}}",
+		"size.tmpl":   "{{.Path}} is\n{{.Size}} bytes",
+		"bad.tmpl":    "{{.Path}\n",
+		"header.tmpl": "{{.Content}}",
+	})
+	t.Chdir(dir)
+
+	tests := []struct {
+		name   string
+		config config.Config
+		err    string
+	}{
+		{"missing field", config.Config{TemplatePath: "size.tmpl"},
+			"--template: size.tmpl:2:2: <.Size>: file templates have no field Size; use .Path, .Content, .Index, .Ext, .Lang or .Lines"},
+		{"bad template", config.Config{TemplatePath: "bad.tmpl"}, `--template: bad.tmpl:1: bad character U+007D '}'`},
+		{"missing file", config.Config{TemplatePath: "missing.tmpl"},
+			"--template: failed to read template: open missing.tmpl: no such file or directory"},
+		{"file field in header", config.Config{TemplatePath: "file.tmpl", HeaderTemplate: "header.tmpl"},
+			"--header-template: header.tmpl:1:2: <.Content>: header and footer templates have no field Content; " +
+				"use .FileCount or .TotalBytes (.Content is only for file templates)"},
+		{"with markdown", config.Config{TemplatePath: "file.tmpl", Markdown: true}, "--template cannot be combined with --markdown"},
+		{"with openai", config.Config{TemplatePath: "file.tmpl", OpenAI: true}, "--template cannot be combined with --openai"},
+		{"with compat", config.Config{TemplatePath: "file.tmpl", Compat: string(CompatFilesToPrompt)}, "--compat files-to-prompt cannot be combined with --template"},
+		{"header alone", config.Config{HeaderTemplate: "header.tmpl"}, "--header-template requires --template"},
+		{"footer alone", config.Config{FooterTemplate: "header.tmpl"}, "--footer-template requires --template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			// A path that does not exist is only reported if the walk is reached
+			cfg.Paths = []string{"missing"}
+			_, err := Generate(context.Background(), cfg, &bytes.Buffer{}, nil)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+
+	withStdout(t)
+	require.NoError(t, os.WriteFile("a.txt", []byte("a\n"), 0o600))
+	_, err := Run(config.Config{Paths: []string{"a.txt"}, TemplatePath: "file.tmpl", HeaderTemplate: "file.tmpl", SplitBytes: 100,
+		OutputFile: filepath.Join(t.TempDir(), "out.txt")})
+	assert.EqualError(t, err, "--split-bytes cannot be combined with --header-template")
+}
@@ -0,0 +1 @@
+secret = 1
@@ -0,0 +1 @@
+export const z = 1;
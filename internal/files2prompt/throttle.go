@@ -0,0 +1,94 @@
+package files2prompt
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// throttleBurst is the share of a second's reading the --throttle bucket can
+// save up while no file is read.
+const throttleBurst = 0.1
+
+// readThrottle is the token bucket --throttle reads files through: every
+// reader of a run draws on the same bucket, so that together they keep to
+// the rate. A nil *readThrottle reads at full speed.
+type readThrottle struct {
+	// rate is in bytes per second, and burst the most tokens saved up
+	rate, burst float64
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	// now and sleepUntil are the clock, which tests replace
+	now        func() time.Time
+	sleepUntil func(time.Time)
+}
+
+// newReadThrottle returns the throttle of --throttle, a rate in MB/s, or nil
+// without it.
+func newReadThrottle(config config.Config) (*readThrottle, error) {
+	switch {
+	case config.Throttle < 0:
+		return nil, fmt.Errorf("invalid --throttle %g: use a rate in MB/s, or 0 for none", config.Throttle)
+	case config.Throttle == 0:
+		return nil, nil
+	}
+	return newThrottleClock(config.Throttle*1e6, time.Now, func(t time.Time) { time.Sleep(time.Until(t)) }), nil
+}
+
+// newThrottleClock returns a throttle reading rate bytes per second by the
+// clock of now and sleepUntil. The bucket starts empty.
+func newThrottleClock(rate float64, now func() time.Time, sleepUntil func(time.Time)) *readThrottle {
+	return &readThrottle{
+		rate:       rate,
+		burst:      max(rate*throttleBurst, streamChunkSize),
+		last:       now(),
+		now:        now,
+		sleepUntil: sleepUntil,
+	}
+}
+
+// wait takes n tokens from the bucket, sleeping until it has refilled enough
+// to cover them. The tokens are reserved before sleeping, so that concurrent
+// readers queue up one after another rather than all waking at once.
+func (t *readThrottle) wait(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	now := t.now()
+	t.tokens = min(t.burst, t.tokens+now.Sub(t.last).Seconds()*t.rate) - float64(n)
+	t.last = now
+	deficit := -t.tokens
+	t.mu.Unlock()
+	if deficit > 0 {
+		t.sleepUntil(now.Add(time.Duration(deficit / t.rate * float64(time.Second))))
+	}
+}
+
+// reader returns r, read through t.
+func (t *readThrottle) reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &throttledReader{r: r, t: t}
+}
+
+// throttledReader reads from r no faster than t allows.
+type throttledReader struct {
+	r io.Reader
+	t *readThrottle
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	// Reads of at most a chunk keep the waits short and even
+	if len(p) > streamChunkSize {
+		p = p[:streamChunkSize]
+	}
+	n, err := r.r.Read(p)
+	r.t.wait(n)
+	return n, err
+}
@@ -0,0 +1,115 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// fakeClock is a clock whose sleepers wake at once, moving it on to when
+// they would have woken. Sleepers that overlap take only as long as the last.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) sleepUntil(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.t) {
+		c.t = t
+	}
+}
+
+func TestReadThrottle(t *testing.T) {
+	const (
+		rate    = 10e6
+		files   = 40
+		size    = 2 << 20
+		readers = 8
+	)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+	throttle := newThrottleClock(rate, clock.now, clock.sleepUntil)
+
+	// In-memory files, read by concurrent readers drawing on the one bucket
+	content := bytes.Repeat([]byte("x"), size)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var total int64
+	for range readers {
+		wg.Go(func() {
+			for range jobs {
+				n, err := io.Copy(io.Discard, throttle.reader(bytes.NewReader(content)))
+				assert.NoError(t, err)
+				mu.Lock()
+				total += n
+				mu.Unlock()
+			}
+		})
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	require.Equal(t, int64(files*size), total)
+	elapsed := clock.now().Sub(start).Seconds()
+	expected := float64(total) / rate
+	assert.InDelta(t, expected, elapsed, expected*0.1, "%.0f bytes in %.2fs", float64(total), elapsed)
+}
+
+func TestReadThrottleBurst(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+	throttle := newThrottleClock(1e6, clock.now, clock.sleepUntil)
+
+	// After an idle minute only a tenth of a second's worth is saved up
+	clock.sleepUntil(start.Add(time.Minute))
+	throttle.wait(100_000)
+	assert.Equal(t, start.Add(time.Minute), clock.now())
+	throttle.wait(500_000)
+	assert.Equal(t, start.Add(time.Minute+500*time.Millisecond), clock.now())
+}
+
+func TestReadThrottleInert(t *testing.T) {
+	throttle, err := newReadThrottle(config.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, throttle)
+	r := bytes.NewReader(nil)
+	// Without --throttle, files are read directly
+	assert.Same(t, r, throttle.reader(r))
+	throttle.wait(1 << 30)
+
+	_, err = newReadThrottle(config.Config{Throttle: -1})
+	assert.EqualError(t, err, "invalid --throttle -1: use a rate in MB/s, or 0 for none")
+}
+
+func TestThrottledRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.txt": string(bytes.Repeat([]byte("a"), 100_000))})
+	t.Chdir(dir)
+	begin := time.Now()
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"a.txt"}, Throttle: 1}, &buf, nil)
+	require.NoError(t, err)
+	// The bucket starts empty, so 100 kB at 1 MB/s take a tenth of a second
+	assert.GreaterOrEqual(t, time.Since(begin), 90*time.Millisecond)
+	assert.Contains(t, buf.String(), "a.txt\n---\naaaa")
+}
@@ -0,0 +1,120 @@
+package files2prompt
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strings"
+)
+
+// osEnv abstracts the operating-system lookups needed for tilde expansion,
+// running commands, finding a clipboard and prompting so that the
+// platform-specific behavior can be tested on any CI runner.
+type osEnv struct {
+	goos       string
+	getenv     func(string) string
+	lookupUser func(string) (*user.User, error)
+	// runCommand runs a --cmd command, writing its combined output to out
+	runCommand func(ctx context.Context, command string, out io.Writer) error
+	// lookPath finds an executable in $PATH
+	lookPath func(string) (string, error)
+	// interactive reports whether the user can answer prompts
+	interactive func() bool
+	// terminal reports whether the output written to w is shown on a terminal
+	terminal func(w io.Writer) bool
+	// confirm asks a yes/no question, defaulting to no
+	confirm func(question string) bool
+	// proceed waits for the user to press Enter after prompt, reporting false
+	// when they stop instead
+	proceed func(prompt string) bool
+	// runTool runs a helper program such as a clipboard command, returning its
+	// standard output
+	runTool func(argv []string, stdin []byte) ([]byte, error)
+}
+
+// hostEnv is the osEnv describing the running system.
+var hostEnv = osEnv{
+	goos:        runtime.GOOS,
+	getenv:      os.Getenv,
+	lookupUser:  user.Lookup,
+	runCommand:  runShellCommand,
+	lookPath:    exec.LookPath,
+	interactive: stdinIsTerminal,
+	terminal:    writesToTerminal,
+	confirm:     confirmOnTerminal,
+	proceed:     proceedOnTerminal,
+	runTool:     runTool,
+}
+
+// homeDir returns the current user's home directory: %USERPROFILE% on Windows
+// and $HOME elsewhere.
+func (e osEnv) homeDir() (string, error) {
+	name := "HOME"
+	if e.goos == "windows" {
+		name = "USERPROFILE"
+	}
+	if home := e.getenv(name); home != "" {
+		return home, nil
+	}
+	return "", fmt.Errorf("cannot expand ~: %s is not set", name)
+}
+
+// isSeparator reports whether c separates path elements on the environment's platform.
+func (e osEnv) isSeparator(c byte) bool {
+	return c == '/' || (e.goos == "windows" && c == '\\')
+}
+
+// expandTilde expands a leading "~" or "~user" in p to the corresponding home directory.
+//
+// Paths that do not start with "~" are returned unchanged, as are "~name" forms where
+// no such user exists, so that literal file names such as "~$report.docx" keep working.
+// An error is returned only when p refers to the current user's home directory and it
+// cannot be determined.
+func (e osEnv) expandTilde(p string) (string, error) {
+	if !strings.HasPrefix(p, "~") {
+		return p, nil
+	}
+
+	end := 1
+	for end < len(p) && !e.isSeparator(p[end]) {
+		end++
+	}
+	name, rest := p[1:end], p[end:]
+
+	var home string
+	if name == "" {
+		var err error
+		home, err = e.homeDir()
+		if err != nil {
+			return p, err
+		}
+	} else {
+		u, err := e.lookupUser(name)
+		if err != nil || u.HomeDir == "" {
+			return p, nil
+		}
+		home = u.HomeDir
+	}
+
+	if rest == "" {
+		return home, nil
+	}
+	return strings.TrimRight(home, `/\`) + rest, nil
+}
+
+// expandPaths applies tilde expansion to every path in paths.
+func (e osEnv) expandPaths(paths []string) ([]string, error) {
+	expanded := make([]string, 0, len(paths))
+	for _, p := range paths {
+		x, err := e.expandTilde(p)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, x)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,101 @@
+package files2prompt
+
+import (
+	"io"
+	"os/user"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func fakeEnv(goos string, env map[string]string) osEnv {
+	return osEnv{
+		goos:   goos,
+		getenv: func(name string) string { return env[name] },
+		lookupUser: func(name string) (*user.User, error) {
+			switch name {
+			case "alice":
+				return &user.User{Username: "alice", HomeDir: "/home/alice"}, nil
+			case "bob":
+				return &user.User{Username: "bob", HomeDir: `C:\Users\bob`}, nil
+			}
+			return nil, user.UnknownUserError(name)
+		},
+		terminal: func(io.Writer) bool { return false },
+	}
+}
+
+func TestExpandTilde(t *testing.T) {
+	unix := fakeEnv("linux", map[string]string{"HOME": "/home/me"})
+	windows := fakeEnv("windows", map[string]string{"USERPROFILE": `C:\Users\me`, "HOME": "/ignored"})
+	homeless := fakeEnv("linux", map[string]string{})
+
+	tests := []struct {
+		name        string
+		env         osEnv
+		path        string
+		expected    string
+		expectedErr bool
+	}{
+		{name: "no tilde", env: unix, path: "src/main.go", expected: "src/main.go"},
+		{name: "tilde in middle", env: unix, path: "src/~/main.go", expected: "src/~/main.go"},
+		{name: "bare tilde", env: unix, path: "~", expected: "/home/me"},
+		{name: "tilde slash", env: unix, path: "~/", expected: "/home/me/"},
+		{name: "tilde path", env: unix, path: "~/projects/foo", expected: "/home/me/projects/foo"},
+		{name: "home with trailing slash", env: fakeEnv("linux", map[string]string{"HOME": "/"}), path: "~/x", expected: "/x"},
+		{name: "named user", env: unix, path: "~alice/code", expected: "/home/alice/code"},
+		{name: "bare named user", env: unix, path: "~alice", expected: "/home/alice"},
+		{name: "unknown user left unchanged", env: unix, path: "~nobody/code", expected: "~nobody/code"},
+		{name: "office lock file left unchanged", env: unix, path: "~$report.docx", expected: "~$report.docx"},
+		{name: "no home directory", env: homeless, path: "~/projects", expected: "~/projects", expectedErr: true},
+		{name: "no home directory for named user", env: homeless, path: "~alice/x", expected: "/home/alice/x"},
+		{name: "windows userprofile", env: windows, path: `~\projects\foo`, expected: `C:\Users\me\projects\foo`},
+		{name: "windows forward slash", env: windows, path: "~/projects", expected: `C:\Users\me/projects`},
+		{name: "windows bare tilde", env: windows, path: "~", expected: `C:\Users\me`},
+		{name: "windows named user", env: windows, path: `~bob\src`, expected: `C:\Users\bob\src`},
+		{name: "windows missing userprofile", env: fakeEnv("windows", map[string]string{"HOME": "/home/me"}), path: "~", expected: "~", expectedErr: true},
+		{name: "backslash is not a separator on unix", env: unix, path: `~\x`, expected: `~\x`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.env.expandTilde(tt.path)
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestExpandPaths(t *testing.T) {
+	env := fakeEnv("linux", map[string]string{"HOME": "/home/me"})
+	paths, err := env.expandPaths([]string{"~/a", "b", "~alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/home/me/a", "b", "/home/alice"}, paths)
+
+	_, err = fakeEnv("linux", nil).expandPaths([]string{"b", "~/a"})
+	assert.Error(t, err)
+}
+
+func TestRunReportsExpandedPath(t *testing.T) {
+	original := hostEnv
+	defer func() { hostEnv = original }()
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	hostEnv = fakeEnv("linux", map[string]string{"HOME": "/nonexistent-home"})
+	_, err := Run(config.Config{Paths: []string{"~/missing"}})
+	assert.NoError(t, err)
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Contains(t, hook.LastEntry().Message, "/nonexistent-home/missing")
+	}
+
+	hostEnv = fakeEnv("linux", nil)
+	_, err = Run(config.Config{Paths: []string{"~/missing"}})
+	assert.EqualError(t, err, "cannot expand ~: HOME is not set")
+}
@@ -0,0 +1,114 @@
+package files2prompt
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// countTokens estimates how many tokens a cl100k_base-style BPE tokenizer
+// produces for text.
+//
+// Text is first split the way cl100k's pre-tokenizer does: contractions, runs
+// of letters with an optional leading space or symbol, groups of up to three
+// digits, runs of punctuation, and whitespace. Each piece is then charged the
+// tokens BPE typically needs for it; common words and short symbol runs merge
+// into a single token, longer ones into a few. Without the merge table the
+// result is an estimate, but a far closer one than counting bytes.
+func countTokens(text string) int64 {
+	var tokens int64
+	for len(text) > 0 {
+		n, cost := nextPiece(text)
+		tokens += cost
+		text = text[n:]
+	}
+	return tokens
+}
+
+var contractions = []string{"'s", "'t", "'re", "'ve", "'m", "'ll", "'d"}
+
+// nextPiece returns the byte length of the pre-tokenizer piece at the start of
+// text and its estimated token cost.
+func nextPiece(text string) (int, int64) {
+	for _, c := range contractions {
+		if len(text) >= len(c) && equalFoldASCII(text[:len(c)], c) {
+			return len(c), 1
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(text)
+	switch {
+	case unicode.IsLetter(r):
+		n, letters := runOf(text, unicode.IsLetter)
+		return n, letterCost(letters)
+	case unicode.IsNumber(r):
+		n, digits := runOf(text, unicode.IsNumber)
+		if digits > 3 {
+			// Digits are grouped in threes
+			n = byteLenOfRunes(text, 3)
+		}
+		return n, 1
+	case r == '\n' || r == '\r':
+		n, _ := runOf(text, func(r rune) bool { return r == '\n' || r == '\r' })
+		return n, 1
+	case unicode.IsSpace(r):
+		n, spaces := runOf(text, func(r rune) bool { return unicode.IsSpace(r) && r != '\n' && r != '\r' })
+		if next, _ := utf8.DecodeRuneInString(text[n:]); n < len(text) && !unicode.IsSpace(next) && spaces == 1 {
+			// A single space is merged into the word or symbol run that follows
+			m, cost := nextPiece(text[n:])
+			return n + m, cost
+		}
+		return n, 1 + int64(spaces-1)/8
+	}
+
+	// A leading symbol joins a following run of letters, as in ".Println" or "_name"
+	if next, _ := utf8.DecodeRuneInString(text[size:]); size < len(text) && unicode.IsLetter(next) {
+		n, letters := runOf(text[size:], unicode.IsLetter)
+		return size + n, letterCost(letters)
+	}
+	n, symbols := runOf(text, func(r rune) bool {
+		return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return n, int64(symbols+1) / 2
+}
+
+// letterCost is the estimated number of tokens for a word of n letters.
+func letterCost(n int) int64 {
+	return int64(n+5) / 6
+}
+
+// runOf returns the byte length and rune count of the prefix of text whose runes satisfy f.
+func runOf(text string, f func(rune) bool) (int, int) {
+	n, count := 0, 0
+	for n < len(text) {
+		r, size := utf8.DecodeRuneInString(text[n:])
+		if !f(r) {
+			break
+		}
+		n += size
+		count++
+	}
+	return n, count
+}
+
+// byteLenOfRunes returns the byte length of the first count runes of text.
+func byteLenOfRunes(text string, count int) int {
+	n := 0
+	for i := 0; i < count && n < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[n:])
+		n += size
+	}
+	return n
+}
+
+func equalFoldASCII(a, b string) bool {
+	for i := 0; i < len(a); i++ {
+		ca, cb := a[i], b[i]
+		if 'A' <= ca && ca <= 'Z' {
+			ca += 'a' - 'A'
+		}
+		if ca != cb {
+			return false
+		}
+	}
+	return true
+}
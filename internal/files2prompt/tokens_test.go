@@ -0,0 +1,78 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestCountTokens(t *testing.T) {
+	// Expected values are the actual cl100k_base token counts
+	tests := []struct {
+		text     string
+		expected int64
+	}{
+		{"", 0},
+		{"Hello, world!", 4},
+		{"func main() {}", 4},
+		{"1234567", 3},
+		{"I'll be there", 4},
+		{"line one\nline two\n", 6},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, countTokens(tt.text), "countTokens(%q)", tt.text)
+	}
+}
+
+func TestGenerateCountTokensAllFormats(t *testing.T) {
+	formats := map[string]config.Config{
+		"plain":    {},
+		"markdown": {Markdown: true},
+		"cxml":     {ClaudeXML: true},
+	}
+	for name, cfg := range formats {
+		t.Run(name, func(t *testing.T) {
+			cfg.Paths = []string{"testdata/test_project"}
+			cfg.CountTokens = true
+
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			// The count covers the rendered output, wrappers and headers included
+			assert.Equal(t, countTokens(buf.String()), summary.Tokens)
+			assert.Equal(t, int64(buf.Len()), summary.Bytes)
+		})
+	}
+}
+
+func TestRunPrintsTokenSummary(t *testing.T) {
+	withStdout(t)
+	var stderr bytes.Buffer
+	originalStderr := osStderr
+	osStderr = &stderr
+	defer func() { osStderr = originalStderr }()
+
+	summary, err := Run(config.Config{Paths: []string{"testdata/file1.txt", "testdata/file2.txt"}, CountTokens: true})
+	require.NoError(t, err)
+	assert.Equal(t, "2 files, 100 bytes, ~39 tokens (cl100k-style estimate)\n", stderr.String())
+	assert.Equal(t, int64(39), summary.Tokens)
+
+	// The gutters' share is told with the summary, and only there
+	stderr.Reset()
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	summary, err = Run(config.Config{Paths: []string{"testdata/file1.txt"}, LineNumbers: true, CountTokens: true})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("1 file, %d bytes, ~%d tokens (cl100k-style estimate)\n  line-number gutters: ~%d tokens (~%d without numbering)\n",
+		summary.Bytes, summary.Tokens, summary.GutterTokens, summary.Tokens-summary.GutterTokens), stderr.String())
+	assert.Positive(t, summary.GutterTokens)
+	for _, entry := range hook.AllEntries() {
+		assert.NotContains(t, entry.Message, "gutters")
+	}
+}
@@ -0,0 +1,145 @@
+package files2prompt
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// treeSource is the display path of the document holding the --tree block.
+const treeSource = "directory-tree"
+
+// treeNode is a directory or file in the rendered tree, with its children in
+// the order they were first seen.
+type treeNode struct {
+	name     string
+	children []*treeNode
+	byName   map[string]*treeNode
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	if c, ok := n.byName[name]; ok {
+		return c
+	}
+	c := &treeNode{name: name, byName: map[string]*treeNode{}}
+	if n.byName == nil {
+		n.byName = map[string]*treeNode{}
+	}
+	n.byName[name] = c
+	n.children = append(n.children, c)
+	return c
+}
+
+// relativeToRoot returns f's path relative to the path argument it was found under.
+func relativeToRoot(f PlannedFile) string {
+	root := f.Root
+	if root == "." {
+		if wd, err := os.Getwd(); err == nil {
+			root = wd
+		}
+	}
+	rel, err := filepath.Rel(root, f.Path)
+	if err != nil {
+		return f.Path
+	}
+	return rel
+}
+
+// renderTree draws the included files of plan as an indented tree rooted at
+// each path argument, in the order the files will be emitted, with the names
+// anonymized by anon.
+func renderTree(plan []PlannedFile, anon *anonymizer) string {
+	var roots []*treeNode
+	byRoot := map[string]*treeNode{}
+	for _, f := range plan {
+		if !f.Included {
+			continue
+		}
+		rel := relativeToRoot(f)
+		root, ok := byRoot[f.Root]
+		if !ok {
+			root = &treeNode{name: anon.path(f.Root, rel == ".")}
+			byRoot[f.Root] = root
+			roots = append(roots, root)
+		}
+		if rel == "." {
+			// The path argument is the file itself
+			continue
+		}
+		node := root
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		for i, part := range parts {
+			node = node.child(anon.name(part, i == len(parts)-1))
+		}
+	}
+
+	var b strings.Builder
+	for _, root := range roots {
+		b.WriteString(root.name + "\n")
+		writeTreeChildren(&b, root, "")
+	}
+	return b.String()
+}
+
+func writeTreeChildren(b *strings.Builder, node *treeNode, indent string) {
+	for i, c := range node.children {
+		branch, next := "├── ", "│   "
+		if i == len(node.children)-1 {
+			branch, next = "└── ", "    "
+		}
+		b.WriteString(indent + branch + c.name + "\n")
+		writeTreeChildren(b, c, indent+next)
+	}
+}
+
+// writeTree writes the --tree block for plan ahead of the documents: a
+// "directory-tree" document in Claude XML mode, a fenced block in Markdown, and
+// a plain document otherwise.
+func writeTree(w io.Writer, plan []PlannedFile, config config.Config, state *emitState) error {
+	tree := renderTree(plan, state.anon)
+	var output string
+	switch {
+	case config.Format != "":
+		var err error
+		if output, err = digestDocument(treeSource, tree, "", config); err != nil {
+			return err
+		}
+		state.index++
+	case state.templates != nil:
+		var err error
+		if output, err = state.templates.document(treeSource, tree, "", state.index); err != nil {
+			return err
+		}
+		state.index++
+	case config.ClaudeXML:
+		output = cxmlNamesOf(config).document(state.index, treeSource, "", tree)
+		state.index++
+	case config.OpenAI:
+		output = state.openai.document(openaiText(treeSource, "", tree, state))
+	case config.JSONL:
+		var err error
+		if output, err = jsonlLine(treeSource, tree, state.index, state); err != nil {
+			return err
+		}
+		state.index++
+	case config.HTML:
+		output = htmlDocument(treeSource, treeSource, "", html.EscapeString(tree), state)
+	case config.Markdown:
+		backticks := getBackticks(tree)
+		output = fmt.Sprintf("%s%s\n%s%s\n%s", markdownLabel(config, treeSource), backticks, tree, backticks, markdownEnd(config))
+	default:
+		separator := getSeparator(tree)
+		header := treeSource
+		if separator != "---" {
+			header += " [sep=" + separator + "]"
+		}
+		output = fmt.Sprintf("%s\n%s\n%s%s\n\n", header, separator, tree, separator)
+	}
+	_, err := io.WriteString(w, output)
+	return err
+}
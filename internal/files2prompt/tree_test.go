@@ -0,0 +1,91 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+func TestRenderTree(t *testing.T) {
+	plan := []PlannedFile{
+		{Path: "proj/a/x.go", Root: "proj", Included: true},
+		{Path: "proj/a/b/y.go", Root: "proj", Included: true},
+		{Path: "proj/a/skipped.go", Root: "proj", Reason: SkipIgnore},
+		{Path: "proj/z.md", Root: "proj", Included: true},
+		{Path: "notes.rst", Root: "notes.rst", Included: true},
+		{Path: "other/c.go", Root: "other", Included: true},
+	}
+	expected := "proj\n" +
+		"├── a\n" +
+		"│   ├── x.go\n" +
+		"│   └── b\n" +
+		"│       └── y.go\n" +
+		"└── z.md\n" +
+		"notes.rst\n" +
+		"other\n" +
+		"└── c.go\n"
+	assert.Equal(t, expected, renderTree(plan, nil))
+	assert.Empty(t, renderTree(nil, nil))
+}
+
+func TestGenerateTree(t *testing.T) {
+	base := config.Config{
+		Paths:          []string{"testdata/test_project", "testdata/file1.txt"},
+		Extensions:     []string{".go", ".txt"},
+		IgnorePatterns: []string{"temp/"},
+		Tree:           true,
+	}
+	tree := "testdata/file1.txt\n" +
+		"testdata/test_project\n" +
+		"├── docs\n" +
+		"│   └── README.txt\n" +
+		"└── src\n" +
+		"    └── main.go\n"
+
+	tests := []struct {
+		name   string
+		config func(c *config.Config)
+		prefix string
+	}{
+		{"plain", func(*config.Config) {}, "directory-tree\n---\n" + tree + "---\n\ntestdata/file1.txt\n---\n"},
+		{"markdown", func(c *config.Config) { c.Markdown = true }, "# Files\n\n## directory-tree\n\n```\n" + tree + "```\n\n## testdata/file1.txt\n\n```\n"},
+		{"cxml", func(c *config.Config) { c.ClaudeXML = true },
+			"<documents>\n<document index=\"1\">\n<source>directory-tree</source>\n<document_content>\n" + tree +
+				"</document_content>\n</document>\n<document index=\"2\">\n<source>testdata/file1.txt</source>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			tt.config(&cfg)
+			var buf bytes.Buffer
+			summary, err := Generate(context.Background(), cfg, &buf, nil)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(buf.String(), tt.prefix), buf.String())
+			// The tree is not counted as a document
+			assert.Equal(t, 3, summary.Files)
+
+			// Without --tree the output is the documents alone
+			cfg.Tree = false
+			var plain bytes.Buffer
+			_, err = Generate(context.Background(), cfg, &plain, nil)
+			require.NoError(t, err)
+			assert.NotContains(t, plain.String(), "directory-tree")
+			if !cfg.ClaudeXML {
+				// The tree goes after any Markdown title
+				assert.True(t, strings.HasSuffix(buf.String(), strings.TrimPrefix(plain.String(), "# Files\n\n")))
+			}
+		})
+	}
+}
+
+func TestGenerateTreeSkippedWhenEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Generate(context.Background(), config.Config{Paths: []string{"testdata/test_project"}, Extensions: []string{".none"}, Tree: true}, &buf, nil)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,25 @@
+//go:build darwin || freebsd || dragonfly
+
+package files2prompt
+
+import "syscall"
+
+// virtualFSNames are the names statfs reports for virtual file systems.
+var virtualFSNames = map[string]bool{"devfs": true, "procfs": true, "linprocfs": true, "linsysfs": true, "fdescfs": true}
+
+// virtualFileSystem returns the name of the virtual file system path is on,
+// following symlinks, and whether it is on one.
+func virtualFileSystem(path string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", false
+	}
+	var name []byte
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name), virtualFSNames[string(name)]
+}
@@ -0,0 +1,33 @@
+package files2prompt
+
+import "syscall"
+
+// virtualFSMagic names the virtual file systems by the magic number statfs
+// reports for them. devtmpfs shares its number with tmpfs, so /dev is left to
+// the device-file rule.
+var virtualFSMagic = map[uint32]string{
+	0x9fa0:     "proc",
+	0x62656572: "sysfs",
+	0x1cd1:     "devpts",
+	0x64626720: "debugfs",
+	0x74726163: "tracefs",
+	0x73636673: "securityfs",
+	0x27e0eb:   "cgroup",
+	0x63677270: "cgroup2",
+	0xcafe4a11: "bpf",
+	0x62656570: "configfs",
+	0x6165676c: "pstore",
+	0xde5e81e4: "efivarfs",
+	0x65735543: "fusectl",
+}
+
+// virtualFileSystem returns the name of the virtual file system path is on,
+// following symlinks, and whether it is on one.
+func virtualFileSystem(path string) (string, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return "", false
+	}
+	name, ok := virtualFSMagic[uint32(st.Type)]
+	return name, ok
+}
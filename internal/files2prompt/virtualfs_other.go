@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly
+
+package files2prompt
+
+// virtualFileSystem reports that no path is known to be on a virtual file
+// system, as the platform has no statfs to tell.
+func virtualFileSystem(path string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,134 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// endlessFS holds files that report a size of size but yield their line
+// without end, as files under /proc can.
+type endlessFS struct {
+	line string
+	size int64
+}
+
+func (e endlessFS) Open(name string) (fs.File, error) {
+	return &endlessFile{endlessFS: e, name: name}, nil
+}
+
+type endlessFile struct {
+	endlessFS
+	name string
+	off  int
+}
+
+func (f *endlessFile) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = f.line[f.off%len(f.line)]
+		f.off++
+	}
+	return len(p), nil
+}
+
+func (f *endlessFile) Stat() (fs.FileInfo, error) { return endlessInfo(*f), nil }
+func (f *endlessFile) Close() error               { return nil }
+
+type endlessInfo endlessFile
+
+func (i endlessInfo) Name() string       { return i.name }
+func (i endlessInfo) Size() int64        { return i.size }
+func (i endlessInfo) Mode() fs.FileMode  { return 0o444 }
+func (i endlessInfo) ModTime() time.Time { return time.Time{} }
+func (i endlessInfo) IsDir() bool        { return false }
+func (i endlessInfo) Sys() any           { return nil }
+
+func TestReadCapped(t *testing.T) {
+	open := func(fsys fs.FS) fs.File {
+		f, err := fsys.Open("stat")
+		require.NoError(t, err)
+		return f
+	}
+
+	// A file reporting a size of 0 is cut off after its last whole line
+	content, cut, err := readCapped(open(endlessFS{line: "cpu 1 2 3\n"}), DefaultReadLimit, 25, nil)
+	require.NoError(t, err)
+	assert.True(t, cut)
+	assert.Equal(t, "cpu 1 2 3\ncpu 1 2 3\n... [cut off after 25 B: the file reports a size of 0, as virtual files do] ...\n", string(content))
+
+	content, cut, err = readCapped(open(endlessFS{line: strings.Repeat("x", 100)}), DefaultReadLimit, 10, nil)
+	require.NoError(t, err)
+	assert.True(t, cut)
+	assert.Equal(t, "xxxxxxxxxx\n... [cut off after 10 B: the file reports a size of 0, as virtual files do] ...\n", string(content))
+
+	// A file reporting a size is held to the read limit, and fails past it
+	_, cut, err = readCapped(open(endlessFS{line: "x", size: 4096}), 100, 10, nil)
+	assert.EqualError(t, err, "file exceeds the 100 B read limit")
+	assert.False(t, cut)
+
+	// A read limit below the cutoff still applies
+	_, _, err = readCapped(open(endlessFS{line: "x"}), 5, 10, nil)
+	assert.EqualError(t, err, "file exceeds the 5 B read limit")
+
+	// An empty file, or a zero-size one yielding less than the cutoff, is read whole
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"empty.txt": ""})
+	f, err := os.Open(filepath.Join(dir, "empty.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+	content, cut, err = readCapped(f, DefaultReadLimit, 10, nil)
+	require.NoError(t, err)
+	assert.False(t, cut)
+	assert.Empty(t, content)
+
+	assert.Equal(t, DefaultZeroSizeLimit, zeroSizeLimit(config.Config{}))
+	assert.Equal(t, int64(10), zeroSizeLimit(config.Config{ZeroSizeLimit: 10}))
+}
+
+func TestVirtualFS(t *testing.T) {
+	// /proc/self/status reports a size of 0, and is on procfs
+	if _, ok := virtualFileSystem("/proc/self/status"); !ok {
+		t.Skip("no procfs mounted at /proc")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n"})
+	require.NoError(t, os.Symlink("/proc/self/status", filepath.Join(dir, "status")))
+	t.Chdir(dir)
+
+	planned, err := Plan(context.Background(), config.Config{Paths: []string{"."}}, true)
+	require.NoError(t, err)
+	reasons := map[string]SkipReason{}
+	for _, f := range planned {
+		reasons[f.Path] = f.Reason
+	}
+	assert.Equal(t, map[string]SkipReason{filepath.Join(dir, "main.go"): "", filepath.Join(dir, "status"): SkipVirtualFS}, reasons)
+
+	planned, err = Plan(context.Background(), config.Config{Paths: []string{"/proc/self/status", "/proc/self"}}, true)
+	require.NoError(t, err)
+	require.Len(t, planned, 2)
+	for _, f := range planned {
+		assert.Equal(t, SkipVirtualFS, f.Reason, f.Path)
+	}
+
+	var out bytes.Buffer
+	_, err = Generate(context.Background(), config.Config{Paths: []string{"status"}, IncludeVirtualFS: true, ZeroSizeLimit: 64}, &out, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "status\n---\nName:"), out.String())
+	assert.Contains(t, out.String(), "... [cut off after 64 B: the file reports a size of 0, as virtual files do] ...\n---\n")
+
+	// Without the cutoff in reach, the file is read whole
+	out.Reset()
+	_, err = Generate(context.Background(), config.Config{Paths: []string{"status"}, IncludeVirtualFS: true}, &out, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, out.String(), "cut off")
+	assert.Contains(t, out.String(), "\nPid:")
+}
@@ -0,0 +1,166 @@
+package files2prompt
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// warningsSource is the display path of the --embed-warnings section.
+const warningsSource = "omissions"
+
+// warningExamples is how many paths a warning names before summarizing the rest.
+const warningExamples = 3
+
+// omissions returns one factual sentence per kind of content left out of the
+// output of plan: files over --max-size or the read limit, withheld sensitive
+// files, links outside the jail, files over a token budget, unreadable files,
+// files modified during the run, and truncated command output. It also notes
+// the file paths that differ only in case, which unpack as one file where
+// case is ignored.
+func omissions(plan []PlannedFile, config config.Config, state *emitState) []string {
+	byReason := map[SkipReason][]PlannedFile{}
+	for _, f := range plan {
+		if !f.Included && !f.IsDir {
+			byReason[f.Reason] = append(byReason[f.Reason], f)
+		}
+	}
+
+	var sentences []string
+	if files := byReason[SkipMaxSize]; len(files) > 0 {
+		sentences = append(sentences, oversizeSentence(files, "over "+formatBytes(int64(config.MaxFileSize))))
+	}
+	if files := byReason[SkipTooLarge]; len(files) > 0 {
+		sentences = append(sentences, oversizeSentence(files, "over the "+formatBytes(readLimit(config))+" read limit"))
+	}
+	if files := byReason[SkipSensitive]; len(files) > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s that commonly %s secrets %s withheld: %s.",
+			len(files), plural(len(files), "file", "files"), plural(len(files), "contains", "contain"),
+			plural(len(files), "was", "were"), examples(displayPaths(files))))
+	}
+	if files := byReason[SkipJail]; len(files) > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s outside the jail directory %s omitted: %s.",
+			len(files), plural(len(files), "link pointing", "links pointing"), plural(len(files), "was", "were"),
+			examples(displayPaths(files))))
+	}
+	if files := byReason[SkipBudget]; len(files) > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s that did not fit the %d-token budget %s omitted: %s.",
+			len(files), plural(len(files), "file", "files"), config.FitTokens, plural(len(files), "was", "were"),
+			examples(displayPaths(files))))
+	}
+	if files := byReason[SkipMaxTokens]; len(files) > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s left out once the output reached the %d-token --max-tokens budget: %s.",
+			len(files), plural(len(files), "file was", "files were"), config.MaxTokens, examples(displayPaths(files))))
+	}
+	if collided := caseCollided(plan); len(collided) > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s only in case from another, so that on a case-insensitive file system one overwrites the other: %s.",
+			len(collided), plural(len(collided), "file path differs", "file paths differ"), examples(collided)))
+	}
+	if n := len(state.unreadable); n > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s could not be read and %s omitted: %s.",
+			n, plural(n, "file", "files"), plural(n, "was", "were"), examples(state.unreadable)))
+	}
+	if n := len(state.drifted); n > 0 {
+		sentences = append(sentences, fmt.Sprintf("%d %s changed while the output was being produced, so %s may not match the rest of it: %s.",
+			n, plural(n, "file", "files"), plural(n, "its document", "their documents"), examples(state.drifted)))
+	}
+	if n := len(state.truncated); n > 0 {
+		limit := config.CmdMaxBytes
+		if limit <= 0 {
+			limit = DefaultCmdMaxBytes
+		}
+		sentences = append(sentences, fmt.Sprintf("The output of %d %s was truncated at %s: %s.",
+			n, plural(n, "command", "commands"), formatBytes(limit), examples(state.truncated)))
+	}
+	return sentences
+}
+
+// oversizeSentence describes files omitted for being over a size bound,
+// naming the largest of them.
+func oversizeSentence(files []PlannedFile, bound string) string {
+	largest := files[0]
+	for _, f := range files[1:] {
+		if f.Size > largest.Size {
+			largest = f
+		}
+	}
+	if len(files) == 1 {
+		return fmt.Sprintf("1 file %s was omitted: %s at %s.", bound, largest.DisplayPath, formatBytes(largest.Size))
+	}
+	return fmt.Sprintf("%d files %s were omitted; the largest is %s at %s.",
+		len(files), bound, largest.DisplayPath, formatBytes(largest.Size))
+}
+
+func displayPaths(files []PlannedFile) []string {
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.DisplayPath
+	}
+	return paths
+}
+
+// examples lists the first warningExamples of items, counting the rest.
+func examples(items []string) string {
+	if len(items) <= warningExamples {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:warningExamples], ", "), len(items)-warningExamples)
+}
+
+// writeWarnings writes the --embed-warnings section listing sentences at the
+// end of the output, in the shape of a document for the output format. Nothing
+// is written when nothing was left out.
+func writeWarnings(w io.Writer, sentences []string, config config.Config, state *emitState) error {
+	if len(sentences) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("Some content was left out of this prompt:\n")
+	for _, s := range sentences {
+		b.WriteString("- " + s + "\n")
+	}
+	body := b.String()
+
+	var output string
+	switch {
+	case config.Format != "":
+		var err error
+		if output, err = digestDocument(warningsSource, body, "", config); err != nil {
+			return err
+		}
+		state.index++
+	case state.templates != nil:
+		var err error
+		if output, err = state.templates.document(warningsSource, body, "", state.index); err != nil {
+			return err
+		}
+		state.index++
+	case config.ClaudeXML:
+		output = cxmlNamesOf(config).document(state.index, warningsSource, "", body)
+		state.index++
+	case config.OpenAI:
+		output = state.openai.document(strings.TrimSuffix(body, "\n"))
+	case config.JSONL:
+		var err error
+		if output, err = jsonlLine(warningsSource, body, state.index, state); err != nil {
+			return err
+		}
+		state.index++
+	case config.HTML:
+		output = htmlDocument(warningsSource, "Omissions", "", html.EscapeString(body), state)
+	case config.Markdown:
+		output = markdownSection(config, "Omissions") + body
+	default:
+		separator := getSeparator(body)
+		header := warningsSource
+		if separator != "---" {
+			header += " [sep=" + separator + "]"
+		}
+		output = fmt.Sprintf("%s\n%s\n%s%s\n\n", header, separator, body, separator)
+	}
+	_, err := io.WriteString(w, output)
+	return err
+}
@@ -0,0 +1,113 @@
+package files2prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+// omissionFixture plans a tree that triggers every kind of omission: files over
+// --max-size and the read limit, a sensitive file, a file deleted between
+// planning and emission, and a truncated command.
+func omissionFixture(t *testing.T, cfg config.Config) (string, string) {
+	t.Helper()
+	withCommandRunner(t, fakeRunner)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":          "package main\n",
+		"assets/data.json": strings.Repeat("x", 9000),
+		"assets/icon.json": strings.Repeat("x", 5000),
+		"dump.sql":         strings.Repeat("x", 2000),
+		".env":             "TOKEN=1\n",
+		"gone.go":          "package gone\n",
+	})
+	cfg.Paths = []string{dir}
+	cfg.IncludeHidden = true
+	cfg.MaxFileSize = 4096
+	cfg.ReadLimit = 1024
+	cfg.Commands = []string{"chatty"}
+	cfg.CmdMaxBytes = 50
+	cfg.EmbedWarnings = true
+	cfg.Sort = "path"
+
+	plan, err := Plan(context.Background(), cfg, true)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "gone.go")))
+	var buf bytes.Buffer
+	_, err = Generate(context.Background(), cfg, &buf, plan)
+	require.NoError(t, err)
+	return dir, buf.String()
+}
+
+func TestEmbedWarnings(t *testing.T) {
+	warnings := func(dir string) string {
+		return "Some content was left out of this prompt:\n" +
+			"- 2 files over 4.0 KiB were omitted; the largest is " + dir + "/assets/data.json at 8.8 KiB.\n" +
+			"- 1 file over the 1.0 KiB read limit was omitted: " + dir + "/dump.sql at 2.0 KiB.\n" +
+			"- 1 file that commonly contains secrets was withheld: " + dir + "/.env.\n" +
+			"- 1 file could not be read and was omitted: " + dir + "/gone.go.\n" +
+			"- The output of 1 command was truncated at 50 B: chatty.\n"
+	}
+	tests := []struct {
+		name   string
+		config config.Config
+		suffix func(dir string) string
+	}{
+		{
+			name: "plain",
+			suffix: func(dir string) string {
+				return "chatty\n---\n" + strings.Repeat("x", 40) + strings.Repeat("y", 10) + "\n[output truncated at 50 B]\n---\n\n" +
+					"omissions\n---\n" + warnings(dir) + "---\n\n"
+			},
+		},
+		{
+			name:   "markdown",
+			config: config.Config{Markdown: true},
+			suffix: func(dir string) string {
+				return "[output truncated at 50 B]\n```\n\n## Omissions\n\n" + warnings(dir)
+			},
+		},
+		{
+			name:   "claude xml",
+			config: config.Config{ClaudeXML: true},
+			suffix: func(dir string) string {
+				return "<document index=\"3\">\n<source>omissions</source>\n<document_content>\n" + warnings(dir) +
+					"</document_content>\n</document>\n</documents>\n"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, out := omissionFixture(t, tt.config)
+			assert.True(t, strings.HasSuffix(out, tt.suffix(dir)), out)
+		})
+	}
+}
+
+func TestEmbedWarningsOff(t *testing.T) {
+	cfg := config.Config{Paths: []string{"testdata/file1.txt"}}
+	var plain bytes.Buffer
+	_, err := Generate(context.Background(), cfg, &plain, nil)
+	require.NoError(t, err)
+
+	// Nothing is written when nothing was left out
+	cfg.EmbedWarnings = true
+	var embedded bytes.Buffer
+	_, err = Generate(context.Background(), cfg, &embedded, nil)
+	require.NoError(t, err)
+	assert.Equal(t, plain.String(), embedded.String())
+}
+
+func TestExamples(t *testing.T) {
+	assert.Equal(t, "a", examples([]string{"a"}))
+	assert.Equal(t, "a, b, c", examples([]string{"a", "b", "c"}))
+	assert.Equal(t, "a, b, c and 2 more", examples([]string{"a", "b", "c", "d", "e"}))
+}
@@ -0,0 +1,204 @@
+// Package history records a local, telemetry-free log of files2prompt runs.
+//
+// Each successful run recorded appends a single JSON line to a history file
+// stored in the user's data directory (never inside the crawled repository).
+// Entries hold the effective flag set so that a previous run can be
+// reproduced exactly, except for the values of sensitive flags, which are
+// never stored.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// SensitiveAnnotation is the pflag annotation key marking flags whose values
+// must never be persisted to the history file.
+const SensitiveAnnotation = "files2prompt_sensitive"
+
+// Entry is a single recorded run.
+type Entry struct {
+	ID         int       `json:"id"`
+	Time       time.Time `json:"time"`
+	Dir        string    `json:"dir"`
+	Args       []string  `json:"args"`
+	OutputFile string    `json:"output_file,omitempty"`
+	// Elided names the flags set in the run whose values were not recorded
+	Elided []string `json:"elided,omitempty"`
+	// Stdin reports whether the run read paths or content from stdin, which
+	// is not recorded
+	Stdin  bool  `json:"stdin,omitempty"`
+	Files  int   `json:"files"`
+	Bytes  int64 `json:"bytes"`
+	Tokens int64 `json:"tokens"`
+}
+
+// DefaultPath returns the location of the history file inside the user data directory.
+//
+// The directory is resolved as $XDG_DATA_HOME when set, %LOCALAPPDATA% on Windows,
+// ~/Library/Application Support on macOS, and ~/.local/share otherwise.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		switch runtime.GOOS {
+		case "windows":
+			dir = os.Getenv("LOCALAPPDATA")
+		case "darwin":
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, "Library", "Application Support")
+		}
+	}
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "files2prompt", "history.jsonl"), nil
+}
+
+// Load reads all entries from the history file at path.
+// A missing file yields no entries and no error.
+func Load(path string) ([]Entry, error) {
+	content, err := os.ReadFile(path) // #nosec G304
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse history file %s: %w", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Append adds entry to the history file at path, assigning it the next ID,
+// and rotates the file so that at most max entries are kept. A max of zero
+// or less disables the cap.
+func Append(path string, entry Entry, max int) (Entry, error) {
+	entries, err := Load(path)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	entries = append(entries, entry)
+	if max > 0 && len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return entry, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return entry, err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return entry, err
+	}
+	return entry, os.Rename(tmp, path)
+}
+
+// Find returns the entry with the given id.
+func Find(entries []Entry, id int) (Entry, bool) {
+	for _, e := range entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// ForDir returns the entries recorded in dir, most recent last, limited to the last n
+// entries when n is positive.
+func ForDir(entries []Entry, dir string, n int) []Entry {
+	var matched []Entry
+	for _, e := range entries {
+		if e.Dir == dir {
+			matched = append(matched, e)
+		}
+	}
+	if n > 0 && len(matched) > n {
+		matched = matched[len(matched)-n:]
+	}
+	return matched
+}
+
+// FlagArgs reconstructs the command-line flags that produce the effective
+// values currently held by fs, whatever set them: every flag explicitly set on
+// the command line, or whose value differs from that of the same flag in
+// defaults, as one set by a config file or environment variable does, is
+// emitted in --name=value form; slice flags are emitted once per element.
+// Flags annotated with SensitiveAnnotation are not emitted but returned in
+// elided.
+func FlagArgs(fs, defaults *pflag.FlagSet) (args, elided []string) {
+	fs.VisitAll(func(f *pflag.Flag) {
+		builtin := defaults.Lookup(f.Name)
+		if !f.Changed && (builtin == nil || f.Value.String() == builtin.DefValue) {
+			return
+		}
+		if _, ok := f.Annotations[SensitiveAnnotation]; ok {
+			elided = append(elided, f.Name)
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				args = append(args, "--"+f.Name+"="+csvQuote(v))
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+	return args, elided
+}
+
+// csvQuote quotes v so that pflag's CSV-based slice parsing reads it back as a single element.
+func csvQuote(v string) string {
+	if !strings.ContainsAny(v, ",\"\n\r") {
+		return v
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{v})
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,145 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+
+	path, err := DefaultPath()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "files2prompt", "history.jsonl"), path)
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	entries, err := Load(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.jsonl")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	first, err := Append(path, Entry{Time: now, Dir: "/repo", Args: []string{"--", "."}, Bytes: 10, Tokens: 3}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+
+	second, err := Append(path, Entry{Time: now, Dir: "/other", Args: []string{"--", "src"}}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+
+	entries, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, Entry{ID: 1, Time: now, Dir: "/repo", Args: []string{"--", "."}, Bytes: 10, Tokens: 3}, entries[0])
+
+	assert.Len(t, ForDir(entries, "/repo", 0), 1)
+	got, ok := Find(entries, 2)
+	assert.True(t, ok)
+	assert.Equal(t, "/other", got.Dir)
+	_, ok = Find(entries, 3)
+	assert.False(t, ok)
+}
+
+func TestAppendRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	for i := 0; i < 5; i++ {
+		_, err := Append(path, Entry{Dir: "/repo"}, 3)
+		require.NoError(t, err)
+	}
+
+	entries, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []int{3, 4, 5}, []int{entries[0].ID, entries[1].ID, entries[2].ID})
+
+	// IDs keep increasing after rotation
+	e, err := Append(path, Entry{Dir: "/repo"}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 6, e.ID)
+
+	assert.Len(t, ForDir(entries, "/repo", 2), 2)
+}
+
+func TestAppendLargeCounters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	entry := Entry{Dir: "/repo", Bytes: 6 << 30, Tokens: 3 << 31}
+	_, err := Append(path, entry, 10)
+	require.NoError(t, err)
+
+	entries, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(6<<30), entries[0].Bytes)
+	assert.Equal(t, int64(3<<31), entries[0].Tokens)
+}
+
+type flagValues struct {
+	extensions []string
+	ignore     []string
+	output     string
+	cxml       bool
+	secret     string
+}
+
+func newTestFlagSet(v *flagValues) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.StringSliceVarP(&v.extensions, "extension", "e", []string{}, "")
+	fs.StringSliceVar(&v.ignore, "ignore", []string{}, "")
+	fs.StringVarP(&v.output, "output", "o", "", "")
+	fs.BoolVarP(&v.cxml, "cxml", "c", false, "")
+	fs.StringVar(&v.secret, "secret", "", "")
+	_ = fs.SetAnnotation("secret", SensitiveAnnotation, []string{"true"})
+	return fs
+}
+
+func TestFlagArgsRerunFidelity(t *testing.T) {
+	var original flagValues
+	fs := newTestFlagSet(&original)
+	require.NoError(t, fs.Parse([]string{
+		"-e", ".go,.md", "--ignore", `weird,"name"`, "-o", "out file.txt", "-c", "--secret", "hunter2",
+	}))
+
+	args, elided := FlagArgs(fs, newTestFlagSet(&flagValues{}))
+	assert.NotContains(t, args, "--secret=hunter2")
+	assert.Equal(t, []string{"secret"}, elided)
+
+	var replayed flagValues
+	require.NoError(t, newTestFlagSet(&replayed).Parse(args))
+	original.secret = ""
+	assert.Equal(t, original, replayed)
+}
+
+func TestFlagArgsUnchangedFlagsOmitted(t *testing.T) {
+	var v flagValues
+	fs := newTestFlagSet(&v)
+	require.NoError(t, fs.Parse([]string{"--cxml"}))
+	args, elided := FlagArgs(fs, newTestFlagSet(&flagValues{}))
+	assert.Equal(t, []string{"--cxml=true"}, args)
+	assert.Empty(t, elided)
+}
+
+func TestFlagArgsRecordsConfiguredValues(t *testing.T) {
+	// Values from config files and the environment are the flags' defaults
+	var v flagValues
+	fs := newTestFlagSet(&v)
+	for name, value := range map[string]string{"extension": ".go", "output": "out.md", "secret": "hunter2"} {
+		f := fs.Lookup(name)
+		require.NoError(t, f.Value.Set(value))
+		f.DefValue = f.Value.String()
+	}
+	require.NoError(t, fs.Parse([]string{"--ignore", "vendor"}))
+
+	args, elided := FlagArgs(fs, newTestFlagSet(&flagValues{}))
+	assert.Equal(t, []string{"--extension=.go", "--ignore=vendor", "--output=out.md"}, args)
+	assert.Equal(t, []string{"secret"}, elided)
+}
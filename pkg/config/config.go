@@ -1,14 +1,17 @@
 // Package config provides secure configuration management for the files2prompt application.
 //
-// This package handles loading configuration from environment variables and .env files
-// with built-in security measures to prevent path traversal attacks. It uses the
-// github.com/caarlos0/env library for environment variable parsing and
-// github.com/joho/godotenv for .env file loading.
+// This package handles loading configuration from YAML config files, environment
+// variables and .env files with built-in security measures to prevent path traversal
+// attacks. It uses the github.com/caarlos0/env library for environment variable
+// parsing, github.com/joho/godotenv for .env file loading and gopkg.in/yaml.v3 for
+// config files.
 //
 // The configuration loading follows a priority order:
 //  1. Environment variables (highest priority)
 //  2. .env file in current working directory
-//  3. Default values (if any)
+//  3. .files2prompt.yaml in the current working directory or its nearest parent
+//  4. ~/.config/files2prompt/config.yaml
+//  5. Default values (if any)
 //
 // Security features:
 //   - Path traversal protection for .env file loading
@@ -22,10 +25,15 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -38,16 +46,143 @@ import (
 // environment variable names for automatic parsing.
 //
 // Configuration options include:
-//   - Paths: File and directory paths to process
-//   - Extensions: File extensions to include in processing
+//   - Paths: File and directory paths to process; "-" stands for the content of standard input
+//   - Profile: Name of the config file profile whose settings are applied over the rest of the config files
+//   - Preset: Names of the built-in or config file presets applied over the config files, in order, each overriding the ones before (name@version pins a version)
+//   - StdinPaths: Paths read from standard input, filtered as a file list rather than as explicit arguments
+//   - StdinContent: Content read from standard input for a "-" path argument, emitted as a single document
+//   - StdinName: Source name of the standard input document ("stdin" if empty)
+//   - Jail: Refuse to read or write anything whose real path lies outside this directory
+//   - Extensions: File extensions to include in processing, with or without the leading dot
+//   - ExcludeExtensions: File extensions to leave out, applied after Extensions
+//   - IgnoreCase: Match extensions and ignore and include patterns regardless of case
 //   - IncludeHidden: Whether to include hidden files and directories
+//   - IncludeVCSDirs: Walk into .git, .hg and .svn directories, which are otherwise always skipped
+//   - IncludeGenerated: Include files marked linguist-generated in .gitattributes and Go files with a "Code generated ... DO NOT EDIT." header
+//   - IncludeJunk: Include OS metadata and editor leftovers such as .DS_Store, ._* AppleDouble files, Thumbs.db and *.swp
 //   - IgnoreGitignore: Whether to ignore .gitignore file rules
+//   - IncludeSensitive: Include files that commonly contain secrets (.env, keys, credentials)
 //   - IgnorePatterns: Custom patterns to ignore during processing
-//   - OutputFile: Path for output file (stdout if empty)
+//   - DisableDefaultIgnores: Walk the dependency, build and editor directories skipped by default
+//   - IncludePatterns: Patterns a file must match to be included (directories are always descended into)
+//   - AutoExtensions: Include only the sources of the repository's main languages, their manifests and READMEs
+//   - UseExportIgnore: Exclude paths marked export-ignore in .gitattributes
+//   - GitTracked: Only walk the files git tracks in the repositories holding the input paths
+//   - MaxDepth: Levels below each directory argument to walk (0 for no limit)
+//   - FollowSymlinks: Walk into symlinked directories, whose files are otherwise left out
+//   - NoRecurse: Walk only the immediate children of each directory argument, as MaxDepth 1
+//   - Submodules: How git submodules are treated: "include" (the default), "skip" or "separate"
+//   - Grep: Only include files whose content matches this regular expression
+//   - GrepContext: Emit only the matching lines with this many lines of context (-1 emits whole files)
+//   - ReadLimit: Never read files larger than this many bytes (0 means the 1 GiB default)
+//   - ZeroSizeLimit: Cut off a file reporting a size of 0 after this many bytes (0 means the 1 MiB default)
+//   - IncludeVirtualFS: Read paths on virtual file systems such as procfs and sysfs, which are skipped by default
+//   - Concurrency: Number of files read at once (0 means one per CPU)
+//   - Throttle: Rate in MB/s that all file reads together keep to (0 means unlimited)
+//   - LowPriority: Lower the CPU and I/O priority of the process where the platform allows
+//   - MaxFileSize: Skip walked and listed files larger than this size, e.g. "500k" or "2m" (0 means unlimited)
+//   - MinFileSize: Skip walked and listed files smaller than this size (0 means no minimum)
+//   - NewerThan: Skip walked and listed files last modified before this age, e.g. "48h" or "7d", or date, e.g. "2024-06-01"
+//   - OlderThan: Skip walked and listed files last modified after this age or date
+//   - OutputFile: Path for output file, or an http(s) URL to upload it to (stdout if empty)
+//   - FlushEveryFile: Flush the buffered output after every file, for watching it as it is generated
+//   - OutputMethod: HTTP method used when OutputFile is a URL, PUT (default) or POST
+//   - OutputAuthEnv: Environment variable holding the Authorization header, or a bearer token, for uploads
+//   - SplitTokens: Divide the output into numbered OutputFile chunks of at most this many tokens each (0 disables)
+//   - SplitBytes: Divide the output into numbered OutputFile chunks of at most this size each, e.g. "200k" (0 disables)
+//   - SplitIndexes: Numbering of Claude XML documents across chunks: continue (default) or restart
+//   - Batch: YAML file listing jobs, each writing its own output with its own filters and format, from a single walk
+//   - MirrorTo: Directory to copy the emitted content of every included file into, preserving relative paths
+//   - MirrorOnly: Only write the MirrorTo copies, without the usual output
+//   - Force: Overwrite existing files in the MirrorTo directory
+//   - Clipboard: Copy the output to the system clipboard instead of writing it to stdout
+//   - CopyChunked: Copy the output in parts of at most CopyChunkSize, waiting for Enter between them
+//   - CopyChunkSize: Largest part CopyChunked copies, and the size above which Clipboard suggests it (0 means 1m)
+//   - TerminalLimit: Output size above which writing to a terminal warns (0 means 256k)
+//   - OutputLimit: Output size above which writing to OutputFile warns (0 for no limit)
+//   - Exec: Command to run after a successful run, with {} replaced by the output path
+//   - Pipes: Commands the rendered output is streamed through, in order, before it reaches the destination
+//   - PipeTimeout: Time limit for the whole Pipes chain (0 means the 5m default)
+//   - Commands: Commands whose combined output is included as synthetic documents
+//   - CmdLabels: Display paths for the Commands documents, by position (the command itself if unset)
+//   - CmdTimeout: Time limit for each command (0 means the 30s default)
+//   - CmdMaxBytes: Output kept from each command before truncating (0 means the 1 MiB default)
+//   - CmdStrict: Abort the run when a command fails or times out
+//   - EnvContext: Start the output with a document describing the platform, Go version and tool versions, never environment variables
+//   - EnvContextCmds: Commands, such as "node --version", whose output is added to the EnvContext document
 //   - ClaudeXML: Enable XML output format for Claude AI
+//   - CXMLMaxDocBytes: Split Claude XML documents larger than this many bytes into parts
+//   - CXMLSchema: Element and attribute naming of Claude XML output: anthropic (default), generic-files or custom
+//   - CXMLRoot: Element wrapping the documents with the custom schema
+//   - CXMLItem: Element of each document with the custom schema
+//   - CXMLIndexAttr: Attribute numbering the documents with the custom schema ("" for none)
+//   - CXMLPathAttr: Attribute holding the path with the custom schema ("" for a <source> element)
+//   - CXMLContentElement: Element holding the content with the custom schema ("" for the document's own text)
+//   - CXMLCData: Wrap Claude XML content in CDATA sections instead of escaping it
 //   - LineNumbers: Include line numbers in output
+//   - LineNumbersCompact: Include line numbers using minimal "1:" gutters
+//   - HeaderStats: Add the number of lines and bytes each document shows to its header
+//   - IncludeMetadata: Add each file's size, line count, modification time and mode to its document
+//   - SquashDataBlocks: Replace runs of more than this many consecutive data lines (digits, hex, base64) with a marker (0 disables)
+//   - HeadLines: Show only this many lines from the start of each file (0 disables)
+//   - TailLines: Show only this many lines from the end of each file (0 disables)
+//   - HunksOnly: Show only the hunks git diff reports against HEAD for each file, skipping unchanged files
+//   - GitDiff: Emit only the files git diff reports changed against this ref, beneath the input paths
+//   - GitStaged: Emit only the files staged in the index, beneath the input paths
+//   - HunkContext: Lines of context around each --hunks-only hunk
+//   - CollapseSiblings: Emit only one of the generated files sharing a directory and a stem, and stubs for the others
+//   - ExpandSymlinkDuplicates: Emit a file reached again through a symlink in full rather than as a stub pointing to its first document
+//   - SiblingPriority: Extensions in the order CollapseSiblings prefers the sibling to keep
 //   - Markdown: Format output as Markdown with code blocks
-//   - Null: Use null character separators for stdin input
+//   - MarkdownStyle: How Markdown output labels each document: headings (the default) or path
+//   - MarkdownHeadingLevel: Level of the heading of each Markdown document, 1 to 6 (0 for 2)
+//   - HTML: Format output as a self-contained HTML page for people to review
+//   - JSONL: Format output as one JSON object per document, each on its own line
+//   - OpenAI: Format output as a JSON array of chat messages for OpenAI-compatible APIs
+//   - OpenAISingleMessage: Put every document of OpenAI output in one user message rather than one each
+//   - OpenAISystemTemplate: Template file for the system message opening OpenAI output
+//   - TemplatePath: Go text/template file each document is rendered with, instead of a built-in format
+//   - HeaderTemplate: Template file rendered once before the documents of template output
+//   - FooterTemplate: Template file rendered once after the documents of template output
+//   - Format: Describe the files rather than show them: "digest" (a tab-separated line each) or "digest-json"
+//   - DigestPreview: Characters of content each digest line previews (0 for 200)
+//   - Prefix, PrefixFile: Free-form text, or a file of it, written before the first document
+//   - Suffix, SuffixFile: Free-form text, or a file of it, written after the last document
+//   - PrefixInsideWrapper: Write the prefix and suffix within the Claude XML root element rather than around it
+//   - DetectLang: Guess the language of files the extension map does not cover from their name and content
+//   - LanguageOverrides: Fence languages by file extension or name, overriding the built-in map
+//   - Compat: Reproduce the output of another tool; only "files-to-prompt" is supported
+//   - Tree: Write a directory tree of the emitted files before their contents
+//   - ListOnly: Print the paths of the files that would be emitted instead of their contents
+//   - EmbedWarnings: End the output with a section telling the model which content was left out
+//   - Sort: Order of the emitted files: "path" (the default), "size", "mtime" or "none" for the order they were found
+//   - Null: Use null character separators for stdin input and --list output
+//   - CountTokens: Print the number of files, bytes and estimated tokens written to stderr
+//   - Stats: Print a table of the bytes, lines and estimated tokens of each emitted file, with totals and skip counts, to stderr
+//   - StatsFormat: Format of the --stats report: "table" (the default) or "json"
+//   - BudgetScope: What byte and token figures count: "rendered" output (the default) or only file "content"
+//   - FitTokens: Emit files in order only while their estimated tokens fit in this budget (0 for no budget)
+//   - SmallFirst: Order files by estimated tokens, smallest first, so that a budget covers as many as possible
+//   - MaxTokens: Stop adding files once the output would exceed this many estimated tokens (0 for no limit)
+//   - Strict: Fail instead of omitting files when the output would exceed --max-tokens
+//   - FailOnEmpty: Fail when a path argument produces no documents
+//   - AllowEmptyGlob: Skip glob path arguments that match nothing instead of failing
+//   - LongRunFiles: Files scanned before the long-run notice is shown (0 means 100000)
+//   - LongRunBytes: Bytes emitted before the long-run notice is shown (0 means 50 MiB)
+//   - LongRunAfter: Elapsed time before the long-run notice is shown (0 means 1m)
+//   - Progress: Report on stderr how many of the files there are have been walked and emitted
+//   - NoPrecount: Skip the pre-count that gives --progress an estimate while walking
+//   - Yes: Crawl a home directory, filesystem root or huge directory outside version control without asking
+//   - GuardEntries: Immediate entries above which a directory outside version control needs confirmation (0 means 1000)
+//   - NoHistory: Disable recording of the run in the local history file
+//   - HistorySize: Maximum number of entries kept in the local history file
+//   - Reproducible: Produce byte-identical output across runs of the same tree, pinning embedded timestamps
+//   - StableView: Hash the selected files before emitting any and mark the documents of those that changed since
+//   - Anonymize: Replace directory and file names, in headers and in file contents, with stable pseudonyms such as dir_a/file_01.go
+//   - AnonymizeSeed: Seed deciding which name gets which --anonymize pseudonym (random when empty)
+//   - AnonymizeMap: File the --anonymize mapping from pseudonyms back to the original names is written to, as JSON
+//   - Redact: Replace credentials found in file contents, such as AWS access keys and private key blocks, with [REDACTED:<kind>]
+//   - RedactPatterns: Further regexps whose matches Redact replaces, only the first capture group when there is one
 //
 // Example:
 //
@@ -57,91 +192,395 @@ import (
 //		// ... other fields
 //	}
 type Config struct {
-	Paths           []string `env:"PATHS" envDefault:""`
-	Extensions      []string `env:"EXTENSIONS" envDefault:""`
-	IncludeHidden   bool     `env:"INCLUDE_HIDDEN" envDefault:"false"`
-	IgnoreGitignore bool     `env:"IGNORE_GITIGNORE" envDefault:"false"`
-	IgnorePatterns  []string `env:"IGNORE_PATTERNS" envDefault:""`
-	OutputFile      string   `env:"OUTPUT_FILE" envDefault:""`
-	ClaudeXML       bool     `env:"CLAUDE_XML" envDefault:"false"`
-	LineNumbers     bool     `env:"LINE_NUMBERS" envDefault:"false"`
-	Markdown        bool     `env:"MARKDOWN" envDefault:"false"`
-	Null            bool     `env:"NULL" envDefault:"false"`
+	Paths                   []string `env:"PATHS" envDefault:""`
+	Profile                 string   `env:"PROFILE" envDefault:""`
+	Preset                  []string `env:"PRESET" envDefault:""`
+	StdinPaths              []string
+	StdinContent            string
+	StdinName               string            `env:"STDIN_NAME" envDefault:""`
+	Jail                    string            `env:"JAIL" envDefault:""`
+	Extensions              []string          `env:"EXTENSIONS" envDefault:""`
+	ExcludeExtensions       []string          `env:"EXCLUDE_EXTENSIONS" envDefault:""`
+	IgnoreCase              bool              `env:"IGNORE_CASE" envDefault:"false"`
+	IncludeHidden           bool              `env:"INCLUDE_HIDDEN" envDefault:"false"`
+	IncludeVCSDirs          bool              `env:"INCLUDE_VCS_DIRS" envDefault:"false"`
+	IncludeJunk             bool              `env:"INCLUDE_JUNK" envDefault:"false"`
+	IncludeGenerated        bool              `env:"INCLUDE_GENERATED" envDefault:"false"`
+	IgnoreGitignore         bool              `env:"IGNORE_GITIGNORE" envDefault:"false"`
+	IncludeSensitive        bool              `env:"INCLUDE_SENSITIVE" envDefault:"false"`
+	IgnorePatterns          []string          `env:"IGNORE_PATTERNS" envDefault:""`
+	DisableDefaultIgnores   bool              `env:"DISABLE_DEFAULT_IGNORES" envDefault:"false"`
+	IncludePatterns         []string          `env:"INCLUDE_PATTERNS" envDefault:""`
+	AutoExtensions          bool              `env:"AUTO_EXTENSIONS" envDefault:"false"`
+	UseExportIgnore         bool              `env:"USE_EXPORT_IGNORE" envDefault:"false"`
+	GitTracked              bool              `env:"GIT_TRACKED" envDefault:"false"`
+	FollowSymlinks          bool              `env:"FOLLOW_SYMLINKS" envDefault:"false"`
+	MaxDepth                int               `env:"MAX_DEPTH" envDefault:"0"`
+	NoRecurse               bool              `env:"NO_RECURSE" envDefault:"false"`
+	Submodules              string            `env:"SUBMODULES" envDefault:""`
+	Grep                    string            `env:"GREP" envDefault:""`
+	GrepContext             int               `env:"GREP_CONTEXT" envDefault:"-1"`
+	ReadLimit               int64             `env:"READ_LIMIT" envDefault:"0"`
+	ZeroSizeLimit           int64             `env:"ZERO_SIZE_LIMIT" envDefault:"0"`
+	IncludeVirtualFS        bool              `env:"INCLUDE_VIRTUAL_FS" envDefault:"false"`
+	Concurrency             int               `env:"CONCURRENCY" envDefault:"0"`
+	Throttle                float64           `env:"THROTTLE" envDefault:"0"`
+	LowPriority             bool              `env:"LOW_PRIORITY" envDefault:"false"`
+	MaxFileSize             ByteSize          `env:"MAX_SIZE" envDefault:""`
+	MinFileSize             ByteSize          `env:"MIN_SIZE" envDefault:""`
+	NewerThan               string            `env:"NEWER_THAN" envDefault:""`
+	OlderThan               string            `env:"OLDER_THAN" envDefault:""`
+	OutputFile              string            `env:"OUTPUT_FILE" envDefault:""`
+	FlushEveryFile          bool              `env:"FLUSH_EVERY_FILE" envDefault:"false"`
+	OutputMethod            string            `env:"OUTPUT_METHOD" envDefault:""`
+	OutputAuthEnv           string            `env:"OUTPUT_AUTH_ENV" envDefault:""`
+	SplitTokens             int64             `env:"SPLIT_TOKENS" envDefault:"0"`
+	SplitBytes              ByteSize          `env:"SPLIT_BYTES" envDefault:""`
+	SplitIndexes            string            `env:"SPLIT_INDEXES" envDefault:"continue"`
+	Batch                   string            `env:"BATCH" envDefault:""`
+	MirrorTo                string            `env:"MIRROR_TO" envDefault:""`
+	MirrorOnly              bool              `env:"MIRROR_ONLY" envDefault:"false"`
+	Force                   bool              `env:"FORCE" envDefault:"false"`
+	Clipboard               bool              `env:"CLIPBOARD" envDefault:"false"`
+	CopyChunked             bool              `env:"COPY_CHUNKED" envDefault:"false"`
+	CopyChunkSize           ByteSize          `env:"COPY_CHUNK_SIZE" envDefault:""`
+	TerminalLimit           ByteSize          `env:"TERMINAL_LIMIT" envDefault:""`
+	OutputLimit             ByteSize          `env:"OUTPUT_LIMIT" envDefault:""`
+	Exec                    string            `env:"EXEC" envDefault:""`
+	Pipes                   []string          `env:"PIPE" envSeparator:"\n"`
+	PipeTimeout             time.Duration     `env:"PIPE_TIMEOUT" envDefault:"0"`
+	Commands                []string          `env:"CMD" envSeparator:"\n"`
+	CmdLabels               []string          `env:"CMD_LABEL" envSeparator:"\n"`
+	CmdTimeout              time.Duration     `env:"CMD_TIMEOUT" envDefault:"0"`
+	CmdMaxBytes             int64             `env:"CMD_MAX_BYTES" envDefault:"0"`
+	CmdStrict               bool              `env:"CMD_STRICT" envDefault:"false"`
+	EnvContext              bool              `env:"ENV_CONTEXT" envDefault:"false"`
+	EnvContextCmds          []string          `env:"ENV_CONTEXT_CMD" envSeparator:"\n"`
+	ClaudeXML               bool              `env:"CLAUDE_XML" envDefault:"false"`
+	CXMLMaxDocBytes         int64             `env:"CXML_MAX_DOC_BYTES" envDefault:"0"`
+	CXMLSchema              string            `env:"CXML_SCHEMA" envDefault:"anthropic"`
+	CXMLRoot                string            `env:"CXML_ROOT" envDefault:"documents"`
+	CXMLItem                string            `env:"CXML_ITEM" envDefault:"document"`
+	CXMLIndexAttr           string            `env:"CXML_INDEX_ATTR" envDefault:"index"`
+	CXMLPathAttr            string            `env:"CXML_PATH_ATTR" envDefault:""`
+	CXMLContentElement      string            `env:"CXML_CONTENT_ELEMENT" envDefault:"document_content"`
+	CXMLCData               bool              `env:"CXML_CDATA" envDefault:"false"`
+	LineNumbers             bool              `env:"LINE_NUMBERS" envDefault:"false"`
+	LineNumbersCompact      bool              `env:"LINE_NUMBERS_COMPACT" envDefault:"false"`
+	HeaderStats             bool              `env:"HEADER_STATS" envDefault:"false"`
+	IncludeMetadata         bool              `env:"METADATA" envDefault:"false"`
+	SquashDataBlocks        int               `env:"SQUASH_DATA_BLOCKS" envDefault:"0"`
+	HeadLines               int               `env:"HEAD_LINES" envDefault:"0"`
+	TailLines               int               `env:"TAIL_LINES" envDefault:"0"`
+	HunksOnly               bool              `env:"HUNKS_ONLY" envDefault:"false"`
+	GitDiff                 string            `env:"GIT_DIFF" envDefault:""`
+	GitStaged               bool              `env:"GIT_STAGED" envDefault:"false"`
+	HunkContext             int               `env:"HUNK_CONTEXT" envDefault:"3"`
+	CollapseSiblings        bool              `env:"COLLAPSE_GENERATED_SIBLINGS" envDefault:"false"`
+	ExpandSymlinkDuplicates bool              `env:"EXPAND_SYMLINK_DUPLICATES" envDefault:"false"`
+	SiblingPriority         []string          `env:"SIBLING_PRIORITY" envDefault:""`
+	Markdown                bool              `env:"MARKDOWN" envDefault:"false"`
+	MarkdownStyle           string            `env:"MARKDOWN_STYLE" envDefault:"headings"`
+	MarkdownHeadingLevel    int               `env:"MARKDOWN_HEADING_LEVEL" envDefault:"2"`
+	HTML                    bool              `env:"HTML" envDefault:"false"`
+	JSONL                   bool              `env:"JSONL" envDefault:"false"`
+	OpenAI                  bool              `env:"OPENAI" envDefault:"false"`
+	OpenAISingleMessage     bool              `env:"OPENAI_SINGLE_MESSAGE" envDefault:"false"`
+	OpenAISystemTemplate    string            `env:"OPENAI_SYSTEM_TEMPLATE" envDefault:""`
+	TemplatePath            string            `env:"TEMPLATE" envDefault:""`
+	HeaderTemplate          string            `env:"HEADER_TEMPLATE" envDefault:""`
+	FooterTemplate          string            `env:"FOOTER_TEMPLATE" envDefault:""`
+	Format                  string            `env:"FORMAT" envDefault:""`
+	DigestPreview           int               `env:"DIGEST_PREVIEW" envDefault:"0"`
+	Prefix                  string            `env:"PREFIX" envDefault:""`
+	PrefixFile              string            `env:"PREFIX_FILE" envDefault:""`
+	Suffix                  string            `env:"SUFFIX" envDefault:""`
+	SuffixFile              string            `env:"SUFFIX_FILE" envDefault:""`
+	PrefixInsideWrapper     bool              `env:"PREFIX_INSIDE_WRAPPER" envDefault:"false"`
+	DetectLang              bool              `env:"DETECT_LANG" envDefault:"false"`
+	LanguageOverrides       map[string]string `env:"LANGUAGE_OVERRIDES" envKeyValSeparator:"="`
+	Compat                  string            `env:"COMPAT" envDefault:""`
+	Tree                    bool              `env:"TREE" envDefault:"false"`
+	ListOnly                bool              `env:"LIST" envDefault:"false"`
+	EmbedWarnings           bool              `env:"EMBED_WARNINGS" envDefault:"false"`
+	Sort                    string            `env:"SORT" envDefault:""`
+	Null                    bool              `env:"NULL" envDefault:"false"`
+	CountTokens             bool              `env:"COUNT_TOKENS" envDefault:"false"`
+	Stats                   bool              `env:"STATS" envDefault:"false"`
+	StatsFormat             string            `env:"STATS_FORMAT" envDefault:""`
+	BudgetScope             string            `env:"BUDGET_SCOPE" envDefault:""`
+	FitTokens               int64             `env:"FIT_TOKENS" envDefault:"0"`
+	SmallFirst              bool              `env:"SMALL_FIRST" envDefault:"false"`
+	MaxTokens               int64             `env:"MAX_TOKENS" envDefault:"0"`
+	Strict                  bool              `env:"STRICT" envDefault:"false"`
+	FailOnEmpty             bool              `env:"FAIL_ON_EMPTY" envDefault:"false"`
+	AllowEmptyGlob          bool              `env:"ALLOW_EMPTY_GLOB" envDefault:"false"`
+	LongRunFiles            int64             `env:"LONG_RUN_FILES" envDefault:"0"`
+	LongRunBytes            int64             `env:"LONG_RUN_BYTES" envDefault:"0"`
+	LongRunAfter            time.Duration     `env:"LONG_RUN_AFTER" envDefault:"0"`
+	Progress                bool              `env:"PROGRESS" envDefault:"false"`
+	NoPrecount              bool              `env:"NO_PRECOUNT" envDefault:"false"`
+	Yes                     bool              `env:"YES" envDefault:"false"`
+	GuardEntries            int               `env:"GUARD_ENTRIES" envDefault:"0"`
+	NoHistory               bool              `env:"NO_HISTORY" envDefault:"false"`
+	HistorySize             int               `env:"HISTORY_SIZE" envDefault:"100"`
+	Reproducible            bool              `env:"REPRODUCIBLE" envDefault:"false"`
+	StableView              bool              `env:"STABLE_VIEW" envDefault:"false"`
+	Anonymize               bool              `env:"ANONYMIZE" envDefault:"false"`
+	AnonymizeSeed           string            `env:"ANONYMIZE_SEED" envDefault:""`
+	AnonymizeMap            string            `env:"ANONYMIZE_MAP" envDefault:""`
+	Redact                  bool              `env:"REDACT" envDefault:"false"`
+	RedactPatterns          []string          `env:"REDACT_PATTERN" envSeparator:"\n"`
 }
 
-// GetEnvVars loads and returns the application configuration from environment
-// variables and .env files with comprehensive security validation.
-//
-// This function performs the following operations:
-//  1. Securely determines the current working directory
-//  2. Constructs and validates the .env file path to prevent traversal attacks
-//  3. Loads .env file if it exists in the current directory
-//  4. Parses environment variables into the Config struct
-//  5. Returns the populated configuration
-//
-// Security measures implemented:
-//   - Path traversal detection and prevention using filepath.Rel
-//   - Absolute path resolution for secure path operations
-//   - Validation against ".." sequences in relative paths
-//   - Safe file existence checking before loading
-//
-// The function will terminate the program with os.Exit(1) if any critical
-// errors occur during configuration loading, such as:
-//   - Current directory access failures
-//   - Path traversal attempts detected
-//   - .env file parsing errors
-//   - Environment variable parsing failures
-//
-// Returns:
-//   - Config: A populated configuration struct with values from environment
-//     variables and/or .env file
+// ByteSize is a size in bytes that can be written in human-friendly form, such
+// as "500k", "2m" or "1.5GiB". Units are binary, so "1k" is 1024 bytes. It
+// implements encoding.TextUnmarshaler for environment parsing and the pflag.Value
+// interface for command-line flags.
+type ByteSize int64
+
+// byteSizeUnits maps the accepted unit prefixes to their multipliers.
+var byteSizeUnits = map[string]float64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// ParseByteSize parses s as a ByteSize. A bare number is a count of bytes; it may
+// be followed by k, m, g or t, optionally with a trailing "b" or "ib", in any case.
+func ParseByteSize(s string) (ByteSize, error) {
+	trimmed := strings.ToLower(strings.TrimSpace(s))
+	if trimmed == "" {
+		return 0, nil
+	}
+	end := len(trimmed)
+	for end > 0 && strings.IndexByte("0123456789.", trimmed[end-1]) < 0 {
+		end--
+	}
+	number, unit := trimmed[:end], strings.TrimSpace(trimmed[end:])
+	switch {
+	case unit == "b":
+		unit = ""
+	case len(unit) == 3 && strings.HasSuffix(unit, "ib"), len(unit) == 2 && strings.HasSuffix(unit, "b"):
+		unit = unit[:1]
+	}
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes or a suffix such as 500k or 2m", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q: use a number of bytes or a suffix such as 500k or 2m", s)
+	}
+	return ByteSize(value * multiplier), nil
+}
+
+// UnmarshalText parses text with ParseByteSize.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// String returns the size as a number of bytes.
+func (b *ByteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+// Set parses s with ParseByteSize, for use as a pflag.Value.
+func (b *ByteSize) Set(s string) error {
+	return b.UnmarshalText([]byte(s))
+}
+
+// Type names the flag value type in help output.
+func (b *ByteSize) Type() string {
+	return "size"
+}
+
+// GetEnvVars loads the configuration for the current working directory with
+// Load, terminating the program with os.Exit(1) when that fails.
 //
 // Example:
 //
 //	// Load configuration
 //	conf := config.GetEnvVars()
 func GetEnvVars() Config {
-	// Get current working directory for secure file operations
 	cwd, err := os.Getwd()
 	if err != nil {
 		fmt.Printf("Error getting current working directory: %s\n", err)
 		os.Exit(1)
 	}
+	conf, err := Load(cwd)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %s\n", err)
+		os.Exit(1)
+	}
+	return conf
+}
+
+// Defaults returns the built-in configuration, before any config file,
+// environment variable or flag applies.
+func Defaults() (Config, error) {
+	var conf Config
+	if err := env.ParseWithOptions(&conf, env.Options{Environment: map[string]string{}}); err != nil {
+		return Config{}, fmt.Errorf("error parsing configuration: %v", err)
+	}
+	return conf, nil
+}
 
-	// Construct secure path for .env file within current directory
-	envPath := filepath.Join(cwd, ".env")
+// Load returns the application configuration for a run in the directory dir
+// with LoadProfile, selecting the profile named by PROFILE, if any.
+func Load(dir string) (Config, error) {
+	return LoadProfile(dir, "")
+}
+
+// LoadProfile returns the application configuration for a run in the directory
+// dir with LoadPresets, selecting the presets named by PRESET, if any.
+func LoadProfile(dir, profile string) (Config, error) {
+	return LoadPresets(dir, profile, nil)
+}
+
+// LoadPresets returns the application configuration for a run in the directory
+// dir, merged from config files, a .env file and environment variables, with
+// the settings of the config file profile called profile and of the presets
+// applied. When profile is "", the profile named by PROFILE in the environment
+// or a config file is used, if any. When presets is empty, those named by
+// PRESET in the environment, the profile or a config file are used, if any.
+//
+// This function performs the following operations:
+//  1. Reads the user config file at UserConfigPath, if it exists
+//  2. Reads the nearest ProjectConfigName file in dir or its parents, if any
+//  3. Constructs and validates the .env file path to prevent traversal attacks
+//  4. Loads the .env file if it exists in dir, except for the settings that
+//     run commands or choose where the output goes
+//  5. Parses the settings into the Config struct, each source overriding the
+//     ones before it: environment variables (including those from .env) over
+//     the selected profile over the selected presets, in order, over the
+//     project config over the user config over the defaults
+//
+// A profile may be defined in both config files, the project's settings for it
+// overriding the user's. A preset is looked up as Presets finds it.
+//
+// Security measures implemented:
+//   - Path traversal detection and prevention using filepath.Rel
+//   - Absolute path resolution for secure path operations
+//   - Validation against ".." sequences in relative paths
+//   - Safe file existence checking before loading
+//
+// An error is returned when a config file cannot be read or holds invalid
+// YAML or unknown settings, when the project config file sets one of those
+// that run commands or choose where the output goes, when the profile or a preset is not defined, or a
+// preset not at its pinned version, when the .env
+// file cannot be parsed, or when a setting has an invalid value. Flags are
+// applied on top by the caller.
+func LoadPresets(dir, profile string, presets []string) (Config, error) {
+	files := map[string]string{}
+	profiles := map[string]map[string]string{}
+	available := readBuiltinPresets()
+	for i, path := range []string{UserConfigPath(), ProjectConfigPath(dir)} {
+		if path == "" {
+			continue
+		}
+		file, err := readConfigFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		if i == 1 {
+			if err := file.checkProject(path); err != nil {
+				return Config{}, err
+			}
+		}
+		maps.Copy(files, file.settings)
+		for name, settings := range file.profiles {
+			if profiles[name] == nil {
+				profiles[name] = map[string]string{}
+			}
+			maps.Copy(profiles[name], settings)
+		}
+		maps.Copy(available, file.presets)
+	}
+
+	// Construct secure path for .env file within the directory
+	envPath := filepath.Join(dir, ".env")
 
 	// Ensure the path is within our expected directory (prevent traversal)
 	cleanEnvPath, err := filepath.Abs(envPath)
 	if err != nil {
-		fmt.Printf("Error resolving .env file path: %s\n", err)
-		os.Exit(1)
+		return Config{}, fmt.Errorf("error resolving .env file path: %v", err)
 	}
-	cleanCwd, err := filepath.Abs(cwd)
+	cleanDir, err := filepath.Abs(dir)
 	if err != nil {
-		fmt.Printf("Error resolving current directory: %s\n", err)
-		os.Exit(1)
+		return Config{}, fmt.Errorf("error resolving directory: %v", err)
 	}
-	relPath, err := filepath.Rel(cleanCwd, cleanEnvPath)
+	relPath, err := filepath.Rel(cleanDir, cleanEnvPath)
 	if err != nil || strings.Contains(relPath, "..") {
-		fmt.Printf("Error: .env file path traversal detected\n")
-		os.Exit(1)
+		return Config{}, fmt.Errorf(".env file path traversal detected")
 	}
 
-	// Load .env file if it exists
+	// Load .env file if it exists; it does not override the environment, and
+	// the hostSettings in it are left to whatever else it configures
 	if _, err := os.Stat(envPath); err == nil {
-		if err := godotenv.Load(envPath); err != nil {
-			fmt.Printf("Error loading .env file: %s\n", err)
-			os.Exit(1)
+		dotenv, err := godotenv.Read(envPath)
+		if err != nil {
+			return Config{}, fmt.Errorf("error loading .env file: %v", err)
+		}
+		for name, value := range dotenv {
+			if _, host := hostSettings[name]; host {
+				continue
+			}
+			if _, set := os.LookupEnv(name); !set {
+				_ = os.Setenv(name, value)
+			}
+		}
+	}
+	// An empty variable counts as unset, as it does without config files
+	environment := map[string]string{}
+	for _, kv := range os.Environ() {
+		if name, value, _ := strings.Cut(kv, "="); value != "" {
+			environment[name] = value
 		}
 	}
 
-	// Parse environment variables into config struct
+	// The selected presets apply over the config files, and the selected
+	// profile over them, both under the environment
+	profile = cmp.Or(profile, environment["PROFILE"], files["PROFILE"])
+	var profileSettings map[string]string
+	if profile != "" {
+		settings, ok := profiles[profile]
+		if !ok {
+			return Config{}, unknownProfile(profile, profiles)
+		}
+		profileSettings = settings
+	}
+	selection := cmp.Or(strings.Join(presets, ","), environment["PRESET"], profileSettings["PRESET"], files["PRESET"])
+	presetSettings, err := resolvePresets(selection, available)
+	if err != nil {
+		return Config{}, err
+	}
+	maps.Copy(files, presetSettings)
+	maps.Copy(files, profileSettings)
+	maps.Copy(files, environment)
+	files["PROFILE"] = profile
+	files["PRESET"] = selection
+
+	// Parse the merged settings into config struct
 	var conf Config
-	if err := env.Parse(&conf); err != nil {
-		fmt.Printf("Error parsing environment variables: %s\n", err)
-		os.Exit(1)
+	if err := env.ParseWithOptions(&conf, env.Options{Environment: files}); err != nil {
+		return Config{}, fmt.Errorf("error parsing configuration: %v", err)
 	}
+	return conf, nil
+}
 
-	return conf
+// unknownProfile returns the error for selecting profile when only the
+// profiles of byName are defined.
+func unknownProfile(profile string, byName map[string]map[string]string) error {
+	if len(byName) == 0 {
+		return fmt.Errorf("unknown profile %q: no profiles are defined in %s or %s", profile, UserConfigPath(), ProjectConfigName)
+	}
+	return fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(slices.Sorted(maps.Keys(byName)), ", "))
 }
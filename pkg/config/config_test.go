@@ -70,14 +70,67 @@ LINE_NUMBERS=false`
 	assert.True(t, conf.IncludeHidden)
 	assert.False(t, conf.IgnoreGitignore)
 	assert.Equal(t, []string{"node_modules", "vendor"}, conf.IgnorePatterns)
-	assert.Equal(t, "output.md", conf.OutputFile)
+	// A .env file cannot choose where the output goes
+	assert.Empty(t, conf.OutputFile)
 	assert.True(t, conf.ClaudeXML)
 	assert.False(t, conf.LineNumbers)
 }
 
+func TestGetEnvVarsLanguageOverrides(t *testing.T) {
+	assert.Empty(t, GetEnvVars().LanguageOverrides)
+
+	t.Setenv("LANGUAGE_OVERRIDES", "tf=hcl,Justfile=make")
+	assert.Equal(t, map[string]string{"tf": "hcl", "Justfile": "make"}, GetEnvVars().LanguageOverrides)
+}
+
 func TestGetEnvVarsParseError(t *testing.T) {
 	// This test is tricky as env.Parse doesn't easily error on valid struct, but for coverage
 	// Set invalid env that might cause issues, but since tags are proper, it should not error
 	// Skip detailed error test as it's not critical for this config
 	t.Skip("env.Parse errors not easily triggered for this struct")
 }
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ByteSize
+	}{
+		{"", 0},
+		{"0", 0},
+		{"512", 512},
+		{"512b", 512},
+		{"500k", 500 << 10},
+		{"500K", 500 << 10},
+		{"2m", 2 << 20},
+		{"2MB", 2 << 20},
+		{"2MiB", 2 << 20},
+		{"1.5g", 3 << 29},
+		{" 1t ", 1 << 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseByteSize(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+
+	for _, input := range []string{"k", "ib", "2x", "-1k", "1.2.3m", "2 kbytes"} {
+		t.Run("invalid/"+input, func(t *testing.T) {
+			_, err := ParseByteSize(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetEnvVarsByteSize(t *testing.T) {
+	t.Setenv("MAX_SIZE", "500k")
+	conf := GetEnvVars()
+	assert.Equal(t, ByteSize(500<<10), conf.MaxFileSize)
+	assert.Equal(t, ByteSize(0), conf.MinFileSize)
+
+	var flagValue ByteSize
+	assert.NoError(t, flagValue.Set("2m"))
+	assert.Equal(t, "2097152", flagValue.String())
+	assert.Equal(t, "size", flagValue.Type())
+}
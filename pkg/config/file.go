@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigName is the name of the per-project config file, looked for in
+// the working directory and each of its parents.
+const ProjectConfigName = ".files2prompt.yaml"
+
+// hostSettings are the settings only the user picks, in the user config file,
+// the environment or flags, each with what it does that calls for that: they
+// run commands, choose where the output goes, read files from anywhere on the
+// host, turn off the protections against leaking them, or answer prompts. A
+// project config file or .env comes with the repository and was written by
+// whoever wrote that, so it cannot set them.
+var hostSettings = map[string]string{
+	"EXEC":                   hostRuns,
+	"PIPE":                   hostRuns,
+	"CMD":                    hostRuns,
+	"ENV_CONTEXT_CMD":        hostRuns,
+	"BATCH":                  hostRuns,
+	"OUTPUT_FILE":            hostOutput,
+	"OUTPUT_METHOD":          hostOutput,
+	"OUTPUT_AUTH_ENV":        hostOutput,
+	"MIRROR_TO":              hostOutput,
+	"FORCE":                  hostOutput,
+	"CLIPBOARD":              hostOutput,
+	"ANONYMIZE_MAP":          hostOutput,
+	"PREFIX_FILE":            hostReads,
+	"SUFFIX_FILE":            hostReads,
+	"TEMPLATE":               hostReads,
+	"HEADER_TEMPLATE":        hostReads,
+	"FOOTER_TEMPLATE":        hostReads,
+	"OPENAI_SYSTEM_TEMPLATE": hostReads,
+	"INCLUDE_SENSITIVE":      hostProtects,
+	"JAIL":                   hostProtects,
+	"REDACT":                 hostProtects,
+	"YES":                    "it answers prompts",
+}
+
+const (
+	hostRuns     = "it runs commands"
+	hostOutput   = "it chooses where the output goes"
+	hostReads    = "it reads a file from anywhere on the host"
+	hostProtects = "it decides what may be read or must be redacted"
+)
+
+// UserConfigPath returns the path of the user config file: config.yaml in the
+// files2prompt directory of $XDG_CONFIG_HOME, or of ~/.config when that is not
+// set. It returns "" when neither can be determined.
+func UserConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "files2prompt", "config.yaml")
+}
+
+// ProjectConfigPath returns the path of the nearest ProjectConfigName file in
+// dir or one of its parents, or "" when there is none.
+func ProjectConfigPath(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configFile is what a config file sets, as the environment variables its
+// settings stand for.
+type configFile struct {
+	settings map[string]string
+	// profiles holds the settings of each named profile
+	profiles map[string]map[string]string
+	// presets holds the presets the file defines by name
+	presets map[string]Preset
+}
+
+// readConfigFile reads the YAML config file at path. A key is the name of an
+// environment variable, in any case and with "-" or "_" between words, so
+// "extensions" and "ignore-patterns" set EXTENSIONS and IGNORE_PATTERNS. Lists
+// are joined with commas and maps written as key=value pairs, as those
+// variables take them. The "profiles" key maps profile names to settings of
+// their own, and the "presets" key preset names to a version, a description
+// and settings. A missing file sets nothing.
+func readConfigFile(path string) (configFile, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if os.IsNotExist(err) {
+		return configFile{}, nil
+	}
+	if err != nil {
+		return configFile{}, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return configFile{}, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	var file configFile
+	if profiles, ok := raw["profiles"]; ok {
+		delete(raw, "profiles")
+		byName, ok := profiles.(map[string]any)
+		if !ok {
+			return configFile{}, fmt.Errorf("invalid config file %s: profiles must map profile names to settings", path)
+		}
+		file.profiles = make(map[string]map[string]string, len(byName))
+		for name, p := range byName {
+			settings, ok := p.(map[string]any)
+			if !ok && p != nil {
+				return configFile{}, fmt.Errorf("invalid config file %s: profile %q must map settings to values", path, name)
+			}
+			if file.profiles[name], err = environmentOf(settings); err != nil {
+				return configFile{}, fmt.Errorf("invalid config file %s: profile %q: %v", path, name, err)
+			}
+			if _, ok := file.profiles[name]["PROFILE"]; ok {
+				return configFile{}, fmt.Errorf("invalid config file %s: profile %q cannot select a profile", path, name)
+			}
+		}
+	}
+	if presets, ok := raw["presets"]; ok {
+		delete(raw, "presets")
+		byName, ok := presets.(map[string]any)
+		if !ok {
+			return configFile{}, fmt.Errorf("invalid config file %s: presets must map preset names to presets", path)
+		}
+		file.presets = make(map[string]Preset, len(byName))
+		for name, p := range byName {
+			if file.presets[name], err = parsePreset(name, path, p); err != nil {
+				return configFile{}, fmt.Errorf("invalid config file %s: %v", path, err)
+			}
+		}
+	}
+	if file.settings, err = environmentOf(raw); err != nil {
+		return configFile{}, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// checkProject returns an error naming the file at path when file, a project
+// config file, sets one of the hostSettings, for itself or in a profile or
+// preset.
+func (file configFile) checkProject(path string) error {
+	check := func(settings map[string]string, where string) error {
+		for _, name := range slices.Sorted(maps.Keys(settings)) {
+			if reason, ok := hostSettings[name]; ok {
+				return fmt.Errorf("invalid config file %s: %s%s cannot be set in a project config file, as %s; pass it as a flag or set it in %s",
+					path, where, strings.ToLower(name), reason, UserConfigPath())
+			}
+		}
+		return nil
+	}
+	if err := check(file.settings, ""); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(file.profiles)) {
+		if err := check(file.profiles[name], fmt.Sprintf("profile %q: ", name)); err != nil {
+			return err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(file.presets)) {
+		if err := check(file.presets[name].Settings, fmt.Sprintf("preset %q: ", name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// environmentOf returns settings as the environment variables they stand for.
+func environmentOf(settings map[string]any) (map[string]string, error) {
+	known := settingNames()
+	environment := make(map[string]string, len(settings))
+	for key, value := range settings {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		switch v := value.(type) {
+		case nil:
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			environment[name] = strings.Join(items, ",")
+		case map[string]any:
+			pairs := make([]string, 0, len(v))
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				pairs = append(pairs, k+"="+fmt.Sprint(v[k]))
+			}
+			environment[name] = strings.Join(pairs, ",")
+		default:
+			environment[name] = fmt.Sprint(v)
+		}
+	}
+	return environment, nil
+}
+
+// settingNames returns the environment variables Config reads.
+func settingNames() map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeFor[Config]()
+	for i := range t.NumField() {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("env"), ","); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
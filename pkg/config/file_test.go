@@ -0,0 +1,260 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes content to path, creating its directory.
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+// clearEnv empties the environment variables the tests set through config
+// files, such as those left behind by .env files loaded by other tests.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"EXTENSIONS", "MARKDOWN", "MAX_SIZE", "IGNORE_PATTERNS", "LANGUAGE_OVERRIDES", "PROFILE", "PRESET"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeConfig(t, filepath.Join(home, "files2prompt", "config.yaml"),
+		"extensions: [go, md]\nmarkdown: true\nmax_size: 2m\nignore-patterns:\n  - vendor\n")
+
+	project := t.TempDir()
+	dir := filepath.Join(project, "internal", "pkg")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	// The user config alone
+	conf, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "md"}, conf.Extensions)
+	assert.True(t, conf.Markdown)
+	assert.Equal(t, ByteSize(2<<20), conf.MaxFileSize)
+	assert.Equal(t, []string{"vendor"}, conf.IgnorePatterns)
+
+	// The nearest project config overrides it, setting by setting
+	writeConfig(t, filepath.Join(project, ProjectConfigName),
+		"EXTENSIONS: [py]\nmax-size: 500k\nlanguage_overrides:\n  tf: hcl\n  Justfile: make\n")
+	conf, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"py"}, conf.Extensions)
+	assert.True(t, conf.Markdown)
+	assert.Equal(t, ByteSize(500<<10), conf.MaxFileSize)
+	assert.Equal(t, map[string]string{"tf": "hcl", "Justfile": "make"}, conf.LanguageOverrides)
+
+	// The environment overrides both
+	t.Setenv("EXTENSIONS", ".rs")
+	t.Setenv("MARKDOWN", "false")
+	conf, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".rs"}, conf.Extensions)
+	assert.False(t, conf.Markdown)
+	assert.Equal(t, ByteSize(500<<10), conf.MaxFileSize)
+}
+
+func TestLoadInvalidConfigFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "malformed", content: "extensions: [go\n", err: "invalid config file %s: yaml: line 1: did not find expected ',' or ']'"},
+		{name: "not a mapping", content: "- go\n", err: "invalid config file %s: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!seq into map[string]interface {}"},
+		{name: "unknown setting", content: "extension: [go]\n", err: `invalid config file %s: unknown setting "extension"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, ProjectConfigName)
+			writeConfig(t, path, tt.content)
+			_, err := Load(dir)
+			assert.EqualError(t, err, fmt.Sprintf(tt.err, path))
+		})
+	}
+
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, ProjectConfigName), "max_size: 2x\n")
+	_, err := Load(dir)
+	assert.ErrorContains(t, err, `invalid size "2x"`)
+}
+
+func TestLoadProjectHostSettings(t *testing.T) {
+	clearEnv(t)
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	userPath := filepath.Join(home, "files2prompt", "config.yaml")
+	for _, tt := range []struct{ content, setting, reason string }{
+		{"exec: touch /tmp/pwned\n", "exec", "it runs commands"},
+		{"cmd: [make]\n", "cmd", "it runs commands"},
+		{"env-context-cmd: [id]\n", "env_context_cmd", "it runs commands"},
+		{"output_file: ../out.md\n", "output_file", "it chooses where the output goes"},
+		{"profiles:\n  ci:\n    pipe: [sh]\n", `profile "ci": pipe`, "it runs commands"},
+		{"presets:\n  p:\n    settings:\n      mirror-to: /\n", `preset "p": mirror_to`, "it chooses where the output goes"},
+		{"prefix-file: ~/.aws/credentials\n", "prefix_file", "it reads a file from anywhere on the host"},
+		{"suffix-file: /etc/passwd\n", "suffix_file", "it reads a file from anywhere on the host"},
+		{"template: ~/.ssh/id_rsa\n", "template", "it reads a file from anywhere on the host"},
+		{"header-template: ~/.netrc\n", "header_template", "it reads a file from anywhere on the host"},
+		{"footer-template: ~/.netrc\n", "footer_template", "it reads a file from anywhere on the host"},
+		{"openai-system-template: ~/.netrc\n", "openai_system_template", "it reads a file from anywhere on the host"},
+		{"include-sensitive: true\n", "include_sensitive", "it decides what may be read or must be redacted"},
+		{"jail: /\n", "jail", "it decides what may be read or must be redacted"},
+		{"profiles:\n  share:\n    redact: false\n", `profile "share": redact`, "it decides what may be read or must be redacted"},
+	} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, ProjectConfigName)
+		writeConfig(t, path, tt.content)
+		_, err := Load(dir)
+		assert.EqualError(t, err, fmt.Sprintf("invalid config file %s: %s cannot be set in a project config file, as %s; pass it as a flag or set it in %s",
+			path, tt.setting, tt.reason, userPath), tt.content)
+	}
+
+	// The user config file may set them
+	writeConfig(t, userPath, "exec: cat\noutput-file: out.md\n")
+	conf, err := Load(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, "cat", conf.Exec)
+	assert.Equal(t, "out.md", conf.OutputFile)
+
+	// A .env file's are ignored, and its other settings applied
+	for _, name := range []string{"EXEC", "CMD", "PREFIX_FILE", "INCLUDE_SENSITIVE", "LINE_NUMBERS"} {
+		t.Setenv(name, "")
+		require.NoError(t, os.Unsetenv(name))
+	}
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, ".env"), "EXEC=touch pwned\nCMD=id\nPREFIX_FILE=/etc/passwd\nINCLUDE_SENSITIVE=true\nLINE_NUMBERS=true\n")
+	require.NoError(t, os.Remove(userPath))
+	conf, err = Load(dir)
+	require.NoError(t, err)
+	assert.Empty(t, conf.Exec)
+	assert.Empty(t, conf.Commands)
+	assert.Empty(t, conf.PrefixFile)
+	assert.False(t, conf.IncludeSensitive)
+	assert.True(t, conf.LineNumbers)
+	_, set := os.LookupEnv("EXEC")
+	assert.False(t, set)
+}
+
+func TestLoadProfile(t *testing.T) {
+	clearEnv(t)
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeConfig(t, filepath.Join(home, "files2prompt", "config.yaml"), `
+markdown: true
+extensions: [txt]
+profiles:
+  backend:
+    extensions: [go, mod]
+    ignore-patterns: [vendor/]
+  frontend:
+    extensions: [ts, tsx, css]
+    ignore-patterns: [node_modules/, dist/]
+`)
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, ProjectConfigName), "profiles:\n  frontend:\n    markdown: false\n  docs:\n")
+
+	conf, err := LoadProfile(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"txt"}, conf.Extensions)
+	assert.Empty(t, conf.Profile)
+
+	conf, err = LoadProfile(dir, "backend")
+	require.NoError(t, err)
+	assert.Equal(t, "backend", conf.Profile)
+	assert.Equal(t, []string{"go", "mod"}, conf.Extensions)
+	assert.Equal(t, []string{"vendor/"}, conf.IgnorePatterns)
+	assert.True(t, conf.Markdown)
+
+	// The project's settings for a profile apply over the user's
+	conf, err = LoadProfile(dir, "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ts", "tsx", "css"}, conf.Extensions)
+	assert.Equal(t, []string{"node_modules/", "dist/"}, conf.IgnorePatterns)
+	assert.False(t, conf.Markdown)
+
+	// PROFILE selects a profile when none is given, and the environment still overrides it
+	t.Setenv("PROFILE", "backend")
+	t.Setenv("IGNORE_PATTERNS", "build/")
+	conf, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "mod"}, conf.Extensions)
+	assert.Equal(t, []string{"build/"}, conf.IgnorePatterns)
+	conf, err = LoadProfile(dir, "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ts", "tsx", "css"}, conf.Extensions)
+
+	// Flags bound with the loaded values as defaults override the profile
+	flags := pflag.NewFlagSet("files2prompt", pflag.ContinueOnError)
+	flags.StringSliceVarP(&conf.Extensions, "extension", "e", conf.Extensions, "")
+	flags.BoolVarP(&conf.Markdown, "markdown", "m", conf.Markdown, "")
+	require.NoError(t, flags.Parse([]string{"-e", "svelte"}))
+	assert.Equal(t, []string{"svelte"}, conf.Extensions)
+	assert.False(t, conf.Markdown)
+
+	_, err = LoadProfile(dir, "mobile")
+	assert.EqualError(t, err, `unknown profile "mobile" (available: backend, docs, frontend)`)
+}
+
+func TestLoadProfileErrors(t *testing.T) {
+	clearEnv(t)
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	_, err := LoadProfile(t.TempDir(), "backend")
+	assert.EqualError(t, err, `unknown profile "backend": no profiles are defined in `+
+		filepath.Join(home, "files2prompt", "config.yaml")+" or "+ProjectConfigName)
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "profiles not a mapping", content: "profiles: [backend]\n", err: "profiles must map profile names to settings"},
+		{name: "profile not a mapping", content: "profiles:\n  backend: [go]\n", err: `profile "backend" must map settings to values`},
+		{name: "unknown setting", content: "profiles:\n  backend:\n    extension: [go]\n", err: `profile "backend": unknown setting "extension"`},
+		{name: "nested profile", content: "profiles:\n  backend:\n    profile: frontend\n", err: `profile "backend" cannot select a profile`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, ProjectConfigName)
+			writeConfig(t, path, tt.content)
+			_, err := Load(dir)
+			assert.EqualError(t, err, "invalid config file "+path+": "+tt.err)
+		})
+	}
+}
+
+func TestProjectConfigPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.Empty(t, ProjectConfigPath(dir))
+
+	writeConfig(t, filepath.Join(root, ProjectConfigName), "")
+	assert.Equal(t, filepath.Join(root, ProjectConfigName), ProjectConfigPath(dir))
+	writeConfig(t, filepath.Join(root, "a", ProjectConfigName), "")
+	assert.Equal(t, filepath.Join(root, "a", ProjectConfigName), ProjectConfigPath(dir))
+}
+
+func TestUserConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	assert.Equal(t, filepath.Join("/xdg", "files2prompt", "config.yaml"), UserConfigPath())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/someone")
+	assert.Equal(t, filepath.Join("/home/someone", ".config", "files2prompt", "config.yaml"), UserConfigPath())
+}
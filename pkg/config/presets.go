@@ -0,0 +1,155 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuiltinPresetSource is the Source of the presets shipped in the binary.
+const BuiltinPresetSource = "built-in"
+
+// builtinPresets holds the presets shipped in the binary, one YAML file each,
+// named after the preset.
+//
+//go:embed presets/*.yaml
+var builtinPresets embed.FS
+
+// Preset is a named bundle of settings, such as ignore patterns, extensions
+// and size limits, that --preset applies.
+type Preset struct {
+	Name string
+	// Version is raised whenever the settings change, so that a name pinned
+	// as name@version fails rather than silently selecting other files. It is
+	// 0 for a preset that is not versioned.
+	Version     int
+	Description string
+	// Source is BuiltinPresetSource or the path of the config file defining
+	// the preset
+	Source string
+	// Settings are the environment variables the settings stand for, as a
+	// config file's are
+	Settings map[string]string
+}
+
+// parsePreset returns the preset called name that source defines as raw: a
+// mapping of an optional version and description, and the settings.
+func parsePreset(name, source string, raw any) (Preset, error) {
+	fields, ok := raw.(map[string]any)
+	if !ok && raw != nil {
+		return Preset{}, fmt.Errorf("preset %q must map version, description and settings to values", name)
+	}
+	preset := Preset{Name: name, Source: source}
+	for key, value := range fields {
+		switch key {
+		case "version":
+			version, ok := value.(int)
+			if !ok || version < 1 {
+				return Preset{}, fmt.Errorf("preset %q: version must be a positive integer", name)
+			}
+			preset.Version = version
+		case "description":
+			preset.Description = fmt.Sprint(value)
+		case "settings":
+			settings, ok := value.(map[string]any)
+			if !ok && value != nil {
+				return Preset{}, fmt.Errorf("preset %q: settings must map settings to values", name)
+			}
+			var err error
+			if preset.Settings, err = environmentOf(settings); err != nil {
+				return Preset{}, fmt.Errorf("preset %q: %v", name, err)
+			}
+		default:
+			return Preset{}, fmt.Errorf("preset %q: unknown key %q (use version, description and settings)", name, key)
+		}
+	}
+	for _, selector := range []string{"PRESET", "PROFILE"} {
+		if _, ok := preset.Settings[selector]; ok {
+			return Preset{}, fmt.Errorf("preset %q cannot select a profile or presets", name)
+		}
+	}
+	return preset, nil
+}
+
+// readBuiltinPresets returns the presets shipped in the binary by name.
+func readBuiltinPresets() map[string]Preset {
+	entries, err := builtinPresets.ReadDir("presets")
+	if err != nil {
+		panic(err)
+	}
+	presets := make(map[string]Preset, len(entries))
+	for _, entry := range entries {
+		data, err := builtinPresets.ReadFile(path.Join("presets", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var raw any
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			panic(fmt.Sprintf("built-in preset %s: %v", entry.Name(), err))
+		}
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		preset, err := parsePreset(name, BuiltinPresetSource, raw)
+		if err != nil {
+			panic(fmt.Sprintf("built-in preset %s: %v", entry.Name(), err))
+		}
+		presets[name] = preset
+	}
+	return presets
+}
+
+// Presets returns the presets a run in the directory dir can select, sorted
+// by name: the built-in ones and those the config files define. A preset
+// defined in a config file replaces any of the same name, the project's
+// replacing the user's and the user's a built-in one.
+func Presets(dir string) ([]Preset, error) {
+	presets := readBuiltinPresets()
+	for _, path := range []string{UserConfigPath(), ProjectConfigPath(dir)} {
+		if path == "" {
+			continue
+		}
+		file, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(presets, file.presets)
+	}
+	return slices.SortedFunc(maps.Values(presets), func(a, b Preset) int { return strings.Compare(a.Name, b.Name) }), nil
+}
+
+// resolvePresets returns the settings of the presets selection names, as a
+// comma-separated list, applied in order, each overriding the settings of
+// the ones before it. A name may be pinned to a version as name@version.
+func resolvePresets(selection string, available map[string]Preset) (map[string]string, error) {
+	settings := map[string]string{}
+	for selector := range strings.SplitSeq(selection, ",") {
+		selector = strings.TrimSpace(selector)
+		if selector == "" {
+			continue
+		}
+		name, pin, pinned := strings.Cut(selector, "@")
+		preset, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(slices.Sorted(maps.Keys(available)), ", "))
+		}
+		if pinned {
+			version, err := strconv.Atoi(pin)
+			if err != nil || version < 1 {
+				return nil, fmt.Errorf("invalid preset %q: pin a version as %s@1", selector, name)
+			}
+			if preset.Version == 0 {
+				return nil, fmt.Errorf("preset %q is not versioned, so it cannot be pinned as %s", name, selector)
+			}
+			if version != preset.Version {
+				return nil, fmt.Errorf("preset %q is at version %d, not %d", name, preset.Version, version)
+			}
+		}
+		maps.Copy(settings, preset.Settings)
+	}
+	return settings, nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinPresets(t *testing.T) {
+	presets := readBuiltinPresets()
+	assert.ElementsMatch(t, []string{"go-project", "minimal-noise", "web-frontend"}, slices.Collect(maps.Keys(presets)))
+	for name, p := range presets {
+		assert.Equal(t, name, p.Name)
+		assert.Positive(t, p.Version, name)
+		assert.NotEmpty(t, p.Description, name)
+		assert.Equal(t, BuiltinPresetSource, p.Source)
+		assert.NotEmpty(t, p.Settings, name)
+	}
+}
+
+func TestLoadPresets(t *testing.T) {
+	clearEnv(t)
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeConfig(t, filepath.Join(home, "files2prompt", "config.yaml"), `
+markdown: true
+max-size: 2m
+extensions: [txt]
+presets:
+  docs:
+    version: 2
+    description: Documentation only
+    settings:
+      extensions: [md, rst]
+  go-project:
+    settings:
+      extensions: [go]
+profiles:
+  review:
+    preset: [minimal-noise]
+    max-size: 64k
+`)
+	dir := t.TempDir()
+
+	conf, err := LoadPresets(dir, "", nil)
+	require.NoError(t, err)
+	assert.Empty(t, conf.Preset)
+	assert.Equal(t, []string{"txt"}, conf.Extensions)
+
+	// A preset applies over the config files
+	conf, err = LoadPresets(dir, "", []string{"web-frontend"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web-frontend"}, conf.Preset)
+	assert.Contains(t, conf.Extensions, ".tsx")
+	assert.Equal(t, ByteSize(512<<10), conf.MaxFileSize)
+	assert.True(t, conf.Markdown)
+
+	// Later presets override the settings of earlier ones, and leave the rest
+	conf, err = LoadPresets(dir, "", []string{"web-frontend", "minimal-noise"})
+	require.NoError(t, err)
+	assert.Contains(t, conf.Extensions, ".tsx")
+	assert.Equal(t, ByteSize(256<<10), conf.MaxFileSize)
+	assert.Contains(t, conf.IgnorePatterns, "go.sum")
+	assert.NotContains(t, conf.IgnorePatterns, ".next/")
+	conf, err = LoadPresets(dir, "", []string{"minimal-noise", "web-frontend"})
+	require.NoError(t, err)
+	assert.Equal(t, ByteSize(512<<10), conf.MaxFileSize)
+	assert.Contains(t, conf.IgnorePatterns, ".next/")
+
+	// A config file preset replaces the built-in one of its name entirely
+	conf, err = LoadPresets(dir, "", []string{"go-project"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, conf.Extensions)
+	assert.Equal(t, ByteSize(2<<20), conf.MaxFileSize)
+
+	// ...and the project's replaces the user's
+	writeConfig(t, filepath.Join(dir, ProjectConfigName), "presets:\n  docs:\n    version: 3\n    settings:\n      extensions: [adoc]\n")
+	conf, err = LoadPresets(dir, "", []string{"docs@3"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"adoc"}, conf.Extensions)
+	_, err = LoadPresets(dir, "", []string{"docs@2"})
+	assert.EqualError(t, err, `preset "docs" is at version 3, not 2`)
+
+	// A profile may select presets, and its own settings apply over them
+	conf, err = LoadPresets(dir, "review", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"minimal-noise"}, conf.Preset)
+	assert.Contains(t, conf.IgnorePatterns, "go.sum")
+	assert.Equal(t, ByteSize(64<<10), conf.MaxFileSize)
+	// Presets given explicitly replace the profile's selection
+	conf, err = LoadPresets(dir, "review", []string{"docs"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"adoc"}, conf.Extensions)
+	assert.Empty(t, conf.IgnorePatterns)
+	assert.Equal(t, ByteSize(64<<10), conf.MaxFileSize)
+
+	// PRESET selects presets when none are given, and the environment overrides them
+	t.Setenv("PRESET", "minimal-noise,go-project@1")
+	_, err = LoadPresets(dir, "", nil)
+	assert.EqualError(t, err, `preset "go-project" is not versioned, so it cannot be pinned as go-project@1`)
+	t.Setenv("PRESET", "go-project,minimal-noise")
+	t.Setenv("MAX_SIZE", "10k")
+	conf, err = Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go-project", "minimal-noise"}, conf.Preset)
+	assert.Equal(t, []string{"go"}, conf.Extensions)
+	assert.Equal(t, ByteSize(10<<10), conf.MaxFileSize)
+}
+
+func TestLoadPresetsErrors(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	_, err := LoadPresets(dir, "", []string{"backend"})
+	assert.EqualError(t, err, `unknown preset "backend" (available: go-project, minimal-noise, web-frontend)`)
+	_, err = LoadPresets(dir, "", []string{"go-project@latest"})
+	assert.EqualError(t, err, `invalid preset "go-project@latest": pin a version as go-project@1`)
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{name: "presets not a mapping", content: "presets: [backend]\n", err: "presets must map preset names to presets"},
+		{name: "preset not a mapping", content: "presets:\n  backend: [go]\n", err: `preset "backend" must map version, description and settings to values`},
+		{name: "settings at the top", content: "presets:\n  backend:\n    extensions: [go]\n", err: `preset "backend": unknown key "extensions" (use version, description and settings)`},
+		{name: "bad version", content: "presets:\n  backend:\n    version: v1\n", err: `preset "backend": version must be a positive integer`},
+		{name: "unknown setting", content: "presets:\n  backend:\n    settings:\n      extension: [go]\n", err: `preset "backend": unknown setting "extension"`},
+		{name: "nested preset", content: "presets:\n  backend:\n    settings:\n      preset: go-project\n", err: `preset "backend" cannot select a profile or presets`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, ProjectConfigName)
+			writeConfig(t, path, tt.content)
+			_, err := Load(dir)
+			assert.EqualError(t, err, "invalid config file "+path+": "+tt.err)
+		})
+	}
+}
+
+func TestPresets(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, ProjectConfigName)
+	writeConfig(t, path, "presets:\n  backend:\n    description: Services\n    settings:\n      extensions: [go]\n")
+
+	presets, err := Presets(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, p := range presets {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"backend", "go-project", "minimal-noise", "web-frontend"}, names)
+	assert.Equal(t, Preset{Name: "backend", Description: "Services", Source: path, Settings: map[string]string{"EXTENSIONS": "go"}}, presets[0])
+}
@@ -0,0 +1,102 @@
+// Package files2prompt is the library API of files2prompt, for tools that embed
+// it rather than run the command.
+//
+// It selects and renders files exactly as the command does: the same
+// config.Config drives both, and the functions here are the ones the command
+// itself runs. Plan walks the input paths and returns the files that would be
+// emitted, Generate renders them to a writer, and Documents yields them one
+// document at a time for consumers that would rather not hold the whole output
+// in memory.
+//
+// Example usage:
+//
+//	import (
+//		"github.com/toozej/files2prompt/pkg/config"
+//		"github.com/toozej/files2prompt/pkg/files2prompt"
+//	)
+//
+//	conf := config.Config{Paths: []string{"."}, Extensions: []string{"go"}}
+//
+//	// Show the selection, then emit it without walking again
+//	plan, err := files2prompt.Plan(ctx, conf, false)
+//	if err != nil {
+//		return err
+//	}
+//	summary, err := files2prompt.Generate(ctx, conf, os.Stdout, plan)
+//
+//	// Or consume the documents as they are produced
+//	for doc, err := range files2prompt.Documents(ctx, conf, nil) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(doc.File.DisplayPath, doc.Stats.Lines)
+//	}
+package files2prompt
+
+import (
+	"context"
+	"io"
+	"iter"
+
+	impl "github.com/toozej/files2prompt/internal/files2prompt"
+	"github.com/toozej/files2prompt/pkg/config"
+)
+
+type (
+	// PlannedFile is a single filter decision made while walking the input
+	// paths: a file or directory, whether it is emitted, and why not.
+	PlannedFile = impl.PlannedFile
+	// FileDoc is a file document, as yielded by Documents.
+	FileDoc = impl.FileDoc
+	// FileStats describes the content of a document.
+	FileStats = impl.FileStats
+	// Summary describes the output produced by Generate.
+	Summary = impl.Summary
+	// SkipReason identifies the filter that excluded a path.
+	SkipReason = impl.SkipReason
+	// Origin records how a path reached the planner.
+	Origin = impl.Origin
+	// Provenance records where the content of a document came from.
+	Provenance = impl.Provenance
+	// ProvenanceKind names the kind of source a document was read from.
+	ProvenanceKind = impl.ProvenanceKind
+)
+
+// Candidate origins recorded in PlannedFile.Origin.
+const (
+	OriginArg   = impl.OriginArg
+	OriginStdin = impl.OriginStdin
+	OriginWalk  = impl.OriginWalk
+	OriginGlob  = impl.OriginGlob
+)
+
+// Plan returns the files that Generate would emit for conf, in emission order.
+//
+// When includeSkipped is true the result also contains the near misses: files
+// and directories excluded by a filter, each with the Reason it was skipped.
+// The returned plan can be passed to Generate or Documents so that the
+// selection and the emission share a single walk.
+func Plan(ctx context.Context, conf config.Config, includeSkipped bool) ([]PlannedFile, error) {
+	return impl.Plan(ctx, conf, includeSkipped)
+}
+
+// Generate renders the documents selected by conf to w, as the command writes
+// them to its output.
+//
+// When plan is nil the input paths are walked first. Otherwise the included
+// entries of plan, typically obtained from Plan, are emitted in order without
+// walking again, producing exactly the same bytes.
+func Generate(ctx context.Context, conf config.Config, w io.Writer, plan []PlannedFile) (Summary, error) {
+	return impl.Generate(ctx, conf, w, plan)
+}
+
+// Documents yields the file documents selected by conf in emission order,
+// rendering each as Generate would; the output wrapper, the tree and --cmd
+// documents are left out. plan is used as by Generate.
+//
+// Files are read ahead of the consumer only a few per worker at a time, so a
+// slow consumer throttles the reads, and stopping early releases the workers. A
+// failure is yielded once with a zero FileDoc, ending the sequence.
+func Documents(ctx context.Context, conf config.Config, plan []PlannedFile) iter.Seq2[FileDoc, error] {
+	return impl.Documents(ctx, conf, plan)
+}
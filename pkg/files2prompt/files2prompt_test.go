@@ -0,0 +1,68 @@
+package files2prompt_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/toozej/files2prompt/pkg/config"
+	"github.com/toozej/files2prompt/pkg/files2prompt"
+)
+
+func TestPublicAPI(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":       "package main\n",
+		"util/util.go":  "package util\n",
+		"notes.txt":     "not go\n",
+		".hidden/x.go":  "package x\n",
+		"util/extra.go": "package util\n",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	conf := config.Config{Paths: []string{dir}, Extensions: []string{"go"}, Markdown: true}
+	ctx := context.Background()
+
+	plan, err := files2prompt.Plan(ctx, conf, true)
+	require.NoError(t, err)
+	reasons := map[string]files2prompt.SkipReason{}
+	var included []string
+	for _, f := range plan {
+		if f.Included {
+			included = append(included, f.DisplayPath)
+		} else {
+			reasons[filepath.Base(f.Path)] = f.Reason
+		}
+	}
+	assert.Len(t, included, 3)
+	assert.Equal(t, files2prompt.SkipReason("extension filter"), reasons["notes.txt"])
+	assert.Equal(t, files2prompt.SkipReason("hidden-file rule"), reasons[".hidden"])
+
+	// The plan is emitted as a fresh walk would be
+	var walked, planned bytes.Buffer
+	summary, err := files2prompt.Generate(ctx, conf, &walked, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Files)
+	_, err = files2prompt.Generate(ctx, conf, &planned, plan)
+	require.NoError(t, err)
+	assert.Equal(t, walked.String(), planned.String())
+
+	var docs []string
+	for doc, err := range files2prompt.Documents(ctx, conf, plan) {
+		require.NoError(t, err)
+		assert.Equal(t, files2prompt.OriginWalk, doc.File.Origin)
+		docs = append(docs, doc.File.DisplayPath)
+		assert.Contains(t, walked.String(), doc.Rendered)
+		if len(docs) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, included[:2], docs)
+}